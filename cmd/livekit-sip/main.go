@@ -96,7 +96,13 @@ func runService(_ context.Context, c *cli.Command) error {
 	if err != nil {
 		return err
 	}
-	svc := service.NewService(conf, log, sipsrv, sipsrv.Stop, sipsrv.ActiveCalls, psrpcClient, bus, mon)
+	if conf.DialogRegistry != nil {
+		sipsrv.SetDialogStore(sip.NewRedisDialogStore(rc))
+	}
+	if conf.Failover != nil {
+		sipsrv.SetCallStateStore(sip.NewRedisCallStateStore(rc))
+	}
+	svc := service.NewService(conf, log, sipsrv, sipsrv.Stop, sipsrv.ActiveCalls, psrpcClient, bus, mon, sipsrv)
 	sipsrv.SetHandler(svc)
 
 	if err = sipsrv.Start(); err != nil {
@@ -15,10 +15,15 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"math"
 	"net"
 	"net/netip"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -49,12 +54,1732 @@ type TLSCert struct {
 	KeyFile  string `yaml:"key_file"`
 }
 
+// SIPHostnamesConfig sets domain names advertised in Contact and Via headers instead of the signaling IP,
+// per transport. Some carriers require names matching TLS certificates. Names may contain the ${IP} placeholder,
+// same as sip_hostname. Transports without a name advertise the signaling IP.
+type SIPHostnamesConfig struct {
+	UDP string `yaml:"udp"`
+	TCP string `yaml:"tcp"`
+	TLS string `yaml:"tls"` // defaults to sip_hostname
+}
+
+func (c *SIPHostnamesConfig) Validate() error {
+	for _, name := range []string{c.UDP, c.TCP, c.TLS} {
+		if strings.ContainsAny(strings.ReplaceAll(name, "${IP}", "ip"), "$%{}[]:/| ") {
+			return fmt.Errorf("invalid sip hostname %q", name)
+		}
+	}
+	return nil
+}
+
 type TLSConfig struct {
 	Port       int       `yaml:"port"`        // announced SIP signaling port
 	ListenPort int       `yaml:"port_listen"` // SIP signaling port to listen on
 	Certs      []TLSCert `yaml:"certs"`
 }
 
+// SRTPConfig controls SDES-SRTP crypto suites used for media encryption.
+type SRTPConfig struct {
+	// Suites lists allowed crypto suites in the order of preference, for example AEAD_AES_256_GCM or AES_CM_128_HMAC_SHA1_80.
+	// Defaults to AES-CM suites if not set.
+	Suites []string `yaml:"suites"`
+	// RejectDisallowed rejects calls when the remote offers encryption, but none of the offered suites are allowed.
+	// By default, such calls fall back to unencrypted media, unless encryption is required.
+	RejectDisallowed bool `yaml:"reject_disallowed"`
+	// MaxFailures is the number of received SRTP packets failing authentication after which FailureAction is taken.
+	// Only sustained failures are counted: the count restarts when no packet fails for a few seconds.
+	// Replayed packets are not counted, since network duplicates are expected. Sustained failures indicate
+	// a key mismatch or an injection attempt. Zero disables the policy.
+	MaxFailures int `yaml:"max_failures"`
+	// FailureAction is taken once a call reaches MaxFailures. Defaults to SRTPFailureAlert.
+	FailureAction SRTPFailureAction `yaml:"failure_action"`
+}
+
+// SRTPFailureAction selects what happens with calls receiving too many invalid SRTP packets.
+type SRTPFailureAction string
+
+const (
+	// SRTPFailureAlert logs a warning and flags the call, but keeps it. This is the default.
+	SRTPFailureAlert = SRTPFailureAction("alert")
+	// SRTPFailureHangup ends the call.
+	SRTPFailureHangup = SRTPFailureAction("hangup")
+)
+
+func (a SRTPFailureAction) Validate() error {
+	switch a {
+	case "", SRTPFailureAlert, SRTPFailureHangup:
+		return nil
+	}
+	return fmt.Errorf("invalid srtp failure action %q", string(a))
+}
+
+// EncryptionPolicy controls media encryption, overriding the setting from the trunk or dispatch rule.
+type EncryptionPolicy string
+
+const (
+	// EncryptionPolicyDefault uses media encryption setting from the trunk or dispatch rule.
+	EncryptionPolicyDefault = EncryptionPolicy("")
+	// EncryptionPolicyRequire only accepts SRTP and rejects unencrypted calls.
+	EncryptionPolicyRequire = EncryptionPolicy("require")
+	// EncryptionPolicyPrefer uses SRTP when possible and offers RTP/SAVP and RTP/AVP media lines to let the remote choose.
+	EncryptionPolicyPrefer = EncryptionPolicy("prefer")
+	// EncryptionPolicyForbid only accepts unencrypted RTP and rejects SRTP-only calls.
+	EncryptionPolicyForbid = EncryptionPolicy("forbid")
+)
+
+func (p EncryptionPolicy) Validate() error {
+	switch p {
+	case EncryptionPolicyDefault, EncryptionPolicyRequire, EncryptionPolicyPrefer, EncryptionPolicyForbid:
+		return nil
+	}
+	return fmt.Errorf("invalid media encryption policy %q", string(p))
+}
+
+// MediaTransport selects the transport of RTP media.
+type MediaTransport string
+
+const (
+	// MediaTransportUDP sends RTP over UDP. This is the default.
+	MediaTransportUDP = MediaTransport("udp")
+	// MediaTransportTCP only sends RTP framed over TCP (RFC 4571), for networks that block UDP.
+	MediaTransportTCP = MediaTransport("tcp")
+	// MediaTransportAuto offers both UDP and TCP media lines and accepts either, preferring the first one offered by the remote.
+	MediaTransportAuto = MediaTransport("auto")
+)
+
+func (t MediaTransport) Validate() error {
+	switch t {
+	case "", MediaTransportUDP, MediaTransportTCP, MediaTransportAuto:
+		return nil
+	}
+	return fmt.Errorf("invalid rtp transport %q", string(t))
+}
+
+// UnmatchedAction selects how inbound calls that don't match any dispatch rule are handled.
+type UnmatchedAction string
+
+const (
+	// UnmatchedReject rejects the call with a status code. This is the default.
+	UnmatchedReject = UnmatchedAction("reject")
+	// UnmatchedAnnounce answers the call, plays an announcement and hangs up.
+	UnmatchedAnnounce = UnmatchedAction("announce")
+	// UnmatchedRedirect redirects the call to a fallback destination with 302.
+	UnmatchedRedirect = UnmatchedAction("redirect")
+)
+
+// UnmatchedCallConfig controls the response to inbound calls that don't match any dispatch rule.
+type UnmatchedCallConfig struct {
+	Action UnmatchedAction `yaml:"action"`
+	// StatusCode and Reason are used for the reject action. Default is 404.
+	StatusCode int    `yaml:"status_code"`
+	Reason     string `yaml:"reason"`
+	// AnnouncementFile is an Ogg Vorbis file (48 kHz, mono) played by the announce action.
+	AnnouncementFile string `yaml:"announcement_file"`
+	// RedirectTo is a SIP URI used in the Contact header by the redirect action, for example sip:voicemail@example.com.
+	RedirectTo string `yaml:"redirect_to"`
+}
+
+func (c *UnmatchedCallConfig) Validate() error {
+	switch c.Action {
+	case "", UnmatchedReject:
+		if c.StatusCode != 0 && (c.StatusCode < 400 || c.StatusCode > 699) {
+			return fmt.Errorf("invalid status code for unmatched calls: %d", c.StatusCode)
+		}
+	case UnmatchedAnnounce:
+		if c.AnnouncementFile == "" {
+			return fmt.Errorf("announcement file is required for unmatched calls")
+		}
+	case UnmatchedRedirect:
+		if c.RedirectTo == "" {
+			return fmt.Errorf("redirect destination is required for unmatched calls")
+		}
+	default:
+		return fmt.Errorf("invalid action for unmatched calls %q", string(c.Action))
+	}
+	return nil
+}
+
+// DialPlanConfig translates phone numbers: inbound caller and called numbers before dispatch,
+// and outbound destinations before INVITE.
+//
+// Translations are applied in order: prefix stripping, E.164 conversion, prefix addition.
+// The blocklist is checked against the translated number.
+type DialPlanConfig struct {
+	// StripPrefixes lists prefixes removed from the number, for example an outside line access code "9".
+	// Only the first matching prefix is removed.
+	StripPrefixes []string `yaml:"strip_prefixes"`
+	// E164 converts numbers to E.164 format. National numbers get DefaultCountry code.
+	E164 bool `yaml:"e164"`
+	// DefaultCountry is a country calling code without "+", for example "1" or "44".
+	DefaultCountry string `yaml:"default_country"`
+	// AddPrefix is prepended to the translated number.
+	AddPrefix string `yaml:"add_prefix"`
+	// Blocklist rejects calls from or to matching numbers. A pattern ending with "*" matches a prefix.
+	Blocklist []string `yaml:"blocklist"`
+}
+
+func (c *DialPlanConfig) Validate() error {
+	for _, p := range c.StripPrefixes {
+		if p == "" {
+			return fmt.Errorf("dial plan strip prefix must not be empty")
+		}
+	}
+	if cc := c.DefaultCountry; cc != "" {
+		if len(cc) > 3 || strings.Trim(cc, "0123456789") != "" || cc[0] == '0' {
+			return fmt.Errorf("invalid dial plan default country code %q", cc)
+		}
+	}
+	for _, p := range c.Blocklist {
+		if p == "" || p == "*" {
+			return fmt.Errorf("invalid dial plan blocklist pattern %q", p)
+		}
+	}
+	return nil
+}
+
+// TrunkConfig contains settings that override global config for a specific SIP trunk.
+type TrunkConfig struct {
+	// Address of the trunk, host[:port]. Only used to probe the trunk from the admin API,
+	// which requires it to be set, even for trunks not yet used for calls.
+	Address       string               `yaml:"address"`
+	SRTP          *SRTPConfig          `yaml:"srtp"`
+	Encryption    EncryptionPolicy     `yaml:"media_encryption"`
+	UnmatchedCall *UnmatchedCallConfig `yaml:"unmatched_call"`
+	DialPlan      *DialPlanConfig      `yaml:"dial_plan"`
+	CallerID      *CallerIDConfig      `yaml:"caller_id"`
+	Pacing        *PacingConfig        `yaml:"pacing"`
+	Silence       *SilenceConfig       `yaml:"silence"`
+	RTPTransport  MediaTransport       `yaml:"rtp_transport"`
+	SDP           *SDPConfig           `yaml:"sdp"`
+	// AttributeUpdates sends attribute changes to the remote during outbound calls on the trunk.
+	AttributeUpdates *AttributeUpdatesConfig `yaml:"attribute_updates"`
+	// MaxCPS limits calls per second started by the batch dialer on the trunk. Overrides dialer.max_cps.
+	MaxCPS float64 `yaml:"max_cps"`
+	// Capacity limits concurrent calls on the trunk. Overrides the global capacity config.
+	Capacity *CapacityConfig `yaml:"capacity"`
+	// Emergency detects outbound calls to emergency numbers on the trunk. Overrides the global emergency config.
+	Emergency *EmergencyConfig `yaml:"emergency"`
+	// ResourcePriority is the default Resource-Priority header value (RFC 4412), like "ets.0", set on outbound INVITEs on the trunk.
+	// Calls can override it with the sip.resourcePriority attribute or the header in the request.
+	ResourcePriority string `yaml:"resource_priority"`
+	// PickupGroup allows calls from the trunk to pick up active calls on trunks with the same group,
+	// using Replaces (RFC 3891), Join (RFC 3911) or Target-Dialog (RFC 4538) headers. Pickup is disabled if empty.
+	PickupGroup string `yaml:"pickup_group"`
+	// Capabilities overrides Allow, Supported and Accept headers sent on the trunk. Unset lists use the global config.
+	Capabilities *CapabilitiesConfig `yaml:"capabilities"`
+	// SIPTimers overrides transaction timers of requests sent on the trunk. Unset timers use the global config.
+	SIPTimers *SIPTimersConfig `yaml:"sip_timers"`
+}
+
+// CallerIDMode selects the caller ID presented on outbound calls.
+type CallerIDMode string
+
+const (
+	// CallerIDDefault uses the trunk number from the call request.
+	CallerIDDefault = CallerIDMode("")
+	// CallerIDFixed always presents the configured number.
+	CallerIDFixed = CallerIDMode("fixed")
+	// CallerIDPassthrough takes the number from the call request attributes or the room metadata.
+	CallerIDPassthrough = CallerIDMode("passthrough")
+	// CallerIDPool presents a random number from the pool.
+	CallerIDPool = CallerIDMode("pool")
+	// CallerIDAnonymous hides the caller ID and requests privacy with "Privacy: id".
+	CallerIDAnonymous = CallerIDMode("anonymous")
+)
+
+func (m CallerIDMode) Validate() error {
+	switch m {
+	case CallerIDDefault, CallerIDFixed, CallerIDPassthrough, CallerIDPool, CallerIDAnonymous:
+		return nil
+	}
+	return fmt.Errorf("invalid caller id mode %q", string(m))
+}
+
+// CallerIDConfig controls the user part of From, P-Asserted-Identity and Contact headers of outbound calls.
+type CallerIDConfig struct {
+	Mode CallerIDMode `yaml:"mode"`
+	// Number is presented in fixed mode.
+	Number string `yaml:"number"`
+	// Pool of numbers for pool mode.
+	Pool []string `yaml:"pool"`
+	// MetadataKey is a room metadata JSON field with the number for passthrough mode. Default is "caller_id".
+	MetadataKey string `yaml:"metadata_key"`
+	// Format is a pipeline of transforms applied to the number, as in header mappings.
+	// For example, "e164:1 | strip_prefix:+" for carriers that expect E.164 numbers without a plus.
+	Format string `yaml:"format"`
+	// AssertedIdentity adds P-Asserted-Identity header with the number.
+	// In anonymous mode, it carries the trunk number, which is hidden from the callee by the carrier.
+	AssertedIdentity bool `yaml:"asserted_identity"`
+	// ContactUser sets the user part of the Contact header to the number.
+	ContactUser bool `yaml:"contact_user"`
+	// NoDisplayName omits the display name in the From header. By default, it's set to the number.
+	NoDisplayName bool `yaml:"no_display_name"`
+}
+
+func (c *CallerIDConfig) Validate() error {
+	if err := c.Mode.Validate(); err != nil {
+		return err
+	}
+	switch c.Mode {
+	case CallerIDFixed:
+		if c.Number == "" {
+			return fmt.Errorf("caller id number is required in fixed mode")
+		}
+	case CallerIDPool:
+		if len(c.Pool) == 0 {
+			return fmt.Errorf("caller id pool is required in pool mode")
+		}
+	}
+	return nil
+}
+
+// ProvisionalMode controls how inbound calls are signaled to the caller before the room answers.
+type ProvisionalMode string
+
+const (
+	// ProvisionalDefault uses the setting from the dispatch rule config, or ProvisionalRinging.
+	ProvisionalDefault = ProvisionalMode("")
+	// ProvisionalRinging sends 180 Ringing without SDP until the room answers.
+	ProvisionalRinging = ProvisionalMode("ringing")
+	// ProvisionalEarlyMedia sends 183 Session Progress with SDP and plays ringback to the caller until the room answers.
+	ProvisionalEarlyMedia = ProvisionalMode("early_media")
+	// ProvisionalAnswer answers the call immediately, without waiting for the room.
+	ProvisionalAnswer = ProvisionalMode("answer")
+)
+
+func (m ProvisionalMode) Validate() error {
+	switch m {
+	case ProvisionalDefault, ProvisionalRinging, ProvisionalEarlyMedia, ProvisionalAnswer:
+		return nil
+	}
+	return fmt.Errorf("invalid provisional mode %q", string(m))
+}
+
+// DispatchRuleConfig contains settings that override global config for a specific dispatch rule.
+type DispatchRuleConfig struct {
+	Provisional ProvisionalMode `yaml:"provisional"`
+	NoSpeech    *NoSpeechConfig `yaml:"no_speech"`
+	// Echo answers calls matching the rule with an echo test, instead of joining the room.
+	Echo *EchoConfig `yaml:"echo"`
+	// Announce answers calls matching the rule with an announcement and hangs up, instead of joining the room.
+	Announce *AnnounceConfig `yaml:"announce"`
+	// MediaStages process audio received from the caller, in the given order, before it is sent to the room.
+	MediaStages []MediaStageConfig `yaml:"media_stages"`
+	// AttributeUpdates sends attribute changes to the caller during calls matching the rule.
+	AttributeUpdates *AttributeUpdatesConfig `yaml:"attribute_updates"`
+	// Priority marks calls matching the rule as priority calls, which may use trunk capacity reserved for them.
+	Priority bool `yaml:"priority"`
+	// ResourcePriority limits the rule to calls with a matching Resource-Priority header (RFC 4412).
+	// Each entry is either a namespace, like "ets", or a full value, like "ets.0".
+	// Other calls are handled as calls which don't match any dispatch rule.
+	ResourcePriority []string `yaml:"resource_priority"`
+	// Busy handles calls matching the rule when the trunk capacity is exceeded. By default, they are rejected with 503.
+	Busy *BusyConfig `yaml:"busy"`
+	// Queue answers calls matching the rule and holds them in a call queue, until the handler signals that an agent is ready.
+	Queue *CallQueueConfig `yaml:"queue"`
+}
+
+// AttributeUpdateMethod selects the in-dialog SIP request which carries attribute updates.
+type AttributeUpdateMethod string
+
+const (
+	AttributeUpdateInfo   = AttributeUpdateMethod("info")
+	AttributeUpdateUpdate = AttributeUpdateMethod("update")
+)
+
+// AttributeUpdatesConfig sends changes of participant attributes, which are mapped by attributes_to_headers,
+// to the remote during the call. Headers are only set on call setup otherwise.
+type AttributeUpdatesConfig struct {
+	// Method is the SIP request carrying the headers: info or update. Default is info.
+	Method AttributeUpdateMethod `yaml:"method"`
+	// Attributes limits updates to the given attributes. All mapped attributes are sent if empty.
+	Attributes []string `yaml:"attributes"`
+}
+
+func (c *AttributeUpdatesConfig) Validate() error {
+	switch c.Method {
+	case "", AttributeUpdateInfo, AttributeUpdateUpdate:
+	default:
+		return fmt.Errorf("invalid attribute update method %q", string(c.Method))
+	}
+	for _, a := range c.Attributes {
+		if a == "" {
+			return fmt.Errorf("empty attribute name in attribute updates")
+		}
+	}
+	return nil
+}
+
+// Built-in media stages.
+const (
+	// MediaStageGain amplifies or attenuates the audio.
+	MediaStageGain = "gain"
+	// MediaStageVAD detects when the caller starts and stops speaking.
+	MediaStageVAD = "vad"
+	// MediaStageNS is a noise gate, which silences the audio between speech.
+	MediaStageNS = "ns"
+	// MediaStageRecorder writes a copy of the audio to a WAV file.
+	MediaStageRecorder = "recorder"
+	// MediaStageDTMF detects in-band DTMF tones in the audio.
+	MediaStageDTMF = "dtmf"
+	// MediaStageSTT streams the audio to the speech-to-text engine and publishes transcripts to the room.
+	MediaStageSTT = "stt"
+
+	// MaxMediaStageGain is the largest gain in dB, in either direction.
+	MaxMediaStageGain = 40.0
+)
+
+// MediaStageConfig configures a single stage of the media pipeline.
+// Stages other than built-in ones can be registered by the handler.
+type MediaStageConfig struct {
+	// Name of the stage.
+	Name string `yaml:"name"`
+	// Gain in dB for the gain stage.
+	Gain float64 `yaml:"gain"`
+	// Threshold is the audio level in dBFS considered as speech by vad and ns stages.
+	// Default is -40 for vad and -50 for ns.
+	Threshold float64 `yaml:"threshold"`
+	// Dir is a directory where the recorder stage writes files.
+	Dir string `yaml:"dir"`
+	// Options are passed to stages registered by the handler.
+	Options map[string]string `yaml:"options"`
+}
+
+func (c *MediaStageConfig) Validate() error {
+	switch c.Name {
+	case "":
+		return fmt.Errorf("media stage name is required")
+	case MediaStageGain:
+		if math.Abs(c.Gain) > MaxMediaStageGain {
+			return fmt.Errorf("media stage gain must be between -%v and %v dB", MaxMediaStageGain, MaxMediaStageGain)
+		}
+	case MediaStageVAD, MediaStageNS:
+		if c.Threshold > 0 {
+			return fmt.Errorf("media stage %q threshold must not be positive", c.Name)
+		}
+	case MediaStageRecorder:
+		if c.Dir == "" {
+			return fmt.Errorf("media stage %q requires dir", c.Name)
+		}
+	}
+	return nil
+}
+
+const DefaultAnnounceDigitTimeout = 5 * time.Second
+
+// AnnounceConfig configures announcement-only calls, for example closed hours messages or number verification.
+type AnnounceConfig struct {
+	// File is an Ogg Vorbis file (48 kHz, mono) to play.
+	File string `yaml:"file"`
+	// Text is synthesized with the TTS service and played, if File is not set.
+	Text string `yaml:"text"`
+	// CollectDigit waits for a single DTMF digit after the announcement.
+	// The digit is reported in the lastDTMF attribute of the call info.
+	CollectDigit bool `yaml:"collect_digit"`
+	// DigitTimeout is how long to wait for the digit. Default is 5s.
+	DigitTimeout time.Duration `yaml:"digit_timeout"`
+}
+
+func (c *AnnounceConfig) Validate() error {
+	if (c.File == "") == (c.Text == "") {
+		return fmt.Errorf("announcement requires either file or text")
+	}
+	if c.DigitTimeout < 0 {
+		return fmt.Errorf("announcement digit timeout must not be negative")
+	}
+	return nil
+}
+
+// BusyAction selects what happens with a call when the trunk has no capacity left for it.
+type BusyAction string
+
+const (
+	// BusyReject rejects the call with 486 Busy Here, or with the configured status, like 600 Busy Everywhere.
+	BusyReject = BusyAction("reject")
+	// BusyQueue answers the call and holds the caller in a queue with music, until the trunk has capacity for it.
+	BusyQueue = BusyAction("queue")
+	// BusyForward redirects the call to an overflow destination with 302 Moved Temporarily.
+	BusyForward = BusyAction("forward")
+)
+
+const DefaultBusyQueueMaxWait = 5 * time.Minute
+
+// BusyConfig handles calls which are accepted by a dispatch rule, but exceed the trunk capacity.
+type BusyConfig struct {
+	Action BusyAction `yaml:"action"`
+	// StatusCode and Reason are used by the reject action, and by the queue action if the queue is full. Default is 486.
+	StatusCode int    `yaml:"status_code"`
+	Reason     string `yaml:"reason"`
+	// ForwardTo is a SIP URI used in the Contact header by the forward action, for example sip:overflow@example.com.
+	ForwardTo string `yaml:"forward_to"`
+	// MusicFile is an Ogg Vorbis file (48 kHz, mono) played in a loop to queued callers.
+	MusicFile string `yaml:"music_file"`
+	// PositionText is synthesized with the TTS service and played to queued callers before each loop of the music.
+	// The {position} placeholder is replaced with the position of the caller in the queue.
+	PositionText string `yaml:"position_text"`
+	// MaxSize limits the number of callers queued on the trunk. Zero means no limit.
+	MaxSize int `yaml:"max_size"`
+	// MaxWait is how long a caller stays in the queue before the call is ended. Default is 5 minutes.
+	MaxWait time.Duration `yaml:"max_wait"`
+}
+
+func (c *BusyConfig) Validate() error {
+	if c.StatusCode != 0 && (c.StatusCode < 400 || c.StatusCode > 699) {
+		return fmt.Errorf("invalid status code for busy calls: %d", c.StatusCode)
+	}
+	switch c.Action {
+	case BusyReject:
+	case BusyQueue:
+		if c.MusicFile == "" {
+			return fmt.Errorf("music file is required for the busy queue")
+		}
+		if c.MaxSize < 0 || c.MaxWait < 0 {
+			return fmt.Errorf("busy queue limits must not be negative")
+		}
+	case BusyForward:
+		if c.ForwardTo == "" {
+			return fmt.Errorf("forward destination is required for busy calls")
+		}
+	default:
+		return fmt.Errorf("invalid action for busy calls %q", string(c.Action))
+	}
+	return nil
+}
+
+const (
+	DefaultCallQueueMaxWait          = 10 * time.Minute
+	DefaultCallQueueAnnounceInterval = 30 * time.Second
+	MinCallQueueAnnounceInterval     = 5 * time.Second
+)
+
+// CallQueueConfig holds answered calls in a queue with music, until the handler signals that an agent is ready for them.
+type CallQueueConfig struct {
+	// Name of the queue used by the handler to release calls. Default is the ID of the dispatch rule.
+	// Rules with the same name share the queue.
+	Name string `yaml:"name"`
+	// MusicFile is an Ogg Vorbis file (48 kHz, mono) played in a loop to queued callers.
+	MusicFile string `yaml:"music_file"`
+	// PositionText is synthesized with the TTS service and played to queued callers periodically.
+	// The {position} placeholder is replaced with the position of the caller in the queue.
+	PositionText string `yaml:"position_text"`
+	// ETAText is played after the position, if the wait time can be estimated from previous calls.
+	// The {eta} placeholder is replaced with the estimated wait time in minutes.
+	ETAText string `yaml:"eta_text"`
+	// AnnounceInterval is the time between announcements. Default is 30 seconds.
+	AnnounceInterval time.Duration `yaml:"announce_interval"`
+	// MaxSize limits the number of queued callers. Callers are rejected with 486 once the queue is full. Zero means no limit.
+	MaxSize int `yaml:"max_size"`
+	// MaxWait is how long a caller stays in the queue before the call is ended. Default is 10 minutes.
+	MaxWait time.Duration `yaml:"max_wait"`
+	// Callback lets queued callers request a callback instead of waiting.
+	Callback *CallbackConfig `yaml:"callback"`
+}
+
+func (c *CallQueueConfig) Validate() error {
+	if c.MusicFile == "" {
+		return fmt.Errorf("music file is required for the call queue")
+	}
+	if c.MaxSize < 0 || c.MaxWait < 0 {
+		return fmt.Errorf("call queue limits must not be negative")
+	}
+	if c.AnnounceInterval != 0 && c.AnnounceInterval < MinCallQueueAnnounceInterval {
+		return fmt.Errorf("call queue announce interval must be at least %v", MinCallQueueAnnounceInterval)
+	}
+	if c.Callback != nil {
+		if err := c.Callback.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const DefaultCallbackMaxWait = time.Hour
+
+// CallbackConfig lets queued callers press a digit to end the call and keep their place in the queue.
+// Once an agent is ready for them, the dialer calls them back and bridges the call to the room.
+//
+// The room token of the dispatch is used for the callback, thus it must stay valid for MaxWait.
+type CallbackConfig struct {
+	// Digit is the DTMF digit which requests a callback.
+	Digit string `yaml:"digit"`
+	// OfferText is synthesized with the TTS service and played after each queue announcement,
+	// for example "Press 1 to hang up and get a call back".
+	OfferText string `yaml:"offer_text"`
+	// ConfirmText is played before the call ends, once the callback is requested.
+	ConfirmText string `yaml:"confirm_text"`
+	// TrunkID, Address, Transport, Number, Username and Password select the trunk for callbacks, as in CreateSIPParticipant.
+	// Number is the caller ID of the callback. Default is the number dialed by the caller.
+	TrunkID   string `yaml:"trunk_id"`
+	Address   string `yaml:"address"`
+	Transport string `yaml:"transport"`
+	Number    string `yaml:"number"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+	// MaxAttempts limits attempts of a busy or unanswered callback. Default is 1.
+	MaxAttempts int `yaml:"max_attempts"`
+	// RetryInterval is the delay before the next attempt. Default is set in the dialer config.
+	RetryInterval time.Duration `yaml:"retry_interval"`
+	// MaxWait is how long a callback request keeps its place in the queue. Default is 1 hour.
+	MaxWait time.Duration `yaml:"max_wait"`
+}
+
+func (c *CallbackConfig) Validate() error {
+	if len(c.Digit) != 1 || !strings.Contains("0123456789*#", c.Digit) {
+		return fmt.Errorf("invalid callback digit %q", c.Digit)
+	}
+	if c.Address == "" {
+		return fmt.Errorf("callback address is required")
+	}
+	switch c.Transport {
+	case "", "udp", "tcp", "tls":
+	default:
+		return fmt.Errorf("invalid callback transport %q", c.Transport)
+	}
+	if c.MaxAttempts < 0 || c.RetryInterval < 0 || c.MaxWait < 0 {
+		return fmt.Errorf("callback limits must not be negative")
+	}
+	return nil
+}
+
+const (
+	DefaultEchoMaxDuration = 5 * time.Minute
+	// MaxEchoDelay is the longest delay of the echoed audio.
+	MaxEchoDelay = 10 * time.Second
+)
+
+// EchoConfig configures a built-in echo test. Received audio is played back to the caller
+// and DTMF digits are sent back, which allows verifying the audio path of a trunk without a LiveKit room.
+type EchoConfig struct {
+	// Delay of the echoed audio. Zero plays the audio back immediately.
+	Delay time.Duration `yaml:"delay"`
+	// MaxDuration ends the echo test call. Default is 5 minutes.
+	MaxDuration time.Duration `yaml:"max_duration"`
+}
+
+func (c *EchoConfig) Validate() error {
+	if c.Delay < 0 || c.Delay > MaxEchoDelay {
+		return fmt.Errorf("echo delay must be between 0 and %v", MaxEchoDelay)
+	}
+	if c.MaxDuration < 0 {
+		return fmt.Errorf("echo max duration must not be negative")
+	}
+	return nil
+}
+
+// NoSpeechAction is taken when no speech is detected on an answered call.
+type NoSpeechAction string
+
+const (
+	// NoSpeechHangup ends the call.
+	NoSpeechHangup = NoSpeechAction("hangup")
+	// NoSpeechTransfer transfers the call with SIP REFER.
+	NoSpeechTransfer = NoSpeechAction("transfer")
+)
+
+// NoSpeechConfig ends or reroutes inbound calls when no caller speech is detected after answer,
+// protecting agents from dead air calls.
+type NoSpeechConfig struct {
+	// Timeout after answer to wait for speech.
+	Timeout time.Duration `yaml:"timeout"`
+	// Action is hangup (default) or transfer.
+	Action NoSpeechAction `yaml:"action"`
+	// TransferTo is a SIP or TEL URI for the transfer action.
+	TransferTo string `yaml:"transfer_to"`
+	// Threshold is the audio level in dBFS considered as speech. Default is -40.
+	Threshold float64 `yaml:"threshold"`
+	// MinSpeech is the minimal duration of continuous speech. Default is 250ms.
+	MinSpeech time.Duration `yaml:"min_speech"`
+}
+
+func (c *NoSpeechConfig) Validate() error {
+	if c.Timeout <= 0 {
+		return fmt.Errorf("no speech timeout is required")
+	}
+	switch c.Action {
+	case "", NoSpeechHangup:
+	case NoSpeechTransfer:
+		if c.TransferTo == "" {
+			return fmt.Errorf("no speech transfer requires transfer_to")
+		}
+	default:
+		return fmt.Errorf("invalid no speech action %q", string(c.Action))
+	}
+	if c.Threshold > 0 || c.MinSpeech < 0 {
+		return fmt.Errorf("invalid no speech detection settings")
+	}
+	return nil
+}
+
+const (
+	DefaultDTMFToneDuration = 250 * time.Millisecond
+	DefaultDTMFGap          = 250 * time.Millisecond
+	DefaultDTMFPause        = 500 * time.Millisecond
+	DefaultDTMFLongPause    = 2 * time.Second
+
+	// MaxDTMFToneDuration is the longest tone that fits into a single telephone-event.
+	MaxDTMFToneDuration = 8 * time.Second
+
+	DefaultDTMFMenuTimeout = 3 * time.Second
+
+	DefaultSIPKeepAliveInterval = 30 * time.Second
+
+	DefaultSIPTransportFallbackTimeout = 5 * time.Second
+
+	DefaultSIPMaxMessageSize  = 64 * 1024
+	DefaultSIPMaxHeaders      = 128
+	DefaultSIPMaxHeaderLength = 4 * 1024
+	DefaultSIPMaxURILength    = 1024
+
+	// MinSIPTimerT1 is the shortest round-trip time estimate accepted in SIP timers.
+	MinSIPTimerT1 = 10 * time.Millisecond
+
+	DefaultTestCallInterval = 5 * time.Minute
+	DefaultTestCallTimeout  = 30 * time.Second
+)
+
+// DTMFMenuAction is taken when an in-call DTMF menu sequence is entered.
+type DTMFMenuAction string
+
+const (
+	// DTMFMenuNotify only reports the sequence to the handler.
+	DTMFMenuNotify = DTMFMenuAction("notify")
+	// DTMFMenuTransfer transfers the call to TransferTo.
+	DTMFMenuTransfer = DTMFMenuAction("transfer")
+	// DTMFMenuHangup ends the call.
+	DTMFMenuHangup = DTMFMenuAction("hangup")
+)
+
+// DTMFMenuItem maps an in-call DTMF sequence to an action. All matched sequences are reported to the handler.
+type DTMFMenuItem struct {
+	// Digits is the sequence to match, for example "*0".
+	Digits string `yaml:"digits"`
+	// Name identifies the item for the handler, for example "operator" or "start_recording".
+	Name string `yaml:"name"`
+	// Action is notify (default), transfer or hangup.
+	Action DTMFMenuAction `yaml:"action"`
+	// TransferTo is a SIP or TEL URI for the transfer action.
+	TransferTo string `yaml:"transfer_to"`
+}
+
+func (m *DTMFMenuItem) Validate() error {
+	if m.Digits == "" {
+		return fmt.Errorf("dtmf menu digits are required")
+	}
+	for _, c := range strings.ToLower(m.Digits) {
+		if !strings.ContainsRune("0123456789*#abcd", c) {
+			return fmt.Errorf("invalid dtmf menu digits %q", m.Digits)
+		}
+	}
+	switch m.Action {
+	case "", DTMFMenuNotify, DTMFMenuHangup:
+	case DTMFMenuTransfer:
+		if m.TransferTo == "" {
+			return fmt.Errorf("dtmf menu transfer requires transfer_to")
+		}
+	default:
+		return fmt.Errorf("invalid dtmf menu action %q", string(m.Action))
+	}
+	return nil
+}
+
+// DTMFConfig controls pacing of DTMF digits sent to SIP.
+type DTMFConfig struct {
+	// ToneDuration is the duration of each digit. Default is 250ms.
+	ToneDuration time.Duration `yaml:"tone_duration"`
+	// Gap is the pause after each digit. Default is 250ms.
+	Gap time.Duration `yaml:"gap"`
+	// Pause is the delay for each 'w' character. Default is 500ms.
+	Pause time.Duration `yaml:"pause"`
+	// LongPause is the delay for each ',' character. Default is 2s.
+	LongPause time.Duration `yaml:"long_pause"`
+
+	// Menu lists DTMF sequences that trigger actions during the call.
+	Menu []DTMFMenuItem `yaml:"menu"`
+	// MenuTimeout is the maximal pause between digits of a menu sequence. Default is 3s.
+	MenuTimeout time.Duration `yaml:"menu_timeout"`
+}
+
+func (c *DTMFConfig) Validate() error {
+	if c.ToneDuration < 0 || c.Gap < 0 || c.Pause < 0 || c.LongPause < 0 {
+		return fmt.Errorf("dtmf durations must not be negative")
+	}
+	if c.ToneDuration > MaxDTMFToneDuration {
+		return fmt.Errorf("dtmf tone duration must not exceed %v", MaxDTMFToneDuration)
+	}
+	if c.MenuTimeout < 0 {
+		return fmt.Errorf("dtmf menu timeout must not be negative")
+	}
+	for i := range c.Menu {
+		m := &c.Menu[i]
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		// A sequence that is a prefix of another one would make the longer one unreachable.
+		for _, m2 := range c.Menu[:i] {
+			a, b := strings.ToLower(m.Digits), strings.ToLower(m2.Digits)
+			if strings.HasPrefix(a, b) || strings.HasPrefix(b, a) {
+				return fmt.Errorf("dtmf menu sequences %q and %q are ambiguous", m2.Digits, m.Digits)
+			}
+		}
+	}
+	return nil
+}
+
+// SIPLimitsConfig caps inbound SIP requests. Zero values use defaults, negative values disable the limit.
+type SIPLimitsConfig struct {
+	// MaxMessageSize is the largest request size in bytes, including the body. Larger requests are rejected with 413.
+	// Default is 64KB.
+	MaxMessageSize int `yaml:"max_message_size"`
+	// MaxHeaders is the maximal number of headers in a request. Requests with more headers are rejected with 400.
+	// Default is 128.
+	MaxHeaders int `yaml:"max_headers"`
+	// MaxHeaderLength is the maximal length of each header value. Requests with longer values are rejected with 400.
+	// Default is 4KB.
+	MaxHeaderLength int `yaml:"max_header_length"`
+	// MaxURILength is the maximal length of the Request-URI. Requests with longer URIs are rejected with 414.
+	// Default is 1KB.
+	MaxURILength int `yaml:"max_uri_length"`
+}
+
+// SIPTimersConfig tunes transaction timers (RFC 3261, section 17.1) of requests sent by SIP.
+// Carrier links with high latency may need longer timers than the defaults.
+type SIPTimersConfig struct {
+	// T1 is the round-trip time estimate. Timer B and Timer F default to 64*T1. Default is 500ms.
+	// It doesn't change retransmission intervals of the transaction layer.
+	T1 time.Duration `yaml:"t1"`
+	// TimerB is how long an INVITE waits for the first response, before it fails with 408.
+	// If it's longer than the transaction layer allows, the INVITE is sent again in a new transaction.
+	TimerB time.Duration `yaml:"timer_b"`
+	// TimerF is how long other requests, like BYE or REFER, wait for a final response, before they fail with 408.
+	// It's capped by the transaction layer, since these requests are not sent again.
+	TimerF time.Duration `yaml:"timer_f"`
+}
+
+func (c *SIPTimersConfig) Validate() error {
+	if c.T1 < 0 || c.TimerB < 0 || c.TimerF < 0 {
+		return fmt.Errorf("sip timers must not be negative")
+	}
+	if c.T1 > 0 && c.T1 < MinSIPTimerT1 {
+		return fmt.Errorf("sip timer t1 must be at least %v", MinSIPTimerT1)
+	}
+	if c.TimerB > 0 && c.TimerB < c.T1 {
+		return fmt.Errorf("sip timer b must not be shorter than t1")
+	}
+	if c.TimerF > 0 && c.TimerF < c.T1 {
+		return fmt.Errorf("sip timer f must not be shorter than t1")
+	}
+	return nil
+}
+
+// PacingConfig controls pacing of RTP packets sent to SIP. Some carriers police the rate of RTP,
+// and drop packets sent in bursts, for example after a jitter event on the room side.
+type PacingConfig struct {
+	// Enabled sends audio packets with strict 20ms spacing.
+	Enabled bool `yaml:"enabled"`
+	// MaxBitrate caps the bitrate of RTP sent to SIP in bits per second, including RTP, UDP and IP headers. Zero disables the cap.
+	MaxBitrate int `yaml:"max_bitrate"`
+	// MaxDelay is how long packets can be delayed by pacing before they are dropped. Default is 200ms.
+	MaxDelay time.Duration `yaml:"max_delay"`
+}
+
+func (c *PacingConfig) Validate() error {
+	if c.MaxBitrate < 0 {
+		return fmt.Errorf("pacing max bitrate must not be negative")
+	}
+	if c.MaxBitrate > 0 && c.MaxBitrate < MinPacingBitrate {
+		return fmt.Errorf("pacing max bitrate must be at least %d", MinPacingBitrate)
+	}
+	if c.MaxDelay < 0 {
+		return fmt.Errorf("pacing max delay must not be negative")
+	}
+	return nil
+}
+
+// MinPacingBitrate is the lowest bitrate cap that fits G.711 audio with headers.
+const MinPacingBitrate = 80000
+
+// Limits of comfort noise and noise gate levels, in dBov.
+const (
+	MinSilenceLevel          = -90.0
+	DefaultComfortNoiseLevel = -70.0
+)
+
+// SilenceConfig keeps RTP flowing to SIP while the room has no audio, for example before an agent publishes
+// or while the call is muted. Some carriers end calls which don't receive RTP for a few seconds.
+type SilenceConfig struct {
+	// Enabled sends silence frames with 20ms spacing when the room doesn't send audio.
+	Enabled bool `yaml:"enabled"`
+	// ComfortNoise sends low level noise instead of digital silence. Some endpoints treat digital silence as a dead line.
+	ComfortNoise bool `yaml:"comfort_noise"`
+	// NoiseLevel is the level of comfort noise in dBov. Default is -70.
+	NoiseLevel float64 `yaml:"noise_level"`
+	// GateThreshold replaces room audio quieter than the threshold (in dBov) with silence or comfort noise.
+	// Zero disables the gate.
+	GateThreshold float64 `yaml:"gate_threshold"`
+}
+
+func (c *SilenceConfig) Validate() error {
+	if c.NoiseLevel != 0 && (c.NoiseLevel < MinSilenceLevel || c.NoiseLevel >= 0) {
+		return fmt.Errorf("silence noise level must be between %v and 0 dBov", MinSilenceLevel)
+	}
+	if c.GateThreshold != 0 && (c.GateThreshold < MinSilenceLevel || c.GateThreshold >= 0) {
+		return fmt.Errorf("silence gate threshold must be between %v and 0 dBov", MinSilenceLevel)
+	}
+	return nil
+}
+
+const (
+	DefaultDialerMaxJobs       = 10000
+	DefaultDialerRetryInterval = 5 * time.Minute
+)
+
+// DialerConfig controls the batch dialer, which places scheduled outbound calls and retries busy or unanswered ones.
+type DialerConfig struct {
+	// MaxJobs limits the number of scheduled and active dial jobs. Default is 10000.
+	MaxJobs int `yaml:"max_jobs"`
+	// MaxCPS limits calls per second started by the dialer on each trunk. Zero means no limit. Can be overridden per trunk.
+	MaxCPS float64 `yaml:"max_cps"`
+	// RetryInterval is the delay before the next attempt of a busy or unanswered call,
+	// if the job doesn't set one. Default is 5m.
+	RetryInterval time.Duration `yaml:"retry_interval"`
+}
+
+func (c *DialerConfig) Validate() error {
+	if c.MaxJobs < 0 {
+		return fmt.Errorf("dialer max jobs must not be negative")
+	}
+	if c.MaxCPS < 0 {
+		return fmt.Errorf("dialer max cps must not be negative")
+	}
+	if c.RetryInterval < 0 {
+		return fmt.Errorf("dialer retry interval must not be negative")
+	}
+	return nil
+}
+
+// CapacityConfig limits concurrent calls on a trunk, on this node, and reserves a part of them for priority calls,
+// so that emergency or VIP calls succeed when the trunk is saturated.
+type CapacityConfig struct {
+	// MaxCalls limits concurrent inbound and outbound calls on the trunk. Zero means no limit.
+	MaxCalls int `yaml:"max_calls"`
+	// PriorityReserve is the percentage of MaxCalls which only priority calls can use.
+	PriorityReserve float64 `yaml:"priority_reserve"`
+	// PriorityNamespaces lists Resource-Priority namespaces (RFC 4412), like "ets" or "esnet", which make a call a priority call.
+	// A call with any Resource-Priority header is a priority call if the list is empty.
+	// Calls can also be marked as priority calls by dispatch rules.
+	PriorityNamespaces []string `yaml:"priority_namespaces"`
+}
+
+func (c *CapacityConfig) Validate() error {
+	if c.MaxCalls < 0 {
+		return fmt.Errorf("trunk capacity max calls must not be negative")
+	}
+	if c.PriorityReserve < 0 || c.PriorityReserve > 100 {
+		return fmt.Errorf("trunk capacity priority reserve must be between 0 and 100 percent")
+	}
+	return nil
+}
+
+// emergencyNumbers lists emergency numbers of supported countries.
+var emergencyNumbers = map[string][]string{
+	"AU": {"000", "112"},
+	"BR": {"190", "192", "193"},
+	"CA": {"911"},
+	"DE": {"110", "112"},
+	"ES": {"112"},
+	"FR": {"15", "17", "18", "112"},
+	"GB": {"999", "112"},
+	"IE": {"999", "112"},
+	"IN": {"112"},
+	"IT": {"112"},
+	"JP": {"110", "119"},
+	"MX": {"911"},
+	"NL": {"112"},
+	"NZ": {"111"},
+	"US": {"911"},
+}
+
+// EmergencyConfig detects outbound calls to emergency numbers. Emergency calls bypass project quotas, trunk capacity limits,
+// the do-not-call list, dial plan blocks and the dialer pacing, and carry the caller location.
+type EmergencyConfig struct {
+	// Country adds emergency numbers of a country, like "US" or "GB".
+	Country string `yaml:"country"`
+	// Numbers lists additional emergency numbers. They are compared with the dialed number, ignoring the leading plus.
+	Numbers []string `yaml:"numbers"`
+	// Location is sent with emergency calls, if set.
+	Location *LocationConfig `yaml:"location"`
+}
+
+func (c *EmergencyConfig) Validate() error {
+	if c.Country != "" && emergencyNumbers[strings.ToUpper(c.Country)] == nil {
+		return fmt.Errorf("unsupported emergency country %q", c.Country)
+	}
+	for _, n := range c.Numbers {
+		if n == "" || strings.ContainsAny(n, "@; ") {
+			return fmt.Errorf("invalid emergency number %q", n)
+		}
+	}
+	if c.Location != nil {
+		if err := c.Location.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllNumbers returns the configured emergency numbers, including numbers of the country.
+func (c *EmergencyConfig) AllNumbers() []string {
+	out := slices.Clone(emergencyNumbers[strings.ToUpper(c.Country)])
+	for _, n := range c.Numbers {
+		out = append(out, strings.TrimPrefix(n, "+"))
+	}
+	return out
+}
+
+// LocationConfig is the caller location conveyed with emergency calls, see RFC 6442.
+type LocationConfig struct {
+	// PIDF is a PIDF-LO document (RFC 4119) sent in the INVITE body and referenced by the Geolocation header.
+	PIDF string `yaml:"pidf"`
+	// URI is a location reference sent in the Geolocation header, like "https://lis.example.com/locations/1".
+	URI string `yaml:"uri"`
+	// Routing allows the carrier to use the location for routing the call, with "Geolocation-Routing: yes".
+	Routing bool `yaml:"routing"`
+}
+
+func (c *LocationConfig) Validate() error {
+	if c.PIDF == "" && c.URI == "" {
+		return fmt.Errorf("emergency location requires pidf or uri")
+	}
+	if c.PIDF != "" && !strings.HasPrefix(strings.TrimSpace(c.PIDF), "<") {
+		return fmt.Errorf("emergency location pidf must be an xml document")
+	}
+	if strings.ContainsAny(c.URI, "<> \r\n") {
+		return fmt.Errorf("invalid emergency location uri %q", c.URI)
+	}
+	return nil
+}
+
+// CapabilitiesConfig sets SIP capabilities advertised in OPTIONS and INVITE responses and in INVITE requests.
+// By default, they list the methods, extensions and body types implemented by SIP. Some SBCs route calls based on them.
+// A list which is not set keeps the default, an empty list omits the header.
+type CapabilitiesConfig struct {
+	// Allow lists SIP methods, like "INVITE" or "OPTIONS".
+	Allow []string `yaml:"allow"`
+	// Supported lists SIP extension option tags, like "replaces" or "timer".
+	Supported []string `yaml:"supported"`
+	// Accept lists body types, like "application/sdp".
+	Accept []string `yaml:"accept"`
+}
+
+func (c *CapabilitiesConfig) Validate() error {
+	for _, m := range c.Allow {
+		if !isSIPToken(m) || strings.ToUpper(m) != m {
+			return fmt.Errorf("invalid sip method %q", m)
+		}
+	}
+	for _, tag := range c.Supported {
+		if !isSIPToken(tag) {
+			return fmt.Errorf("invalid sip option tag %q", tag)
+		}
+	}
+	for _, v := range c.Accept {
+		typ, sub, ok := strings.Cut(v, "/")
+		if !ok || !isSIPToken(typ) || !isSIPToken(sub) {
+			return fmt.Errorf("invalid accepted body type %q", v)
+		}
+	}
+	return nil
+}
+
+// isSIPToken checks if the value is a token, as defined by RFC 3261.
+func isSIPToken(v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, r := range v {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("-.!%*_+`'~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validateResourcePriority checks a Resource-Priority value, like "ets.0". The priority part is optional in filters.
+func validateResourcePriority(v string, full bool) error {
+	ns, prio, ok := strings.Cut(v, ".")
+	if ns == "" || strings.ContainsAny(ns, " \t,;") {
+		return fmt.Errorf("invalid resource priority %q", v)
+	}
+	if (full || ok) && (prio == "" || strings.ContainsAny(prio, " \t,;.")) {
+		return fmt.Errorf("invalid resource priority %q", v)
+	}
+	return nil
+}
+
+// SDPConfig controls session-level fields of SDP sent by SIP and parsing of SDP received from the remote.
+// Some carriers validate them.
+type SDPConfig struct {
+	// SessionName is sent in the s= line. Default is "LiveKit".
+	SessionName string `yaml:"session_name"`
+	// Username is sent in the o= line. Default is "-".
+	Username string `yaml:"username"`
+	// Lenient skips malformed lines and duplicate rtpmaps in remote SDP, and fixes invalid ports and missing
+	// session lines, instead of failing the call. Fixed anomalies are logged.
+	Lenient bool `yaml:"lenient"`
+}
+
+func (c *SDPConfig) Validate() error {
+	if strings.ContainsAny(c.SessionName, "\r\n") {
+		return fmt.Errorf("sdp session name must be a single line")
+	}
+	if strings.ContainsAny(c.Username, " \t\r\n") {
+		return fmt.Errorf("sdp username must not contain spaces")
+	}
+	return nil
+}
+
+const DefaultToneFailDuration = 3 * time.Second
+
+// TonesConfig selects call progress tones played by SIP. Tones are validated when the service starts.
+type TonesConfig struct {
+	// Country selects standard tones: etsi (default), us, uk, de, fr, au or jp.
+	Country string `yaml:"country"`
+	// Custom overrides tones by name: dial, ringing, busy, reorder or sit. Cadences are in the form of
+	// "freq1+freq2/ms,0/ms,...", where zero frequency is silence. For example, "480+620/500,0/500".
+	Custom map[string]string `yaml:"custom"`
+	// FailDuration is how long busy or reorder tone is played when a call fails after audio was connected.
+	// Default is 3s, negative disables.
+	FailDuration time.Duration `yaml:"fail_duration"`
+}
+
+// MetricsConfig controls optional Prometheus metric labels.
+type MetricsConfig struct {
+	// TrunkLabels enables per-trunk call metrics labeled by trunk ID and direction.
+	TrunkLabels bool `yaml:"trunk_labels"`
+	// TrunkAllowlist limits trunk IDs used as label values, to keep cardinality under control.
+	// Other trunks are aggregated under the "other" label. All trunks are labeled if empty.
+	TrunkAllowlist []string `yaml:"trunk_allowlist"`
+}
+
+// VQReportConfig enables call quality reports sent in SIP PUBLISH with vq-rtcpxr event (RFC 6035).
+type VQReportConfig struct {
+	// Collector is the address of the report collector, host[:port].
+	Collector string `yaml:"collector"`
+	// Transport for SIP PUBLISH: udp (default), tcp or tls.
+	Transport string `yaml:"transport"`
+}
+
+// CNAMConfig enables caller ID name lookups for inbound calls.
+type CNAMConfig struct {
+	// URL of the HTTP provider. "{number}" is replaced with the caller number.
+	// The provider must respond with JSON object with a "name" field, or with a plain text name.
+	URL string `yaml:"url"`
+	// Headers are added to each request, for example for authorization.
+	Headers map[string]string `yaml:"headers"`
+	// Timeout bounds each lookup to protect call setup latency. Default is 500ms.
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheTTL is how long lookup results are cached, including empty ones. Default is 24 hours.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+	// CacheSize limits the number of cached numbers. Default is 10000.
+	CacheSize int `yaml:"cache_size"`
+}
+
+func (c *CNAMConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("cnam url is required")
+	}
+	if c.Timeout < 0 || c.CacheTTL < 0 || c.CacheSize < 0 {
+		return fmt.Errorf("cnam values must not be negative")
+	}
+	return nil
+}
+
+// TTSConfig enables synthesis of announcement texts and texts spoken to calls with an HTTP service.
+type TTSConfig struct {
+	// URL of the service. It receives a POST request with JSON object with a "text" field,
+	// and optional "voice", "language" and "speed" fields. It must respond with Ogg Vorbis audio (48 kHz, mono).
+	URL string `yaml:"url"`
+	// Headers are added to each request, for example for authorization.
+	Headers map[string]string `yaml:"headers"`
+	// Timeout bounds each request. Default is 5s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (c *TTSConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("tts url is required")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("tts timeout must not be negative")
+	}
+	return nil
+}
+
+// Speech-to-text engines.
+const (
+	// STTEngineGRPC streams audio to an external plugin over gRPC, see pkg/stt/stt.proto.
+	STTEngineGRPC = "grpc"
+	// STTEngineWhisper sends each utterance to OpenAI Whisper API.
+	STTEngineWhisper = "whisper"
+	// STTEngineGCP sends each utterance to Google Cloud Speech-to-Text API.
+	STTEngineGCP = "gcp"
+)
+
+// STTConfig enables transcription of SIP audio with the stt media stage.
+type STTConfig struct {
+	// Engine is grpc, whisper or gcp.
+	Engine string `yaml:"engine"`
+	// URL is the address of the gRPC plugin (host:port), or overrides the API endpoint of other engines.
+	URL string `yaml:"url"`
+	// TLS enables TLS for the gRPC plugin connection.
+	TLS bool `yaml:"tls"`
+	// APIKey authorizes requests to the API of whisper and gcp engines.
+	APIKey string `yaml:"api_key"`
+	// Model overrides the default model of the engine.
+	Model string `yaml:"model"`
+	// Language of the speech, for example "en" for whisper or "en-US" for gcp. Detected by the engine if empty.
+	Language string `yaml:"language"`
+	// Timeout bounds each request of whisper and gcp engines. Default is 10s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (c *STTConfig) Validate() error {
+	switch c.Engine {
+	case STTEngineGRPC:
+		if c.URL == "" {
+			return fmt.Errorf("stt url is required for grpc engine")
+		}
+	case STTEngineWhisper, STTEngineGCP:
+		if c.APIKey == "" && c.URL == "" {
+			return fmt.Errorf("stt api key is required for %s engine", c.Engine)
+		}
+	default:
+		return fmt.Errorf("invalid stt engine %q", c.Engine)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("stt timeout must not be negative")
+	}
+	return nil
+}
+
+// DNCConfig enables do-not-call checks for outbound calls. All configured sources are consulted before dialing.
+type DNCConfig struct {
+	// Numbers is a static list of blocked numbers. A trailing "*" matches a prefix.
+	Numbers []string `yaml:"numbers"`
+	// BloomFilter is a path to a Bloom filter file with blocked numbers, see pkg/internal/bloom for the format.
+	BloomFilter string `yaml:"bloom_filter"`
+	// URL of the HTTP service. "{number}" is replaced with the called number.
+	// The service must respond with JSON object with a boolean "blocked" field. 404 means the number is not blocked.
+	URL string `yaml:"url"`
+	// Headers are added to each request, for example for authorization.
+	Headers map[string]string `yaml:"headers"`
+	// Timeout bounds each check. Default is 1s.
+	Timeout time.Duration `yaml:"timeout"`
+	// FailOpen allows the call if the check fails. By default, calls are rejected.
+	FailOpen bool `yaml:"fail_open"`
+}
+
+func (c *DNCConfig) Validate() error {
+	if len(c.Numbers) == 0 && c.BloomFilter == "" && c.URL == "" {
+		return fmt.Errorf("do-not-call list requires numbers, bloom_filter or url")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("do-not-call timeout must not be negative")
+	}
+	return nil
+}
+
+// ArtifactKeySize is the size of keys used for artifact encryption, in bytes (AES-256).
+const ArtifactKeySize = 32
+
+// ArtifactEncryptionConfig enables envelope encryption of call artifacts.
+// Each artifact is encrypted with a random data key, which is stored in the artifact, wrapped with a key of the project.
+type ArtifactEncryptionConfig struct {
+	// Keys wrap data keys of artifacts. The first key of a project encrypts new artifacts, others are only used
+	// to decrypt existing ones. To rotate a key, add the new key before the old one.
+	Keys []ArtifactKeyConfig `yaml:"keys"`
+}
+
+// ArtifactKeyConfig is a key which wraps data keys of artifacts.
+type ArtifactKeyConfig struct {
+	// ID is stored in artifacts, to find the key when decrypting them.
+	ID string `yaml:"id"`
+	// ProjectID limits the key to a single project. Keys without a project are used by projects without own keys.
+	ProjectID string `yaml:"project_id"`
+	// Key is a base64-encoded 256-bit key.
+	Key string `yaml:"key"`
+}
+
+// Decode returns the key.
+func (c *ArtifactKeyConfig) Decode() ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(c.Key)
+	if err != nil {
+		return nil, fmt.Errorf("artifact key %q is not valid base64: %w", c.ID, err)
+	}
+	if len(key) != ArtifactKeySize {
+		return nil, fmt.Errorf("artifact key %q must be %d bytes, got %d", c.ID, ArtifactKeySize, len(key))
+	}
+	return key, nil
+}
+
+func (c *ArtifactEncryptionConfig) Validate() error {
+	if len(c.Keys) == 0 {
+		return fmt.Errorf("artifact encryption requires keys")
+	}
+	ids := make(map[string]struct{}, len(c.Keys))
+	for i := range c.Keys {
+		k := &c.Keys[i]
+		if k.ID == "" {
+			return fmt.Errorf("artifact key id is required")
+		}
+		if _, ok := ids[k.ID]; ok {
+			return fmt.Errorf("duplicate artifact key id %q", k.ID)
+		}
+		ids[k.ID] = struct{}{}
+		if _, err := k.Decode(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RedactionConfig masks phone numbers, users of SIP URIs and other personal data.
+// Digits of phone numbers are replaced with "*", other values are masked completely.
+type RedactionConfig struct {
+	// Logs enables redaction of log fields with phone numbers and SIP URIs, and of values matching patterns.
+	Logs bool `yaml:"logs"`
+	// CallInfo enables redaction of call info reported to LiveKit, which is stored in call records and sent in webhooks.
+	// Attributes of participants in the room are not affected.
+	CallInfo bool `yaml:"call_info"`
+	// KeepDigits is the number of trailing digits of phone numbers left visible, for example 4. By default, all digits are masked.
+	KeepDigits int `yaml:"keep_digits"`
+	// Fields are names of additional log fields with phone numbers or SIP URIs.
+	Fields []string `yaml:"fields"`
+	// Headers are names of SIP headers with personal data. Attributes with their values are masked in call info.
+	Headers []string `yaml:"headers"`
+	// Patterns are regular expressions matching other personal data. Matches are masked in logged strings and errors,
+	// and in attributes in call info.
+	Patterns []string `yaml:"patterns"`
+}
+
+func (c *RedactionConfig) Validate() error {
+	if !c.Logs && !c.CallInfo {
+		return fmt.Errorf("redaction requires logs or call_info")
+	}
+	if c.KeepDigits < 0 {
+		return fmt.Errorf("redaction keep_digits must not be negative")
+	}
+	for _, h := range c.Headers {
+		if h == "" {
+			return fmt.Errorf("redaction header name is required")
+		}
+	}
+	for _, p := range c.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// DefaultLeakTimeout is the default time for a call to release its resources after it ends.
+const DefaultLeakTimeout = 30 * time.Second
+
+// LeakWatchdogConfig enables checks that calls release their resources after they end.
+// Leaks are counted in the livekit_sip_call_leaks metric and logged with the call ID.
+type LeakWatchdogConfig struct {
+	// Timeout for resources of a call to be released after it ends. Default is 30s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (c *LeakWatchdogConfig) Validate() error {
+	if c.Timeout < 0 {
+		return fmt.Errorf("leak watchdog timeout must not be negative")
+	}
+	return nil
+}
+
+// DefaultDialogTTL is the default time dialogs stay in the registry if the owner stops refreshing them.
+const DefaultDialogTTL = 5 * time.Minute
+
+// DialogRegistryConfig enables a registry of dialogs shared by instances running behind a load balancer.
+// Each instance registers SIP Call-IDs of its calls in Redis. In-dialog requests (BYE, re-INVITE, NOTIFY)
+// received by an instance which doesn't own the call are proxied to the owner.
+type DialogRegistryConfig struct {
+	// Address where other instances send requests for dialogs of this instance, host:port.
+	// Defaults to the local signaling IP and the SIP port.
+	Address string `yaml:"address"`
+	// TTL of dialogs in the registry. Dialogs of active calls are refreshed periodically. Default is 5m.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+func (c *DialogRegistryConfig) Validate() error {
+	if c.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			return fmt.Errorf("invalid dialog registry address %q: %w", c.Address, err)
+		}
+	}
+	if c.TTL < 0 {
+		return fmt.Errorf("dialog registry ttl must not be negative")
+	}
+	return nil
+}
+
+// Defaults of FailoverConfig.
+const (
+	DefaultFailoverHeartbeat = time.Second
+	DefaultFailoverTimeout   = 5 * time.Second
+)
+
+// FailoverConfig replicates minimal state of answered calls (dialog identifiers, media config and room binding)
+// to Redis for a peer instance. If an instance stops sending heartbeats, the peer takes over its calls: it joins
+// the rooms again and sends re-INVITEs, so that media flows through the peer instead of dropping the calls.
+// Both instances of a pair must set each other as the peer.
+type FailoverConfig struct {
+	// Address identifying this instance, host:port. Defaults to the local signaling IP and the SIP port.
+	Address string `yaml:"address"`
+	// Peer is the address of the instance taking over calls of this instance, and vice versa.
+	Peer string `yaml:"peer"`
+	// Heartbeat is the interval between heartbeats. Default is 1s.
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// Timeout after the last heartbeat of the peer, after which its calls are taken over. Default is 5s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (c *FailoverConfig) Validate() error {
+	if c.Peer == "" {
+		return fmt.Errorf("failover peer must be set")
+	}
+	for _, addr := range []string{c.Address, c.Peer} {
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid failover address %q: %w", addr, err)
+		}
+	}
+	if c.Address != "" && c.Address == c.Peer {
+		return fmt.Errorf("failover peer must differ from the address")
+	}
+	if c.Heartbeat < 0 || c.Timeout < 0 {
+		return fmt.Errorf("failover heartbeat and timeout must not be negative")
+	}
+	heartbeat, timeout := c.Heartbeat, c.Timeout
+	if heartbeat == 0 {
+		heartbeat = DefaultFailoverHeartbeat
+	}
+	if timeout == 0 {
+		timeout = DefaultFailoverTimeout
+	}
+	if timeout <= heartbeat {
+		return fmt.Errorf("failover timeout must be longer than the heartbeat interval")
+	}
+	return nil
+}
+
+// Defaults and limits of PortMappingConfig.
+const (
+	DefaultPortMappingLifetime = time.Hour
+	// MaxPortMappings limits the number of RTP ports mapped on the gateway. Each port is mapped separately,
+	// and consumer gateways often hold only a few dozen to a hundred mappings.
+	MaxPortMappings = 100
+)
+
+// PortMappingConfig maps SIP ports and the RTP port range on the NAT gateway with NAT-PMP or UPnP IGD, for deployments
+// behind consumer and SMB routers. Mappings are renewed before they expire and removed on shutdown.
+// The RTP port range must not exceed MaxPortMappings ports, so rtp_port must be narrowed from the default.
+type PortMappingConfig struct {
+	// Protocol is natpmp or upnp. By default, NAT-PMP is tried first, then UPnP.
+	Protocol string `yaml:"protocol"`
+	// Gateway is the IP of the NAT-PMP gateway. Defaults to the gateway of the default route.
+	Gateway string `yaml:"gateway"`
+	// Lifetime of mappings, which are renewed at half of it. Default is 1h.
+	Lifetime time.Duration `yaml:"lifetime"`
+}
+
+// Validate checks the config, together with the RTP port range which is mapped.
+func (c *PortMappingConfig) Validate(rtp rtcconfig.PortRange) error {
+	switch c.Protocol {
+	case "", "natpmp", "upnp":
+	default:
+		return fmt.Errorf("unsupported port mapping protocol %q", c.Protocol)
+	}
+	if c.Gateway != "" {
+		if _, err := netip.ParseAddr(c.Gateway); err != nil {
+			return fmt.Errorf("invalid port mapping gateway %q: %w", c.Gateway, err)
+		}
+	}
+	if c.Lifetime < 0 {
+		return fmt.Errorf("port mapping lifetime must not be negative")
+	}
+	if c.Lifetime != 0 && c.Lifetime < time.Minute {
+		return fmt.Errorf("port mapping lifetime must be at least 1m")
+	}
+	if n := rtp.End - rtp.Start + 1; n > MaxPortMappings {
+		return fmt.Errorf("port_mapping supports at most %d RTP ports, but rtp_port has %d", MaxPortMappings, n)
+	}
+	return nil
+}
+
+// DefaultPublicIPInterval is the default interval between checks of the public IP.
+const DefaultPublicIPInterval = 5 * time.Minute
+
+// PublicIPConfig discovers the public IP via STUN at startup and checks it again periodically. If the address changes,
+// SDP and Contact headers of new calls advertise the new address, and the change is logged and counted in
+// the livekit_sip_public_ip_changes metric. Calls in progress keep the previous address.
+type PublicIPConfig struct {
+	// StunServers used for discovery, host:port. Defaults to public STUN servers.
+	StunServers []string `yaml:"stun_servers"`
+	// Interval between checks. Default is 5m.
+	Interval time.Duration `yaml:"interval"`
+}
+
+func (c *PublicIPConfig) Validate() error {
+	for _, addr := range c.StunServers {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid stun server %q: %w", addr, err)
+		}
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("public ip check interval must not be negative")
+	}
+	return nil
+}
+
+// DefaultProxyProtocolTimeout is the default time to wait for the PROXY protocol header.
+const DefaultProxyProtocolTimeout = 5 * time.Second
+
+// ProxyProtocolConfig accepts PROXY protocol (v1 and v2) headers on TCP and TLS signaling listeners, sent by L4 load
+// balancers in front of the instance. Requests are attributed to the original client address, and the Contact and Via
+// addresses advertised on each connection are set to the address the client connected to, for example an anycast IP.
+type ProxyProtocolConfig struct {
+	// Trusted lists networks of load balancers, in CIDR notation. Connections from these networks must start with
+	// a PROXY header, other connections are served as is, even if they send one. Required.
+	Trusted []string `yaml:"trusted"`
+	// Timeout to receive the header after a connection is accepted. Default is 5s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (c *ProxyProtocolConfig) Validate() error {
+	if len(c.Trusted) == 0 {
+		return fmt.Errorf("proxy protocol requires trusted networks of load balancers")
+	}
+	for _, s := range c.Trusted {
+		if _, err := netip.ParsePrefix(s); err != nil {
+			return fmt.Errorf("invalid proxy protocol trusted network %q: %w", s, err)
+		}
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("proxy protocol timeout must not be negative")
+	}
+	return nil
+}
+
+// MemoryBudgetConfig limits memory used by buffers of calls: jitter buffers, mixers of concurrent RTP streams and recordings.
+// Buffers over the budget are not allocated and the call degrades instead, so that the node doesn't run out of memory:
+// the jitter buffer is disabled, additional RTP streams are ignored and recordings are paused.
+type MemoryBudgetConfig struct {
+	// Max is the memory for buffers of all calls, in bytes. Zero means no limit.
+	Max int64 `yaml:"max"`
+	// PerCall is the memory for buffers of a single call, in bytes. Zero means no limit.
+	PerCall int64 `yaml:"per_call"`
+}
+
+func (c *MemoryBudgetConfig) Validate() error {
+	if c.Max < 0 || c.PerCall < 0 {
+		return fmt.Errorf("memory budget must not be negative")
+	}
+	if c.Max == 0 && c.PerCall == 0 {
+		return fmt.Errorf("memory budget requires max or per_call")
+	}
+	return nil
+}
+
+type AdminScope string
+
+const (
+	// AdminScopeMetrics allows reading state of the node, such as active dialogs.
+	AdminScopeMetrics = AdminScope("metrics")
+	// AdminScopeCalls allows controlling calls, such as hanging up, speaking to a call or placing outbound calls.
+	AdminScopeCalls = AdminScope("calls")
+	// AdminScopeConfig allows administering the configuration, such as probing trunks or dry runs of dispatch rules.
+	AdminScopeConfig = AdminScope("config")
+	// AdminScopeMedia allows access to call audio, such as recording or listening in.
+	AdminScopeMedia = AdminScope("media")
+)
+
+var AdminScopes = []AdminScope{AdminScopeMetrics, AdminScopeCalls, AdminScopeConfig, AdminScopeMedia}
+
+// AdminAuthConfig requires authentication for the admin API.
+// Requests must set "Authorization: Bearer <token>", where the token is either the secret of a key,
+// or a JWT signed with it (HS256), with the key as the issuer. A JWT may limit scopes of the key with a "scopes" claim.
+type AdminAuthConfig struct {
+	Keys []AdminKeyConfig `yaml:"keys"`
+}
+
+// AdminKeyConfig is an API key for the admin API.
+type AdminKeyConfig struct {
+	Key    string       `yaml:"key"`
+	Secret string       `yaml:"secret"`
+	Scopes []AdminScope `yaml:"scopes"`
+}
+
+func (c *AdminAuthConfig) Validate() error {
+	if len(c.Keys) == 0 {
+		return fmt.Errorf("admin auth requires keys")
+	}
+	keys := make(map[string]struct{}, len(c.Keys))
+	for i := range c.Keys {
+		k := &c.Keys[i]
+		if k.Key == "" || k.Secret == "" {
+			return fmt.Errorf("admin key and secret are required")
+		}
+		if _, ok := keys[k.Key]; ok {
+			return fmt.Errorf("duplicate admin key %q", k.Key)
+		}
+		keys[k.Key] = struct{}{}
+		if len(k.Scopes) == 0 {
+			return fmt.Errorf("admin key %q: scopes are required", k.Key)
+		}
+		for _, s := range k.Scopes {
+			if !slices.Contains(AdminScopes, s) {
+				return fmt.Errorf("admin key %q: unknown scope %q", k.Key, s)
+			}
+		}
+	}
+	return nil
+}
+
+// ManagementConfig serves health, metrics and admin API on a single listener, separate from SIP,
+// so that SIP can be exposed publicly while control interfaces are only reachable from the management network.
+// Paths are /health, /metrics and /admin/. Pprof is served by the admin API under /admin/debug/pprof/,
+// so it requires the same authentication. Ports configured for these separately are still opened.
+type ManagementConfig struct {
+	// Address to listen on, host:port, for example an address on the management network.
+	Address string               `yaml:"address"`
+	TLS     *ManagementTLSConfig `yaml:"tls"` // optional
+}
+
+// ManagementTLSConfig enables TLS on the management listener, independent of SIP TLS.
+type ManagementTLSConfig struct {
+	Certs []TLSCert `yaml:"certs"`
+	// ClientCAFile enables mutual TLS: clients must present a certificate signed by one of the CAs in the file.
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+func (c *ManagementConfig) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		return fmt.Errorf("invalid management address %q: %w", c.Address, err)
+	}
+	if c.TLS != nil && len(c.TLS.Certs) == 0 {
+		return fmt.Errorf("management TLS requires certs")
+	}
+	return nil
+}
+
+// TestCallConfig describes a synthetic call placed periodically to monitor the audio path end-to-end.
+// The destination must send the audio back, for example a dispatch rule with an echo agent, or an echo extension of the trunk.
+type TestCallConfig struct {
+	// Name identifies the test call in logs and metrics.
+	Name string `yaml:"name"`
+	// Address of the destination, host[:port]. Default is the SIP port of this node.
+	Address string `yaml:"address"`
+	// Number to call.
+	Number string `yaml:"number"`
+	// From is the caller number. Default is "livekit-test".
+	From string `yaml:"from"`
+	// Username and Password are used if the destination requests authentication.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Headers are added to the INVITE, for example to select a dispatch rule.
+	Headers map[string]string `yaml:"headers"`
+	// Codec used for the call. Default is PCMU.
+	Codec string `yaml:"codec"`
+	// Interval between test calls. Default is 5 minutes.
+	Interval time.Duration `yaml:"interval"`
+	// Timeout bounds receiving the test tone back, after the call is answered. Default is 30s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (c *TestCallConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("test call name is required")
+	}
+	if c.Number == "" {
+		return fmt.Errorf("test call %q: number is required", c.Name)
+	}
+	if c.Interval < 0 || c.Timeout < 0 {
+		return fmt.Errorf("test call %q: interval and timeout must not be negative", c.Name)
+	}
+	return nil
+}
+
+// RecordingSyncConfig publishes identifiers and timing of SIP calls in room metadata, so that room recordings (egress)
+// can be aligned with SIP CDRs. Room metadata is expected to be a JSON object, other keys are preserved.
+type RecordingSyncConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MetadataKey is the room metadata key with call legs, keyed by participant identity. Defaults to "sip_calls".
+	MetadataKey string `yaml:"metadata_key"`
+}
+
+// MediaUnreachableConfig handles ICMP errors (port or host unreachable) reported for RTP sent to the remote.
+type MediaUnreachableConfig struct {
+	// MaxErrors is the number of ICMP errors, each less than 5s apart, after which Action is taken. Zero disables it.
+	// Errors are usually rate limited by the kernel to one per second.
+	MaxErrors int `yaml:"max_errors"`
+	// Action is taken once a call reaches MaxErrors. Defaults to MediaUnreachableReInvite.
+	Action MediaUnreachableAction `yaml:"action"`
+}
+
+// MediaUnreachableAction selects what happens with calls whose media destination is unreachable.
+type MediaUnreachableAction string
+
+const (
+	// MediaUnreachableReInvite refreshes the media session with a re-INVITE. If errors continue, the call is ended.
+	// This is the default.
+	MediaUnreachableReInvite = MediaUnreachableAction("reinvite")
+	// MediaUnreachableHangup ends the call.
+	MediaUnreachableHangup = MediaUnreachableAction("hangup")
+)
+
+func (a MediaUnreachableAction) Validate() error {
+	switch a {
+	case "", MediaUnreachableReInvite, MediaUnreachableHangup:
+		return nil
+	}
+	return fmt.Errorf("invalid media unreachable action %q", string(a))
+}
+
+// OneWayAudioConfig configures detection of calls where audio flows in only one direction after answer.
+type OneWayAudioConfig struct {
+	// Timeout is how long audio may flow in only one direction before the call is flagged. Zero disables detection.
+	Timeout time.Duration `yaml:"timeout"`
+	// Latch sends media to the address it's received from, if it differs from the address in SDP (symmetric RTP).
+	Latch bool `yaml:"latch"`
+	// ReInvite refreshes the media session with a re-INVITE.
+	ReInvite bool `yaml:"reinvite"`
+}
+
+// ProjectQuota limits resources used by a single project on this node. Zero values mean no limit.
+type ProjectQuota struct {
+	MaxConcurrentCalls int     `yaml:"max_concurrent_calls"`
+	MaxCallsPerSecond  float64 `yaml:"max_calls_per_second"`
+	// MaxMinutes limits total call duration within Period. Active calls are ended once they use it up together.
+	MaxMinutes float64 `yaml:"max_minutes"`
+	// Period for MaxMinutes. Default is 24 hours.
+	Period time.Duration `yaml:"period"`
+}
+
+func (q *ProjectQuota) Validate() error {
+	if q.MaxConcurrentCalls < 0 || q.MaxCallsPerSecond < 0 || q.MaxMinutes < 0 || q.Period < 0 {
+		return fmt.Errorf("project quota values must not be negative")
+	}
+	return nil
+}
+
 type Config struct {
 	Redis     *redis.RedisConfig `yaml:"redis"`      // required
 	ApiKey    string             `yaml:"api_key"`    // required (env LIVEKIT_API_KEY)
@@ -64,15 +1789,47 @@ type Config struct {
 	HealthPort         int                 `yaml:"health_port"`
 	PrometheusPort     int                 `yaml:"prometheus_port"`
 	PProfPort          int                 `yaml:"pprof_port"`
+	AdminPort          int                 `yaml:"admin_port"`      // if set, opens an HTTP port for admin API; localhost only without admin_auth
+	AdminAuth          *AdminAuthConfig    `yaml:"admin_auth"`      // optional; requires authentication on the admin port
+	Management         *ManagementConfig   `yaml:"management"`      // optional; separate listener for health, metrics and admin API
 	SIPPort            int                 `yaml:"sip_port"`        // announced SIP signaling port
 	SIPPortListen      int                 `yaml:"sip_port_listen"` // SIP signaling port to listen on
 	SIPHostname        string              `yaml:"sip_hostname"`
+	SIPHostnames       *SIPHostnamesConfig `yaml:"sip_hostnames"`        // optional; advertised names per transport
 	SIPRingingInterval time.Duration       `yaml:"sip_ringing_interval"` // from 1 sec up to 60 (default '1s')
 	TLS                *TLSConfig          `yaml:"tls"`
 	RTPPort            rtcconfig.PortRange `yaml:"rtp_port"`
-	Logging            logger.Config       `yaml:"logging"`
-	ClusterID          string              `yaml:"cluster_id"` // cluster this instance belongs to
-	MaxCpuUtilization  float64             `yaml:"max_cpu_utilization"`
+	// RTPPortReserved reserves sub-ranges of RTPPort for specific trunks, keyed by trunk ID.
+	// Reserved ports are not used by other trunks. Calls fall back to the shared range when the reservation is exhausted.
+	RTPPortReserved   map[string]rtcconfig.PortRange `yaml:"rtp_port_reserved"`
+	Logging           logger.Config                  `yaml:"logging"`
+	ClusterID         string                         `yaml:"cluster_id"` // cluster this instance belongs to
+	MaxCpuUtilization float64                        `yaml:"max_cpu_utilization"`
+	Metrics           MetricsConfig                  `yaml:"metrics"`
+	VQReport          *VQReportConfig                `yaml:"vq_report"`   // optional
+	CNAM              *CNAMConfig                    `yaml:"cnam"`        // optional
+	DNC               *DNCConfig                     `yaml:"do_not_call"` // optional
+	TTS               *TTSConfig                     `yaml:"tts"`         // optional
+	STT               *STTConfig                     `yaml:"stt"`         // optional
+	// RTPPortPrewarm is the number of ports from the shared RTPPort range that are bound in advance,
+	// so that new calls skip socket allocation. MediaPort and SDP are still created per call. Disabled by default.
+	RTPPortPrewarm int `yaml:"rtp_port_prewarm"`
+	// ArtifactEncryption encrypts call artifacts written to disk, such as recordings.
+	ArtifactEncryption *ArtifactEncryptionConfig `yaml:"artifact_encryption"` // optional
+	// Redaction masks personal data, such as phone numbers, in logs and call info.
+	Redaction *RedactionConfig `yaml:"redaction"` // optional
+	// LeakWatchdog checks that calls release their goroutines, sockets and timers after they end.
+	LeakWatchdog *LeakWatchdogConfig `yaml:"leak_watchdog"` // optional
+	// MemoryBudget limits memory used by buffers of calls.
+	MemoryBudget *MemoryBudgetConfig `yaml:"memory_budget"` // optional
+	// DialogRegistry shares dialogs between instances, forwarding in-dialog requests to the instance owning the call.
+	DialogRegistry *DialogRegistryConfig `yaml:"dialog_registry"` // optional
+	// Failover replicates call state to a peer instance, which takes over the calls if this instance fails.
+	Failover *FailoverConfig `yaml:"failover"` // optional
+	// ProxyProtocol reads PROXY protocol headers from load balancers on TCP and TLS signaling listeners.
+	ProxyProtocol *ProxyProtocolConfig `yaml:"proxy_protocol"` // optional
+	// TestCalls are synthetic calls placed periodically by this node, reported in livekit_sip_test_call* metrics.
+	TestCalls []TestCallConfig `yaml:"test_calls"`
 
 	UseExternalIP bool   `yaml:"use_external_ip"`
 	LocalNet      string `yaml:"local_net"` // local IP net to use, e.g. 192.168.0.0/24
@@ -83,14 +1840,55 @@ type Config struct {
 	MediaUseExternalIP bool   `yaml:"media_use_external_ip"`
 	MediaNAT1To1IP     string `yaml:"media_nat_1_to_1_ip"`
 
+	// PublicIP configures discovery of the external IP for use_external_ip and media_use_external_ip.
+	PublicIP *PublicIPConfig `yaml:"public_ip"` // optional
+	// PortMapping maps SIP and RTP ports on the NAT gateway with NAT-PMP or UPnP. It requires a narrow rtp_port range.
+	PortMapping *PortMappingConfig `yaml:"port_mapping"` // optional
+
 	MediaTimeout        time.Duration   `yaml:"media_timeout"`
 	MediaTimeoutInitial time.Duration   `yaml:"media_timeout_initial"`
 	Codecs              map[string]bool `yaml:"codecs"`
 
+	// MediaTimeoutWarnOnly makes SIP log and flag calls with media timeout instead of ending them.
+	MediaTimeoutWarnOnly bool `yaml:"media_timeout_warn_only"`
+	// MediaTimeoutIgnoreCN stops comfort noise packets from counting as media activity.
+	// By default, a remote that is on mute and sends only comfort noise is not considered timed out.
+	MediaTimeoutIgnoreCN bool `yaml:"media_timeout_ignore_cn"`
+	// MediaTimeoutKeepAlive makes keep-alive packets (empty, CRLF or STUN datagrams) count as media activity.
+	// Other non-RTP datagrams are never counted.
+	MediaTimeoutKeepAlive bool `yaml:"media_timeout_keepalive"`
+	// MediaTimeoutRTCP makes RTCP packets count as media activity, even if RTP is not received.
+	MediaTimeoutRTCP bool `yaml:"media_timeout_rtcp"`
+	// RTCPXRInterval enables RTCP-XR VoIP metrics reports (RFC 3611) sent to the remote with a given interval.
+	// Reports are only sent on unencrypted UDP media, to the RTCP port of the remote (a=rtcp, or RTP port + 1).
+	// Quality estimates (R-factor, MOS) are only reported for G.711.
+	RTCPXRInterval time.Duration `yaml:"rtcp_xr_interval"`
+	// StripZRTP silently drops ZRTP packets, without counting them as media activity.
+	// ZRTP is not supported, but attempts are always reported in the "sip.zrtp" participant attribute.
+	StripZRTP bool `yaml:"strip_zrtp"`
+	// OneWayAudio flags calls where audio flows in only one direction after answer, and optionally tries to recover them.
+	OneWayAudio OneWayAudioConfig `yaml:"one_way_audio"`
+	// MediaUnreachable recovers or ends calls when RTP sent to the remote is rejected with ICMP errors,
+	// instead of waiting for media timeout.
+	MediaUnreachable MediaUnreachableConfig `yaml:"media_unreachable"`
+
+	// SIPKeepAliveInterval is the interval of CRLF keep-alives on TCP and TLS connections of active calls.
+	// Broken connections are detected and re-established before the next in-dialog request. Default is 30s, negative disables.
+	SIPKeepAliveInterval time.Duration `yaml:"sip_keepalive_interval"`
+	// SIPTransportFallbackTimeout is how long an outbound INVITE waits for any response before it's retried
+	// over the next transport, if the trunk doesn't specify one. Default is 5s, negative only falls back if the connection fails.
+	SIPTransportFallbackTimeout time.Duration `yaml:"sip_transport_fallback_timeout"`
+	// SIPLimits caps message size and headers of inbound requests, protecting the public port from malformed traffic.
+	SIPLimits SIPLimitsConfig `yaml:"sip_limits"`
+	// SIPTimers tunes transaction timers of requests sent by SIP. Can be overridden per trunk.
+	SIPTimers SIPTimersConfig `yaml:"sip_timers"`
+
 	// HideInboundPort controls how SIP endpoint responds to unverified inbound requests.
 	// Setting it to true makes SIP server silently drop INVITE requests if it gets a negative Auth or Dispatch response.
 	// Doing so hides our SIP endpoint from (a low effort) port scanners.
 	HideInboundPort bool `yaml:"hide_inbound_port"`
+	// OptionsAuth requires inbound OPTIONS requests to pass the same trunk authentication as INVITE.
+	OptionsAuth bool `yaml:"options_auth"`
 	// AddRecordRoute forces SIP to add Record-Route headers to the responses.
 	AddRecordRoute bool `yaml:"add_record_route"`
 
@@ -98,6 +1896,68 @@ type Config struct {
 	AudioDTMF              bool    `yaml:"audio_dtmf"`
 	EnableJitterBuffer     bool    `yaml:"enable_jitter_buffer"`
 	EnableJitterBufferProb float64 `yaml:"enable_jitter_buffer_prob"`
+	// DTMF controls pacing of DTMF digits sent to SIP.
+	DTMF DTMFConfig `yaml:"dtmf"`
+	// Tones selects call progress tones, like ringback or busy tone.
+	Tones TonesConfig `yaml:"tones"`
+	// EnableMSRP accepts MSRP (RFC 4975) chat sessions offered by inbound calls over TCP,
+	// and relays text messages to the room as chat messages.
+	EnableMSRP bool `yaml:"enable_msrp"`
+	// EnableRTT accepts real-time text (RFC 4103, T.140) offered by inbound calls. Text from SIP is published
+	// as a transcription of the SIP participant, and chat messages from the room are sent back as text.
+	EnableRTT bool `yaml:"enable_rtt"`
+	// EnableDriftCorrection compensates clock drift of the remote on long calls by inserting or dropping samples
+	// of audio sent to the room. Drift is always estimated and reported in call stats.
+	EnableDriftCorrection bool `yaml:"enable_drift_correction"`
+	// MaxInputStreams limits the number of concurrent RTP streams from the remote that are mixed together.
+	// Defaults to 4. Setting it to 1 decodes all streams as one, which was the behavior before mixing was added.
+	// Each call accepts at most 4 times as many streams in total (but at least 16), packets of other streams are dropped.
+	MaxInputStreams int `yaml:"max_input_streams"`
+	// MediaStreamRate limits the number of new RTP streams accepted from the remote on each call per second,
+	// after a burst of 4 streams. Defaults to 1.
+	MediaStreamRate float64 `yaml:"media_stream_rate"`
+	// EnableMediaImpairment allows injecting packet loss, jitter, reordering and duplication into RTP of active calls
+	// via the admin API. It's meant for testing jitter buffers and quality metrics, and must not be used in production.
+	EnableMediaImpairment bool `yaml:"enable_media_impairment"`
+	// Pacing smooths bursts of audio sent to SIP and caps its bitrate. Can be overridden per trunk.
+	Pacing PacingConfig `yaml:"pacing"`
+	// Silence fills gaps in audio sent to SIP with silence or comfort noise. Can be overridden per trunk.
+	Silence SilenceConfig `yaml:"silence"`
+	// Dialer controls the batch dialer of scheduled outbound calls.
+	Dialer DialerConfig `yaml:"dialer"`
+	// Capacity limits concurrent calls on each trunk. Can be overridden per trunk.
+	Capacity CapacityConfig `yaml:"capacity"`
+	// Emergency detects outbound calls to emergency numbers. Can be overridden per trunk.
+	Emergency EmergencyConfig `yaml:"emergency"`
+	// Capabilities overrides Allow, Supported and Accept headers advertised in requests and responses. Can be overridden per trunk.
+	Capabilities CapabilitiesConfig `yaml:"capabilities"`
+	// RecordingSync publishes SIP call identifiers and call leg timing in room metadata. Disabled by default.
+	RecordingSync RecordingSyncConfig `yaml:"recording_sync"`
+
+	SRTP SRTPConfig `yaml:"srtp"`
+	// MediaEncryption sets media encryption policy for all trunks. Can be overridden per trunk.
+	MediaEncryption EncryptionPolicy `yaml:"media_encryption"`
+	// RTPTransport selects RTP transport for all trunks: udp (default), tcp or auto. Can be overridden per trunk.
+	RTPTransport MediaTransport `yaml:"rtp_transport"`
+	// SDP sets session name and origin username of SDP and lenient parsing for all trunks. Can be overridden per trunk.
+	SDP SDPConfig `yaml:"sdp"`
+	// Trunks contains per-trunk overrides, keyed by trunk ID.
+	Trunks map[string]*TrunkConfig `yaml:"trunks"`
+	// UnmatchedCall controls the response to inbound calls that don't match any dispatch rule.
+	UnmatchedCall UnmatchedCallConfig `yaml:"unmatched_call"`
+	// DialPlan translates phone numbers for all trunks. Can be overridden per trunk.
+	DialPlan DialPlanConfig `yaml:"dial_plan"`
+	// CallerID selects the caller ID of outbound calls for all trunks. Can be overridden per trunk.
+	CallerID CallerIDConfig `yaml:"caller_id"`
+	// Provisional controls responses sent to inbound calls before the room answers. Default is ringing.
+	Provisional ProvisionalMode `yaml:"provisional"`
+	// DispatchRules contains per-rule overrides, keyed by dispatch rule ID.
+	DispatchRules map[string]*DispatchRuleConfig `yaml:"dispatch_rules"`
+
+	// ProjectQuota sets default resource limits for each project. Can be overridden per project.
+	ProjectQuota ProjectQuota `yaml:"project_quota"`
+	// ProjectQuotas contains per-project limits, keyed by project ID. Only these projects are labeled in metrics.
+	ProjectQuotas map[string]*ProjectQuota `yaml:"project_quotas"`
 
 	// internal
 	ServiceName string `yaml:"-"`
@@ -163,9 +2023,574 @@ func (c *Config) Init() error {
 		return fmt.Errorf("media_use_external_ip and media_nat_1_to_1_ip can not both be set")
 	}
 
+	if c.PortMapping != nil {
+		if err := c.PortMapping.Validate(c.RTPPort); err != nil {
+			return err
+		}
+	}
+	if c.PublicIP != nil {
+		if !c.UseExternalIP && !c.MediaUseExternalIP {
+			return fmt.Errorf("public_ip requires use_external_ip or media_use_external_ip")
+		}
+		if err := c.PublicIP.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.MediaEncryption.Validate(); err != nil {
+		return err
+	}
+	if err := c.RTPTransport.Validate(); err != nil {
+		return err
+	}
+	if err := c.SRTP.FailureAction.Validate(); err != nil {
+		return err
+	}
+	if err := c.MediaUnreachable.Action.Validate(); err != nil {
+		return err
+	}
+	for id, t := range c.Trunks {
+		if t == nil {
+			continue
+		}
+		if err := t.RTPTransport.Validate(); err != nil {
+			return fmt.Errorf("trunk %q: %w", id, err)
+		}
+		if t.SRTP != nil {
+			if err := t.SRTP.FailureAction.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if err := t.Encryption.Validate(); err != nil {
+			return fmt.Errorf("trunk %q: %w", id, err)
+		}
+		if t.UnmatchedCall != nil {
+			if err := t.UnmatchedCall.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if t.DialPlan != nil {
+			if err := t.DialPlan.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if t.CallerID != nil {
+			if err := t.CallerID.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if t.Pacing != nil {
+			if err := t.Pacing.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if t.Silence != nil {
+			if err := t.Silence.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if t.SDP != nil {
+			if err := t.SDP.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if t.AttributeUpdates != nil {
+			if err := t.AttributeUpdates.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if t.MaxCPS < 0 {
+			return fmt.Errorf("trunk %q: max cps must not be negative", id)
+		}
+		if t.Capacity != nil {
+			if err := t.Capacity.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if t.Emergency != nil {
+			if err := t.Emergency.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if t.Capabilities != nil {
+			if err := t.Capabilities.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if t.SIPTimers != nil {
+			if err := t.SIPTimers.Validate(); err != nil {
+				return fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+		if t.ResourcePriority != "" {
+			for _, v := range strings.Split(t.ResourcePriority, ",") {
+				if err := validateResourcePriority(strings.TrimSpace(v), true); err != nil {
+					return fmt.Errorf("trunk %q: %w", id, err)
+				}
+			}
+		}
+	}
+	if err := c.UnmatchedCall.Validate(); err != nil {
+		return err
+	}
+	if err := c.DialPlan.Validate(); err != nil {
+		return err
+	}
+	if err := c.CallerID.Validate(); err != nil {
+		return err
+	}
+	if err := c.Pacing.Validate(); err != nil {
+		return err
+	}
+	if err := c.Silence.Validate(); err != nil {
+		return err
+	}
+	if err := c.Dialer.Validate(); err != nil {
+		return err
+	}
+	if err := c.Capacity.Validate(); err != nil {
+		return err
+	}
+	if err := c.Emergency.Validate(); err != nil {
+		return err
+	}
+	if err := c.Capabilities.Validate(); err != nil {
+		return err
+	}
+	if err := c.SIPTimers.Validate(); err != nil {
+		return err
+	}
+	if err := c.SDP.Validate(); err != nil {
+		return err
+	}
+	if c.CNAM != nil {
+		if err := c.CNAM.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.DNC != nil {
+		if err := c.DNC.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.STT != nil {
+		if err := c.STT.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.TTS != nil {
+		if err := c.TTS.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.ArtifactEncryption != nil {
+		if err := c.ArtifactEncryption.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Redaction != nil {
+		if err := c.Redaction.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.LeakWatchdog != nil {
+		if err := c.LeakWatchdog.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.MemoryBudget != nil {
+		if err := c.MemoryBudget.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.DialogRegistry != nil {
+		if err := c.DialogRegistry.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Failover != nil {
+		if err := c.Failover.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.ProxyProtocol != nil {
+		if err := c.ProxyProtocol.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.SIPHostnames != nil {
+		if err := c.SIPHostnames.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.AdminAuth != nil {
+		if err := c.AdminAuth.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Management != nil {
+		if err := c.Management.Validate(); err != nil {
+			return err
+		}
+	}
+	names := make(map[string]struct{}, len(c.TestCalls))
+	for i := range c.TestCalls {
+		t := &c.TestCalls[i]
+		if err := t.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[t.Name]; ok {
+			return fmt.Errorf("duplicate test call %q", t.Name)
+		}
+		names[t.Name] = struct{}{}
+	}
+	if err := c.Provisional.Validate(); err != nil {
+		return err
+	}
+	if err := c.DTMF.Validate(); err != nil {
+		return err
+	}
+	for id, r := range c.DispatchRules {
+		if r == nil {
+			continue
+		}
+		if err := r.Provisional.Validate(); err != nil {
+			return fmt.Errorf("dispatch rule %q: %w", id, err)
+		}
+		if r.NoSpeech != nil {
+			if err := r.NoSpeech.Validate(); err != nil {
+				return fmt.Errorf("dispatch rule %q: %w", id, err)
+			}
+		}
+		if r.Echo != nil {
+			if err := r.Echo.Validate(); err != nil {
+				return fmt.Errorf("dispatch rule %q: %w", id, err)
+			}
+		}
+		if r.Announce != nil {
+			if err := r.Announce.Validate(); err != nil {
+				return fmt.Errorf("dispatch rule %q: %w", id, err)
+			}
+			if r.Announce.Text != "" && c.TTS == nil {
+				return fmt.Errorf("dispatch rule %q: announcement text requires tts", id)
+			}
+			if r.Echo != nil {
+				return fmt.Errorf("dispatch rule %q: echo and announce can not both be set", id)
+			}
+		}
+		if r.Busy != nil {
+			if err := r.Busy.Validate(); err != nil {
+				return fmt.Errorf("dispatch rule %q: %w", id, err)
+			}
+			if r.Busy.PositionText != "" && c.TTS == nil {
+				return fmt.Errorf("dispatch rule %q: busy queue position text requires tts", id)
+			}
+			if r.Busy.Action == BusyQueue && (r.Echo != nil || r.Announce != nil) {
+				return fmt.Errorf("dispatch rule %q: busy queue can not be used with echo or announce", id)
+			}
+		}
+		if r.Queue != nil {
+			if err := r.Queue.Validate(); err != nil {
+				return fmt.Errorf("dispatch rule %q: %w", id, err)
+			}
+			if (r.Queue.PositionText != "" || r.Queue.ETAText != "") && c.TTS == nil {
+				return fmt.Errorf("dispatch rule %q: call queue announcements require tts", id)
+			}
+			if cb := r.Queue.Callback; cb != nil && (cb.OfferText != "" || cb.ConfirmText != "") && c.TTS == nil {
+				return fmt.Errorf("dispatch rule %q: callback announcements require tts", id)
+			}
+			if r.Echo != nil || r.Announce != nil {
+				return fmt.Errorf("dispatch rule %q: call queue can not be used with echo or announce", id)
+			}
+		}
+		for i := range r.MediaStages {
+			if err := r.MediaStages[i].Validate(); err != nil {
+				return fmt.Errorf("dispatch rule %q: %w", id, err)
+			}
+			if r.MediaStages[i].Name == MediaStageSTT && c.STT == nil {
+				return fmt.Errorf("dispatch rule %q: stt media stage requires stt", id)
+			}
+		}
+		if r.AttributeUpdates != nil {
+			if err := r.AttributeUpdates.Validate(); err != nil {
+				return fmt.Errorf("dispatch rule %q: %w", id, err)
+			}
+		}
+		for _, v := range r.ResourcePriority {
+			if err := validateResourcePriority(v, false); err != nil {
+				return fmt.Errorf("dispatch rule %q: %w", id, err)
+			}
+		}
+	}
+	if err := c.ProjectQuota.Validate(); err != nil {
+		return err
+	}
+	for id, q := range c.ProjectQuotas {
+		if q == nil {
+			continue
+		}
+		if err := q.Validate(); err != nil {
+			return fmt.Errorf("project %q: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// QuotaForProject returns resource limits for a given project.
+func (c *Config) QuotaForProject(projectID string) ProjectQuota {
+	if q := c.ProjectQuotas[projectID]; q != nil {
+		return *q
+	}
+	return c.ProjectQuota
+}
+
+// TrunkEncryption returns media encryption policy for a given trunk.
+func (c *Config) TrunkEncryption(trunkID string) EncryptionPolicy {
+	if t := c.Trunks[trunkID]; t != nil && t.Encryption != EncryptionPolicyDefault {
+		return t.Encryption
+	}
+	return c.MediaEncryption
+}
+
+// DispatchProvisional returns provisional response mode for a given dispatch rule.
+func (c *Config) DispatchProvisional(ruleID string) ProvisionalMode {
+	if r := c.DispatchRules[ruleID]; r != nil && r.Provisional != ProvisionalDefault {
+		return r.Provisional
+	}
+	if c.Provisional != ProvisionalDefault {
+		return c.Provisional
+	}
+	return ProvisionalRinging
+}
+
+// DispatchNoSpeech returns no speech detection settings for a given dispatch rule, or nil if it's disabled.
+func (c *Config) DispatchNoSpeech(ruleID string) *NoSpeechConfig {
+	if r := c.DispatchRules[ruleID]; r != nil {
+		return r.NoSpeech
+	}
+	return nil
+}
+
+// DispatchEcho returns echo test settings for a given dispatch rule, or nil if it's not an echo test.
+func (c *Config) DispatchEcho(ruleID string) *EchoConfig {
+	if r := c.DispatchRules[ruleID]; r != nil {
+		return r.Echo
+	}
+	return nil
+}
+
+// DispatchAnnounce returns announcement settings for a given dispatch rule, or nil if it's not an announcement.
+func (c *Config) DispatchAnnounce(ruleID string) *AnnounceConfig {
+	if r := c.DispatchRules[ruleID]; r != nil {
+		return r.Announce
+	}
+	return nil
+}
+
+// DispatchMediaStages returns media pipeline stages for a given dispatch rule.
+func (c *Config) DispatchMediaStages(ruleID string) []MediaStageConfig {
+	if r := c.DispatchRules[ruleID]; r != nil {
+		return r.MediaStages
+	}
+	return nil
+}
+
+// DispatchPriority reports if calls matching a given dispatch rule are priority calls.
+func (c *Config) DispatchPriority(ruleID string) bool {
+	if r := c.DispatchRules[ruleID]; r != nil {
+		return r.Priority
+	}
+	return false
+}
+
+// DispatchResourcePriority returns Resource-Priority namespaces or values required by a given dispatch rule.
+func (c *Config) DispatchResourcePriority(ruleID string) []string {
+	if r := c.DispatchRules[ruleID]; r != nil {
+		return r.ResourcePriority
+	}
+	return nil
+}
+
+// DispatchAttributeUpdates returns settings of mid-call attribute updates for a given dispatch rule, or nil if they are disabled.
+func (c *Config) DispatchAttributeUpdates(ruleID string) *AttributeUpdatesConfig {
+	if r := c.DispatchRules[ruleID]; r != nil {
+		return r.AttributeUpdates
+	}
+	return nil
+}
+
+// DispatchBusy returns the busy handling config of a given dispatch rule, or nil if it's not set.
+func (c *Config) DispatchBusy(ruleID string) *BusyConfig {
+	if r := c.DispatchRules[ruleID]; r != nil {
+		return r.Busy
+	}
+	return nil
+}
+
+// DispatchQueue returns the call queue config of a given dispatch rule, or nil if it's not set.
+// The queue name defaults to the rule ID.
+func (c *Config) DispatchQueue(ruleID string) *CallQueueConfig {
+	r := c.DispatchRules[ruleID]
+	if r == nil || r.Queue == nil {
+		return nil
+	}
+	q := *r.Queue
+	if q.Name == "" {
+		q.Name = ruleID
+	}
+	return &q
+}
+
+// TrunkUnmatchedCall returns the response config for unmatched calls on a given trunk.
+func (c *Config) TrunkUnmatchedCall(trunkID string) UnmatchedCallConfig {
+	if t := c.Trunks[trunkID]; t != nil && t.UnmatchedCall != nil {
+		return *t.UnmatchedCall
+	}
+	return c.UnmatchedCall
+}
+
+// TrunkDialPlan returns number translation settings for a given trunk.
+func (c *Config) TrunkDialPlan(trunkID string) DialPlanConfig {
+	if t := c.Trunks[trunkID]; t != nil && t.DialPlan != nil {
+		return *t.DialPlan
+	}
+	return c.DialPlan
+}
+
+// TrunkCallerID returns caller ID settings for a given trunk.
+func (c *Config) TrunkCallerID(trunkID string) CallerIDConfig {
+	if t := c.Trunks[trunkID]; t != nil && t.CallerID != nil {
+		return *t.CallerID
+	}
+	return c.CallerID
+}
+
+// TrunkRTPTransport returns RTP transport for a given trunk.
+func (c *Config) TrunkRTPTransport(trunkID string) MediaTransport {
+	if t := c.Trunks[trunkID]; t != nil && t.RTPTransport != "" {
+		return t.RTPTransport
+	}
+	if c.RTPTransport != "" {
+		return c.RTPTransport
+	}
+	return MediaTransportUDP
+}
+
+// TrunkPacing returns RTP pacing settings for a given trunk.
+func (c *Config) TrunkPacing(trunkID string) PacingConfig {
+	if t := c.Trunks[trunkID]; t != nil && t.Pacing != nil {
+		return *t.Pacing
+	}
+	return c.Pacing
+}
+
+// TrunkAttributeUpdates returns settings of mid-call attribute updates for a given trunk, or nil if they are disabled.
+func (c *Config) TrunkAttributeUpdates(trunkID string) *AttributeUpdatesConfig {
+	if t := c.Trunks[trunkID]; t != nil {
+		return t.AttributeUpdates
+	}
 	return nil
 }
 
+// TrunkMaxCPS returns the limit of calls per second started by the dialer on a given trunk. Zero means no limit.
+func (c *Config) TrunkMaxCPS(trunkID string) float64 {
+	if t := c.Trunks[trunkID]; t != nil && t.MaxCPS > 0 {
+		return t.MaxCPS
+	}
+	return c.Dialer.MaxCPS
+}
+
+// TrunkCapacity returns concurrent call limits for a given trunk.
+func (c *Config) TrunkCapacity(trunkID string) CapacityConfig {
+	if t := c.Trunks[trunkID]; t != nil && t.Capacity != nil {
+		return *t.Capacity
+	}
+	return c.Capacity
+}
+
+// TrunkEmergency returns emergency call settings for a given trunk.
+func (c *Config) TrunkEmergency(trunkID string) EmergencyConfig {
+	if t := c.Trunks[trunkID]; t != nil && t.Emergency != nil {
+		return *t.Emergency
+	}
+	return c.Emergency
+}
+
+// TrunkResourcePriority returns the default Resource-Priority header value for outbound calls on a given trunk.
+func (c *Config) TrunkResourcePriority(trunkID string) string {
+	if t := c.Trunks[trunkID]; t != nil {
+		return t.ResourcePriority
+	}
+	return ""
+}
+
+// TrunkPickupGroup returns the call pickup group of a given trunk, or an empty string if pickup is disabled.
+func (c *Config) TrunkPickupGroup(trunkID string) string {
+	if t := c.Trunks[trunkID]; t != nil {
+		return t.PickupGroup
+	}
+	return ""
+}
+
+// TrunkCapabilities returns SIP capabilities advertised on a given trunk. Lists not set for the trunk are taken from the global config.
+func (c *Config) TrunkCapabilities(trunkID string) CapabilitiesConfig {
+	caps := c.Capabilities
+	if t := c.Trunks[trunkID]; t != nil && t.Capabilities != nil {
+		if t.Capabilities.Allow != nil {
+			caps.Allow = t.Capabilities.Allow
+		}
+		if t.Capabilities.Supported != nil {
+			caps.Supported = t.Capabilities.Supported
+		}
+		if t.Capabilities.Accept != nil {
+			caps.Accept = t.Capabilities.Accept
+		}
+	}
+	return caps
+}
+
+// TrunkSIPTimers returns transaction timers of requests sent on a given trunk. Timers not set for the trunk are taken from the global config.
+func (c *Config) TrunkSIPTimers(trunkID string) SIPTimersConfig {
+	timers := c.SIPTimers
+	if t := c.Trunks[trunkID]; t != nil && t.SIPTimers != nil {
+		if t.SIPTimers.T1 != 0 {
+			timers.T1 = t.SIPTimers.T1
+		}
+		if t.SIPTimers.TimerB != 0 {
+			timers.TimerB = t.SIPTimers.TimerB
+		}
+		if t.SIPTimers.TimerF != 0 {
+			timers.TimerF = t.SIPTimers.TimerF
+		}
+	}
+	return timers
+}
+
+// TrunkSilence returns silence fill settings for a given trunk.
+func (c *Config) TrunkSilence(trunkID string) SilenceConfig {
+	if t := c.Trunks[trunkID]; t != nil && t.Silence != nil {
+		return *t.Silence
+	}
+	return c.Silence
+}
+
+// TrunkSDP returns SDP session settings for a given trunk.
+func (c *Config) TrunkSDP(trunkID string) SDPConfig {
+	if t := c.Trunks[trunkID]; t != nil && t.SDP != nil {
+		return *t.SDP
+	}
+	return c.SDP
+}
+
+// TrunkSRTP returns SRTP settings for a given trunk.
+func (c *Config) TrunkSRTP(trunkID string) SRTPConfig {
+	if t := c.Trunks[trunkID]; t != nil && t.SRTP != nil {
+		return *t.SRTP
+	}
+	return c.SRTP
+}
+
 func (c *Config) InitLogger(values ...interface{}) error {
 	zl, err := logger.NewZapLogger(&c.Logging)
 	if err != nil {
@@ -21,6 +21,23 @@ import (
 var (
 	ErrNoConfig    = psrpc.NewErrorf(psrpc.InvalidArgument, "missing config")
 	ErrUnavailable = psrpc.NewErrorf(psrpc.Unavailable, "cpu exhausted")
+
+	ErrMediaPortsExhausted      = psrpc.NewErrorf(psrpc.Unavailable, "media ports exhausted")
+	ErrMediaEncryptionForbidden = psrpc.NewErrorf(psrpc.FailedPrecondition, "encrypted media is not allowed")
+	ErrMediaTransportForbidden  = psrpc.NewErrorf(psrpc.FailedPrecondition, "media transport is not allowed")
+
+	ErrProjectCallLimit        = psrpc.NewErrorf(psrpc.ResourceExhausted, "project concurrent call limit reached")
+	ErrProjectRateLimit        = psrpc.NewErrorf(psrpc.ResourceExhausted, "project call rate limit reached")
+	ErrProjectMinutesExhausted = psrpc.NewErrorf(psrpc.ResourceExhausted, "project call minutes exhausted")
+
+	ErrTrunkCallLimit = psrpc.NewErrorf(psrpc.ResourceExhausted, "trunk concurrent call limit reached")
+	ErrTrunkQueueFull = psrpc.NewErrorf(psrpc.ResourceExhausted, "trunk call queue is full")
+	ErrCallQueueFull  = psrpc.NewErrorf(psrpc.ResourceExhausted, "call queue is full")
+
+	ErrCallLoop = psrpc.NewErrorf(psrpc.FailedPrecondition, "call looped back to this node")
+
+	ErrDestinationBlocked = psrpc.NewErrorf(psrpc.PermissionDenied, "destination is on the do-not-call list")
+	ErrDNCCheckFailed     = psrpc.NewErrorf(psrpc.Unavailable, "do-not-call check failed")
 )
 
 func ErrCouldNotParseConfig(err error) psrpc.Error {
@@ -0,0 +1,159 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bloom implements a simple Bloom filter with a stable binary encoding.
+//
+// Encoded filter layout (big endian):
+//
+//	magic  [4]byte  "LKBF"
+//	k      uint32   number of hash functions
+//	m      uint64   number of bits
+//	bits   [(m+63)/64]uint64
+//
+// Bit positions are derived from a 64-bit FNV-1a hash of the value, using double hashing:
+// pos(i) = (h1 + i*h2) mod m, where h1 and h2 are the lower and upper 32 bits of the hash.
+package bloom
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	magic = "LKBF"
+	// maxBits limits the size of the filter loaded from a file (512 MB).
+	maxBits = 1 << 32
+)
+
+var ErrInvalidFormat = errors.New("invalid bloom filter format")
+
+// Filter is a Bloom filter. It is not safe for concurrent modification, but concurrent lookups are safe.
+type Filter struct {
+	k    uint32
+	m    uint64
+	bits []uint64
+}
+
+// New creates a filter sized for n values with a given false positive rate.
+func New(n int, fpRate float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+	m := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(n) * math.Ln2)
+	return NewWithSize(uint64(m), uint32(max(k, 1)))
+}
+
+// NewWithSize creates a filter with m bits and k hash functions.
+func NewWithSize(m uint64, k uint32) *Filter {
+	m = max(m, 64)
+	k = max(k, 1)
+	return &Filter{k: k, m: m, bits: make([]uint64, (m+63)/64)}
+}
+
+func (f *Filter) hash(v string) (uint64, uint64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(v))
+	s := h.Sum64()
+	return s & 0xffffffff, s >> 32
+}
+
+// Add a value to the filter.
+func (f *Filter) Add(v string) {
+	h1, h2 := f.hash(v)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		pos := (h1 + i*h2) % f.m
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// Contains checks if the value may be in the filter. False positives are possible, false negatives are not.
+func (f *Filter) Contains(v string) bool {
+	h1, h2 := f.hash(v)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		pos := (h1 + i*h2) % f.m
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTo encodes the filter to w.
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var hdr [16]byte
+	copy(hdr[:4], magic)
+	binary.BigEndian.PutUint32(hdr[4:8], f.k)
+	binary.BigEndian.PutUint64(hdr[8:16], f.m)
+	if _, err := bw.Write(hdr[:]); err != nil {
+		return 0, err
+	}
+	var buf [8]byte
+	for _, b := range f.bits {
+		binary.BigEndian.PutUint64(buf[:], b)
+		if _, err := bw.Write(buf[:]); err != nil {
+			return 0, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	return int64(len(hdr) + 8*len(f.bits)), nil
+}
+
+// Read decodes the filter from r.
+func Read(r io.Reader) (*Filter, error) {
+	br := bufio.NewReader(r)
+	var hdr [16]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	if string(hdr[:4]) != magic {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidFormat)
+	}
+	k := binary.BigEndian.Uint32(hdr[4:8])
+	m := binary.BigEndian.Uint64(hdr[8:16])
+	if k == 0 || m == 0 || m > maxBits {
+		return nil, fmt.Errorf("%w: bad size", ErrInvalidFormat)
+	}
+	f := &Filter{k: k, m: m, bits: make([]uint64, (m+63)/64)}
+	var buf [8]byte
+	for i := range f.bits {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+		}
+		f.bits[i] = binary.BigEndian.Uint64(buf[:])
+	}
+	return f, nil
+}
+
+// Load reads the filter from a file.
+func Load(path string) (*Filter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
@@ -0,0 +1,39 @@
+package bloom
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	const n = 1000
+	f := New(n, 0.01)
+	for i := 0; i < n; i++ {
+		f.Add(fmt.Sprintf("+1555%07d", i))
+	}
+	for i := 0; i < n; i++ {
+		require.True(t, f.Contains(fmt.Sprintf("+1555%07d", i)))
+	}
+	fp := 0
+	for i := 0; i < n; i++ {
+		if f.Contains(fmt.Sprintf("+1666%07d", i)) {
+			fp++
+		}
+	}
+	require.Less(t, fp, n/20)
+
+	var buf bytes.Buffer
+	_, err := f.WriteTo(&buf)
+	require.NoError(t, err)
+	f2, err := Read(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, f, f2)
+
+	_, err = Read(bytes.NewReader([]byte("LKBX")))
+	require.ErrorIs(t, err, ErrInvalidFormat)
+	_, err = Read(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	require.ErrorIs(t, err, ErrInvalidFormat)
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drift
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	msdk "github.com/livekit/media-sdk"
+)
+
+const (
+	// MinCorrection is the smallest drift that is corrected. Smaller values are within the estimation error.
+	MinCorrection = 20 // ppm
+	// MaxCorrection is the largest drift that is corrected. Larger values indicate a broken sender, not a clock skew.
+	MaxCorrection = 1000 // ppm
+)
+
+// DriftFunc returns current drift in ppm and a flag indicating if the estimate is available.
+type DriftFunc func() (float64, bool)
+
+// NewCorrector returns a writer that compensates clock drift by dropping or inserting single samples.
+// Frame size is preserved, so the output can be passed to encoders that expect fixed frames.
+// Number of inserted (positive) or dropped (negative) samples is added to adjusted, if it's set.
+func NewCorrector(w msdk.PCM16Writer, drift DriftFunc, adjusted *atomic.Int64) msdk.PCM16Writer {
+	return &corrector{w: w, drift: drift, adjusted: adjusted}
+}
+
+type corrector struct {
+	w        msdk.PCM16Writer
+	drift    DriftFunc
+	adjusted *atomic.Int64
+
+	frame int
+	acc   float64 // accumulated drift, in samples
+	buf   msdk.PCM16Sample
+}
+
+func (c *corrector) String() string {
+	return fmt.Sprintf("Drift -> %s", c.w)
+}
+
+func (c *corrector) SampleRate() int {
+	return c.w.SampleRate()
+}
+
+func (c *corrector) Close() error {
+	return c.w.Close()
+}
+
+func (c *corrector) correction(n int) int {
+	ppm, ok := c.drift()
+	if !ok || math.Abs(ppm) < MinCorrection || math.Abs(ppm) > MaxCorrection {
+		return 0
+	}
+	c.acc += float64(n) * ppm / 1e6
+	adj := int(c.acc) // truncate towards zero
+	c.acc -= float64(adj)
+	return -adj
+}
+
+func (c *corrector) WriteSample(sample msdk.PCM16Sample) error {
+	n := len(sample)
+	if n < 2 {
+		return c.w.WriteSample(sample)
+	}
+	if n != c.frame {
+		// Frame size changed, flush the remainder as is.
+		if len(c.buf) != 0 {
+			if err := c.w.WriteSample(c.buf); err != nil {
+				return err
+			}
+		}
+		c.frame = n
+		c.buf = nil
+	}
+	adj := c.correction(n)
+	if adj == 0 && len(c.buf) == 0 {
+		return c.w.WriteSample(sample)
+	}
+	c.buf = append(c.buf, sample...)
+	c.buf = adjust(c.buf, len(c.buf)-n, adj)
+	if adj != 0 && c.adjusted != nil {
+		c.adjusted.Add(int64(adj))
+	}
+	for len(c.buf) >= n {
+		frame := make(msdk.PCM16Sample, n)
+		copy(frame, c.buf[:n])
+		c.buf = c.buf[n:]
+		if err := c.w.WriteSample(frame); err != nil {
+			return err
+		}
+	}
+	if len(c.buf) == 0 {
+		c.buf = nil
+	} else {
+		c.buf = append(msdk.PCM16Sample(nil), c.buf...)
+	}
+	return nil
+}
+
+// adjust inserts (positive adj) or drops (negative adj) samples in buf, starting from the offset.
+// Adjustments are spread evenly and use interpolation to avoid audible clicks.
+func adjust(buf msdk.PCM16Sample, off int, adj int) msdk.PCM16Sample {
+	if adj == 0 {
+		return buf
+	}
+	n := len(buf) - off
+	cnt := adj
+	if cnt < 0 {
+		cnt = -cnt
+	}
+	step := n / (cnt + 1)
+	if step < 1 {
+		return buf
+	}
+	for i := cnt; i >= 1; i-- {
+		pos := off + i*step
+		if adj > 0 {
+			// Insert an interpolated sample before pos.
+			v := int16((int32(buf[pos-1]) + int32(buf[pos])) / 2)
+			buf = append(buf, 0)
+			copy(buf[pos+1:], buf[pos:])
+			buf[pos] = v
+		} else {
+			// Replace two samples with their average.
+			buf[pos-1] = int16((int32(buf[pos-1]) + int32(buf[pos])) / 2)
+			buf = append(buf[:pos], buf[pos+1:]...)
+		}
+	}
+	return buf
+}
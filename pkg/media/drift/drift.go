@@ -0,0 +1,146 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drift estimates and corrects clock drift between the remote RTP sender and the local clock.
+package drift
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/livekit/media-sdk/rtp"
+)
+
+const (
+	// Window is the interval for finding the minimal transit time, which filters out network jitter.
+	Window = 5 * time.Second
+	// MinSpan is the minimal duration of the stream before the drift is estimated.
+	MinSpan = time.Minute
+	// MaxJump is the largest RTP timestamp jump that is still considered the same stream.
+	MaxJump = 30 * time.Second
+)
+
+// Estimator estimates clock drift of the RTP stream by comparing RTP timestamps with the wall clock.
+//
+// Drift is a difference of the remote clock rate relative to the local clock, in parts per million.
+// Positive values mean that the remote clock is faster and sends more samples than the local side consumes.
+type Estimator struct {
+	mu          sync.Mutex
+	payloadType byte
+	clockRate   int
+
+	started bool
+	ssrc    uint32
+	start   time.Time // wall clock of the first packet
+	lastTS  uint32
+	extTS   int64 // extended RTP timestamp, relative to the first packet
+
+	winEnd   time.Duration
+	winMin   float64 // minimal transit in the current window, in seconds
+	firstEnd time.Duration
+	firstMin float64
+	hasFirst bool
+	ppm      float64
+	hasDrift bool
+}
+
+func NewEstimator() *Estimator {
+	return &Estimator{}
+}
+
+// SetCodec sets the payload type and clock rate of the audio stream. Other payload types are ignored.
+func (e *Estimator) SetCodec(payloadType byte, clockRate int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.payloadType != payloadType || e.clockRate != clockRate {
+		e.payloadType = payloadType
+		e.clockRate = clockRate
+		e.started = false
+	}
+}
+
+// Drift returns the last drift estimate in ppm. It returns false if there's not enough data yet.
+func (e *Estimator) Drift() (float64, bool) {
+	if e == nil {
+		return 0, false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.ppm, e.hasDrift
+}
+
+// Update accounts a received RTP packet. It returns true if the drift estimate was updated.
+func (e *Estimator) Update(h *rtp.Header, now time.Time) (float64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.clockRate <= 0 || h.PayloadType != e.payloadType {
+		return 0, false
+	}
+	if !e.started || h.SSRC != e.ssrc {
+		e.reset(h, now)
+		return 0, false
+	}
+	d := int64(int32(h.Timestamp - e.lastTS))
+	if time.Duration(abs(d))*time.Second/time.Duration(e.clockRate) > MaxJump {
+		e.reset(h, now)
+		return 0, false
+	}
+	ts := e.extTS + d
+	if d > 0 {
+		e.extTS = ts
+		e.lastTS = h.Timestamp
+	}
+	elapsed := now.Sub(e.start)
+	transit := elapsed.Seconds() - float64(ts)/float64(e.clockRate)
+	e.winMin = min(e.winMin, transit)
+	if elapsed < e.winEnd {
+		return 0, false
+	}
+	winMin := e.winMin
+	e.winEnd = elapsed + Window
+	e.winMin = math.Inf(1)
+	if !e.hasFirst {
+		e.hasFirst = true
+		e.firstEnd, e.firstMin = elapsed, winMin
+		return 0, false
+	}
+	span := elapsed - e.firstEnd
+	if span < MinSpan {
+		return 0, false
+	}
+	// Transit time grows when the remote clock is slower than ours.
+	e.ppm = -(winMin - e.firstMin) / span.Seconds() * 1e6
+	e.hasDrift = true
+	return e.ppm, true
+}
+
+func (e *Estimator) reset(h *rtp.Header, now time.Time) {
+	e.started = true
+	e.ssrc = h.SSRC
+	e.start = now
+	e.lastTS = h.Timestamp
+	e.extTS = 0
+	e.winEnd = Window
+	e.winMin = 0 // transit of the first packet
+	e.hasFirst = false
+	// Keep the last estimate until a new one is available.
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
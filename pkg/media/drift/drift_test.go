@@ -0,0 +1,95 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drift
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/media-sdk/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimator(t *testing.T) {
+	const (
+		clockRate = 8000
+		frame     = clockRate / 50
+	)
+	for _, ppm := range []float64{0, 100, -250} {
+		e := NewEstimator()
+		e.SetCodec(0, clockRate)
+		start := time.Unix(0, 0)
+		// Remote sends a frame every 20ms of its own clock.
+		frameDur := time.Duration(float64(20*time.Millisecond) / (1 + ppm/1e6))
+		ts := uint32(0xffff0000) // wraps around
+		for i := 0; i < 50*300; i++ {
+			jitter := time.Duration(rand.IntN(30)) * time.Millisecond
+			if i%50 == 0 {
+				jitter = 0 // at least a few packets without delay
+			}
+			now := start.Add(time.Duration(i)*frameDur + jitter)
+			e.Update(&rtp.Header{PayloadType: 0, SSRC: 1, Timestamp: ts}, now)
+			e.Update(&rtp.Header{PayloadType: 101, SSRC: 1, Timestamp: 0}, now) // ignored
+			ts += frame
+		}
+		got, ok := e.Drift()
+		require.True(t, ok)
+		require.InDelta(t, ppm, got, 5)
+	}
+}
+
+type frameWriter struct {
+	frames []int
+	total  int
+}
+
+func (w *frameWriter) String() string  { return "Frames" }
+func (w *frameWriter) SampleRate() int { return 8000 }
+func (w *frameWriter) Close() error    { return nil }
+func (w *frameWriter) WriteSample(s msdk.PCM16Sample) error {
+	w.frames = append(w.frames, len(s))
+	w.total += len(s)
+	return nil
+}
+
+func TestCorrector(t *testing.T) {
+	const (
+		frame  = 160
+		frames = 50 * 600 // 10 min
+	)
+	for _, ppm := range []float64{-500, 10, 500, 5000} {
+		var (
+			out frameWriter
+			adj atomic.Int64
+		)
+		w := NewCorrector(&out, func() (float64, bool) { return ppm, true }, &adj)
+		in := make(msdk.PCM16Sample, frame)
+		for i := 0; i < frames; i++ {
+			require.NoError(t, w.WriteSample(in))
+		}
+		for _, n := range out.frames {
+			require.Equal(t, frame, n)
+		}
+		exp := 0
+		if ppm >= -MaxCorrection && ppm <= MaxCorrection && (ppm >= MinCorrection || ppm <= -MinCorrection) {
+			exp = -int(ppm * frame * frames / 1e6)
+		}
+		require.InDelta(t, exp, adj.Load(), 1)
+		require.InDelta(t, frame*frames+exp, out.total, frame)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tonegen generates standard call progress tones, with cadences of different countries.
+package tonegen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/media-sdk/tones"
+)
+
+// Volume is the amplitude of generated tones.
+const Volume = math.MaxInt16 / 2
+
+const (
+	maxFreq = 4000
+	maxDur  = time.Minute
+)
+
+// Kind of call progress tone.
+type Kind string
+
+const (
+	// Dial tone, played when the line is ready.
+	Dial = Kind("dial")
+	// Ringing tone (ringback), played while the remote is alerted.
+	Ringing = Kind("ringing")
+	// Busy tone, played when the remote is busy.
+	Busy = Kind("busy")
+	// Reorder (congestion) tone, played when the call cannot be completed.
+	Reorder = Kind("reorder")
+	// SIT is the special information tone, played before intercept messages, for example when the number is not in service.
+	SIT = Kind("sit")
+)
+
+// Kinds lists all supported tone kinds.
+var Kinds = []Kind{Dial, Ringing, Busy, Reorder, SIT}
+
+// DefaultCountry is used when the country is not set.
+const DefaultCountry = "etsi"
+
+// sit is the same in all countries (ITU-T E.180): three rising tones of 330ms, followed by a pause.
+const sit = "950/330,1400/330,1800/330,0/1000"
+
+// countries lists cadences of standard tones, see ITU-T E.180 Supplement 2.
+var countries = map[string]map[Kind]string{
+	"etsi": {
+		Dial:    "425",
+		Ringing: "425/1000,0/4000",
+		Busy:    "425/500,0/500",
+		Reorder: "425/200,0/200",
+		SIT:     sit,
+	},
+	"us": {
+		Dial:    "350+440",
+		Ringing: "440+480/2000,0/4000",
+		Busy:    "480+620/500,0/500",
+		Reorder: "480+620/250,0/250",
+		SIT:     sit,
+	},
+	"uk": {
+		Dial:    "350+440",
+		Ringing: "400+450/400,0/200,400+450/400,0/2000",
+		Busy:    "400/375,0/375",
+		Reorder: "400/400,0/350,400/225,0/525",
+		SIT:     sit,
+	},
+	"de": {
+		Dial:    "425",
+		Ringing: "425/1000,0/4000",
+		Busy:    "425/480,0/480",
+		Reorder: "425/240,0/240",
+		SIT:     sit,
+	},
+	"fr": {
+		Dial:    "440",
+		Ringing: "440/1500,0/3500",
+		Busy:    "440/500,0/500",
+		Reorder: "440/250,0/250",
+		SIT:     sit,
+	},
+	"au": {
+		Dial:    "413+438",
+		Ringing: "413+438/400,0/200,413+438/400,0/2000",
+		Busy:    "425/375,0/375",
+		Reorder: "425/375,0/375",
+		SIT:     sit,
+	},
+	"jp": {
+		Dial:    "400",
+		Ringing: "400/1000,0/2000",
+		Busy:    "400/500,0/500",
+		Reorder: "400/500,0/500",
+		SIT:     sit,
+	},
+}
+
+// Countries returns the list of supported country profiles.
+func Countries() []string {
+	var out []string
+	for c := range countries {
+		out = append(out, c)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// ParseCadence parses a tone cadence in the form of "freq1+freq2/ms,0/ms,...", where zero frequency is silence.
+// The cadence is played in a loop. A single tone without a duration, for example "350+440", is played continuously.
+func ParseCadence(s string) ([]tones.Tone, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errors.New("empty tone cadence")
+	}
+	parts := strings.Split(s, ",")
+	var out []tones.Tone
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		fstr, dstr, hasDur := strings.Cut(part, "/")
+		var dur time.Duration
+		if hasDur {
+			ms, err := strconv.Atoi(strings.TrimSpace(dstr))
+			if err != nil || ms <= 0 || time.Duration(ms)*time.Millisecond > maxDur {
+				return nil, fmt.Errorf("invalid tone duration in %q", part)
+			}
+			dur = time.Duration(ms) * time.Millisecond
+		} else if len(parts) != 1 {
+			return nil, fmt.Errorf("tone duration is required in %q", part)
+		}
+		var freq []tones.Hz
+		for _, f := range strings.Split(fstr, "+") {
+			hz, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil || hz < 0 || hz > maxFreq {
+				return nil, fmt.Errorf("invalid tone frequency in %q", part)
+			}
+			if hz != 0 {
+				freq = append(freq, tones.Hz(hz))
+			}
+		}
+		if len(freq) == 0 {
+			if !hasDur {
+				return nil, errors.New("tone cannot be silent")
+			}
+			if n := len(out); n > 0 && out[n-1].Silence == 0 {
+				out[n-1].Silence = dur
+			} else {
+				out = append(out, tones.Tone{Dur: dur})
+			}
+			continue
+		}
+		out = append(out, tones.Tone{Freq: freq, Dur: dur})
+	}
+	return out, nil
+}
+
+func mustParseCadence(s string) []tones.Tone {
+	t, err := ParseCadence(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Profile is a set of call progress tones.
+type Profile struct {
+	country string
+	tones   map[Kind][]tones.Tone
+}
+
+// NewProfile creates a profile for a given country. Custom cadences override tones of the country by kind.
+func NewProfile(country string, custom map[string]string) (*Profile, error) {
+	if country == "" {
+		country = DefaultCountry
+	}
+	country = strings.ToLower(country)
+	base, ok := countries[country]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tone country %q, expected one of: %s", country, strings.Join(Countries(), ", "))
+	}
+	p := &Profile{country: country, tones: make(map[Kind][]tones.Tone, len(base))}
+	for k, s := range base {
+		p.tones[k] = mustParseCadence(s)
+	}
+	for name, s := range custom {
+		k := Kind(strings.ToLower(name))
+		if !slices.Contains(Kinds, k) {
+			return nil, fmt.Errorf("unsupported tone %q", name)
+		}
+		t, err := ParseCadence(s)
+		if err != nil {
+			return nil, fmt.Errorf("tone %q: %w", name, err)
+		}
+		p.tones[k] = t
+	}
+	return p, nil
+}
+
+var defaultProfile, _ = NewProfile(DefaultCountry, nil)
+
+// Country returns the country of the profile.
+func (p *Profile) Country() string {
+	if p == nil {
+		p = defaultProfile
+	}
+	return p.country
+}
+
+// Tones returns the cadence of a given tone. Nil profile returns ETSI tones.
+func (p *Profile) Tones(k Kind) []tones.Tone {
+	if p == nil {
+		p = defaultProfile
+	}
+	return p.tones[k]
+}
+
+// Play the tone to the writer in a loop until the context is cancelled.
+func (p *Profile) Play(ctx context.Context, w msdk.Writer[msdk.PCM16Sample], k Kind) error {
+	t := p.Tones(k)
+	if len(t) == 0 {
+		return fmt.Errorf("unsupported tone %q", k)
+	}
+	return tones.Play(ctx, w, Volume, t)
+}
+
+// PlayFor plays the tone for a given duration. It returns early if the context is cancelled.
+func (p *Profile) PlayFor(ctx context.Context, w msdk.Writer[msdk.PCM16Sample], k Kind, dur time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, dur)
+	defer cancel()
+	err := p.Play(ctx, w, k)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tonegen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/media-sdk/tones"
+)
+
+func TestParseCadence(t *testing.T) {
+	cases := []struct {
+		in  string
+		exp []tones.Tone
+		err bool
+	}{
+		{in: "350+440", exp: []tones.Tone{{Freq: []tones.Hz{350, 440}}}},
+		{in: "480+620/500,0/500", exp: []tones.Tone{{Freq: []tones.Hz{480, 620}, Dur: 500 * time.Millisecond, Silence: 500 * time.Millisecond}}},
+		{in: "400/400, 0/200, 400/400, 0/2000", exp: []tones.Tone{
+			{Freq: []tones.Hz{400}, Dur: 400 * time.Millisecond, Silence: 200 * time.Millisecond},
+			{Freq: []tones.Hz{400}, Dur: 400 * time.Millisecond, Silence: 2 * time.Second},
+		}},
+		{in: "0/100,425/200,0/100,0/100", exp: []tones.Tone{
+			{Dur: 100 * time.Millisecond},
+			{Freq: []tones.Hz{425}, Dur: 200 * time.Millisecond, Silence: 100 * time.Millisecond},
+			{Dur: 100 * time.Millisecond},
+		}},
+		{in: "", err: true},
+		{in: "0", err: true},
+		{in: "425,0/100", err: true},
+		{in: "425/0", err: true},
+		{in: "abc/100", err: true},
+		{in: "9000/100", err: true},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			got, err := ParseCadence(c.in)
+			if c.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.exp, got)
+		})
+	}
+}
+
+func TestProfile(t *testing.T) {
+	for _, c := range Countries() {
+		p, err := NewProfile(c, nil)
+		require.NoError(t, err)
+		for _, k := range Kinds {
+			require.NotEmpty(t, p.Tones(k), "%s %s", c, k)
+		}
+	}
+
+	p, err := NewProfile("US", map[string]string{"Busy": "425/100,0/100"})
+	require.NoError(t, err)
+	require.Equal(t, "us", p.Country())
+	require.Equal(t, []tones.Tone{{Freq: []tones.Hz{425}, Dur: 100 * time.Millisecond, Silence: 100 * time.Millisecond}}, p.Tones(Busy))
+	require.Equal(t, []tones.Hz{480, 620}, p.Tones(Reorder)[0].Freq)
+
+	_, err = NewProfile("xx", nil)
+	require.Error(t, err)
+	_, err = NewProfile("", map[string]string{"howler": "425"})
+	require.Error(t, err)
+
+	var none *Profile
+	require.Equal(t, DefaultCountry, none.Country())
+	require.Equal(t, []tones.Hz{425}, none.Tones(Dial)[0].Freq)
+}
+
+type countWriter struct {
+	frames int
+}
+
+func (w *countWriter) String() string                       { return "Count" }
+func (w *countWriter) SampleRate() int                      { return 8000 }
+func (w *countWriter) WriteSample(_ msdk.PCM16Sample) error { w.frames++; return nil }
+
+func TestPlayFor(t *testing.T) {
+	var w countWriter
+	err := (*Profile)(nil).PlayFor(context.Background(), &w, Busy, 200*time.Millisecond)
+	require.NoError(t, err)
+	require.NotZero(t, w.frames)
+}
@@ -0,0 +1,200 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vad
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+)
+
+// DefaultHangover is the pause in speech that still counts as the same speech burst.
+const DefaultHangover = 200 * time.Millisecond
+
+// Side of the conversation.
+type Side int
+
+const (
+	SideRemote Side = iota
+	SideLocal
+)
+
+func (s Side) String() string {
+	switch s {
+	case SideRemote:
+		return "remote"
+	case SideLocal:
+		return "local"
+	}
+	return "unknown"
+}
+
+// SideStats is the talk time summary for one side of the conversation.
+type SideStats struct {
+	Talk   time.Duration
+	Bursts int
+}
+
+// TalkSummary is the talk time summary for the conversation.
+type TalkSummary struct {
+	Remote SideStats
+	Local  SideStats
+	// Overlap is the time when both sides were talking.
+	Overlap time.Duration
+	// LongestSilence is the longest time when neither side was talking.
+	LongestSilence time.Duration
+}
+
+type talkSide struct {
+	SideStats
+	last time.Time // last frame with speech
+}
+
+// TalkStats tracks talk time, double-talk and silence of both sides of the conversation.
+// The timeline starts with the first audio frame.
+type TalkStats struct {
+	threshold float64 // RMS in sample units
+	hangover  time.Duration
+	now       func() time.Time
+
+	mu             sync.Mutex
+	start          time.Time
+	lastSpeech     time.Time // end of the last speech frame on any side
+	sides          [2]talkSide
+	overlap        time.Duration
+	overlapEnd     time.Time
+	longestSilence time.Duration
+}
+
+func NewTalkStats(conf Config) *TalkStats {
+	if conf.Threshold == 0 {
+		conf.Threshold = DefaultThreshold
+	}
+	return &TalkStats{
+		threshold: math.MaxInt16 * math.Pow(10, conf.Threshold/20),
+		hangover:  DefaultHangover,
+		now:       time.Now,
+	}
+}
+
+func (t *TalkStats) speaking(s *talkSide, now time.Time) bool {
+	return !s.last.IsZero() && now.Sub(s.last) <= t.hangover
+}
+
+// Process analyzes an audio frame from a given side.
+func (t *TalkStats) Process(side Side, sample msdk.PCM16Sample, sampleRate int) {
+	if t == nil || len(sample) == 0 || sampleRate <= 0 || side < 0 || int(side) >= len(t.sides) {
+		return
+	}
+	speech := rms(sample) >= t.threshold
+	dur := time.Duration(len(sample)) * time.Second / time.Duration(sampleRate)
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.start.IsZero() {
+		t.start = now
+		t.lastSpeech = now
+	}
+	if !speech {
+		return
+	}
+	s, other := &t.sides[side], &t.sides[1-side]
+	if !t.speaking(s, now) {
+		s.Bursts++
+	}
+	end := now.Add(dur)
+	if t.speaking(other, now) {
+		// Both sides report the same interval, count it once.
+		t.overlap += max(0, end.Sub(later(now, t.overlapEnd)))
+		t.overlapEnd = later(end, t.overlapEnd)
+	}
+	s.Talk += dur
+	s.last = now
+	t.longestSilence = max(t.longestSilence, now.Sub(t.lastSpeech))
+	t.lastSpeech = later(end, t.lastSpeech)
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// Reset discards collected statistics, for example, to ignore ringback played before the call is answered.
+func (t *TalkStats) Reset() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.start = time.Time{}
+	t.lastSpeech = time.Time{}
+	t.sides = [2]talkSide{}
+	t.overlap = 0
+	t.overlapEnd = time.Time{}
+	t.longestSilence = 0
+}
+
+// Summary returns talk statistics collected so far. Silence at the end of the conversation is included.
+func (t *TalkStats) Summary() TalkSummary {
+	if t == nil {
+		return TalkSummary{}
+	}
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sum := TalkSummary{
+		Remote:         t.sides[SideRemote].SideStats,
+		Local:          t.sides[SideLocal].SideStats,
+		Overlap:        t.overlap,
+		LongestSilence: t.longestSilence,
+	}
+	if !t.start.IsZero() {
+		sum.LongestSilence = max(sum.LongestSilence, now.Sub(t.lastSpeech))
+	}
+	return sum
+}
+
+// Writer returns a writer that passes audio to w and counts it as a given side of the conversation.
+func (t *TalkStats) Writer(side Side, w msdk.PCM16Writer) msdk.PCM16Writer {
+	return &talkWriter{t: t, side: side, w: w}
+}
+
+type talkWriter struct {
+	t    *TalkStats
+	side Side
+	w    msdk.PCM16Writer
+}
+
+func (w *talkWriter) String() string {
+	return "TalkStats(" + w.side.String() + ") -> " + w.w.String()
+}
+
+func (w *talkWriter) SampleRate() int {
+	return w.w.SampleRate()
+}
+
+func (w *talkWriter) WriteSample(sample msdk.PCM16Sample) error {
+	w.t.Process(w.side, sample, w.w.SampleRate())
+	return w.w.WriteSample(sample)
+}
+
+func (w *talkWriter) Close() error {
+	return w.w.Close()
+}
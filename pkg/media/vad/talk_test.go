@@ -0,0 +1,66 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vad
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTalkStats(t *testing.T) {
+	now := time.Unix(0, 0)
+	ts := NewTalkStats(Config{})
+	ts.now = func() time.Time { return now }
+	require.Equal(t, TalkSummary{}, ts.Summary())
+
+	// step writes one 20ms frame for each side.
+	step := func(remote, local float64) {
+		ts.Process(SideRemote, frame(remote), sampleRate)
+		ts.Process(SideLocal, frame(local), sampleRate)
+		now = now.Add(20 * time.Millisecond)
+	}
+	for i := 0; i < 50; i++ { // 1s of silence
+		step(-60, -60)
+	}
+	for i := 0; i < 100; i++ { // 2s of remote speech
+		step(-20, -60)
+	}
+	for i := 0; i < 5; i++ { // short pause, same burst
+		step(-60, -60)
+	}
+	for i := 0; i < 25; i++ { // 0.5s of double-talk
+		step(-20, -20)
+	}
+	for i := 0; i < 100; i++ { // 2s of silence
+		step(-60, -60)
+	}
+	for i := 0; i < 50; i++ { // 1s of local speech
+		step(-60, -20)
+	}
+	for i := 0; i < 25; i++ { // 0.5s of trailing silence
+		step(-60, -60)
+	}
+
+	sum := ts.Summary()
+	require.Equal(t, SideStats{Talk: 2500 * time.Millisecond, Bursts: 1}, sum.Remote)
+	require.Equal(t, SideStats{Talk: 1500 * time.Millisecond, Bursts: 2}, sum.Local)
+	require.Equal(t, 500*time.Millisecond, sum.Overlap)
+	require.Equal(t, 2*time.Second, sum.LongestSilence)
+
+	ts.Reset()
+	require.Equal(t, TalkSummary{}, ts.Summary())
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vad implements a simple energy-based voice activity detector.
+package vad
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+
+	msdk "github.com/livekit/media-sdk"
+)
+
+const (
+	DefaultThreshold = -40.0 // dBFS
+	DefaultMinSpeech = 250 * time.Millisecond
+)
+
+type Config struct {
+	// Threshold is the audio level in dBFS considered as speech.
+	Threshold float64
+	// MinSpeech is the minimal duration of continuous audio above the threshold.
+	MinSpeech time.Duration
+}
+
+// Detector reports when speech is detected in the audio. It only detects the first occurrence of speech.
+type Detector struct {
+	threshold float64 // RMS in sample units
+	minSpeech time.Duration
+	detected  core.Fuse
+
+	mu     sync.Mutex
+	speech time.Duration
+}
+
+func NewDetector(conf Config) *Detector {
+	if conf.Threshold == 0 {
+		conf.Threshold = DefaultThreshold
+	}
+	if conf.MinSpeech <= 0 {
+		conf.MinSpeech = DefaultMinSpeech
+	}
+	return &Detector{
+		threshold: math.MaxInt16 * math.Pow(10, conf.Threshold/20),
+		minSpeech: conf.MinSpeech,
+	}
+}
+
+// Detected is closed when speech is detected.
+func (d *Detector) Detected() <-chan struct{} {
+	return d.detected.Watch()
+}
+
+// Process analyzes an audio frame. Consecutive frames above the threshold count as speech, a quiet frame resets the count.
+func (d *Detector) Process(sample msdk.PCM16Sample, sampleRate int) {
+	if len(sample) == 0 || sampleRate <= 0 || d.detected.IsBroken() {
+		return
+	}
+	level := rms(sample)
+	dur := time.Duration(len(sample)) * time.Second / time.Duration(sampleRate)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if level < d.threshold {
+		d.speech = 0
+		return
+	}
+	d.speech += dur
+	if d.speech >= d.minSpeech {
+		d.detected.Break()
+	}
+}
+
+func rms(sample msdk.PCM16Sample) float64 {
+	var sum float64
+	for _, v := range sample {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum / float64(len(sample)))
+}
+
+// SilenceLevel is the audio level of silence, as defined by RFC 6464.
+const SilenceLevel = 127
+
+// AudioLevel returns the audio level of the frame in -dBov, as used by the RTP audio level header extension (RFC 6464).
+// The value is in the range from 0 (loudest) to 127 (silence).
+func AudioLevel(sample msdk.PCM16Sample) uint8 {
+	if len(sample) == 0 {
+		return SilenceLevel
+	}
+	level := rms(sample)
+	if level <= 0 {
+		return SilenceLevel
+	}
+	db := -20 * math.Log10(level/math.MaxInt16)
+	return uint8(math.Round(min(max(db, 0), SilenceLevel)))
+}
+
+// Writer returns a writer that passes audio to w and runs detection on it.
+func (d *Detector) Writer(w msdk.PCM16Writer) msdk.PCM16Writer {
+	return &writer{d: d, w: w}
+}
+
+type writer struct {
+	d *Detector
+	w msdk.PCM16Writer
+}
+
+func (w *writer) String() string {
+	return "VAD -> " + w.w.String()
+}
+
+func (w *writer) SampleRate() int {
+	return w.w.SampleRate()
+}
+
+func (w *writer) WriteSample(sample msdk.PCM16Sample) error {
+	w.d.Process(sample, w.w.SampleRate())
+	return w.w.WriteSample(sample)
+}
+
+func (w *writer) Close() error {
+	return w.w.Close()
+}
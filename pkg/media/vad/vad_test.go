@@ -0,0 +1,74 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vad
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleRate = 8000
+
+// frame generates 20ms of a sine wave with a given level in dBFS.
+func frame(level float64) msdk.PCM16Sample {
+	amp := math.MaxInt16 * math.Pow(10, level/20) * math.Sqrt2 // RMS of a sine is amp/sqrt(2)
+	out := make(msdk.PCM16Sample, sampleRate/50)
+	for i := range out {
+		out[i] = int16(amp * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+	}
+	return out
+}
+
+func detected(d *Detector) bool {
+	select {
+	case <-d.Detected():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestAudioLevel(t *testing.T) {
+	require.EqualValues(t, SilenceLevel, AudioLevel(nil))
+	require.EqualValues(t, SilenceLevel, AudioLevel(make(msdk.PCM16Sample, 160)))
+	require.EqualValues(t, 20, AudioLevel(frame(-20)))
+	require.EqualValues(t, 60, AudioLevel(frame(-60)))
+}
+
+func TestDetector(t *testing.T) {
+	d := NewDetector(Config{MinSpeech: 100 * time.Millisecond})
+	// Background noise is not speech.
+	for i := 0; i < 50; i++ {
+		d.Process(frame(-60), sampleRate)
+	}
+	require.False(t, detected(d))
+
+	// Short bursts are not speech.
+	for i := 0; i < 10; i++ {
+		d.Process(frame(-20), sampleRate)
+		d.Process(frame(-20), sampleRate)
+		d.Process(frame(-60), sampleRate)
+	}
+	require.False(t, detected(d))
+
+	for i := 0; i < 5; i++ {
+		d.Process(frame(-20), sampleRate)
+	}
+	require.True(t, detected(d))
+}
@@ -95,6 +95,12 @@ func NewMixer(out msdk.Writer[msdk.PCM16Sample], bufferDur time.Duration, st *St
 	return m
 }
 
+// InputBufferSize returns the size of the buffer of each input, in bytes, for a mixer with given sample rate and buffer duration.
+func InputBufferSize(sampleRate int, bufferDur time.Duration) int {
+	mixSize := int(time.Duration(sampleRate) * bufferDur / time.Second)
+	return mixSize * inputBufferFrames * 2
+}
+
 func newMixer(out msdk.Writer[msdk.PCM16Sample], mixSize int, st *Stats) *Mixer {
 	if st == nil {
 		st = new(Stats)
@@ -0,0 +1,156 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/sip"
+)
+
+// AdminAPI is implemented by the SIP service and exposed on the admin port.
+type AdminAPI interface {
+	ProbeTrunk(ctx context.Context, req *sip.TrunkProbeRequest) (*sip.TrunkProbeResult, error)
+	DryRunInbound(ctx context.Context, req *sip.InboundDryRunRequest) (*sip.InboundDryRunResponse, error)
+	Dialogs(ctx context.Context) (*sip.DialogDump, error)
+	SetMediaStages(ctx context.Context, req *sip.SetMediaStagesRequest) (*sip.SetMediaStagesResponse, error)
+	SetMediaImpairment(ctx context.Context, req *sip.SetMediaImpairmentRequest) (*sip.SetMediaImpairmentResponse, error)
+	SpeakToCall(ctx context.Context, req *sip.SpeakRequest) (*sip.SpeakResponse, error)
+	SubmitDialJobs(ctx context.Context, req *sip.DialJobsRequest) (*sip.DialJobsResponse, error)
+	DialJobStatus(ctx context.Context, req *sip.DialJobRequest) (*sip.DialJobStatus, error)
+	CancelDialJob(ctx context.Context, req *sip.DialJobRequest) (*sip.DialJobStatus, error)
+	MigrateCalls(ctx context.Context, req *sip.MigrateCallsRequest) (*sip.MigrateCallsResponse, error)
+}
+
+const maxAdminRequestSize = 1 << 20
+
+// newAdminHandler serves the admin API. If auth is set, each route requires a scope.
+func newAdminHandler(log logger.Logger, api AdminAPI, auth *adminAuth) http.Handler {
+	mux := http.NewServeMux()
+	handle := func(pattern string, scope config.AdminScope, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, auth.Require(scope, h))
+	}
+	handle("POST /trunks/probe", config.AdminScopeConfig, func(w http.ResponseWriter, r *http.Request) {
+		var req sip.TrunkProbeRequest
+		if !readAdminRequest(w, r, &req) {
+			return
+		}
+		resp, err := api.ProbeTrunk(r.Context(), &req)
+		writeAdminResponse(log, w, resp, err)
+	})
+	handle("POST /dispatch/dry-run", config.AdminScopeConfig, func(w http.ResponseWriter, r *http.Request) {
+		var req sip.InboundDryRunRequest
+		if !readAdminRequest(w, r, &req) {
+			return
+		}
+		resp, err := api.DryRunInbound(r.Context(), &req)
+		writeAdminResponse(log, w, resp, err)
+	})
+	handle("GET /dialogs", config.AdminScopeMetrics, func(w http.ResponseWriter, r *http.Request) {
+		resp, err := api.Dialogs(r.Context())
+		writeAdminResponse(log, w, resp, err)
+	})
+	handle("PUT /calls/{id}/media-stages", config.AdminScopeMedia, func(w http.ResponseWriter, r *http.Request) {
+		var req sip.SetMediaStagesRequest
+		if !readAdminRequest(w, r, &req) {
+			return
+		}
+		req.CallID = r.PathValue("id")
+		resp, err := api.SetMediaStages(r.Context(), &req)
+		writeAdminResponse(log, w, resp, err)
+	})
+	handle("PUT /calls/{id}/impairment", config.AdminScopeCalls, func(w http.ResponseWriter, r *http.Request) {
+		var req sip.SetMediaImpairmentRequest
+		if !readAdminRequest(w, r, &req) {
+			return
+		}
+		req.CallID = r.PathValue("id")
+		resp, err := api.SetMediaImpairment(r.Context(), &req)
+		writeAdminResponse(log, w, resp, err)
+	})
+	handle("POST /calls/{id}/speak", config.AdminScopeCalls, func(w http.ResponseWriter, r *http.Request) {
+		var req sip.SpeakRequest
+		if !readAdminRequest(w, r, &req) {
+			return
+		}
+		req.CallID = r.PathValue("id")
+		resp, err := api.SpeakToCall(r.Context(), &req)
+		writeAdminResponse(log, w, resp, err)
+	})
+	handle("POST /calls/migrate", config.AdminScopeCalls, func(w http.ResponseWriter, r *http.Request) {
+		var req sip.MigrateCallsRequest
+		if !readAdminRequest(w, r, &req) {
+			return
+		}
+		resp, err := api.MigrateCalls(r.Context(), &req)
+		writeAdminResponse(log, w, resp, err)
+	})
+	handle("POST /dialer/jobs", config.AdminScopeCalls, func(w http.ResponseWriter, r *http.Request) {
+		var req sip.DialJobsRequest
+		if !readAdminRequest(w, r, &req) {
+			return
+		}
+		resp, err := api.SubmitDialJobs(r.Context(), &req)
+		writeAdminResponse(log, w, resp, err)
+	})
+	handle("GET /dialer/jobs/{id}", config.AdminScopeCalls, func(w http.ResponseWriter, r *http.Request) {
+		resp, err := api.DialJobStatus(r.Context(), &sip.DialJobRequest{ID: r.PathValue("id")})
+		writeAdminResponse(log, w, resp, err)
+	})
+	handle("DELETE /dialer/jobs/{id}", config.AdminScopeCalls, func(w http.ResponseWriter, r *http.Request) {
+		resp, err := api.CancelDialJob(r.Context(), &sip.DialJobRequest{ID: r.PathValue("id")})
+		writeAdminResponse(log, w, resp, err)
+	})
+	pprofMux := http.NewServeMux()
+	registerPProf(pprofMux)
+	handle("/debug/pprof/", config.AdminScopeMetrics, pprofMux.ServeHTTP)
+	handle("GET /debug/runtime", config.AdminScopeMetrics, func(w http.ResponseWriter, r *http.Request) {
+		writeAdminResponse(log, w, readRuntimeStats(), nil)
+	})
+	return mux
+}
+
+func readAdminRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminRequestSize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeAdminResponse(log logger.Logger, w http.ResponseWriter, resp any, err error) {
+	if err != nil {
+		code := http.StatusInternalServerError
+		var perr psrpc.Error
+		if errors.As(err, &perr) {
+			code = perr.ToHttp()
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		log.Warnw("cannot write admin response", err)
+	}
+}
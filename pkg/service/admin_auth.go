@@ -0,0 +1,131 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+var (
+	errAdminUnauthorized = errors.New("unauthorized")
+	errAdminNoExpiry     = errors.New("token must expire")
+)
+
+// adminClaims are claims of a JWT for the admin API, in addition to the standard ones.
+type adminClaims struct {
+	// Scopes limit scopes of the key which signed the token. All scopes of the key are granted if empty.
+	Scopes []config.AdminScope `json:"scopes,omitempty"`
+}
+
+// adminAuth checks credentials and scopes of admin API requests.
+// A nil adminAuth allows all requests, which keeps the admin port open if authentication is not configured.
+type adminAuth struct {
+	log  logger.Logger
+	keys []config.AdminKeyConfig
+}
+
+func newAdminAuth(log logger.Logger, conf *config.AdminAuthConfig) *adminAuth {
+	if conf == nil {
+		return nil
+	}
+	return &adminAuth{log: log, keys: conf.Keys}
+}
+
+// Authenticate returns the key and scopes granted to the request.
+func (a *adminAuth) Authenticate(r *http.Request) (string, []config.AdminScope, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", nil, errAdminUnauthorized
+	}
+	for i := range a.keys {
+		k := &a.keys[i]
+		if subtle.ConstantTimeCompare([]byte(token), []byte(k.Secret)) == 1 {
+			return k.Key, k.Scopes, nil
+		}
+	}
+	return a.verifyJWT(token)
+}
+
+func (a *adminAuth) verifyJWT(token string) (string, []config.AdminScope, error) {
+	tok, err := jwt.ParseSigned(token)
+	if err != nil {
+		return "", nil, errAdminUnauthorized
+	}
+	var std jwt.Claims
+	if err = tok.UnsafeClaimsWithoutVerification(&std); err != nil {
+		return "", nil, errAdminUnauthorized
+	}
+	i := slices.IndexFunc(a.keys, func(k config.AdminKeyConfig) bool {
+		return k.Key == std.Issuer
+	})
+	if i < 0 {
+		return "", nil, errAdminUnauthorized
+	}
+	k := &a.keys[i]
+	var claims adminClaims
+	if err = tok.Claims([]byte(k.Secret), &std, &claims); err != nil {
+		return "", nil, errAdminUnauthorized
+	}
+	if std.Expiry == nil {
+		return "", nil, errAdminNoExpiry
+	}
+	if err = std.Validate(jwt.Expected{Issuer: k.Key, Time: time.Now()}); err != nil {
+		return "", nil, err
+	}
+	if len(claims.Scopes) == 0 {
+		return k.Key, k.Scopes, nil
+	}
+	var scopes []config.AdminScope
+	for _, s := range claims.Scopes {
+		if slices.Contains(k.Scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return k.Key, scopes, nil
+}
+
+// Require wraps the handler to only serve requests granted the scope.
+func (a *adminAuth) Require(scope config.AdminScope, h http.HandlerFunc) http.HandlerFunc {
+	if a == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, scopes, err := a.Authenticate(r)
+		if err != nil {
+			a.log.Debugw("admin request not authenticated", "error", err, "method", r.Method, "path", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="livekit-sip"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !slices.Contains(scopes, scope) {
+			a.log.Infow("admin request denied", "key", key, "scope", scope, "method", r.Method, "path", r.URL.Path)
+			http.Error(w, "missing scope "+string(scope), http.StatusForbidden)
+			return
+		}
+		a.log.Debugw("admin request", "key", key, "scope", scope, "method", r.Method, "path", r.URL.Path)
+		h(w, r)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/sip"
+)
+
+type testAdminAPI struct {
+	AdminAPI
+}
+
+func (testAdminAPI) Dialogs(ctx context.Context) (*sip.DialogDump, error) {
+	return &sip.DialogDump{}, nil
+}
+
+func (testAdminAPI) SpeakToCall(ctx context.Context, req *sip.SpeakRequest) (*sip.SpeakResponse, error) {
+	return &sip.SpeakResponse{}, nil
+}
+
+func testAdminJWT(t testing.TB, key, secret string, exp time.Duration, scopes ...config.AdminScope) string {
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte(secret)}, nil)
+	require.NoError(t, err)
+	std := jwt.Claims{Issuer: key}
+	if exp != 0 {
+		std.Expiry = jwt.NewNumericDate(time.Now().Add(exp))
+	}
+	tok, err := jwt.Signed(sig).Claims(std).Claims(adminClaims{Scopes: scopes}).CompactSerialize()
+	require.NoError(t, err)
+	return tok
+}
+
+func TestAdminAuth(t *testing.T) {
+	conf := &config.AdminAuthConfig{Keys: []config.AdminKeyConfig{
+		{Key: "monitor", Secret: "monitor-secret", Scopes: []config.AdminScope{config.AdminScopeMetrics}},
+		{Key: "ops", Secret: "ops-secret", Scopes: []config.AdminScope{config.AdminScopeMetrics, config.AdminScopeCalls}},
+	}}
+	require.NoError(t, conf.Validate())
+	h := newAdminHandler(logger.GetLogger(), testAdminAPI{}, newAdminAuth(logger.GetLogger(), conf))
+
+	do := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"text":"hello"}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+	const (
+		dialogs = "/dialogs"
+		speak   = "/calls/SCL_test/speak"
+	)
+
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, dialogs, ""))
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, dialogs, "wrong"))
+	require.Equal(t, http.StatusOK, do(http.MethodGet, dialogs, "monitor-secret"))
+	require.Equal(t, http.StatusForbidden, do(http.MethodPost, speak, "monitor-secret"))
+	require.Equal(t, http.StatusOK, do(http.MethodPost, speak, "ops-secret"))
+	// Profiles and runtime stats are read-only diagnostics.
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/debug/runtime", "monitor-secret"))
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/debug/pprof/goroutine", "monitor-secret"))
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/debug/pprof/goroutine", ""))
+	// Media access requires its own scope, even for keys which control calls.
+	require.Equal(t, http.StatusForbidden, do(http.MethodPut, "/calls/SCL_test/media-stages", "ops-secret"))
+	// Routing dry-run exposes dispatch config.
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/dispatch/dry-run", ""))
+	require.Equal(t, http.StatusForbidden, do(http.MethodPost, "/dispatch/dry-run", "ops-secret"))
+
+	require.Equal(t, http.StatusOK, do(http.MethodPost, speak, testAdminJWT(t, "ops", "ops-secret", time.Minute)))
+	// Tokens may only narrow scopes of the key.
+	tok := testAdminJWT(t, "ops", "ops-secret", time.Minute, config.AdminScopeMetrics, config.AdminScopeMedia)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, dialogs, tok))
+	require.Equal(t, http.StatusForbidden, do(http.MethodPost, speak, tok))
+	require.Equal(t, http.StatusForbidden, do(http.MethodPut, "/calls/SCL_test/media-stages", tok))
+
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, dialogs, testAdminJWT(t, "ops", "monitor-secret", time.Minute)))
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, dialogs, testAdminJWT(t, "unknown", "ops-secret", time.Minute)))
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, dialogs, testAdminJWT(t, "ops", "ops-secret", -time.Minute)))
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, dialogs, testAdminJWT(t, "ops", "ops-secret", 0)))
+
+	// Without auth config, the admin API stays open.
+	open := newAdminHandler(logger.GetLogger(), testAdminAPI{}, nil)
+	w := httptest.NewRecorder()
+	open.ServeHTTP(w, httptest.NewRequest(http.MethodGet, dialogs, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAdminListeners(t *testing.T) {
+	newService := func(auth *config.AdminAuthConfig) *Service {
+		return NewService(&config.Config{
+			AdminPort:  9000,
+			AdminAuth:  auth,
+			Management: &config.ManagementConfig{Address: "0.0.0.0:9090"},
+		}, logger.GetLogger(), nil, nil, nil, nil, nil, nil, testAdminAPI{})
+	}
+	dryRun := func(h http.Handler) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/dispatch/dry-run", strings.NewReader(`{}`)))
+		return w.Code
+	}
+
+	// Without auth, the admin API is only served on localhost.
+	s := newService(nil)
+	require.Equal(t, "127.0.0.1:9000", s.adminServer.Addr)
+	require.Equal(t, http.StatusNotFound, dryRun(s.mgmtServer.Handler))
+
+	s = newService(&config.AdminAuthConfig{Keys: []config.AdminKeyConfig{{Key: "key", Secret: "secret"}}})
+	require.Equal(t, ":9000", s.adminServer.Addr)
+	require.Equal(t, http.StatusUnauthorized, dryRun(s.mgmtServer.Handler))
+}
+
+func TestAdminAuthConfig(t *testing.T) {
+	for _, c := range []config.AdminAuthConfig{
+		{},
+		{Keys: []config.AdminKeyConfig{{Key: "k", Scopes: []config.AdminScope{config.AdminScopeMetrics}}}},
+		{Keys: []config.AdminKeyConfig{{Key: "k", Secret: "s"}}},
+		{Keys: []config.AdminKeyConfig{{Key: "k", Secret: "s", Scopes: []config.AdminScope{"everything"}}}},
+		{Keys: []config.AdminKeyConfig{
+			{Key: "k", Secret: "s1", Scopes: []config.AdminScope{config.AdminScopeMetrics}},
+			{Key: "k", Secret: "s2", Scopes: []config.AdminScope{config.AdminScopeCalls}},
+		}},
+	} {
+		require.Error(t, c.Validate(), "%+v", c)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// newManagementHandler serves health, metrics and the admin API on a single listener.
+// The admin API is served under /admin/, if enabled. Pprof is only served by the admin API,
+// since profiles and goroutine dumps expose call data.
+func (s *Service) newManagementHandler(admin http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.serveHealth)
+	mux.Handle("/metrics", promhttp.Handler())
+	if admin != nil {
+		mux.Handle("/admin/", http.StripPrefix("/admin", admin))
+	}
+	return mux
+}
+
+// listenManagement opens the management listener, with TLS if configured.
+func listenManagement(conf *config.ManagementConfig) (net.Listener, error) {
+	var tlsConf *tls.Config
+	if conf.TLS != nil {
+		var err error
+		tlsConf, err = newManagementTLS(conf.TLS)
+		if err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("tcp", conf.Address)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConf != nil {
+		l = tls.NewListener(l, tlsConf)
+	}
+	return l, nil
+}
+
+func newManagementTLS(conf *config.ManagementTLSConfig) (*tls.Config, error) {
+	var certs []tls.Certificate
+	for _, c := range conf.Certs {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	tlsConf := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: certs,
+	}
+	if conf.ClientCAFile != "" {
+		data, err := os.ReadFile(conf.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in management client CA file %q", conf.ClientCAFile)
+		}
+		tlsConf.ClientCAs = pool
+		tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConf, nil
+}
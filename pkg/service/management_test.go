@@ -0,0 +1,162 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t testing.TB, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	signer, signKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+	} else {
+		signer, signKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+}
+
+func (c *testCert) keyPEM(t testing.TB) []byte {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c *testCert) tls(t testing.TB) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM(), c.keyPEM(t))
+	require.NoError(t, err)
+	return cert
+}
+
+func TestManagementListener(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	srvCert := newTestCert(t, "server", ca)
+	cliCert := newTestCert(t, "client", ca)
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0600))
+		return path
+	}
+	conf := &config.ManagementConfig{
+		Address: "127.0.0.1:0",
+		TLS: &config.ManagementTLSConfig{
+			Certs:        []config.TLSCert{{CertFile: write("server.crt", srvCert.certPEM()), KeyFile: write("server.key", srvCert.keyPEM(t))}},
+			ClientCAFile: write("ca.crt", ca.certPEM()),
+		},
+	}
+	require.NoError(t, conf.Validate())
+
+	l, err := listenManagement(conf)
+	require.NoError(t, err)
+	defer l.Close()
+	s := &Service{}
+	srv := &http.Server{Handler: s.newManagementHandler(newAdminHandler(logger.GetLogger(), testAdminAPI{}, nil))}
+	go func() {
+		_ = srv.Serve(l)
+	}()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(path string, certs ...tls.Certificate) (int, error) {
+		cli := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+		defer cli.CloseIdleConnections()
+		resp, err := cli.Get("https://" + l.Addr().String() + path)
+		if err != nil {
+			return 0, err
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	code, err := get("/admin/dialogs", cliCert.tls(t))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	code, err = get("/metrics", cliCert.tls(t))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	// Admin routes are only served under /admin/.
+	code, err = get("/dialogs", cliCert.tls(t))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, code)
+	// Pprof is only served by the admin API.
+	code, err = get("/debug/pprof/goroutine", cliCert.tls(t))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, code)
+	code, err = get("/admin/debug/pprof/goroutine", cliCert.tls(t))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+
+	// Clients without a certificate signed by the CA are rejected.
+	_, err = get("/metrics")
+	require.Error(t, err)
+	_, err = get("/metrics", newTestCert(t, "other", newTestCert(t, "other-ca", nil)).tls(t))
+	require.Error(t, err)
+}
+
+func TestManagementConfig(t *testing.T) {
+	require.NoError(t, (&config.ManagementConfig{Address: "10.0.0.1:9090"}).Validate())
+	for _, c := range []config.ManagementConfig{
+		{},
+		{Address: "10.0.0.1"},
+		{Address: "10.0.0.1:9090", TLS: &config.ManagementTLSConfig{}},
+	} {
+		require.Error(t, c.Validate(), "%+v", c)
+	}
+}
@@ -52,6 +52,11 @@ func GetAuthCredentials(ctx context.Context, psrpcClient rpc.IOInfoClient, call
 func DispatchCall(ctx context.Context, psrpcClient rpc.IOInfoClient, log logger.Logger, info *sip.CallInfo) sip.CallDispatch {
 	ctx, span := tracer.Start(ctx, "service.DispatchCall")
 	defer span.End()
+	var extra map[string]string
+	if len(info.ResourcePriority) != 0 {
+		// Let the dispatch evaluation see the priority of the call.
+		extra = map[string]string{sip.AttrSIPResourcePriority: sip.FormatResourcePriority(info.ResourcePriority)}
+	}
 	resp, err := psrpcClient.EvaluateSIPDispatchRules(ctx, &rpc.EvaluateSIPDispatchRulesRequest{
 		SipTrunkId: info.TrunkID,
 		Call:       info.Call,
@@ -64,6 +69,8 @@ func DispatchCall(ctx context.Context, psrpcClient rpc.IOInfoClient, log logger.
 		CalledNumber:  info.Call.To.User,
 		CalledHost:    info.Call.To.Host,
 		SrcAddress:    info.Call.SourceIp,
+
+		ExtraAttributes: extra,
 	})
 
 	if err != nil {
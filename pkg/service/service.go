@@ -54,6 +54,8 @@ type Service struct {
 	promServer   *http.Server
 	pprofServer  *http.Server
 	healthServer *http.Server
+	adminServer  *http.Server
+	mgmtServer   *http.Server
 	rpcSIPServer rpc.SIPInternalServer
 
 	sipServiceStop        sipServiceStopFunc
@@ -67,6 +69,7 @@ type Service struct {
 func NewService(
 	conf *config.Config, log logger.Logger, srv rpc.SIPInternalServerImpl, sipServiceStop sipServiceStopFunc,
 	sipServiceActiveCalls sipServiceActiveCallsFunc, cli rpc.IOInfoClient, bus psrpc.MessageBus, mon *stats.Monitor,
+	admin AdminAPI,
 ) *Service {
 	s := &Service{
 		conf: conf,
@@ -89,11 +92,7 @@ func NewService(
 	}
 	if conf.PProfPort > 0 {
 		mux := http.NewServeMux()
-		mux.HandleFunc("/debug/pprof/", pprof.Index)
-		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		registerPProf(mux)
 		s.pprofServer = &http.Server{
 			Addr:    fmt.Sprintf(":%d", conf.PProfPort),
 			Handler: mux,
@@ -106,25 +105,62 @@ func NewService(
 			Handler: mux,
 		}
 
-		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			st := s.Health()
-			var code int
-			switch st {
-			case stats.HealthOK:
-				code = http.StatusOK
-			case stats.HealthUnderLoad:
-				code = http.StatusTooManyRequests
-			default:
-				code = http.StatusServiceUnavailable
-			}
-			w.Header().Set("Content-Type", "text/plain")
-			w.WriteHeader(code)
-			_, _ = w.Write([]byte(st.String()))
-		})
+		mux.HandleFunc("/", s.serveHealth)
+	}
+	var adminHandler http.Handler
+	if admin != nil && (conf.AdminPort > 0 || conf.Management != nil) {
+		adminHandler = newAdminHandler(log, admin, newAdminAuth(log, conf.AdminAuth))
+	}
+	if conf.AdminPort > 0 && adminHandler != nil {
+		addr := fmt.Sprintf(":%d", conf.AdminPort)
+		if conf.AdminAuth == nil {
+			// Unauthenticated admin API is only served locally.
+			addr = fmt.Sprintf("127.0.0.1:%d", conf.AdminPort)
+			log.Warnw("admin API is not authenticated, listening on localhost only, set admin_auth to require API keys", nil, "port", conf.AdminPort)
+		}
+		s.adminServer = &http.Server{
+			Addr:    addr,
+			Handler: adminHandler,
+		}
+	}
+	if mconf := conf.Management; mconf != nil {
+		mgmtAdmin := adminHandler
+		if adminHandler != nil && conf.AdminAuth == nil && (mconf.TLS == nil || mconf.TLS.ClientCAFile == "") {
+			log.Warnw("admin API is not authenticated, not serving it on management listener, set admin_auth or management client_ca_file", nil, "address", mconf.Address)
+			mgmtAdmin = nil
+		}
+		s.mgmtServer = &http.Server{
+			Addr:    mconf.Address,
+			Handler: s.newManagementHandler(mgmtAdmin),
+		}
 	}
 	return s
 }
 
+func registerPProf(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
+
+func (s *Service) serveHealth(w http.ResponseWriter, r *http.Request) {
+	st := s.Health()
+	var code int
+	switch st {
+	case stats.HealthOK:
+		code = http.StatusOK
+	case stats.HealthUnderLoad:
+		code = http.StatusTooManyRequests
+	default:
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(code)
+	_, _ = w.Write([]byte(st.String()))
+}
+
 func (s *Service) Stop(kill bool) {
 	s.mon.Shutdown()
 	s.shutdown.Break()
@@ -167,6 +203,28 @@ func (s *Service) Run() error {
 		}()
 	}
 
+	if srv := s.adminServer; srv != nil {
+		l, err := net.Listen("tcp", srv.Addr)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		go func() {
+			_ = srv.Serve(l)
+		}()
+	}
+
+	if srv := s.mgmtServer; srv != nil {
+		l, err := listenManagement(s.conf.Management)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		go func() {
+			_ = srv.Serve(l)
+		}()
+	}
+
 	var err error
 	if s.rpcSIPServer, err = rpc.NewSIPInternalServer(s.psrpcServer, s.bus); err != nil {
 		return err
@@ -255,5 +313,13 @@ func (s *Service) DeregisterTransferSIPParticipantTopic(sipCallId string) {
 }
 
 func (s *Service) OnSessionEnd(ctx context.Context, callIdentifier *sip.CallIdentifier, callInfo *livekit.SIPCallInfo, reason string) {
-	s.log.Infow("SIP call ended", "callID", callInfo.CallId, "reason", reason)
+	log := s.log
+	if tags := sip.CallTags(callInfo.ParticipantAttributes); len(tags) != 0 {
+		log = log.WithValues("tags", tags)
+	}
+	log.Infow("SIP call ended", "callID", callInfo.CallId, "reason", reason)
+}
+
+func (s *Service) OnDTMFMenu(ctx context.Context, callIdentifier *sip.CallIdentifier, item config.DTMFMenuItem) {
+	s.log.Infow("SIP DTMF menu sequence entered", "callID", callIdentifier.CallID, "name", item.Name, "action", item.Action)
 }
@@ -0,0 +1,481 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// Encrypted artifacts start with a header, followed by records:
+//
+//	header: "LKA1" | file ID | u16 len | project ID | u16 len | key ID | u16 len | wrapped data key
+//	record: u64 sequence | u64 offset | u32 size | nonce | sealed data
+//
+// Each record is written at the offset of the plaintext, later records overwrite earlier ones.
+// This allows seeking back, for example to update the header of a WAV file.
+// The last record has no data and marks the end of the artifact, so that truncation is detected.
+//
+// Records are authenticated together with the random file ID and their header, so that records
+// can't be dropped, reordered or moved to another artifact. The file ID is kept when the key is rewrapped.
+const (
+	artifactMagic = "LKA1"
+	// ArtifactExt is added to names of encrypted artifacts.
+	ArtifactExt = ".enc"
+
+	artifactFileID       = 16
+	artifactRecordHeader = 20
+	artifactMaxRecord    = 64 << 10
+	artifactMaxField     = 4096
+)
+
+var (
+	ErrArtifactKeyNotFound = errors.New("artifact key not found")
+	ErrArtifactTruncated   = errors.New("artifact is truncated")
+	ErrArtifactInvalid     = errors.New("artifact is not valid")
+)
+
+// KeyManager wraps data keys of artifacts with keys of projects, for example using a KMS.
+//
+// Implementations must be able to unwrap keys wrapped with previous keys of the project, to support key rotation.
+type KeyManager interface {
+	// WrapKey encrypts a data key with the current key of the project and returns the ID of that key.
+	WrapKey(ctx context.Context, projectID string, key []byte) (keyID string, wrapped []byte, err error)
+	// UnwrapKey decrypts a data key which was wrapped with the given key of the project.
+	UnwrapKey(ctx context.Context, projectID, keyID string, wrapped []byte) ([]byte, error)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type staticKey struct {
+	projectID string
+	aead      cipher.AEAD
+}
+
+// staticKeyManager wraps data keys with keys from the config.
+type staticKeyManager struct {
+	keys    map[string]staticKey
+	current map[string]string // key ID by project ID; empty project is the default
+}
+
+func newStaticKeyManager(conf *config.ArtifactEncryptionConfig) (*staticKeyManager, error) {
+	m := &staticKeyManager{
+		keys:    make(map[string]staticKey, len(conf.Keys)),
+		current: make(map[string]string),
+	}
+	for _, k := range conf.Keys {
+		key, err := k.Decode()
+		if err != nil {
+			return nil, err
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		m.keys[k.ID] = staticKey{projectID: k.ProjectID, aead: aead}
+		if _, ok := m.current[k.ProjectID]; !ok {
+			m.current[k.ProjectID] = k.ID
+		}
+	}
+	return m, nil
+}
+
+func (m *staticKeyManager) WrapKey(_ context.Context, projectID string, key []byte) (string, []byte, error) {
+	id, ok := m.current[projectID]
+	if !ok {
+		id, ok = m.current[""]
+	}
+	if !ok {
+		return "", nil, fmt.Errorf("%w: project %q", ErrArtifactKeyNotFound, projectID)
+	}
+	aead := m.keys[id].aead
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return id, aead.Seal(nonce, nonce, key, []byte(projectID)), nil
+}
+
+func (m *staticKeyManager) UnwrapKey(_ context.Context, projectID, keyID string, wrapped []byte) ([]byte, error) {
+	k, ok := m.keys[keyID]
+	if !ok || (k.projectID != "" && k.projectID != projectID) {
+		return nil, fmt.Errorf("%w: %q", ErrArtifactKeyNotFound, keyID)
+	}
+	n := k.aead.NonceSize()
+	if len(wrapped) < n {
+		return nil, ErrArtifactInvalid
+	}
+	return k.aead.Open(nil, wrapped[:n], wrapped[n:], []byte(projectID))
+}
+
+// ArtifactFile is a file with a call artifact, such as a recording.
+type ArtifactFile interface {
+	io.WriteSeeker
+	io.Closer
+}
+
+// Artifacts creates files with call artifacts. They are encrypted if a key manager is set.
+type Artifacts struct {
+	km KeyManager
+}
+
+// Encrypted tells if artifacts are encrypted.
+func (a *Artifacts) Encrypted() bool {
+	return a != nil && a.km != nil
+}
+
+// Create creates a file for an artifact of the project. The name of encrypted artifacts gets the ArtifactExt suffix.
+func (a *Artifacts) Create(ctx context.Context, projectID, path string) (ArtifactFile, error) {
+	key, err := a.newKey(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return key.Create(key.Path(path))
+}
+
+// artifactKey is a data key of an artifact, together with the header which stores it wrapped.
+// The file ID in the header is set when the file is created. It's nil if artifacts are not encrypted.
+type artifactKey struct {
+	header []byte
+	aead   cipher.AEAD
+}
+
+// newKey generates a data key for an artifact. The key is wrapped by the key manager, which may take a while,
+// so it's better done when the call is set up, not once the artifact is written.
+func (a *Artifacts) newKey(ctx context.Context, projectID string) (*artifactKey, error) {
+	if !a.Encrypted() {
+		return nil, nil
+	}
+	key := make([]byte, config.ArtifactKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := a.km.WrapKey(ctx, projectID, key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot wrap artifact key: %w", err)
+	}
+	header, err := appendArtifactHeader(nil, artifactHeader{projectID: projectID, keyID: keyID, wrapped: wrapped})
+	if err != nil {
+		return nil, err
+	}
+	return &artifactKey{header: header, aead: aead}, nil
+}
+
+// Path returns the name of the artifact file, with ArtifactExt suffix if it's encrypted.
+func (k *artifactKey) Path(path string) string {
+	if k == nil {
+		return path
+	}
+	return path + ArtifactExt
+}
+
+// Create creates the artifact file, writing the header of encrypted artifacts.
+func (k *artifactKey) Create(path string) (ArtifactFile, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return f, nil
+	}
+	header := slices.Clone(k.header)
+	fileID := header[len(artifactMagic) : len(artifactMagic)+artifactFileID]
+	_, err = rand.Read(fileID)
+	if err == nil {
+		_, err = f.Write(header)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &encryptedArtifact{f: f, aead: k.aead, fileID: fileID}, nil
+}
+
+type artifactHeader struct {
+	fileID    []byte
+	projectID string
+	keyID     string
+	wrapped   []byte
+}
+
+func appendArtifactHeader(b []byte, h artifactHeader) ([]byte, error) {
+	b = append(b, artifactMagic...)
+	if h.fileID == nil {
+		b = append(b, make([]byte, artifactFileID)...)
+	} else if len(h.fileID) != artifactFileID {
+		return nil, fmt.Errorf("invalid artifact file id size: %d", len(h.fileID))
+	} else {
+		b = append(b, h.fileID...)
+	}
+	for _, v := range [][]byte{[]byte(h.projectID), []byte(h.keyID), h.wrapped} {
+		if len(v) > artifactMaxField {
+			return nil, fmt.Errorf("artifact header field is too long: %d", len(v))
+		}
+		b = binary.BigEndian.AppendUint16(b, uint16(len(v)))
+		b = append(b, v...)
+	}
+	return b, nil
+}
+
+func readArtifactHeader(r io.Reader) (artifactHeader, error) {
+	var h artifactHeader
+	magic := make([]byte, len(artifactMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != artifactMagic {
+		return h, ErrArtifactInvalid
+	}
+	h.fileID = make([]byte, artifactFileID)
+	if _, err := io.ReadFull(r, h.fileID); err != nil {
+		return h, ErrArtifactInvalid
+	}
+	var fields [3][]byte
+	for i := range fields {
+		var n [2]byte
+		if _, err := io.ReadFull(r, n[:]); err != nil {
+			return h, ErrArtifactInvalid
+		}
+		size := binary.BigEndian.Uint16(n[:])
+		if size > artifactMaxField {
+			return h, ErrArtifactInvalid
+		}
+		fields[i] = make([]byte, size)
+		if _, err := io.ReadFull(r, fields[i]); err != nil {
+			return h, ErrArtifactInvalid
+		}
+	}
+	h.projectID, h.keyID, h.wrapped = string(fields[0]), string(fields[1]), fields[2]
+	return h, nil
+}
+
+// encryptedArtifact encrypts written data into records. Consecutive writes are buffered into a single record.
+type encryptedArtifact struct {
+	f      io.WriteCloser
+	aead   cipher.AEAD
+	fileID []byte
+	seq    uint64 // of the next record
+	off    int64  // of the next write
+	size   int64
+
+	buf    []byte
+	bufOff int64
+}
+
+func (a *encryptedArtifact) Write(p []byte) (int, error) {
+	if len(a.buf) != 0 && a.bufOff+int64(len(a.buf)) != a.off {
+		if err := a.flush(); err != nil {
+			return 0, err
+		}
+	}
+	if len(a.buf) == 0 {
+		a.bufOff = a.off
+	}
+	n := len(p)
+	for len(p) != 0 {
+		m := min(len(p), artifactMaxRecord-len(a.buf))
+		a.buf = append(a.buf, p[:m]...)
+		p = p[m:]
+		if len(a.buf) == artifactMaxRecord {
+			if err := a.flush(); err != nil {
+				return 0, err
+			}
+			a.bufOff += artifactMaxRecord
+		}
+	}
+	a.off += int64(n)
+	a.size = max(a.size, a.off)
+	return n, nil
+}
+
+func (a *encryptedArtifact) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += a.off
+	case io.SeekEnd:
+		offset += a.size
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset: %d", offset)
+	}
+	a.off = offset
+	return offset, nil
+}
+
+func (a *encryptedArtifact) flush() error {
+	if len(a.buf) == 0 {
+		return nil
+	}
+	err := a.writeRecord(a.bufOff, a.buf)
+	a.buf = a.buf[:0]
+	return err
+}
+
+func (a *encryptedArtifact) writeRecord(off int64, data []byte) error {
+	rec := make([]byte, 0, artifactRecordHeader+a.aead.NonceSize()+len(data)+a.aead.Overhead())
+	rec = binary.BigEndian.AppendUint64(rec, a.seq)
+	rec = binary.BigEndian.AppendUint64(rec, uint64(off))
+	rec = binary.BigEndian.AppendUint32(rec, uint32(len(data)))
+	hdr := rec[:artifactRecordHeader]
+	nonce := rec[artifactRecordHeader : artifactRecordHeader+a.aead.NonceSize()]
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	rec = a.aead.Seal(rec[:len(hdr)+len(nonce)], nonce, data, artifactRecordAD(a.fileID, hdr))
+	a.seq++
+	_, err := a.f.Write(rec)
+	return err
+}
+
+// artifactRecordAD returns additional data authenticated with a record.
+func artifactRecordAD(fileID, hdr []byte) []byte {
+	return append(slices.Clone(fileID), hdr...)
+}
+
+// Close writes the end of the artifact and closes the file.
+func (a *encryptedArtifact) Close() error {
+	err := a.flush()
+	if err == nil {
+		err = a.writeRecord(a.size, nil)
+	}
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// DecryptArtifact decrypts an artifact read from r and writes it to w.
+// If the artifact was not closed properly, the data which was written is still decrypted and ErrArtifactTruncated is returned.
+func DecryptArtifact(ctx context.Context, km KeyManager, r io.Reader, w io.WriterAt) error {
+	h, err := readArtifactHeader(r)
+	if err != nil {
+		return err
+	}
+	key, err := km.UnwrapKey(ctx, h.projectID, h.keyID, h.wrapped)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	var (
+		hdr   = make([]byte, artifactRecordHeader)
+		nonce = make([]byte, aead.NonceSize())
+		buf   []byte
+	)
+	for seq := uint64(0); ; seq++ {
+		if _, err := io.ReadFull(r, hdr); err == io.EOF {
+			return ErrArtifactTruncated
+		} else if err != nil {
+			return ErrArtifactInvalid
+		}
+		off := int64(binary.BigEndian.Uint64(hdr[8:]))
+		size := int(binary.BigEndian.Uint32(hdr[16:]))
+		if binary.BigEndian.Uint64(hdr) != seq || size > artifactMaxRecord || off < 0 {
+			return ErrArtifactInvalid
+		}
+		if _, err := io.ReadFull(r, nonce); err != nil {
+			return ErrArtifactTruncated
+		}
+		if n := size + aead.Overhead(); cap(buf) < n {
+			buf = make([]byte, n)
+		} else {
+			buf = buf[:n]
+		}
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return ErrArtifactTruncated
+		}
+		data, err := aead.Open(buf[:0], nonce, buf, artifactRecordAD(h.fileID, hdr))
+		if err != nil {
+			return ErrArtifactInvalid
+		}
+		if size == 0 {
+			return nil
+		}
+		if _, err := w.WriteAt(data, off); err != nil {
+			return err
+		}
+	}
+}
+
+// RewrapArtifact wraps the data key of an encrypted artifact with the current key of its project, after key rotation.
+// The data itself is not decrypted. It reports whether the file was changed.
+func RewrapArtifact(ctx context.Context, km KeyManager, path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	h, err := readArtifactHeader(f)
+	if err != nil {
+		return false, err
+	}
+	key, err := km.UnwrapKey(ctx, h.projectID, h.keyID, h.wrapped)
+	if err != nil {
+		return false, err
+	}
+	keyID, wrapped, err := km.WrapKey(ctx, h.projectID, key)
+	if err != nil {
+		return false, err
+	}
+	if keyID == h.keyID {
+		return false, nil
+	}
+	header, err := appendArtifactHeader(nil, artifactHeader{fileID: h.fileID, projectID: h.projectID, keyID: keyID, wrapped: wrapped})
+	if err != nil {
+		return false, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(header)
+	if err == nil {
+		_, err = io.Copy(tmp, f)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return false, err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
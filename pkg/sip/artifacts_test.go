@@ -0,0 +1,255 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	msdk "github.com/livekit/media-sdk"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func testArtifactKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, config.ArtifactKeySize))
+}
+
+func newTestKeyManager(t testing.TB, keys ...config.ArtifactKeyConfig) *staticKeyManager {
+	conf := &config.ArtifactEncryptionConfig{Keys: keys}
+	require.NoError(t, conf.Validate())
+	km, err := newStaticKeyManager(conf)
+	require.NoError(t, err)
+	return km
+}
+
+func decryptArtifactFile(t testing.TB, km KeyManager, path string) ([]byte, error) {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	out, err := os.Create(filepath.Join(t.TempDir(), "plain"))
+	require.NoError(t, err)
+	defer out.Close()
+	derr := DecryptArtifact(context.Background(), km, f, out)
+	data, err := os.ReadFile(out.Name())
+	require.NoError(t, err)
+	return data, derr
+}
+
+func TestArtifactEncryptionConfig(t *testing.T) {
+	for _, c := range []config.ArtifactEncryptionConfig{
+		{Keys: []config.ArtifactKeyConfig{{ID: "k1", Key: testArtifactKey(1)}}},
+		{Keys: []config.ArtifactKeyConfig{{ID: "k2", Key: testArtifactKey(2), ProjectID: "p1"}, {ID: "k1", Key: testArtifactKey(1)}}},
+	} {
+		require.NoError(t, c.Validate(), "%+v", c)
+	}
+	for _, c := range []config.ArtifactEncryptionConfig{
+		{},
+		{Keys: []config.ArtifactKeyConfig{{Key: testArtifactKey(1)}}},
+		{Keys: []config.ArtifactKeyConfig{{ID: "k1", Key: "not base64!"}}},
+		{Keys: []config.ArtifactKeyConfig{{ID: "k1", Key: base64.StdEncoding.EncodeToString([]byte("short"))}}},
+		{Keys: []config.ArtifactKeyConfig{{ID: "k1", Key: testArtifactKey(1)}, {ID: "k1", Key: testArtifactKey(2)}}},
+	} {
+		require.Error(t, c.Validate(), "%+v", c)
+	}
+}
+
+func TestStaticKeyManager(t *testing.T) {
+	ctx := context.Background()
+	km := newTestKeyManager(t,
+		config.ArtifactKeyConfig{ID: "p1-new", ProjectID: "p1", Key: testArtifactKey(3)},
+		config.ArtifactKeyConfig{ID: "p1-old", ProjectID: "p1", Key: testArtifactKey(2)},
+		config.ArtifactKeyConfig{ID: "default", Key: testArtifactKey(1)},
+	)
+	key := bytes.Repeat([]byte{7}, config.ArtifactKeySize)
+
+	id, wrapped, err := km.WrapKey(ctx, "p1", key)
+	require.NoError(t, err)
+	require.Equal(t, "p1-new", id)
+	got, err := km.UnwrapKey(ctx, "p1", id, wrapped)
+	require.NoError(t, err)
+	require.Equal(t, key, got)
+	// Wrapped keys are bound to the project.
+	_, err = km.UnwrapKey(ctx, "p2", id, wrapped)
+	require.ErrorIs(t, err, ErrArtifactKeyNotFound)
+
+	id, wrapped, err = km.WrapKey(ctx, "p2", key)
+	require.NoError(t, err)
+	require.Equal(t, "default", id)
+	_, err = km.UnwrapKey(ctx, "p1", id, wrapped)
+	require.Error(t, err)
+	got, err = km.UnwrapKey(ctx, "p2", id, wrapped)
+	require.NoError(t, err)
+	require.Equal(t, key, got)
+
+	_, err = km.UnwrapKey(ctx, "p2", "unknown", wrapped)
+	require.ErrorIs(t, err, ErrArtifactKeyNotFound)
+
+	noDefault := newTestKeyManager(t, config.ArtifactKeyConfig{ID: "p1", ProjectID: "p1", Key: testArtifactKey(1)})
+	_, _, err = noDefault.WrapKey(ctx, "p2", key)
+	require.ErrorIs(t, err, ErrArtifactKeyNotFound)
+}
+
+func TestEncryptedArtifact(t *testing.T) {
+	ctx := context.Background()
+	km := newTestKeyManager(t, config.ArtifactKeyConfig{ID: "k1", Key: testArtifactKey(1)})
+	a := &Artifacts{km: km}
+	require.True(t, a.Encrypted())
+	require.False(t, (*Artifacts)(nil).Encrypted())
+
+	path := filepath.Join(t.TempDir(), "trace.txt")
+	f, err := a.Create(ctx, "p1", path)
+	require.NoError(t, err)
+	// Larger than a single record.
+	data := bytes.Repeat([]byte("0123456789"), artifactMaxRecord/4)
+	_, err = f.Write([]byte("hdr:"))
+	require.NoError(t, err)
+	_, err = f.Write(data)
+	require.NoError(t, err)
+	_, err = f.Seek(0, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("HDR"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	enc, err := os.ReadFile(path + ArtifactExt)
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(enc, []byte("0123456789")))
+
+	plain, err := decryptArtifactFile(t, km, path+ArtifactExt)
+	require.NoError(t, err)
+	require.Equal(t, append([]byte("HDR:"), data...), plain)
+
+	// Data written before the artifact was closed can be recovered.
+	_, err = decryptArtifactFile(t, km, writeTruncated(t, path+ArtifactExt, enc, 100))
+	require.ErrorIs(t, err, ErrArtifactTruncated)
+
+	// Modified data is detected.
+	bad := bytes.Clone(enc)
+	bad[len(bad)-100] ^= 1
+	_, err = decryptArtifactFile(t, km, writeTruncated(t, path+ArtifactExt, bad, 0))
+	require.ErrorIs(t, err, ErrArtifactInvalid)
+
+	// Dropped or reordered records are detected, as well as records of another file.
+	hdr, recs := splitArtifactRecords(t, enc)
+	require.Len(t, recs, 5)
+	for _, recs := range [][][]byte{
+		{recs[0], recs[2], recs[3], recs[4]},
+		{recs[1], recs[0], recs[2], recs[3], recs[4]},
+	} {
+		_, err = decryptArtifactFile(t, km, writeTruncated(t, path+ArtifactExt, bytes.Join(append([][]byte{hdr}, recs...), nil), 0))
+		require.ErrorIs(t, err, ErrArtifactInvalid)
+	}
+	bad = bytes.Clone(enc)
+	bad[len(artifactMagic)] ^= 1
+	_, err = decryptArtifactFile(t, km, writeTruncated(t, path+ArtifactExt, bad, 0))
+	require.ErrorIs(t, err, ErrArtifactInvalid)
+
+	// Artifacts are not encrypted without a key manager.
+	f, err = (&Artifacts{}).Create(ctx, "p1", path)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("plain"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "plain", string(raw))
+}
+
+// splitArtifactRecords splits an encrypted artifact into the header and records.
+func splitArtifactRecords(t testing.TB, data []byte) ([]byte, [][]byte) {
+	r := bytes.NewReader(data)
+	_, err := readArtifactHeader(r)
+	require.NoError(t, err)
+	hdr := data[:len(data)-r.Len()]
+	data = data[len(hdr):]
+	var recs [][]byte
+	for len(data) != 0 {
+		size := artifactRecordHeader + 12 + int(binary.BigEndian.Uint32(data[16:])) + 16
+		recs = append(recs, data[:size])
+		data = data[size:]
+	}
+	return hdr, recs
+}
+
+func writeTruncated(t testing.TB, path string, data []byte, cut int) string {
+	out := path + ".copy"
+	require.NoError(t, os.WriteFile(out, data[:len(data)-cut], 0644))
+	return out
+}
+
+func TestRewrapArtifact(t *testing.T) {
+	ctx := context.Background()
+	old := newTestKeyManager(t, config.ArtifactKeyConfig{ID: "k1", Key: testArtifactKey(1)})
+	path := filepath.Join(t.TempDir(), "trace.txt")
+	f, err := (&Artifacts{km: old}).Create(ctx, "p1", path)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("secret"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	path += ArtifactExt
+
+	changed, err := RewrapArtifact(ctx, old, path)
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// Rotate the key: the new one goes first, the old one is kept until artifacts are rewrapped.
+	rotated := newTestKeyManager(t,
+		config.ArtifactKeyConfig{ID: "k2", Key: testArtifactKey(2)},
+		config.ArtifactKeyConfig{ID: "k1", Key: testArtifactKey(1)},
+	)
+	changed, err = RewrapArtifact(ctx, rotated, path)
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	onlyNew := newTestKeyManager(t, config.ArtifactKeyConfig{ID: "k2", Key: testArtifactKey(2)})
+	plain, err := decryptArtifactFile(t, onlyNew, path)
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(plain))
+	_, err = decryptArtifactFile(t, old, path)
+	require.ErrorIs(t, err, ErrArtifactKeyNotFound)
+}
+
+func TestEncryptedRecorderStage(t *testing.T) {
+	dir := t.TempDir()
+	km := newTestKeyManager(t, config.ArtifactKeyConfig{ID: "k1", Key: testArtifactKey(1)})
+	st, err := newRecorderStage(MediaStageEnv{CallID: "SCL_test", ProjectID: "p1", Artifacts: &Artifacts{km: km}}, config.MediaStageConfig{Dir: dir})
+	require.NoError(t, err)
+
+	w := st.Process(&stageTestWriter{rate: 16000})
+	require.NoError(t, w.WriteSample(msdk.PCM16Sample{1, -2, 3}))
+	require.NoError(t, st.(*recorderStage).Close())
+
+	_, err = os.Stat(filepath.Join(dir, "SCL_test.wav"))
+	require.True(t, os.IsNotExist(err))
+	data, err := decryptArtifactFile(t, km, filepath.Join(dir, "SCL_test.wav"+ArtifactExt))
+	require.NoError(t, err)
+	require.Len(t, data, wavHeaderSize+6)
+	require.Equal(t, "RIFF", string(data[0:4]))
+	require.Equal(t, uint32(len(data)-8), binary.LittleEndian.Uint32(data[4:]))
+
+	// Recording fails early if the data key can't be wrapped.
+	_, err = newRecorderStage(MediaStageEnv{CallID: "SCL_test", ProjectID: "p1", Artifacts: &Artifacts{km: newTestKeyManager(t,
+		config.ArtifactKeyConfig{ID: "k2", ProjectID: "p2", Key: testArtifactKey(2)},
+	)}}, config.MediaStageConfig{Dir: dir})
+	require.ErrorIs(t, err, ErrArtifactKeyNotFound)
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// attrUpdateTimeout limits how long a single attribute update may wait for the response.
+const attrUpdateTimeout = 10 * time.Second
+
+// headerSender sends headers to the remote in an in-dialog request.
+type headerSender func(ctx context.Context, method sip.RequestMethod, headers map[string]string) error
+
+// attrUpdater sends changes of participant attributes to the remote during the call,
+// using the attributes_to_headers mapping of the call.
+//
+// Updates are sent one at a time. Changes made while a request is in flight are merged into the next one.
+type attrUpdater struct {
+	log     logger.Logger
+	method  sip.RequestMethod
+	mapping map[string]string        // attribute to header mapping, limited to attributes which are sent
+	attrs   func() map[string]string // current attributes of the participant
+	send    headerSender
+
+	mu      sync.Mutex
+	pending map[string]struct{}
+	running bool
+	closed  bool
+}
+
+// newAttrUpdater returns an updater for the given settings, or nil if updates are disabled.
+func newAttrUpdater(log logger.Logger, conf *config.AttributeUpdatesConfig, attrToHdr map[string]string, attrs func() map[string]string, send headerSender) *attrUpdater {
+	if conf == nil || len(attrToHdr) == 0 {
+		return nil
+	}
+	mapping := attrToHdr
+	if len(conf.Attributes) != 0 {
+		mapping = make(map[string]string, len(conf.Attributes))
+		for _, a := range conf.Attributes {
+			if spec, ok := attrToHdr[a]; ok {
+				mapping[a] = spec
+			}
+		}
+		if len(mapping) == 0 {
+			log.Warnw("no attribute updates are mapped to headers", nil, "attributes", conf.Attributes)
+			return nil
+		}
+	}
+	method := sip.INFO
+	if conf.Method == config.AttributeUpdateUpdate {
+		method = sip.UPDATE
+	}
+	return &attrUpdater{
+		log:     log,
+		method:  method,
+		mapping: mapping,
+		attrs:   attrs,
+		send:    send,
+		pending: make(map[string]struct{}),
+	}
+}
+
+// OnChanged schedules an update if any of the changed attributes is mapped to a header.
+func (u *attrUpdater) OnChanged(changed map[string]string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.closed {
+		return
+	}
+	for k := range changed {
+		if _, ok := u.mapping[k]; ok {
+			u.pending[k] = struct{}{}
+		}
+	}
+	if len(u.pending) == 0 || u.running {
+		return
+	}
+	u.running = true
+	go u.run()
+}
+
+func (u *attrUpdater) run() {
+	for {
+		u.mu.Lock()
+		if u.closed || len(u.pending) == 0 {
+			u.running = false
+			u.mu.Unlock()
+			return
+		}
+		keys := u.pending
+		u.pending = make(map[string]struct{})
+		u.mu.Unlock()
+
+		u.sendUpdate(keys)
+	}
+}
+
+func (u *attrUpdater) sendUpdate(keys map[string]struct{}) {
+	mapping := make(map[string]string, len(keys))
+	for k := range keys {
+		mapping[k] = u.mapping[k]
+	}
+	// Removed attributes are not sent, there's no way to remove a header mid-call.
+	headers := AttrsToHeaders(u.attrs(), mapping, nil)
+	if len(headers) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), attrUpdateTimeout)
+	defer cancel()
+	if err := u.send(ctx, u.method, headers); err != nil {
+		u.log.Warnw("cannot send attribute update", err, "method", u.method, "headers", headers)
+		return
+	}
+	u.log.Infow("sent attribute update", "method", u.method, "headers", headers)
+}
+
+// Close stops sending updates. Requests in flight are not interrupted.
+func (u *attrUpdater) Close() {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.closed = true
+	u.pending = make(map[string]struct{})
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+type sentHeaders struct {
+	method  sip.RequestMethod
+	headers map[string]string
+}
+
+func TestAttrUpdater(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		attrs = map[string]string{"agent.name": "Alice", "agent.team": "sales", "other": "x"}
+	)
+	getAttrs := func() map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return attrs
+	}
+	sent := make(chan sentHeaders, 10)
+	release := make(chan struct{})
+	send := func(_ context.Context, method sip.RequestMethod, headers map[string]string) error {
+		sent <- sentHeaders{method: method, headers: headers}
+		<-release
+		return nil
+	}
+	mapping := map[string]string{"agent.name": "X-Agent-Name", "agent.team": "X-Agent-Team"}
+
+	require.Nil(t, newAttrUpdater(logger.GetLogger(), nil, mapping, getAttrs, send), "disabled")
+	require.Nil(t, newAttrUpdater(logger.GetLogger(), &config.AttributeUpdatesConfig{}, nil, getAttrs, send), "nothing mapped")
+	require.Nil(t, newAttrUpdater(logger.GetLogger(), &config.AttributeUpdatesConfig{Attributes: []string{"other"}}, mapping, getAttrs, send))
+
+	u := newAttrUpdater(logger.GetLogger(), &config.AttributeUpdatesConfig{Method: config.AttributeUpdateUpdate}, mapping, getAttrs, send)
+	require.NotNil(t, u)
+	t.Cleanup(u.Close)
+
+	expect := func(exp sentHeaders) {
+		t.Helper()
+		select {
+		case got := <-sent:
+			require.Equal(t, exp, got)
+		case <-time.After(time.Second):
+			t.Fatal("update not sent")
+		}
+	}
+
+	u.OnChanged(map[string]string{"other": "y"})
+	u.OnChanged(map[string]string{"agent.name": "Alice"})
+	expect(sentHeaders{method: sip.UPDATE, headers: map[string]string{"X-Agent-Name": "Alice"}})
+
+	// Changes during the request are merged into the next one.
+	mu.Lock()
+	attrs = map[string]string{"agent.name": "Bob", "agent.team": "support"}
+	mu.Unlock()
+	u.OnChanged(map[string]string{"agent.name": "Bob"})
+	u.OnChanged(map[string]string{"agent.team": "support"})
+	release <- struct{}{}
+	expect(sentHeaders{method: sip.UPDATE, headers: map[string]string{"X-Agent-Name": "Bob", "X-Agent-Team": "support"}})
+	release <- struct{}{}
+
+	u.Close()
+	u.OnChanged(map[string]string{"agent.name": "Carol"})
+	select {
+	case got := <-sent:
+		t.Fatalf("unexpected update after close: %v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAttrUpdaterSubset(t *testing.T) {
+	sent := make(chan sentHeaders, 10)
+	send := func(_ context.Context, method sip.RequestMethod, headers map[string]string) error {
+		sent <- sentHeaders{method: method, headers: headers}
+		return nil
+	}
+	attrs := map[string]string{"agent.name": "Alice", "agent.team": "sales"}
+	mapping := map[string]string{"agent.name": "X-Agent-Name", "agent.team": "X-Agent-Team"}
+	u := newAttrUpdater(logger.GetLogger(), &config.AttributeUpdatesConfig{Attributes: []string{"agent.team"}}, mapping, func() map[string]string { return attrs }, send)
+	require.NotNil(t, u)
+	t.Cleanup(u.Close)
+
+	u.OnChanged(map[string]string{"agent.name": "Alice", "agent.team": "sales"})
+	select {
+	case got := <-sent:
+		require.Equal(t, sentHeaders{method: sip.INFO, headers: map[string]string{"X-Agent-Team": "sales"}}, got)
+	case <-time.After(time.Second):
+		t.Fatal("update not sent")
+	}
+}
+
+func TestNewDialogRequest(t *testing.T) {
+	invite := sip.NewRequest(sip.INVITE, sip.Uri{User: "callee", Host: "example.com"})
+	invite.AppendHeader(&sip.ViaHeader{ProtocolName: "SIP", ProtocolVersion: "2.0", Transport: "UDP", Host: "1.1.1.1", Port: 5060, Params: sip.NewParams()})
+	invite.AppendHeader(&sip.FromHeader{Address: sip.Uri{User: "caller", Host: "example.com"}, Params: sip.NewParams()})
+	invite.AppendHeader(&sip.ToHeader{Address: sip.Uri{User: "callee", Host: "example.com"}, Params: sip.NewParams()})
+	callID := sip.CallIDHeader("call-1")
+	invite.AppendHeader(&callID)
+	invite.AppendHeader(&sip.CSeqHeader{SeqNo: 1, MethodName: sip.INVITE})
+	resp := sip.NewResponseFromRequest(invite, sip.StatusOK, "OK", nil)
+	contact := &sip.ContactHeader{Address: sip.Uri{Host: "2.2.2.2"}}
+
+	req := newDialogRequest(sip.UPDATE, invite, resp, contact)
+	require.Equal(t, sip.UPDATE, req.Method)
+	require.Equal(t, "call-1", req.CallID().Value())
+	require.Equal(t, sip.UPDATE, req.CSeq().MethodName)
+	require.EqualValues(t, 2, req.CSeq().SeqNo)
+	require.Empty(t, req.Body())
+	require.Nil(t, req.GetHeader("Refer-To"))
+
+	refer := NewReferRequest(invite, resp, contact, "sip:other@example.com", map[string]string{"X-Test": "1"})
+	require.Equal(t, sip.REFER, refer.CSeq().MethodName)
+	require.Equal(t, "sip:other@example.com", refer.GetHeader("Refer-To").Value())
+	require.Equal(t, "1", refer.GetHeader("X-Test").Value())
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"sync/atomic"
+
+	"github.com/pion/webrtc/v4/pkg/media"
+
+	msdk "github.com/livekit/media-sdk"
+	lksdk "github.com/livekit/server-sdk-go/v2"
+
+	"github.com/livekit/sip/pkg/media/vad"
+)
+
+// audioLevelTrack writes encoded audio to a local track, attaching the audio level header extension to each sample.
+// The level is measured on PCM audio before encoding, see Meter.
+type audioLevelTrack struct {
+	track *lksdk.LocalTrack
+	level atomic.Uint32
+}
+
+func newAudioLevelTrack(track *lksdk.LocalTrack) *audioLevelTrack {
+	t := &audioLevelTrack{track: track}
+	t.level.Store(vad.SilenceLevel)
+	return t
+}
+
+// Level returns the audio level of the last frame, as defined by RFC 6464.
+func (t *audioLevelTrack) Level() uint8 {
+	return uint8(t.level.Load())
+}
+
+func (t *audioLevelTrack) WriteSample(sample media.Sample) error {
+	level := t.Level()
+	return t.track.WriteSample(sample, &lksdk.SampleWriteOptions{AudioLevel: &level})
+}
+
+// Meter returns a writer that measures the audio level before passing audio to the encoder.
+func (t *audioLevelTrack) Meter(w msdk.PCM16Writer) msdk.PCM16Writer {
+	return &audioLevelWriter{t: t, w: w}
+}
+
+type audioLevelWriter struct {
+	t *audioLevelTrack
+	w msdk.PCM16Writer
+}
+
+func (w *audioLevelWriter) String() string {
+	return "AudioLevel -> " + w.w.String()
+}
+
+func (w *audioLevelWriter) SampleRate() int {
+	return w.w.SampleRate()
+}
+
+func (w *audioLevelWriter) WriteSample(sample msdk.PCM16Sample) error {
+	w.t.level.Store(uint32(vad.AudioLevel(sample)))
+	return w.w.WriteSample(sample)
+}
+
+func (w *audioLevelWriter) Close() error {
+	return w.w.Close()
+}
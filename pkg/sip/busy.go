@@ -0,0 +1,167 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+// busyPositionPlaceholder is replaced with the position of the caller in the busy queue.
+const busyPositionPlaceholder = "{position}"
+
+// busyStatus returns the status used to reject busy calls.
+func busyStatus(conf *config.BusyConfig) (sip.StatusCode, string) {
+	code, reason := sip.StatusBusyHere, conf.Reason
+	if conf.StatusCode != 0 {
+		code = sip.StatusCode(conf.StatusCode)
+	}
+	if reason == "" {
+		reason = sipStatus(code)
+	}
+	return code, reason
+}
+
+// handleBusy handles a call which was accepted by the dispatch rule, but exceeds the trunk capacity.
+// Depending on the rule, the call is rejected, forwarded to an overflow destination, or the caller is queued.
+//
+// Queued calls are answered, and get a lease once the trunk has capacity for them. The answer is returned as well.
+// It returns false if the call was not queued, or ended while waiting.
+func (c *inboundCall) handleBusy(
+	ctx context.Context,
+	disp CallDispatch,
+	priority bool,
+	runMedia func(enc livekit.SIPMediaEncryption) ([]byte, error),
+	acceptCall func(answerData []byte) (bool, error),
+) (*TrunkLease, []byte, bool, error) {
+	busy := c.s.conf.DispatchBusy(disp.DispatchRuleID)
+	if busy == nil {
+		c.log.Infow("Rejecting inbound call, trunk capacity exceeded", "priority", priority)
+		c.cc.RespondAndDrop(sip.StatusServiceUnavailable, "Trunk capacity exceeded")
+		c.close(false, callDropped, "trunk-capacity")
+		return nil, nil, false, siperrors.ErrTrunkCallLimit
+	}
+	switch busy.Action {
+	case config.BusyForward:
+		c.log.Infow("Forwarding inbound call, trunk capacity exceeded", "forwardTo", busy.ForwardTo, "priority", priority)
+		c.cc.RedirectAndDrop(busy.ForwardTo)
+		c.close(false, callDropped, "busy-forward")
+		return nil, nil, false, siperrors.ErrTrunkCallLimit
+	case config.BusyQueue:
+		if len(c.s.res.announcements[busy.MusicFile]) == 0 {
+			c.log.Warnw("Queue music is not loaded, rejecting the call", nil, "file", busy.MusicFile)
+		} else if ticket, err := c.s.capacity.Queue(c.trunkID, priority, busy.MaxSize, stats.Inbound); err == nil {
+			return c.waitInQueue(ctx, ticket, *busy, disp.MediaEncryption, runMedia, acceptCall)
+		}
+	}
+	code, reason := busyStatus(busy)
+	c.log.Infow("Rejecting inbound call, trunk capacity exceeded", "status", code, "priority", priority)
+	c.cc.RespondAndDrop(code, reason)
+	c.close(false, callDropped, "busy")
+	return nil, nil, false, siperrors.ErrTrunkCallLimit
+}
+
+// waitInQueue answers the call and plays music to the caller, until the trunk has capacity for the call.
+func (c *inboundCall) waitInQueue(
+	ctx context.Context,
+	ticket *CapacityTicket,
+	busy config.BusyConfig,
+	enc livekit.SIPMediaEncryption,
+	runMedia func(enc livekit.SIPMediaEncryption) ([]byte, error),
+	acceptCall func(answerData []byte) (bool, error),
+) (*TrunkLease, []byte, bool, error) {
+	select {
+	case l := <-ticket.Ready():
+		// Another call ended in the meantime.
+		return l, nil, true, nil
+	default:
+	}
+	answerData, err := runMedia(enc)
+	if err != nil {
+		ticket.Cancel()
+		return nil, nil, false, err // already sent a response
+	}
+	if ok, err := acceptCall(answerData); !ok {
+		ticket.Cancel()
+		return nil, nil, false, err // could be success if the caller hung up
+	}
+	maxWait := busy.MaxWait
+	if maxWait <= 0 {
+		maxWait = config.DefaultBusyQueueMaxWait
+	}
+	start := time.Now()
+	c.log.Infow("Caller queued, trunk capacity exceeded", "position", ticket.Position(), "maxWait", maxWait)
+	c.cc.history.SetTimer(timerBusyQueue, start.Add(maxWait))
+	defer c.cc.history.ClearTimer(timerBusyQueue)
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	playCtx, stopPlay := context.WithCancel(ctx)
+	played := make(chan struct{})
+	go func() {
+		defer close(played)
+		c.playQueue(playCtx, ticket, busy)
+	}()
+	// The music must stop before the caller joins the room.
+	defer func() {
+		stopPlay()
+		<-played
+	}()
+
+	select {
+	case l := <-ticket.Ready():
+		c.log.Infow("Caller left the queue", "waited", time.Since(start))
+		return l, answerData, true, nil
+	case <-ctx.Done():
+		ticket.Cancel()
+		c.closeWithHangup()
+		return nil, nil, false, nil
+	case <-c.media.Timeout():
+		ticket.Cancel()
+		c.closeWithTimeout()
+		return nil, nil, false, psrpc.NewErrorf(psrpc.DeadlineExceeded, "media timeout")
+	case <-timer.C:
+		ticket.Cancel()
+		c.log.Infow("Hanging up queued call, trunk capacity is still exceeded", "waited", maxWait)
+		c.close(false, callDropped, "busy-queue-timeout")
+		return nil, nil, false, siperrors.ErrTrunkCallLimit
+	}
+}
+
+// playQueue plays music to a queued caller in a loop. The position in the queue is announced before each loop, if configured.
+func (c *inboundCall) playQueue(ctx context.Context, ticket *CapacityTicket, busy config.BusyConfig) {
+	music := c.s.res.announcements[busy.MusicFile]
+	for ctx.Err() == nil && !c.done.Load() {
+		if pos := ticket.Position(); pos > 0 && busy.PositionText != "" && c.s.tts != nil {
+			text := strings.ReplaceAll(busy.PositionText, busyPositionPlaceholder, strconv.Itoa(pos))
+			if frames, err := c.s.tts.Synthesize(ctx, text, VoiceOptions{}); err != nil {
+				c.log.Warnw("Cannot synthesize queue position", err)
+			} else {
+				c.playAudio(ctx, frames)
+			}
+		}
+		c.playAudio(ctx, music)
+	}
+}
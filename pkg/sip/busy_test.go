@@ -0,0 +1,57 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestBusyConfig(t *testing.T) {
+	for _, c := range []config.BusyConfig{
+		{Action: config.BusyReject},
+		{Action: config.BusyReject, StatusCode: 600},
+		{Action: config.BusyQueue, MusicFile: "hold.ogg", MaxSize: 10},
+		{Action: config.BusyForward, ForwardTo: "sip:overflow@example.com"},
+	} {
+		require.NoError(t, c.Validate(), "%+v", c)
+	}
+	for _, c := range []config.BusyConfig{
+		{},
+		{Action: "drop"},
+		{Action: config.BusyReject, StatusCode: 200},
+		{Action: config.BusyQueue},
+		{Action: config.BusyQueue, MusicFile: "hold.ogg", MaxSize: -1},
+		{Action: config.BusyForward},
+	} {
+		require.Error(t, c.Validate(), "%+v", c)
+	}
+
+}
+
+func TestBusyStatus(t *testing.T) {
+	code, reason := busyStatus(&config.BusyConfig{Action: config.BusyReject})
+	require.Equal(t, sip.StatusBusyHere, code)
+	require.Equal(t, sipStatus(sip.StatusBusyHere), reason)
+
+	code, reason = busyStatus(&config.BusyConfig{Action: config.BusyReject, StatusCode: 600, Reason: "Busy Everywhere"})
+	require.Equal(t, sip.StatusCode(600), code)
+	require.Equal(t, "Busy Everywhere", reason)
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/livekit/media-sdk/sdp"
+	psdp "github.com/pion/sdp/v3"
+)
+
+// qualityAttrInterval is how often AttrSIPQuality is refreshed during the call.
+const qualityAttrInterval = 10 * time.Second
+
+const (
+	holdStateActive = "active"
+	holdStateHeld   = "held"
+)
+
+const (
+	transferStateInProgress = "in-progress"
+	transferStateCompleted  = "completed"
+	transferStateFailed     = "failed"
+)
+
+func transferStateAttrs(state string) map[string]string {
+	return map[string]string{AttrSIPTransferState: state}
+}
+
+func transferResultAttrs(err error) map[string]string {
+	if err != nil {
+		return transferStateAttrs(transferStateFailed)
+	}
+	return transferStateAttrs(transferStateCompleted)
+}
+
+// holdStateAttrs returns participant attributes for the hold state of the SDP offer, or nil if it's unknown.
+func holdStateAttrs(offer []byte) map[string]string {
+	st := sdpHoldState(offer)
+	if st == "" {
+		return nil
+	}
+	return map[string]string{AttrSIPHoldState: st}
+}
+
+// sdpHoldState checks if the SDP offer puts the call on hold (RFC 3264, section 8.4).
+// It returns an empty string if the offer cannot be parsed.
+func sdpHoldState(offer []byte) string {
+	var s psdp.SessionDescription
+	if err := s.Unmarshal(offer); err != nil {
+		return ""
+	}
+	audio := sdp.GetAudio(&s)
+	if audio == nil {
+		return ""
+	}
+	if isHoldDirection(sdpMediaDirection(&s, audio)) {
+		return holdStateHeld
+	}
+	return holdStateActive
+}
+
+// attrRequests drives SIP actions from participant attributes set by other parties.
+type attrRequests struct {
+	Hangup func()
+	Mute   func(muted bool)
+	// Update receives all changes, for example to send them to the remote.
+	Update func(changed map[string]string)
+}
+
+// OnChanged handles attribute changes of the SIP participant. Removed attributes are reported with empty values.
+func (h *attrRequests) OnChanged(changed map[string]string) {
+	if v, ok := changed[AttrSIPRequestMute]; ok && h.Mute != nil {
+		h.Mute(v == "true")
+	}
+	if changed[AttrSIPRequestHangup] == "true" && h.Hangup != nil {
+		// Closing the room from its own callback may block, so hang up asynchronously.
+		go h.Hangup()
+	}
+	if h.Update != nil {
+		h.Update(changed)
+	}
+}
+
+// syncQualityAttr periodically publishes estimated call quality to participant attributes, until done is closed.
+func syncQualityAttr(done <-chan struct{}, media *MediaPort, room *Room) {
+	ticker := time.NewTicker(qualityAttrInterval)
+	defer ticker.Stop()
+	last := ""
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		m := media.VoIPMetrics()
+		if m.Expected == 0 || m.MOS == 0 {
+			continue
+		}
+		v := strconv.FormatFloat(m.MOS, 'f', 1, 64)
+		if v == last {
+			continue
+		}
+		last = v
+		room.SetAttributes(map[string]string{AttrSIPQuality: v})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/livekit/media-sdk/sdp"
+	psdp "github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSDPHoldState(t *testing.T) {
+	offer := func(ip string, sessAttr, mediaAttr string) []byte {
+		o, err := sdp.NewOffer(netip.MustParseAddr(ip), 10000, sdp.EncryptionNone)
+		require.NoError(t, err)
+		s := o.SDP
+		if sessAttr != "" {
+			s.Attributes = append(s.Attributes, psdp.NewPropertyAttribute(sessAttr))
+		}
+		audio := sdp.GetAudio(&s)
+		audio.Attributes = slices.DeleteFunc(audio.Attributes, func(a psdp.Attribute) bool {
+			return sdpDirection([]psdp.Attribute{a}) != ""
+		})
+		if mediaAttr != "" {
+			audio.Attributes = append(audio.Attributes, psdp.NewPropertyAttribute(mediaAttr))
+		}
+		data, err := s.Marshal()
+		require.NoError(t, err)
+		return data
+	}
+	cases := []struct {
+		name  string
+		offer []byte
+		exp   string
+	}{
+		{name: "default", offer: offer("10.0.0.1", "", ""), exp: holdStateActive},
+		{name: "sendrecv", offer: offer("10.0.0.1", "", "sendrecv"), exp: holdStateActive},
+		{name: "sendonly", offer: offer("10.0.0.1", "", "sendonly"), exp: holdStateHeld},
+		{name: "inactive", offer: offer("10.0.0.1", "", "inactive"), exp: holdStateHeld},
+		{name: "session sendonly", offer: offer("10.0.0.1", "sendonly", ""), exp: holdStateHeld},
+		{name: "media overrides session", offer: offer("10.0.0.1", "sendonly", "sendrecv"), exp: holdStateActive},
+		{name: "legacy", offer: offer("0.0.0.0", "", ""), exp: holdStateHeld},
+		{name: "invalid", offer: []byte("invalid"), exp: ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.exp, sdpHoldState(c.offer))
+		})
+	}
+}
+
+func TestAttrRequests(t *testing.T) {
+	var muted []bool
+	hangup := make(chan struct{}, 1)
+	h := &attrRequests{
+		Hangup: func() { hangup <- struct{}{} },
+		Mute:   func(v bool) { muted = append(muted, v) },
+	}
+	// Own attribute updates must not trigger any actions.
+	h.OnChanged(map[string]string{AttrSIPMediaState: "ok"})
+	h.OnChanged(map[string]string{AttrSIPRequestMute: "true"})
+	h.OnChanged(map[string]string{AttrSIPRequestMute: ""})
+	require.Equal(t, []bool{true, false}, muted)
+	select {
+	case <-hangup:
+		t.Fatal("unexpected hangup")
+	default:
+	}
+
+	h.OnChanged(map[string]string{AttrSIPRequestHangup: "true"})
+	select {
+	case <-hangup:
+	case <-time.After(time.Second):
+		t.Fatal("no hangup")
+	}
+}
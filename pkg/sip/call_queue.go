@@ -0,0 +1,468 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/media/tonegen"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+const (
+	// callQueueETAPlaceholder is replaced with the estimated wait time in minutes.
+	callQueueETAPlaceholder = "{eta}"
+	// callQueueStatsInterval is how often queue metrics are refreshed while callers wait.
+	callQueueStatsInterval = 5 * time.Second
+)
+
+// CallQueues holds answered inbound calls until the handler signals that an agent is ready for them.
+type CallQueues struct {
+	log logger.Logger
+	mon *stats.Monitor
+
+	mu     sync.Mutex
+	queues map[string]*callQueue
+}
+
+// callQueue is a single named queue. It's kept after it becomes empty, to estimate wait times of the next callers.
+type callQueue struct {
+	calls []*QueuedCall
+	// interval is a moving average of the time between released calls.
+	interval    time.Duration
+	lastRelease time.Time
+}
+
+func NewCallQueues(log logger.Logger, mon *stats.Monitor) *CallQueues {
+	return &CallQueues{
+		log:    log,
+		mon:    mon,
+		queues: make(map[string]*callQueue),
+	}
+}
+
+// Enqueue places a call at the end of the queue. Zero maxSize means the queue is not limited.
+//
+// The call must leave the queue when it stops waiting, even if it was released.
+func (q *CallQueues) Enqueue(queue, callID string, maxSize int) (*QueuedCall, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	cq := q.queues[queue]
+	if cq == nil {
+		cq = &callQueue{}
+		q.queues[queue] = cq
+	}
+	if n := len(cq.calls); maxSize > 0 && n >= maxSize {
+		q.mon.PolicyRejected(stats.Inbound, "call-queue")
+		q.log.Infow("call queue is full", "queue", queue, "callID", callID, "queued", n)
+		return nil, siperrors.ErrCallQueueFull
+	}
+	c := &QueuedCall{
+		q:      q,
+		queue:  queue,
+		callID: callID,
+		joined: time.Now(),
+		ready:  make(chan struct{}),
+	}
+	cq.calls = append(cq.calls, c)
+	q.reportLocked(queue, cq)
+	return c, nil
+}
+
+// Release signals that agents are ready for calls in the queue. A specific call is released if callID is set,
+// otherwise count calls which wait the longest are released. It returns IDs of released calls,
+// and separately IDs of released callback requests.
+func (q *CallQueues) Release(queue, callID string, count int) (ids, callbacks []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	cq := q.queues[queue]
+	if cq == nil {
+		return nil, nil
+	}
+	var released []*QueuedCall
+	if callID != "" {
+		if i := slices.IndexFunc(cq.calls, func(c *QueuedCall) bool { return c.callID == callID }); i >= 0 {
+			released = append(released, cq.calls[i])
+			cq.calls = slices.Delete(cq.calls, i, i+1)
+		}
+	} else {
+		n := min(count, len(cq.calls))
+		released = slices.Clone(cq.calls[:n])
+		cq.calls = slices.Delete(cq.calls, 0, n)
+	}
+	if len(released) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	ids = make([]string, 0, len(released))
+	for _, c := range released {
+		// The caller waited at the head of the queue since the previous release, or since it was queued.
+		sample := now.Sub(c.joined)
+		if c.joined.Before(cq.lastRelease) {
+			sample = now.Sub(cq.lastRelease)
+		}
+		if cq.interval == 0 {
+			cq.interval = sample
+		} else {
+			cq.interval = (cq.interval*7 + sample*3) / 10
+		}
+		cq.lastRelease = now
+		close(c.ready)
+		ids = append(ids, c.callID)
+		if c.callback {
+			callbacks = append(callbacks, c.callID)
+		}
+	}
+	q.reportLocked(queue, cq)
+	q.log.Infow("released calls from the call queue", "queue", queue, "calls", ids, "callbacks", callbacks, "queued", len(cq.calls))
+	return ids, callbacks
+}
+
+// Len returns the number of calls waiting in the queue.
+func (q *CallQueues) Len(queue string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if cq := q.queues[queue]; cq != nil {
+		return len(cq.calls)
+	}
+	return 0
+}
+
+func (q *CallQueues) reportLocked(queue string, cq *callQueue) {
+	var oldest time.Duration
+	if len(cq.calls) != 0 {
+		oldest = time.Since(cq.calls[0].joined)
+	}
+	q.mon.CallQueueStats(queue, len(cq.calls), oldest)
+}
+
+// CallQueueInfo is the state of a call queue.
+type CallQueueInfo struct {
+	Name  string           `json:"name"`
+	Calls []QueuedCallInfo `json:"calls"`
+	// Interval is the average time between released calls. It's zero if no calls were released yet.
+	Interval time.Duration `json:"interval"`
+}
+
+// QueuedCallInfo is a call waiting in a call queue.
+type QueuedCallInfo struct {
+	CallID   string        `json:"call_id"`
+	Position int           `json:"position"`
+	Waited   time.Duration `json:"waited"`
+	// ETA is the estimated remaining wait time. It's zero if it can't be estimated yet.
+	ETA time.Duration `json:"eta"`
+	// Callback is set if the caller hung up and waits for a callback.
+	Callback bool `json:"callback"`
+}
+
+// Info returns the state of all call queues, sorted by name.
+func (q *CallQueues) Info() []CallQueueInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	out := make([]CallQueueInfo, 0, len(q.queues))
+	for name, cq := range q.queues {
+		info := CallQueueInfo{Name: name, Interval: cq.interval, Calls: make([]QueuedCallInfo, 0, len(cq.calls))}
+		for i, c := range cq.calls {
+			info.Calls = append(info.Calls, QueuedCallInfo{
+				CallID:   c.callID,
+				Position: i + 1,
+				Waited:   now.Sub(c.joined),
+				ETA:      cq.eta(i+1, now),
+				Callback: c.callback,
+			})
+		}
+		out = append(out, info)
+	}
+	slices.SortFunc(out, func(a, b CallQueueInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return out
+}
+
+// eta estimates the remaining wait time of a call at a given position, from the time between previous releases.
+func (cq *callQueue) eta(pos int, now time.Time) time.Duration {
+	if cq.interval <= 0 || pos <= 0 {
+		return 0
+	}
+	eta := time.Duration(pos) * cq.interval
+	if !cq.lastRelease.IsZero() {
+		// The head of the queue already waited for a part of the interval.
+		eta -= min(now.Sub(cq.lastRelease), cq.interval-time.Second)
+	}
+	return eta
+}
+
+// QueuedCall is a place of a call in a call queue.
+type QueuedCall struct {
+	q      *CallQueues
+	queue  string
+	callID string
+	joined time.Time
+	ready  chan struct{}
+
+	callback bool // guarded by CallQueues.mu
+}
+
+// Ready is closed once the handler releases the call from the queue.
+func (c *QueuedCall) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// Position returns the 1-based position of the call in the queue, or zero if it's not queued anymore.
+func (c *QueuedCall) Position() int {
+	c.q.mu.Lock()
+	defer c.q.mu.Unlock()
+	return slices.Index(c.q.queues[c.queue].calls, c) + 1
+}
+
+// ETA returns the estimated remaining wait time of the call, or zero if it can't be estimated yet.
+func (c *QueuedCall) ETA() time.Duration {
+	c.q.mu.Lock()
+	defer c.q.mu.Unlock()
+	cq := c.q.queues[c.queue]
+	return cq.eta(slices.Index(cq.calls, c)+1, time.Now())
+}
+
+// SetCallback marks the call as a callback request. The caller hung up, but keeps the place in the queue.
+func (c *QueuedCall) SetCallback() {
+	c.q.mu.Lock()
+	defer c.q.mu.Unlock()
+	c.callback = true
+}
+
+// Report refreshes metrics of the queue, so that the wait time of the oldest caller is up to date.
+func (c *QueuedCall) Report() {
+	c.q.mu.Lock()
+	defer c.q.mu.Unlock()
+	c.q.reportLocked(c.queue, c.q.queues[c.queue])
+}
+
+// Leave removes the call from the queue and records the outcome. It returns the time the call waited.
+func (c *QueuedCall) Leave(outcome stats.CallQueueOutcome) time.Duration {
+	waited := time.Since(c.joined)
+	c.q.mu.Lock()
+	cq := c.q.queues[c.queue]
+	if i := slices.Index(cq.calls, c); i >= 0 {
+		cq.calls = slices.Delete(cq.calls, i, i+1)
+		c.q.reportLocked(c.queue, cq)
+	}
+	c.q.mu.Unlock()
+	c.q.mon.CallQueueLeft(c.queue, outcome, waited)
+	return waited
+}
+
+// callQueueText returns the announcement for a queued caller. The ETA text is only added if the wait time is known,
+// and the callback offer only if callbacks are enabled.
+func callQueueText(conf config.CallQueueConfig, pos int, eta time.Duration, callback bool) string {
+	var parts []string
+	if conf.PositionText != "" {
+		parts = append(parts, strings.ReplaceAll(conf.PositionText, busyPositionPlaceholder, strconv.Itoa(pos)))
+	}
+	if conf.ETAText != "" && eta > 0 {
+		minutes := int(math.Ceil(eta.Minutes()))
+		parts = append(parts, strings.ReplaceAll(conf.ETAText, callQueueETAPlaceholder, strconv.Itoa(minutes)))
+	}
+	if callback && conf.Callback != nil && conf.Callback.OfferText != "" {
+		parts = append(parts, conf.Callback.OfferText)
+	}
+	return strings.Join(parts, " ")
+}
+
+// waitInCallQueue holds the call in the queue with music, until the handler releases it.
+// The call is answered with acceptCall, unless it was already answered.
+// It returns false if the call ended while waiting, including when the caller requested a callback.
+func (c *inboundCall) waitInCallQueue(
+	ctx context.Context,
+	disp CallDispatch,
+	conf config.CallQueueConfig,
+	answered bool,
+	acceptCall func() (bool, error),
+) (bool, error) {
+	if len(c.s.res.announcements[conf.MusicFile]) == 0 {
+		c.log.Warnw("Call queue music is not loaded", nil, "file", conf.MusicFile)
+		if answered {
+			c.playFailTone(ctx, tonegen.Reorder)
+		} else {
+			c.cc.RespondAndDrop(sip.StatusServiceUnavailable, "Call queue unavailable")
+		}
+		c.close(true, callDropped, "call-queue-failed")
+		return false, psrpc.NewErrorf(psrpc.Unavailable, "call queue music is not loaded")
+	}
+	qc, err := c.s.queues.Enqueue(conf.Name, string(c.cc.ID()), conf.MaxSize)
+	if err != nil {
+		c.log.Infow("Call queue is full", "queue", conf.Name, "answered", answered)
+		if answered {
+			c.playFailTone(ctx, tonegen.Busy)
+		} else {
+			c.cc.RespondAndDrop(sip.StatusBusyHere, "Call queue full")
+		}
+		c.close(false, callDropped, "call-queue-full")
+		return false, err
+	}
+	if !answered {
+		if ok, err := acceptCall(); !ok {
+			qc.Leave(stats.CallQueueAbandoned)
+			return false, err // could be success if the caller hung up
+		}
+	}
+	maxWait := conf.MaxWait
+	if maxWait <= 0 {
+		maxWait = config.DefaultCallQueueMaxWait
+	}
+	c.log.Infow("Caller queued, waiting for an agent", "queue", conf.Name, "position", qc.Position(), "maxWait", maxWait)
+	c.cc.history.SetTimer(timerCallQueue, time.Now().Add(maxWait))
+	defer c.cc.history.ClearTimer(timerCallQueue)
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(callQueueStatsInterval)
+	defer ticker.Stop()
+	callback := conf.Callback
+	if c.s.dial == nil {
+		callback = nil
+	}
+
+	playCtx, stopPlay := context.WithCancel(ctx)
+	played := make(chan struct{})
+	go func() {
+		defer close(played)
+		c.playCallQueue(playCtx, qc, conf, callback != nil)
+	}()
+	// The music must stop before the caller joins the room.
+	defer func() {
+		stopPlay()
+		<-played
+	}()
+
+	for {
+		select {
+		case <-qc.Ready():
+			waited := qc.Leave(stats.CallQueueReleased)
+			c.log.Infow("Caller released from the call queue", "queue", conf.Name, "waited", waited)
+			return true, nil
+		case <-ticker.C:
+			qc.Report()
+		case ev := <-c.dtmf:
+			if callback == nil || ev.Digit != callback.Digit[0] {
+				continue
+			}
+			stopPlay()
+			<-played
+			c.requestCallback(ctx, qc, disp, *callback)
+			return false, nil
+		case <-ctx.Done():
+			waited := qc.Leave(stats.CallQueueAbandoned)
+			c.log.Infow("Caller abandoned the call queue", "queue", conf.Name, "waited", waited)
+			c.closeWithHangup()
+			return false, nil
+		case <-c.media.Timeout():
+			waited := qc.Leave(stats.CallQueueAbandoned)
+			c.log.Infow("Caller abandoned the call queue, media timeout", "queue", conf.Name, "waited", waited)
+			c.closeWithTimeout()
+			return false, psrpc.NewErrorf(psrpc.DeadlineExceeded, "media timeout")
+		case <-timer.C:
+			qc.Leave(stats.CallQueueTimeout)
+			c.log.Infow("Hanging up queued call, no agent was ready", "queue", conf.Name, "waited", maxWait)
+			c.close(false, callDropped, "call-queue-timeout")
+			return false, nil
+		}
+	}
+}
+
+// playCallQueue plays music to a queued caller in a loop, interrupted by periodic announcements of the position and wait time.
+func (c *inboundCall) playCallQueue(ctx context.Context, qc *QueuedCall, conf config.CallQueueConfig, callback bool) {
+	music := c.s.res.announcements[conf.MusicFile]
+	interval := conf.AnnounceInterval
+	if interval <= 0 {
+		interval = config.DefaultCallQueueAnnounceInterval
+	}
+	for ctx.Err() == nil && !c.done.Load() {
+		if pos := qc.Position(); pos > 0 && c.s.tts != nil {
+			if text := callQueueText(conf, pos, qc.ETA(), callback); text != "" {
+				if frames, err := c.s.tts.Synthesize(ctx, text, VoiceOptions{}); err != nil {
+					c.log.Warnw("Cannot synthesize call queue announcement", err)
+				} else {
+					c.playAudio(ctx, frames)
+				}
+			}
+		}
+		musicCtx, cancel := context.WithTimeout(ctx, interval)
+		for musicCtx.Err() == nil && !c.done.Load() {
+			c.playAudio(musicCtx, music)
+		}
+		cancel()
+	}
+}
+
+// AgentReadyRequest signals that agents are ready to take calls from a call queue.
+type AgentReadyRequest struct {
+	Queue string `json:"queue"`
+	// CallID selects a specific call in the queue. Otherwise, calls which wait the longest are released.
+	CallID string `json:"call_id"`
+	// Count is the number of calls to release, if CallID is not set. Default is 1.
+	Count int `json:"count"`
+}
+
+// AgentReadyResponse lists calls released from the queue.
+type AgentReadyResponse struct {
+	// Released lists IDs of calls which join the room.
+	Released []string `json:"released"`
+	// Callbacks lists IDs of released calls which requested a callback. They join the room once the callback is answered.
+	Callbacks []string `json:"callbacks,omitempty"`
+	// Queued is the number of calls still waiting in the queue.
+	Queued int `json:"queued"`
+}
+
+// AgentReady releases queued calls into their rooms.
+func (s *Service) AgentReady(ctx context.Context, req *AgentReadyRequest) (*AgentReadyResponse, error) {
+	if req.Queue == "" {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "queue is required")
+	}
+	if req.Count < 0 {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "count must not be negative")
+	}
+	count := req.Count
+	if count == 0 {
+		count = 1
+	}
+	released, callbacks := s.srv.queues.Release(req.Queue, req.CallID, count)
+	if req.CallID != "" && len(released) == 0 {
+		return nil, psrpc.NewErrorf(psrpc.NotFound, "call %q is not queued in %q", req.CallID, req.Queue)
+	}
+	return &AgentReadyResponse{Released: released, Callbacks: callbacks, Queued: s.srv.queues.Len(req.Queue)}, nil
+}
+
+// CallQueueList is the state of call queues.
+type CallQueueList struct {
+	Time   time.Time       `json:"time"`
+	Queues []CallQueueInfo `json:"queues"`
+}
+
+// CallQueues lists call queues with the position, wait time and estimated remaining wait time of each call.
+func (s *Service) CallQueues(ctx context.Context) (*CallQueueList, error) {
+	return &CallQueueList{Time: time.Now(), Queues: s.srv.queues.Info()}, nil
+}
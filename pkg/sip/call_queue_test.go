@@ -0,0 +1,154 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+func isReady(c *QueuedCall) bool {
+	select {
+	case <-c.Ready():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestCallQueues(t *testing.T) {
+	q := NewCallQueues(logger.GetLogger(), nil)
+
+	a, err := q.Enqueue("sales", "a", 3)
+	require.NoError(t, err)
+	b, err := q.Enqueue("sales", "b", 3)
+	require.NoError(t, err)
+	c, err := q.Enqueue("sales", "c", 3)
+	require.NoError(t, err)
+	_, err = q.Enqueue("sales", "d", 3)
+	require.ErrorIs(t, err, siperrors.ErrCallQueueFull)
+	// Other queues are not affected.
+	s, err := q.Enqueue("support", "s", 3)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, a.Position())
+	require.Equal(t, 3, c.Position())
+	// Nothing was released yet.
+	require.Zero(t, b.ETA())
+
+	ids, callbacks := q.Release("sales", "b", 0)
+	require.Equal(t, []string{"b"}, ids)
+	require.Empty(t, callbacks)
+	require.True(t, isReady(b))
+	require.False(t, isReady(a))
+	require.Equal(t, 2, c.Position())
+	require.Zero(t, b.Position())
+	require.Positive(t, c.ETA())
+	require.Greater(t, c.ETA(), a.ETA())
+
+	// Abandoned calls leave the queue.
+	require.Positive(t, a.Leave(stats.CallQueueAbandoned))
+	require.Equal(t, 1, c.Position())
+	// Released calls leave the queue as well, which is a no-op.
+	b.Leave(stats.CallQueueReleased)
+
+	ids, _ = q.Release("sales", "b", 0)
+	require.Nil(t, ids)
+	// The caller hung up, but keeps the place for a callback.
+	c.SetCallback()
+	ids, callbacks = q.Release("sales", "", 5)
+	require.Equal(t, []string{"c"}, ids)
+	require.Equal(t, []string{"c"}, callbacks)
+	require.True(t, isReady(c))
+	require.Zero(t, q.Len("sales"))
+	ids, _ = q.Release("unknown", "", 1)
+	require.Nil(t, ids)
+
+	info := q.Info()
+	require.Len(t, info, 2)
+	require.Equal(t, "sales", info[0].Name)
+	require.Empty(t, info[0].Calls)
+	require.Positive(t, info[0].Interval)
+	require.Equal(t, "support", info[1].Name)
+	require.Len(t, info[1].Calls, 1)
+	require.Equal(t, "s", info[1].Calls[0].CallID)
+	require.Equal(t, 1, info[1].Calls[0].Position)
+	require.Zero(t, info[1].Calls[0].ETA)
+	s.Leave(stats.CallQueueTimeout)
+}
+
+func TestCallQueueETA(t *testing.T) {
+	now := time.Now()
+	cq := &callQueue{interval: time.Minute, lastRelease: now.Add(-20 * time.Second)}
+	require.Equal(t, 40*time.Second, cq.eta(1, now))
+	require.Equal(t, 100*time.Second, cq.eta(2, now))
+	// The estimate doesn't drop to zero if the next release is late.
+	require.Equal(t, time.Second, cq.eta(1, now.Add(time.Hour)))
+	require.Zero(t, cq.eta(0, now))
+	require.Zero(t, (&callQueue{}).eta(1, now))
+}
+
+func TestCallQueueText(t *testing.T) {
+	conf := config.CallQueueConfig{
+		PositionText: "You are caller number {position}.",
+		ETAText:      "Expected wait is {eta} minutes.",
+	}
+	require.Equal(t, "You are caller number 2.", callQueueText(conf, 2, 0, false))
+	require.Equal(t, "You are caller number 2. Expected wait is 3 minutes.", callQueueText(conf, 2, 150*time.Second, false))
+	require.Equal(t, "Expected wait is 1 minutes.", callQueueText(config.CallQueueConfig{ETAText: conf.ETAText}, 1, time.Second, false))
+	require.Empty(t, callQueueText(config.CallQueueConfig{}, 1, time.Minute, false))
+
+	conf.Callback = &config.CallbackConfig{Digit: "1", OfferText: "Press 1 to be called back."}
+	require.Equal(t, "You are caller number 2. Press 1 to be called back.", callQueueText(conf, 2, 0, true))
+	// The offer is not played if callbacks can't be placed.
+	require.Equal(t, "You are caller number 2.", callQueueText(conf, 2, 0, false))
+}
+
+func TestCallQueueConfig(t *testing.T) {
+	for _, c := range []config.CallQueueConfig{
+		{MusicFile: "hold.ogg"},
+		{MusicFile: "hold.ogg", Name: "sales", MaxSize: 10, MaxWait: time.Minute, AnnounceInterval: time.Minute},
+	} {
+		require.NoError(t, c.Validate(), "%+v", c)
+	}
+	for _, c := range []config.CallQueueConfig{
+		{},
+		{MusicFile: "hold.ogg", MaxSize: -1},
+		{MusicFile: "hold.ogg", MaxWait: -time.Second},
+		{MusicFile: "hold.ogg", AnnounceInterval: time.Second},
+	} {
+		require.Error(t, c.Validate(), "%+v", c)
+	}
+
+	conf := &config.Config{DispatchRules: map[string]*config.DispatchRuleConfig{
+		"SDR_1": {Queue: &config.CallQueueConfig{MusicFile: "hold.ogg"}},
+		"SDR_2": {Queue: &config.CallQueueConfig{MusicFile: "hold.ogg", Name: "sales"}},
+		"SDR_3": {},
+	}}
+	require.Equal(t, "SDR_1", conf.DispatchQueue("SDR_1").Name)
+	require.Equal(t, "sales", conf.DispatchQueue("SDR_2").Name)
+	require.Nil(t, conf.DispatchQueue("SDR_3"))
+	require.Nil(t, conf.DispatchQueue("SDR_4"))
+	// The rule config is not modified.
+	require.Empty(t, conf.DispatchRules["SDR_1"].Queue.Name)
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+// callbackParams identify the caller and the room of a queued call, which is dialed back.
+type callbackParams struct {
+	CallID    string
+	Queue     string
+	ProjectID string
+	Caller    string // number of the caller, dialed by the callback
+	Called    string // number dialed by the caller, used as the caller ID of the callback by default
+	Room      RoomConfig
+}
+
+// callbackJob creates a dial job which calls the caller back and bridges the call to the room.
+func callbackJob(conf config.CallbackConfig, p callbackParams) DialJob {
+	number := conf.Number
+	if number == "" {
+		number = p.Called
+	}
+	attrs := maps.Clone(p.Room.Participant.Attributes)
+	if attrs == nil {
+		attrs = make(map[string]string)
+	}
+	attrs[AttrSIPCallbackCallID] = p.CallID
+	return DialJob{
+		Call: &rpc.InternalCreateSIPParticipantRequest{
+			ProjectId:             p.ProjectID,
+			SipTrunkId:            conf.TrunkID,
+			Address:               conf.Address,
+			Transport:             SIPTransportFrom(Transport(conf.Transport)),
+			Number:                number,
+			CallTo:                p.Caller,
+			Username:              conf.Username,
+			Password:              conf.Password,
+			RoomName:              p.Room.RoomName,
+			ParticipantIdentity:   p.Room.Participant.Identity,
+			ParticipantName:       p.Room.Participant.Name,
+			ParticipantMetadata:   p.Room.Participant.Metadata,
+			ParticipantAttributes: attrs,
+			Token:                 p.Room.Token,
+			WsUrl:                 p.Room.WsUrl,
+		},
+		MaxAttempts:     min(conf.MaxAttempts, maxDialAttempts),
+		RetryIntervalMs: int(conf.RetryInterval / time.Millisecond),
+		Metadata: map[string]string{
+			"queue":   p.Queue,
+			"call_id": p.CallID,
+		},
+	}
+}
+
+// requestCallback ends a queued call, keeping the place of the caller in the queue for a callback.
+func (c *inboundCall) requestCallback(ctx context.Context, qc *QueuedCall, disp CallDispatch, conf config.CallbackConfig) {
+	job := callbackJob(conf, callbackParams{
+		CallID:    string(c.cc.ID()),
+		Queue:     qc.queue,
+		ProjectID: disp.ProjectID,
+		Caller:    c.cc.From().User,
+		Called:    c.cc.To().User,
+		Room:      disp.Room,
+	})
+	maxWait := conf.MaxWait
+	if maxWait <= 0 {
+		maxWait = config.DefaultCallbackMaxWait
+	}
+	qc.SetCallback()
+	go c.s.waitCallback(qc, job, maxWait)
+	c.log.Infow("Caller requested a callback", "queue", qc.queue, "position", qc.Position(), "maxWait", maxWait)
+	if conf.ConfirmText != "" && c.s.tts != nil {
+		if frames, err := c.s.tts.Synthesize(ctx, conf.ConfirmText, VoiceOptions{}); err != nil {
+			c.log.Warnw("Cannot synthesize callback confirmation", err)
+		} else {
+			c.playAudio(ctx, frames)
+		}
+	}
+	c.close(false, callDropped, "call-queue-callback")
+}
+
+// waitCallback keeps a place in the queue for a caller who requested a callback.
+// Once an agent is ready for the caller, a dial job is submitted to call them back.
+func (s *Server) waitCallback(qc *QueuedCall, job DialJob, maxWait time.Duration) {
+	log := s.log.WithValues("queue", qc.queue, "callID", qc.callID)
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	select {
+	case <-qc.Ready():
+		waited := qc.Leave(stats.CallQueueCallback)
+		resp, err := s.dial.Submit(&DialJobsRequest{Jobs: []DialJob{job}})
+		if err != nil {
+			log.Warnw("cannot submit callback", err, "waited", waited)
+			return
+		}
+		log.Infow("calling back queued caller", "dialerJob", resp.IDs[0], "waited", waited)
+	case <-timer.C:
+		qc.Leave(stats.CallQueueTimeout)
+		log.Infow("callback request expired, no agent was ready", "waited", maxWait)
+	case <-s.closing.Watch():
+		qc.Leave(stats.CallQueueAbandoned)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestCallbackJob(t *testing.T) {
+	conf := config.CallbackConfig{
+		Digit:         "1",
+		Address:       "sip.example.com",
+		Transport:     "tcp",
+		Username:      "user",
+		Password:      "pass",
+		MaxAttempts:   20,
+		RetryInterval: time.Minute,
+	}
+	p := callbackParams{
+		CallID:    "SCL_1",
+		Queue:     "sales",
+		ProjectID: "p_1",
+		Caller:    "+15550100",
+		Called:    "+15550199",
+		Room: RoomConfig{
+			WsUrl:    "wss://example.livekit.cloud",
+			Token:    "token",
+			RoomName: "room",
+			Participant: ParticipantConfig{
+				Identity:   "caller",
+				Name:       "Caller",
+				Attributes: map[string]string{"a": "b"},
+			},
+		},
+	}
+	job := callbackJob(conf, p)
+	require.Equal(t, "+15550100", job.Call.CallTo)
+	require.Equal(t, "+15550199", job.Call.Number)
+	require.Equal(t, "sip.example.com", job.Call.Address)
+	require.Equal(t, livekit.SIPTransport_SIP_TRANSPORT_TCP, job.Call.Transport)
+	require.Equal(t, "room", job.Call.RoomName)
+	require.Equal(t, "token", job.Call.Token)
+	require.Equal(t, "caller", job.Call.ParticipantIdentity)
+	require.Equal(t, map[string]string{"a": "b", AttrSIPCallbackCallID: "SCL_1"}, job.Call.ParticipantAttributes)
+	// Attributes of the dispatch are not modified.
+	require.Len(t, p.Room.Participant.Attributes, 1)
+	require.Equal(t, maxDialAttempts, job.MaxAttempts)
+	require.Equal(t, 60000, job.RetryIntervalMs)
+	require.Equal(t, map[string]string{"queue": "sales", "call_id": "SCL_1"}, job.Metadata)
+
+	conf.Number = "+15550111"
+	require.Equal(t, "+15550111", callbackJob(conf, p).Call.Number)
+}
+
+func TestWaitCallback(t *testing.T) {
+	log := logger.GetLogger()
+	dialed := make(chan *rpc.InternalCreateSIPParticipantRequest, 1)
+	d := newDialer(log, &config.Config{}, func(ctx context.Context, req *rpc.InternalCreateSIPParticipantRequest) (*rpc.InternalCreateSIPParticipantResponse, error) {
+		dialed <- req
+		return &rpc.InternalCreateSIPParticipantResponse{}, nil
+	})
+	d.Start()
+	t.Cleanup(d.Stop)
+	s := &Server{log: log, queues: NewCallQueues(log, nil), dial: d}
+
+	qc, err := s.queues.Enqueue("sales", "SCL_1", 0)
+	require.NoError(t, err)
+	qc.SetCallback()
+	job := callbackJob(config.CallbackConfig{Digit: "1", Address: "sip.example.com"}, callbackParams{
+		CallID: "SCL_1",
+		Queue:  "sales",
+		Caller: "+15550100",
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.waitCallback(qc, job, time.Minute)
+	}()
+	require.True(t, s.queues.Info()[0].Calls[0].Callback)
+
+	_, callbacks := s.queues.Release("sales", "", 1)
+	require.Equal(t, []string{"SCL_1"}, callbacks)
+	<-done
+	select {
+	case req := <-dialed:
+		require.Equal(t, "+15550100", req.CallTo)
+		require.Equal(t, "SCL_1", req.ParticipantAttributes[AttrSIPCallbackCallID])
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was not placed")
+	}
+
+	// Expired requests leave the queue.
+	qc, err = s.queues.Enqueue("sales", "SCL_2", 0)
+	require.NoError(t, err)
+	s.waitCallback(qc, job, time.Millisecond)
+	require.Zero(t, s.queues.Len("sales"))
+}
+
+func TestCallbackConfig(t *testing.T) {
+	for _, c := range []config.CallbackConfig{
+		{Digit: "1", Address: "sip.example.com"},
+		{Digit: "#", Address: "sip.example.com", Transport: "tls", MaxAttempts: 3, MaxWait: time.Hour},
+	} {
+		require.NoError(t, c.Validate(), "%+v", c)
+	}
+	for _, c := range []config.CallbackConfig{
+		{Address: "sip.example.com"},
+		{Digit: "12", Address: "sip.example.com"},
+		{Digit: "a", Address: "sip.example.com"},
+		{Digit: "1"},
+		{Digit: "1", Address: "sip.example.com", Transport: "sctp"},
+		{Digit: "1", Address: "sip.example.com", MaxAttempts: -1},
+	} {
+		require.Error(t, c.Validate(), "%+v", c)
+	}
+	q := config.CallQueueConfig{MusicFile: "hold.ogg", Callback: &config.CallbackConfig{Digit: "1"}}
+	require.Error(t, q.Validate())
+}
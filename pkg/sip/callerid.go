@@ -0,0 +1,156 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	defaultCallerIDMetadataKey = "caller_id"
+
+	// Anonymous From header, as recommended by RFC 3323.
+	anonymousDisplayName = "Anonymous"
+	anonymousUser        = "anonymous"
+	anonymousHost        = "anonymous.invalid"
+)
+
+// callerID is the identity presented on an outbound call.
+type callerID struct {
+	// User is the user part of the From header.
+	User        string
+	DisplayName string
+	// Anonymous hides the From header and requests privacy.
+	Anonymous bool
+	// Asserted is the user part of P-Asserted-Identity header. The header is not sent if it's empty.
+	Asserted string
+	// ContactUser is the user part of the Contact header. The user part is not set if it's empty.
+	ContactUser string
+}
+
+// callerIDPolicy selects the caller ID of an outbound call.
+type callerIDPolicy struct {
+	conf   config.CallerIDConfig
+	number string // from the call request attributes
+	format []valueTransform
+}
+
+// newCallerIDPolicy creates a caller ID policy for the trunk config. Call request attributes may override
+// the mode with AttrSIPCallerIDMode and the number with AttrSIPCallerID.
+func newCallerIDPolicy(conf config.CallerIDConfig, attrs map[string]string) (*callerIDPolicy, error) {
+	if mode := attrs[AttrSIPCallerIDMode]; mode != "" {
+		conf.Mode = config.CallerIDMode(mode)
+		if err := conf.Mode.Validate(); err != nil {
+			return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+		}
+	}
+	p := &callerIDPolicy{conf: conf, number: attrs[AttrSIPCallerID]}
+	if conf.Format != "" {
+		funcs, err := parseValueTransforms(conf.Format)
+		if err != nil {
+			return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "invalid caller id format: %v", err)
+		}
+		p.format = funcs
+	}
+	return p, nil
+}
+
+// validateCallerIDFormats checks caller ID formats in the config, since the config package cannot parse transforms.
+func validateCallerIDFormats(conf *config.Config) error {
+	if _, err := newCallerIDPolicy(conf.CallerID, nil); err != nil {
+		return err
+	}
+	for id, t := range conf.Trunks {
+		if t == nil || t.CallerID == nil {
+			continue
+		}
+		if _, err := newCallerIDPolicy(*t.CallerID, nil); err != nil {
+			return fmt.Errorf("trunk %q: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (p *callerIDPolicy) apply(v string) string {
+	for _, fnc := range p.format {
+		v = fnc(v, nil)
+	}
+	return v
+}
+
+// Select picks the caller ID. The trunk number from the call request is used if the policy gives no number.
+// Room metadata is used in passthrough mode.
+func (p *callerIDPolicy) Select(log logger.Logger, number string, roomMeta string) callerID {
+	if p == nil {
+		return callerID{User: number, DisplayName: number}
+	}
+	user := number
+	switch p.conf.Mode {
+	case config.CallerIDFixed:
+		user = cmp.Or(p.number, p.conf.Number, number)
+	case config.CallerIDPassthrough:
+		v := p.number
+		if v == "" {
+			v = metadataString(roomMeta, cmp.Or(p.conf.MetadataKey, defaultCallerIDMetadataKey))
+		}
+		if v == "" {
+			log.Infow("caller id is not set in the request or room metadata, using trunk number")
+		}
+		user = cmp.Or(v, number)
+	case config.CallerIDPool:
+		if len(p.conf.Pool) != 0 {
+			user = p.conf.Pool[rand.IntN(len(p.conf.Pool))]
+		}
+	case config.CallerIDAnonymous:
+		id := callerID{User: anonymousUser, DisplayName: anonymousDisplayName, Anonymous: true}
+		if p.conf.AssertedIdentity {
+			id.Asserted = p.apply(number)
+		}
+		return id
+	}
+	user = p.apply(user)
+	id := callerID{User: user}
+	if !p.conf.NoDisplayName {
+		id.DisplayName = user
+	}
+	if p.conf.AssertedIdentity {
+		id.Asserted = user
+	}
+	if p.conf.ContactUser {
+		id.ContactUser = user
+	}
+	return id
+}
+
+// metadataString returns a string field of JSON metadata, or an empty string.
+func metadataString(meta, key string) string {
+	if meta == "" {
+		return ""
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(meta), &m); err != nil {
+		return ""
+	}
+	v, _ := m[key].(string)
+	return v
+}
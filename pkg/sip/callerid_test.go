@@ -0,0 +1,121 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/sipgo/sip"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestCallerIDPolicy(t *testing.T) {
+	const trunkNum = "+15550000"
+	log := logger.NewTestLogger(t)
+	cases := []struct {
+		name  string
+		conf  config.CallerIDConfig
+		attrs map[string]string
+		meta  string
+		exp   callerID
+	}{
+		{
+			name: "default",
+			exp:  callerID{User: trunkNum, DisplayName: trunkNum},
+		},
+		{
+			name: "fixed",
+			conf: config.CallerIDConfig{Mode: config.CallerIDFixed, Number: "+15550100", AssertedIdentity: true, ContactUser: true},
+			exp:  callerID{User: "+15550100", DisplayName: "+15550100", Asserted: "+15550100", ContactUser: "+15550100"},
+		},
+		{
+			name:  "fixed from request",
+			conf:  config.CallerIDConfig{Mode: config.CallerIDFixed, Number: "+15550100"},
+			attrs: map[string]string{AttrSIPCallerID: "+15550101"},
+			exp:   callerID{User: "+15550101", DisplayName: "+15550101"},
+		},
+		{
+			name: "passthrough metadata",
+			conf: config.CallerIDConfig{Mode: config.CallerIDPassthrough, NoDisplayName: true},
+			meta: `{"caller_id": "+15550200"}`,
+			exp:  callerID{User: "+15550200"},
+		},
+		{
+			name: "passthrough custom key",
+			conf: config.CallerIDConfig{Mode: config.CallerIDPassthrough, MetadataKey: "cli"},
+			meta: `{"caller_id": "+15550200", "cli": "+15550201"}`,
+			exp:  callerID{User: "+15550201", DisplayName: "+15550201"},
+		},
+		{
+			name: "passthrough missing",
+			conf: config.CallerIDConfig{Mode: config.CallerIDPassthrough},
+			meta: `invalid`,
+			exp:  callerID{User: trunkNum, DisplayName: trunkNum},
+		},
+		{
+			name: "pool",
+			conf: config.CallerIDConfig{Mode: config.CallerIDPool, Pool: []string{"+15550300"}},
+			exp:  callerID{User: "+15550300", DisplayName: "+15550300"},
+		},
+		{
+			name: "anonymous",
+			conf: config.CallerIDConfig{Mode: config.CallerIDAnonymous, AssertedIdentity: true, ContactUser: true},
+			exp:  callerID{User: anonymousUser, DisplayName: anonymousDisplayName, Anonymous: true, Asserted: trunkNum},
+		},
+		{
+			name:  "anonymous from request",
+			conf:  config.CallerIDConfig{Mode: config.CallerIDFixed, Number: "+15550100"},
+			attrs: map[string]string{AttrSIPCallerIDMode: "anonymous"},
+			exp:   callerID{User: anonymousUser, DisplayName: anonymousDisplayName, Anonymous: true},
+		},
+		{
+			name: "format",
+			conf: config.CallerIDConfig{Mode: config.CallerIDFixed, Number: "555 0100", Format: "e164:1 | strip_prefix:+", NoDisplayName: true},
+			exp:  callerID{User: "15550100"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := newCallerIDPolicy(c.conf, c.attrs)
+			require.NoError(t, err)
+			require.Equal(t, c.exp, p.Select(log, trunkNum, c.meta))
+		})
+	}
+
+	_, err := newCallerIDPolicy(config.CallerIDConfig{}, map[string]string{AttrSIPCallerIDMode: "unknown"})
+	require.Error(t, err)
+	_, err = newCallerIDPolicy(config.CallerIDConfig{Format: "e164 | unknown"}, nil)
+	require.Error(t, err)
+}
+
+func TestSetCallerID(t *testing.T) {
+	cli := &Client{}
+	from := URI{User: "+15550000", Host: "example.com"}
+	contact := URI{Host: "10.0.0.1"}
+
+	cc := cli.newOutbound(logger.GetLogger(), "tag", from, contact, nil)
+	cc.setCallerID(callerID{User: "+15550100", ContactUser: "+15550100"})
+	require.Equal(t, "<sip:+15550100@example.com>;tag=tag", cc.from.Value())
+	require.Equal(t, "sip:+15550100@10.0.0.1", cc.contact.Address.String())
+
+	cc = cli.newOutbound(logger.GetLogger(), "tag", from, contact, nil)
+	cc.setCallerID(callerID{User: anonymousUser, DisplayName: anonymousDisplayName, Anonymous: true})
+	require.Equal(t, `"Anonymous" <sip:anonymous@anonymous.invalid>;tag=tag`, cc.from.Value())
+	require.Equal(t, "sip:10.0.0.1", cc.contact.Address.String())
+	require.Equal(t, sip.Uri{User: anonymousUser, Host: anonymousHost}, cc.From())
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	// logSampleInterval is the interval of sampling for noisy call logs.
+	logSampleInterval = 10 * time.Second
+	// logSampleBurst is the number of lines with the same message logged in each interval.
+	logSampleBurst = 5
+)
+
+// callLogFields sets identifiers of the call that are only known after the logger is created.
+// They are added to every line logged by the call logger and loggers derived from it.
+type callLogFields struct {
+	res logger.DeferredFieldResolver
+}
+
+// newCallLogger creates a call-scoped logger, tagged with call ID and remote address.
+// Project and trunk IDs are added later via callLogFields, when the call is matched.
+func newCallLogger(log logger.Logger, callID string, remote any) (logger.Logger, callLogFields) {
+	log = log.WithValues("callID", callID, "remote", remote)
+	log, res := log.WithDeferredValues()
+	res.Resolve() // do not hold lines until IDs are known
+	return log, callLogFields{res: res}
+}
+
+// SetProject adds project ID to all lines of the call logger.
+func (f callLogFields) SetProject(id string) {
+	if f.res != nil && id != "" {
+		f.res.Resolve("projectID", id)
+	}
+}
+
+// SetTrunk adds SIP trunk ID to all lines of the call logger.
+func (f callLogFields) SetTrunk(id string) {
+	if f.res != nil && id != "" {
+		f.res.Resolve("sipTrunk", id)
+	}
+}
+
+// logSampler counts lines with each message in the current interval.
+type logSampler struct {
+	mu    sync.Mutex
+	start time.Time
+	cnt   map[string]int
+	drop  map[string]int
+}
+
+// Allow checks if a line with a given message should be logged.
+// It also returns the number of lines with this message that were dropped since the last logged one.
+func (s *logSampler) Allow(msg string, now time.Time) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cnt == nil || now.Sub(s.start) >= logSampleInterval {
+		s.start = now
+		s.cnt = make(map[string]int)
+	}
+	if s.cnt[msg] >= logSampleBurst {
+		if s.drop == nil {
+			s.drop = make(map[string]int)
+		}
+		s.drop[msg]++
+		return 0, false
+	}
+	s.cnt[msg]++
+	dropped := s.drop[msg]
+	delete(s.drop, msg)
+	return dropped, true
+}
+
+// sampledLogger limits the rate of lines with the same message. It is used for noisy per-packet logs,
+// so that a single bad call cannot flood the logs. Loggers derived from it share the same limits.
+type sampledLogger struct {
+	logger.Logger
+	s *logSampler
+}
+
+func newSampledLogger(log logger.Logger) logger.Logger {
+	if sl, ok := log.(*sampledLogger); ok {
+		return sl
+	}
+	return &sampledLogger{Logger: log.WithCallDepth(1), s: new(logSampler)}
+}
+
+func (l *sampledLogger) allow(msg string) (logger.Logger, bool) {
+	dropped, ok := l.s.Allow(msg, time.Now())
+	if !ok {
+		return nil, false
+	}
+	if dropped != 0 {
+		return l.Logger.WithValues("droppedLines", dropped), true
+	}
+	return l.Logger, true
+}
+
+func (l *sampledLogger) Debugw(msg string, keysAndValues ...any) {
+	if log, ok := l.allow(msg); ok {
+		log.Debugw(msg, keysAndValues...)
+	}
+}
+
+func (l *sampledLogger) Infow(msg string, keysAndValues ...any) {
+	if log, ok := l.allow(msg); ok {
+		log.Infow(msg, keysAndValues...)
+	}
+}
+
+func (l *sampledLogger) Warnw(msg string, err error, keysAndValues ...any) {
+	if log, ok := l.allow(msg); ok {
+		log.Warnw(msg, err, keysAndValues...)
+	}
+}
+
+func (l *sampledLogger) Errorw(msg string, err error, keysAndValues ...any) {
+	if log, ok := l.allow(msg); ok {
+		log.Errorw(msg, err, keysAndValues...)
+	}
+}
+
+func (l *sampledLogger) WithValues(keysAndValues ...any) logger.Logger {
+	return &sampledLogger{Logger: l.Logger.WithValues(keysAndValues...), s: l.s}
+}
+
+func (l *sampledLogger) WithName(name string) logger.Logger {
+	return &sampledLogger{Logger: l.Logger.WithName(name), s: l.s}
+}
+
+func (l *sampledLogger) WithComponent(component string) logger.Logger {
+	return &sampledLogger{Logger: l.Logger.WithComponent(component), s: l.s}
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSampler(t *testing.T) {
+	var s logSampler
+	now := time.Now()
+	allowed := func(msg string, n int) int {
+		cnt := 0
+		for range n {
+			if _, ok := s.Allow(msg, now); ok {
+				cnt++
+			}
+		}
+		return cnt
+	}
+	require.Equal(t, logSampleBurst, allowed("packet", 100))
+	require.Equal(t, logSampleBurst, allowed("other", 100))
+
+	// Dropped lines are reported with the next logged line.
+	now = now.Add(logSampleInterval)
+	dropped, ok := s.Allow("packet", now)
+	require.True(t, ok)
+	require.Equal(t, 100-logSampleBurst, dropped)
+	require.Equal(t, logSampleBurst-1, allowed("packet", 100))
+
+	now = now.Add(logSampleInterval)
+	dropped, ok = s.Allow("other", now)
+	require.True(t, ok)
+	require.Equal(t, 100-logSampleBurst, dropped)
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"strings"
+
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// Capabilities advertised by default. They must only list what is implemented.
+var (
+	// defaultAllow lists methods handled in inbound requests. Others are rejected with 405.
+	defaultAllow = []string{"INVITE", "ACK", "CANCEL", "BYE", "NOTIFY", "OPTIONS"}
+	// defaultSupported lists extensions: Replaces (RFC 3891), Join (RFC 3911) and Target-Dialog (RFC 4538).
+	defaultSupported = []string{"replaces", "join", "tdialog"}
+	// defaultAccept lists body types accepted in INVITE. Multipart bodies (RFC 5621) may carry ISUP or location.
+	defaultAccept = []string{"application/sdp", "multipart/mixed"}
+)
+
+// defaultCapabilities are advertised in requests created without a trunk config.
+var defaultCapabilities = newSIPCapabilities(config.CapabilitiesConfig{})
+
+// sipCapabilities are values of Allow, Supported and Accept headers. Empty values are not sent.
+type sipCapabilities struct {
+	Allow     string
+	Supported string
+	Accept    string
+}
+
+func newSIPCapabilities(conf config.CapabilitiesConfig) sipCapabilities {
+	list := func(v, def []string) string {
+		if v == nil {
+			v = def
+		}
+		return strings.Join(v, ", ")
+	}
+	return sipCapabilities{
+		Allow:     list(conf.Allow, defaultAllow),
+		Supported: list(conf.Supported, defaultSupported),
+		Accept:    list(conf.Accept, defaultAccept),
+	}
+}
+
+// capabilities returns SIP capabilities advertised on a trunk. An empty trunk ID selects the global config.
+func (s *Server) capabilities(trunkID string) sipCapabilities {
+	return newSIPCapabilities(s.conf.TrunkCapabilities(trunkID))
+}
+
+// capabilities returns SIP capabilities advertised on a trunk. An empty trunk ID selects the global config.
+func (c *Client) capabilities(trunkID string) sipCapabilities {
+	return newSIPCapabilities(c.conf.TrunkCapabilities(trunkID))
+}
+
+// SetHeaders replaces Allow and Supported headers of a message. Accept is only set if requested,
+// since it's only expected in OPTIONS and 415 responses.
+func (c sipCapabilities) SetHeaders(m sip.Message, accept bool) {
+	setCapabilityHeader(m, "Allow", c.Allow)
+	setCapabilityHeader(m, "Supported", c.Supported)
+	if accept {
+		setCapabilityHeader(m, "Accept", c.Accept)
+	}
+}
+
+func setCapabilityHeader(m sip.Message, name, value string) {
+	if r, ok := m.(interface{ RemoveHeader(string) bool }); ok {
+		for r.RemoveHeader(name) {
+		}
+	}
+	if value != "" {
+		m.AppendHeader(sip.NewHeader(name, value))
+	}
+}
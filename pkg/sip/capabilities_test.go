@@ -0,0 +1,77 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestSIPCapabilities(t *testing.T) {
+	require.Equal(t, sipCapabilities{
+		Allow:     "INVITE, ACK, CANCEL, BYE, NOTIFY, OPTIONS",
+		Supported: "replaces, join, tdialog",
+		Accept:    "application/sdp, multipart/mixed",
+	}, defaultCapabilities)
+
+	conf := &config.Config{
+		Capabilities: config.CapabilitiesConfig{Supported: []string{"replaces"}},
+		Trunks: map[string]*config.TrunkConfig{
+			"ST_1": {Capabilities: &config.CapabilitiesConfig{Allow: []string{"INVITE", "ACK", "BYE"}, Accept: []string{}}},
+		},
+	}
+	require.Equal(t, sipCapabilities{
+		Allow:     defaultCapabilities.Allow,
+		Supported: "replaces",
+		Accept:    defaultCapabilities.Accept,
+	}, newSIPCapabilities(conf.TrunkCapabilities("")))
+	caps := newSIPCapabilities(conf.TrunkCapabilities("ST_1"))
+	require.Equal(t, sipCapabilities{
+		Allow:     "INVITE, ACK, BYE",
+		Supported: "replaces",
+	}, caps)
+
+	req := sip.NewRequest(sip.INVITE, sip.Uri{User: "callee", Host: "example.com"})
+	defaultCapabilities.SetHeaders(req, true)
+	caps.SetHeaders(req, true)
+	require.Len(t, req.GetHeaders("Allow"), 1)
+	require.Equal(t, "INVITE, ACK, BYE", req.GetHeader("Allow").Value())
+	require.Equal(t, "replaces", req.GetHeader("Supported").Value())
+	require.Nil(t, req.GetHeader("Accept"))
+}
+
+func TestCapabilitiesConfig(t *testing.T) {
+	for _, c := range []config.CapabilitiesConfig{
+		{},
+		{Allow: []string{"INVITE", "OPTIONS"}, Supported: []string{"timer", "100rel"}, Accept: []string{"application/sdp"}},
+		{Supported: []string{}},
+	} {
+		require.NoError(t, c.Validate(), "%+v", c)
+	}
+	for _, c := range []config.CapabilitiesConfig{
+		{Allow: []string{"invite"}},
+		{Allow: []string{"INVITE, BYE"}},
+		{Supported: []string{""}},
+		{Accept: []string{"sdp"}},
+		{Accept: []string{"application/sdp; x=1"}},
+	} {
+		require.Error(t, c.Validate(), "%+v", c)
+	}
+}
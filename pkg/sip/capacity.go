@@ -0,0 +1,222 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+// headerValues returns values of all headers with a given name. Header names are not case-sensitive.
+func headerValues(headers map[string]string, name string) []string {
+	var out []string
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// TrunkCapacity limits concurrent calls on each trunk, keeping a part of the capacity for priority calls.
+type TrunkCapacity struct {
+	log  logger.Logger
+	mon  *stats.Monitor
+	conf *config.Config
+
+	mu      sync.Mutex
+	active  map[string]int
+	waiting map[string][]*capacityWaiter // calls queued for a slot, by trunk
+}
+
+func NewTrunkCapacity(log logger.Logger, mon *stats.Monitor, conf *config.Config) *TrunkCapacity {
+	return &TrunkCapacity{
+		log:     log,
+		mon:     mon,
+		conf:    conf,
+		active:  make(map[string]int),
+		waiting: make(map[string][]*capacityWaiter),
+	}
+}
+
+// IsPriority checks if Resource-Priority header values make a call on the trunk a priority call.
+func (t *TrunkCapacity) IsPriority(trunkID string, values []string) bool {
+	if t == nil {
+		return false
+	}
+	return hasResourcePriority(values, t.conf.TrunkCapacity(trunkID).PriorityNamespaces)
+}
+
+// Acquire reserves a call slot on the trunk. Regular calls can't use the part of the capacity reserved for priority calls.
+// The returned lease must be released when the call ends.
+//
+// It returns a nil lease if there's nothing to track. Lease methods are safe to call on nil.
+func (t *TrunkCapacity) Acquire(trunkID string, priority bool, dir stats.CallDir) (*TrunkLease, error) {
+	if t == nil || trunkID == "" {
+		return nil, nil
+	}
+	conf := t.conf.TrunkCapacity(trunkID)
+	if conf.MaxCalls <= 0 {
+		return nil, nil
+	}
+	limit := capacityLimit(conf, priority)
+	t.mu.Lock()
+	active := t.active[trunkID]
+	if active >= limit {
+		t.mu.Unlock()
+		t.mon.PolicyRejected(dir, "trunk-capacity")
+		t.log.Infow("trunk capacity exceeded", "trunkID", trunkID, "dir", dir, "priority", priority, "active", active, "limit", limit)
+		return nil, siperrors.ErrTrunkCallLimit
+	}
+	t.active[trunkID] = active + 1
+	t.mu.Unlock()
+	return &TrunkLease{t: t, trunkID: trunkID}, nil
+}
+
+// capacityLimit returns the number of calls allowed on a trunk. Regular calls can't use the reserved part.
+func capacityLimit(conf config.CapacityConfig, priority bool) int {
+	limit := conf.MaxCalls
+	if !priority {
+		limit -= int(math.Ceil(float64(conf.MaxCalls) * conf.PriorityReserve / 100))
+	}
+	return limit
+}
+
+func (t *TrunkCapacity) release(trunkID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := t.active[trunkID] - 1; n > 0 {
+		t.active[trunkID] = n
+	} else {
+		delete(t.active, trunkID)
+	}
+	t.grantLocked(trunkID)
+}
+
+// grantLocked passes free slots of the trunk to queued calls, in the order they were queued.
+// Priority calls may skip regular calls if only the reserved part of the capacity is free.
+func (t *TrunkCapacity) grantLocked(trunkID string) {
+	queue := t.waiting[trunkID]
+	if len(queue) == 0 {
+		return
+	}
+	conf := t.conf.TrunkCapacity(trunkID)
+	for i := 0; i < len(queue); {
+		w := queue[i]
+		if t.active[trunkID] >= capacityLimit(conf, w.priority) {
+			i++
+			continue
+		}
+		t.active[trunkID]++
+		w.lease <- &TrunkLease{t: t, trunkID: trunkID}
+		queue = slices.Delete(queue, i, i+1)
+	}
+	if len(queue) == 0 {
+		delete(t.waiting, trunkID)
+	} else {
+		t.waiting[trunkID] = queue
+	}
+}
+
+// capacityWaiter is a call queued for a slot on a trunk.
+type capacityWaiter struct {
+	priority bool
+	lease    chan *TrunkLease
+}
+
+// Queue places a call, which couldn't acquire a slot, in the queue of the trunk. The call gets a lease once
+// other calls on the trunk end. Zero maxSize means the queue is not limited.
+//
+// The returned ticket must be cancelled if the call stops waiting.
+func (t *TrunkCapacity) Queue(trunkID string, priority bool, maxSize int, dir stats.CallDir) (*CapacityTicket, error) {
+	if t == nil || trunkID == "" {
+		return nil, siperrors.ErrTrunkCallLimit
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := len(t.waiting[trunkID]); maxSize > 0 && n >= maxSize {
+		t.mon.PolicyRejected(dir, "trunk-queue")
+		t.log.Infow("trunk call queue is full", "trunkID", trunkID, "dir", dir, "priority", priority, "queued", n)
+		return nil, siperrors.ErrTrunkQueueFull
+	}
+	w := &capacityWaiter{priority: priority, lease: make(chan *TrunkLease, 1)}
+	t.waiting[trunkID] = append(t.waiting[trunkID], w)
+	// A slot may have been released after the call failed to acquire it.
+	t.grantLocked(trunkID)
+	return &CapacityTicket{t: t, trunkID: trunkID, w: w}, nil
+}
+
+// CapacityTicket is a place of a call in the queue for a trunk slot.
+type CapacityTicket struct {
+	t       *TrunkCapacity
+	trunkID string
+	w       *capacityWaiter
+}
+
+// Ready returns a lease once the call gets a slot on the trunk.
+func (q *CapacityTicket) Ready() <-chan *TrunkLease {
+	return q.w.lease
+}
+
+// Position returns the 1-based position of the call in the queue, or zero if it's not queued anymore.
+func (q *CapacityTicket) Position() int {
+	q.t.mu.Lock()
+	defer q.t.mu.Unlock()
+	return slices.Index(q.t.waiting[q.trunkID], q.w) + 1
+}
+
+// Cancel removes the call from the queue. A slot granted to the call in the meantime is released.
+func (q *CapacityTicket) Cancel() {
+	q.t.mu.Lock()
+	queue := q.t.waiting[q.trunkID]
+	if i := slices.Index(queue, q.w); i >= 0 {
+		queue = slices.Delete(queue, i, i+1)
+		if len(queue) == 0 {
+			delete(q.t.waiting, q.trunkID)
+		} else {
+			q.t.waiting[q.trunkID] = queue
+		}
+	}
+	q.t.mu.Unlock()
+	select {
+	case l := <-q.w.lease:
+		l.Release()
+	default:
+	}
+}
+
+// TrunkLease is a call slot acquired from TrunkCapacity.
+type TrunkLease struct {
+	t        *TrunkCapacity
+	trunkID  string
+	released atomic.Bool
+}
+
+// Release returns the call slot. It's safe to call it multiple times.
+func (l *TrunkLease) Release() {
+	if l == nil || !l.released.CompareAndSwap(false, true) {
+		return
+	}
+	l.t.release(l.trunkID)
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+func TestHasResourcePriority(t *testing.T) {
+	require.False(t, hasResourcePriority(nil, nil))
+	require.False(t, hasResourcePriority([]string{" "}, nil))
+	require.True(t, hasResourcePriority([]string{"wps.2"}, nil))
+	require.True(t, hasResourcePriority([]string{"wps.2, ETS.0"}, []string{"ets"}))
+	require.False(t, hasResourcePriority([]string{"wps.2"}, []string{"ets", "esnet"}))
+
+	hdrs := map[string]string{"resource-priority": "esnet.0", "X-Other": "1"}
+	require.Equal(t, []string{"esnet.0"}, headerValues(hdrs, headerResourcePriority))
+}
+
+func TestTrunkCapacity(t *testing.T) {
+	c := NewTrunkCapacity(logger.GetLogger(), nil, &config.Config{
+		Capacity: config.CapacityConfig{MaxCalls: 4, PriorityReserve: 25},
+		Trunks: map[string]*config.TrunkConfig{
+			"small": {Capacity: &config.CapacityConfig{MaxCalls: 1}},
+			"none":  {Capacity: &config.CapacityConfig{}},
+		},
+	})
+
+	var leases []*TrunkLease
+	for range 3 {
+		l, err := c.Acquire("t1", false, stats.Inbound)
+		require.NoError(t, err)
+		leases = append(leases, l)
+	}
+	// The last slot is reserved.
+	_, err := c.Acquire("t1", false, stats.Outbound)
+	require.ErrorIs(t, err, siperrors.ErrTrunkCallLimit)
+	p, err := c.Acquire("t1", true, stats.Inbound)
+	require.NoError(t, err)
+	_, err = c.Acquire("t1", true, stats.Inbound)
+	require.ErrorIs(t, err, siperrors.ErrTrunkCallLimit, "priority calls are limited too")
+
+	// Other trunks are counted separately.
+	l, err := c.Acquire("t2", false, stats.Inbound)
+	require.NoError(t, err)
+	l.Release()
+
+	// Releasing a priority call frees the reserved slot.
+	p.Release()
+	p.Release()
+	_, err = c.Acquire("t1", false, stats.Inbound)
+	require.ErrorIs(t, err, siperrors.ErrTrunkCallLimit)
+	leases[0].Release()
+	_, err = c.Acquire("t1", false, stats.Inbound)
+	require.NoError(t, err)
+
+	// Trunk settings override the global limits.
+	l, err = c.Acquire("small", true, stats.Inbound)
+	require.NoError(t, err)
+	_, err = c.Acquire("small", true, stats.Inbound)
+	require.Error(t, err)
+	l.Release()
+
+	for range 10 {
+		l, err = c.Acquire("none", false, stats.Inbound)
+		require.NoError(t, err)
+		require.Nil(t, l)
+	}
+}
+
+func TestTrunkCapacityQueue(t *testing.T) {
+	c := NewTrunkCapacity(logger.GetLogger(), nil, &config.Config{
+		Capacity: config.CapacityConfig{MaxCalls: 2, PriorityReserve: 50},
+	})
+	l, err := c.Acquire("t1", false, stats.Inbound)
+	require.NoError(t, err)
+	p, err := c.Acquire("t1", true, stats.Inbound)
+	require.NoError(t, err)
+
+	q1, err := c.Queue("t1", false, 2, stats.Inbound)
+	require.NoError(t, err)
+	q2, err := c.Queue("t1", false, 2, stats.Inbound)
+	require.NoError(t, err)
+	_, err = c.Queue("t1", false, 2, stats.Inbound)
+	require.ErrorIs(t, err, siperrors.ErrTrunkQueueFull)
+	require.Equal(t, 1, q1.Position())
+	require.Equal(t, 2, q2.Position())
+
+	// Regular calls can't use the reserved slot, but priority calls in the queue can.
+	p.Release()
+	require.Empty(t, q1.Ready())
+	q3, err := c.Queue("t1", true, 0, stats.Inbound)
+	require.NoError(t, err)
+	require.Zero(t, q3.Position())
+	lp := <-q3.Ready()
+	require.NotNil(t, lp)
+
+	// Slots are passed to queued calls in order, once they fit into the limit.
+	l.Release()
+	require.Empty(t, q1.Ready())
+	lp.Release()
+	l1 := <-q1.Ready()
+	require.Zero(t, q1.Position())
+	require.Equal(t, 1, q2.Position())
+
+	// Cancelled calls leave the queue, and release slots granted to them.
+	q2.Cancel()
+	require.Zero(t, q2.Position())
+	l1.Release()
+	l, err = c.Acquire("t1", false, stats.Inbound)
+	require.NoError(t, err)
+	q4, err := c.Queue("t1", false, 0, stats.Inbound)
+	require.NoError(t, err)
+	l.Release()
+	q4.Cancel()
+	l, err = c.Acquire("t1", false, stats.Inbound)
+	require.NoError(t, err, "slot granted to the cancelled call is free")
+	l.Release()
+}
@@ -16,6 +16,7 @@ package sip
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/netip"
 	"strings"
@@ -35,7 +36,9 @@ import (
 
 	"github.com/livekit/sip/pkg/config"
 	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/media/tonegen"
 	"github.com/livekit/sip/pkg/stats"
+	"github.com/livekit/sip/pkg/stt"
 )
 
 type Client struct {
@@ -51,9 +54,25 @@ type Client struct {
 	cmu         sync.Mutex
 	activeCalls map[LocalTag]*outboundCall
 	byRemote    map[RemoteTag]*outboundCall
+	transports  transportCache // last transport that worked for each destination
 
 	handler     Handler
 	getIOClient GetIOInfoClient
+	ports       *PortAllocator // optional
+	quotas      *ProjectQuotas // optional
+	capacity    *TrunkCapacity // optional
+	vq          *vqReporter    // optional
+	dnc         *dncPolicy     // optional
+	loops       *loopDetector  // optional
+	leaks       *leakWatchdog  // optional
+	mem         *memBudget     // optional
+	owners      *dialogOwners  // optional; dialogs shared by instances
+	failover    *failover      // optional; calls taken over by the peer instance
+	artifacts   *Artifacts     // optional
+	stt         stt.Engine     // optional
+	tts         TTS            // optional
+
+	tones *tonegen.Profile // call progress tones, ETSI if nil
 }
 
 func NewClient(region string, conf *config.Config, log logger.Logger, mon *stats.Monitor, getIOClient GetIOInfoClient) *Client {
@@ -120,7 +139,17 @@ func (c *Client) SetHandler(handler Handler) {
 }
 
 func (c *Client) ContactURI(tr Transport) URI {
-	return getContactURI(c.conf, c.sconf.SignalingIP, tr)
+	return getContactURI(c.conf, c.sconf.signalingIP(), tr)
+}
+
+// addVia adds a Via header of this node to the request, with the hostname advertised for the transport, if any.
+func (c *Client) addVia(req *sip.Request) {
+	_ = sipgo.ClientRequestAddVia(c.sipCli, req)
+	host := advertisedHost(c.conf, Transport(strings.ToLower(req.Transport())))
+	if host == "" {
+		host = c.sconf.signalingIP().String()
+	}
+	req.Via().Host = host
 }
 
 func (c *Client) CreateSIPParticipant(ctx context.Context, req *rpc.InternalCreateSIPParticipantRequest) (*rpc.InternalCreateSIPParticipantResponse, error) {
@@ -133,6 +162,9 @@ func (c *Client) createSIPParticipant(ctx context.Context, req *rpc.InternalCrea
 	if c.mon.Health() != stats.HealthOK {
 		return nil, siperrors.ErrUnavailable
 	}
+	if !c.ports.Unallocated(req.SipTrunkId) {
+		return nil, siperrors.ErrMediaPortsExhausted
+	}
 	if req.CallTo == "" {
 		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "call-to number must be set")
 	} else if req.Address == "" {
@@ -154,19 +186,60 @@ func (c *Client) createSIPParticipant(ctx context.Context, req *rpc.InternalCrea
 	if strings.ContainsAny(req.Address, ";=") {
 		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "address must not contain parameters")
 	}
-	log := c.log
-	if req.ProjectId != "" {
-		log = log.WithValues("projectID", req.ProjectId)
+	log, logFields := newCallLogger(c.log, req.SipCallId, req.Address)
+	logFields.SetProject(req.ProjectId)
+	logFields.SetTrunk(req.SipTrunkId)
+	if tags := CallTags(req.ParticipantAttributes); len(tags) != 0 {
+		log = log.WithValues("tags", tags)
 	}
-	if req.SipTrunkId != "" {
-		log = log.WithValues("sipTrunk", req.SipTrunkId)
+	emergencyConf := c.conf.TrunkEmergency(req.SipTrunkId)
+	callTo, ok := translateNumber(c.conf.TrunkDialPlan(req.SipTrunkId), req.CallTo)
+	emergency := isEmergencyNumber(emergencyConf, req.CallTo) || isEmergencyNumber(emergencyConf, callTo)
+	if !ok && !emergency {
+		log.Infow("called number is blocked by dial plan", "toUser", callTo)
+		return nil, errNumberBlocked
+	}
+	if callTo != req.CallTo {
+		log.Infow("translated number by dial plan", "toUserOrig", req.CallTo, "toUser", callTo)
+		req.CallTo = callTo
+	}
+	if emergency {
+		// Emergency calls must go through, regardless of the limits and policies applied to regular calls.
+		log = log.WithValues("emergency", true)
+		log.Infow("Placing emergency call", "toUser", req.CallTo)
+		c.mon.EmergencyCall(stats.Outbound, req.SipTrunkId)
+		req.ParticipantAttributes = maps.Clone(req.ParticipantAttributes)
+		if req.ParticipantAttributes == nil {
+			req.ParticipantAttributes = make(map[string]string)
+		}
+		req.ParticipantAttributes[AttrSIPEmergency] = "true"
 	}
 	enc, err := sdpEncryption(req.MediaEncryption)
 	if err != nil {
 		return nil, err
 	}
+	callerID, err := newCallerIDPolicy(c.conf.TrunkCallerID(req.SipTrunkId), req.ParticipantAttributes)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := outboundResourcePriority(req.Headers, req.ParticipantAttributes, c.conf.TrunkResourcePriority(req.SipTrunkId))
+	if err != nil {
+		return nil, err
+	}
+	locConf, err := requestLocation(req.ParticipantAttributes)
+	if err != nil {
+		return nil, err
+	}
+	if locConf != nil {
+		// The document may be large, keep it out of participant attributes.
+		req.ParticipantAttributes = maps.Clone(req.ParticipantAttributes)
+		delete(req.ParticipantAttributes, AttrSIPLocationPIDF)
+	} else if emergency {
+		locConf = emergencyConf.Location
+	}
+	prewarm, attrs := requestPrewarm(req.ParticipantAttributes)
+	req.ParticipantAttributes = attrs
 	log = log.WithValues(
-		"callID", req.SipCallId,
 		"room", req.RoomName,
 		"participant", req.ParticipantIdentity,
 		"participantName", req.ParticipantName,
@@ -181,9 +254,17 @@ func (c *Client) createSIPParticipant(ctx context.Context, req *rpc.InternalCrea
 	defer func() {
 		state.Update(ctx, func(info *livekit.SIPCallInfo) {
 
-			switch retErr {
-			case nil:
+			switch {
+			case retErr == nil:
 				info.CallStatus = livekit.SIPCallStatus_SCS_PARTICIPANT_JOINED
+			case errors.Is(retErr, siperrors.ErrDestinationBlocked):
+				info.CallStatus = livekit.SIPCallStatus_SCS_ERROR
+				info.DisconnectReason = livekit.DisconnectReason_UNKNOWN_REASON
+				info.CallStatusCode = &livekit.SIPStatus{
+					Code:   livekit.SIPStatusCode_SIP_STATUS_FORBIDDEN,
+					Status: dncPolicyRejected,
+				}
+				info.Error = dncPolicyRejected + ": " + retErr.Error()
 			default:
 				info.CallStatus = livekit.SIPCallStatus_SCS_ERROR
 				info.DisconnectReason = livekit.DisconnectReason_UNKNOWN_REASON
@@ -192,6 +273,16 @@ func (c *Client) createSIPParticipant(ctx context.Context, req *rpc.InternalCrea
 		})
 	}()
 
+	if !emergency {
+		if err := c.dnc.Check(ctx, log, req.CallTo); err != nil {
+			if errors.Is(err, siperrors.ErrDestinationBlocked) {
+				log.Infow("Rejecting outbound call, destination is on the do-not-call list")
+				c.mon.PolicyRejected(stats.Outbound, "dnc")
+			}
+			return nil, err
+		}
+	}
+
 	roomConf := RoomConfig{
 		WsUrl:    req.WsUrl,
 		Token:    req.Token,
@@ -204,6 +295,7 @@ func (c *Client) createSIPParticipant(ctx context.Context, req *rpc.InternalCrea
 		},
 	}
 	sipConf := sipOutboundConfig{
+		trunkID:         req.SipTrunkId,
 		address:         req.Address,
 		transport:       req.Transport,
 		host:            req.Hostname,
@@ -213,7 +305,7 @@ func (c *Client) createSIPParticipant(ctx context.Context, req *rpc.InternalCrea
 		pass:            req.Password,
 		dtmf:            req.Dtmf,
 		dialtone:        req.PlayDialtone,
-		headers:         req.Headers,
+		headers:         headers,
 		includeHeaders:  req.IncludeHeaders,
 		headersToAttrs:  req.HeadersToAttributes,
 		attrsToHeaders:  req.AttributesToHeaders,
@@ -221,12 +313,50 @@ func (c *Client) createSIPParticipant(ctx context.Context, req *rpc.InternalCrea
 		maxCallDuration: req.MaxCallDuration.AsDuration(),
 		enabledFeatures: req.EnabledFeatures,
 		mediaEncryption: enc,
+		callerID:        callerID,
+		wsUrl:           req.WsUrl,
+		prewarm:         prewarm,
+	}
+	var (
+		quota    *ProjectLease
+		capacity *TrunkLease
+	)
+	if locConf != nil {
+		host := sipConf.host
+		if host == "" {
+			host = c.ContactURI(TransportFrom(sipConf.transport)).GetHost()
+		}
+		sipConf.location = newCallLocation(locConf, host)
+		sipConf.headers = mergeHeaders(sipConf.location.Headers(), sipConf.headers)
+	}
+	if emergency {
+		sipConf.headers = emergencyHeaders(sipConf.headers)
+	} else {
+		quota, err = c.quotas.Acquire(req.ProjectId, stats.Outbound)
+		if err != nil {
+			log.Infow("Rejecting outbound call, project quota exceeded", "error", err)
+			return nil, err
+		}
+		if d := quota.MaxDuration(); d > 0 && (sipConf.maxCallDuration <= 0 || d < sipConf.maxCallDuration) {
+			sipConf.maxCallDuration = d
+		}
+		priority := c.capacity.IsPriority(req.SipTrunkId, headerValues(headers, headerResourcePriority))
+		capacity, err = c.capacity.Acquire(req.SipTrunkId, priority, stats.Outbound)
+		if err != nil {
+			log.Infow("Rejecting outbound call, trunk capacity exceeded", "error", err, "priority", priority)
+			quota.Release()
+			return nil, err
+		}
 	}
 	log.Infow("Creating SIP participant")
 	call, err := c.newCall(ctx, c.conf, log, LocalTag(req.SipCallId), roomConf, sipConf, state, req.ProjectId)
 	if err != nil {
+		quota.Release()
+		capacity.Release()
 		return nil, err
 	}
+	call.quota = quota
+	call.capacity = capacity
 	p := call.Participant()
 	// Start actual SIP call async.
 
@@ -251,7 +381,7 @@ func (c *Client) createSIPCallInfo(req *rpc.InternalCreateSIPParticipantRequest)
 	fromiUri := URI{
 		User: req.Number,
 		Host: req.Hostname,
-		Addr: netip.AddrPortFrom(c.sconf.SignalingIP, uint16(c.conf.SIPPort)),
+		Addr: netip.AddrPortFrom(c.sconf.signalingIP(), uint16(c.conf.SIPPort)),
 	}
 
 	callInfo := &livekit.SIPCallInfo{
@@ -278,6 +408,8 @@ func (c *Client) OnRequest(req *sip.Request, tx sip.ServerTransaction) bool {
 		return c.onBye(req, tx)
 	case "NOTIFY":
 		return c.onNotify(req, tx)
+	case "INVITE":
+		return c.onReInvite(req, tx)
 	}
 }
 
@@ -294,6 +426,7 @@ func (c *Client) onBye(req *sip.Request, tx sip.ServerTransaction) bool {
 		return false
 	}
 	call.log.Infow("BYE")
+	recordISUPCause(call.log, call.state, req)
 	go func(call *outboundCall) {
 		call.cc.AcceptBye(req, tx)
 		call.CloseWithReason(CallHangup, "bye", livekit.DisconnectReason_CLIENT_INITIATED)
@@ -311,6 +444,7 @@ func (c *Client) onNotify(req *sip.Request, tx sip.ServerTransaction) bool {
 	}
 
 	go func() {
+		tx := call.cc.history.ServerTx(req, tx)
 		err := call.cc.handleNotify(req, tx)
 
 		code, msg := sipCodeAndMessageFromError(err)
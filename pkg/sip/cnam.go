@@ -0,0 +1,224 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	defaultCNAMTimeout   = 500 * time.Millisecond
+	defaultCNAMCacheTTL  = 24 * time.Hour
+	defaultCNAMCacheSize = 10000
+	// maxCNAMResponse limits the size of the provider response.
+	maxCNAMResponse = 4096
+	// maxCNAMLength limits the length of the caller name. CNAM is 15 characters in the PSTN, but providers may return more.
+	maxCNAMLength = 64
+)
+
+// CNAMProvider resolves caller ID names for phone numbers.
+// It returns an empty name if the number is unknown.
+type CNAMProvider interface {
+	LookupCNAM(ctx context.Context, number string) (string, error)
+}
+
+// httpCNAMProvider looks up caller names with an HTTP GET request.
+type httpCNAMProvider struct {
+	conf *config.CNAMConfig
+	cli  *http.Client
+}
+
+func newHTTPCNAMProvider(conf *config.CNAMConfig) *httpCNAMProvider {
+	return &httpCNAMProvider{conf: conf, cli: &http.Client{}}
+}
+
+func (p *httpCNAMProvider) LookupCNAM(ctx context.Context, number string) (string, error) {
+	u := strings.ReplaceAll(p.conf.URL, "{number}", url.PathEscape(number))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range p.conf.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := p.cli.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", nil
+	case resp.StatusCode/100 != 2:
+		return "", fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCNAMResponse))
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var v struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return "", err
+		}
+		return v.Name, nil
+	}
+	return string(data), nil
+}
+
+type cnamEntry struct {
+	name    string
+	expires time.Time
+}
+
+// cnamResolver wraps CNAM provider with timeouts and caching.
+type cnamResolver struct {
+	log      logger.Logger
+	provider CNAMProvider
+	timeout  time.Duration
+	ttl      time.Duration
+	size     int
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cnamEntry
+}
+
+// newCNAMResolver creates a resolver for the configured HTTP provider. It returns nil if CNAM is not configured.
+func newCNAMResolver(log logger.Logger, conf *config.CNAMConfig) *cnamResolver {
+	if conf == nil || conf.URL == "" {
+		return nil
+	}
+	return newCNAMResolverWith(log, conf, newHTTPCNAMProvider(conf))
+}
+
+func newCNAMResolverWith(log logger.Logger, conf *config.CNAMConfig, p CNAMProvider) *cnamResolver {
+	r := &cnamResolver{
+		log:      log,
+		provider: p,
+		timeout:  defaultCNAMTimeout,
+		ttl:      defaultCNAMCacheTTL,
+		size:     defaultCNAMCacheSize,
+		now:      time.Now,
+		cache:    make(map[string]cnamEntry),
+	}
+	if conf != nil {
+		if conf.Timeout > 0 {
+			r.timeout = conf.Timeout
+		}
+		if conf.CacheTTL > 0 {
+			r.ttl = conf.CacheTTL
+		}
+		if conf.CacheSize > 0 {
+			r.size = conf.CacheSize
+		}
+	}
+	return r
+}
+
+// Lookup returns the caller name for a number, or an empty string if it's unknown or the lookup fails.
+// It never blocks longer than the configured timeout.
+func (r *cnamResolver) Lookup(ctx context.Context, number string) string {
+	if r == nil || number == "" {
+		return ""
+	}
+	if name, ok := r.cached(number); ok {
+		return name
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	name, err := r.provider.LookupCNAM(ctx, number)
+	if err != nil {
+		// Failures are not cached, the provider may recover.
+		r.log.Infow("cannot lookup caller name", "error", err, "number", number)
+		return ""
+	}
+	name = sanitizeCNAM(name)
+	r.store(number, name)
+	return name
+}
+
+func (r *cnamResolver) cached(number string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.cache[number]
+	if !ok {
+		return "", false
+	}
+	if r.now().After(e.expires) {
+		delete(r.cache, number)
+		return "", false
+	}
+	return e.name, true
+}
+
+func (r *cnamResolver) store(number, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if len(r.cache) >= r.size {
+		for k, e := range r.cache {
+			if now.After(e.expires) {
+				delete(r.cache, k)
+			}
+		}
+	}
+	// Still full, evict an arbitrary entry.
+	for k := range r.cache {
+		if len(r.cache) < r.size {
+			break
+		}
+		delete(r.cache, k)
+	}
+	r.cache[number] = cnamEntry{name: name, expires: now.Add(r.ttl)}
+}
+
+// sanitizeCNAM removes control characters and limits the length of the caller name.
+func sanitizeCNAM(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if r := []rune(name); len(r) > maxCNAMLength {
+		name = strings.TrimSpace(string(r[:maxCNAMLength]))
+	}
+	return name
+}
+
+// SetCNAMProvider sets a custom caller ID name provider, overriding the one from the config.
+func (s *Server) SetCNAMProvider(p CNAMProvider) {
+	if p == nil {
+		s.cnam = nil
+		return
+	}
+	s.cnam = newCNAMResolverWith(s.log, s.conf.CNAM, p)
+}
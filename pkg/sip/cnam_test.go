@@ -0,0 +1,124 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/sipgo/sip"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestCNAMResolver(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		require.Equal(t, "secret", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/cnam/+15550100":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name": "ACME Corp"}`))
+		case "/cnam/+15550101":
+			_, _ = w.Write([]byte("Bob\r\n"))
+		case "/cnam/+15550102":
+			time.Sleep(time.Second)
+			_, _ = w.Write([]byte("Slow"))
+		case "/cnam/+15550103":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	r := newCNAMResolver(logger.GetLogger(), &config.CNAMConfig{
+		URL:       srv.URL + "/cnam/{number}",
+		Headers:   map[string]string{"Authorization": "secret"},
+		Timeout:   100 * time.Millisecond,
+		CacheSize: 2,
+	})
+	ctx := context.Background()
+
+	require.Equal(t, "ACME Corp", r.Lookup(ctx, "+15550100"))
+	require.Equal(t, "ACME Corp", r.Lookup(ctx, "+15550100"))
+	require.EqualValues(t, 1, requests.Load(), "expected a cache hit")
+
+	require.Equal(t, "Bob", r.Lookup(ctx, "+15550101"))
+
+	start := time.Now()
+	require.Equal(t, "", r.Lookup(ctx, "+15550102"))
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// Failures are not cached.
+	requests.Store(0)
+	require.Equal(t, "", r.Lookup(ctx, "+15550103"))
+	require.Equal(t, "", r.Lookup(ctx, "+15550103"))
+	require.EqualValues(t, 2, requests.Load())
+
+	// Unknown numbers are cached.
+	requests.Store(0)
+	require.Equal(t, "", r.Lookup(ctx, "+15550104"))
+	require.Equal(t, "", r.Lookup(ctx, "+15550104"))
+	require.EqualValues(t, 1, requests.Load())
+	require.LessOrEqual(t, len(r.cache), 2)
+
+	// Expired entries are refreshed.
+	r.now = func() time.Time { return time.Now().Add(defaultCNAMCacheTTL + time.Minute) }
+	requests.Store(0)
+	require.Equal(t, "", r.Lookup(ctx, "+15550104"))
+	require.EqualValues(t, 1, requests.Load())
+
+	var nilResolver *cnamResolver
+	require.Equal(t, "", nilResolver.Lookup(ctx, "+15550100"))
+}
+
+func TestSanitizeCNAM(t *testing.T) {
+	require.Equal(t, "Bob", sanitizeCNAM(" B\x00ob\n"))
+	long := "0123456789012345678901234567890123456789012345678901234567890123456789"
+	require.Equal(t, long[:maxCNAMLength], sanitizeCNAM(long))
+}
+
+func TestService_CNAM(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ACME Corp"))
+	}))
+	t.Cleanup(srv.Close)
+
+	names := make(chan string, 1)
+	h := &TestHandler{
+		GetAuthCredentialsFunc: func(ctx context.Context, call *rpc.SIPCall) (AuthInfo, error) {
+			return AuthInfo{Result: AuthAccept}, nil
+		},
+		DispatchCallFunc: func(ctx context.Context, info *CallInfo) CallDispatch {
+			names <- info.CallerName
+			return CallDispatch{Result: DispatchNoRuleReject}
+		},
+	}
+	conf := &config.Config{CNAM: &config.CNAMConfig{URL: srv.URL + "/{number}"}}
+	testInviteConf(t, h, conf, "+15550100", "bar", true, func(tx sip.ClientTransaction) {
+		for getResponseOrFail(t, tx).StatusCode < 200 {
+		}
+		require.Equal(t, "ACME Corp", <-names)
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/livekit/sipgo/sip"
+)
+
+// statusUnsupportedURIScheme is sent for Request-URIs with schemes other than sip, sips and tel (RFC 3261, section 8.2.2.1).
+const statusUnsupportedURIScheme sip.StatusCode = 416
+
+// checkRequest rejects malformed requests, like the ones from RFC 4475, before they reach handlers.
+// It returns a status code and a reason if the request must be rejected.
+func checkRequest(req *sip.Request) (sip.StatusCode, string, bool) {
+	switch {
+	case req.Via() == nil:
+		return sip.StatusBadRequest, "Missing Via", false
+	case req.From() == nil:
+		return sip.StatusBadRequest, "Missing From", false
+	case req.To() == nil:
+		return sip.StatusBadRequest, "Missing To", false
+	case req.CallID() == nil:
+		return sip.StatusBadRequest, "Missing Call-ID", false
+	case req.CSeq() == nil:
+		return sip.StatusBadRequest, "Missing CSeq", false
+	}
+	if req.CSeq().MethodName != req.Method {
+		return sip.StatusBadRequest, "CSeq method does not match", false
+	}
+	switch req.Recipient.Scheme {
+	case "sip", "sips", "tel", "": // empty scheme means sip
+	default:
+		return statusUnsupportedURIScheme, "Unsupported URI Scheme", false
+	}
+	return 0, "", true
+}
+
+// unescapeUser decodes escaped characters in the user part of a URI, so that "sip:%2B1555@host" is routed
+// the same way as "sip:+1555@host" (RFC 3261, section 19.1.4). Invalid escapes are kept as is.
+func unescapeUser(user string) string {
+	if !strings.Contains(user, "%") {
+		return user
+	}
+	v, err := url.PathUnescape(user)
+	if err != nil {
+		return user
+	}
+	return v
+}
+
+// unfoldHeaders joins header lines folded with leading whitespace (RFC 3261, section 7.3.1) in place,
+// since the parser only accepts headers on a single line. It returns the new length of the message.
+func unfoldHeaders(b []byte) int {
+	end := bytes.Index(b, []byte("\r\n\r\n"))
+	if end < 0 {
+		end = len(b)
+	}
+	if !bytes.Contains(b[:end], []byte("\r\n ")) && !bytes.Contains(b[:end], []byte("\r\n\t")) {
+		return len(b)
+	}
+	w := 0
+	for r := 0; r < end; {
+		if b[r] == '\r' && r+2 < len(b) && b[r+1] == '\n' && (b[r+2] == ' ' || b[r+2] == '\t') {
+			b[w] = ' '
+			w++
+			for r += 2; r < end && (b[r] == ' ' || b[r] == '\t'); r++ {
+			}
+			continue
+		}
+		b[w] = b[r]
+		w++
+		r++
+	}
+	w += copy(b[w:], b[end:])
+	return w
+}
+
+// unfoldPacketConn unfolds headers of SIP messages received over UDP before they are parsed.
+type unfoldPacketConn struct {
+	net.PacketConn
+}
+
+func (c unfoldPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(b)
+	if err == nil {
+		n = unfoldHeaders(b[:n])
+	}
+	return n, addr, err
+}
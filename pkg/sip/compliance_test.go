@@ -0,0 +1,120 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/livekit/sipgo/sip"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestCheckRequest(t *testing.T) {
+	newReq := func(uri sip.Uri, skip string, cseq sip.RequestMethod) *sip.Request {
+		req := sip.NewRequest(sip.INVITE, uri)
+		if skip != "Via" {
+			req.AppendHeader(&sip.ViaHeader{ProtocolName: "SIP", ProtocolVersion: "2.0", Transport: "UDP", Host: "192.0.2.2", Port: 5060, Params: sip.NewParams()})
+		}
+		if skip != "From" {
+			req.AppendHeader(&sip.FromHeader{Address: sip.Uri{Scheme: "sip", User: "caller", Host: "example.com"}, Params: sip.NewParams()})
+		}
+		if skip != "To" {
+			req.AppendHeader(&sip.ToHeader{Address: uri, Params: sip.NewParams()})
+		}
+		if skip != "Call-ID" {
+			callID := sip.CallIDHeader("torture-1")
+			req.AppendHeader(&callID)
+		}
+		if skip != "CSeq" {
+			req.AppendHeader(&sip.CSeqHeader{SeqNo: 1, MethodName: cseq})
+		}
+		return req
+	}
+	uri := sip.Uri{Scheme: "sip", User: "callee", Host: "example.com"}
+
+	_, _, ok := checkRequest(newReq(uri, "", sip.INVITE))
+	require.True(t, ok)
+	_, _, ok = checkRequest(newReq(sip.Uri{Scheme: "tel", Host: "+15550100"}, "", sip.INVITE))
+	require.True(t, ok)
+
+	// RFC 4475 "insuf": missing mandatory headers.
+	for _, h := range []string{"Via", "From", "To", "Call-ID", "CSeq"} {
+		code, reason, ok := checkRequest(newReq(uri, h, sip.INVITE))
+		require.False(t, ok, h)
+		require.Equal(t, sip.StatusBadRequest, code, h)
+		require.Equal(t, "Missing "+h, reason)
+	}
+
+	// RFC 4475 "mismatch01": method in CSeq doesn't match the request.
+	code, _, ok := checkRequest(newReq(uri, "", sip.ACK))
+	require.False(t, ok)
+	require.Equal(t, sip.StatusBadRequest, code)
+
+	// RFC 4475 "unksm": unknown Request-URI scheme.
+	code, _, ok = checkRequest(newReq(sip.Uri{Scheme: "isbn", Host: "2983792873"}, "", sip.INVITE))
+	require.False(t, ok)
+	require.Equal(t, statusUnsupportedURIScheme, code)
+}
+
+func TestRequestURILimit(t *testing.T) {
+	// RFC 4475 "longreq": long values in the Request-URI.
+	req := sip.NewRequest(sip.INVITE, sip.Uri{User: strings.Repeat("a", 2000), Host: "example.com"})
+	code, _, ok := newRequestLimits(config.SIPLimitsConfig{}).Check(req)
+	require.False(t, ok)
+	require.Equal(t, sip.StatusRequestURITooLong, code)
+
+	_, _, ok = newRequestLimits(config.SIPLimitsConfig{MaxURILength: -1}).Check(req)
+	require.True(t, ok)
+}
+
+func TestUnescapeUser(t *testing.T) {
+	// RFC 4475 "esc01" and "escnull": escaped characters in the user part.
+	require.Equal(t, "+15550100", unescapeUser("%2B15550100"))
+	require.Equal(t, "sips:user@example.com", unescapeUser("sips%3Auser%40example.com"))
+	require.Equal(t, "null-\x00-null", unescapeUser("null-%00-null"))
+	require.Equal(t, "bad%zzescape", unescapeUser("bad%zzescape"))
+	require.Equal(t, "bob", unescapeUser("bob"))
+}
+
+func TestUnfoldHeaders(t *testing.T) {
+	// RFC 4475 "wsinv": header folding with unusual whitespace.
+	const msg = "INVITE sip:vivekg@chair-dnrc.example.com SIP/2.0\r\n" +
+		"TO :\r\n sip:vivekg@chair-dnrc.example.com ;   tag    = 1918181833n\r\n" +
+		"from   : <sip:jdrosen@example.com>\r\n  ;\r\n  tag = 98asjd8\r\n" +
+		"cseq: 0009\r\n  INVITE\r\n" +
+		"Via  : SIP  /   2.0\r\n  /UDP\r\n\t192.0.2.2;branch=390skdjuw\r\n" +
+		"Content-Length: 10\r\n" +
+		"\r\n" +
+		"v=0\r\n \r\nx\r\n"
+	const exp = "INVITE sip:vivekg@chair-dnrc.example.com SIP/2.0\r\n" +
+		"TO : sip:vivekg@chair-dnrc.example.com ;   tag    = 1918181833n\r\n" +
+		"from   : <sip:jdrosen@example.com> ; tag = 98asjd8\r\n" +
+		"cseq: 0009 INVITE\r\n" +
+		"Via  : SIP  /   2.0 /UDP 192.0.2.2;branch=390skdjuw\r\n" +
+		"Content-Length: 10\r\n" +
+		"\r\n" +
+		"v=0\r\n \r\nx\r\n"
+	b := []byte(msg)
+	n := unfoldHeaders(b)
+	require.Equal(t, exp, string(b[:n]))
+
+	// Messages without folding are not changed.
+	b = []byte(exp)
+	require.Equal(t, len(exp), unfoldHeaders(b))
+	require.Equal(t, exp, string(b))
+}
@@ -31,7 +31,7 @@ func GetServiceConfig(conf *config.Config) (*ServiceConfig, error) {
 	s := new(ServiceConfig)
 	var err error
 	if conf.UseExternalIP {
-		if s.SignalingIP, err = getPublicIP(); err != nil {
+		if s.SignalingIP, err = getPublicIP(stunServers(conf)); err != nil {
 			return nil, err
 		}
 		if s.SignalingIPLocal, err = getLocalIP(conf.LocalNet); err != nil {
@@ -51,7 +51,7 @@ func GetServiceConfig(conf *config.Config) (*ServiceConfig, error) {
 		s.SignalingIPLocal = s.SignalingIP
 	}
 	if conf.MediaUseExternalIP && !conf.UseExternalIP {
-		if s.MediaIP, err = getPublicIP(); err != nil {
+		if s.MediaIP, err = getPublicIP(stunServers(conf)); err != nil {
 			return nil, err
 		}
 	} else if conf.MediaNAT1To1IP != "" && conf.MediaNAT1To1IP != conf.NAT1To1IP {
@@ -66,11 +66,19 @@ func GetServiceConfig(conf *config.Config) (*ServiceConfig, error) {
 	return s, nil
 }
 
-func getPublicIP() (netip.Addr, error) {
+// stunServers returns STUN servers used to discover the public IP.
+func stunServers(conf *config.Config) []string {
+	if conf.PublicIP != nil && len(conf.PublicIP.StunServers) != 0 {
+		return conf.PublicIP.StunServers
+	}
+	return rtcconfig.DefaultStunServers
+}
+
+func getPublicIP(servers []string) (netip.Addr, error) {
 	var err error
 	for i := 0; i < 3; i++ {
 		var ip string
-		ip, err = rtcconfig.GetExternalIP(context.Background(), rtcconfig.DefaultStunServers, nil)
+		ip, err = rtcconfig.GetExternalIP(context.Background(), servers, nil)
 		if err == nil {
 			return netip.ParseAddr(ip)
 		} else {
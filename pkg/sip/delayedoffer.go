@@ -0,0 +1,130 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/livekit/media-sdk/sdp"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/sipgo/sip"
+)
+
+// ackTimeout is the time we wait for ACK after sending 200 OK, same as Timer H in RFC 3261.
+const ackTimeout = 32 * time.Second
+
+// delayedOffer is set for inbound calls that sent INVITE without SDP (RFC 3261, section 13.2.1).
+// In that case we send an offer in 200 OK and expect an answer in ACK.
+type delayedOffer struct {
+	offer    *sdp.Offer
+	enc      sdp.Encryption
+	features []livekit.SIPFeature
+}
+
+// ackState tracks the ACK for 200 OK response to the initial INVITE.
+type ackState struct {
+	once sync.Once
+	done chan struct{}
+	body []byte
+}
+
+func newAckState() *ackState {
+	return &ackState{done: make(chan struct{})}
+}
+
+func (a *ackState) set(body []byte) {
+	a.once.Do(func() {
+		a.body = body
+		close(a.done)
+	})
+}
+
+func (s *Server) onAck(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
+	tag, err := getFromTag(req)
+	if err != nil {
+		return
+	}
+	s.cmu.RLock()
+	c := s.activeCalls[tag]
+	s.cmu.RUnlock()
+	if c != nil {
+		c.cc.AcceptAck(req)
+	}
+}
+
+// AcceptAck records the ACK for 200 OK, along with an SDP answer it may contain.
+func (c *sipInbound) AcceptAck(req *sip.Request) {
+	c.history.Request(dialogIn, req)
+	answer, _, err := requestSDP(req)
+	if err != nil {
+		c.s.log.Infow("Invalid ACK body", "error", err)
+	}
+	c.acked.set(answer)
+}
+
+// Acked is closed when the ACK for 200 OK is received.
+func (c *sipInbound) Acked() <-chan struct{} {
+	return c.acked.done
+}
+
+// AckBody returns the body of the ACK. It must only be called after Acked is closed.
+func (c *sipInbound) AckBody() []byte {
+	return c.acked.body
+}
+
+// waitDelayedAnswer waits for the SDP answer in ACK and configures media accordingly.
+// The call must already be accepted. On failure, it closes the call.
+func (c *inboundCall) waitDelayedAnswer(ctx context.Context, d *delayedOffer) (bool, error) {
+	timer := time.NewTimer(ackTimeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		c.closeWithHangup()
+		return false, nil
+	case <-c.cc.Cancelled():
+		c.closeWithCancelled()
+		return false, nil
+	case <-timer.C:
+		c.log.Warnw("No ACK for delayed offer", nil)
+		c.close(true, callMediaFailed, "no-ack")
+		return false, psrpc.NewErrorf(psrpc.DeadlineExceeded, "no ACK received")
+	case <-c.cc.Acked():
+	}
+	answerData := c.cc.AckBody()
+	if len(answerData) == 0 {
+		c.log.Warnw("No SDP answer in ACK for delayed offer", nil)
+		c.close(false, callMediaFailed, "no-answer")
+		return false, psrpc.NewErrorf(psrpc.InvalidArgument, "no SDP answer in ACK")
+	}
+	c.mon.SDPSize(len(answerData), true)
+	c.log.Debugw("SDP answer", "sdp", string(answerData))
+	mconf, err := c.media.SetAnswer(d.offer, answerData, d.enc)
+	if res := mediaEncryptionResult(mconf, err); res != "" {
+		c.mon.MediaEncryption(res)
+	}
+	if err == nil {
+		err = c.setMediaConf(mconf, d.features)
+	}
+	if err != nil {
+		c.log.Warnw("Cannot apply SDP answer from ACK", err)
+		c.close(false, callMediaFailed, "bad-answer")
+		return false, psrpc.NewErrorf(psrpc.InvalidArgument, "cannot apply SDP answer: %v", err)
+	}
+	return true, nil
+}
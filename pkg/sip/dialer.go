@@ -0,0 +1,453 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	lksip "github.com/livekit/protocol/sip"
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	// maxDialAttempts is the upper limit of attempts of a single dial job.
+	maxDialAttempts = 10
+	// dialJobRetention is how long finished jobs are kept for status requests.
+	dialJobRetention = time.Hour
+	// dialerIdleWait is the longest time the dialer sleeps without checking jobs.
+	dialerIdleWait = time.Minute
+)
+
+// DialJobState is the state of a dial job.
+type DialJobState string
+
+const (
+	// DialJobScheduled waits for the start time, the next retry or a free slot on the trunk.
+	DialJobScheduled = DialJobState("scheduled")
+	// DialJobDialing is placing the call.
+	DialJobDialing = DialJobState("dialing")
+	// DialJobAnswered is done, the call was answered.
+	DialJobAnswered = DialJobState("answered")
+	// DialJobFailed is done, the last attempt failed and no attempts are left.
+	DialJobFailed = DialJobState("failed")
+	// DialJobCanceled is done, the job was canceled.
+	DialJobCanceled = DialJobState("canceled")
+)
+
+// Finished reports if the job will not place any more calls.
+func (s DialJobState) Finished() bool {
+	switch s {
+	case DialJobAnswered, DialJobFailed, DialJobCanceled:
+		return true
+	}
+	return false
+}
+
+// DialJob is a scheduled outbound call.
+type DialJob struct {
+	// ID of the job. It's generated if not set.
+	ID string `json:"id,omitempty"`
+	// Call is the outbound call request, same as for CreateSIPParticipant. It must include the room token.
+	// SipCallId is generated for each attempt, and the call always waits until it's answered.
+	Call *rpc.InternalCreateSIPParticipantRequest `json:"call"`
+	// StartAt is the earliest time of the first attempt. The call is placed immediately if not set.
+	StartAt time.Time `json:"start_at,omitzero"`
+	// MaxAttempts limits attempts of a busy or unanswered call, including the first one. Default is 1.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// RetryIntervalMs is the delay before the next attempt. Default is set in the dialer config.
+	RetryIntervalMs int `json:"retry_interval_ms,omitempty"`
+	// Metadata is returned with the job status. It's not used by the dialer.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// DialJobsRequest submits a batch of dial jobs.
+type DialJobsRequest struct {
+	Jobs []DialJob `json:"jobs"`
+}
+
+// DialJobsResponse lists IDs of submitted jobs, in the order of the request.
+type DialJobsResponse struct {
+	IDs []string `json:"ids"`
+}
+
+// DialJobRequest selects a dial job.
+type DialJobRequest struct {
+	ID string `json:"id"`
+}
+
+// DialJobStatus reports the state of a dial job.
+type DialJobStatus struct {
+	ID          string       `json:"id"`
+	State       DialJobState `json:"state"`
+	Attempts    int          `json:"attempts"`
+	MaxAttempts int          `json:"max_attempts"`
+	// NextAttempt is the time of the next attempt, if the job is scheduled.
+	NextAttempt time.Time `json:"next_attempt,omitzero"`
+	// SipCallID is the call ID of the last attempt.
+	SipCallID string `json:"sip_call_id,omitempty"`
+	// StatusCode is the SIP status of the last failed attempt, if it was rejected by the remote.
+	StatusCode int               `json:"status_code,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+type dialFunc func(ctx context.Context, req *rpc.InternalCreateSIPParticipantRequest) (*rpc.InternalCreateSIPParticipantResponse, error)
+
+type dialJob struct {
+	st       DialJobStatus
+	call     *rpc.InternalCreateSIPParticipantRequest
+	retry    time.Duration
+	cancel   context.CancelFunc // set while dialing
+	canceled bool
+	doneAt   time.Time
+}
+
+// dialRetryable reports if a failed call may succeed later, because the callee was busy or didn't answer.
+func dialRetryable(err error) bool {
+	var e *livekit.SIPStatus
+	if errors.As(err, &e) {
+		switch int(e.Code) {
+		case int(sip.StatusBusyHere), int(sip.StatusGlobalBusyEverywhere),
+			int(sip.StatusTemporarilyUnavailable), int(sip.StatusRequestTimeout), int(sip.StatusRequestTerminated):
+			return true
+		}
+		return false
+	}
+	// Ringing timeout cancels the INVITE.
+	var perr psrpc.Error
+	if errors.As(err, &perr) {
+		switch perr.Code() {
+		case psrpc.Canceled, psrpc.DeadlineExceeded:
+			return true
+		}
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// cpsLimiter spaces call starts on each trunk to stay within its limit of calls per second.
+type cpsLimiter struct {
+	rate func(trunkID string) float64
+	next map[string]time.Time
+}
+
+func newCPSLimiter(rate func(trunkID string) float64) *cpsLimiter {
+	return &cpsLimiter{rate: rate, next: make(map[string]time.Time)}
+}
+
+// Reserve takes a slot and returns now if a call can start on the trunk. Otherwise, it returns the time of the next free slot.
+func (l *cpsLimiter) Reserve(trunkID string, now time.Time) time.Time {
+	cps := l.rate(trunkID)
+	if cps <= 0 {
+		return now
+	}
+	if next := l.next[trunkID]; next.After(now) {
+		return next
+	}
+	l.next[trunkID] = now.Add(time.Duration(float64(time.Second) / cps))
+	return now
+}
+
+// dialer places scheduled outbound calls and retries the ones that were busy or not answered.
+type dialer struct {
+	log  logger.Logger
+	conf config.DialerConfig
+	dial dialFunc
+	wake chan struct{}
+	stop core.Fuse
+	ctx  context.Context // canceled on stop
+	done context.CancelFunc
+
+	// emergency checks if a call is placed to an emergency number. Such calls are not paced.
+	emergency func(trunkID, number string) bool
+
+	mu   sync.Mutex
+	cps  *cpsLimiter
+	jobs map[string]*dialJob
+}
+
+func newDialer(log logger.Logger, conf *config.Config, dial dialFunc) *dialer {
+	d := &dialer{
+		log:  log,
+		conf: conf.Dialer,
+		dial: dial,
+		wake: make(chan struct{}, 1),
+		cps:  newCPSLimiter(conf.TrunkMaxCPS),
+		jobs: make(map[string]*dialJob),
+		emergency: func(trunkID, number string) bool {
+			return isEmergencyNumber(conf.TrunkEmergency(trunkID), number)
+		},
+	}
+	if d.conf.MaxJobs == 0 {
+		d.conf.MaxJobs = config.DefaultDialerMaxJobs
+	}
+	if d.conf.RetryInterval == 0 {
+		d.conf.RetryInterval = config.DefaultDialerRetryInterval
+	}
+	d.ctx, d.done = context.WithCancel(context.Background())
+	return d
+}
+
+func (d *dialer) Start() {
+	go d.run()
+}
+
+// Stop the dialer and cancel calls which are not answered yet.
+func (d *dialer) Stop() {
+	d.stop.Break()
+	d.done()
+}
+
+func (d *dialer) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *dialer) run() {
+	timer := time.NewTimer(dialerIdleWait)
+	defer timer.Stop()
+	for {
+		timer.Reset(d.dispatch(time.Now()))
+		select {
+		case <-d.stop.Watch():
+			return
+		case <-d.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// Submit schedules a batch of jobs. Either all jobs are accepted, or none.
+func (d *dialer) Submit(req *DialJobsRequest) (*DialJobsResponse, error) {
+	if len(req.Jobs) == 0 {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "no dial jobs")
+	}
+	now := time.Now()
+	jobs := make([]*dialJob, 0, len(req.Jobs))
+	for i, r := range req.Jobs {
+		if r.Call == nil {
+			return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "job %d: call must be set", i)
+		}
+		if r.MaxAttempts < 0 || r.MaxAttempts > maxDialAttempts {
+			return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "job %d: max attempts must be between 1 and %d", i, maxDialAttempts)
+		}
+		if r.RetryIntervalMs < 0 {
+			return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "job %d: retry interval must not be negative", i)
+		}
+		j := &dialJob{
+			st: DialJobStatus{
+				ID:          r.ID,
+				State:       DialJobScheduled,
+				MaxAttempts: max(r.MaxAttempts, 1),
+				NextAttempt: r.StartAt,
+				Metadata:    maps.Clone(r.Metadata),
+			},
+			call:  proto.Clone(r.Call).(*rpc.InternalCreateSIPParticipantRequest),
+			retry: time.Duration(r.RetryIntervalMs) * time.Millisecond,
+		}
+		if j.st.ID == "" {
+			j.st.ID = guid.New("SDJ_")
+		}
+		if j.st.NextAttempt.IsZero() {
+			j.st.NextAttempt = now
+		}
+		if j.retry == 0 {
+			j.retry = d.conf.RetryInterval
+		}
+		jobs = append(jobs, j)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	active := 0
+	for _, j := range d.jobs {
+		if !j.st.State.Finished() {
+			active++
+		}
+	}
+	if active+len(jobs) > d.conf.MaxJobs {
+		return nil, psrpc.NewErrorf(psrpc.ResourceExhausted, "too many dial jobs: %d active, limit is %d", active, d.conf.MaxJobs)
+	}
+	ids := make([]string, 0, len(jobs))
+	for _, j := range jobs {
+		if _, ok := d.jobs[j.st.ID]; ok || slices.Contains(ids, j.st.ID) {
+			return nil, psrpc.NewErrorf(psrpc.AlreadyExists, "dial job %q already exists", j.st.ID)
+		}
+		ids = append(ids, j.st.ID)
+	}
+	for _, j := range jobs {
+		d.jobs[j.st.ID] = j
+	}
+	d.log.Infow("dial jobs submitted", "jobs", len(jobs))
+	d.notify()
+	return &DialJobsResponse{IDs: ids}, nil
+}
+
+// Status returns the status of a job.
+func (d *dialer) Status(id string) (*DialJobStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	j := d.jobs[id]
+	if j == nil {
+		return nil, psrpc.NewErrorf(psrpc.NotFound, "dial job %q not found", id)
+	}
+	st := j.st
+	return &st, nil
+}
+
+// Cancel stops a job. A call that is being placed is canceled, but answered calls are not affected.
+func (d *dialer) Cancel(id string) (*DialJobStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	j := d.jobs[id]
+	if j == nil {
+		return nil, psrpc.NewErrorf(psrpc.NotFound, "dial job %q not found", id)
+	}
+	if j.st.State.Finished() {
+		return nil, psrpc.NewErrorf(psrpc.FailedPrecondition, "dial job %q is already %s", id, j.st.State)
+	}
+	j.canceled = true
+	if j.cancel != nil {
+		j.cancel() // the state is updated when the attempt returns
+	} else {
+		d.finishLocked(j, DialJobCanceled, time.Now())
+	}
+	st := j.st
+	return &st, nil
+}
+
+func (d *dialer) finishLocked(j *dialJob, state DialJobState, now time.Time) {
+	j.st.State = state
+	j.st.NextAttempt = time.Time{}
+	j.doneAt = now
+}
+
+// dispatch starts due jobs which fit into trunk limits and returns the time until the next job is due.
+func (d *dialer) dispatch(now time.Time) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	wait := dialerIdleWait
+	var due []*dialJob
+	for id, j := range d.jobs {
+		switch {
+		case j.st.State.Finished():
+			if now.Sub(j.doneAt) > dialJobRetention {
+				delete(d.jobs, id)
+			}
+		case j.st.State != DialJobScheduled:
+		case j.st.NextAttempt.After(now):
+			wait = min(wait, j.st.NextAttempt.Sub(now))
+		default:
+			due = append(due, j)
+		}
+	}
+	if d.stop.IsBroken() {
+		return wait
+	}
+	slices.SortFunc(due, func(a, b *dialJob) int {
+		if c := a.st.NextAttempt.Compare(b.st.NextAttempt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.st.ID, b.st.ID)
+	})
+	for _, j := range due {
+		if d.emergency(j.call.SipTrunkId, j.call.CallTo) {
+			d.startLocked(j)
+			continue
+		}
+		if at := d.cps.Reserve(j.call.SipTrunkId, now); at.After(now) {
+			wait = min(wait, at.Sub(now))
+			continue
+		}
+		d.startLocked(j)
+	}
+	return wait
+}
+
+func (d *dialer) startLocked(j *dialJob) {
+	j.st.State = DialJobDialing
+	j.st.Attempts++
+	j.st.NextAttempt = time.Time{}
+
+	req := proto.Clone(j.call).(*rpc.InternalCreateSIPParticipantRequest)
+	req.SipCallId = lksip.NewCallID()
+	req.WaitUntilAnswered = true
+	if req.ParticipantAttributes == nil {
+		req.ParticipantAttributes = make(map[string]string)
+	}
+	req.ParticipantAttributes[AttrSIPDialerJob] = j.st.ID
+	req.ParticipantAttributes[AttrSIPDialerAttempt] = strconv.Itoa(j.st.Attempts)
+	j.st.SipCallID = req.SipCallId
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	j.cancel = cancel
+	log := d.log.WithValues("dialerJob", j.st.ID, "attempt", j.st.Attempts, "sipCallID", req.SipCallId)
+	go d.attempt(ctx, log, j, req)
+}
+
+func (d *dialer) attempt(ctx context.Context, log logger.Logger, j *dialJob, req *rpc.InternalCreateSIPParticipantRequest) {
+	log.Infow("dialer placing call")
+	_, err := d.dial(ctx, req)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	defer d.notify()
+	j.cancel()
+	j.cancel = nil
+	now := time.Now()
+	j.st.StatusCode = 0
+	j.st.Error = ""
+	switch {
+	case err == nil:
+		log.Infow("dialer call answered")
+		d.finishLocked(j, DialJobAnswered, now)
+		return
+	case j.canceled:
+		log.Infow("dialer call canceled")
+		d.finishLocked(j, DialJobCanceled, now)
+		return
+	}
+	var e *livekit.SIPStatus
+	if errors.As(err, &e) {
+		j.st.StatusCode = int(e.Code)
+	}
+	j.st.Error = err.Error()
+	if dialRetryable(err) && j.st.Attempts < j.st.MaxAttempts && !d.stop.IsBroken() {
+		j.st.State = DialJobScheduled
+		j.st.NextAttempt = now.Add(j.retry)
+		log.Infow("dialer call not answered, retrying", "error", err, "retryAt", j.st.NextAttempt)
+		return
+	}
+	log.Infow("dialer call failed", "error", err)
+	d.finishLocked(j, DialJobFailed, now)
+}
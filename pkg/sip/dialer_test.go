@@ -0,0 +1,240 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestCPSLimiter(t *testing.T) {
+	l := newCPSLimiter(func(trunkID string) float64 {
+		if trunkID == "limited" {
+			return 2
+		}
+		return 0
+	})
+	now := time.Now()
+	for range 5 {
+		require.Equal(t, now, l.Reserve("free", now))
+	}
+	require.Equal(t, now, l.Reserve("limited", now))
+	require.Equal(t, now.Add(500*time.Millisecond), l.Reserve("limited", now))
+	require.Equal(t, now.Add(500*time.Millisecond), l.Reserve("limited", now.Add(100*time.Millisecond)))
+	later := now.Add(500 * time.Millisecond)
+	require.Equal(t, later, l.Reserve("limited", later))
+}
+
+func TestDialRetryable(t *testing.T) {
+	require.True(t, dialRetryable(fmt.Errorf("INVITE failed: %w", &livekit.SIPStatus{Code: livekit.SIPStatusCode_SIP_STATUS_BUSY_HERE})))
+	require.True(t, dialRetryable(&livekit.SIPStatus{Code: livekit.SIPStatusCode_SIP_STATUS_TEMPORARILY_UNAVAILABLE}))
+	require.True(t, dialRetryable(psrpc.NewErrorf(psrpc.Canceled, "canceled")))
+	require.False(t, dialRetryable(&livekit.SIPStatus{Code: livekit.SIPStatusCode_SIP_STATUS_NOTFOUND}))
+	require.False(t, dialRetryable(psrpc.NewErrorf(psrpc.InvalidArgument, "call-to number must be set")))
+}
+
+type dialAttempt struct {
+	req  *rpc.InternalCreateSIPParticipantRequest
+	resp chan error
+}
+
+func newTestDialer(t *testing.T, conf *config.Config) (*dialer, chan dialAttempt) {
+	calls := make(chan dialAttempt, 10)
+	d := newDialer(logger.GetLogger(), conf, func(ctx context.Context, req *rpc.InternalCreateSIPParticipantRequest) (*rpc.InternalCreateSIPParticipantResponse, error) {
+		a := dialAttempt{req: req, resp: make(chan error, 1)}
+		calls <- a
+		select {
+		case err := <-a.resp:
+			return &rpc.InternalCreateSIPParticipantResponse{SipCallId: req.SipCallId}, err
+		case <-ctx.Done():
+			return nil, psrpc.NewErrorf(psrpc.Canceled, "canceled")
+		}
+	})
+	d.Start()
+	t.Cleanup(d.Stop)
+	return d, calls
+}
+
+func expectDial(t *testing.T, calls chan dialAttempt) dialAttempt {
+	t.Helper()
+	select {
+	case a := <-calls:
+		return a
+	case <-time.After(time.Second):
+		t.Fatal("call not placed")
+		return dialAttempt{}
+	}
+}
+
+func expectDialState(t *testing.T, d *dialer, id string, state DialJobState) *DialJobStatus {
+	t.Helper()
+	var st *DialJobStatus
+	require.Eventually(t, func() bool {
+		var err error
+		st, err = d.Status(id)
+		require.NoError(t, err)
+		return st.State == state
+	}, time.Second, 5*time.Millisecond)
+	return st
+}
+
+func TestDialerRetry(t *testing.T) {
+	d, calls := newTestDialer(t, &config.Config{})
+
+	resp, err := d.Submit(&DialJobsRequest{Jobs: []DialJob{{
+		ID:              "job1",
+		Call:            &rpc.InternalCreateSIPParticipantRequest{SipTrunkId: "trunk", CallTo: "+1000"},
+		MaxAttempts:     2,
+		RetryIntervalMs: 10,
+		Metadata:        map[string]string{"campaign": "test"},
+	}}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"job1"}, resp.IDs)
+
+	a := expectDial(t, calls)
+	require.True(t, a.req.WaitUntilAnswered)
+	require.NotEmpty(t, a.req.SipCallId)
+	require.Equal(t, "job1", a.req.ParticipantAttributes[AttrSIPDialerJob])
+	require.Equal(t, "1", a.req.ParticipantAttributes[AttrSIPDialerAttempt])
+	first := a.req.SipCallId
+	a.resp <- &livekit.SIPStatus{Code: livekit.SIPStatusCode_SIP_STATUS_BUSY_HERE}
+
+	a = expectDial(t, calls)
+	require.Equal(t, "2", a.req.ParticipantAttributes[AttrSIPDialerAttempt])
+	require.NotEqual(t, first, a.req.SipCallId)
+	a.resp <- nil
+
+	st := expectDialState(t, d, "job1", DialJobAnswered)
+	require.Equal(t, 2, st.Attempts)
+	require.Equal(t, a.req.SipCallId, st.SipCallID)
+	require.Equal(t, map[string]string{"campaign": "test"}, st.Metadata)
+	require.Empty(t, st.Error)
+
+	// No attempts left.
+	_, err = d.Submit(&DialJobsRequest{Jobs: []DialJob{{
+		ID:   "job2",
+		Call: &rpc.InternalCreateSIPParticipantRequest{CallTo: "+1001"},
+	}}})
+	require.NoError(t, err)
+	a = expectDial(t, calls)
+	a.resp <- &livekit.SIPStatus{Code: livekit.SIPStatusCode_SIP_STATUS_BUSY_HERE}
+	st = expectDialState(t, d, "job2", DialJobFailed)
+	require.Equal(t, int(livekit.SIPStatusCode_SIP_STATUS_BUSY_HERE), st.StatusCode)
+	require.NotEmpty(t, st.Error)
+}
+
+func TestDialerSchedule(t *testing.T) {
+	d, calls := newTestDialer(t, &config.Config{Dialer: config.DialerConfig{MaxCPS: 10}})
+
+	start := time.Now()
+	_, err := d.Submit(&DialJobsRequest{Jobs: []DialJob{
+		{ID: "later", Call: &rpc.InternalCreateSIPParticipantRequest{SipTrunkId: "trunk"}, StartAt: start.Add(50 * time.Millisecond)},
+		{ID: "now", Call: &rpc.InternalCreateSIPParticipantRequest{SipTrunkId: "trunk"}},
+	}})
+	require.NoError(t, err)
+	st, err := d.Status("later")
+	require.NoError(t, err)
+	require.Equal(t, DialJobScheduled, st.State)
+
+	a := expectDial(t, calls)
+	require.Equal(t, "now", a.req.ParticipantAttributes[AttrSIPDialerJob])
+	a.resp <- nil
+	a = expectDial(t, calls)
+	require.Equal(t, "later", a.req.ParticipantAttributes[AttrSIPDialerJob])
+	// Both the start time and the trunk limit delay the second call.
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	a.resp <- nil
+}
+
+func TestDialerEmergency(t *testing.T) {
+	d, calls := newTestDialer(t, &config.Config{
+		Dialer:    config.DialerConfig{MaxCPS: 0.1},
+		Emergency: config.EmergencyConfig{Country: "US"},
+	})
+
+	_, err := d.Submit(&DialJobsRequest{Jobs: []DialJob{
+		{ID: "a", Call: &rpc.InternalCreateSIPParticipantRequest{SipTrunkId: "trunk", CallTo: "+1000"}},
+		{ID: "b", Call: &rpc.InternalCreateSIPParticipantRequest{SipTrunkId: "trunk", CallTo: "+1001"}},
+		{ID: "c", Call: &rpc.InternalCreateSIPParticipantRequest{SipTrunkId: "trunk", CallTo: "911"}},
+	}})
+	require.NoError(t, err)
+
+	// Emergency calls are not paced, the second regular call waits for 10s.
+	got := make(map[string]bool)
+	for range 2 {
+		a := expectDial(t, calls)
+		got[a.req.ParticipantAttributes[AttrSIPDialerJob]] = true
+		a.resp <- nil
+	}
+	require.True(t, got["a"])
+	require.True(t, got["c"])
+}
+
+func TestDialerCancel(t *testing.T) {
+	d, calls := newTestDialer(t, &config.Config{})
+
+	_, err := d.Submit(&DialJobsRequest{Jobs: []DialJob{
+		{ID: "ringing", Call: &rpc.InternalCreateSIPParticipantRequest{}},
+		{ID: "scheduled", Call: &rpc.InternalCreateSIPParticipantRequest{}, StartAt: time.Now().Add(time.Hour)},
+	}})
+	require.NoError(t, err)
+
+	st, err := d.Cancel("scheduled")
+	require.NoError(t, err)
+	require.Equal(t, DialJobCanceled, st.State)
+	_, err = d.Cancel("scheduled")
+	require.Error(t, err)
+
+	expectDial(t, calls)
+	_, err = d.Cancel("ringing")
+	require.NoError(t, err)
+	st = expectDialState(t, d, "ringing", DialJobCanceled)
+	require.Equal(t, 1, st.Attempts)
+
+	_, err = d.Status("unknown")
+	require.Error(t, err)
+}
+
+func TestDialerSubmitErrors(t *testing.T) {
+	d := newDialer(logger.GetLogger(), &config.Config{Dialer: config.DialerConfig{MaxJobs: 2}}, nil)
+	call := &rpc.InternalCreateSIPParticipantRequest{}
+
+	_, err := d.Submit(&DialJobsRequest{})
+	require.Error(t, err)
+	_, err = d.Submit(&DialJobsRequest{Jobs: []DialJob{{ID: "a"}}})
+	require.Error(t, err, "no call")
+	_, err = d.Submit(&DialJobsRequest{Jobs: []DialJob{{Call: call, MaxAttempts: maxDialAttempts + 1}}})
+	require.Error(t, err)
+	_, err = d.Submit(&DialJobsRequest{Jobs: []DialJob{{ID: "a", Call: call}, {ID: "a", Call: call}}})
+	require.Error(t, err, "duplicate in the batch")
+
+	resp, err := d.Submit(&DialJobsRequest{Jobs: []DialJob{{ID: "a", Call: call}, {Call: call}}})
+	require.NoError(t, err)
+	require.Len(t, resp.IDs, 2)
+	require.NotEmpty(t, resp.IDs[1])
+	_, err = d.Submit(&DialJobsRequest{Jobs: []DialJob{{ID: "b", Call: call}}})
+	require.Error(t, err, "over the limit")
+}
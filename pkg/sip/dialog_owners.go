@@ -0,0 +1,293 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/livekit/sipgo"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	// dialogForwardedHeader is added to requests forwarded to the owner of the dialog. Such requests are never forwarded again.
+	dialogForwardedHeader = "X-Lk-Forwarded-By"
+	// storeTimeout limits requests to stores shared by instances.
+	storeTimeout = time.Second
+)
+
+// DialogStore maps SIP Call-IDs to instances owning the dialog, identified by their SIP address.
+// It is shared by all instances, see NewRedisDialogStore.
+type DialogStore interface {
+	// Register sets the owner of dialogs. Dialogs expire after ttl, unless they are registered again.
+	Register(ctx context.Context, owner string, ttl time.Duration, callIDs ...string) error
+	// Unregister removes dialogs, if they are still owned by the owner.
+	Unregister(ctx context.Context, owner string, callIDs ...string) error
+	// Owner returns the owner of a dialog, or an empty string if the dialog is unknown.
+	Owner(ctx context.Context, callID string) (string, error)
+}
+
+// dialogOwners registers dialogs of this instance in the shared DialogStore, and forwards in-dialog requests
+// for dialogs of other instances to their owners. Requests are proxied statefully: the response of the owner is
+// relayed back in the transaction received by this instance.
+type dialogOwners struct {
+	log     logger.Logger
+	address string
+	ttl     time.Duration
+	store   DialogStore
+	cli     *sipgo.Client // set when the client starts
+	stop    core.Fuse
+	updates storeQueue
+
+	mu    sync.Mutex
+	owned map[string]struct{}
+}
+
+// newDialogOwners creates a registry from the config. It returns nil if the registry is disabled.
+func newDialogOwners(log logger.Logger, conf *config.DialogRegistryConfig, sconf *ServiceConfig, sipPort int) *dialogOwners {
+	if conf == nil {
+		return nil
+	}
+	r := &dialogOwners{
+		log:     log,
+		address: conf.Address,
+		ttl:     conf.TTL,
+		updates: storeQueue{log: log, msg: "cannot update dialog registry"},
+		owned:   make(map[string]struct{}),
+	}
+	if r.address == "" {
+		r.address = net.JoinHostPort(sconf.SignalingIPLocal.String(), strconv.Itoa(sipPort))
+	}
+	if r.ttl == 0 {
+		r.ttl = config.DefaultDialogTTL
+	}
+	return r
+}
+
+// Add registers a dialog owned by this instance.
+func (r *dialogOwners) Add(callID string) {
+	if r == nil || callID == "" {
+		return
+	}
+	r.mu.Lock()
+	r.owned[callID] = struct{}{}
+	r.mu.Unlock()
+	r.updates.Push(func(ctx context.Context) error {
+		return r.store.Register(ctx, r.address, r.ttl, callID)
+	})
+}
+
+// Remove unregisters a dialog once the call ends.
+func (r *dialogOwners) Remove(callID string) {
+	if r == nil || callID == "" {
+		return
+	}
+	r.mu.Lock()
+	_, ok := r.owned[callID]
+	delete(r.owned, callID)
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+	r.updates.Push(func(ctx context.Context) error {
+		return r.store.Unregister(ctx, r.address, callID)
+	})
+}
+
+func (r *dialogOwners) owns(callID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.owned[callID]
+	return ok
+}
+
+// storeQueue updates a shared store in the background. Updates are applied in order, so that a dialog of a short call
+// is not registered after it's removed.
+type storeQueue struct {
+	log logger.Logger
+	msg string // logged if an update fails
+
+	mu    sync.Mutex
+	queue []func(ctx context.Context) error
+}
+
+func (q *storeQueue) Push(fnc func(ctx context.Context) error) {
+	q.mu.Lock()
+	q.queue = append(q.queue, fnc)
+	start := len(q.queue) == 1
+	q.mu.Unlock()
+	if start {
+		go q.flush()
+	}
+}
+
+func (q *storeQueue) flush() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.queue) != 0 {
+		fnc := q.queue[0]
+		q.mu.Unlock()
+		ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		if err := fnc(ctx); err != nil {
+			q.log.Warnw(q.msg, err)
+		}
+		cancel()
+		q.mu.Lock()
+		q.queue = q.queue[1:]
+	}
+}
+
+func (r *dialogOwners) Start(cli *sipgo.Client) {
+	if r == nil {
+		return
+	}
+	r.cli = cli
+	go r.run()
+}
+
+func (r *dialogOwners) Stop() {
+	if r == nil {
+		return
+	}
+	r.stop.Break()
+}
+
+// run refreshes dialogs of active calls, so that they don't expire.
+func (r *dialogOwners) run() {
+	ticker := time.NewTicker(r.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop.Watch():
+			return
+		case <-ticker.C:
+			r.updates.Push(r.refresh)
+		}
+	}
+}
+
+func (r *dialogOwners) refresh(ctx context.Context) error {
+	r.mu.Lock()
+	callIDs := slices.Collect(maps.Keys(r.owned))
+	r.mu.Unlock()
+	if len(callIDs) == 0 {
+		return nil
+	}
+	return r.store.Register(ctx, r.address, r.ttl, callIDs...)
+}
+
+// dialogOwner returns the address of another instance which owns the dialog of the request.
+// It returns an empty string if the request must be handled by this instance.
+func (r *dialogOwners) dialogOwner(req *sip.Request) string {
+	to := req.To()
+	if to == nil || req.GetHeader(dialogForwardedHeader) != nil {
+		return ""
+	}
+	if _, ok := getTagFrom(to.Params); !ok {
+		// Not an in-dialog request.
+		return ""
+	}
+	h := req.CallID()
+	if h == nil || r.owns(h.Value()) {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	owner, err := r.store.Owner(ctx, h.Value())
+	if err != nil {
+		r.log.Warnw("cannot find dialog owner", err, "sipCallID", h.Value())
+		return ""
+	}
+	if owner == r.address {
+		return ""
+	}
+	return owner
+}
+
+// forwardDialogs returns a handler which forwards in-dialog requests to the instance owning the dialog,
+// and passes other requests to h.
+func (s *Server) forwardDialogs(h sipgo.RequestHandler) sipgo.RequestHandler {
+	r := s.owners
+	if r == nil {
+		return h
+	}
+	return func(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
+		owner := r.dialogOwner(req)
+		if owner == "" || r.cli == nil {
+			h(log, req, tx)
+			return
+		}
+		r.forward(req, tx, owner)
+	}
+}
+
+func (r *dialogOwners) forward(req *sip.Request, tx sip.ServerTransaction, owner string) {
+	log := r.log.WithValues("method", req.Method.String(), "sipCallID", req.CallID().Value(), "owner", owner)
+	log.Debugw("forwarding request to dialog owner")
+	fwd := req.Clone()
+	fwd.SetDestination(owner)
+	fwd.AppendHeader(sip.NewHeader(dialogForwardedHeader, r.address))
+	if req.IsAck() {
+		if err := r.cli.WriteRequest(fwd, sipgo.ClientRequestAddVia, sipgo.ClientRequestDecreaseMaxForward); err != nil {
+			log.Warnw("cannot forward request to dialog owner", err)
+		}
+		return
+	}
+	ftx, err := r.cli.TransactionRequest(fwd, sipgo.ClientRequestAddVia, sipgo.ClientRequestDecreaseMaxForward)
+	if err != nil {
+		log.Warnw("cannot forward request to dialog owner", err)
+		_ = tx.Respond(sip.NewResponseFromRequest(req, sip.StatusServiceUnavailable, "Service Unavailable", nil))
+		return
+	}
+	defer ftx.Terminate()
+	resp, err := sipResponse(context.Background(), ftx, r.stop.Watch(), func(resp *sip.Response) {
+		if resp.StatusCode/100 == 1 && resp.StatusCode != sip.StatusTrying {
+			_ = tx.Respond(relayedResponse(req, resp))
+		}
+	})
+	if err != nil {
+		log.Warnw("no response from dialog owner", err)
+		_ = tx.Respond(sip.NewResponseFromRequest(req, sip.StatusRequestTimeout, "Request Timeout", nil))
+		return
+	}
+	_ = tx.Respond(relayedResponse(req, resp))
+}
+
+// relayedResponse creates a response to req with the status, headers and body of a response received from the owner.
+func relayedResponse(req *sip.Request, resp *sip.Response) *sip.Response {
+	out := sip.NewResponseFromRequest(req, resp.StatusCode, resp.Reason, resp.Body())
+	for _, h := range resp.Headers() {
+		switch strings.ToLower(h.Name()) {
+		case "via", "from", "to", "call-id", "cseq", "content-length", "record-route":
+			// Set from the request.
+			continue
+		}
+		out.AppendHeader(sip.HeaderClone(h))
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/sipgo"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+type testDialogStore struct {
+	mu     sync.Mutex
+	owners map[string]string
+}
+
+func newTestDialogStore() *testDialogStore {
+	return &testDialogStore{owners: make(map[string]string)}
+}
+
+func (s *testDialogStore) Register(ctx context.Context, owner string, ttl time.Duration, callIDs ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range callIDs {
+		s.owners[id] = owner
+	}
+	return nil
+}
+
+func (s *testDialogStore) Unregister(ctx context.Context, owner string, callIDs ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range callIDs {
+		if s.owners[id] == owner {
+			delete(s.owners, id)
+		}
+	}
+	return nil
+}
+
+func (s *testDialogStore) Owner(ctx context.Context, callID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.owners[callID], nil
+}
+
+func (s *testDialogStore) get(callID string) string {
+	owner, _ := s.Owner(context.Background(), callID)
+	return owner
+}
+
+func newTestDialogRequest(method sip.RequestMethod, callID string, toTag bool) *sip.Request {
+	req := sip.NewRequest(method, sip.Uri{User: "to", Host: "example.com"})
+	req.AppendHeader(&sip.FromHeader{Address: sip.Uri{User: "from", Host: "example.com"}, Params: sip.NewParams().Add("tag", "from-tag")})
+	to := &sip.ToHeader{Address: sip.Uri{User: "to", Host: "example.com"}, Params: sip.NewParams()}
+	if toTag {
+		to.Params.Add("tag", "to-tag")
+	}
+	req.AppendHeader(to)
+	id := sip.CallIDHeader(callID)
+	req.AppendHeader(&id)
+	return req
+}
+
+func TestDialogRegistryConfig(t *testing.T) {
+	require.NoError(t, (&config.DialogRegistryConfig{}).Validate())
+	require.NoError(t, (&config.DialogRegistryConfig{Address: "10.0.0.1:5060", TTL: time.Minute}).Validate())
+	require.Error(t, (&config.DialogRegistryConfig{Address: "10.0.0.1"}).Validate())
+	require.Error(t, (&config.DialogRegistryConfig{TTL: -time.Second}).Validate())
+}
+
+func TestDialogOwners(t *testing.T) {
+	require.Nil(t, newDialogOwners(logger.GetLogger(), nil, nil, 0))
+	var none *dialogOwners
+	none.Add("a")
+	none.Remove("a")
+
+	store := newTestDialogStore()
+	sconf := &ServiceConfig{SignalingIPLocal: newIP("10.0.0.1")}
+	r := newDialogOwners(logger.GetLogger(), &config.DialogRegistryConfig{}, sconf, 5060)
+	r.store = store
+	require.Equal(t, "10.0.0.1:5060", r.address)
+	require.Equal(t, config.DefaultDialogTTL, r.ttl)
+
+	r.Add("local")
+	require.Eventually(t, func() bool {
+		return store.get("local") == r.address
+	}, time.Second, 10*time.Millisecond)
+	_ = store.Register(context.Background(), "10.0.0.2:5060", time.Minute, "remote")
+
+	require.Empty(t, r.dialogOwner(newTestDialogRequest(sip.BYE, "local", true)))
+	require.Equal(t, "10.0.0.2:5060", r.dialogOwner(newTestDialogRequest(sip.BYE, "remote", true)))
+	require.Empty(t, r.dialogOwner(newTestDialogRequest(sip.BYE, "unknown", true)))
+	// Initial requests are not forwarded.
+	require.Empty(t, r.dialogOwner(newTestDialogRequest(sip.INVITE, "remote", false)))
+	// Forwarded requests are never forwarded again.
+	fwd := newTestDialogRequest(sip.BYE, "remote", true)
+	fwd.AppendHeader(sip.NewHeader(dialogForwardedHeader, "10.0.0.3:5060"))
+	require.Empty(t, r.dialogOwner(fwd))
+
+	// Dialogs taken over by another instance are not removed.
+	_ = store.Register(context.Background(), "10.0.0.2:5060", time.Minute, "local")
+	r.Add("other")
+	r.Remove("local")
+	r.Remove("other")
+	require.Eventually(t, func() bool {
+		return store.get("other") == ""
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "10.0.0.2:5060", store.get("local"))
+	require.False(t, r.owns("local"))
+}
+
+func newDialogTestService(t *testing.T, sipPort int, store DialogStore) *Service {
+	mon, err := stats.NewMonitor(&config.Config{MaxCpuUtilization: 0.9})
+	require.NoError(t, err)
+	s, err := NewService("", &config.Config{
+		SIPPort:        sipPort,
+		SIPPortListen:  sipPort,
+		RTPPort:        rtcconfig.PortRange{Start: testPortRTPMin, End: testPortRTPMax},
+		DialogRegistry: &config.DialogRegistryConfig{},
+	}, mon, logger.NewTestLogger(t), func(projectID string) rpc.IOInfoClient { return nil })
+	require.NoError(t, err)
+	t.Cleanup(s.Stop)
+	s.SetHandler(&TestHandler{})
+	s.SetDialogStore(store)
+	return s
+}
+
+func TestDialogOwnersForward(t *testing.T) {
+	localIP, err := config.GetLocalIP()
+	require.NoError(t, err)
+	portA := testPortSIPMin + 40
+	portB := portA + 1
+
+	store := newTestDialogStore()
+	a := newDialogTestService(t, portA, store)
+	b := newDialogTestService(t, portB, store)
+
+	// Instance A answers BYE for its dialog.
+	forwarded := make(chan string, 1)
+	a.Use(func(next sipgo.RequestHandler) sipgo.RequestHandler {
+		return func(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
+			if req.CallID().Value() != "dlg-a" {
+				next(log, req, tx)
+				return
+			}
+			forwarded <- req.GetHeader(dialogForwardedHeader).Value()
+			resp := sip.NewResponseFromRequest(req, sip.StatusOK, "OK", nil)
+			resp.AppendHeader(sip.NewHeader("X-Owner", "a"))
+			_ = tx.Respond(resp)
+		}
+	}, sip.BYE)
+	require.NoError(t, a.Start())
+	require.NoError(t, b.Start())
+	// Fails without a store.
+	require.Error(t, newDialogTestService(t, portB+1, nil).Start())
+
+	a.srv.owners.Add("dlg-a")
+	require.Eventually(t, func() bool {
+		return store.get("dlg-a") != ""
+	}, time.Second, 10*time.Millisecond)
+
+	ua, err := sipgo.NewUA(sipgo.WithUserAgentLogger(slog.New(logger.ToSlogHandler(logger.NewTestLogger(t)))))
+	require.NoError(t, err)
+	cli, err := sipgo.NewClient(ua)
+	require.NoError(t, err)
+	addrB := fmt.Sprintf("%s:%d", localIP, portB)
+	bye := func(callID string) *sip.Response {
+		req := newTestDialogRequest(sip.BYE, callID, true)
+		req.SetDestination(addrB)
+		tx, err := cli.TransactionRequest(req)
+		require.NoError(t, err)
+		defer tx.Terminate()
+		return getResponseOrFail(t, tx)
+	}
+
+	// Requests received by B for the dialog of A are proxied to A.
+	resp := bye("dlg-a")
+	require.Equal(t, sip.StatusOK, resp.StatusCode)
+	require.Equal(t, "a", resp.GetHeader("X-Owner").Value())
+	require.Equal(t, "dlg-a", resp.CallID().Value())
+	require.Equal(t, fmt.Sprintf("%s:%d", localIP, portB), <-forwarded)
+
+	// Unknown dialogs are handled by B.
+	resp = bye("dlg-unknown")
+	require.Equal(t, sip.StatusCallTransactionDoesNotExists, resp.StatusCode)
+}
@@ -0,0 +1,242 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/stats"
+)
+
+// DialogState is the state of a call, as seen by SIP signaling.
+type DialogState int
+
+const (
+	// DialogIdle means the INVITE was not sent yet.
+	DialogIdle DialogState = iota
+	// DialogProceeding means the INVITE was sent or received, but the call is not ringing yet.
+	DialogProceeding
+	// DialogEarly means the call is ringing or has early media.
+	DialogEarly
+	// DialogAnswered means the call was accepted with 200 OK.
+	DialogAnswered
+	// DialogHeld means the remote put the answered call on hold.
+	DialogHeld
+	// DialogTerminating means the call is being hung up, cancelled or rejected.
+	DialogTerminating
+	// DialogDone means the call is closed and all its resources are released.
+	DialogDone
+)
+
+func (s DialogState) String() string {
+	switch s {
+	case DialogIdle:
+		return "idle"
+	case DialogProceeding:
+		return "proceeding"
+	case DialogEarly:
+		return "early"
+	case DialogAnswered:
+		return "answered"
+	case DialogHeld:
+		return "held"
+	case DialogTerminating:
+		return "terminating"
+	case DialogDone:
+		return "done"
+	}
+	return "unknown"
+}
+
+// canTransition checks if the call can move from one state to another.
+// The call may skip setup states, but never returns to them.
+func (s DialogState) canTransition(to DialogState) bool {
+	switch to {
+	case DialogProceeding, DialogEarly:
+		return s < to
+	case DialogAnswered:
+		return s < DialogAnswered || s == DialogHeld
+	case DialogHeld:
+		return s == DialogAnswered
+	case DialogTerminating:
+		return s < DialogTerminating
+	case DialogDone:
+		return s != DialogDone
+	}
+	return false
+}
+
+type dialogStateHook struct {
+	state DialogState
+	enter bool
+	all   bool // called on any transition
+	fnc   func(from, to DialogState)
+}
+
+type stateTimeout struct {
+	name    string
+	states  []DialogState
+	timer   *time.Timer
+	expired chan struct{}
+}
+
+// callFSM is the state machine of a single call. Signaling moves it between states,
+// while call handling reacts to the transitions with hooks and timeouts owned by states.
+type callFSM struct {
+	log  logger.Logger
+	hist *dialogHistory // timeouts are reported as pending timers
+
+	mu       sync.Mutex
+	state    DialogState
+	since    time.Time
+	mon      *stats.CallMonitor
+	hooks    []dialogStateHook
+	timeouts []*stateTimeout
+}
+
+func newCallFSM(log logger.Logger, hist *dialogHistory, state DialogState) *callFSM {
+	return &callFSM{
+		log:   log,
+		hist:  hist,
+		state: state,
+		since: time.Now(),
+	}
+}
+
+// State returns the current state and the time when the call entered it.
+func (f *callFSM) State() (DialogState, time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state, f.since
+}
+
+// SetMonitor reports the state of the call to call state metrics.
+func (f *callFSM) SetMonitor(mon *stats.CallMonitor) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mon = mon
+	f.report()
+}
+
+func (f *callFSM) report() {
+	if f.mon == nil {
+		return
+	}
+	if f.state == DialogDone {
+		f.mon.CallState("")
+	} else {
+		f.mon.CallState(f.state.String())
+	}
+}
+
+// OnEnter registers a hook called after the call enters a given state.
+func (f *callFSM) OnEnter(state DialogState, fnc func(from DialogState)) {
+	f.addHook(dialogStateHook{state: state, enter: true, fnc: func(from, _ DialogState) { fnc(from) }})
+}
+
+// OnExit registers a hook called after the call leaves a given state.
+func (f *callFSM) OnExit(state DialogState, fnc func(to DialogState)) {
+	f.addHook(dialogStateHook{state: state, fnc: func(_, to DialogState) { fnc(to) }})
+}
+
+// OnChange registers a hook called after any state transition.
+func (f *callFSM) OnChange(fnc func(from, to DialogState)) {
+	f.addHook(dialogStateHook{all: true, fnc: fnc})
+}
+
+func (f *callFSM) addHook(h dialogStateHook) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hooks = append(f.hooks, h)
+}
+
+// Transition moves the call to a given state. It returns false if the call is already in that state,
+// or if the transition is not allowed, for example when a late provisional response arrives after the answer.
+//
+// Hooks are called synchronously after the state changes, exit hooks first. They must not block.
+func (f *callFSM) Transition(to DialogState) bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	from := f.state
+	if from == to {
+		f.mu.Unlock()
+		return false
+	}
+	if !from.canTransition(to) {
+		f.mu.Unlock()
+		f.log.Debugw("ignoring call state transition", "from", from.String(), "to", to.String())
+		return false
+	}
+	f.state, f.since = to, time.Now()
+	f.report()
+	f.timeouts = slices.DeleteFunc(f.timeouts, func(t *stateTimeout) bool {
+		if slices.Contains(t.states, to) {
+			return false
+		}
+		t.timer.Stop()
+		f.hist.ClearTimer(t.name)
+		return true
+	})
+	var hooks []dialogStateHook
+	for _, h := range f.hooks {
+		if !h.all && !h.enter && h.state == from {
+			hooks = append(hooks, h)
+		}
+	}
+	for _, h := range f.hooks {
+		if h.all || (h.enter && h.state == to) {
+			hooks = append(hooks, h)
+		}
+	}
+	f.mu.Unlock()
+
+	f.log.Debugw("call state changed", "from", from.String(), "to", to.String())
+	for _, h := range hooks {
+		h.fnc(from, to)
+	}
+	return true
+}
+
+// Timeout starts a timer owned by given states. The returned channel is closed when the timer expires.
+// The timer is stopped once the call moves to any other state, and the channel is never closed in that case.
+// If the call already left these states, the timer is not started and nil is returned.
+func (f *callFSM) Timeout(name string, dur time.Duration, states ...DialogState) <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !slices.Contains(states, f.state) {
+		return nil
+	}
+	t := &stateTimeout{name: name, states: states, expired: make(chan struct{})}
+	f.hist.SetTimer(name, time.Now().Add(dur))
+	t.timer = time.AfterFunc(dur, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		n := len(f.timeouts)
+		f.timeouts = slices.DeleteFunc(f.timeouts, func(v *stateTimeout) bool { return v == t })
+		if len(f.timeouts) == n {
+			return // stopped by a transition
+		}
+		f.hist.ClearTimer(name)
+		close(t.expired)
+	})
+	f.timeouts = append(f.timeouts, t)
+	return t.expired
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallFSMTransitions(t *testing.T) {
+	f := newCallFSM(logger.GetLogger(), newDialogHistory(), DialogIdle)
+
+	var events []string
+	f.OnEnter(DialogAnswered, func(from DialogState) {
+		events = append(events, "enter answered from "+from.String())
+	})
+	f.OnExit(DialogAnswered, func(to DialogState) {
+		events = append(events, "exit answered to "+to.String())
+	})
+	f.OnChange(func(from, to DialogState) {
+		events = append(events, fmt.Sprintf("%v -> %v", from, to))
+	})
+
+	require.True(t, f.Transition(DialogProceeding))
+	require.True(t, f.Transition(DialogEarly))
+	require.False(t, f.Transition(DialogEarly))      // already there
+	require.False(t, f.Transition(DialogProceeding)) // never goes back
+	require.False(t, f.Transition(DialogHeld))       // not answered yet
+	require.True(t, f.Transition(DialogAnswered))
+	require.False(t, f.Transition(DialogEarly)) // late provisional response
+	require.True(t, f.Transition(DialogHeld))
+	require.True(t, f.Transition(DialogAnswered))
+	require.True(t, f.Transition(DialogTerminating))
+	require.False(t, f.Transition(DialogAnswered))
+	require.True(t, f.Transition(DialogDone))
+	require.False(t, f.Transition(DialogTerminating))
+
+	st, _ := f.State()
+	require.Equal(t, DialogDone, st)
+	require.Equal(t, []string{
+		"idle -> proceeding",
+		"proceeding -> early",
+		"enter answered from early",
+		"early -> answered",
+		"exit answered to held",
+		"answered -> held",
+		"enter answered from held",
+		"held -> answered",
+		"exit answered to terminating",
+		"answered -> terminating",
+		"terminating -> done",
+	}, events)
+}
+
+func TestCallFSMSkipStates(t *testing.T) {
+	f := newCallFSM(logger.GetLogger(), newDialogHistory(), DialogIdle)
+	require.True(t, f.Transition(DialogAnswered))
+
+	f = newCallFSM(logger.GetLogger(), newDialogHistory(), DialogProceeding)
+	require.True(t, f.Transition(DialogDone))
+}
+
+func TestCallFSMTimeout(t *testing.T) {
+	t.Run("expires", func(t *testing.T) {
+		h := newDialogHistory()
+		f := newCallFSM(logger.GetLogger(), h, DialogProceeding)
+		expired := f.Timeout(timerRinging, 20*time.Millisecond, DialogProceeding, DialogEarly)
+		require.Len(t, h.Timers(), 1)
+		require.True(t, f.Transition(DialogEarly)) // still owned
+		select {
+		case <-expired:
+		case <-time.After(time.Second):
+			t.Fatal("timeout did not expire")
+		}
+		require.Empty(t, h.Timers())
+	})
+	t.Run("stopped", func(t *testing.T) {
+		h := newDialogHistory()
+		f := newCallFSM(logger.GetLogger(), h, DialogProceeding)
+		expired := f.Timeout(timerRinging, 20*time.Millisecond, DialogProceeding, DialogEarly)
+		require.True(t, f.Transition(DialogAnswered))
+		require.Empty(t, h.Timers())
+		select {
+		case <-expired:
+			t.Fatal("timeout expired after leaving the state")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+	t.Run("not in state", func(t *testing.T) {
+		f := newCallFSM(logger.GetLogger(), newDialogHistory(), DialogAnswered)
+		require.Nil(t, f.Timeout(timerRinging, time.Millisecond, DialogProceeding, DialogEarly))
+	})
+}
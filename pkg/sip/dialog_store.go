@@ -0,0 +1,72 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisDialogKeyPrefix is the prefix of Redis keys of dialogs, followed by the SIP Call-ID.
+const redisDialogKeyPrefix = "sip_dialog:"
+
+// unregisterDialog deletes a dialog only if it's still owned by the instance, since another instance
+// may take it over, for example after a restart.
+var unregisterDialog = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type redisDialogStore struct {
+	rc redis.UniversalClient
+}
+
+// NewRedisDialogStore creates a DialogStore shared by all instances using the same Redis.
+func NewRedisDialogStore(rc redis.UniversalClient) DialogStore {
+	return &redisDialogStore{rc: rc}
+}
+
+func (s *redisDialogStore) Register(ctx context.Context, owner string, ttl time.Duration, callIDs ...string) error {
+	_, err := s.rc.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, id := range callIDs {
+			p.Set(ctx, redisDialogKeyPrefix+id, owner, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *redisDialogStore) Unregister(ctx context.Context, owner string, callIDs ...string) error {
+	var errs []error
+	for _, id := range callIDs {
+		if err := unregisterDialog.Run(ctx, s.rc, []string{redisDialogKeyPrefix + id}, owner).Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *redisDialogStore) Owner(ctx context.Context, callID string) (string, error) {
+	owner, err := s.rc.Get(ctx, redisDialogKeyPrefix+callID).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return owner, err
+}
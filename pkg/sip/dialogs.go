@@ -0,0 +1,391 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/sipgo/sip"
+)
+
+// dialogHistorySize is the number of the last signaling messages kept per call.
+const dialogHistorySize = 10
+
+// Directions of signaling messages in the dialog history.
+const (
+	dialogIn  = "in"
+	dialogOut = "out"
+)
+
+// Timers reported in the dialog dump.
+const (
+	timerMaxCallDuration = "max_call_duration"
+	timerRinging         = "ringing"
+	timerB               = "timer_b" // INVITE waits for the first response
+	timerF               = "timer_f" // other requests wait for the final response
+	timerBusyQueue       = "busy_queue"
+	timerCallQueue       = "call_queue"
+)
+
+// DialogMessage is a signaling message sent or received in the dialog.
+type DialogMessage struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Method    string    `json:"method"` // method of the request, or the method the response is for
+	Status    int       `json:"status,omitempty"`
+	CSeq      uint32    `json:"cseq"`
+	StartLine string    `json:"start_line"`
+}
+
+// DialogTransaction is the last transaction of the dialog.
+type DialogTransaction struct {
+	Method    string `json:"method"`
+	CSeq      uint32 `json:"cseq"`
+	Direction string `json:"direction"` // direction of the request
+	Status    int    `json:"status,omitempty"`
+	Completed bool   `json:"completed"`
+}
+
+// DialogTimer is a pending timer of the call.
+type DialogTimer struct {
+	Name     string    `json:"name"`
+	Deadline time.Time `json:"deadline"`
+}
+
+// DialogMedia describes the media session of the call.
+type DialogMedia struct {
+	LocalPort int    `json:"local_port"`
+	Local     string `json:"local,omitempty"`
+	Remote    string `json:"remote,omitempty"`
+	State     string `json:"state"`
+	Held      bool   `json:"held"`
+	Timeout   string `json:"timeout"` // disabled, paused or armed
+
+	// Stages are the names of media stages processing audio received from SIP.
+	Stages []string `json:"stages,omitempty"`
+}
+
+// DialogInfo is the state of a single active call.
+type DialogInfo struct {
+	CallID          string             `json:"call_id"`
+	SIPCallID       string             `json:"sip_call_id"`
+	Direction       string             `json:"direction"`
+	State           string             `json:"state"`
+	StateSince      time.Time          `json:"state_since"`
+	CallStatus      string             `json:"call_status,omitempty"`
+	TrunkID         string             `json:"trunk_id,omitempty"`
+	RoomName        string             `json:"room_name,omitempty"`
+	LocalTag        string             `json:"local_tag"`
+	RemoteTag       string             `json:"remote_tag,omitempty"`
+	From            string             `json:"from,omitempty"`
+	To              string             `json:"to,omitempty"`
+	LastTransaction *DialogTransaction `json:"last_transaction,omitempty"`
+	Timers          []DialogTimer      `json:"timers,omitempty"`
+	Media           *DialogMedia       `json:"media,omitempty"`
+	Messages        []DialogMessage    `json:"messages"`
+}
+
+// DialogDump lists all active calls of the service.
+type DialogDump struct {
+	Time    time.Time    `json:"time"`
+	Dialogs []DialogInfo `json:"dialogs"`
+}
+
+// dialogHistory keeps the last signaling messages, the last transaction and the pending timers of a call.
+type dialogHistory struct {
+	mu     sync.Mutex
+	msgs   [dialogHistorySize]DialogMessage
+	cnt    int
+	lastTx *DialogTransaction
+	timers map[string]time.Time
+}
+
+func newDialogHistory() *dialogHistory {
+	return &dialogHistory{timers: make(map[string]time.Time)}
+}
+
+func (h *dialogHistory) add(m DialogMessage) {
+	h.msgs[h.cnt%dialogHistorySize] = m
+	h.cnt++
+}
+
+// Request records a request sent or received in the dialog.
+func (h *dialogHistory) Request(dir string, req *sip.Request) {
+	if h == nil || req == nil {
+		return
+	}
+	m := DialogMessage{
+		Time:      time.Now(),
+		Direction: dir,
+		Method:    req.Method.String(),
+		StartLine: req.StartLine(),
+	}
+	if cseq := req.CSeq(); cseq != nil {
+		m.CSeq = cseq.SeqNo
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.add(m)
+	if req.IsAck() {
+		return // not a separate transaction
+	}
+	h.lastTx = &DialogTransaction{Method: m.Method, CSeq: m.CSeq, Direction: dir}
+}
+
+// Response records a response sent or received in the dialog.
+func (h *dialogHistory) Response(dir string, res *sip.Response) {
+	if h == nil || res == nil {
+		return
+	}
+	m := DialogMessage{
+		Time:      time.Now(),
+		Direction: dir,
+		Status:    int(res.StatusCode),
+		StartLine: res.StartLine(),
+	}
+	if cseq := res.CSeq(); cseq != nil {
+		m.Method, m.CSeq = cseq.MethodName.String(), cseq.SeqNo
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.add(m)
+	if tx := h.lastTx; tx != nil && tx.Method == m.Method && tx.CSeq == m.CSeq && !tx.Completed {
+		tx.Status = m.Status
+		tx.Completed = m.Status >= 200
+	}
+}
+
+// Messages returns the recorded messages, oldest first.
+func (h *dialogHistory) Messages() []DialogMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := min(h.cnt, dialogHistorySize)
+	out := make([]DialogMessage, 0, n)
+	for i := h.cnt - n; i < h.cnt; i++ {
+		out = append(out, h.msgs[i%dialogHistorySize])
+	}
+	return out
+}
+
+// LastTransaction returns the last transaction started in the dialog, or nil if there's none.
+func (h *dialogHistory) LastTransaction() *DialogTransaction {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.lastTx == nil {
+		return nil
+	}
+	tx := *h.lastTx
+	return &tx
+}
+
+// SetTimer reports that the call has a timer with a given deadline.
+func (h *dialogHistory) SetTimer(name string, deadline time.Time) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timers[name] = deadline
+}
+
+// ClearTimer removes the timer, when it's stopped.
+func (h *dialogHistory) ClearTimer(name string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.timers, name)
+}
+
+// Timers returns timers which did not fire yet, ordered by deadline.
+func (h *dialogHistory) Timers() []DialogTimer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	var out []DialogTimer
+	for _, name := range slices.Sorted(maps.Keys(h.timers)) {
+		if d := h.timers[name]; d.After(now) {
+			out = append(out, DialogTimer{Name: name, Deadline: d})
+		}
+	}
+	slices.SortStableFunc(out, func(a, b DialogTimer) int {
+		return a.Deadline.Compare(b.Deadline)
+	})
+	return out
+}
+
+// ServerTx records the request received in the dialog and returns a transaction that records responses to it.
+func (h *dialogHistory) ServerTx(req *sip.Request, tx sip.ServerTransaction) sip.ServerTransaction {
+	if h == nil {
+		return tx
+	}
+	h.Request(dialogIn, req)
+	if tx == nil {
+		return nil
+	}
+	return &historyServerTx{ServerTransaction: tx, h: h}
+}
+
+// ClientTx records the request sent in the dialog and returns a transaction that records responses to it.
+func (h *dialogHistory) ClientTx(req *sip.Request, tx sip.ClientTransaction) sip.ClientTransaction {
+	if h == nil {
+		return tx
+	}
+	h.Request(dialogOut, req)
+	return &historyClientTx{ClientTransaction: tx, h: h}
+}
+
+type historyServerTx struct {
+	sip.ServerTransaction
+	h *dialogHistory
+}
+
+func (tx *historyServerTx) Respond(res *sip.Response) error {
+	tx.h.Response(dialogOut, res)
+	return tx.ServerTransaction.Respond(res)
+}
+
+// responseRecorder is implemented by client transactions which record received responses.
+// Responses are delivered over a channel, thus they are recorded by sipResponse.
+type responseRecorder interface {
+	recordResponse(res *sip.Response)
+}
+
+type historyClientTx struct {
+	sip.ClientTransaction
+	h *dialogHistory
+}
+
+func (tx *historyClientTx) recordResponse(res *sip.Response) {
+	tx.h.Response(dialogIn, res)
+}
+
+// dump fills the dialog info from the call state and the history.
+func (h *dialogHistory) dump(info *DialogInfo, fsm *callFSM, state *CallState, media *MediaPort) {
+	st, since := fsm.State()
+	info.State, info.StateSince = st.String(), since
+	if state != nil {
+		state.mu.Lock()
+		ci := state.callInfo
+		info.CallID = ci.GetCallId()
+		info.TrunkID = ci.GetTrunkId()
+		info.RoomName = ci.GetRoomName()
+		info.CallStatus = ci.GetCallStatus().String()
+		state.mu.Unlock()
+	}
+	info.LastTransaction = h.LastTransaction()
+	info.Timers = h.Timers()
+	info.Messages = h.Messages()
+	if media != nil {
+		info.Media = media.dialogMedia()
+	}
+}
+
+// dialogMedia describes the media session for the dialog dump.
+func (p *MediaPort) dialogMedia() *DialogMedia {
+	m := &DialogMedia{
+		LocalPort: p.Port(),
+		State:     p.MediaState().String(),
+		Held:      p.Held(),
+		Timeout:   "disabled",
+		Stages:    mediaStageNames(p.Stages()),
+	}
+	if p.timeoutStart.Load() != nil {
+		m.Timeout = "armed"
+		if p.timeoutPaused.Load() {
+			m.Timeout = "paused"
+		}
+	}
+	if conf := p.Config(); conf != nil {
+		if conf.Local.IsValid() {
+			m.Local = conf.Local.String()
+		}
+		if conf.Remote.IsValid() {
+			m.Remote = conf.Remote.String()
+		}
+	}
+	return m
+}
+
+func (c *inboundCall) dialogInfo() DialogInfo {
+	from, to := c.cc.From(), c.cc.To()
+	info := DialogInfo{
+		SIPCallID: c.cc.CallID(),
+		Direction: "inbound",
+		LocalTag:  string(c.cc.ID()),
+		RemoteTag: string(c.cc.Tag()),
+		From:      from.String(),
+		To:        to.String(),
+	}
+	c.cc.history.dump(&info, c.cc.fsm, c.state, c.media)
+	return info
+}
+
+func (c *outboundCall) dialogInfo() DialogInfo {
+	c.cc.mu.RLock()
+	info := DialogInfo{
+		SIPCallID: c.cc.callID,
+		Direction: "outbound",
+		LocalTag:  string(c.cc.id),
+		RemoteTag: string(c.cc.tag),
+		From:      c.cc.from.Address.String(),
+	}
+	if c.cc.to != nil {
+		info.To = c.cc.to.Address.String()
+	}
+	c.cc.mu.RUnlock()
+	c.cc.history.dump(&info, c.cc.fsm, c.state, c.media)
+	return info
+}
+
+// Dialogs returns the state of active inbound calls.
+func (s *Server) Dialogs() []DialogInfo {
+	s.cmu.RLock()
+	calls := slices.Collect(maps.Values(s.activeCalls))
+	s.cmu.RUnlock()
+	out := make([]DialogInfo, 0, len(calls))
+	for _, c := range calls {
+		out = append(out, c.dialogInfo())
+	}
+	return out
+}
+
+// Dialogs returns the state of active outbound calls.
+func (c *Client) Dialogs() []DialogInfo {
+	c.cmu.Lock()
+	calls := slices.Collect(maps.Values(c.activeCalls))
+	c.cmu.Unlock()
+	out := make([]DialogInfo, 0, len(calls))
+	for _, call := range calls {
+		out = append(out, call.dialogInfo())
+	}
+	return out
+}
+
+// Dialogs dumps the state of all active calls: SIP state, last transaction, pending timers,
+// media addresses and last signaling messages.
+func (s *Service) Dialogs(ctx context.Context) (*DialogDump, error) {
+	dialogs := append(s.srv.Dialogs(), s.cli.Dialogs()...)
+	slices.SortFunc(dialogs, func(a, b DialogInfo) int {
+		return strings.Compare(a.CallID, b.CallID)
+	})
+	return &DialogDump{Time: time.Now(), Dialogs: dialogs}, nil
+}
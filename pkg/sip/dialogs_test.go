@@ -0,0 +1,85 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/livekit/sipgo/sip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialogHistory(t *testing.T) {
+	h := newDialogHistory()
+	require.Nil(t, h.LastTransaction())
+	require.Empty(t, h.Messages())
+
+	newReq := func(method sip.RequestMethod, seq uint32) *sip.Request {
+		req := sip.NewRequest(method, sip.Uri{User: "bob", Host: "example.com"})
+		req.AppendHeader(&sip.ToHeader{Address: sip.Uri{User: "bob", Host: "example.com"}, Params: sip.NewParams()})
+		req.AppendHeader(&sip.CSeqHeader{SeqNo: seq, MethodName: method})
+		return req
+	}
+
+	invite := newReq(sip.INVITE, 1)
+	h.Request(dialogIn, invite)
+	h.Response(dialogOut, sip.NewResponseFromRequest(invite, sip.StatusRinging, "Ringing", nil))
+	require.Equal(t, &DialogTransaction{
+		Method: "INVITE", CSeq: 1, Direction: dialogIn, Status: 180,
+	}, h.LastTransaction())
+
+	h.Response(dialogOut, sip.NewResponseFromRequest(invite, sip.StatusOK, "OK", nil))
+	h.Request(dialogIn, newReq(sip.ACK, 1))
+	require.Equal(t, &DialogTransaction{
+		Method: "INVITE", CSeq: 1, Direction: dialogIn, Status: 200, Completed: true,
+	}, h.LastTransaction())
+
+	msgs := h.Messages()
+	require.Len(t, msgs, 4)
+	require.Equal(t, "INVITE sip:bob@example.com SIP/2.0", msgs[0].StartLine)
+	require.Equal(t, "SIP/2.0 200 OK", msgs[2].StartLine)
+	require.Equal(t, "INVITE", msgs[2].Method)
+	require.Equal(t, "ACK", msgs[3].Method)
+
+	// Only the last messages are kept.
+	for i := range 2 * dialogHistorySize {
+		h.Request(dialogOut, newReq(sip.INFO, uint32(i+2)))
+	}
+	msgs = h.Messages()
+	require.Len(t, msgs, dialogHistorySize)
+	require.Equal(t, uint32(dialogHistorySize+2), msgs[0].CSeq)
+	require.Equal(t, uint32(2*dialogHistorySize+1), msgs[len(msgs)-1].CSeq)
+	require.Equal(t, &DialogTransaction{
+		Method: "INFO", CSeq: uint32(2*dialogHistorySize + 1), Direction: dialogOut,
+	}, h.LastTransaction())
+}
+
+func TestDialogHistoryTimers(t *testing.T) {
+	h := newDialogHistory()
+	now := time.Now()
+	h.SetTimer(timerMaxCallDuration, now.Add(time.Hour))
+	h.SetTimer(timerRinging, now.Add(time.Minute))
+	h.SetTimer("expired", now.Add(-time.Second))
+	require.Equal(t, []DialogTimer{
+		{Name: timerRinging, Deadline: now.Add(time.Minute)},
+		{Name: timerMaxCallDuration, Deadline: now.Add(time.Hour)},
+	}, h.Timers())
+
+	h.ClearTimer(timerRinging)
+	require.Equal(t, []DialogTimer{
+		{Name: timerMaxCallDuration, Deadline: now.Add(time.Hour)},
+	}, h.Timers())
+}
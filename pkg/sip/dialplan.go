@@ -0,0 +1,112 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+var errNumberBlocked = psrpc.NewErrorf(psrpc.PermissionDenied, "number is blocked by dial plan")
+
+// translateNumber applies the dial plan to a phone number. It returns false if the number is blocked.
+func translateNumber(conf config.DialPlanConfig, num string) (string, bool) {
+	if num == "" {
+		return num, true
+	}
+	for _, p := range conf.StripPrefixes {
+		if s, ok := strings.CutPrefix(num, p); ok {
+			num = s
+			break
+		}
+	}
+	// SIP users that are not phone numbers are never converted to E.164.
+	if conf.E164 && isPhoneNumber(num) {
+		num = normalizeE164(num, conf.DefaultCountry)
+	}
+	num = conf.AddPrefix + num
+	return num, !numberBlocked(conf.Blocklist, num)
+}
+
+// isPhoneNumber checks if the value only contains digits and common visual separators.
+func isPhoneNumber(v string) bool {
+	digits := 0
+	for _, r := range v {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case strings.ContainsRune("+-.() ", r):
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+func numberBlocked(blocklist []string, num string) bool {
+	for _, p := range blocklist {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(num, prefix) {
+				return true
+			}
+		} else if num == p {
+			return true
+		}
+	}
+	return false
+}
+
+// translateInbound applies the trunk dial plan to caller and called numbers of an inbound call.
+// Numbers are only translated after the trunk is matched, so trunk authentication always uses the original numbers.
+func (s *Server) translateInbound(log logger.Logger, trunkID string, call *rpc.SIPCall) error {
+	conf := s.conf.TrunkDialPlan(trunkID)
+	from, ok := translateURIUser(conf, call.From)
+	if !ok {
+		log.Infow("caller number is blocked by dial plan", "fromUser", call.From.GetUser())
+		return errNumberBlocked
+	}
+	to, ok := translateURIUser(conf, call.To)
+	if !ok {
+		log.Infow("called number is blocked by dial plan", "toUser", call.To.GetUser())
+		return errNumberBlocked
+	}
+	if from != call.From || to != call.To {
+		log.Infow("translated numbers by dial plan", "fromUser", from.GetUser(), "toUser", to.GetUser())
+	}
+	call.From, call.To = from, to
+	return nil
+}
+
+// translateURIUser applies the dial plan to the user part of the URI. The URI is copied if the user changes.
+func translateURIUser(conf config.DialPlanConfig, u *livekit.SIPUri) (*livekit.SIPUri, bool) {
+	if u == nil {
+		return nil, true
+	}
+	user, ok := translateNumber(conf, u.User)
+	if !ok || user == u.User {
+		return u, ok
+	}
+	u = proto.Clone(u).(*livekit.SIPUri)
+	u.User = user
+	return u, true
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestTranslateNumber(t *testing.T) {
+	conf := config.DialPlanConfig{
+		StripPrefixes:  []string{"9", "*67"},
+		E164:           true,
+		DefaultCountry: "44",
+		Blocklist:      []string{"+44900*", "+441234567"},
+	}
+	cases := []struct {
+		conf    config.DialPlanConfig
+		in      string
+		out     string
+		blocked bool
+	}{
+		{conf: conf, in: "", out: ""},
+		{conf: conf, in: "9020 7946 0000", out: "+442079460000"},
+		{conf: conf, in: "*6702079460000", out: "+442079460000"},
+		{conf: conf, in: "+1 (555) 010-0000", out: "+15550100000"},
+		{conf: conf, in: "alice", out: "alice"},
+		{conf: conf, in: "09001234", out: "+449001234", blocked: true},
+		{conf: conf, in: "01234567", out: "+441234567", blocked: true},
+		{conf: conf, in: "012345678", out: "+4412345678"},
+		{conf: config.DialPlanConfig{AddPrefix: "00"}, in: "15550100", out: "0015550100"},
+		{conf: config.DialPlanConfig{}, in: "15550100", out: "15550100"},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			out, ok := translateNumber(c.conf, c.in)
+			require.Equal(t, c.out, out)
+			require.Equal(t, !c.blocked, ok)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/internal/bloom"
+)
+
+const (
+	defaultDNCTimeout = time.Second
+	// maxDNCResponse limits the size of the do-not-call service response.
+	maxDNCResponse = 4096
+	// dncPolicyRejected is reported in call info and metrics for calls rejected by the do-not-call list.
+	dncPolicyRejected = "policy-rejected"
+)
+
+// DNCChecker checks if a number is on a do-not-call list.
+type DNCChecker interface {
+	Blocked(ctx context.Context, number string) (bool, error)
+}
+
+// dncNumber removes visual separators from a number, so that lists match regardless of formatting.
+func dncNumber(number string) string {
+	if !isPhoneNumber(number) {
+		return number
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '+' || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, number)
+}
+
+// staticDNC is a static list of numbers. A trailing "*" matches a prefix.
+type staticDNC []string
+
+func newStaticDNC(numbers []string) staticDNC {
+	list := make(staticDNC, 0, len(numbers))
+	for _, n := range numbers {
+		if p, ok := strings.CutSuffix(n, "*"); ok {
+			list = append(list, dncNumber(p)+"*")
+		} else {
+			list = append(list, dncNumber(n))
+		}
+	}
+	return list
+}
+
+func (l staticDNC) Blocked(_ context.Context, number string) (bool, error) {
+	return numberBlocked(l, dncNumber(number)), nil
+}
+
+// bloomDNC checks numbers against a Bloom filter. It may block a small fraction of numbers that are not on the list.
+type bloomDNC struct {
+	f *bloom.Filter
+}
+
+func (b bloomDNC) Blocked(_ context.Context, number string) (bool, error) {
+	return b.f.Contains(dncNumber(number)), nil
+}
+
+// httpDNC checks numbers with an HTTP GET request.
+type httpDNC struct {
+	conf *config.DNCConfig
+	cli  *http.Client
+}
+
+func (p *httpDNC) Blocked(ctx context.Context, number string) (bool, error) {
+	u := strings.ReplaceAll(p.conf.URL, "{number}", url.PathEscape(dncNumber(number)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false, err
+	}
+	for k, v := range p.conf.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := p.cli.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode/100 != 2:
+		return false, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	var v struct {
+		Blocked *bool `json:"blocked"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDNCResponse)).Decode(&v); err != nil {
+		return false, err
+	}
+	if v.Blocked == nil {
+		return false, fmt.Errorf("missing blocked field in the response")
+	}
+	return *v.Blocked, nil
+}
+
+// dncPolicy consults do-not-call checkers before outbound calls.
+type dncPolicy struct {
+	checkers []DNCChecker
+	timeout  time.Duration
+	failOpen bool
+}
+
+// newDNCPolicy creates a policy for the configured sources. It returns nil if do-not-call list is not configured.
+func newDNCPolicy(conf *config.DNCConfig) (*dncPolicy, error) {
+	if conf == nil {
+		return nil, nil
+	}
+	var checkers []DNCChecker
+	if len(conf.Numbers) != 0 {
+		checkers = append(checkers, newStaticDNC(conf.Numbers))
+	}
+	if conf.BloomFilter != "" {
+		f, err := bloom.Load(conf.BloomFilter)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load do-not-call bloom filter: %w", err)
+		}
+		checkers = append(checkers, bloomDNC{f: f})
+	}
+	if conf.URL != "" {
+		checkers = append(checkers, &httpDNC{conf: conf, cli: &http.Client{}})
+	}
+	return newDNCPolicyWith(conf, checkers...), nil
+}
+
+func newDNCPolicyWith(conf *config.DNCConfig, checkers ...DNCChecker) *dncPolicy {
+	p := &dncPolicy{
+		checkers: checkers,
+		timeout:  defaultDNCTimeout,
+	}
+	if conf != nil {
+		if conf.Timeout > 0 {
+			p.timeout = conf.Timeout
+		}
+		p.failOpen = conf.FailOpen
+	}
+	return p
+}
+
+// Check returns ErrDestinationBlocked if the number is on any of the lists.
+// If a check fails, it returns ErrDNCCheckFailed, unless the policy fails open.
+func (p *dncPolicy) Check(ctx context.Context, log logger.Logger, number string) error {
+	if p == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	for _, c := range p.checkers {
+		blocked, err := c.Blocked(ctx, number)
+		if err != nil {
+			if p.failOpen {
+				log.Warnw("do-not-call check failed, allowing the call", err)
+				continue
+			}
+			log.Warnw("do-not-call check failed, rejecting the call", err)
+			return siperrors.ErrDNCCheckFailed
+		}
+		if blocked {
+			return siperrors.ErrDestinationBlocked
+		}
+	}
+	return nil
+}
+
+// SetDNCChecker sets a custom do-not-call checker, overriding the lists from the config.
+func (c *Client) SetDNCChecker(checker DNCChecker) {
+	if checker == nil {
+		c.dnc = nil
+		return
+	}
+	c.dnc = newDNCPolicyWith(c.conf.DNC, checker)
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/internal/bloom"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+type dncCheckerFunc func(ctx context.Context, number string) (bool, error)
+
+func (f dncCheckerFunc) Blocked(ctx context.Context, number string) (bool, error) {
+	return f(ctx, number)
+}
+
+func TestDNCPolicy(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewTestLogger(t)
+
+	f := bloom.New(100, 0.001)
+	f.Add("+15550200")
+	path := filepath.Join(t.TempDir(), "dnc.bloom")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	_, err = f.WriteTo(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/+15550300":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"blocked": true}`))
+		case "/+15550301":
+			_, _ = w.Write([]byte(`{"blocked": false}`))
+		case "/+15550302":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := newDNCPolicy(&config.DNCConfig{
+		Numbers:     []string{"+1 555 0100", "+1555019*"},
+		BloomFilter: path,
+		URL:         srv.URL + "/{number}",
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		number string
+		err    error
+	}{
+		{number: "+15550100", err: siperrors.ErrDestinationBlocked},
+		{number: "+1 (555) 019-9", err: siperrors.ErrDestinationBlocked},
+		{number: "+15550200", err: siperrors.ErrDestinationBlocked},
+		{number: "+1-555-0300", err: siperrors.ErrDestinationBlocked},
+		{number: "+15550301"},
+		{number: "+15550302", err: siperrors.ErrDNCCheckFailed},
+		{number: "+15550400"},
+		{number: "alice"},
+	}
+	for _, c := range cases {
+		t.Run(c.number, func(t *testing.T) {
+			err := p.Check(ctx, log, c.number)
+			if c.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, c.err)
+			}
+		})
+	}
+
+	p.failOpen = true
+	require.NoError(t, p.Check(ctx, log, "+15550302"))
+
+	_, err = newDNCPolicy(&config.DNCConfig{BloomFilter: filepath.Join(t.TempDir(), "missing")})
+	require.Error(t, err)
+
+	var nilPolicy *dncPolicy
+	require.NoError(t, nilPolicy.Check(ctx, log, "+15550100"))
+
+	custom := newDNCPolicyWith(nil, dncCheckerFunc(func(ctx context.Context, number string) (bool, error) {
+		return number == "+15550500", nil
+	}))
+	require.ErrorIs(t, custom.Check(ctx, log, "+15550500"), siperrors.ErrDestinationBlocked)
+	require.NoError(t, custom.Check(ctx, log, "+15550501"))
+}
+
+type testStateUpdater struct {
+	rpc.IOInfoClient
+	infos chan *livekit.SIPCallInfo
+}
+
+func (u *testStateUpdater) UpdateSIPCallState(ctx context.Context, req *rpc.UpdateSIPCallStateRequest, opts ...psrpc.RequestOption) (*emptypb.Empty, error) {
+	u.infos <- proto.Clone(req.CallInfo).(*livekit.SIPCallInfo)
+	return &emptypb.Empty{}, nil
+}
+
+func TestService_DNC(t *testing.T) {
+	sipPort := rand.Intn(testPortSIPMax-testPortSIPMin) + testPortSIPMin
+	mon, err := stats.NewMonitor(&config.Config{MaxCpuUtilization: 0.9})
+	require.NoError(t, err)
+
+	upd := &testStateUpdater{infos: make(chan *livekit.SIPCallInfo, 10)}
+	s, err := NewService("", &config.Config{
+		SIPPort:       sipPort,
+		SIPPortListen: sipPort,
+		RTPPort:       rtcconfig.PortRange{Start: testPortRTPMin, End: testPortRTPMax},
+		DNC:           &config.DNCConfig{Numbers: []string{"+15550100"}},
+	}, mon, logger.NewTestLogger(t), func(projectID string) rpc.IOInfoClient { return upd })
+	require.NoError(t, err)
+	t.Cleanup(s.Stop)
+	s.SetHandler(&TestHandler{})
+	require.NoError(t, s.Start())
+	require.Eventually(t, func() bool {
+		return mon.Health() == stats.HealthOK
+	}, 5*time.Second, 100*time.Millisecond)
+
+	_, err = s.CreateSIPParticipant(context.Background(), &rpc.InternalCreateSIPParticipantRequest{
+		SipCallId: "SCL_test",
+		CallTo:    "+15550100",
+		Address:   "sip.example.com",
+		Number:    "+15550000",
+		RoomName:  "room",
+	})
+	require.ErrorIs(t, err, siperrors.ErrDestinationBlocked)
+	var perr psrpc.Error
+	require.True(t, errors.As(err, &perr))
+	require.Equal(t, psrpc.PermissionDenied, perr.Code())
+
+	info := <-upd.infos
+	require.Equal(t, livekit.SIPCallStatus_SCS_ERROR, info.CallStatus)
+	require.Equal(t, livekit.SIPStatusCode_SIP_STATUS_FORBIDDEN, info.CallStatusCode.GetCode())
+	require.Equal(t, dncPolicyRejected, info.CallStatusCode.GetStatus())
+}
@@ -0,0 +1,273 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	lksip "github.com/livekit/protocol/sip"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/sipgo/sip"
+)
+
+const maxDryRunCalls = 100
+
+// InboundDryRunCall describes a hypothetical inbound call.
+type InboundDryRunCall struct {
+	SourceIP string `json:"source_ip"`
+	// From and To accept SIP URIs (sip:user@host:port), user@host or a plain number.
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Pin is used if the dispatch rule requests a pin.
+	Pin string `json:"pin,omitempty"`
+}
+
+// InboundDryRunRequest is a batch of hypothetical inbound calls to evaluate.
+type InboundDryRunRequest struct {
+	Calls []InboundDryRunCall `json:"calls"`
+}
+
+// Values for InboundDryRunResult.Auth.
+const (
+	dryRunAuthNotFound = "not_found"
+	dryRunAuthDrop     = "drop"
+	dryRunAuthPassword = "password"
+	dryRunAuthAccept   = "accept"
+)
+
+// Values for InboundDryRunResult.Dispatch.
+const (
+	dryRunDispatchAccept = "accept"
+	dryRunDispatchPin    = "pin"
+	dryRunDispatchReject = "reject"
+	dryRunDispatchDrop   = "drop"
+	dryRunDispatchEcho   = "echo"
+	// dryRunDispatchAnnounce is reported for dispatch rules that only play an announcement.
+	dryRunDispatchAnnounce = "announce"
+	// dryRunDispatchBlocked is reported when the caller or called number is blocked by the dial plan.
+	dryRunDispatchBlocked = "blocked"
+)
+
+// InboundDryRunResult reports how the call would be routed.
+type InboundDryRunResult struct {
+	// Auth is the trunk match result: not_found, drop, password or accept.
+	Auth      string `json:"auth"`
+	ProjectID string `json:"project_id,omitempty"`
+	TrunkID   string `json:"trunk_id,omitempty"`
+	// FromUser and ToUser are caller and called numbers after the dial plan translation, as seen by dispatch rules.
+	FromUser string `json:"from_user,omitempty"`
+	ToUser   string `json:"to_user,omitempty"`
+	// CallerName is resolved with CNAM lookup, if enabled.
+	CallerName string `json:"caller_name,omitempty"`
+	// Dispatch is the dispatch rule result: accept, pin, echo, announce, reject, drop or blocked.
+	Dispatch       string `json:"dispatch,omitempty"`
+	DispatchRuleID string `json:"dispatch_rule_id,omitempty"`
+	// PinRequired is set if the dispatch rule requests a pin. PinAccepted reports if the provided pin is valid.
+	PinRequired           bool              `json:"pin_required,omitempty"`
+	PinAccepted           bool              `json:"pin_accepted,omitempty"`
+	RoomName              string            `json:"room_name,omitempty"`
+	ParticipantIdentity   string            `json:"participant_identity,omitempty"`
+	ParticipantName       string            `json:"participant_name,omitempty"`
+	ParticipantAttributes map[string]string `json:"participant_attributes,omitempty"`
+	// Tags are call tags taken from participant attributes, see AttrSIPTagPrefix.
+	Tags map[string]string `json:"tags,omitempty"`
+	// Headers that would be sent in the 200 OK response.
+	Headers         map[string]string `json:"headers,omitempty"`
+	MediaEncryption string            `json:"media_encryption,omitempty"`
+	// Provisional is the mode used before the room answers: ringing, early_media or answer.
+	Provisional string `json:"provisional,omitempty"`
+	// MappingErrors lists invalid header/attribute mappings of the dispatch rule. Such mappings are skipped.
+	MappingErrors []string `json:"mapping_errors,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// InboundDryRunResponse contains one result for each call in the request.
+type InboundDryRunResponse struct {
+	Results []InboundDryRunResult `json:"results"`
+}
+
+// DryRunInbound evaluates trunk and dispatch rules for hypothetical inbound calls without placing them.
+func (s *Server) DryRunInbound(ctx context.Context, req *InboundDryRunRequest) (*InboundDryRunResponse, error) {
+	if len(req.Calls) == 0 {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "no calls provided")
+	}
+	if len(req.Calls) > maxDryRunCalls {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "too many calls, max %d", maxDryRunCalls)
+	}
+	resp := &InboundDryRunResponse{Results: make([]InboundDryRunResult, 0, len(req.Calls))}
+	for i := range req.Calls {
+		res, err := s.dryRunInboundCall(ctx, &req.Calls[i])
+		if err != nil {
+			return nil, err
+		}
+		resp.Results = append(resp.Results, *res)
+	}
+	return resp, nil
+}
+
+func (s *Server) dryRunInboundCall(ctx context.Context, c *InboundDryRunCall) (*InboundDryRunResult, error) {
+	src, err := netip.ParseAddr(c.SourceIP)
+	if err != nil {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "invalid source ip %q", c.SourceIP)
+	}
+	from, to := parseDryRunURI(c.From), parseDryRunURI(c.To)
+	if from.User == "" && from.Host == "" {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "from is required")
+	}
+	if to.User == "" && to.Host == "" {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "to is required")
+	}
+	call := &rpc.SIPCall{
+		LkCallId: lksip.NewCallID(),
+		SourceIp: src.String(),
+		Address:  to,
+		From:     from,
+		To:       to,
+	}
+	log := s.log.WithValues("fromIP", src, "fromUser", from.User, "toUser", to.User)
+
+	res := &InboundDryRunResult{}
+	r, err := s.handler.GetAuthCredentials(ctx, call)
+	if err != nil {
+		res.Error = err.Error()
+		return res, nil
+	}
+	res.ProjectID, res.TrunkID = r.ProjectID, r.TrunkID
+	switch r.Result {
+	case AuthNotFound:
+		res.Auth = dryRunAuthNotFound
+		return res, nil
+	case AuthDrop:
+		res.Auth = dryRunAuthDrop
+		return res, nil
+	case AuthPassword:
+		res.Auth = dryRunAuthPassword
+	default:
+		res.Auth = dryRunAuthAccept
+	}
+	if err := s.translateInbound(log, r.TrunkID, call); err != nil {
+		res.Dispatch = dryRunDispatchBlocked
+		return res, nil
+	}
+	res.FromUser, res.ToUser = call.From.GetUser(), call.To.GetUser()
+	res.CallerName = s.cnam.Lookup(ctx, res.FromUser)
+
+	rp := ParseResourcePriority(headerValues(c.Headers, headerResourcePriority)...)
+	disp := s.handler.DispatchCall(ctx, &CallInfo{TrunkID: r.TrunkID, Call: call, CallerName: res.CallerName, ResourcePriority: rp})
+	disp.checkResourcePriority(s.conf, rp)
+	if disp.Result == DispatchRequestPin {
+		res.PinRequired = true
+		if c.Pin != "" {
+			disp = s.handler.DispatchCall(ctx, &CallInfo{TrunkID: r.TrunkID, Call: call, Pin: c.Pin, CallerName: res.CallerName, ResourcePriority: rp})
+			disp.checkResourcePriority(s.conf, rp)
+			res.PinAccepted = disp.Result == DispatchAccept && disp.Room.RoomName != ""
+		}
+	}
+	if disp.ProjectID != "" {
+		res.ProjectID = disp.ProjectID
+	}
+	if disp.TrunkID != "" {
+		res.TrunkID = disp.TrunkID
+	}
+	res.DispatchRuleID = disp.DispatchRuleID
+	if disp.Announcement(s.conf) != nil {
+		res.Dispatch = dryRunDispatchAnnounce
+		log.Debugw("dry run completed", "auth", res.Auth, "dispatch", res.Dispatch)
+		return res, nil
+	}
+	if _, ok := disp.EchoTest(s.conf); ok {
+		disp.Result = DispatchEcho
+	}
+	switch disp.Result {
+	case DispatchAccept:
+		res.Dispatch = dryRunDispatchAccept
+	case DispatchRequestPin:
+		res.Dispatch = dryRunDispatchPin
+	case DispatchNoRuleDrop:
+		res.Dispatch = dryRunDispatchDrop
+	case DispatchEcho:
+		res.Dispatch = dryRunDispatchEcho
+	default:
+		res.Dispatch = dryRunDispatchReject
+	}
+	if disp.Result != DispatchAccept {
+		log.Debugw("dry run completed", "auth", res.Auth, "dispatch", res.Dispatch)
+		return res, nil
+	}
+	var headers Headers
+	for k, v := range c.Headers {
+		headers = append(headers, sip.NewHeader(k, v))
+	}
+	p := disp.Room.Participant
+	res.RoomName = disp.Room.RoomName
+	res.ParticipantIdentity = p.Identity
+	res.ParticipantName = p.Name
+	if res.CallerName != "" {
+		res.ParticipantName = res.CallerName
+	}
+	res.ParticipantAttributes = HeadersToAttrs(p.Attributes, disp.HeadersToAttributes, disp.IncludeHeaders, nil, headers)
+	if res.CallerName != "" {
+		res.ParticipantAttributes[AttrSIPCallerName] = res.CallerName
+	}
+	if len(rp) != 0 {
+		res.ParticipantAttributes[AttrSIPResourcePriority] = FormatResourcePriority(rp)
+	}
+	res.Tags = CallTags(res.ParticipantAttributes)
+	res.Headers = AttrsToHeaders(res.ParticipantAttributes, disp.AttributesToHeaders, disp.Headers)
+	if disp.MediaEncryption != livekit.SIPMediaEncryption_SIP_MEDIA_ENCRYPT_DISABLE {
+		res.MediaEncryption = disp.MediaEncryption.String()
+	}
+	res.Provisional = string(disp.ProvisionalMode(s.conf))
+	for _, err := range MappingErrors(disp.HeadersToAttributes, disp.AttributesToHeaders) {
+		res.MappingErrors = append(res.MappingErrors, err.Error())
+	}
+	slices.Sort(res.MappingErrors)
+	log.Debugw("dry run completed", "auth", res.Auth, "dispatch", res.Dispatch, "room", res.RoomName)
+	return res, nil
+}
+
+// parseDryRunURI parses a SIP URI in a relaxed form: sip:user@host:port, user@host, or just the user.
+func parseDryRunURI(s string) *livekit.SIPUri {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "<"), ">")
+	if i := strings.IndexByte(s, ':'); i > 0 && (strings.EqualFold(s[:i], "sip") || strings.EqualFold(s[:i], "sips")) {
+		s = s[i+1:]
+	}
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		s = s[:i]
+	}
+	u := &livekit.SIPUri{}
+	user, host, ok := strings.Cut(s, "@")
+	if !ok {
+		u.User = user
+		return u
+	}
+	u.User = user
+	u.Host = host
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		if port, err := strconv.Atoi(p); err == nil {
+			u.Host, u.Port = h, uint32(port)
+		}
+	}
+	return u
+}
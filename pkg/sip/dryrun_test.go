@@ -0,0 +1,163 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestParseDryRunURI(t *testing.T) {
+	cases := []struct {
+		in  string
+		exp *livekit.SIPUri
+	}{
+		{in: "+15550100", exp: &livekit.SIPUri{User: "+15550100"}},
+		{in: "1000@example.com", exp: &livekit.SIPUri{User: "1000", Host: "example.com"}},
+		{in: "<sip:1000@10.0.0.1:5080;transport=udp>", exp: &livekit.SIPUri{User: "1000", Host: "10.0.0.1", Port: 5080}},
+		{in: "sips:@example.com", exp: &livekit.SIPUri{Host: "example.com"}},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			require.Equal(t, c.exp, parseDryRunURI(c.in))
+		})
+	}
+}
+
+func TestDryRunInbound(t *testing.T) {
+	s := &Server{log: logger.GetLogger(), conf: &config.Config{
+		DispatchRules: map[string]*config.DispatchRuleConfig{
+			"SDR_1": {Provisional: config.ProvisionalEarlyMedia},
+			"SDR_3": {Echo: &config.EchoConfig{Delay: time.Second}},
+			"SDR_4": {Announce: &config.AnnounceConfig{File: "closed.ogg"}},
+		},
+		Trunks: map[string]*config.TrunkConfig{
+			"ST_1": {DialPlan: &config.DialPlanConfig{
+				StripPrefixes:  []string{"9"},
+				E164:           true,
+				DefaultCountry: "1",
+				Blocklist:      []string{"+1900*"},
+			}},
+		},
+	}}
+	s.handler = &TestHandler{
+		GetAuthCredentialsFunc: func(ctx context.Context, call *rpc.SIPCall) (AuthInfo, error) {
+			switch call.To.User {
+			case "unknown":
+				return AuthInfo{Result: AuthNotFound}, nil
+			}
+			return AuthInfo{Result: AuthPassword, ProjectID: "p1", TrunkID: "ST_1", Username: "u", Password: "p"}, nil
+		},
+		DispatchCallFunc: func(ctx context.Context, info *CallInfo) CallDispatch {
+			require.Equal(t, "ST_1", info.TrunkID)
+			require.Equal(t, "1.2.3.4", info.Call.SourceIp)
+			switch info.Call.To.User {
+			case "norule":
+				return CallDispatch{Result: DispatchNoRuleReject}
+			case "echo":
+				return CallDispatch{Result: DispatchAccept, DispatchRuleID: "SDR_3"}
+			case "closed":
+				return CallDispatch{Result: DispatchAccept, DispatchRuleID: "SDR_4"}
+			case "pin":
+				if info.Pin != "1234" {
+					return CallDispatch{Result: DispatchRequestPin, DispatchRuleID: "SDR_2"}
+				}
+			}
+			return CallDispatch{
+				Result:         DispatchAccept,
+				DispatchRuleID: "SDR_1",
+				Room: RoomConfig{
+					RoomName: "room-" + info.Call.From.User,
+					Token:    "secret",
+					Participant: ParticipantConfig{
+						Identity:   "sip_" + info.Call.From.User,
+						Attributes: map[string]string{AttrSIPTagPrefix + "campaign": "summer"},
+					},
+				},
+				HeadersToAttributes: map[string]string{"X-Customer": "customer", "X-Ticket": AttrSIPTagPrefix + "ticket"},
+				AttributesToHeaders: map[string]string{"customer": "X-Echo"},
+			}
+		},
+	}
+	call := func(to string) InboundDryRunCall {
+		return InboundDryRunCall{
+			SourceIP: "1.2.3.4",
+			From:     "sip:+15550100@carrier.example.com",
+			To:       to,
+			Headers:  map[string]string{"X-Customer": "acme", "X-Ticket": "T-1"},
+		}
+	}
+	pinCall := call("pin")
+	pinCall.Pin = "1234"
+	resp, err := s.DryRunInbound(context.Background(), &InboundDryRunRequest{Calls: []InboundDryRunCall{
+		call("2000"),
+		call("unknown"),
+		call("norule"),
+		call("pin"),
+		pinCall,
+		call("919005550000"),
+		call("echo"),
+		call("closed"),
+	}})
+	require.NoError(t, err)
+	require.Equal(t, []InboundDryRunResult{
+		{
+			Auth: dryRunAuthPassword, ProjectID: "p1", TrunkID: "ST_1",
+			FromUser: "+15550100", ToUser: "+12000",
+			Dispatch: dryRunDispatchAccept, DispatchRuleID: "SDR_1",
+			RoomName: "room-+15550100", ParticipantIdentity: "sip_+15550100",
+			ParticipantAttributes: map[string]string{
+				"customer":                    "acme",
+				AttrSIPTagPrefix + "campaign": "summer",
+				AttrSIPTagPrefix + "ticket":   "T-1",
+			},
+			Tags:        map[string]string{"campaign": "summer", "ticket": "T-1"},
+			Headers:     map[string]string{"X-Echo": "acme"},
+			Provisional: string(config.ProvisionalEarlyMedia),
+		},
+		{Auth: dryRunAuthNotFound},
+		{Auth: dryRunAuthPassword, ProjectID: "p1", TrunkID: "ST_1", FromUser: "+15550100", ToUser: "norule", Dispatch: dryRunDispatchReject},
+		{Auth: dryRunAuthPassword, ProjectID: "p1", TrunkID: "ST_1", FromUser: "+15550100", ToUser: "pin", Dispatch: dryRunDispatchPin, DispatchRuleID: "SDR_2", PinRequired: true},
+		{
+			Auth: dryRunAuthPassword, ProjectID: "p1", TrunkID: "ST_1",
+			FromUser: "+15550100", ToUser: "pin",
+			Dispatch: dryRunDispatchAccept, DispatchRuleID: "SDR_1",
+			PinRequired: true, PinAccepted: true,
+			RoomName: "room-+15550100", ParticipantIdentity: "sip_+15550100",
+			ParticipantAttributes: map[string]string{
+				"customer":                    "acme",
+				AttrSIPTagPrefix + "campaign": "summer",
+				AttrSIPTagPrefix + "ticket":   "T-1",
+			},
+			Tags:        map[string]string{"campaign": "summer", "ticket": "T-1"},
+			Headers:     map[string]string{"X-Echo": "acme"},
+			Provisional: string(config.ProvisionalEarlyMedia),
+		},
+		{Auth: dryRunAuthPassword, ProjectID: "p1", TrunkID: "ST_1", Dispatch: dryRunDispatchBlocked},
+		{Auth: dryRunAuthPassword, ProjectID: "p1", TrunkID: "ST_1", FromUser: "+15550100", ToUser: "echo", Dispatch: dryRunDispatchEcho, DispatchRuleID: "SDR_3"},
+		{Auth: dryRunAuthPassword, ProjectID: "p1", TrunkID: "ST_1", FromUser: "+15550100", ToUser: "closed", Dispatch: dryRunDispatchAnnounce, DispatchRuleID: "SDR_4"},
+	}, resp.Results)
+
+	_, err = s.DryRunInbound(context.Background(), &InboundDryRunRequest{Calls: []InboundDryRunCall{{SourceIP: "bad", From: "a", To: "b"}}})
+	require.Error(t, err)
+}
@@ -0,0 +1,389 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/media-sdk/dtmf"
+	"github.com/livekit/media-sdk/rtp"
+	"github.com/livekit/media-sdk/tones"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	// dtmfCodeFlash is the telephone-event code for a hook flash (RFC 4733).
+	dtmfCodeFlash = 16
+	// dtmfDigitFlash is the digit used for a hook flash in DTMF strings and events.
+	dtmfDigitFlash = '!'
+
+	// dtmfEventVolume is the volume of generated telephone-events, in -dBm0.
+	dtmfEventVolume = 10
+	// dtmfToneVolume is the amplitude of generated audio tones.
+	dtmfToneVolume = math.MaxInt16 / 2
+	// dtmfEndTimeout is the time without event packets after which the event is considered ended,
+	// in case all end packets were lost.
+	dtmfEndTimeout = time.Second
+)
+
+// DTMFEvent is a DTMF event received from the remote. It's reported once the key is released.
+type DTMFEvent struct {
+	dtmf.Event
+	// Duration is how long the key was pressed.
+	Duration time.Duration
+}
+
+// dtmfDigit returns a digit for an event code, or zero if the code is not supported.
+func dtmfDigit(ev dtmf.Event) byte {
+	if ev.Code == dtmfCodeFlash {
+		return dtmfDigitFlash
+	}
+	return ev.Digit
+}
+
+// dtmfAttrs returns participant attributes describing the last received DTMF event.
+func dtmfAttrs(ev DTMFEvent) map[string]string {
+	return map[string]string{
+		AttrSIPLastDTMF:         string([]byte{ev.Digit}),
+		AttrSIPLastDTMFDuration: strconv.FormatInt(ev.Duration.Milliseconds(), 10),
+		AttrSIPLastDTMFVolume:   strconv.Itoa(int(ev.Volume)),
+	}
+}
+
+// dtmfReceiver assembles telephone-event packets into DTMF events.
+//
+// All packets of an event have the same RTP timestamp and a growing duration. The event is reported
+// when the first end packet is received, when the next event starts or after dtmfEndTimeout.
+type dtmfReceiver struct {
+	handler func(ev DTMFEvent)
+
+	mu      sync.Mutex
+	started bool
+	ts      uint32
+	done    bool
+	cur     dtmf.Event
+	timer   *time.Timer
+}
+
+func newDTMFReceiver(h func(ev DTMFEvent)) *dtmfReceiver {
+	return &dtmfReceiver{handler: h}
+}
+
+func (r *dtmfReceiver) HandleRTP(h *rtp.Header, payload []byte) error {
+	ev, err := dtmf.Decode(payload)
+	if err != nil {
+		return nil
+	}
+	ev.Digit = dtmfDigit(ev)
+	var report []DTMFEvent
+
+	r.mu.Lock()
+	switch {
+	case !r.started:
+	case h.Timestamp == r.ts:
+		if r.done {
+			r.mu.Unlock()
+			return nil // repeated end packet
+		}
+	case int32(h.Timestamp-r.ts) < 0:
+		r.mu.Unlock()
+		return nil // late packet of a previous event
+	default:
+		// New event. End packets of the previous one might have been lost.
+		if !r.done {
+			report = append(report, r.event())
+		}
+	}
+	if !r.started || h.Timestamp != r.ts {
+		r.started = true
+		r.ts = h.Timestamp
+		r.done = false
+		r.cur = ev
+	}
+	r.cur.Dur = max(r.cur.Dur, ev.Dur)
+	r.cur.Volume = ev.Volume
+	if ev.End {
+		r.done = true
+		report = append(report, r.event())
+	}
+	r.resetTimer()
+	r.mu.Unlock()
+
+	for _, e := range report {
+		r.handler(e)
+	}
+	return nil
+}
+
+func (r *dtmfReceiver) String() string {
+	return "DTMF"
+}
+
+// event returns the current event. Must be called holding the lock.
+func (r *dtmfReceiver) event() DTMFEvent {
+	ev := r.cur
+	ev.End = true
+	return DTMFEvent{
+		Event:    ev,
+		Duration: time.Duration(ev.Dur) * time.Second / dtmf.SampleRate,
+	}
+}
+
+// resetTimer restarts the end timeout. Must be called holding the lock.
+func (r *dtmfReceiver) resetTimer() {
+	if r.done {
+		if r.timer != nil {
+			r.timer.Stop()
+		}
+		return
+	}
+	if r.timer != nil {
+		r.timer.Reset(dtmfEndTimeout)
+		return
+	}
+	r.timer = time.AfterFunc(dtmfEndTimeout, r.timeout)
+}
+
+func (r *dtmfReceiver) timeout() {
+	r.mu.Lock()
+	if r.done || !r.started {
+		r.mu.Unlock()
+		return
+	}
+	r.done = true
+	ev := r.event()
+	r.mu.Unlock()
+	r.handler(ev)
+}
+
+// DTMFOptions controls pacing of DTMF digits sent to SIP.
+type DTMFOptions struct {
+	// ToneDur is the duration of each digit.
+	ToneDur time.Duration
+	// Gap is the pause after each digit.
+	Gap time.Duration
+	// Pause is the delay for each 'w' character.
+	Pause time.Duration
+	// LongPause is the delay for each ',' character.
+	LongPause time.Duration
+	// OnDigit is called after each digit or pause is sent.
+	OnDigit func(digit byte)
+}
+
+// dtmfOptions returns DTMF options from the config, using defaults for unset values.
+func dtmfOptions(conf config.DTMFConfig) DTMFOptions {
+	return DTMFOptions{
+		ToneDur:   conf.ToneDuration,
+		Gap:       conf.Gap,
+		Pause:     conf.Pause,
+		LongPause: conf.LongPause,
+	}.withDefaults()
+}
+
+func (opts DTMFOptions) withDefaults() DTMFOptions {
+	if opts.ToneDur <= 0 {
+		opts.ToneDur = config.DefaultDTMFToneDuration
+	}
+	if opts.Gap <= 0 {
+		opts.Gap = config.DefaultDTMFGap
+	}
+	if opts.Pause <= 0 {
+		opts.Pause = config.DefaultDTMFPause
+	}
+	if opts.LongPause <= 0 {
+		opts.LongPause = config.DefaultDTMFLongPause
+	}
+	return opts
+}
+
+// normalizeDTMF converts DTMF string to lower case and replaces alternative flash characters.
+// Spaces and dashes, often used for readability, are removed.
+func normalizeDTMF(digits string) (string, error) {
+	var b strings.Builder
+	b.Grow(len(digits))
+	for i := 0; i < len(digits); i++ {
+		c := digits[i]
+		switch {
+		case c == ' ' || c == '-':
+			continue
+		case c == 'f' || c == 'F':
+			c = dtmfDigitFlash
+		case c >= 'A' && c <= 'D':
+			c += 'a' - 'A'
+		}
+		switch code, _ := dtmf.Tone(c); {
+		case c == 'w' || c == ',' || c == dtmfDigitFlash:
+		case code == 0 && c != '0':
+			return "", psrpc.NewErrorf(psrpc.InvalidArgument, "invalid dtmf character %q", c)
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
+// dtmfSender writes DTMF digits to audio and RTP streams.
+type dtmfSender struct {
+	audio  msdk.PCM16Writer
+	events *rtp.Stream
+	ts     func() uint32 // current RTP timestamp of the audio stream
+	opts   DTMFOptions
+
+	ticker *time.Ticker
+	pcm    msdk.PCM16Sample
+	buf    [4]byte
+}
+
+// writeDTMF writes DTMF digits to audio and RTP streams, following pacing options.
+// Digits may contain 'w' and ',' characters for a short and a long pause. Hook flash is sent for '!' or 'f'.
+//
+// It returns the number of digits and pauses sent. If the context is cancelled in the middle of a digit,
+// the event is ended immediately, so that the remote doesn't consider the key to be stuck.
+func writeDTMF(ctx context.Context, audio msdk.PCM16Writer, events *rtp.Stream, ts func() uint32, digits string, opts DTMFOptions) (int, error) {
+	digits, err := normalizeDTMF(digits)
+	if err != nil {
+		return 0, err
+	}
+	s := &dtmfSender{audio: audio, events: events, ts: ts, opts: opts}
+	if audio != nil {
+		s.pcm = make(msdk.PCM16Sample, audio.SampleRate()/int(time.Second/rtp.DefFrameDur))
+	}
+	s.ticker = time.NewTicker(rtp.DefFrameDur)
+	defer s.ticker.Stop()
+
+	for i := 0; i < len(digits); i++ {
+		c := digits[i]
+		switch c {
+		case 'w':
+			err = s.pause(ctx, opts.Pause)
+		case ',':
+			err = s.pause(ctx, opts.LongPause)
+		default:
+			err = s.tone(ctx, c)
+			if err == nil {
+				err = s.pause(ctx, opts.Gap)
+			}
+		}
+		if err != nil {
+			return i, err
+		}
+		if opts.OnDigit != nil {
+			opts.OnDigit(c)
+		}
+	}
+	return len(digits), nil
+}
+
+func (s *dtmfSender) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.ticker.C:
+		return nil
+	}
+}
+
+// pause writes silence for a given duration.
+func (s *dtmfSender) pause(ctx context.Context, dur time.Duration) error {
+	for t := time.Duration(0); t < dur; t += rtp.DefFrameDur {
+		if err := s.wait(ctx); err != nil {
+			return err
+		}
+		if s.audio != nil {
+			s.pcm.Clear()
+			if err := s.audio.WriteSample(s.pcm); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tone writes a single digit. Hook flash has no audio representation, so silence is written instead.
+func (s *dtmfSender) tone(ctx context.Context, digit byte) error {
+	const step = rtp.DefFrameDur
+	var (
+		code byte
+		freq []tones.Hz
+	)
+	if digit == dtmfDigitFlash {
+		code = dtmfCodeFlash
+	} else {
+		code, freq = dtmf.Tone(digit)
+	}
+	if s.events != nil {
+		s.events.ResetTimestamp(s.ts())
+	}
+	for dur := step; ; dur += step {
+		if err := s.wait(ctx); err != nil {
+			if dur > step {
+				// Do not leave the key pressed.
+				_ = s.event(code, dur-step, true)
+			}
+			return err
+		}
+		if s.audio != nil {
+			if len(freq) == 0 {
+				s.pcm.Clear()
+			} else {
+				tones.Generate(s.pcm, dur-step, step, dtmfToneVolume, freq)
+			}
+			if err := s.audio.WriteSample(s.pcm); err != nil {
+				return err
+			}
+		}
+		end := dur+step > s.opts.ToneDur
+		if err := s.event(code, dur, end); err != nil {
+			return err
+		}
+		if end {
+			return nil
+		}
+	}
+}
+
+// event writes a telephone-event packet. End packet is repeated 3 times, as per RFC.
+func (s *dtmfSender) event(code byte, dur time.Duration, end bool) error {
+	if s.events == nil {
+		return nil
+	}
+	n, err := dtmf.Encode(s.buf[:], dtmf.Event{
+		Code:   code,
+		Volume: dtmfEventVolume,
+		Dur:    uint16(dur / (time.Second / dtmf.SampleRate)),
+		End:    end,
+	})
+	if err != nil {
+		return err
+	}
+	repeat := 1
+	if end {
+		repeat = 3
+	}
+	for range repeat {
+		// All packets of an event have the same timestamp. Marker is set on the first one.
+		if err = s.events.WritePayloadAtCurrent(s.buf[:n], dur == rtp.DefFrameDur); err != nil {
+			return err
+		}
+	}
+	return nil
+}
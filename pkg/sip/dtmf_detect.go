@@ -0,0 +1,153 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/media-sdk/dtmf"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/media/vad"
+)
+
+var (
+	dtmfRowFreqs = [4]float64{697, 770, 852, 941}
+	dtmfColFreqs = [4]float64{1209, 1336, 1477, 1633}
+)
+
+// dtmfKeys are DTMF digits by row and column of their tones.
+const dtmfKeys = "123A456B789C*0#D"
+
+const (
+	// dtmfMinLevel is the lowest audio level of a tone, in -dBov.
+	dtmfMinLevel = 40
+	// dtmfMinPower is the part of frame energy which must be in the row and column tones.
+	dtmfMinPower = 0.8
+	// dtmfMinTonePower is the part of frame energy for each of the tones, which limits the twist.
+	dtmfMinTonePower = 0.1
+	// dtmfMinDuration is the time the tone must be present to be reported.
+	dtmfMinDuration = 40 * time.Millisecond
+)
+
+// goertzel returns the power of the frequency in the frame, normalized so that a pure sine wave
+// with the same energy as the frame has the power of 1.
+func goertzel(sample msdk.PCM16Sample, sampleRate int, freq float64, energy float64) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*freq/float64(sampleRate))
+	var s1, s2 float64
+	for _, v := range sample {
+		s0 := float64(v) + coeff*s1 - s2
+		s2, s1 = s1, s0
+	}
+	power := s1*s1 + s2*s2 - coeff*s1*s2
+	return power / (energy * float64(len(sample)) / 2)
+}
+
+// detectDTMFTone returns the digit of the DTMF tone in the frame, or zero if there is none.
+func detectDTMFTone(sample msdk.PCM16Sample, sampleRate int) byte {
+	if len(sample) == 0 || sampleRate <= 0 || vad.AudioLevel(sample) > dtmfMinLevel {
+		return 0
+	}
+	var energy float64
+	for _, v := range sample {
+		energy += float64(v) * float64(v)
+	}
+	best := func(freqs [4]float64) (int, float64) {
+		idx, pow := 0, 0.0
+		for i, f := range freqs {
+			if p := goertzel(sample, sampleRate, f, energy); p > pow {
+				idx, pow = i, p
+			}
+		}
+		return idx, pow
+	}
+	row, rowPow := best(dtmfRowFreqs)
+	col, colPow := best(dtmfColFreqs)
+	if rowPow < dtmfMinTonePower || colPow < dtmfMinTonePower || rowPow+colPow < dtmfMinPower {
+		return 0
+	}
+	return dtmfKeys[row*4+col]
+}
+
+// dtmfDetectStage detects in-band DTMF tones, for remotes which send digits as audio instead of telephone-events.
+// Digits are reported once the tone ends. Tones stay in the audio.
+type dtmfDetectStage struct {
+	handler func(ev DTMFEvent)
+
+	mu    sync.Mutex
+	cand  byte // tone in the last frames, not reported yet
+	cur   byte // reported tone
+	dur   time.Duration
+	level uint8
+}
+
+func newDTMFDetectStage(env MediaStageEnv, _ config.MediaStageConfig) (MediaStage, error) {
+	return &dtmfDetectStage{handler: env.OnDTMF}, nil
+}
+
+func (s *dtmfDetectStage) Name() string {
+	return config.MediaStageDTMF
+}
+
+func (s *dtmfDetectStage) Process(w msdk.PCM16Writer) msdk.PCM16Writer {
+	return &stageWriter{name: "DTMFDetect", w: w, fnc: s.detect}
+}
+
+func (s *dtmfDetectStage) detect(sample msdk.PCM16Sample, sampleRate int) msdk.PCM16Sample {
+	if ev, ok := s.update(sample, sampleRate); ok && s.handler != nil {
+		s.handler(ev)
+	}
+	return sample
+}
+
+// update processes a frame and returns the event if a tone has just ended.
+func (s *dtmfDetectStage) update(sample msdk.PCM16Sample, sampleRate int) (DTMFEvent, bool) {
+	if len(sample) == 0 || sampleRate <= 0 {
+		return DTMFEvent{}, false
+	}
+	digit := detectDTMFTone(sample, sampleRate)
+	dur := time.Duration(len(sample)) * time.Second / time.Duration(sampleRate)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if digit != 0 && digit == s.cand {
+		s.dur += dur
+		s.level = min(s.level, vad.AudioLevel(sample))
+		if s.dur >= dtmfMinDuration {
+			s.cur = digit
+		}
+		return DTMFEvent{}, false
+	}
+	var (
+		ev DTMFEvent
+		ok bool
+	)
+	if s.cur != 0 {
+		code, _ := dtmf.Tone(s.cur)
+		ev = DTMFEvent{
+			Event:    dtmf.Event{Code: code, Digit: s.cur, Volume: s.level, End: true},
+			Duration: s.dur,
+		}
+		ok = true
+	}
+	s.cand, s.cur, s.dur, s.level = digit, 0, 0, vad.SilenceLevel
+	if digit != 0 {
+		s.dur, s.level = dur, vad.AudioLevel(sample)
+	}
+	return ev, ok
+}
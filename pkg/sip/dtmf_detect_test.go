@@ -0,0 +1,96 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	msdk "github.com/livekit/media-sdk"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// dtmfTestFrames generates 20ms frames of dual tones with given frequencies.
+func dtmfTestFrames(sampleRate int, dur time.Duration, freqs ...float64) []msdk.PCM16Sample {
+	frameSize := sampleRate / 50
+	n := int(dur / (20 * time.Millisecond))
+	out := make([]msdk.PCM16Sample, n)
+	for i := range out {
+		frame := make(msdk.PCM16Sample, frameSize)
+		for j := range frame {
+			t := float64(i*frameSize+j) / float64(sampleRate)
+			var v float64
+			for _, f := range freqs {
+				v += 6000 * math.Sin(2*math.Pi*f*t)
+			}
+			frame[j] = int16(v)
+		}
+		out[i] = frame
+	}
+	return out
+}
+
+func TestDetectDTMFTone(t *testing.T) {
+	for _, rate := range []int{8000, 16000, 48000} {
+		t.Run(strconv.Itoa(rate), func(t *testing.T) {
+			for i := range dtmfKeys {
+				frames := dtmfTestFrames(rate, 20*time.Millisecond, dtmfRowFreqs[i/4], dtmfColFreqs[i%4])
+				require.Equal(t, string(dtmfKeys[i]), string(detectDTMFTone(frames[0], rate)))
+			}
+			// Single tones and speech-like chords are not digits.
+			require.Zero(t, detectDTMFTone(dtmfTestFrames(rate, 20*time.Millisecond, 697)[0], rate))
+			require.Zero(t, detectDTMFTone(dtmfTestFrames(rate, 20*time.Millisecond, 300, 697, 1209, 2500)[0], rate))
+			require.Zero(t, detectDTMFTone(make(msdk.PCM16Sample, rate/50), rate))
+		})
+	}
+}
+
+func TestDTMFDetectStage(t *testing.T) {
+	const rate = 8000
+	var events []DTMFEvent
+	st, err := newDTMFDetectStage(MediaStageEnv{OnDTMF: func(ev DTMFEvent) {
+		events = append(events, ev)
+	}}, config.MediaStageConfig{})
+	require.NoError(t, err)
+	w := st.Process(&stageTestWriter{rate: rate})
+
+	write := func(frames []msdk.PCM16Sample) {
+		for _, f := range frames {
+			require.NoError(t, w.WriteSample(f))
+		}
+	}
+	silence := dtmfTestFrames(rate, 100*time.Millisecond)
+	write(dtmfTestFrames(rate, 100*time.Millisecond, 852, 1336)) // 8
+	write(silence)
+	write(dtmfTestFrames(rate, 20*time.Millisecond, 941, 1477)) // too short
+	write(silence)
+	write(dtmfTestFrames(rate, 60*time.Millisecond, 941, 1477)) // #
+	write(dtmfTestFrames(rate, 60*time.Millisecond, 697, 1209)) // 1, right after
+	write(silence)
+
+	require.Len(t, events, 3)
+	require.Equal(t, byte('8'), events[0].Digit)
+	require.Equal(t, byte(8), events[0].Code)
+	require.Equal(t, 100*time.Millisecond, events[0].Duration)
+	require.True(t, events[0].End)
+	require.Equal(t, byte('#'), events[1].Digit)
+	require.Equal(t, 60*time.Millisecond, events[1].Duration)
+	require.Equal(t, byte('1'), events[2].Digit)
+}
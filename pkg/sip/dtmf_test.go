@@ -0,0 +1,145 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/livekit/media-sdk/dtmf"
+	"github.com/livekit/media-sdk/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDTMFReceiver(t *testing.T) {
+	var got []DTMFEvent
+	r := newDTMFReceiver(func(ev DTMFEvent) {
+		got = append(got, ev)
+	})
+	send := func(ts uint32, ev dtmf.Event) {
+		var buf [4]byte
+		n, err := dtmf.Encode(buf[:], ev)
+		require.NoError(t, err)
+		require.NoError(t, r.HandleRTP(&rtp.Header{Timestamp: ts}, buf[:n]))
+	}
+	press := func(ts uint32, ev dtmf.Event, dur time.Duration, end bool) {
+		const step = 160 // 20ms
+		for d := uint16(step); d <= uint16(dur/(time.Second/dtmf.SampleRate)); d += step {
+			ev.Dur = d
+			send(ts, ev)
+		}
+		if end {
+			ev.End = true
+			for range 3 {
+				send(ts, ev)
+			}
+		}
+	}
+
+	// Short press of a regular digit.
+	press(1000, dtmf.Event{Digit: '5', Volume: 10}, 100*time.Millisecond, true)
+	require.Len(t, got, 1)
+	require.Equal(t, byte('5'), got[0].Digit)
+	require.Equal(t, byte(10), got[0].Volume)
+	require.Equal(t, 100*time.Millisecond, got[0].Duration)
+
+	// Long press, end packets are lost. Reported when the next event starts.
+	press(5000, dtmf.Event{Digit: 'a', Volume: 7}, 2*time.Second, false)
+	require.Len(t, got, 1)
+	press(30000, dtmf.Event{Code: dtmfCodeFlash, Volume: 10}, 200*time.Millisecond, true)
+	require.Len(t, got, 3)
+	require.Equal(t, byte('a'), got[1].Digit)
+	require.Equal(t, byte(7), got[1].Volume)
+	require.Equal(t, 2*time.Second, got[1].Duration)
+	require.Equal(t, byte(dtmfDigitFlash), got[2].Digit)
+	require.Equal(t, 200*time.Millisecond, got[2].Duration)
+
+	// Late packets of the previous event are ignored.
+	send(5000, dtmf.Event{Digit: 'a', Dur: 160, End: true})
+	require.Len(t, got, 3)
+}
+
+func TestNormalizeDTMF(t *testing.T) {
+	digits, err := normalizeDTMF("12-AbF w,#")
+	require.NoError(t, err)
+	require.Equal(t, "12ab!w,#", digits)
+
+	_, err = normalizeDTMF("12x")
+	require.Error(t, err)
+}
+
+func TestWriteDTMF(t *testing.T) {
+	opts := DTMFOptions{
+		ToneDur:   60 * time.Millisecond,
+		Gap:       20 * time.Millisecond,
+		Pause:     20 * time.Millisecond,
+		LongPause: 40 * time.Millisecond,
+	}
+	newStream := func() (*rtp.Buffer, *rtp.Stream) {
+		var buf rtp.Buffer
+		return &buf, rtp.NewSeqWriter(&buf).NewStream(101, dtmf.SampleRate)
+	}
+	var ts uint32
+	nextTs := func() uint32 {
+		ts += 8000
+		return ts
+	}
+
+	t.Run("sequence", func(t *testing.T) {
+		buf, s := newStream()
+		var sent []byte
+		opts := opts
+		opts.OnDigit = func(digit byte) {
+			sent = append(sent, digit)
+		}
+		n, err := writeDTMF(context.Background(), nil, s, nextTs, "1w,F", opts)
+		require.NoError(t, err)
+		require.Equal(t, 4, n)
+		require.Equal(t, []byte("1w,!"), sent)
+
+		var got []DTMFEvent
+		r := newDTMFReceiver(func(ev DTMFEvent) {
+			got = append(got, ev)
+		})
+		for _, p := range *buf {
+			require.NoError(t, r.HandleRTP(&p.Header, p.Payload))
+		}
+		require.Len(t, got, 2)
+		require.Equal(t, byte('1'), got[0].Digit)
+		require.Equal(t, opts.ToneDur, got[0].Duration)
+		require.Equal(t, byte(dtmfDigitFlash), got[1].Digit)
+		require.Equal(t, opts.ToneDur, got[1].Duration)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		buf, s := newStream()
+		opts := opts
+		opts.ToneDur = 5 * time.Second
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		n, err := writeDTMF(ctx, nil, s, nextTs, "12", opts)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Zero(t, n)
+
+		// The key must be released.
+		require.NotEmpty(t, *buf)
+		last := (*buf)[len(*buf)-1]
+		ev, err := dtmf.Decode(last.Payload)
+		require.NoError(t, err)
+		require.True(t, ev.End)
+		require.Equal(t, byte('1'), ev.Digit)
+	})
+}
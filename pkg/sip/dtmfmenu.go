@@ -0,0 +1,145 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// dtmfMenu matches in-call DTMF digits against configured menu sequences.
+//
+// Digits are still forwarded to the room, the menu only observes them.
+type dtmfMenu struct {
+	items   []config.DTMFMenuItem
+	timeout time.Duration
+
+	mu   sync.Mutex
+	buf  []byte
+	last time.Time
+}
+
+// newDTMFMenu creates a menu matcher. It returns nil if no menu items are configured.
+func newDTMFMenu(conf config.DTMFConfig) *dtmfMenu {
+	if len(conf.Menu) == 0 {
+		return nil
+	}
+	m := &dtmfMenu{
+		items:   make([]config.DTMFMenuItem, len(conf.Menu)),
+		timeout: conf.MenuTimeout,
+	}
+	if m.timeout <= 0 {
+		m.timeout = config.DefaultDTMFMenuTimeout
+	}
+	for i, it := range conf.Menu {
+		it.Digits = strings.ToLower(it.Digits)
+		if it.Action == "" {
+			it.Action = config.DTMFMenuNotify
+		}
+		m.items[i] = it
+	}
+	return m
+}
+
+// Feed adds a received digit and returns a menu item, if the digit completes its sequence.
+func (m *dtmfMenu) Feed(digit byte, now time.Time) (config.DTMFMenuItem, bool) {
+	if m == nil || digit == 0 {
+		return config.DTMFMenuItem{}, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.last.IsZero() && now.Sub(m.last) > m.timeout {
+		m.buf = m.buf[:0]
+	}
+	m.last = now
+	m.buf = append(m.buf, digit)
+	it, prefix := m.match()
+	if !prefix && len(m.buf) > 1 {
+		// Sequence is broken, but the last digit may start a new one.
+		m.buf = append(m.buf[:0], digit)
+		it, prefix = m.match()
+	}
+	if it != nil || !prefix {
+		m.buf = m.buf[:0]
+	}
+	if it == nil {
+		return config.DTMFMenuItem{}, false
+	}
+	return *it, true
+}
+
+// match checks the current sequence. It returns a matched item and reports whether the sequence
+// is a prefix of any item.
+func (m *dtmfMenu) match() (*config.DTMFMenuItem, bool) {
+	seq := string(m.buf)
+	prefix := false
+	for i := range m.items {
+		it := &m.items[i]
+		if it.Digits == seq {
+			return it, true
+		}
+		if strings.HasPrefix(it.Digits, seq) {
+			prefix = true
+		}
+	}
+	return nil, prefix
+}
+
+// handleDTMFMenu reports the matched menu item to the handler and runs its action.
+func (c *inboundCall) handleDTMFMenu(it config.DTMFMenuItem) {
+	c.log.Infow("dtmf menu sequence entered", "name", it.Name, "digits", it.Digits, "action", it.Action)
+	if c.s.handler != nil {
+		c.s.handler.OnDTMFMenu(c.ctx, &CallIdentifier{
+			ProjectID: c.projectID,
+			CallID:    c.call.LkCallId,
+			SipCallID: c.call.SipCallId,
+		}, it)
+	}
+	switch it.Action {
+	case config.DTMFMenuTransfer:
+		if err := c.transferCall(c.ctx, it.TransferTo, nil, false); err != nil {
+			c.log.Warnw("dtmf menu transfer failed", err, "name", it.Name, "transferTo", it.TransferTo)
+		}
+	case config.DTMFMenuHangup:
+		c.close(false, CallHangup, "dtmf-menu")
+	}
+}
+
+// handleDTMFMenu reports the matched menu item to the handler and runs its action.
+func (c *outboundCall) handleDTMFMenu(it config.DTMFMenuItem) {
+	c.log.Infow("dtmf menu sequence entered", "name", it.Name, "digits", it.Digits, "action", it.Action)
+	ctx := context.Background()
+	if c.c.handler != nil {
+		c.c.handler.OnDTMFMenu(ctx, &CallIdentifier{
+			ProjectID: c.projectID,
+			CallID:    c.state.callInfo.CallId,
+			SipCallID: c.cc.CallID(),
+		}, it)
+	}
+	switch it.Action {
+	case config.DTMFMenuTransfer:
+		if err := c.transferCall(ctx, it.TransferTo, nil, false); err != nil {
+			c.log.Warnw("dtmf menu transfer failed", err, "name", it.Name, "transferTo", it.TransferTo)
+		}
+	case config.DTMFMenuHangup:
+		c.CloseWithReason(CallHangup, "dtmf-menu", livekit.DisconnectReason_CLIENT_INITIATED)
+	}
+}
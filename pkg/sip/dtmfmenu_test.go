@@ -0,0 +1,64 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestDTMFMenu(t *testing.T) {
+	require.Nil(t, newDTMFMenu(config.DTMFConfig{}))
+
+	m := newDTMFMenu(config.DTMFConfig{
+		Menu: []config.DTMFMenuItem{
+			{Digits: "*0", Name: "operator", Action: config.DTMFMenuTransfer, TransferTo: "sip:operator@example.com"},
+			{Digits: "*9", Name: "record"},
+			{Digits: "##A", Name: "end", Action: config.DTMFMenuHangup},
+		},
+		MenuTimeout: time.Second,
+	})
+	now := time.Now()
+	feed := func(digits string) []string {
+		var names []string
+		for i := 0; i < len(digits); i++ {
+			now = now.Add(100 * time.Millisecond)
+			if it, ok := m.Feed(digits[i], now); ok {
+				names = append(names, it.Name)
+			}
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"operator"}, feed("12*0"))
+	require.Equal(t, []string{"record"}, feed("**9"))
+	require.Equal(t, []string{"end"}, feed("#*##a"))
+	require.Empty(t, feed("*1"))
+
+	// Sequence is reset after a long pause.
+	require.Empty(t, feed("*"))
+	now = now.Add(2 * time.Second)
+	require.Empty(t, feed("9"))
+
+	it, ok := m.Feed('*', now)
+	require.False(t, ok)
+	it, ok = m.Feed('9', now)
+	require.True(t, ok)
+	require.Equal(t, config.DTMFMenuNotify, it.Action)
+}
@@ -0,0 +1,172 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/frostbyte73/core"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// echoBufferFrames limits the number of audio frames queued by the echo, enough for the maximal delay.
+const echoBufferFrames = int(config.MaxEchoDelay/(20*time.Millisecond)) + 50
+
+type echoFrame struct {
+	at     time.Time
+	sample msdk.PCM16Sample
+}
+
+// echoWriter plays received audio back with a fixed delay.
+type echoWriter struct {
+	out    msdk.PCM16Writer
+	delay  time.Duration
+	frames chan echoFrame
+	closed core.Fuse
+}
+
+func newEchoWriter(out msdk.PCM16Writer, delay time.Duration) *echoWriter {
+	w := &echoWriter{
+		out:    out,
+		delay:  delay,
+		frames: make(chan echoFrame, echoBufferFrames),
+	}
+	go w.run()
+	return w
+}
+
+func (w *echoWriter) String() string {
+	return "Echo(" + w.delay.String() + ") -> " + w.out.String()
+}
+
+func (w *echoWriter) SampleRate() int {
+	return w.out.SampleRate()
+}
+
+func (w *echoWriter) WriteSample(sample msdk.PCM16Sample) error {
+	if w.closed.IsBroken() {
+		return nil
+	}
+	select {
+	case w.frames <- echoFrame{at: time.Now().Add(w.delay), sample: slices.Clone(sample)}:
+	default:
+		// Drop the frame if the output is stuck.
+	}
+	return nil
+}
+
+func (w *echoWriter) Close() error {
+	w.closed.Break()
+	return nil
+}
+
+func (w *echoWriter) run() {
+	defer w.out.Close()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		var f echoFrame
+		select {
+		case <-w.closed.Watch():
+			return
+		case f = <-w.frames:
+		}
+		if d := time.Until(f.at); d > 0 {
+			timer.Reset(d)
+			select {
+			case <-w.closed.Watch():
+				return
+			case <-timer.C:
+			}
+		}
+		if err := w.out.WriteSample(f.sample); err != nil {
+			return
+		}
+	}
+}
+
+// runEcho answers the call and runs an echo test until the caller hangs up or the max duration is reached.
+func (c *inboundCall) runEcho(ctx context.Context, answerData []byte, econf config.EchoConfig) error {
+	if econf.MaxDuration <= 0 {
+		econf.MaxDuration = config.DefaultEchoMaxDuration
+	}
+	if d := c.quota.MaxDuration(); d > 0 && d < econf.MaxDuration {
+		econf.MaxDuration = d
+	}
+	c.log.Infow("Accepting the call for echo test", "delay", econf.Delay, "maxDuration", econf.MaxDuration)
+	if err := c.cc.Accept(ctx, answerData, nil); err != nil {
+		if errors.Is(err, errInviteCancelled) {
+			c.closeWithCancelled()
+			return nil
+		}
+		c.log.Errorw("Cannot respond to INVITE", err)
+		return err
+	}
+	if d := c.delayed; d != nil {
+		if ok, err := c.waitDelayedAnswer(ctx, d); !ok {
+			return err
+		}
+	}
+	c.mon.CallAnswered()
+	c.media.EnableOut()
+	if ok, err := c.waitMedia(ctx); !ok {
+		return err
+	}
+	// Echo is written via the mixer, which paces the audio and conceals gaps.
+	track := c.lkRoom.NewTrack()
+	if track == nil {
+		c.closeWithHangup()
+		return nil
+	}
+	c.media.WriteAudioTo(newEchoWriter(msdk.ResampleWriter(track, RoomSampleRate), econf.Delay))
+
+	c.state.Update(ctx, func(info *livekit.SIPCallInfo) {
+		info.StartedAtNs = time.Now().UnixNano()
+		info.CallStatus = livekit.SIPCallStatus_SCS_ACTIVE
+	})
+	c.started.Break()
+
+	ctx, cancel := context.WithTimeout(ctx, econf.MaxDuration)
+	defer cancel()
+	for {
+		select {
+		case ev := <-c.dtmf:
+			if ev.Digit == 0 {
+				continue
+			}
+			c.log.Debugw("reading back dtmf", "digit", string([]byte{ev.Digit}))
+			if err := c.media.WriteDTMF(ctx, string([]byte{ev.Digit})); err != nil && ctx.Err() == nil {
+				c.log.Infow("cannot read back dtmf", "error", err)
+			}
+		case <-ctx.Done():
+			c.closeWithHangup()
+			return nil
+		case <-c.quota.Exhausted():
+			c.closeWithQuotaExhausted()
+			return nil
+		case <-c.media.Timeout():
+			c.closeWithTimeout()
+			return psrpc.NewErrorf(psrpc.DeadlineExceeded, "media timeout")
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	msdk "github.com/livekit/media-sdk"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+type echoTestWriter struct {
+	samples chan msdk.PCM16Sample
+	closed  chan struct{}
+}
+
+func (w *echoTestWriter) String() string  { return "Test" }
+func (w *echoTestWriter) SampleRate() int { return RoomSampleRate }
+func (w *echoTestWriter) Close() error {
+	close(w.closed)
+	return nil
+}
+func (w *echoTestWriter) WriteSample(s msdk.PCM16Sample) error {
+	w.samples <- s
+	return nil
+}
+
+func TestEchoWriter(t *testing.T) {
+	out := &echoTestWriter{samples: make(chan msdk.PCM16Sample, 10), closed: make(chan struct{})}
+	const delay = 100 * time.Millisecond
+	w := newEchoWriter(out, delay)
+
+	in := msdk.PCM16Sample{1, 2, 3}
+	start := time.Now()
+	require.NoError(t, w.WriteSample(in))
+	in[0] = 10 // must be copied
+
+	select {
+	case s := <-out.samples:
+		require.GreaterOrEqual(t, time.Since(start), delay)
+		require.Equal(t, msdk.PCM16Sample{1, 2, 3}, s)
+	case <-time.After(time.Second):
+		t.Fatal("no echo")
+	}
+
+	require.NoError(t, w.Close())
+	select {
+	case <-out.closed:
+	case <-time.After(time.Second):
+		t.Fatal("output not closed")
+	}
+}
+
+func TestDispatchEchoTest(t *testing.T) {
+	conf := &config.Config{DispatchRules: map[string]*config.DispatchRuleConfig{
+		"echo": {Echo: &config.EchoConfig{Delay: time.Second}},
+	}}
+	e, ok := (&CallDispatch{Result: DispatchAccept, DispatchRuleID: "echo"}).EchoTest(conf)
+	require.True(t, ok)
+	require.Equal(t, time.Second, e.Delay)
+
+	_, ok = (&CallDispatch{Result: DispatchAccept, DispatchRuleID: "other"}).EchoTest(conf)
+	require.False(t, ok)
+
+	_, ok = (&CallDispatch{Result: DispatchRequestPin, DispatchRuleID: "echo"}).EchoTest(conf)
+	require.False(t, ok)
+
+	e, ok = (&CallDispatch{Result: DispatchEcho}).EchoTest(conf)
+	require.True(t, ok)
+	require.Zero(t, e.Delay)
+}
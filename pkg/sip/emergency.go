@@ -0,0 +1,56 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const headerPriority = "Priority"
+
+// isEmergencyNumber checks if the dialed number is one of the emergency numbers. The leading plus is ignored.
+func isEmergencyNumber(conf config.EmergencyConfig, number string) bool {
+	number = strings.TrimPrefix(strings.TrimSpace(number), "+")
+	if number == "" {
+		return false
+	}
+	return slices.Contains(conf.AllNumbers(), number)
+}
+
+// emergencyHeaders adds the priority header to the INVITE of an emergency call.
+// Headers set explicitly in the request take priority.
+func emergencyHeaders(headers map[string]string) map[string]string {
+	return mergeHeaders(map[string]string{headerPriority: "emergency"}, headers)
+}
+
+// mergeHeaders adds headers to the defaults, replacing defaults with the same name. Header names are not case-sensitive.
+func mergeHeaders(defaults, headers map[string]string) map[string]string {
+	out := make(map[string]string, len(defaults)+len(headers))
+	for k, v := range defaults {
+		out[k] = v
+	}
+	for k, v := range headers {
+		for dk := range out {
+			if strings.EqualFold(k, dk) {
+				delete(out, dk)
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestIsEmergencyNumber(t *testing.T) {
+	conf := config.EmergencyConfig{Country: "us", Numbers: []string{"+4433"}}
+	require.True(t, isEmergencyNumber(conf, "911"))
+	require.True(t, isEmergencyNumber(conf, "+911"))
+	require.True(t, isEmergencyNumber(conf, "4433"))
+	require.True(t, isEmergencyNumber(conf, "+4433"))
+	require.False(t, isEmergencyNumber(conf, "999"))
+	require.False(t, isEmergencyNumber(conf, "9110"))
+	require.False(t, isEmergencyNumber(conf, ""))
+	require.False(t, isEmergencyNumber(config.EmergencyConfig{}, "911"))
+}
+
+func TestEmergencyHeaders(t *testing.T) {
+	require.Equal(t, map[string]string{
+		"Priority": "emergency",
+		"X-Test":   "1",
+	}, emergencyHeaders(map[string]string{"X-Test": "1"}))
+
+	// Headers from the request take priority.
+	require.Equal(t, map[string]string{
+		"priority": "urgent",
+	}, emergencyHeaders(map[string]string{"priority": "urgent"}))
+}
@@ -0,0 +1,173 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+
+	"github.com/livekit/media-sdk/sdp"
+	psdp "github.com/pion/sdp/v3"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+)
+
+// Values for the media encryption metric.
+const (
+	encryptionSRTP       = "srtp"
+	encryptionRTP        = "rtp"
+	encryptionDowngraded = "downgraded"
+	encryptionRejected   = "rejected"
+)
+
+// applyEncryptionPolicy overrides call encryption setting with the trunk policy.
+// It also sets media options required by the policy.
+func applyEncryptionPolicy(policy config.EncryptionPolicy, enc sdp.Encryption, opts *MediaOptions) sdp.Encryption {
+	switch policy {
+	case config.EncryptionPolicyRequire:
+		return sdp.EncryptionRequire
+	case config.EncryptionPolicyPrefer:
+		opts.OfferPlainRTP = true
+		return sdp.EncryptionAllow
+	case config.EncryptionPolicyForbid:
+		opts.RejectEncrypted = true
+		return sdp.EncryptionNone
+	}
+	return enc
+}
+
+// mediaEncryptionResult returns a value for the media encryption metric.
+func mediaEncryptionResult(mc *MediaConf, err error) string {
+	if err != nil {
+		if errors.Is(err, sdp.ErrNoCommonCrypto) || errors.Is(err, siperrors.ErrMediaEncryptionForbidden) {
+			return encryptionRejected
+		}
+		return ""
+	}
+	switch {
+	case mc.Crypto != nil:
+		return encryptionSRTP
+	case mc.Downgraded:
+		return encryptionDowngraded
+	}
+	return encryptionRTP
+}
+
+func isSecureMedia(m *psdp.MediaDescription) bool {
+	return slices.Contains(m.MediaName.Protos, "SAVP")
+}
+
+// addPlainAudioMedia adds a copy of the encrypted audio media line as an unencrypted RTP/AVP alternative.
+//
+// Remote accepts one of the media lines and rejects the other by setting its port to zero.
+func addPlainAudioMedia(s *psdp.SessionDescription) {
+	audio := sdp.GetAudio(s)
+	if audio == nil || !isSecureMedia(audio) {
+		return
+	}
+	plain := *audio
+	plain.MediaName.Protos = []string{"RTP", "AVP"}
+	if isTCPMedia(audio) {
+		plain.MediaName.Protos = []string{"TCP", "RTP", "AVP"}
+	}
+	plain.Attributes = slices.DeleteFunc(slices.Clone(audio.Attributes), func(a psdp.Attribute) bool {
+		return a.Key == "crypto"
+	})
+	s.MediaDescriptions = append(s.MediaDescriptions, &plain)
+}
+
+// selectAudioMedia removes all audio media lines from SDP except the one that should be used.
+// Inactive lines (port 0) and lines not allowed by the RTP transport are skipped,
+// and lines with encrypted media are preferred if preferSecure is set.
+//
+// It returns the modified SDP, the original session description and the index of the selected media line.
+// If SDP contains only one audio line, it's returned unmodified and the session is nil.
+//
+// SDP parser in media-sdk always uses the first audio media line, so we have to select it here.
+func selectAudioMedia(data []byte, preferSecure bool, transport config.MediaTransport) ([]byte, *psdp.SessionDescription, int, error) {
+	if bytes.Count(data, []byte("m=audio ")) < 2 {
+		return data, nil, 0, nil
+	}
+	orig := new(psdp.SessionDescription)
+	if err := orig.Unmarshal(data); err != nil {
+		return nil, nil, 0, err
+	}
+	sel := -1
+	for i, m := range orig.MediaDescriptions {
+		if m.MediaName.Media != "audio" || m.MediaName.Port.Value == 0 || !allowsMedia(transport, m) {
+			continue
+		}
+		if sel < 0 {
+			sel = i
+		}
+		if isSecureMedia(m) == preferSecure {
+			sel = i
+			break
+		}
+	}
+	if sel < 0 {
+		return data, nil, 0, nil
+	}
+	s := *orig
+	s.MediaDescriptions = slices.DeleteFunc(slices.Clone(orig.MediaDescriptions), func(m *psdp.MediaDescription) bool {
+		return m.MediaName.Media == "audio" && m != orig.MediaDescriptions[sel]
+	})
+	out, err := s.Marshal()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return out, orig, sel, nil
+}
+
+// hasPlainAudioMedia checks if the offer from selectAudioMedia has an unencrypted audio media line
+// which can be accepted instead of the encrypted one.
+func hasPlainAudioMedia(offer *psdp.SessionDescription, transport config.MediaTransport) bool {
+	if offer == nil {
+		return false
+	}
+	for _, m := range offer.MediaDescriptions {
+		if m.MediaName.Media == "audio" && m.MediaName.Port.Value != 0 && allowsMedia(transport, m) && !isSecureMedia(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// answerAudioMedia expands the answer to include all media lines from the offer, as required by RFC 3264.
+// Media lines that were not selected are rejected by setting the port to zero.
+func answerAudioMedia(answer *psdp.SessionDescription, offer *psdp.SessionDescription, sel int) {
+	if offer == nil || len(answer.MediaDescriptions) == 0 {
+		return
+	}
+	audio := answer.MediaDescriptions[0]
+	out := make([]*psdp.MediaDescription, 0, len(offer.MediaDescriptions))
+	for i, m := range offer.MediaDescriptions {
+		if i == sel {
+			out = append(out, audio)
+			continue
+		}
+		out = append(out, &psdp.MediaDescription{
+			MediaName: psdp.MediaName{
+				Media:   m.MediaName.Media,
+				Port:    psdp.RangedPort{Value: 0},
+				Protos:  m.MediaName.Protos,
+				Formats: m.MediaName.Formats[:min(1, len(m.MediaName.Formats))],
+			},
+		})
+	}
+	answer.MediaDescriptions = out
+}
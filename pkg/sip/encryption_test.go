@@ -0,0 +1,89 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/livekit/media-sdk/sdp"
+	"github.com/livekit/protocol/logger"
+	psdp "github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+)
+
+func TestEncryptionPolicy(t *testing.T) {
+	newPort := func(t testing.TB, policy config.EncryptionPolicy, enc sdp.Encryption) (*MediaPort, sdp.Encryption) {
+		opts := &MediaOptions{IP: newIP("1.1.1.1")}
+		enc = applyEncryptionPolicy(policy, enc, opts)
+		p, err := NewMediaPortWith(logger.GetLogger(), nil, newTestConn(1), opts, 8000)
+		require.NoError(t, err)
+		t.Cleanup(p.Close)
+		return p, enc
+	}
+	offerer, oenc := newPort(t, config.EncryptionPolicyPrefer, sdp.EncryptionNone)
+	require.Equal(t, sdp.EncryptionAllow, oenc)
+	offer, err := offerer.NewOffer(oenc)
+	require.NoError(t, err)
+	require.Len(t, offer.SDP.MediaDescriptions, 2)
+	require.Equal(t, []string{"RTP", "SAVP"}, offer.SDP.MediaDescriptions[0].MediaName.Protos)
+	require.Equal(t, []string{"RTP", "AVP"}, offer.SDP.MediaDescriptions[1].MediaName.Protos)
+	offerData, err := offer.SDP.Marshal()
+	require.NoError(t, err)
+
+	answer := func(t *testing.T, policy config.EncryptionPolicy, enc sdp.Encryption) (*MediaConf, *MediaConf) {
+		answerer, aenc := newPort(t, policy, enc)
+		ans, conf, err := answerer.SetOffer(offerData, aenc)
+		require.NoError(t, err)
+		answerData, err := ans.SDP.Marshal()
+		require.NoError(t, err)
+
+		var s psdp.SessionDescription
+		require.NoError(t, s.Unmarshal(answerData))
+		require.Len(t, s.MediaDescriptions, 2, "answer must contain all offered media lines")
+
+		oconf, err := offerer.SetAnswer(offer, answerData, oenc)
+		require.NoError(t, err)
+		require.Equal(t, conf.Remote, oconf.Local)
+		return conf, oconf
+	}
+	t.Run("srtp", func(t *testing.T) {
+		conf, oconf := answer(t, config.EncryptionPolicyDefault, sdp.EncryptionAllow)
+		require.NotNil(t, conf.Crypto)
+		require.NotNil(t, oconf.Crypto)
+		require.Equal(t, encryptionSRTP, mediaEncryptionResult(oconf, nil))
+	})
+	t.Run("rtp", func(t *testing.T) {
+		conf, oconf := answer(t, config.EncryptionPolicyForbid, sdp.EncryptionAllow)
+		require.Nil(t, conf.Crypto)
+		require.Nil(t, oconf.Crypto)
+		require.Equal(t, encryptionDowngraded, mediaEncryptionResult(oconf, nil))
+	})
+	t.Run("forbid", func(t *testing.T) {
+		secure, enc := newPort(t, config.EncryptionPolicyRequire, sdp.EncryptionNone)
+		offer, err := secure.NewOffer(enc)
+		require.NoError(t, err)
+		require.Len(t, offer.SDP.MediaDescriptions, 1)
+		data, err := offer.SDP.Marshal()
+		require.NoError(t, err)
+
+		answerer, aenc := newPort(t, config.EncryptionPolicyForbid, sdp.EncryptionAllow)
+		_, _, err = answerer.SetOffer(data, aenc)
+		require.ErrorIs(t, err, siperrors.ErrMediaEncryptionForbidden)
+		require.Equal(t, encryptionRejected, mediaEncryptionResult(nil, err))
+	})
+}
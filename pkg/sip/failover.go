@@ -0,0 +1,553 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"maps"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/livekit/media-sdk/sdp"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+const (
+	// failoverCSeqGap is added to CSeq of dialogs taken over from the peer, since requests sent by the peer
+	// after the last snapshot are unknown.
+	failoverCSeqGap = 100
+	// failoverSetupTimeout limits joining the room and the re-INVITE when taking over a call.
+	failoverSetupTimeout = 30 * time.Second
+	// handoffResultTTL is how long results of handoffs are kept for the instance which passed the call.
+	handoffResultTTL = time.Minute
+)
+
+// CallSnapshot is the minimal state of an answered call, enough for the peer instance to take it over.
+// Dialog fields are from the point of view of this instance sending in-dialog requests.
+type CallSnapshot struct {
+	CallID       string    `json:"call_id"`
+	LocalTag     LocalTag  `json:"local_tag"`
+	RemoteTag    RemoteTag `json:"remote_tag"`
+	LocalURI     sip.Uri   `json:"local_uri"`     // From of requests
+	RemoteURI    sip.Uri   `json:"remote_uri"`    // To of requests
+	RemoteTarget sip.Uri   `json:"remote_target"` // Request-URI, from the Contact of the remote side
+	RouteSet     []sip.Uri `json:"route_set,omitempty"`
+	Destination  string    `json:"destination"` // next hop, host:port
+	Transport    string    `json:"transport"`
+	CSeq         uint32    `json:"cseq"` // next CSeq of requests
+	Inbound      bool      `json:"inbound"`
+
+	TrunkID    string         `json:"trunk_id,omitempty"`
+	Encryption sdp.Encryption `json:"encryption"`
+
+	ProjectID  string            `json:"project_id,omitempty"`
+	LKCallID   string            `json:"lk_call_id"`
+	WsUrl      string            `json:"ws_url,omitempty"`
+	RoomName   string            `json:"room_name"`
+	Identity   string            `json:"identity"`
+	Name       string            `json:"name,omitempty"`
+	Metadata   string            `json:"metadata,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// setRoom sets the room binding of the snapshot from the room the call joined.
+func (s *CallSnapshot) setRoom(r *Room) {
+	lk := r.Room()
+	if lk == nil {
+		return
+	}
+	s.RoomName = lk.Name()
+	p := lk.LocalParticipant
+	s.Identity = p.Identity()
+	s.Name = p.Name()
+	s.Metadata = p.Metadata()
+	s.Attributes = p.Attributes()
+}
+
+// CallStateStore keeps snapshots of calls of each instance, and heartbeats of instances, identified by their address.
+// It is shared by the instance and its peer, see NewRedisCallStateStore.
+type CallStateStore interface {
+	// Save stores snapshots of calls of the owner, replacing previous snapshots of the same calls.
+	Save(ctx context.Context, owner string, calls ...*CallSnapshot) error
+	// Delete removes snapshots of ended calls.
+	Delete(ctx context.Context, owner string, callIDs ...string) error
+	// Take removes and returns all snapshots of the owner. Concurrent callers never get the same snapshot.
+	Take(ctx context.Context, owner string) ([]*CallSnapshot, error)
+	// Heartbeat marks the owner as alive for ttl.
+	Heartbeat(ctx context.Context, owner string, ttl time.Duration) error
+	// Alive checks if the heartbeat of the owner did not expire yet.
+	Alive(ctx context.Context, owner string) (bool, error)
+
+	// Handoff passes a call to another instance, which takes it over. See Service.MigrateCalls.
+	Handoff(ctx context.Context, to string, call *CallSnapshot) error
+	// TakeHandoffs removes and returns calls passed to the owner.
+	TakeHandoffs(ctx context.Context, owner string) ([]*CallSnapshot, error)
+	// SetHandoffResult records whether a call passed to this instance was taken over. Results expire after ttl.
+	SetHandoffResult(ctx context.Context, callID string, ok bool, ttl time.Duration) error
+	// HandoffResult returns the result of a handoff. It returns false for done if the call is not taken over yet.
+	HandoffResult(ctx context.Context, callID string) (ok, done bool, _ error)
+}
+
+// failover replicates snapshots of answered calls for the peer instance, and takes over calls of the peer
+// once its heartbeats stop. It also takes over calls migrated from other instances.
+//
+// Calls are taken over with a re-INVITE, so the remote side must support them. Recordings, media stages and
+// other features of the call which are not in the snapshot are not restored.
+type failover struct {
+	log       logger.Logger
+	mon       *stats.Monitor
+	address   string
+	peer      string
+	heartbeat time.Duration
+	timeout   time.Duration
+	store     CallStateStore
+	takeOver  func(ctx context.Context, call *CallSnapshot) error // set when the service starts
+	stop      core.Fuse
+	updates   storeQueue
+}
+
+// newFailover creates call replication from the config. It returns nil if failover is disabled.
+func newFailover(log logger.Logger, mon *stats.Monitor, conf *config.FailoverConfig, sconf *ServiceConfig, sipPort int) *failover {
+	if conf == nil {
+		return nil
+	}
+	f := &failover{
+		log:       log,
+		mon:       mon,
+		address:   conf.Address,
+		peer:      conf.Peer,
+		heartbeat: conf.Heartbeat,
+		timeout:   conf.Timeout,
+		updates:   storeQueue{log: log, msg: "cannot update call snapshots"},
+	}
+	if f.address == "" {
+		f.address = net.JoinHostPort(sconf.SignalingIPLocal.String(), strconv.Itoa(sipPort))
+	}
+	if f.heartbeat == 0 {
+		f.heartbeat = config.DefaultFailoverHeartbeat
+	}
+	if f.timeout == 0 {
+		f.timeout = config.DefaultFailoverTimeout
+	}
+	return f
+}
+
+// Save replicates a snapshot of an answered call.
+func (f *failover) Save(call *CallSnapshot) {
+	if f == nil || call == nil {
+		return
+	}
+	f.updates.Push(func(ctx context.Context) error {
+		return f.store.Save(ctx, f.address, call)
+	})
+}
+
+// Delete removes the snapshot once the call ends.
+func (f *failover) Delete(callID string) {
+	if f == nil || callID == "" {
+		return
+	}
+	f.updates.Push(func(ctx context.Context) error {
+		return f.store.Delete(ctx, f.address, callID)
+	})
+}
+
+func (f *failover) Start(takeOver func(ctx context.Context, call *CallSnapshot) error) {
+	if f == nil {
+		return
+	}
+	f.takeOver = takeOver
+	// Snapshots left by a previous run of this instance are stale: the calls were either taken over or dropped.
+	f.updates.Push(func(ctx context.Context) error {
+		calls, err := f.store.Take(ctx, f.address)
+		if len(calls) != 0 {
+			f.log.Infow("dropped stale call snapshots", "calls", len(calls))
+		}
+		return err
+	})
+	go f.run()
+}
+
+func (f *failover) Stop() {
+	if f == nil {
+		return
+	}
+	f.stop.Break()
+}
+
+// run sends heartbeats and takes over calls of the peer once its heartbeats stop.
+func (f *failover) run() {
+	ticker := time.NewTicker(f.heartbeat)
+	defer ticker.Stop()
+	// Calls are also taken over if the peer is already down when this instance starts.
+	peerUp := true
+	for {
+		f.beat()
+		if up := f.peerAlive(); up != peerUp {
+			peerUp = up
+			if up {
+				f.log.Infow("failover peer is up", "peer", f.peer)
+			} else {
+				go f.takeOverPeer()
+			}
+		}
+		f.takeHandoffs()
+		select {
+		case <-f.stop.Watch():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *failover) beat() {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := f.store.Heartbeat(ctx, f.address, f.timeout); err != nil {
+		f.log.Warnw("cannot send failover heartbeat", err)
+	}
+}
+
+// peerAlive checks the heartbeat of the peer. The peer is assumed to be alive if the store fails,
+// so that calls are not taken over while both instances run.
+func (f *failover) peerAlive() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	ok, err := f.store.Alive(ctx, f.peer)
+	if err != nil {
+		f.log.Warnw("cannot check failover peer", err, "peer", f.peer)
+		return true
+	}
+	return ok
+}
+
+func (f *failover) takeOverPeer() {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	calls, err := f.store.Take(ctx, f.peer)
+	cancel()
+	if err != nil {
+		f.log.Warnw("cannot load calls of failover peer", err, "peer", f.peer)
+		return
+	}
+	if len(calls) == 0 {
+		return
+	}
+	f.log.Infow("failover peer is down, taking over its calls", "peer", f.peer, "calls", len(calls))
+	var wg sync.WaitGroup
+	for _, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := f.takeOver(context.Background(), call)
+			if err != nil {
+				f.log.Warnw("cannot take over call", err, "sipCallID", call.CallID, "callID", call.LKCallID)
+			}
+			f.mon.FailoverCall(err == nil)
+		}()
+	}
+	wg.Wait()
+}
+
+// takeHandoffs takes over calls migrated to this instance, and reports the results to the instances passing them.
+func (f *failover) takeHandoffs() {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	calls, err := f.store.TakeHandoffs(ctx, f.address)
+	cancel()
+	if err != nil {
+		f.log.Warnw("cannot load migrated calls", err)
+	}
+	for _, call := range calls {
+		go func() {
+			err := f.takeOver(context.Background(), call)
+			if err != nil {
+				f.log.Warnw("cannot take over migrated call", err, "sipCallID", call.CallID, "callID", call.LKCallID)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+			defer cancel()
+			if err = f.store.SetHandoffResult(ctx, call.CallID, err == nil, handoffResultTTL); err != nil {
+				f.log.Warnw("cannot report migrated call", err, "sipCallID", call.CallID)
+			}
+		}()
+	}
+}
+
+// Handoff passes the call to another instance and waits until the instance takes it over.
+func (f *failover) Handoff(ctx context.Context, to string, call *CallSnapshot) error {
+	if err := f.store.Handoff(ctx, to, call); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(f.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return psrpc.NewErrorf(psrpc.DeadlineExceeded, "call was not taken over by %s", to)
+		case <-ticker.C:
+		}
+		ok, done, err := f.store.HandoffResult(ctx, call.CallID)
+		if err != nil {
+			f.log.Warnw("cannot check migrated call", err, "sipCallID", call.CallID)
+		} else if done && !ok {
+			return psrpc.NewErrorf(psrpc.Unavailable, "call was not taken over by %s", to)
+		} else if done {
+			return nil
+		}
+	}
+}
+
+// dialogSnapshot sets dialog fields of the snapshot. It returns false if the call is not established.
+func (c *sipInbound) dialogSnapshot(s *CallSnapshot) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.invite == nil || c.inviteOk == nil {
+		return false
+	}
+	s.CallID = c.callID
+	s.LocalTag = c.id
+	s.RemoteTag = c.tag
+	s.LocalURI = c.to.Address
+	s.RemoteURI = c.from.Address
+	s.RemoteTarget = c.from.Address
+	if contact := c.invite.Contact(); contact != nil {
+		s.RemoteTarget = contact.Address
+	}
+	for _, h := range c.invite.GetHeaders("Record-Route") {
+		s.RouteSet = append(s.RouteSet, h.(*sip.RecordRouteHeader).Address)
+	}
+	s.Destination = c.inviteOk.Destination()
+	s.Transport = c.invite.Transport()
+	s.CSeq = c.nextRequestCSeq
+	s.Inbound = true
+	return true
+}
+
+// dialogSnapshot sets dialog fields of the snapshot. It returns false if the call is not established.
+func (c *sipOutbound) dialogSnapshot(s *CallSnapshot) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.invite == nil || c.inviteOk == nil {
+		return false
+	}
+	s.CallID = c.callID
+	s.LocalTag = c.id
+	s.RemoteTag = c.tag
+	s.LocalURI = c.from.Address
+	if to := c.inviteOk.To(); to != nil {
+		s.RemoteURI = to.Address
+	}
+	s.RemoteTarget = c.invite.Recipient
+	for _, h := range c.invite.GetHeaders("Route") {
+		s.RouteSet = append(s.RouteSet, h.(*sip.RouteHeader).Address)
+	}
+	s.Destination = c.invite.Destination()
+	s.Transport = c.invite.Transport()
+	s.CSeq = c.nextCSeq
+	return true
+}
+
+// restoreDialog sets the dialog of a call taken over from the peer, as if this side sent the INVITE.
+func (c *sipOutbound) restoreDialog(s *CallSnapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.callID = s.CallID
+	c.tag = s.RemoteTag
+	c.log = c.log.WithValues("sipCallID", c.callID)
+	c.transport = Transport(strings.ToLower(s.Transport))
+	if c.transport == TransportUDP {
+		c.setTransport("") // UDP is used by default, keep the Contact unchanged
+	} else {
+		c.setTransport(c.transport)
+	}
+	// Keep the URI of the original dialog, it's only set by the transport.
+	c.from.Address = s.LocalURI
+	c.from.DisplayName = ""
+	c.to = &sip.ToHeader{Address: s.RemoteURI, Params: sip.NewParams()}
+	c.to.Params.Add("tag", string(s.RemoteTag))
+	c.nextCSeq = s.CSeq + failoverCSeqGap
+
+	req := sip.NewRequest(sip.INVITE, s.RemoteTarget)
+	setCSeq(req, s.CSeq)
+	callID := sip.CallIDHeader(s.CallID)
+	req.AppendHeader(&callID)
+	req.AppendHeader(c.from)
+	req.AppendHeader(&sip.ToHeader{Address: s.RemoteURI, Params: sip.NewParams()})
+	for _, uri := range s.RouteSet {
+		req.AppendHeader(&sip.RouteHeader{Address: uri})
+	}
+	req.SetTransport(s.Transport)
+	req.SetDestination(s.Destination)
+	c.c.addVia(req)
+
+	resp := sip.NewResponseFromRequest(req, sip.StatusOK, "OK", nil)
+	resp.RemoveHeader("To")
+	resp.AppendHeader(c.to)
+	c.invite, c.inviteOk = req, resp
+	c.fsm.Transition(DialogAnswered)
+}
+
+func (c *inboundCall) snapshot() *CallSnapshot {
+	if c.media == nil {
+		return nil
+	}
+	s := &CallSnapshot{
+		TrunkID:    c.trunkID,
+		Encryption: mediaEncryption(c.media),
+		ProjectID:  c.projectID,
+		LKCallID:   c.state.callInfo.CallId,
+		WsUrl:      c.wsUrl,
+	}
+	if !c.cc.dialogSnapshot(s) {
+		return nil
+	}
+	s.setRoom(c.lkRoom)
+	return s
+}
+
+func (c *outboundCall) snapshot() *CallSnapshot {
+	s := &CallSnapshot{
+		TrunkID:    c.sipConf.trunkID,
+		Encryption: mediaEncryption(c.media),
+		ProjectID:  c.projectID,
+		LKCallID:   c.state.callInfo.CallId,
+		WsUrl:      c.sipConf.wsUrl,
+	}
+	if !c.cc.dialogSnapshot(s) {
+		return nil
+	}
+	s.setRoom(c.lkRoom)
+	return s
+}
+
+// recoverCall takes over a call of the failed peer. It joins the room again and sends a re-INVITE with a new offer,
+// so that the remote side sends media to this instance. Since the remote side only sees in-dialog requests,
+// the call continues as an outbound call of this instance, regardless of its original direction.
+func (c *Client) recoverCall(ctx context.Context, s *CallSnapshot) error {
+	ctx, cancel := context.WithTimeout(ctx, failoverSetupTimeout)
+	defer cancel()
+	log := c.log.WithValues(
+		"callID", s.LKCallID,
+		"sipCallID", s.CallID,
+		"room", s.RoomName,
+		"participant", s.Identity,
+	)
+	log.Infow("Taking over call of failover peer")
+	dir := livekit.SIPCallDirection_SCD_OUTBOUND
+	if s.Inbound {
+		dir = livekit.SIPCallDirection_SCD_INBOUND
+	}
+	state := NewCallState(c.getIOClient(s.ProjectID), &livekit.SIPCallInfo{
+		CallId:                s.LKCallID,
+		Region:                c.region,
+		TrunkId:               s.TrunkID,
+		RoomName:              s.RoomName,
+		ParticipantIdentity:   s.Identity,
+		ParticipantAttributes: s.Attributes,
+		CallDirection:         dir,
+		CreatedAtNs:           time.Now().UnixNano(),
+	})
+	room := RoomConfig{
+		WsUrl:    s.WsUrl,
+		RoomName: s.RoomName,
+		Participant: ParticipantConfig{
+			Identity:   s.Identity,
+			Name:       s.Name,
+			Metadata:   s.Metadata,
+			Attributes: maps.Clone(s.Attributes),
+		},
+	}
+	sipConf := sipOutboundConfig{
+		trunkID:         s.TrunkID,
+		address:         s.RemoteURI.Host,
+		transport:       SIPTransportFrom(Transport(strings.ToLower(s.Transport))),
+		host:            s.LocalURI.Host,
+		from:            s.LocalURI.User,
+		to:              s.RemoteURI.User,
+		mediaEncryption: s.Encryption,
+		wsUrl:           s.WsUrl,
+	}
+	call, err := c.newCall(ctx, c.conf, log, s.LocalTag, room, sipConf, state, s.ProjectID)
+	if err != nil {
+		return err
+	}
+	if err := call.resume(ctx, s); err != nil {
+		call.mu.Lock()
+		call.close(err, callDropped, "failover-failed", livekit.DisconnectReason_UNKNOWN_REASON)
+		call.mu.Unlock()
+		return err
+	}
+	go call.WaitClose(context.WithoutCancel(ctx))
+	return nil
+}
+
+// resume restores the dialog of a call taken over from the peer and moves media of the call to this instance.
+func (c *outboundCall) resume(ctx context.Context, s *CallSnapshot) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cc.restoreDialog(s)
+	c.c.owners.Add(s.CallID)
+	c.c.cmu.Lock()
+	c.c.byRemote[c.cc.Tag()] = c
+	c.c.cmu.Unlock()
+
+	offer, err := c.media.NewOffer(s.Encryption)
+	if err != nil {
+		return err
+	}
+	offerData, err := offer.SDP.Marshal()
+	if err != nil {
+		return err
+	}
+	answerData, err := c.cc.ReInvite(ctx, offerData)
+	if err != nil {
+		return err
+	}
+	mc, err := c.media.SetAnswer(offer, answerData, s.Encryption)
+	if err != nil {
+		return err
+	}
+	if err = c.media.SetConfig(mc); err != nil {
+		return err
+	}
+	c.media.setHandlerStage(c.c.handler.GetMediaProcessor(c.sipConf.enabledFeatures))
+	c.media.EnableOut()
+	c.connectMedia()
+	c.setStatus(CallActive)
+	c.started.Break()
+	go syncQualityAttr(c.stopped.Watch(), c.media, c.lkRoom)
+	go c.cc.keepAlive(c.stopped.Watch())
+	c.lkRoom.Subscribe()
+	c.state.Update(ctx, func(info *livekit.SIPCallInfo) {
+		if room := c.lkRoom.Room(); room != nil {
+			info.RoomId = room.SID()
+		}
+		info.StartedAtNs = time.Now().UnixNano()
+		info.CallStatus = livekit.SIPCallStatus_SCS_ACTIVE
+		info.AudioCodec = mc.Audio.Codec.Info().SDPName
+	})
+	c.c.failover.Save(c.snapshot())
+	c.log.Infow("Call taken over from failover peer")
+	return nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisCallsKeyPrefix is the prefix of Redis hashes of call snapshots, followed by the owner address.
+	// Fields are SIP Call-IDs.
+	redisCallsKeyPrefix = "sip_failover_calls:"
+	// redisHeartbeatKeyPrefix is the prefix of Redis keys of heartbeats, followed by the owner address.
+	redisHeartbeatKeyPrefix = "sip_failover_alive:"
+	// redisCallsTTL limits how long snapshots stay in Redis if neither the owner nor its peer run.
+	// It's refreshed by heartbeats of the owner.
+	redisCallsTTL = 24 * time.Hour
+	// redisHandoffKeyPrefix is the prefix of Redis hashes of migrated calls, followed by the address of the instance
+	// taking them over. Fields are SIP Call-IDs.
+	redisHandoffKeyPrefix = "sip_failover_handoff:"
+	// redisHandoffResultKeyPrefix is the prefix of Redis keys of handoff results, followed by the SIP Call-ID.
+	redisHandoffResultKeyPrefix = "sip_failover_handoff_result:"
+	// redisHandoffTTL limits how long migrated calls wait for an instance which doesn't run.
+	redisHandoffTTL = time.Minute
+)
+
+type redisCallStateStore struct {
+	rc redis.UniversalClient
+}
+
+// NewRedisCallStateStore creates a CallStateStore shared by instances using the same Redis.
+func NewRedisCallStateStore(rc redis.UniversalClient) CallStateStore {
+	return &redisCallStateStore{rc: rc}
+}
+
+func (s *redisCallStateStore) Save(ctx context.Context, owner string, calls ...*CallSnapshot) error {
+	values := make([]any, 0, 2*len(calls))
+	for _, call := range calls {
+		data, err := json.Marshal(call)
+		if err != nil {
+			return err
+		}
+		values = append(values, call.CallID, data)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	key := redisCallsKeyPrefix + owner
+	_, err := s.rc.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, key, values...)
+		p.Expire(ctx, key, redisCallsTTL)
+		return nil
+	})
+	return err
+}
+
+func (s *redisCallStateStore) Delete(ctx context.Context, owner string, callIDs ...string) error {
+	if len(callIDs) == 0 {
+		return nil
+	}
+	return s.rc.HDel(ctx, redisCallsKeyPrefix+owner, callIDs...).Err()
+}
+
+func (s *redisCallStateStore) Take(ctx context.Context, owner string) ([]*CallSnapshot, error) {
+	return s.take(ctx, redisCallsKeyPrefix+owner)
+}
+
+func (s *redisCallStateStore) take(ctx context.Context, key string) ([]*CallSnapshot, error) {
+	var get *redis.MapStringStringCmd
+	_, err := s.rc.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.HGetAll(ctx, key)
+		p.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var (
+		calls []*CallSnapshot
+		errs  []error
+	)
+	for _, data := range get.Val() {
+		call := new(CallSnapshot)
+		if err := json.Unmarshal([]byte(data), call); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		calls = append(calls, call)
+	}
+	return calls, errors.Join(errs...)
+}
+
+func (s *redisCallStateStore) Heartbeat(ctx context.Context, owner string, ttl time.Duration) error {
+	_, err := s.rc.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, redisHeartbeatKeyPrefix+owner, time.Now().Unix(), ttl)
+		p.Expire(ctx, redisCallsKeyPrefix+owner, redisCallsTTL)
+		return nil
+	})
+	return err
+}
+
+func (s *redisCallStateStore) Alive(ctx context.Context, owner string) (bool, error) {
+	n, err := s.rc.Exists(ctx, redisHeartbeatKeyPrefix+owner).Result()
+	return n != 0, err
+}
+
+func (s *redisCallStateStore) Handoff(ctx context.Context, to string, call *CallSnapshot) error {
+	data, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+	key := redisHandoffKeyPrefix + to
+	_, err = s.rc.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, redisHandoffResultKeyPrefix+call.CallID)
+		p.HSet(ctx, key, call.CallID, data)
+		p.Expire(ctx, key, redisHandoffTTL)
+		return nil
+	})
+	return err
+}
+
+func (s *redisCallStateStore) TakeHandoffs(ctx context.Context, owner string) ([]*CallSnapshot, error) {
+	return s.take(ctx, redisHandoffKeyPrefix+owner)
+}
+
+func (s *redisCallStateStore) SetHandoffResult(ctx context.Context, callID string, ok bool, ttl time.Duration) error {
+	val := "0"
+	if ok {
+		val = "1"
+	}
+	return s.rc.Set(ctx, redisHandoffResultKeyPrefix+callID, val, ttl).Err()
+}
+
+func (s *redisCallStateStore) HandoffResult(ctx context.Context, callID string) (ok, done bool, _ error) {
+	val, err := s.rc.Get(ctx, redisHandoffResultKeyPrefix+callID).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, false, nil
+	} else if err != nil {
+		return false, false, err
+	}
+	return val == "1", true, nil
+}
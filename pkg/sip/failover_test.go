@@ -0,0 +1,297 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/media-sdk/sdp"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+type testCallStateStore struct {
+	mu       sync.Mutex
+	calls    map[string]map[string]*CallSnapshot
+	alive    map[string]time.Time
+	handoffs map[string][]*CallSnapshot
+	results  map[string]bool
+}
+
+func newTestCallStateStore() *testCallStateStore {
+	return &testCallStateStore{
+		calls:    make(map[string]map[string]*CallSnapshot),
+		alive:    make(map[string]time.Time),
+		handoffs: make(map[string][]*CallSnapshot),
+		results:  make(map[string]bool),
+	}
+}
+
+func (s *testCallStateStore) Save(ctx context.Context, owner string, calls ...*CallSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.calls[owner] == nil {
+		s.calls[owner] = make(map[string]*CallSnapshot)
+	}
+	for _, call := range calls {
+		s.calls[owner][call.CallID] = call
+	}
+	return nil
+}
+
+func (s *testCallStateStore) Delete(ctx context.Context, owner string, callIDs ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range callIDs {
+		delete(s.calls[owner], id)
+	}
+	return nil
+}
+
+func (s *testCallStateStore) Take(ctx context.Context, owner string) ([]*CallSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var calls []*CallSnapshot
+	for _, call := range s.calls[owner] {
+		calls = append(calls, call)
+	}
+	delete(s.calls, owner)
+	return calls, nil
+}
+
+func (s *testCallStateStore) Heartbeat(ctx context.Context, owner string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alive[owner] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *testCallStateStore) Alive(ctx context.Context, owner string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Before(s.alive[owner]), nil
+}
+
+func (s *testCallStateStore) Handoff(ctx context.Context, to string, call *CallSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.results, call.CallID)
+	s.handoffs[to] = append(s.handoffs[to], call)
+	return nil
+}
+
+func (s *testCallStateStore) TakeHandoffs(ctx context.Context, owner string) ([]*CallSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := s.handoffs[owner]
+	delete(s.handoffs, owner)
+	return calls, nil
+}
+
+func (s *testCallStateStore) SetHandoffResult(ctx context.Context, callID string, ok bool, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[callID] = ok
+	return nil
+}
+
+func (s *testCallStateStore) HandoffResult(ctx context.Context, callID string) (ok, done bool, _ error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ok, done = s.results[callID]
+	return ok, done, nil
+}
+
+func (s *testCallStateStore) count(owner string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls[owner])
+}
+
+func TestFailoverConfig(t *testing.T) {
+	require.NoError(t, (&config.FailoverConfig{Peer: "10.0.0.2:5060"}).Validate())
+	require.NoError(t, (&config.FailoverConfig{Address: "10.0.0.1:5060", Peer: "10.0.0.2:5060", Heartbeat: time.Second, Timeout: 3 * time.Second}).Validate())
+	require.Error(t, (&config.FailoverConfig{}).Validate())
+	require.Error(t, (&config.FailoverConfig{Peer: "10.0.0.2"}).Validate())
+	require.Error(t, (&config.FailoverConfig{Address: "10.0.0.2:5060", Peer: "10.0.0.2:5060"}).Validate())
+	require.Error(t, (&config.FailoverConfig{Peer: "10.0.0.2:5060", Heartbeat: -time.Second}).Validate())
+	require.Error(t, (&config.FailoverConfig{Peer: "10.0.0.2:5060", Heartbeat: 10 * time.Second}).Validate())
+}
+
+func TestFailover(t *testing.T) {
+	require.Nil(t, newFailover(logger.GetLogger(), nil, nil, nil, 0))
+	var none *failover
+	none.Save(&CallSnapshot{CallID: "a"})
+	none.Delete("a")
+	none.Start(nil)
+	none.Stop()
+
+	const peer = "10.0.0.2:5060"
+	store := newTestCallStateStore()
+	sconf := &ServiceConfig{SignalingIPLocal: newIP("10.0.0.1")}
+	f := newFailover(logger.GetLogger(), nil, &config.FailoverConfig{
+		Peer:      peer,
+		Heartbeat: 10 * time.Millisecond,
+		Timeout:   50 * time.Millisecond,
+	}, sconf, 5060)
+	f.store = store
+	require.Equal(t, "10.0.0.1:5060", f.address)
+
+	// Stale snapshots of this instance and calls of the peer, which is down.
+	_ = store.Save(context.Background(), f.address, &CallSnapshot{CallID: "stale"})
+	_ = store.Save(context.Background(), peer, &CallSnapshot{CallID: "peer-1"}, &CallSnapshot{CallID: "peer-2"})
+
+	taken := make(chan string, 10)
+	f.Start(func(ctx context.Context, call *CallSnapshot) error {
+		taken <- call.CallID
+		return nil
+	})
+	t.Cleanup(f.Stop)
+	got := []string{<-taken, <-taken}
+	require.ElementsMatch(t, []string{"peer-1", "peer-2"}, got)
+	require.Zero(t, store.count(peer))
+
+	ok, _ := store.Alive(context.Background(), f.address)
+	require.True(t, ok)
+	f.Save(&CallSnapshot{CallID: "local"})
+	require.Eventually(t, func() bool {
+		return store.count(f.address) == 1
+	}, time.Second, 10*time.Millisecond)
+	f.Delete("local")
+	require.Eventually(t, func() bool {
+		return store.count(f.address) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// Calls are taken over again once the peer restarts and fails again.
+	_ = store.Heartbeat(context.Background(), peer, 100*time.Millisecond)
+	_ = store.Save(context.Background(), peer, &CallSnapshot{CallID: "peer-3"})
+	select {
+	case id := <-taken:
+		require.Equal(t, "peer-3", id)
+	case <-time.After(time.Second):
+		t.Fatal("call of the failed peer was not taken over")
+	}
+}
+
+func errCode(t *testing.T, err error) psrpc.ErrorCode {
+	var perr psrpc.Error
+	require.True(t, errors.As(err, &perr))
+	return perr.Code()
+}
+
+func TestFailoverHandoff(t *testing.T) {
+	store := newTestCallStateStore()
+	newInstance := func(ip, peer string) *failover {
+		f := newFailover(logger.GetLogger(), nil, &config.FailoverConfig{
+			Peer:      peer,
+			Heartbeat: 10 * time.Millisecond,
+			Timeout:   50 * time.Millisecond,
+		}, &ServiceConfig{SignalingIPLocal: newIP(ip)}, 5060)
+		f.store = store
+		return f
+	}
+	a := newInstance("10.0.0.1", "10.0.0.2:5060")
+	b := newInstance("10.0.0.2", "10.0.0.1:5060")
+	b.Start(func(ctx context.Context, call *CallSnapshot) error {
+		if call.CallID == "bad" {
+			return errors.New("re-INVITE failed")
+		}
+		return nil
+	})
+	t.Cleanup(b.Stop)
+
+	ctx := context.Background()
+	require.NoError(t, a.Handoff(ctx, b.address, &CallSnapshot{CallID: "ok"}))
+	err := a.Handoff(ctx, b.address, &CallSnapshot{CallID: "bad"})
+	require.Equal(t, psrpc.Unavailable, errCode(t, err))
+
+	// Calls are not passed to instances which don't run.
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	err = a.Handoff(ctx, "10.0.0.3:5060", &CallSnapshot{CallID: "lost"})
+	require.Equal(t, psrpc.DeadlineExceeded, errCode(t, err))
+
+	_, err = (&Service{srv: &Server{}}).MigrateCalls(ctx, &MigrateCallsRequest{})
+	require.Equal(t, psrpc.FailedPrecondition, errCode(t, err))
+	require.Equal(t, livekit.DisconnectReason_MIGRATION, callMigrated.DisconnectReason())
+}
+
+func TestFailoverRestoreDialog(t *testing.T) {
+	cli := NewClient("", &config.Config{SIPPort: 5060}, logger.GetLogger(), nil, nil)
+	require.NoError(t, cli.Start(nil, &ServiceConfig{SignalingIP: newIP("10.0.0.1"), SignalingIPLocal: newIP("10.0.0.1")}))
+	t.Cleanup(cli.Stop)
+
+	snap := &CallSnapshot{
+		CallID:       "call-id",
+		LocalTag:     "local-tag",
+		RemoteTag:    "remote-tag",
+		LocalURI:     sip.Uri{User: "+15550100", Host: "sip.example.com"},
+		RemoteURI:    sip.Uri{User: "+15550200", Host: "carrier.example.com"},
+		RemoteTarget: sip.Uri{User: "+15550200", Host: "192.0.2.10", Port: 5080},
+		RouteSet:     []sip.Uri{{Host: "proxy.example.com", UriParams: sip.HeaderParams{"lr": ""}}},
+		Destination:  "192.0.2.1:5060",
+		Transport:    "UDP",
+		CSeq:         5,
+		Encryption:   sdp.EncryptionRequire,
+		RoomName:     "room",
+		Identity:     "caller",
+	}
+	data, err := json.Marshal(snap)
+	require.NoError(t, err)
+	var s CallSnapshot
+	require.NoError(t, json.Unmarshal(data, &s))
+	require.Equal(t, *snap, s)
+
+	cc := cli.newOutbound(logger.GetLogger(), s.LocalTag, URI{User: s.LocalURI.User, Host: s.LocalURI.Host}, cli.ContactURI(""), nil)
+	cc.restoreDialog(&s)
+	require.True(t, cc.Established())
+	require.Equal(t, s.CallID, cc.CallID())
+	require.Equal(t, s.RemoteTag, cc.Tag())
+
+	// Requests sent in the dialog are the same as if this instance sent the INVITE.
+	req := NewReInviteRequest(cc.invite, cc.inviteOk, cc.contact, []byte("v=0"), nil)
+	cc.setCSeq(req)
+	require.Equal(t, s.RemoteTarget, req.Recipient)
+	require.Equal(t, "<sip:proxy.example.com;lr>", req.Route().Value())
+	require.Equal(t, "<sip:+15550100@sip.example.com>;tag=local-tag", req.From().Value())
+	require.Equal(t, "<sip:+15550200@carrier.example.com>;tag=remote-tag", req.To().Value())
+	require.Equal(t, s.CallID, req.CallID().Value())
+	require.Equal(t, s.CSeq+failoverCSeqGap, req.CSeq().SeqNo)
+	require.Equal(t, s.Destination, req.Destination())
+	require.NotNil(t, req.Via())
+	require.Equal(t, "sip:10.0.0.1:5060", req.Contact().Address.String())
+
+	// The snapshot of a restored dialog can be taken over again.
+	var again CallSnapshot
+	require.True(t, cc.dialogSnapshot(&again))
+	require.Equal(t, s.CallID, again.CallID)
+	require.Equal(t, s.LocalURI, again.LocalURI)
+	require.Equal(t, s.RemoteURI, again.RemoteURI)
+	require.Equal(t, s.RemoteTarget, again.RemoteTarget)
+	require.Equal(t, s.RouteSet, again.RouteSet)
+	require.Equal(t, s.Destination, again.Destination)
+	require.Equal(t, s.CSeq+failoverCSeqGap+1, again.CSeq)
+}
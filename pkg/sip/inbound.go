@@ -20,9 +20,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log/slog"
-	"math"
+	"maps"
 	"net/netip"
-	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -35,9 +34,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/livekit/media-sdk/dtmf"
-	"github.com/livekit/media-sdk/rtp"
 	"github.com/livekit/media-sdk/sdp"
-	"github.com/livekit/media-sdk/tones"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	lksip "github.com/livekit/protocol/sip"
@@ -47,8 +44,11 @@ import (
 	"github.com/livekit/sipgo/sip"
 
 	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/media/tonegen"
+	"github.com/livekit/sip/pkg/media/vad"
 	"github.com/livekit/sip/pkg/stats"
-	"github.com/livekit/sip/res"
+	"github.com/livekit/sip/pkg/stt"
 )
 
 const (
@@ -66,18 +66,20 @@ func hashPassword(password string) string {
 	return hex.EncodeToString(hash[:8]) // Use first 8 bytes for shorter hash
 }
 
-func (s *Server) getInvite(from string) *inProgressInvite {
+// getInvite returns digest challenge state for a given request method and caller.
+// Methods are kept apart, so an OPTIONS probe never replaces a nonce of a pending INVITE.
+func (s *Server) getInvite(method sip.RequestMethod, from string) *inProgressInvite {
 	s.imu.Lock()
 	defer s.imu.Unlock()
 	for i := range s.inProgressInvites {
-		if s.inProgressInvites[i].from == from {
+		if is := s.inProgressInvites[i]; is.method == method && is.from == from {
 			return s.inProgressInvites[i]
 		}
 	}
 	if len(s.inProgressInvites) >= digestLimit {
 		s.inProgressInvites = s.inProgressInvites[1:]
 	}
-	is := &inProgressInvite{from: from}
+	is := &inProgressInvite{method: method, from: from}
 	s.inProgressInvites = append(s.inProgressInvites, is)
 	return is
 }
@@ -103,7 +105,7 @@ func (s *Server) handleInviteAuth(log logger.Logger, req *sip.Request, tx sip.Se
 		_ = tx.Respond(sip.NewResponseFromRequest(req, 100, "Processing", nil))
 	}
 
-	inviteState := s.getInvite(from)
+	inviteState := s.getInvite(req.Method, from)
 	log = log.WithValues("inviteStateFrom", from)
 
 	h := req.GetHeader("Proxy-Authorization")
@@ -191,8 +193,19 @@ func (s *Server) handleInviteAuth(log logger.Logger, req *sip.Request, tx sip.Se
 }
 
 func (s *Server) onInvite(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
+	if isReInvite(req) && s.onReInvite(log, req, tx) {
+		return
+	}
+	inv, dup, final := s.invites.Begin(req, time.Now())
+	if dup != "" {
+		s.onDuplicateInvite(req, tx, dup, final)
+		return
+	}
+	defer func() {
+		s.invites.Finish(inv, time.Now())
+	}()
 	// Error processed in defer
-	_ = s.processInvite(req, tx)
+	_ = s.processInvite(req, s.invites.ServerTx(inv, tx))
 }
 
 func (s *Server) processInvite(req *sip.Request, tx sip.ServerTransaction) (retErr error) {
@@ -213,6 +226,9 @@ func (s *Server) processInvite(req *sip.Request, tx sip.ServerTransaction) (retE
 		})
 	}()
 	s.mon.InviteReqRaw(stats.Inbound)
+	if err := s.checkInviteLoop(req, tx); err != nil {
+		return err
+	}
 	src, err := netip.ParseAddrPort(req.Source())
 	if err != nil {
 		tx.Terminate()
@@ -220,8 +236,8 @@ func (s *Server) processInvite(req *sip.Request, tx sip.ServerTransaction) (retE
 		return psrpc.NewError(psrpc.MalformedRequest, errors.Wrap(err, "cannot parse source IP"))
 	}
 	callID := lksip.NewCallID()
-	log := s.log.WithValues(
-		"callID", callID,
+	log, logFields := newCallLogger(s.log, callID, src)
+	log = log.WithValues(
 		"fromIP", src.Addr(),
 		"toIP", req.Destination(),
 	)
@@ -229,7 +245,7 @@ func (s *Server) processInvite(req *sip.Request, tx sip.ServerTransaction) (retE
 	var call *inboundCall
 
 	tr := transportFromReq(req)
-	cc := s.newInbound(LocalTag(callID), s.ContactURI(tr), req, tx, func(headers map[string]string) map[string]string {
+	cc := s.newInbound(LocalTag(callID), s.contactFor(req, tr), req, tx, func(headers map[string]string) map[string]string {
 		c := call
 		if c == nil || len(c.attrsToHdr) == 0 {
 			return headers
@@ -259,6 +275,13 @@ func (s *Server) processInvite(req *sip.Request, tx sip.ServerTransaction) (retE
 
 	cmon := s.mon.NewCall(stats.Inbound, from.Host, to.Host)
 	cmon.InviteReq()
+	cc.fsm.SetMonitor(cmon)
+	defer cc.fsm.Transition(DialogDone)
+	defer func() {
+		if code := cc.FinalStatus(); code >= 300 {
+			cmon.CallFailed(int(code))
+		}
+	}()
 	defer cmon.SessionDur()()
 	joinDur := cmon.JoinDur()
 
@@ -284,19 +307,20 @@ func (s *Server) processInvite(req *sip.Request, tx sip.ServerTransaction) (retE
 		}
 	}
 
+	authDur := cmon.SetupPhaseDur(stats.SetupAuth)
 	r, err := s.handler.GetAuthCredentials(ctx, callInfo)
+	authDur()
 	if err != nil {
 		cmon.InviteErrorShort("auth-error")
 		log.Warnw("Rejecting inbound, auth check failed", err)
 		cc.RespondAndDrop(sip.StatusServiceUnavailable, "Try again later")
 		return psrpc.NewError(psrpc.PermissionDenied, errors.Wrap(err, "rejecting inbound, auth check failed"))
 	}
-	if r.ProjectID != "" {
-		log = log.WithValues("projectID", r.ProjectID)
-	}
-	if r.TrunkID != "" {
-		log = log.WithValues("sipTrunk", r.TrunkID)
-	}
+	logFields.SetProject(r.ProjectID)
+	logFields.SetTrunk(r.TrunkID)
+	cmon.SetTrunk(r.TrunkID)
+	cc.SetCapabilities(s.capabilities(r.TrunkID))
+	cc.SetTimers(s.timers(r.TrunkID))
 
 	state = NewCallState(s.getIOClient(r.ProjectID), &livekit.SIPCallInfo{
 		CallId:        string(cc.ID()),
@@ -336,15 +360,38 @@ func (s *Server) processInvite(req *sip.Request, tx sip.ServerTransaction) (retE
 		// ok
 	}
 
+	if err := s.translateInbound(log, r.TrunkID, callInfo); err != nil {
+		cmon.InviteErrorShort("blocked")
+		if s.conf.HideInboundPort {
+			cc.Drop()
+		} else {
+			cc.RespondAndDrop(sip.StatusForbidden, "Number is blocked")
+		}
+		return err
+	}
+
+	if !s.ports.Unallocated(r.TrunkID) {
+		cmon.InviteErrorShort("no-ports")
+		log.Warnw("Rejecting inbound, media ports exhausted", nil)
+		cc.RespondAndDrop(sip.StatusServiceUnavailable, "Media ports exhausted")
+		return siperrors.ErrMediaPortsExhausted
+	}
+
+	pickup, code, err := s.findPickup(req, r)
+	if err != nil {
+		cmon.InviteErrorShort("pickup")
+		log.Infow("Rejecting call pickup", "error", err, "status", code)
+		cc.RespondAndDrop(code, "")
+		return psrpc.NewError(psrpc.FailedPrecondition, errors.Wrap(err, "call pickup failed"))
+	}
+
 	call = s.newInboundCall(log, cmon, cc, callInfo, state, nil)
+	call.pickup = pickup
+	call.logFields = logFields
 	call.joinDur = joinDur
 	return call.handleInvite(call.ctx, req, r.TrunkID, s.conf)
 }
 
-func (s *Server) onOptions(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
-	_ = tx.Respond(sip.NewResponseFromRequest(req, 200, "OK", nil))
-}
-
 func (s *Server) onBye(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
 	tag, err := getFromTag(req)
 	if err != nil {
@@ -357,6 +404,7 @@ func (s *Server) onBye(log *slog.Logger, req *sip.Request, tx sip.ServerTransact
 	s.cmu.RUnlock()
 	if c != nil {
 		c.log.Infow("BYE")
+		recordISUPCause(c.log, c.state, req)
 		c.cc.AcceptBye(req, tx)
 		_ = c.Close()
 		return
@@ -378,18 +426,20 @@ func (s *Server) OnNoRoute(log *slog.Logger, req *sip.Request, tx sip.ServerTran
 	}
 	from := ""
 	if h := req.From(); h != nil {
-		from = h.Address.String()
+		from = sanitizeValue(h.Address.String())
 	}
 	to := ""
 	if h := req.To(); h != nil {
-		to = h.Address.String()
+		to = sanitizeValue(h.Address.String())
 	}
 	s.log.Infow("Inbound SIP request not handled",
 		"method", req.Method.String(),
 		"callID", callID,
 		"from", from,
 		"to", to)
-	tx.Respond(sip.NewResponseFromRequest(req, 405, "Method Not Allowed", nil))
+	r := sip.NewResponseFromRequest(req, 405, "Method Not Allowed", nil)
+	s.capabilities("").SetHeaders(r, false)
+	tx.Respond(r)
 }
 
 func (s *Server) onNotify(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
@@ -404,6 +454,7 @@ func (s *Server) onNotify(log *slog.Logger, req *sip.Request, tx sip.ServerTrans
 	s.cmu.RUnlock()
 	if c != nil {
 		c.log.Infow("NOTIFY")
+		tx = c.cc.history.ServerTx(req, tx)
 		err := c.cc.handleNotify(req, tx)
 
 		code, msg := sipCodeAndMessageFromError(err)
@@ -425,6 +476,7 @@ func (s *Server) onNotify(log *slog.Logger, req *sip.Request, tx sip.ServerTrans
 type inboundCall struct {
 	s           *Server
 	log         logger.Logger
+	logFields   callLogFields
 	cc          *sipInbound
 	mon         *stats.CallMonitor
 	state       *CallState
@@ -439,11 +491,35 @@ type inboundCall struct {
 	callDur     func() time.Duration
 	joinDur     func() time.Duration
 	forwardDTMF atomic.Bool
+	menu        *dtmfMenu
 	done        atomic.Bool
+	reinvite    atomic.Bool
+	recovery    atomic.Bool // set once a re-INVITE was sent to recover unreachable media
 	started     core.Fuse
 	stats       Stats
 	jitterBuf   bool
 	projectID   string
+	trunkID     string
+	quota       *ProjectLease
+	capacity    *TrunkLease
+	res         *callResources     // nil if leak watchdog is disabled
+	mem         *callMemory        // nil if memory budget is disabled
+	delayed     *delayedOffer      // set if INVITE had no SDP
+	callerName  string             // resolved with CNAM lookup
+	rp          []ResourcePriority // parsed from rpHeaders
+	sdpOffer    []byte             // SDP part of the INVITE body
+	isup        *ISUPMessage       // set if SIP-I INVITE has ISUP IAM
+	bodyParts   []BodyPart         // other parts of a multipart INVITE body
+	pickup      *callPickup        // set if INVITE joins or replaces another call
+	rpHeaders   []string           // raw Resource-Priority header values
+	vad         *vad.Detector      // set if no speech detection is enabled
+	msrp        *msrpSession       // set if MSRP chat was negotiated
+	rtt         *rttSession        // set if real-time text was negotiated
+	speaker     *callSpeaker
+	attrUpdates *attrUpdater   // set if attribute changes are sent to the caller
+	wsUrl       string         // LiveKit URL from the dispatch
+	migrated    atomic.Bool    // set while the call is handed off to another instance
+	recSync     *recordingSync // set if call legs are published in room metadata
 }
 
 func (s *Server) newInboundCall(
@@ -465,13 +541,20 @@ func (s *Server) newInboundCall(
 		state:      state,
 		extraAttrs: extra,
 		dtmf:       make(chan dtmf.Event, 10),
+		menu:       newDTMFMenu(s.conf.DTMF),
 		jitterBuf:  SelectValueBool(s.conf.EnableJitterBuffer, s.conf.EnableJitterBufferProb),
 		projectID:  "", // Will be set in handleInvite when available
+		res:        s.leaks.NewCall(call.LkCallId),
+		mem:        s.mem.NewCall(),
 	}
 	// we need it created earlier so that the audio mixer is available for pin prompts
 	c.lkRoom = NewRoom(log, &c.stats.Room)
+	c.speaker = newCallSpeaker(log, s.tts, c.playAudio, c.detectSpeech)
+	c.recSync = newRecordingSync(log, s.conf, recordingLeg{CallID: call.LkCallId, SIPCallID: call.SipCallId, Direction: "inbound"})
+	cc.fsm.OnChange(c.onDialogState)
 	c.log = c.log.WithValues("jitterBuf", c.jitterBuf)
 	c.ctx, c.cancel = context.WithCancel(context.Background())
+	s.owners.Add(cc.CallID())
 	s.cmu.Lock()
 	s.activeCalls[cc.Tag()] = c
 	s.byLocal[cc.ID()] = c
@@ -484,6 +567,8 @@ func (c *inboundCall) handleInvite(ctx context.Context, req *sip.Request, trunkI
 	c.mon.CallStart()
 	defer c.mon.CallEnd()
 	defer c.close(true, callDropped, "other")
+	c.trunkID = trunkID
+	c.recSync.SetTrunk(trunkID)
 
 	// Extract and store the SIP call ID from the request
 	if h := req.CallID(); h != nil {
@@ -491,24 +576,86 @@ func (c *inboundCall) handleInvite(ctx context.Context, req *sip.Request, trunkI
 	}
 
 	c.cc.StartRinging()
-	// Send initial request. In the best case scenario, we will immediately get a room name to join.
-	// Otherwise, we could even learn that this number is not allowed and reject the call, or ask for pin if required.
-	disp := c.s.handler.DispatchCall(ctx, &CallInfo{
-		TrunkID: trunkID,
-		Call:    c.call,
-		Pin:     "",
-		NoPin:   false,
-	})
+	// Abort dispatch, media setup and room join as soon as the caller cancels.
+	// The call is closed on this goroutine, once it notices the cancellation.
+	done := ctx.Done()
+	go func() {
+		select {
+		case <-c.cc.Cancelled():
+			c.cancel()
+		case <-done:
+		}
+	}()
+	sdpOffer, parts, err := requestSDP(req)
+	if err != nil {
+		c.log.Infow("Rejecting inbound call with invalid body", "error", err)
+		c.cc.RespondAndDrop(sip.StatusBadRequest, "Invalid message body")
+		c.close(false, callDropped, "invalid-body")
+		return psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	c.sdpOffer, c.bodyParts = sdpOffer, parts
+	if len(parts) != 0 {
+		types := make([]string, 0, len(parts))
+		for _, p := range parts {
+			types = append(types, p.ContentType)
+		}
+		c.log.Infow("INVITE has multipart body", "parts", types)
+	}
+	if m := isupFromParts(c.log, parts); m != nil && m.Type == ISUPInitialAddress {
+		c.isup = m
+		c.log.Infow("INVITE has isup IAM", "category", m.CallingPartyCategory, "originalCalled", m.OriginalCalledNumber)
+	}
+	// Resolve caller name first, so that both the dispatch and the participant can use it.
+	c.callerName = c.s.cnam.Lookup(ctx, c.call.From.GetUser())
+	for _, h := range req.GetHeaders(headerResourcePriority) {
+		c.rpHeaders = append(c.rpHeaders, h.Value())
+	}
+	c.rp = ParseResourcePriority(c.rpHeaders...)
+	if len(c.rp) != 0 {
+		c.log = c.log.WithValues("resourcePriority", FormatResourcePriority(c.rp))
+	}
+	var disp CallDispatch
+	if p := c.pickup; p != nil {
+		// The call goes to the room of the picked up call, dispatch rules do not apply.
+		c.log.Infow("Picking up an active call", "mode", p.mode, "pickupCallID", p.info.CallID, "room", p.info.RoomName)
+		disp = p.dispatch(c.cc, trunkID)
+	} else {
+		// Send initial request. In the best case scenario, we will immediately get a room name to join.
+		// Otherwise, we could even learn that this number is not allowed and reject the call, or ask for pin if required.
+		dispatchDur := c.mon.SetupPhaseDur(stats.SetupDispatch)
+		disp = c.s.handler.DispatchCall(ctx, &CallInfo{
+			TrunkID:          trunkID,
+			Call:             c.call,
+			Pin:              "",
+			NoPin:            false,
+			CallerName:       c.callerName,
+			ResourcePriority: c.rp,
+			BodyParts:        c.bodyParts,
+		})
+		dispatchDur()
+		if !disp.checkResourcePriority(c.s.conf, c.rp) {
+			c.log.Infow("Dispatch rule requires resource priority", "sipRule", disp.DispatchRuleID)
+		}
+	}
+	if c.checkCancelled() {
+		return nil
+	}
 	if disp.ProjectID != "" {
-		c.log = c.log.WithValues("projectID", disp.ProjectID)
+		c.logFields.SetProject(disp.ProjectID)
 		c.projectID = disp.ProjectID
 	}
 	if disp.TrunkID != "" {
-		c.log = c.log.WithValues("sipTrunk", disp.TrunkID)
+		c.logFields.SetTrunk(disp.TrunkID)
+		c.trunkID = disp.TrunkID
 	}
 	if disp.DispatchRuleID != "" {
 		c.log = c.log.WithValues("sipRule", disp.DispatchRuleID)
 	}
+	// Header mappings are applied to participant attributes later, but tags must be known before media starts.
+	if tags := CallTags(HeadersToAttrs(maps.Clone(disp.Room.Participant.Attributes), disp.HeadersToAttributes, livekit.SIPHeaderOptions_SIP_NO_HEADERS, c.cc, nil)); len(tags) != 0 {
+		c.log = c.log.WithValues("tags", tags)
+		c.mon.SetTags(tags)
+	}
 
 	c.state.Update(ctx, func(info *livekit.SIPCallInfo) {
 		info.TrunkId = disp.TrunkID
@@ -532,26 +679,44 @@ func (c *inboundCall) handleInvite(ctx context.Context, req *sip.Request, trunkI
 		c.close(false, callFlood, "flood")
 		return psrpc.NewErrorf(psrpc.PermissionDenied, "call was not authorized by trunk configuration")
 	case DispatchNoRuleReject:
-		c.log.Infow("Rejecting inbound call, doesn't match any Dispatch Rules")
-		c.cc.RespondAndDrop(sip.StatusNotFound, "Does not match Trunks or Dispatch Rules")
-		c.close(false, callDropped, "no-dispatch")
+		c.rejectUnmatched(ctx, req, conf)
 		return psrpc.NewErrorf(psrpc.NotFound, "no trunk configuration for call")
-	case DispatchAccept:
+	case DispatchAccept, DispatchEcho:
 		pinPrompt = false
 	case DispatchRequestPin:
 		pinPrompt = true
 	}
-
+	quota, err := c.s.quotas.Acquire(c.projectID, stats.Inbound)
+	if err != nil {
+		c.log.Infow("Rejecting inbound call, project quota exceeded", "error", err)
+		c.cc.RespondAndDrop(sip.StatusServiceUnavailable, "Project quota exceeded")
+		c.close(false, callDropped, "project-quota")
+		return err
+	}
+	c.quota = quota
 	runMedia := func(enc livekit.SIPMediaEncryption) ([]byte, error) {
-		answerData, err := c.runMediaConn(req.Body(), enc, conf, disp.EnabledFeatures)
+		answerData, err := c.runMediaConn(c.sdpOffer, enc, conf, disp.EnabledFeatures)
 		if err != nil {
 			isError := true
 			status, reason := callDropped, "media-failed"
-			if errors.Is(err, sdp.ErrNoCommonMedia) {
+			code, msg := sip.StatusInternalServerError, ""
+			if errors.Is(err, siperrors.ErrMediaPortsExhausted) {
+				reason = "no-ports"
+				code, msg = sip.StatusServiceUnavailable, "Media ports exhausted"
+			} else if errors.Is(err, sdp.ErrNoCommonMedia) {
 				status, reason = callMediaFailed, "no-common-codec"
 				isError = false
 			} else if errors.Is(err, sdp.ErrNoCommonCrypto) {
 				status, reason = callMediaFailed, "no-common-crypto"
+				code, msg = sip.StatusNotAcceptableHere, "No acceptable SRTP crypto"
+				isError = false
+			} else if errors.Is(err, siperrors.ErrMediaEncryptionForbidden) {
+				status, reason = callMediaFailed, "encryption-forbidden"
+				code, msg = sip.StatusNotAcceptableHere, "Encrypted media not allowed"
+				isError = false
+			} else if errors.Is(err, siperrors.ErrMediaTransportForbidden) {
+				status, reason = callMediaFailed, "transport-forbidden"
+				code, msg = sip.StatusNotAcceptableHere, "Media transport not allowed"
 				isError = false
 			}
 			if isError {
@@ -559,15 +724,19 @@ func (c *inboundCall) handleInvite(ctx context.Context, req *sip.Request, trunkI
 			} else {
 				c.log.Warnw("Cannot start media", err)
 			}
-			c.cc.RespondAndDrop(sip.StatusInternalServerError, "")
+			c.cc.RespondAndDrop(code, msg)
 			c.close(true, status, reason)
 			return nil, err
 		}
 		return answerData, nil
 	}
 
+	var stopRingback context.CancelFunc
 	// We need to start media first, otherwise we won't be able to send audio prompts to the caller, or receive DTMF.
 	acceptCall := func(answerData []byte) (bool, error) {
+		if stopRingback != nil {
+			stopRingback()
+		}
 		headers := disp.Headers
 		c.attrsToHdr = disp.AttributesToHeaders
 		if r := c.lkRoom.Room(); r != nil {
@@ -575,10 +744,21 @@ func (c *inboundCall) handleInvite(ctx context.Context, req *sip.Request, trunkI
 		}
 		c.log.Infow("Accepting the call", "headers", headers)
 		if err := c.cc.Accept(ctx, answerData, headers); err != nil {
+			if errors.Is(err, errInviteCancelled) {
+				c.closeWithCancelled()
+				return false, nil
+			}
 			c.log.Errorw("Cannot respond to INVITE", err)
 			return false, err
 		}
-		c.media.EnableTimeout(true)
+		if d := c.delayed; d != nil {
+			if ok, err := c.waitDelayedAnswer(ctx, d); !ok {
+				return false, err
+			}
+		}
+		c.mon.CallAnswered()
+		c.recSync.Mark(recordingAnswered)
+		c.media.ResetTalkStats() // ignore ringback
 		c.media.EnableOut()
 		if ok, err := c.waitMedia(ctx); !ok {
 			return false, err
@@ -587,58 +767,170 @@ func (c *inboundCall) handleInvite(ctx context.Context, req *sip.Request, trunkI
 		return true, nil
 	}
 
-	ok := false
-	var answerData []byte
-	if pinPrompt {
-		var err error
-		// Accept the call first on the SIP side, so that we can send audio prompts.
-		// This also means we have to pick encryption setting early, before room is selected.
-		// Backend must explicitly enable encryption for pin prompts.
-		answerData, err = runMedia(disp.MediaEncryption)
+	priority := c.s.conf.DispatchPriority(disp.DispatchRuleID) || c.s.capacity.IsPriority(c.trunkID, c.rpHeaders)
+	capacity, err := c.s.capacity.Acquire(c.trunkID, priority, stats.Inbound)
+	// Set if the call was answered while waiting in the busy queue.
+	var queuedAnswer []byte
+	if err != nil {
+		var ok bool
+		capacity, queuedAnswer, ok, err = c.handleBusy(ctx, disp, priority, runMedia, acceptCall)
+		if !ok {
+			return err // already sent a response. Could be success if the caller hung up
+		}
+	}
+	c.capacity = capacity
+
+	if aconf := disp.Announcement(conf); aconf != nil {
+		frames, err := c.announcementFrames(ctx, aconf)
+		if err != nil {
+			c.log.Warnw("Cannot load announcement", err)
+			c.cc.RespondAndDrop(sip.StatusServiceUnavailable, "Announcement unavailable")
+			c.close(true, callDropped, "announce-failed")
+			return err
+		}
+		answerData, err := runMedia(disp.MediaEncryption)
+		if err != nil {
+			return err // already sent a response
+		}
+		if c.checkCancelled() {
+			return nil
+		}
+		return c.runAnnouncement(ctx, answerData, frames, *aconf)
+	}
+	if econf, ok := disp.EchoTest(conf); ok {
+		answerData, err := runMedia(disp.MediaEncryption)
 		if err != nil {
 			return err // already sent a response
 		}
-		if ok, err = acceptCall(answerData); !ok {
-			return err // could be success if the caller hung up
+		if c.checkCancelled() {
+			return nil
+		}
+		return c.runEcho(ctx, answerData, econf)
+	}
+
+	ok := false
+	// Set if the call was answered before joining the room.
+	answered := pinPrompt || queuedAnswer != nil
+	answerData := queuedAnswer
+	if pinPrompt {
+		var err error
+		if queuedAnswer == nil {
+			// Accept the call first on the SIP side, so that we can send audio prompts.
+			// This also means we have to pick encryption setting early, before room is selected.
+			// Backend must explicitly enable encryption for pin prompts.
+			answerData, err = runMedia(disp.MediaEncryption)
+			if err != nil {
+				return err // already sent a response
+			}
+			if ok, err = acceptCall(answerData); !ok {
+				return err // could be success if the caller hung up
+			}
 		}
 		disp, ok, err = c.pinPrompt(ctx, trunkID)
 		if !ok {
 			return err // already sent a response. Could be success if user hung up
 		}
-	} else {
+	} else if queuedAnswer == nil {
 		// Start media with given encryption settings.
 		var err error
 		answerData, err = runMedia(disp.MediaEncryption)
 		if err != nil {
 			return err // already sent a response
 		}
+		if c.checkCancelled() {
+			return nil
+		}
+		mode := disp.ProvisionalMode(conf)
+		if mode == config.ProvisionalEarlyMedia && c.delayed != nil {
+			// Early media requires an SDP answer, but the remote didn't send an offer.
+			mode = config.ProvisionalRinging
+		}
+		switch mode {
+		case config.ProvisionalEarlyMedia:
+			stopRingback = c.startEarlyMedia(ctx, answerData)
+			defer stopRingback()
+		case config.ProvisionalAnswer:
+			if ok, err = acceptCall(answerData); !ok {
+				return err // could be success if the caller hung up
+			}
+			answered = true
+		}
+	}
+	if qconf := c.s.conf.DispatchQueue(disp.DispatchRuleID); qconf != nil {
+		ok, err := c.waitInCallQueue(ctx, disp, *qconf, answered, func() (bool, error) {
+			return acceptCall(answerData)
+		})
+		if !ok {
+			return err // already sent a response. Could be success if the caller hung up
+		}
+		answered = true
 	}
 	p := &disp.Room.Participant
 	p.Attributes = HeadersToAttrs(p.Attributes, disp.HeadersToAttributes, disp.IncludeHeaders, c.cc, nil)
+	if c.callerName != "" {
+		p.Attributes[AttrSIPCallerName] = c.callerName
+		p.Name = c.callerName
+	}
+	if len(c.rp) != 0 {
+		p.Attributes[AttrSIPResourcePriority] = FormatResourcePriority(c.rp)
+	}
+	if c.isup != nil {
+		maps.Copy(p.Attributes, c.isup.Attributes())
+	}
 	if disp.MaxCallDuration <= 0 || disp.MaxCallDuration > maxCallDuration {
 		disp.MaxCallDuration = maxCallDuration
 	}
+	if d := c.quota.MaxDuration(); d > 0 && d < disp.MaxCallDuration {
+		disp.MaxCallDuration = d
+	}
 	if disp.RingingTimeout <= 0 {
 		disp.RingingTimeout = defaultRingingTimeout
 	}
 	disp.Room.JitterBuf = c.jitterBuf
 	ctx, cancel := context.WithTimeout(ctx, disp.MaxCallDuration)
 	defer cancel()
+	c.cc.history.SetTimer(timerMaxCallDuration, time.Now().Add(disp.MaxCallDuration))
 	status := CallRinging
-	if pinPrompt {
+	if answered {
 		status = CallActive
 	}
+	c.attrUpdates = newAttrUpdater(c.log, c.s.conf.DispatchAttributeUpdates(disp.DispatchRuleID), disp.AttributesToHeaders, c.lkRoom.LocalAttributes, c.cc.SendHeaders)
+	c.wsUrl = disp.Room.WsUrl
 	if err := c.joinRoom(ctx, disp.Room, status); err != nil {
+		if c.checkCancelled() {
+			return nil
+		}
+		if answered {
+			c.playFailTone(ctx, tonegen.Reorder)
+		}
 		return errors.Wrap(err, "failed joining room")
 	}
+	if c.callerName != "" && disp.Room.Token != "" {
+		// Participant name is set in the token, update it after joining.
+		c.lkRoom.SetName(c.callerName)
+	}
+	noSpeech := c.s.conf.DispatchNoSpeech(disp.DispatchRuleID)
+	if noSpeech != nil {
+		c.vad = vad.NewDetector(vad.Config{Threshold: noSpeech.Threshold, MinSpeech: noSpeech.MinSpeech})
+	}
+	if confs := c.s.conf.DispatchMediaStages(disp.DispatchRuleID); len(confs) != 0 {
+		if stages, err := newMediaStages(c.mediaStageEnv(), confs); err != nil {
+			c.log.Warnw("cannot create media stages", err)
+		} else {
+			c.media.replaceStages(stages)
+		}
+	}
 	// Publish our own track.
 	if err := c.publishTrack(); err != nil {
 		c.log.Errorw("Cannot publish track", err)
+		if answered {
+			c.playFailTone(ctx, tonegen.Reorder)
+		}
 		c.close(true, callDropped, "publish-failed")
 		return errors.Wrap(err, "publishing track to room failed")
 	}
 	c.lkRoom.Subscribe()
-	if !pinPrompt {
+	if !answered {
 		c.log.Infow("Waiting for track subscription(s)")
 		// For dispatches without pin, we first wait for LK participant to become available,
 		// and also for at least one track subscription. In the meantime we keep ringing.
@@ -660,7 +952,18 @@ func (c *inboundCall) handleInvite(ctx context.Context, req *sip.Request, trunkI
 		}
 	})
 
+	if p := c.pickup; p != nil && p.mode == pickupReplaces {
+		c.log.Infow("Closing replaced call", "pickupCallID", p.info.CallID)
+		p.target.closeReplaced()
+	}
+
 	c.started.Break()
+	c.s.failover.Save(c.snapshot())
+	go syncQualityAttr(c.ctx.Done(), c.media, c.lkRoom)
+	go c.cc.keepAlive(c.ctx.Done(), c.log)
+	if noSpeech != nil {
+		go c.watchNoSpeech(noSpeech)
+	}
 
 	ticker := time.NewTicker(10 * time.Minute)
 	defer ticker.Stop()
@@ -679,6 +982,9 @@ func (c *inboundCall) handleInvite(ctx context.Context, req *sip.Request, trunkI
 			})
 			c.close(false, callDropped, "removed")
 			return nil
+		case <-c.quota.Exhausted():
+			c.closeWithQuotaExhausted()
+			return nil
 		case <-c.media.Timeout():
 			c.closeWithTimeout()
 			return psrpc.NewErrorf(psrpc.DeadlineExceeded, "media timeout")
@@ -686,35 +992,249 @@ func (c *inboundCall) handleInvite(ctx context.Context, req *sip.Request, trunkI
 	}
 }
 
+// rejectUnmatched responds to a call that doesn't match any dispatch rule, as configured for the trunk.
+func (c *inboundCall) rejectUnmatched(ctx context.Context, req *sip.Request, conf *config.Config) {
+	uc := conf.TrunkUnmatchedCall(c.trunkID)
+	switch uc.Action {
+	case config.UnmatchedRedirect:
+		c.log.Infow("Redirecting inbound call, doesn't match any Dispatch Rules", "redirectTo", uc.RedirectTo)
+		c.cc.RedirectAndDrop(uc.RedirectTo)
+		c.close(false, callDropped, "no-dispatch-redirect")
+		return
+	case config.UnmatchedAnnounce:
+		if frames := c.s.res.announcements[uc.AnnouncementFile]; len(frames) != 0 {
+			c.log.Infow("Playing announcement for inbound call, doesn't match any Dispatch Rules")
+			c.announceAndHangup(ctx, req, conf, frames)
+			return
+		}
+		c.log.Warnw("Announcement is not loaded, rejecting the call", nil, "file", uc.AnnouncementFile)
+	}
+	code, reason := sip.StatusNotFound, "Does not match Trunks or Dispatch Rules"
+	if uc.StatusCode != 0 {
+		code, reason = sip.StatusCode(uc.StatusCode), uc.Reason
+		if reason == "" {
+			reason = sipStatus(code)
+		}
+	}
+	c.log.Infow("Rejecting inbound call, doesn't match any Dispatch Rules", "status", code)
+	c.cc.RespondAndDrop(code, reason)
+	c.close(false, callDropped, "no-dispatch")
+}
+
+// announceAndHangup answers the call, plays the announcement and sends BYE.
+func (c *inboundCall) announceAndHangup(ctx context.Context, req *sip.Request, conf *config.Config, frames []msdk.PCM16Sample) {
+	answerData, err := c.runMediaConn(c.sdpOffer, livekit.SIPMediaEncryption_SIP_MEDIA_ENCRYPT_ALLOW, conf, nil)
+	if err != nil {
+		c.log.Warnw("Cannot start media for announcement", err)
+		c.cc.RespondAndDrop(sip.StatusNotFound, "Does not match Trunks or Dispatch Rules")
+		c.close(false, callDropped, "no-dispatch")
+		return
+	}
+	if !c.acceptAnnouncement(ctx, answerData, "no-dispatch") {
+		return
+	}
+	c.playAudio(ctx, frames)
+	c.close(false, callDropped, "no-dispatch-announce")
+}
+
+// acceptAnnouncement answers the call for playing an announcement. It returns false if the call was closed.
+func (c *inboundCall) acceptAnnouncement(ctx context.Context, answerData []byte, reason string) bool {
+	if err := c.cc.Accept(ctx, answerData, nil); err != nil {
+		if errors.Is(err, errInviteCancelled) {
+			c.closeWithCancelled()
+			return false
+		}
+		c.log.Warnw("Cannot answer the call for announcement", err)
+		c.close(false, callDropped, reason)
+		return false
+	}
+	if d := c.delayed; d != nil {
+		if ok, _ := c.waitDelayedAnswer(ctx, d); !ok {
+			return false
+		}
+	}
+	c.media.EnableOut()
+	if ok, _ := c.waitMedia(ctx); !ok {
+		return false
+	}
+	return true
+}
+
+// announcementFrames returns audio of the announcement configured for the dispatch rule.
+func (c *inboundCall) announcementFrames(ctx context.Context, aconf *config.AnnounceConfig) ([]msdk.PCM16Sample, error) {
+	if aconf.File != "" {
+		frames := c.s.res.announcements[aconf.File]
+		if len(frames) == 0 {
+			return nil, fmt.Errorf("announcement %q is not loaded", aconf.File)
+		}
+		return frames, nil
+	}
+	if c.s.tts == nil {
+		return nil, fmt.Errorf("tts is not configured")
+	}
+	return c.s.tts.Synthesize(ctx, aconf.Text, VoiceOptions{})
+}
+
+// runAnnouncement answers the call, plays the announcement, optionally collects a digit and hangs up.
+func (c *inboundCall) runAnnouncement(ctx context.Context, answerData []byte, frames []msdk.PCM16Sample, aconf config.AnnounceConfig) error {
+	c.log.Infow("Accepting the call for announcement", "collectDigit", aconf.CollectDigit)
+	if !c.acceptAnnouncement(ctx, answerData, "announce-failed") {
+		return nil
+	}
+	c.mon.CallAnswered()
+	c.state.Update(ctx, func(info *livekit.SIPCallInfo) {
+		info.StartedAtNs = time.Now().UnixNano()
+		info.CallStatus = livekit.SIPCallStatus_SCS_ACTIVE
+	})
+	c.playAudio(ctx, frames)
+	if aconf.CollectDigit {
+		if aconf.DigitTimeout <= 0 {
+			aconf.DigitTimeout = config.DefaultAnnounceDigitTimeout
+		}
+		c.collectDigit(ctx, aconf.DigitTimeout)
+	}
+	c.close(false, callDropped, "announcement")
+	return nil
+}
+
+// collectDigit waits for a single DTMF digit and reports it in the call info.
+func (c *inboundCall) collectDigit(ctx context.Context, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+		c.log.Infow("No digit entered after announcement")
+	case ev := <-c.dtmf:
+		digit := string([]byte{ev.Digit})
+		c.log.Infow("Digit entered after announcement", "digit", digit)
+		c.state.DeferUpdate(func(info *livekit.SIPCallInfo) {
+			if info.ParticipantAttributes == nil {
+				info.ParticipantAttributes = make(map[string]string)
+			}
+			info.ParticipantAttributes[AttrSIPLastDTMF] = digit
+		})
+	}
+}
+
+// playFailTone plays a tone to the caller when the call fails after it was answered, instead of hanging up in silence.
+func (c *inboundCall) playFailTone(ctx context.Context, k tonegen.Kind) {
+	dur := c.s.conf.Tones.FailDuration
+	if dur == 0 {
+		dur = config.DefaultToneFailDuration
+	}
+	if dur < 0 {
+		return
+	}
+	c.log.Infow("playing fail tone", "tone", k, "duration", dur)
+	if err := c.s.tones.PlayFor(ctx, c.media.GetAudioWriter(), k, dur); err != nil && !errors.Is(err, context.Canceled) {
+		c.log.Infow("cannot play fail tone", "error", err)
+	}
+}
+
+// startEarlyMedia sends 183 Session Progress with SDP and plays ringback to the caller until the returned function is called.
+func (c *inboundCall) startEarlyMedia(ctx context.Context, answerData []byte) context.CancelFunc {
+	c.log.Infow("Sending early media")
+	c.cc.EarlyMedia(answerData)
+	c.media.EnableOut()
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		err := c.s.tones.Play(ctx, c.media.GetAudioWriter(), tonegen.Ringing)
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			c.log.Infow("cannot play ringback", "error", err)
+		}
+	}()
+	return cancel
+}
+
 func (c *inboundCall) runMediaConn(offerData []byte, enc livekit.SIPMediaEncryption, conf *config.Config, features []livekit.SIPFeature) (answerData []byte, _ error) {
-	c.mon.SDPSize(len(offerData), true)
-	c.log.Debugw("SDP offer", "sdp", string(offerData))
+	sdpDur := c.mon.SetupPhaseDur(stats.SetupSDP)
 	e, err := sdpEncryption(enc)
 	if err != nil {
 		c.log.Errorw("Cannot parse encryption", err)
 		return nil, err
 	}
 
-	mp, err := NewMediaPort(c.log, c.mon, &MediaOptions{
-		IP:                  c.s.sconf.MediaIP,
-		Ports:               conf.RTPPort,
-		MediaTimeoutInitial: c.s.conf.MediaTimeoutInitial,
-		MediaTimeout:        c.s.conf.MediaTimeout,
-		EnableJitterBuffer:  c.jitterBuf,
-		Stats:               &c.stats.Port,
-	}, RoomSampleRate)
+	srtpConf := c.s.conf.TrunkSRTP(c.trunkID)
+	signalingAddr, _ := netip.ParseAddr(c.call.SourceIp)
+	opts := &MediaOptions{
+		IP:                    c.s.sconf.mediaIP(),
+		Ports:                 conf.RTPPort,
+		MediaTimeoutInitial:   c.s.conf.MediaTimeoutInitial,
+		MediaTimeout:          c.s.conf.MediaTimeout,
+		MediaTimeoutWarnOnly:  c.s.conf.MediaTimeoutWarnOnly,
+		MediaTimeoutIgnoreCN:  c.s.conf.MediaTimeoutIgnoreCN,
+		MediaTimeoutRTCP:      c.s.conf.MediaTimeoutRTCP,
+		MediaTimeoutKeepAlive: c.s.conf.MediaTimeoutKeepAlive,
+		RTCPXRInterval:        c.s.conf.RTCPXRInterval,
+		OneWayTimeout:         c.s.conf.OneWayAudio.Timeout,
+		OneWayLatch:           c.s.conf.OneWayAudio.Latch,
+		StripZRTP:             c.s.conf.StripZRTP,
+		EnableDriftCorrection: c.s.conf.EnableDriftCorrection,
+		MaxInputStreams:       c.s.conf.MaxInputStreams,
+		StreamRate:            c.s.conf.MediaStreamRate,
+		Pacer:                 pacerConfig(c.s.conf.TrunkPacing(c.trunkID)),
+		Silence:               c.s.conf.TrunkSilence(c.trunkID),
+		RTPTransport:          c.s.conf.TrunkRTPTransport(c.trunkID),
+		SDP:                   c.s.conf.TrunkSDP(c.trunkID),
+		SignalingAddr:         signalingAddr,
+		EnableJitterBuffer:    c.jitterBuf,
+		Stats:                 &c.stats.Port,
+		Allocator:             c.s.ports,
+		TrunkID:               c.trunkID,
+		CallID:                c.call.LkCallId,
+		resources:             c.res,
+		memory:                c.mem,
+		SRTPSuites:            srtpConf.Suites,
+		SRTPRejectDisallowed:  srtpConf.RejectDisallowed,
+		SRTPMaxFailures:       srtpConf.MaxFailures,
+		ICMPMaxErrors:         c.s.conf.MediaUnreachable.MaxErrors,
+	}
+	e = applyEncryptionPolicy(c.s.conf.TrunkEncryption(c.trunkID), e, opts)
+	mp, err := NewMediaPort(c.log, c.mon, opts, RoomSampleRate)
 	if err != nil {
 		return nil, err
 	}
 	c.media = mp
-	c.media.EnableTimeout(false) // enabled once we accept the call
-	c.media.DisableOut()         // disabled until we send 200
+	c.media.OnEvent(c.onMediaEvent)
+	c.media.followDialog(c.cc.fsm) // timeout is enabled once we accept the call
+	c.media.DisableOut()           // disabled until we send 200
 	c.media.SetDTMFAudio(conf.AudioDTMF)
+	c.media.SetDTMFOptions(dtmfOptions(conf.DTMF))
+
+	if len(offerData) == 0 {
+		// Delayed offer: send our offer in 200 OK, media is configured once the answer arrives in ACK.
+		offer, err := mp.NewOffer(e)
+		if err != nil {
+			return nil, err
+		}
+		offerData, err = offer.SDP.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		c.delayed = &delayedOffer{offer: offer, enc: e, features: features}
+		c.mon.SDPSize(len(offerData), false)
+		c.log.Infow("INVITE without SDP, sending offer in 200 OK")
+		c.log.Debugw("SDP offer", "sdp", string(offerData))
+		sdpDur()
+		return offerData, nil
+	}
+	c.mon.SDPSize(len(offerData), true)
+	c.log.Debugw("SDP offer", "sdp", string(offerData))
 
 	answer, mconf, err := mp.SetOffer(offerData, e)
+	if res := mediaEncryptionResult(mconf, err); res != "" {
+		c.mon.MediaEncryption(res)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if conf.EnableMSRP {
+		c.setupMSRP(offerData, &answer.SDP, conf.RTPPort)
+	}
+	if conf.EnableRTT {
+		c.setupRTT(offerData, &answer.SDP, conf.RTPPort)
+	}
 	answerData, err = answer.SDP.Marshal()
 	if err != nil {
 		return nil, err
@@ -722,10 +1242,19 @@ func (c *inboundCall) runMediaConn(offerData []byte, enc livekit.SIPMediaEncrypt
 	c.mon.SDPSize(len(answerData), false)
 	c.log.Debugw("SDP answer", "sdp", string(answerData))
 
-	mconf.Processor = c.s.handler.GetMediaProcessor(features)
-	if err = c.media.SetConfig(mconf); err != nil {
+	if err = c.setMediaConf(mconf, features); err != nil {
 		return nil, err
 	}
+	sdpDur()
+	return answerData, nil
+}
+
+// setMediaConf applies negotiated media config and connects media to the room.
+func (c *inboundCall) setMediaConf(mconf *MediaConf, features []livekit.SIPFeature) error {
+	if err := c.media.SetConfig(mconf); err != nil {
+		return err
+	}
+	c.media.setHandlerStage(c.s.handler.GetMediaProcessor(features))
 	if mconf.Audio.DTMFType != 0 {
 		c.media.HandleDTMF(c.handleDTMF)
 	}
@@ -740,7 +1269,7 @@ func (c *inboundCall) runMediaConn(offerData []byte, enc livekit.SIPMediaEncrypt
 	c.state.DeferUpdate(func(info *livekit.SIPCallInfo) {
 		info.AudioCodec = mconf.Audio.Codec.Info().SDPName
 	})
-	return answerData, nil
+	return nil
 }
 
 func (c *inboundCall) waitMedia(ctx context.Context) (bool, error) {
@@ -760,7 +1289,7 @@ func (c *inboundCall) waitMedia(ctx context.Context) (bool, error) {
 		c.closeWithCancelled()
 		return false, nil // caller hung up
 	case <-ctx.Done():
-		c.closeWithHangup()
+		c.closeOnDone()
 		return false, nil // caller hung up
 	case <-c.lkRoom.Closed():
 		c.closeWithHangup()
@@ -777,14 +1306,14 @@ func (c *inboundCall) waitMedia(ctx context.Context) (bool, error) {
 func (c *inboundCall) waitSubscribe(ctx context.Context, timeout time.Duration) (bool, error) {
 	ctx, span := tracer.Start(ctx, "inboundCall.waitSubscribe")
 	defer span.End()
-	timer := time.NewTimer(timeout)
-	defer timer.Stop()
+	// Ringing timeout is owned by the setup states and stops once the call is answered or terminated.
+	ringing := c.cc.fsm.Timeout(timerRinging, timeout, DialogProceeding, DialogEarly)
 	select {
 	case <-c.cc.Cancelled():
 		c.closeWithCancelled()
 		return false, nil
 	case <-ctx.Done():
-		c.closeWithHangup()
+		c.closeOnDone()
 		return false, nil
 	case <-c.lkRoom.Closed():
 		c.closeWithHangup()
@@ -792,7 +1321,7 @@ func (c *inboundCall) waitSubscribe(ctx context.Context, timeout time.Duration)
 	case <-c.media.Timeout():
 		c.closeWithTimeout()
 		return false, psrpc.NewErrorf(psrpc.DeadlineExceeded, "media timed out")
-	case <-timer.C:
+	case <-ringing:
 		c.close(false, callDropped, "cannot-subscribe")
 		return false, psrpc.NewErrorf(psrpc.DeadlineExceeded, "room subscription timed out")
 	case <-c.lkRoom.Subscribed():
@@ -832,18 +1361,26 @@ func (c *inboundCall) pinPrompt(ctx context.Context, trunkID string) (disp CallD
 				noPin = pin == ""
 
 				c.log.Infow("Checking Pin for SIP call", "pin", pin, "noPin", noPin)
+				dispatchDur := c.mon.SetupPhaseDur(stats.SetupDispatch)
 				disp = c.s.handler.DispatchCall(ctx, &CallInfo{
-					TrunkID: trunkID,
-					Call:    c.call,
-					Pin:     pin,
-					NoPin:   noPin,
+					TrunkID:          trunkID,
+					Call:             c.call,
+					Pin:              pin,
+					NoPin:            noPin,
+					CallerName:       c.callerName,
+					ResourcePriority: c.rp,
+					BodyParts:        c.bodyParts,
 				})
+				dispatchDur()
+				if !disp.checkResourcePriority(c.s.conf, c.rp) {
+					c.log.Infow("Dispatch rule requires resource priority", "sipRule", disp.DispatchRuleID)
+				}
 				if disp.ProjectID != "" {
-					c.log = c.log.WithValues("projectID", disp.ProjectID)
+					c.logFields.SetProject(disp.ProjectID)
 					c.projectID = disp.ProjectID
 				}
 				if disp.TrunkID != "" {
-					c.log = c.log.WithValues("sipTrunk", disp.TrunkID)
+					c.logFields.SetTrunk(disp.TrunkID)
 				}
 				if disp.DispatchRuleID != "" {
 					c.log = c.log.WithValues("sipRule", disp.DispatchRuleID)
@@ -873,6 +1410,15 @@ func (c *inboundCall) close(error bool, status CallStatus, reason string) {
 	if !c.done.CompareAndSwap(false, true) {
 		return
 	}
+	migrated := c.migrated.Load()
+	if migrated {
+		// The other instance continues the dialog and the session.
+		status, reason = callMigrated, "migrated"
+		c.cc.Drop()
+		c.state.DeferUpdate(func(info *livekit.SIPCallInfo) {
+			info.DisconnectReason = livekit.DisconnectReason_MIGRATION
+		})
+	}
 	c.setStatus(status)
 	c.mon.CallTerminate(reason)
 	sipCode, sipStatus := status.SIPStatus()
@@ -889,20 +1435,31 @@ func (c *inboundCall) close(error bool, status CallStatus, reason string) {
 		defer log.Infow("Inbound call closed")
 	}
 
+	c.s.vq.Report(log, c.media, c.cc, stats.Inbound)
+	if c.media != nil {
+		talkAttrs := talkStatsAttrs(c.media.TalkStats())
+		c.state.DeferUpdate(func(info *livekit.SIPCallInfo) {
+			setTalkStats(info, talkAttrs)
+		})
+	}
+	c.recSync.Mark(recordingEnded)
 	c.closeMedia()
 	c.cc.CloseWithStatus(sipCode, sipStatus)
 	if c.callDur != nil {
 		c.callDur()
 	}
+	c.quota.Release()
+	c.capacity.Release()
 	c.s.cmu.Lock()
 	delete(c.s.activeCalls, c.cc.Tag())
 	delete(c.s.byLocal, c.cc.ID())
 	c.s.cmu.Unlock()
+	c.cc.fsm.Transition(DialogDone)
 
 	c.s.DeregisterTransferSIPParticipant(c.cc.ID())
 
 	// Call the handler asynchronously to avoid blocking
-	if c.s.handler != nil {
+	if c.s.handler != nil && !migrated {
 		go c.s.handler.OnSessionEnd(context.Background(), &CallIdentifier{
 			ProjectID: c.projectID,
 			CallID:    c.call.LkCallId,
@@ -911,19 +1468,53 @@ func (c *inboundCall) close(error bool, status CallStatus, reason string) {
 	}
 
 	c.cancel()
+	c.mem.Close()
+	c.s.owners.Remove(c.cc.CallID())
+	c.s.failover.Delete(c.cc.CallID())
+	c.s.leaks.CallEnded(c.res)
 }
 
 func (c *inboundCall) closeWithTimeout() {
 	c.close(true, callDropped, "media-timeout")
 }
 
+// closeWithCancelled closes the call after the caller sent CANCEL.
+// The reason is the same as for other cancellations, the call status code tells that it was the caller.
 func (c *inboundCall) closeWithCancelled() {
 	c.state.DeferUpdate(func(info *livekit.SIPCallInfo) {
 		info.DisconnectReason = livekit.DisconnectReason_CLIENT_INITIATED
+		info.CallStatusCode = &livekit.SIPStatus{
+			Code:   livekit.SIPStatusCode_SIP_STATUS_REQUEST_TERMINATED,
+			Status: callerCancelled,
+		}
 	})
 	c.close(false, CallHangup, "cancelled")
 }
 
+// checkCancelled closes the call if the caller sent CANCEL. It returns true if the call was cancelled.
+func (c *inboundCall) checkCancelled() bool {
+	select {
+	case <-c.cc.Cancelled():
+		c.closeWithCancelled()
+		return true
+	default:
+		return false
+	}
+}
+
+// closeOnDone closes the call after the call context is done, either due to CANCEL or a hangup.
+func (c *inboundCall) closeOnDone() {
+	if !c.checkCancelled() {
+		c.closeWithHangup()
+	}
+}
+
+// closeWithQuotaExhausted ends the call after the project used up its call minutes.
+func (c *inboundCall) closeWithQuotaExhausted() {
+	c.log.Infow("project call minutes exhausted")
+	c.close(false, CallHangup, "quota-exhausted")
+}
+
 func (c *inboundCall) closeWithHangup() {
 	c.state.DeferUpdate(func(info *livekit.SIPCallInfo) {
 		info.DisconnectReason = livekit.DisconnectReason_CLIENT_INITIATED
@@ -931,18 +1522,93 @@ func (c *inboundCall) closeWithHangup() {
 	c.close(false, CallHangup, "hangup")
 }
 
+// closeWithRequestedHangup closes the call after hangup was requested via participant attributes.
+func (c *inboundCall) closeWithRequestedHangup() {
+	c.log.Infow("hangup requested by participant attributes")
+	c.close(false, CallHangup, "requested-hangup")
+}
+
+func (c *inboundCall) setMuted(muted bool) {
+	if c.media == nil {
+		return
+	}
+	c.log.Infow("mute requested by participant attributes", "muted", muted)
+	c.media.SetMuted(muted)
+}
+
 func (c *inboundCall) Close() error {
 	c.cancel()
 	return nil
 }
 
 func (c *inboundCall) closeMedia() {
+	c.speaker.Close()
+	c.attrUpdates.Close()
+	c.msrp.Close()
+	c.rtt.Close()
 	c.lkRoom.Close()
 	if c.media != nil {
 		c.media.Close()
 	}
 }
 
+func (c *inboundCall) onMediaEvent(ev MediaEvent) {
+	if attrs := mediaStateAttrs(ev); attrs != nil {
+		c.lkRoom.SetAttributes(attrs)
+	}
+	switch ev.Type {
+	case MediaEventReceived:
+		c.recSync.Mark(recordingMedia)
+	case MediaEventKeyExpiring:
+		go c.rekey()
+	case MediaEventHold:
+		c.cc.fsm.Transition(DialogHeld)
+	case MediaEventResume:
+		c.cc.fsm.Transition(DialogAnswered)
+	case MediaEventSRTPFailures:
+		if c.s.conf.TrunkSRTP(c.trunkID).FailureAction == config.SRTPFailureHangup {
+			c.log.Warnw("ending call due to invalid SRTP packets", nil)
+			// Called from the media read loop, which is stopped when the call is closed.
+			go c.close(true, callDropped, "srtp-failures")
+		}
+	case MediaEventOneWay:
+		if c.s.conf.OneWayAudio.ReInvite {
+			go c.refreshMedia()
+		}
+	case MediaEventUnreachable:
+		if c.s.conf.MediaUnreachable.Action != config.MediaUnreachableHangup && !c.recovery.Swap(true) {
+			go c.refreshMedia()
+			break
+		}
+		c.log.Warnw("ending call, media destination is unreachable", nil, "addr", ev.Addr.String())
+		// Called from the media loops, which are stopped when the call is closed.
+		go c.close(true, callDropped, "media-unreachable")
+	}
+}
+
+func (c *inboundCall) mediaStageEnv() MediaStageEnv {
+	return MediaStageEnv{
+		Log:       c.log,
+		CallID:    string(c.cc.ID()),
+		ProjectID: c.projectID,
+		Artifacts: c.s.artifacts,
+		mem:       c.mem,
+		OnDTMF:    c.handleDTMF,
+		OnSpeech: func(speaking bool) {
+			c.lkRoom.SetAttributes(speakingAttrs(speaking))
+		},
+		STT: c.s.stt,
+		OnTranscript: func(t stt.Transcript) {
+			publishTranscript(c.log, c.lkRoom, t)
+		},
+	}
+}
+
+// onDialogState reports the dialog state of the call to the room.
+func (c *inboundCall) onDialogState(_, to DialogState) {
+	c.lkRoom.SetAttributes(map[string]string{AttrSIPDialogState: to.String()})
+}
+
 func (c *inboundCall) setStatus(v CallStatus) {
 	attr := v.Attribute()
 	if attr == "" {
@@ -972,6 +1638,17 @@ func (c *inboundCall) createLiveKitParticipant(ctx context.Context, rconf RoomCo
 		partConf.Attributes[k] = v
 	}
 	partConf.Attributes[livekit.AttrSIPCallStatus] = status.Attribute()
+	if st, _ := c.cc.fsm.State(); st != DialogDone {
+		partConf.Attributes[AttrSIPDialogState] = st.String()
+	}
+	if c.media != nil {
+		if st := c.media.MediaState(); st != MediaStateNone {
+			partConf.Attributes[AttrSIPMediaState] = st.String()
+		}
+		if c.media.ZRTPDetected() {
+			partConf.Attributes[AttrSIPZRTP] = "true"
+		}
+	}
 	c.forwardDTMF.Store(true)
 	select {
 	case <-ctx.Done():
@@ -984,10 +1661,16 @@ func (c *inboundCall) createLiveKitParticipant(ctx context.Context, rconf RoomCo
 		return err
 	}
 
+	c.lkRoom.OnAttributesChanged((&attrRequests{
+		Hangup: c.closeWithRequestedHangup,
+		Mute:   c.setMuted,
+		Update: c.attrUpdates.OnChanged,
+	}).OnChanged)
 	err = c.lkRoom.Connect(c.s.conf, rconf)
 	if err != nil {
 		return err
 	}
+	c.recSync.Joined(c.lkRoom, rconf.WsUrl)
 	return nil
 }
 
@@ -997,7 +1680,11 @@ func (c *inboundCall) publishTrack() error {
 		_ = c.lkRoom.Close()
 		return err
 	}
-	c.media.WriteAudioTo(local)
+	var w msdk.PCM16Writer = local
+	if c.vad != nil {
+		w = c.vad.Writer(local)
+	}
+	c.media.WriteAudioTo(w)
 	return nil
 }
 
@@ -1012,33 +1699,36 @@ func (c *inboundCall) joinRoom(ctx context.Context, rconf RoomConfig, status Cal
 		"participantName", rconf.Participant.Name,
 	)
 	c.log.Infow("Joining room")
+	roomDur := c.mon.SetupPhaseDur(stats.SetupRoomJoin)
 	if err := c.createLiveKitParticipant(ctx, rconf, status); err != nil {
 		c.log.Errorw("Cannot create LiveKit participant", err)
 		c.close(true, callDropped, "participant-failed")
 		return errors.Wrap(err, "cannot create LiveKit participant")
 	}
+	roomDur()
 	return nil
 }
 
 func (c *inboundCall) playAudio(ctx context.Context, frames []msdk.PCM16Sample) {
-	t := c.lkRoom.NewTrack()
-	if t == nil {
-		return // closed
-	}
-	defer t.Close()
+	playToRoom(ctx, c.lkRoom, frames)
+}
 
-	sampleRate := res.SampleRate
-	if t.SampleRate() != sampleRate {
-		frames = slices.Clone(frames)
-		for i := range frames {
-			frames[i] = msdk.Resample(nil, t.SampleRate(), frames[i], sampleRate)
-		}
+// detectSpeech runs the detector on audio from the caller, for barge-in.
+func (c *inboundCall) detectSpeech(d *vad.Detector) func() {
+	if c.media == nil {
+		return func() {}
 	}
-	_ = msdk.PlayAudio[msdk.PCM16Sample](ctx, t, rtp.DefFrameDur, frames)
+	return c.media.DetectSpeech(d)
 }
 
-func (c *inboundCall) handleDTMF(tone dtmf.Event) {
+func (c *inboundCall) handleDTMF(tone DTMFEvent) {
+	c.log.Debugw("received dtmf", "digit", string([]byte{tone.Digit}), "duration", tone.Duration, "volume", tone.Volume)
+	c.speaker.BargeIn("dtmf")
 	if c.forwardDTMF.Load() {
+		c.lkRoom.SetAttributes(dtmfAttrs(tone))
+		if it, ok := c.menu.Feed(tone.Digit, time.Now()); ok {
+			go c.handleDTMFMenu(it)
+		}
 		_ = c.lkRoom.SendData(&livekit.SipDTMF{
 			Code:  uint32(tone.Code),
 			Digit: string([]byte{tone.Digit}),
@@ -1047,7 +1737,7 @@ func (c *inboundCall) handleDTMF(tone dtmf.Event) {
 	}
 	// We should have enough buffer here.
 	select {
-	case c.dtmf <- tone:
+	case c.dtmf <- tone.Event:
 	default:
 	}
 }
@@ -1056,12 +1746,16 @@ func (c *inboundCall) transferCall(ctx context.Context, transferTo string, heade
 	var err error
 
 	tID := c.state.StartTransfer(ctx, transferTo)
+	c.lkRoom.SetAttributes(transferStateAttrs(transferStateInProgress))
 	defer func() {
 		c.state.EndTransfer(ctx, tID, retErr)
+		c.lkRoom.SetAttributes(transferResultAttrs(retErr))
 	}()
+	// The remote may stop sending media while it connects the transfer target.
+	c.media.pauseTimeout(timeoutPauseTransfer, true)
+	defer c.media.pauseTimeout(timeoutPauseTransfer, false)
 
 	if dialtone && c.started.IsBroken() && !c.done.Load() {
-		const ringVolume = math.MaxInt16 / 2
 		rctx, rcancel := context.WithCancel(ctx)
 		defer rcancel()
 
@@ -1079,7 +1773,7 @@ func (c *inboundCall) transferCall(ctx context.Context, transferTo string, heade
 		go func() {
 			aw := c.media.GetAudioWriter()
 
-			err := tones.Play(rctx, aw, ringVolume, tones.ETSIRinging)
+			err := c.s.tones.Play(rctx, aw, tonegen.Ringing)
 			if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
 				c.log.Infow("cannot play dial tone", "error", err)
 			}
@@ -1111,9 +1805,15 @@ func (s *Server) newInbound(id LocalTag, contact URI, invite *sip.Request, invit
 			Address: *contact.GetContactURI(),
 		},
 		cancelled:  make(chan struct{}),
+		acked:      newAckState(),
 		referDone:  make(chan error), // Do not buffer the channel to avoid reading a result for an old request
 		setHeaders: getHeaders,
+		history:    newDialogHistory(),
+		caps:       s.capabilities(""),
+		timers:     s.timers(""),
 	}
+	c.inviteTx = c.history.ServerTx(invite, inviteTx)
+	c.fsm = newCallFSM(s.log, c.history, DialogProceeding)
 	c.from = invite.From()
 	if c.from != nil {
 		c.tag, _ = getTagFrom(c.from.Params)
@@ -1128,6 +1828,25 @@ func (s *Server) newInbound(id LocalTag, contact URI, invite *sip.Request, invit
 	return c
 }
 
+// inviteState is the state of the initial INVITE server transaction.
+type inviteState int
+
+const (
+	// inviteProceeding means only provisional responses were sent.
+	inviteProceeding = inviteState(iota)
+	// inviteCancelled means the caller sent CANCEL and we responded with 487.
+	inviteCancelled
+	// inviteRejected means we sent an error response.
+	inviteRejected
+	// inviteAccepted means we sent 200 OK.
+	inviteAccepted
+)
+
+var errInviteCancelled = errors.New("call cancelled by the caller")
+
+// callerCancelled is reported in the call status of calls cancelled by the caller.
+const callerCancelled = "caller-cancelled"
+
 type sipInbound struct {
 	s         *Server
 	id        LocalTag
@@ -1137,15 +1856,23 @@ type sipInbound struct {
 	inviteTx  sip.ServerTransaction
 	contact   *sip.ContactHeader
 	cancelled chan struct{}
+	acked     *ackState
 	from      *sip.FromHeader
 	to        *sip.ToHeader
 	referDone chan error
+	history   *dialogHistory
+	fsm       *callFSM
+	timers    sipTimers // not guarded by mu, since requests are sent with the lock held
 
 	mu              sync.RWMutex
+	state           inviteState
 	inviteOk        *sip.Response
+	finalStatus     sip.StatusCode
 	nextRequestCSeq uint32
 	referCseq       uint32
 	ringing         chan struct{}
+	earlySDP        []byte // if set, 183 with SDP is sent instead of 180
+	caps            sipCapabilities
 	setHeaders      setHeadersFunc
 }
 
@@ -1162,6 +1889,18 @@ func (c *sipInbound) ValidateInvite() error {
 	return nil
 }
 
+// SetCapabilities updates capabilities advertised in responses, once the trunk is known.
+func (c *sipInbound) SetCapabilities(caps sipCapabilities) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.caps = caps
+}
+
+// SetTimers updates transaction timers once the trunk is known. It must be called before any requests are sent in the dialog.
+func (c *sipInbound) SetTimers(timers sipTimers) {
+	c.timers = timers
+}
+
 func (c *sipInbound) Drop() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -1169,6 +1908,7 @@ func (c *sipInbound) Drop() {
 }
 
 func (c *sipInbound) drop() {
+	c.fsm.Transition(DialogTerminating)
 	c.stopRinging()
 	if c.inviteTx != nil {
 		c.inviteTx.Terminate()
@@ -1185,9 +1925,25 @@ func (c *sipInbound) respond(status sip.StatusCode, reason string) {
 	}
 
 	r := sip.NewResponseFromRequest(c.invite, status, reason, nil)
-	r.AppendHeader(sip.NewHeader("Allow", "INVITE, ACK, CANCEL, BYE, NOTIFY, REFER, MESSAGE, OPTIONS, INFO, SUBSCRIBE"))
+	c.caps.SetHeaders(r, false)
 	c.addExtraHeaders(r)
+	if status >= 300 {
+		c.finalStatus = status
+		if c.state == inviteProceeding {
+			c.state = inviteRejected
+		}
+	}
 	_ = c.inviteTx.Respond(r)
+	if status == sip.StatusRinging {
+		c.fsm.Transition(DialogEarly)
+	}
+}
+
+// FinalStatus returns the error status code sent in response to INVITE, or zero if there's none.
+func (c *sipInbound) FinalStatus() sip.StatusCode {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.finalStatus
 }
 
 func (c *sipInbound) RespondAndDrop(status sip.StatusCode, reason string) {
@@ -1198,25 +1954,46 @@ func (c *sipInbound) RespondAndDrop(status sip.StatusCode, reason string) {
 	c.drop()
 }
 
+// RedirectAndDrop responds with 302 Moved Temporarily to a given SIP URI.
+func (c *sipInbound) RedirectAndDrop(target string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopRinging()
+	if c.inviteTx != nil {
+		r := sip.NewResponseFromRequest(c.invite, sip.StatusMovedTemporarily, "Moved Temporarily", nil)
+		r.AppendHeader(sip.NewHeader("Contact", "<"+target+">"))
+		c.addExtraHeaders(r)
+		if c.state == inviteProceeding {
+			c.state = inviteRejected
+		}
+		c.finalStatus = sip.StatusMovedTemporarily
+		_ = c.inviteTx.Respond(r)
+	}
+	c.drop()
+}
+
+// Address returns the Request-URI of the INVITE, with the user unescaped and sanitized.
 func (c *sipInbound) Address() sip.Uri {
 	if c.invite == nil {
 		return sip.Uri{}
 	}
-	return c.invite.Recipient
+	return sanitizeUserURI(c.invite.Recipient)
 }
 
+// From returns the From URI with the user unescaped and sanitized. The header itself is kept as received.
 func (c *sipInbound) From() sip.Uri {
 	if c.from == nil {
 		return sip.Uri{}
 	}
-	return c.from.Address
+	return sanitizeUserURI(c.from.Address)
 }
 
+// To returns the To URI with the user unescaped and sanitized. The header itself is kept as received.
 func (c *sipInbound) To() sip.Uri {
 	if c.to == nil {
 		return sip.Uri{}
 	}
-	return c.to.Address
+	return sanitizeUserURI(c.to.Address)
 }
 
 func (c *sipInbound) ID() LocalTag {
@@ -1247,9 +2024,36 @@ func (c *sipInbound) Processing() {
 }
 
 func (c *sipInbound) sendRinging() {
+	if c.earlySDP != nil {
+		c.sendSessionProgress()
+		return
+	}
 	c.respond(sip.StatusRinging, "Ringing")
 }
 
+func (c *sipInbound) sendSessionProgress() {
+	if c.inviteTx == nil {
+		return
+	}
+	r := sip.NewResponseFromRequest(c.invite, sip.StatusSessionInProgress, "Session Progress", c.earlySDP)
+	r.AppendHeader(c.contact)
+	c.caps.SetHeaders(r, false)
+	c.addExtraHeaders(r)
+	c.setDestFromVia(r)
+	r.AppendHeader(&contentTypeHeaderSDP)
+	_ = c.inviteTx.Respond(r)
+	c.fsm.Transition(DialogEarly)
+}
+
+// EarlyMedia sends 183 Session Progress with SDP answer, allowing media to flow before the call is accepted.
+// It replaces 180 Ringing in periodic provisional responses.
+func (c *sipInbound) EarlyMedia(sdpData []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.earlySDP = sdpData
+	c.sendRinging()
+}
+
 func (c *sipInbound) attachTag() {
 	// Set the SIP tag for following requests from us to remote (e.g. BYE).
 	c.to.Params["tag"] = string(c.id)
@@ -1272,9 +2076,8 @@ func (c *sipInbound) StartRinging() {
 			case <-stop:
 				return
 			case r := <-cancels:
-				close(c.cancelled)
 				_ = tx.Respond(sip.NewResponseFromRequest(r, sip.StatusOK, "OK", nil))
-				c.RespondAndDrop(sip.StatusRequestTerminated, "Request Terminated")
+				c.onCancel()
 				return
 			case <-ticker.C:
 			}
@@ -1285,6 +2088,20 @@ func (c *sipInbound) StartRinging() {
 	}()
 }
 
+// onCancel terminates the INVITE transaction with 487 after CANCEL from the caller.
+// It has no effect if the final response was already sent.
+func (c *sipInbound) onCancel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != inviteProceeding {
+		return
+	}
+	c.state = inviteCancelled
+	close(c.cancelled)
+	c.respond(sip.StatusRequestTerminated, "Request Terminated")
+	c.drop()
+}
+
 func (c *sipInbound) stopRinging() {
 	if c.ringing != nil {
 		close(c.ringing)
@@ -1330,6 +2147,9 @@ func (c *sipInbound) Accept(ctx context.Context, sdpData []byte, headers map[str
 	defer span.End()
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.state == inviteCancelled {
+		return errInviteCancelled
+	}
 	if c.inviteTx == nil {
 		return errors.New("call already rejected")
 	}
@@ -1337,6 +2157,7 @@ func (c *sipInbound) Accept(ctx context.Context, sdpData []byte, headers map[str
 
 	// This will effectively redirect future SIP requests to this server instance (if host address is not LB).
 	r.AppendHeader(c.contact)
+	c.caps.SetHeaders(r, false)
 
 	c.addExtraHeaders(r)
 
@@ -1352,10 +2173,13 @@ func (c *sipInbound) Accept(ctx context.Context, sdpData []byte, headers map[str
 	}
 	c.inviteOk = r
 	c.inviteTx = nil // accepted
+	c.state = inviteAccepted
+	c.fsm.Transition(DialogAnswered)
 	return nil
 }
 
 func (c *sipInbound) AcceptBye(req *sip.Request, tx sip.ServerTransaction) {
+	tx = c.history.ServerTx(req, tx)
 	_ = tx.Respond(sip.NewResponseFromRequest(req, 200, "OK", nil))
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -1389,12 +2213,19 @@ func (c *sipInbound) swapSrcDst(req *sip.Request) {
 }
 
 func (c *sipInbound) generateViaHeader(req *sip.Request) *sip.ViaHeader {
+	host := advertisedHost(c.s.conf, transportFromReq(c.invite))
+	if host == "" {
+		host = c.s.sconf.signalingIP().String()
+		if addr, ok := c.s.proxied.Local(c.invite.Source()); ok {
+			host = addr.Addr().String()
+		}
+	}
 	newvia := &sip.ViaHeader{
 		ProtocolName:    "SIP",
 		ProtocolVersion: "2.0",
 		Transport:       req.Transport(),
-		Host:            c.s.sconf.SignalingIP.String(), // This can be rewritten by transport layer
-		Port:            c.s.conf.SIPPort,               // This can be rewritten by transport layer
+		Host:            host,             // This can be rewritten by transport layer
+		Port:            c.s.conf.SIPPort, // This can be rewritten by transport layer
 		Params:          sip.NewParams(),
 	}
 	// NOTE: Consider lenght of branch configurable
@@ -1453,16 +2284,37 @@ func (c *sipInbound) sendStatus(code sip.StatusCode, status string) {
 		}
 	}
 	c.setDestFromVia(r)
+	if code >= 300 {
+		c.finalStatus = code
+		if c.state == inviteProceeding {
+			c.state = inviteRejected
+		}
+	}
 	_ = c.inviteTx.Respond(r)
 	c.drop()
 }
 
 func (c *sipInbound) WriteRequest(req *sip.Request) error {
-	return c.s.sipSrv.TransportLayer().WriteMsg(req)
+	c.history.Request(dialogOut, req)
+	return retryTransport(c.s.log, req.Transport(), func() error {
+		return c.s.sipSrv.TransportLayer().WriteMsg(req)
+	})
+}
+
+func (c *sipInbound) Transaction(req *sip.Request) (tx sip.ClientTransaction, err error) {
+	err = retryTransport(c.s.log, req.Transport(), func() error {
+		tx, err = c.s.sipSrv.TransactionLayer().Request(req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.timers.ClientTx(c.history, req, c.history.ClientTx(req, tx)), nil
 }
 
-func (c *sipInbound) Transaction(req *sip.Request) (sip.ClientTransaction, error) {
-	return c.s.sipSrv.TransactionLayer().Request(req)
+// keepAlive sends keep-alives over the connection of the call, if it uses TCP or TLS.
+func (c *sipInbound) keepAlive(done <-chan struct{}, log logger.Logger) {
+	keepAliveConn(done, log, c.s.sipSrv.TransportLayer(), string(transportFromReq(c.invite)), c.invite.Source(), c.s.conf.SIPKeepAliveInterval)
 }
 
 func (c *sipInbound) newReferReq(transferTo string, headers map[string]string) (*sip.Request, error) {
@@ -1483,6 +2335,7 @@ func (c *sipInbound) newReferReq(transferTo string, headers map[string]string) (
 
 	// This will effectively redirect future SIP requests to this server instance (if host address is not LB).
 	req := NewReferRequest(c.invite, c.inviteOk, c.contact, transferTo, headers)
+	c.caps.SetHeaders(req, false)
 	c.setCSeq(req)
 	c.swapSrcDst(req)
 
@@ -1517,6 +2370,24 @@ func (c *sipInbound) TransferCall(ctx context.Context, transferTo string, header
 	return nil
 }
 
+// SendHeaders sends headers to the caller in an in-dialog request without a body, such as INFO or UPDATE.
+func (c *sipInbound) SendHeaders(ctx context.Context, method sip.RequestMethod, headers map[string]string) error {
+	c.mu.Lock()
+	if c.invite == nil || c.inviteOk == nil {
+		c.mu.Unlock()
+		return psrpc.NewErrorf(psrpc.FailedPrecondition, "can't send %s in non established call", method)
+	}
+	req := newDialogRequest(method, c.invite, c.inviteOk, c.contact)
+	for k, v := range headers {
+		req.AppendHeader(sip.NewHeader(k, v))
+	}
+	c.setCSeq(req)
+	c.swapSrcDst(req)
+	c.mu.Unlock()
+
+	return sendDialogRequest(ctx, c, req, c.s.closing.Watch())
+}
+
 func (c *sipInbound) handleNotify(req *sip.Request, tx sip.ServerTransaction) error {
 	method, cseq, status, err := handleNotify(req)
 	if err != nil {
@@ -0,0 +1,240 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/livekit/sipgo/sip"
+	"github.com/livekit/sipgo/transaction"
+)
+
+const (
+	// inviteDedupTTL is how long completed INVITE transactions are remembered, same as Timer L of the transaction layer.
+	inviteDedupTTL = transaction.Timer_L
+	// inviteDedupMax limits the number of remembered completed transactions.
+	inviteDedupMax = 10000
+)
+
+// inviteDupKind is the kind of duplicate INVITE.
+type inviteDupKind string
+
+const (
+	// inviteRetransmit is a retransmission of an INVITE which is still processed. It's absorbed.
+	inviteRetransmit = inviteDupKind("retransmit")
+	// inviteMerged has a different branch than the INVITE which is still processed. It's rejected with 482 (RFC 3261, 8.2.2.2).
+	inviteMerged = inviteDupKind("merged")
+	// inviteCompleted is a late retransmission of an INVITE which already got a final response. The response is sent again.
+	inviteCompleted = inviteDupKind("completed")
+)
+
+// inviteKey identifies an initial INVITE. Retransmissions and merged requests have the same key.
+type inviteKey struct {
+	callID  string
+	fromTag string
+	cseq    uint32
+}
+
+func inviteKeyOf(req *sip.Request) (inviteKey, bool) {
+	callID, from, cseq := req.CallID(), req.From(), req.CSeq()
+	if callID == nil || from == nil || cseq == nil {
+		return inviteKey{}, false
+	}
+	tag, _ := from.Params.Get("tag")
+	return inviteKey{callID: callID.Value(), fromTag: tag, cseq: cseq.SeqNo}, true
+}
+
+func viaBranch(req *sip.Request) string {
+	if via := req.Via(); via != nil {
+		branch, _ := via.Params.Get("branch")
+		return branch
+	}
+	return ""
+}
+
+// inviteEntry is an initial INVITE which is processed, or was completed recently.
+type inviteEntry struct {
+	key    inviteKey
+	branch string
+	final  *sip.Response // guarded by inviteDedup.mu
+	doneAt time.Time     // guarded by inviteDedup.mu
+}
+
+// inviteDedup detects duplicate INVITEs which are not matched by the transaction layer.
+//
+// The transaction layer matches retransmissions by the branch and the sent-by address of the Via header,
+// and only while the transaction exists. Retransmissions which arrive with a different Via, or after the
+// transaction ended, would otherwise create another call.
+type inviteDedup struct {
+	ttl time.Duration
+	max int
+
+	mu      sync.Mutex
+	entries map[inviteKey]*inviteEntry
+	done    []*inviteEntry // completed entries, in the order of completion
+}
+
+func newInviteDedup() *inviteDedup {
+	return &inviteDedup{
+		ttl:     inviteDedupTTL,
+		max:     inviteDedupMax,
+		entries: make(map[inviteKey]*inviteEntry),
+	}
+}
+
+// Begin registers an initial INVITE. If it duplicates a known INVITE, the kind of the duplicate is returned instead,
+// together with the final response sent to the original INVITE, if any.
+//
+// The entry must be finished once the INVITE is processed. It's nil if the INVITE can't be tracked.
+func (d *inviteDedup) Begin(req *sip.Request, now time.Time) (*inviteEntry, inviteDupKind, *sip.Response) {
+	if d == nil {
+		return nil, "", nil
+	}
+	key, ok := inviteKeyOf(req)
+	if !ok {
+		return nil, "", nil
+	}
+	branch := viaBranch(req)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expireLocked(now)
+	if e := d.entries[key]; e != nil {
+		switch {
+		case !e.doneAt.IsZero() && e.branch != branch && isAuthChallenge(e.final):
+			// Some clients answer the challenge without incrementing CSeq. It's a new transaction.
+			d.removeLocked(e)
+		case !e.doneAt.IsZero():
+			return nil, inviteCompleted, e.final
+		case e.branch != branch:
+			return nil, inviteMerged, nil
+		default:
+			return nil, inviteRetransmit, nil
+		}
+	}
+	e := &inviteEntry{key: key, branch: branch}
+	d.entries[key] = e
+	return e, "", nil
+}
+
+// ServerTx returns a transaction which records the final response to the INVITE.
+func (d *inviteDedup) ServerTx(e *inviteEntry, tx sip.ServerTransaction) sip.ServerTransaction {
+	if e == nil || tx == nil {
+		return tx
+	}
+	return &dedupServerTx{ServerTransaction: tx, d: d, e: e}
+}
+
+// Finish marks the INVITE completed, if it didn't get a final response. Duplicates are still detected for a while.
+func (d *inviteDedup) Finish(e *inviteEntry, now time.Time) {
+	if e == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.completeLocked(e, nil, now)
+}
+
+func (d *inviteDedup) completeLocked(e *inviteEntry, final *sip.Response, now time.Time) {
+	if !e.doneAt.IsZero() {
+		return
+	}
+	e.final = final
+	e.doneAt = now
+	d.done = append(d.done, e)
+	if n := len(d.done) - d.max; n > 0 {
+		for _, old := range d.done[:n] {
+			delete(d.entries, old.key)
+		}
+		d.done = d.done[n:]
+	}
+}
+
+func (d *inviteDedup) removeLocked(e *inviteEntry) {
+	delete(d.entries, e.key)
+	d.done = slices.DeleteFunc(d.done, func(v *inviteEntry) bool { return v == e })
+}
+
+func (d *inviteDedup) expireLocked(now time.Time) {
+	n := 0
+	for _, e := range d.done {
+		if now.Sub(e.doneAt) < d.ttl {
+			break
+		}
+		delete(d.entries, e.key)
+		n++
+	}
+	d.done = d.done[n:]
+}
+
+// Len returns the number of tracked INVITEs.
+func (d *inviteDedup) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.entries)
+}
+
+type dedupServerTx struct {
+	sip.ServerTransaction
+	d *inviteDedup
+	e *inviteEntry
+}
+
+func (tx *dedupServerTx) Respond(res *sip.Response) error {
+	if !res.IsProvisional() {
+		tx.d.mu.Lock()
+		tx.d.completeLocked(tx.e, res, time.Now())
+		tx.d.mu.Unlock()
+	}
+	return tx.ServerTransaction.Respond(res)
+}
+
+func isAuthChallenge(res *sip.Response) bool {
+	return res != nil && (res.StatusCode == sip.StatusUnauthorized || res.StatusCode == sip.StatusProxyAuthRequired)
+}
+
+// replayResponse copies the final response to the original INVITE as a response to its retransmission.
+func replayResponse(req *sip.Request, final *sip.Response) *sip.Response {
+	res := final.Clone()
+	for res.RemoveHeader("Via") {
+	}
+	sip.CopyHeaders("Via", req, res)
+	res.SetTransport(req.Transport())
+	res.SetSource(req.Destination())
+	res.SetDestination(req.Source())
+	return res
+}
+
+// onDuplicateInvite handles an INVITE which duplicates a known one, without creating a new call.
+func (s *Server) onDuplicateInvite(req *sip.Request, tx sip.ServerTransaction, kind inviteDupKind, final *sip.Response) {
+	s.mon.InviteDuplicate(string(kind))
+	s.log.Infow("duplicate invite",
+		"kind", kind,
+		"sipCallID", req.CallID().Value(),
+		"branch", viaBranch(req),
+		"fromIP", req.Source(),
+		"replay", final != nil,
+	)
+	switch {
+	case kind == inviteMerged:
+		_ = tx.Respond(sip.NewResponseFromRequest(req, sip.StatusLoopDetected, "Loop Detected", nil))
+	case final != nil:
+		_ = tx.Respond(replayResponse(req, final))
+	default:
+		// The original transaction responds to the caller.
+		tx.Terminate()
+	}
+}
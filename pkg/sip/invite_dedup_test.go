@@ -0,0 +1,180 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/sipgo/sip"
+)
+
+type testServerTx struct {
+	sip.ServerTransaction
+	responses  []*sip.Response
+	terminated bool
+}
+
+func (tx *testServerTx) Respond(res *sip.Response) error {
+	tx.responses = append(tx.responses, res)
+	return nil
+}
+
+func (tx *testServerTx) Terminate() {
+	tx.terminated = true
+}
+
+func newDedupInvite(callID string, cseq uint32, branch string, port int) *sip.Request {
+	req := sip.NewRequest(sip.INVITE, sip.Uri{User: "bob", Host: "example.com"})
+	via := &sip.ViaHeader{ProtocolName: "SIP", ProtocolVersion: "2.0", Transport: "UDP", Host: "1.1.1.1", Port: port, Params: sip.NewParams()}
+	via.Params.Add("branch", branch)
+	req.AppendHeader(via)
+	from := &sip.FromHeader{Address: sip.Uri{User: "alice", Host: "example.com"}, Params: sip.NewParams()}
+	from.Params.Add("tag", "abc")
+	req.AppendHeader(from)
+	req.AppendHeader(&sip.ToHeader{Address: sip.Uri{User: "bob", Host: "example.com"}, Params: sip.NewParams()})
+	cid := sip.CallIDHeader(callID)
+	req.AppendHeader(&cid)
+	req.AppendHeader(&sip.CSeqHeader{SeqNo: cseq, MethodName: sip.INVITE})
+	return req
+}
+
+func TestInviteDedup(t *testing.T) {
+	now := time.Now()
+	d := newInviteDedup()
+
+	req := newDedupInvite("call-1", 1, "z9hG4bK-1", 5060)
+	e, dup, _ := d.Begin(req, now)
+	require.NotNil(t, e)
+	require.Empty(t, dup)
+
+	// Retransmission with a different sent-by, not matched by the transaction layer.
+	e2, dup, final := d.Begin(newDedupInvite("call-1", 1, "z9hG4bK-1", 5070), now)
+	require.Nil(t, e2)
+	require.Equal(t, inviteRetransmit, dup)
+	require.Nil(t, final)
+
+	// Same request forked through a different path.
+	_, dup, _ = d.Begin(newDedupInvite("call-1", 1, "z9hG4bK-2", 5060), now)
+	require.Equal(t, inviteMerged, dup)
+
+	// Other calls and re-INVITEs are not affected.
+	e3, dup, _ := d.Begin(newDedupInvite("call-2", 1, "z9hG4bK-3", 5060), now)
+	require.NotNil(t, e3)
+	require.Empty(t, dup)
+	e4, dup, _ := d.Begin(newDedupInvite("call-1", 2, "z9hG4bK-4", 5060), now)
+	require.NotNil(t, e4)
+	require.Empty(t, dup)
+
+	// Final response is recorded and replayed to late retransmissions.
+	tx := &testServerTx{}
+	stx := d.ServerTx(e, tx)
+	require.NoError(t, stx.Respond(sip.NewResponseFromRequest(req, sip.StatusRinging, "Ringing", nil)))
+	_, dup, _ = d.Begin(req, now)
+	require.Equal(t, inviteRetransmit, dup)
+	busy := sip.NewResponseFromRequest(req, sip.StatusBusyHere, "Busy Here", nil)
+	require.NoError(t, stx.Respond(busy))
+	require.Len(t, tx.responses, 2)
+	d.Finish(e, now)
+
+	late := newDedupInvite("call-1", 1, "z9hG4bK-1", 5070)
+	_, dup, final = d.Begin(late, now.Add(time.Second))
+	require.Equal(t, inviteCompleted, dup)
+	require.Same(t, busy, final)
+	res := replayResponse(late, final)
+	require.Equal(t, sip.StatusBusyHere, res.StatusCode)
+	require.Equal(t, 5070, res.Via().Port)
+	require.Len(t, res.GetHeaders("Via"), 1)
+	// The original response is not modified.
+	require.Equal(t, 5060, busy.Via().Port)
+
+	// Calls which ended without a final response are still deduplicated.
+	d.Finish(e3, now)
+	_, dup, final = d.Begin(newDedupInvite("call-2", 1, "z9hG4bK-3", 5060), now)
+	require.Equal(t, inviteCompleted, dup)
+	require.Nil(t, final)
+
+	// Completed entries expire.
+	require.Equal(t, 3, d.Len())
+	e, dup, _ = d.Begin(req, now.Add(inviteDedupTTL+time.Second))
+	require.NotNil(t, e)
+	require.Empty(t, dup)
+	require.Equal(t, 2, d.Len())
+}
+
+func TestInviteDedupAuthRetry(t *testing.T) {
+	now := time.Now()
+	d := newInviteDedup()
+
+	req := newDedupInvite("call-1", 1, "z9hG4bK-1", 5060)
+	e, _, _ := d.Begin(req, now)
+	require.NoError(t, d.ServerTx(e, &testServerTx{}).Respond(sip.NewResponseFromRequest(req, sip.StatusUnauthorized, "Unauthorized", nil)))
+	d.Finish(e, now)
+
+	// Retransmission of the challenged request gets the challenge again.
+	_, dup, final := d.Begin(req, now)
+	require.Equal(t, inviteCompleted, dup)
+	require.Equal(t, sip.StatusUnauthorized, final.StatusCode)
+
+	// Answer to the challenge which reuses the CSeq is a new transaction.
+	e, dup, _ = d.Begin(newDedupInvite("call-1", 1, "z9hG4bK-2", 5060), now)
+	require.NotNil(t, e)
+	require.Empty(t, dup)
+	require.Equal(t, 1, d.Len())
+	d.Finish(e, now)
+	_, dup, _ = d.Begin(newDedupInvite("call-1", 1, "z9hG4bK-2", 5060), now)
+	require.Equal(t, inviteCompleted, dup)
+}
+
+func TestInviteDedupMax(t *testing.T) {
+	now := time.Now()
+	d := newInviteDedup()
+	d.max = 2
+	for i, id := range []string{"a", "b", "c"} {
+		e, _, _ := d.Begin(newDedupInvite(id, 1, "z9hG4bK", 5060), now)
+		d.Finish(e, now.Add(time.Duration(i)*time.Second))
+	}
+	require.Equal(t, 2, d.Len())
+	_, dup, _ := d.Begin(newDedupInvite("a", 1, "z9hG4bK", 5060), now)
+	require.Empty(t, dup)
+
+	var nilDedup *inviteDedup
+	e, dup, _ := nilDedup.Begin(newDedupInvite("a", 1, "z9hG4bK", 5060), now)
+	require.Nil(t, e)
+	require.Empty(t, dup)
+}
+
+func TestOnDuplicateInvite(t *testing.T) {
+	s := &Server{log: logger.GetLogger()}
+	req := newDedupInvite("call-1", 1, "z9hG4bK-1", 5060)
+
+	tx := &testServerTx{}
+	s.onDuplicateInvite(req, tx, inviteRetransmit, nil)
+	require.True(t, tx.terminated)
+	require.Empty(t, tx.responses)
+
+	tx = &testServerTx{}
+	s.onDuplicateInvite(req, tx, inviteMerged, nil)
+	require.Len(t, tx.responses, 1)
+	require.Equal(t, sip.StatusLoopDetected, tx.responses[0].StatusCode)
+
+	tx = &testServerTx{}
+	s.onDuplicateInvite(req, tx, inviteCompleted, sip.NewResponseFromRequest(req, sip.StatusBusyHere, "Busy Here", nil))
+	require.Len(t, tx.responses, 1)
+	require.Equal(t, sip.StatusBusyHere, tx.responses[0].StatusCode)
+}
@@ -0,0 +1,290 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/sipgo/sip"
+)
+
+// contentTypeISUP is the type of ISUP messages encapsulated in SIP-I bodies, see RFC 3204 and ITU-T Q.1912.5.
+const contentTypeISUP = "application/isup"
+
+// ISUP message types, see ITU-T Q.763.
+const (
+	ISUPInitialAddress  = byte(0x01) // IAM
+	ISUPAddressComplete = byte(0x06) // ACM
+	ISUPConnect         = byte(0x07) // CON
+	ISUPAnswer          = byte(0x09) // ANM
+	ISUPRelease         = byte(0x0c) // REL
+	ISUPCallProgress    = byte(0x2c) // CPG
+)
+
+// ISUP parameter codes, see ITU-T Q.763.
+const (
+	isupParamEnd                  = 0x00
+	isupParamCalledPartyNumber    = 0x04
+	isupParamCallingPartyNumber   = 0x0a
+	isupParamRedirectingNumber    = 0x0b
+	isupParamCauseIndicators      = 0x12
+	isupParamOriginalCalledNumber = 0x28
+)
+
+// isupLayout describes the mandatory part of ISUP messages: the length of fixed parameters
+// and the number of variable parameters. All supported messages have an optional part.
+var isupLayout = map[byte]struct{ fixed, variable int }{
+	ISUPInitialAddress:  {fixed: 5, variable: 1},
+	ISUPAddressComplete: {fixed: 2},
+	ISUPConnect:         {fixed: 2},
+	ISUPAnswer:          {},
+	ISUPRelease:         {variable: 1},
+	ISUPCallProgress:    {fixed: 1},
+}
+
+// ISUPMessage is the part of an encapsulated ISUP message relevant for the call.
+// Numbers with restricted presentation are omitted.
+type ISUPMessage struct {
+	Type byte
+	// CallingPartyCategory is only set for IAM.
+	CallingPartyCategory ISUPCategory
+	CalledNumber         string
+	CallingNumber        string
+	OriginalCalledNumber string
+	RedirectingNumber    string
+	// Cause is set if the message carries cause indicators, for example in REL.
+	Cause *ISUPCause
+}
+
+// ISUPCategory is the calling party's category (ITU-T Q.763, 3.11).
+type ISUPCategory byte
+
+func (c ISUPCategory) String() string {
+	switch c {
+	case 0x00:
+		return "unknown"
+	case 0x01, 0x02, 0x03, 0x04, 0x05, 0x09:
+		return "operator"
+	case 0x0a:
+		return "ordinary"
+	case 0x0b:
+		return "priority"
+	case 0x0c:
+		return "data"
+	case 0x0d:
+		return "test"
+	case 0x0f:
+		return "payphone"
+	}
+	return strconv.Itoa(int(c))
+}
+
+// ISUPCause is a cause value from cause indicators (ITU-T Q.850).
+type ISUPCause struct {
+	Location byte
+	Value    byte
+}
+
+func (c ISUPCause) String() string {
+	return strconv.Itoa(int(c.Value))
+}
+
+// ParseISUP parses an ISUP message body, starting from the message type. Routing label and CIC are not included in SIP-I.
+func ParseISUP(data []byte) (*ISUPMessage, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty isup message")
+	}
+	m := &ISUPMessage{Type: data[0]}
+	layout, ok := isupLayout[m.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported isup message type 0x%02x", m.Type)
+	}
+	pos := 1 + layout.fixed
+	if len(data) < pos+layout.variable+1 {
+		return nil, errors.New("isup message is too short")
+	}
+	if m.Type == ISUPInitialAddress {
+		m.CallingPartyCategory = ISUPCategory(data[4])
+	}
+	for i := 0; i < layout.variable; i++ {
+		val, err := isupPointed(data, pos+i)
+		if err != nil {
+			return nil, err
+		}
+		if err = m.setParam(isupVariableParam(m.Type), val); err != nil {
+			return nil, err
+		}
+	}
+	optPtr := pos + layout.variable
+	if data[optPtr] == 0 {
+		return m, nil // no optional part
+	}
+	off := optPtr + int(data[optPtr])
+	for {
+		if off >= len(data) {
+			return nil, errors.New("isup optional part is not terminated")
+		}
+		code := data[off]
+		if code == isupParamEnd {
+			return m, nil
+		}
+		if off+1 >= len(data) || off+2+int(data[off+1]) > len(data) {
+			return nil, fmt.Errorf("isup parameter 0x%02x is truncated", code)
+		}
+		val := data[off+2 : off+2+int(data[off+1])]
+		if err := m.setParam(code, val); err != nil {
+			return nil, err
+		}
+		off += 2 + len(val)
+	}
+}
+
+// isupVariableParam returns the parameter code of the mandatory variable parameter of a message type.
+func isupVariableParam(typ byte) byte {
+	switch typ {
+	case ISUPInitialAddress:
+		return isupParamCalledPartyNumber
+	case ISUPRelease:
+		return isupParamCauseIndicators
+	}
+	return isupParamEnd
+}
+
+// isupPointed returns the value of a variable parameter referenced by a pointer at a given offset.
+func isupPointed(data []byte, ptr int) ([]byte, error) {
+	start := ptr + int(data[ptr])
+	if data[ptr] == 0 || start >= len(data) || start+1+int(data[start]) > len(data) {
+		return nil, errors.New("invalid isup parameter pointer")
+	}
+	return data[start+1 : start+1+int(data[start])], nil
+}
+
+func (m *ISUPMessage) setParam(code byte, val []byte) error {
+	var err error
+	switch code {
+	case isupParamCalledPartyNumber:
+		m.CalledNumber, err = isupNumber(val, false)
+	case isupParamCallingPartyNumber:
+		m.CallingNumber, err = isupNumber(val, true)
+	case isupParamOriginalCalledNumber:
+		m.OriginalCalledNumber, err = isupNumber(val, true)
+	case isupParamRedirectingNumber:
+		m.RedirectingNumber, err = isupNumber(val, true)
+	case isupParamCauseIndicators:
+		if len(val) < 2 {
+			return errors.New("isup cause indicators are too short")
+		}
+		m.Cause = &ISUPCause{Location: val[0] & 0x0f, Value: val[1] & 0x7f}
+	}
+	return err
+}
+
+// isupNumber decodes digits of a number parameter. Numbers with restricted presentation are returned empty.
+func isupNumber(val []byte, presentation bool) (string, error) {
+	if len(val) < 2 {
+		return "", errors.New("isup number is too short")
+	}
+	if presentation && (val[1]>>2)&0x03 != 0 {
+		return "", nil // restricted or not available
+	}
+	const digits = "0123456789ABCDE"
+	var b strings.Builder
+	if val[0]&0x7f == 0x04 { // international number
+		b.WriteByte('+')
+	}
+	odd := val[0]&0x80 != 0
+	for i, d := range val[2:] {
+		for j, n := range [2]byte{d & 0x0f, d >> 4} {
+			last := i == len(val)-3 && j == 1
+			if n == 0x0f || (last && odd) {
+				break // end of pulsing or filler
+			}
+			b.WriteByte(digits[n])
+		}
+	}
+	return b.String(), nil
+}
+
+// isupFromParts parses the first ISUP part of a message body.
+func isupFromParts(log logger.Logger, parts []BodyPart) *ISUPMessage {
+	for _, p := range parts {
+		if p.ContentType != contentTypeISUP {
+			continue
+		}
+		m, err := ParseISUP(p.Body)
+		if err != nil {
+			log.Infow("cannot parse isup body", "error", err)
+			return nil
+		}
+		return m
+	}
+	return nil
+}
+
+// Attributes returns participant attributes with ISUP information.
+func (m *ISUPMessage) Attributes() map[string]string {
+	attrs := make(map[string]string)
+	if m.Type == ISUPInitialAddress {
+		attrs[AttrSIPISUPCallingPartyCategory] = m.CallingPartyCategory.String()
+	}
+	if m.OriginalCalledNumber != "" {
+		attrs[AttrSIPISUPOriginalCalledNumber] = m.OriginalCalledNumber
+	}
+	if m.RedirectingNumber != "" {
+		attrs[AttrSIPISUPRedirectingNumber] = m.RedirectingNumber
+	}
+	if m.Cause != nil {
+		attrs[AttrSIPISUPCause] = m.Cause.String()
+	}
+	return attrs
+}
+
+// isupCause returns the ISUP release cause from a message body, if any.
+func isupCause(log logger.Logger, contentType string, body []byte) *ISUPCause {
+	var parts []BodyPart
+	if mt, _, _ := mime.ParseMediaType(contentType); mt == contentTypeISUP {
+		// ISUP is the only body of the message.
+		parts = []BodyPart{{ContentType: contentTypeISUP, Body: body}}
+	} else if _, parts, _ = splitBody(contentType, body); len(parts) == 0 {
+		return nil
+	}
+	if m := isupFromParts(log, parts); m != nil {
+		return m.Cause
+	}
+	return nil
+}
+
+// recordISUPCause stores the ISUP release cause from a BYE request in the call info.
+func recordISUPCause(log logger.Logger, state *CallState, req *sip.Request) {
+	cause := isupCause(log, bodyContentType(req), req.Body())
+	if cause == nil {
+		return
+	}
+	log.Infow("BYE has isup release cause", "cause", cause.Value, "location", cause.Location)
+	state.DeferUpdate(func(info *livekit.SIPCallInfo) {
+		info.ParticipantAttributes = maps.Clone(info.ParticipantAttributes)
+		if info.ParticipantAttributes == nil {
+			info.ParticipantAttributes = make(map[string]string)
+		}
+		info.ParticipantAttributes[AttrSIPISUPCause] = cause.String()
+	})
+}
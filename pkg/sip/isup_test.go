@@ -0,0 +1,102 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/sipgo/sip"
+)
+
+var (
+	testIAM = []byte{
+		0x01,       // IAM
+		0x00,       // nature of connection indicators
+		0x20, 0x01, // forward call indicators
+		0x0f, // calling party's category: payphone
+		0x00, // transmission medium requirement
+		0x02, // pointer to called party number
+		0x07, // pointer to optional part
+		// called party number: odd, national, "12345"
+		0x05, 0x83, 0x10, 0x21, 0x43, 0x05,
+		// original called number: even, international, "4420"
+		0x28, 0x04, 0x04, 0x10, 0x44, 0x02,
+		// redirecting number: presentation restricted
+		0x0b, 0x03, 0x03, 0x14, 0x21,
+		// calling party number: odd, international, "12345"
+		0x0a, 0x05, 0x84, 0x11, 0x21, 0x43, 0x05,
+		0x00, // end of optional parameters
+	}
+	testREL = []byte{
+		0x0c,             // REL
+		0x02,             // pointer to cause indicators
+		0x00,             // no optional part
+		0x02, 0x80, 0x91, // cause 17: user busy
+	}
+)
+
+func TestParseISUP(t *testing.T) {
+	m, err := ParseISUP(testIAM)
+	require.NoError(t, err)
+	require.Equal(t, &ISUPMessage{
+		Type:                 ISUPInitialAddress,
+		CallingPartyCategory: 0x0f,
+		CalledNumber:         "12345",
+		CallingNumber:        "+12345",
+		OriginalCalledNumber: "+4420",
+	}, m)
+	require.Equal(t, map[string]string{
+		AttrSIPISUPCallingPartyCategory: "payphone",
+		AttrSIPISUPOriginalCalledNumber: "+4420",
+	}, m.Attributes())
+
+	m, err = ParseISUP(testREL)
+	require.NoError(t, err)
+	require.Equal(t, ISUPRelease, m.Type)
+	require.Equal(t, &ISUPCause{Location: 0, Value: 17}, m.Cause)
+	require.Equal(t, map[string]string{AttrSIPISUPCause: "17"}, m.Attributes())
+
+	for _, data := range [][]byte{
+		nil,
+		{0x42},                   // unsupported type
+		testIAM[:6],              // too short
+		testIAM[:10],             // called number truncated
+		testIAM[:len(testIAM)-1], // not terminated
+		{0x0c, 0x02, 0x00, 0x01, 0x80},
+	} {
+		_, err = ParseISUP(data)
+		require.Error(t, err, "%x", data)
+	}
+}
+
+func TestISUPCause(t *testing.T) {
+	log := logger.GetLogger()
+	require.Equal(t, &ISUPCause{Value: 17}, isupCause(log, "application/ISUP;version=itu-t92+", testREL))
+	require.Nil(t, isupCause(log, "application/sdp", []byte(testSDP)))
+	require.Nil(t, isupCause(log, "", nil))
+
+	body := "--b\r\nContent-Type: application/isup; version=itu-t92+\r\n\r\n" + string(testREL) + "\r\n--b--\r\n"
+	require.Equal(t, &ISUPCause{Value: 17}, isupCause(log, "multipart/mixed;boundary=b", []byte(body)))
+
+	req := sip.NewRequest(sip.BYE, sip.Uri{User: "callee", Host: "example.com"})
+	req.AppendHeader(sip.NewHeader("Content-Type", "application/isup"))
+	req.SetBody(testREL)
+	state := NewCallState(nil, nil)
+	recordISUPCause(log, state, req)
+	require.Equal(t, "17", state.callInfo.ParticipantAttributes[AttrSIPISUPCause])
+}
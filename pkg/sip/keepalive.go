@@ -0,0 +1,111 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/sipgo/transport"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	// transportRetries is the number of times a request is re-sent after a transport error on TCP or TLS.
+	transportRetries = 3
+	// transportRetryDelay is the delay before the first retry. It doubles on each attempt.
+	transportRetryDelay = 250 * time.Millisecond
+)
+
+// crlfPing is a keep-alive for connection-oriented transports (RFC 5626, section 3.5.1).
+var crlfPing = []byte("\r\n\r\n")
+
+// isReliableTransport checks if the network uses persistent connections.
+func isReliableTransport(network string) bool {
+	switch Transport(strings.ToLower(network)) {
+	case TransportTCP, TransportTLS:
+		return true
+	}
+	return false
+}
+
+// isTransportError checks if the request failed because of a broken or refused connection.
+// Such requests never reached the remote, so it's safe to send them again.
+func isTransportError(err error) bool {
+	var nerr *net.OpError
+	return errors.As(err, &nerr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// retryTransport calls fn and retries it with backoff if it fails with a transport error on TCP or TLS.
+// The transport layer drops broken connections, so each retry dials a new one.
+func retryTransport(log logger.Logger, network string, fn func() error) error {
+	err := fn()
+	if err == nil || !isReliableTransport(network) {
+		return err
+	}
+	delay := transportRetryDelay
+	for i := 0; i < transportRetries && isTransportError(err); i++ {
+		log.Infow("sip connection broken, retrying request", "error", err, "transport", network, "attempt", i+1)
+		time.Sleep(delay)
+		delay *= 2
+		err = fn()
+	}
+	return err
+}
+
+// keepAliveConn periodically sends CRLF keep-alives over the TCP or TLS connection of the call,
+// until done is closed. If the connection is broken, it's closed, so that the next request reconnects.
+func keepAliveConn(done <-chan struct{}, log logger.Logger, tpl *transport.Layer, network, addr string, interval time.Duration) {
+	if interval == 0 {
+		interval = config.DefaultSIPKeepAliveInterval
+	}
+	if interval < 0 || tpl == nil || addr == "" || !isReliableTransport(network) {
+		return
+	}
+	log = log.WithValues("transport", network, "remote", addr)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		conn, err := tpl.GetConnection(network, addr)
+		if err != nil || conn == nil {
+			continue // already dropped, the next request reconnects
+		}
+		w, ok := conn.(io.Writer)
+		if !ok {
+			return
+		}
+		if _, err = w.Write(crlfPing); err != nil {
+			log.Warnw("sip connection keep-alive failed, closing connection", err)
+			_ = conn.Close()
+		}
+	}
+}
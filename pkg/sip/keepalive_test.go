@@ -0,0 +1,59 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryTransport(t *testing.T) {
+	log := logger.GetLogger()
+	connErr := fmt.Errorf("tcp dial err=%w", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})
+	require.True(t, isTransportError(connErr))
+	require.False(t, isTransportError(errors.New("transaction already exists")))
+
+	failing := func(n int, err error) (func() error, *int) {
+		calls := 0
+		return func() error {
+			calls++
+			if calls <= n {
+				return err
+			}
+			return nil
+		}, &calls
+	}
+
+	// Reconnects on TCP.
+	fn, calls := failing(1, connErr)
+	require.NoError(t, retryTransport(log, "TCP", fn))
+	require.Equal(t, 2, *calls)
+
+	// Not retried on UDP.
+	fn, calls = failing(1, connErr)
+	require.Error(t, retryTransport(log, "udp", fn))
+	require.Equal(t, 1, *calls)
+
+	// Not retried for other errors.
+	fn, calls = failing(1, errors.New("invalid request"))
+	require.Error(t, retryTransport(log, "tls", fn))
+	require.Equal(t, 1, *calls)
+}
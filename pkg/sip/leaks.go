@@ -0,0 +1,249 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+// Kinds of call resources checked by the leak watchdog.
+const (
+	leakGoroutine = "goroutine"
+	leakSocket    = "socket"
+	leakTimer     = "timer"
+)
+
+// callResources counts resources held by a call. Sockets and timers are acquired and released explicitly,
+// goroutines are found by their call_id pprof label (see MediaPort.goLabeled).
+// A nil callResources doesn't track anything.
+type callResources struct {
+	callID string
+
+	mu   sync.Mutex
+	held map[string]int
+}
+
+// Acquire marks a resource of the kind as held by the call. The returned function releases it, it may be called more than once.
+func (r *callResources) Acquire(kind string) func() {
+	if r == nil {
+		return func() {}
+	}
+	r.mu.Lock()
+	r.held[kind]++
+	r.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			r.held[kind]--
+			r.mu.Unlock()
+		})
+	}
+}
+
+// Held returns the number of resources of each kind which are not released yet.
+func (r *callResources) Held() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := maps.Clone(r.held)
+	maps.DeleteFunc(out, func(_ string, n int) bool {
+		return n == 0
+	})
+	return out
+}
+
+type pendingLeakCheck struct {
+	res      *callResources
+	deadline time.Time
+}
+
+// leakWatchdog verifies that calls release their resources within a timeout after they end.
+// Leaks at scale otherwise only show up as a gradual memory growth.
+type leakWatchdog struct {
+	log     logger.Logger
+	mon     *stats.Monitor
+	timeout time.Duration
+	// goroutines counts goroutines by call ID.
+	goroutines func() (map[string]int, error)
+	wake       chan struct{}
+	stop       core.Fuse
+
+	mu      sync.Mutex
+	pending []pendingLeakCheck // ordered by deadline
+}
+
+// newLeakWatchdog creates a watchdog from the config. It returns nil if the watchdog is disabled.
+func newLeakWatchdog(log logger.Logger, mon *stats.Monitor, conf *config.LeakWatchdogConfig) *leakWatchdog {
+	if conf == nil {
+		return nil
+	}
+	w := &leakWatchdog{
+		log:        log,
+		mon:        mon,
+		timeout:    conf.Timeout,
+		goroutines: labeledGoroutines,
+		wake:       make(chan struct{}, 1),
+	}
+	if w.timeout == 0 {
+		w.timeout = config.DefaultLeakTimeout
+	}
+	return w
+}
+
+// NewCall starts tracking resources of a call.
+func (w *leakWatchdog) NewCall(callID string) *callResources {
+	if w == nil || callID == "" {
+		return nil
+	}
+	return &callResources{callID: callID, held: make(map[string]int)}
+}
+
+// CallEnded schedules a check of resources of the call, once the timeout passes.
+func (w *leakWatchdog) CallEnded(res *callResources) {
+	if w == nil || res == nil {
+		return
+	}
+	w.mu.Lock()
+	w.pending = append(w.pending, pendingLeakCheck{res: res, deadline: time.Now().Add(w.timeout)})
+	first := len(w.pending) == 1
+	w.mu.Unlock()
+	if first {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *leakWatchdog) Start() {
+	if w == nil {
+		return
+	}
+	go w.run()
+}
+
+func (w *leakWatchdog) Stop() {
+	if w == nil {
+		return
+	}
+	w.stop.Break()
+}
+
+func (w *leakWatchdog) run() {
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	for {
+		timer.Reset(w.check(time.Now()))
+		select {
+		case <-w.stop.Watch():
+			return
+		case <-w.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// check reports leaks of calls which ended more than the timeout ago, and returns the time until the next check.
+func (w *leakWatchdog) check(now time.Time) time.Duration {
+	w.mu.Lock()
+	i := 0
+	for i < len(w.pending) && !w.pending[i].deadline.After(now) {
+		i++
+	}
+	due := w.pending[:i:i]
+	w.pending = w.pending[i:]
+	wait := w.timeout
+	if len(w.pending) != 0 {
+		wait = w.pending[0].deadline.Sub(now)
+	}
+	w.mu.Unlock()
+	if len(due) == 0 {
+		return wait
+	}
+	// A single goroutine dump is used for all calls which are due.
+	goroutines, err := w.goroutines()
+	if err != nil {
+		w.log.Warnw("cannot count goroutines of calls", err)
+	}
+	for _, c := range due {
+		leaks := c.res.Held()
+		if n := goroutines[c.res.callID]; n > 0 {
+			leaks[leakGoroutine] = n
+		}
+		if len(leaks) == 0 {
+			continue
+		}
+		for kind, n := range leaks {
+			w.mon.CallLeaks(kind, n)
+		}
+		w.log.Warnw("call resources not released", nil,
+			"callID", c.res.callID,
+			"timeout", w.timeout,
+			"goroutines", leaks[leakGoroutine],
+			"sockets", leaks[leakSocket],
+			"timers", leaks[leakTimer],
+		)
+	}
+	return wait
+}
+
+// labeledGoroutines counts goroutines by their call_id pprof label.
+func labeledGoroutines() (map[string]int, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil, err
+	}
+	return parseGoroutineLabels(buf.String()), nil
+}
+
+// parseGoroutineLabels counts goroutines by call_id label in a goroutine profile in the text format (debug=1).
+// The profile groups goroutines with the same stack and labels:
+//
+//	2 @ 0x49460a 0x46c357 0x101f366
+//	# labels: {"call_id":"SCL_xxx", "loop":"rtpReadLoop"}
+func parseGoroutineLabels(profile string) map[string]int {
+	out := make(map[string]int)
+	count := 0
+	for _, line := range strings.Split(profile, "\n") {
+		if n, _, ok := strings.Cut(line, " @ "); ok {
+			count, _ = strconv.Atoi(n)
+			continue
+		}
+		labels, ok := strings.CutPrefix(line, "# labels: ")
+		if !ok || count == 0 {
+			continue
+		}
+		var m map[string]string
+		if err := json.Unmarshal([]byte(labels), &m); err == nil && m["call_id"] != "" {
+			out[m["call_id"]] += count
+		}
+		count = 0
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestParseGoroutineLabels(t *testing.T) {
+	const profile = `goroutine profile: total 8
+2 @ 0x49460a 0x46c357 0x101f366
+# labels: {"call_id":"SCL_a", "codec":"PCMU/8000", "loop":"rtpReadLoop", "trunk_id":""}
+#	0x101f365	github.com/livekit/media-sdk/rtp.(*readStream).ReadRTP+0x385
+
+1 @ 0x49460a 0x46c357 0x10efa85
+# labels: {"call_id":"SCL_a", "codec":"", "loop":"timeoutLoop", "trunk_id":""}
+#	0x10efa84	github.com/livekit/sip/pkg/sip.(*MediaPort).timeoutLoop+0x1c4
+
+3 @ 0x49460a 0x46c357 0x10efa85
+# labels: {"call_id":"SCL_b", "loop":"timeoutLoop"}
+
+1 @ 0x49460a 0x46c357 0x10efa85
+# labels: {"call_id":"", "loop":"timeoutLoop"}
+
+1 @ 0x44f331 0x49337d
+#	0x64dd90	runtime/pprof.writeRuntimeProfile+0xb0
+`
+	require.Equal(t, map[string]int{"SCL_a": 3, "SCL_b": 3}, parseGoroutineLabels(profile))
+}
+
+func TestLeakWatchdog(t *testing.T) {
+	rec := newTestRecordLogger()
+	w := newLeakWatchdog(rec, nil, &config.LeakWatchdogConfig{Timeout: time.Second})
+	w.goroutines = func() (map[string]int, error) {
+		return map[string]int{"SCL_goroutines": 3}, nil
+	}
+	require.Nil(t, (*leakWatchdog)(nil).NewCall("SCL_nil"))
+	// Nil resources are not tracked.
+	(*callResources)(nil).Acquire(leakSocket)()
+
+	timers := w.NewCall("SCL_timers")
+	timers.Acquire(leakSocket)()
+	timers.Acquire(leakTimer)
+	clean := w.NewCall("SCL_clean")
+	release := clean.Acquire(leakSocket)
+	release()
+	release()
+	require.Empty(t, clean.Held())
+
+	start := time.Now()
+	w.CallEnded(timers)
+	w.CallEnded(w.NewCall("SCL_goroutines"))
+	w.CallEnded(clean)
+
+	wait := w.check(start)
+	require.InDelta(t, time.Second, wait, float64(100*time.Millisecond))
+	require.Empty(t, *rec.lines)
+
+	require.Equal(t, time.Second, w.check(start.Add(2*time.Second)))
+	lines := *rec.lines
+	require.Len(t, lines, 2)
+	require.Equal(t, "call resources not released", lines[0].msg)
+	require.Equal(t, []any{"callID", "SCL_timers", "timeout", time.Second, "goroutines", 0, "sockets", 0, "timers", 1}, lines[0].fields)
+	require.Equal(t, []any{"callID", "SCL_goroutines", "timeout", time.Second, "goroutines", 3, "sockets", 0, "timers", 0}, lines[1].fields)
+
+	// Calls are only checked once.
+	w.check(start.Add(3 * time.Second))
+	require.Len(t, *rec.lines, 2)
+}
+
+func TestMediaPortResources(t *testing.T) {
+	w := newLeakWatchdog(logger.GetLogger(), nil, &config.LeakWatchdogConfig{})
+	res := w.NewCall("SCL_resources")
+	c1, _ := newUDPPipe()
+	m, err := NewMediaPortWith(logger.GetLogger(), nil, c1, &MediaOptions{
+		IP:        newIP("1.1.1.1"),
+		CallID:    "SCL_resources",
+		resources: res,
+	}, 8000)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		g, err := labeledGoroutines()
+		require.NoError(t, err)
+		return g["SCL_resources"] == 1 && res.Held()[leakTimer] == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, 1, res.Held()[leakSocket])
+
+	m.Close()
+	require.Eventually(t, func() bool {
+		g, err := labeledGoroutines()
+		require.NoError(t, err)
+		return g["SCL_resources"] == 0 && len(res.Held()) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/livekit/sipgo"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// requestLimits are effective limits for inbound requests. Zero means no limit.
+type requestLimits struct {
+	maxSize      int
+	maxHeaders   int
+	maxHeaderLen int
+	maxURILen    int
+}
+
+func newRequestLimits(conf config.SIPLimitsConfig) requestLimits {
+	limit := func(v, def int) int {
+		if v == 0 {
+			return def
+		} else if v < 0 {
+			return 0
+		}
+		return v
+	}
+	return requestLimits{
+		maxSize:      limit(conf.MaxMessageSize, config.DefaultSIPMaxMessageSize),
+		maxHeaders:   limit(conf.MaxHeaders, config.DefaultSIPMaxHeaders),
+		maxHeaderLen: limit(conf.MaxHeaderLength, config.DefaultSIPMaxHeaderLength),
+		maxURILen:    limit(conf.MaxURILength, config.DefaultSIPMaxURILength),
+	}
+}
+
+// Check the request against limits. It returns a status code and a reason if the request must be rejected.
+func (l requestLimits) Check(req *sip.Request) (sip.StatusCode, string, bool) {
+	hdrs := req.Headers()
+	if l.maxHeaders > 0 && len(hdrs) > l.maxHeaders {
+		return sip.StatusBadRequest, "Too many headers", false
+	}
+	uri := len(req.Recipient.String())
+	if l.maxURILen > 0 && uri > l.maxURILen {
+		return sip.StatusRequestURITooLong, "Request-URI Too Long", false
+	}
+	size := len(req.Method) + uri + len(req.SipVersion) + 4 // request line
+	for _, h := range hdrs {
+		n := len(h.Value())
+		if l.maxHeaderLen > 0 && n > l.maxHeaderLen {
+			return sip.StatusBadRequest, "Header too long", false
+		}
+		size += len(h.Name()) + n + 4
+	}
+	size += len(req.Body()) + 2
+	if l.maxSize > 0 && size > l.maxSize {
+		return sip.StatusRequestEntityTooLarge, "Request too large", false
+	}
+	return 0, "", true
+}
+
+// limitRequests wraps the handler and rejects requests that exceed configured limits or are malformed.
+// Requests that pass are not modified, since From and To must be echoed back as received. Values copied from them
+// into call info, logs and attributes are sanitized instead, see sanitizeUserURI.
+//
+// Limits are only checked after sipgo has read and parsed the message, so they filter requests passed to handlers,
+// but don't bound memory used by the transport. Reads are bounded by sipgo's transport buffer size instead.
+func (s *Server) limitRequests(h sipgo.RequestHandler) sipgo.RequestHandler {
+	return func(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
+		if code, reason, ok := s.limits.Check(req); !ok {
+			s.log.Debugw("rejecting request over limits", "method", req.Method, "fromIP", req.Source(), "status", int(code), "reason", reason)
+			if req.Method != sip.ACK {
+				_ = tx.Respond(sip.NewResponseFromRequest(req, code, reason, nil))
+			}
+			return
+		}
+		if code, reason, ok := checkRequest(req); !ok {
+			s.log.Debugw("rejecting malformed request", "method", req.Method, "fromIP", req.Source(), "status", int(code), "reason", reason)
+			if req.Method != sip.ACK {
+				_ = tx.Respond(sip.NewResponseFromRequest(req, code, reason, nil))
+			}
+			return
+		}
+		h(log, req, tx)
+	}
+}
+
+// sanitizeUserURI returns a copy of the URI with the user unescaped and sanitized.
+// It must only be used for values passed to LiveKit or logs, never for headers sent back to the peer.
+func sanitizeUserURI(u sip.Uri) sip.Uri {
+	u = *u.Clone()
+	u.User = sanitizeValue(unescapeUser(u.User))
+	return u
+}
+
+// sanitizeValue replaces malformed UTF-8 and strips control characters from values received from SIP,
+// so that they can be safely logged and used in participant attributes.
+func sanitizeValue(s string) string {
+	if utf8.ValidString(s) && strings.IndexFunc(s, isUnsafeRune) < 0 {
+		return s
+	}
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	return strings.Map(func(r rune) rune {
+		if isUnsafeRune(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func isUnsafeRune(r rune) bool {
+	return r != '\t' && unicode.IsControl(r)
+}
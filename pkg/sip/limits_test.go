@@ -0,0 +1,101 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/livekit/sipgo/sip"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestRequestLimits(t *testing.T) {
+	newReq := func(hdrs int, value string, body int) *sip.Request {
+		req := sip.NewRequest(sip.INVITE, sip.Uri{User: "bob", Host: "example.com"})
+		for range hdrs {
+			req.AppendHeader(sip.NewHeader("X-Test", value))
+		}
+		req.SetBody(make([]byte, body))
+		return req
+	}
+	l := newRequestLimits(config.SIPLimitsConfig{})
+
+	_, _, ok := l.Check(newReq(10, "value", 1000))
+	require.True(t, ok)
+
+	code, _, ok := l.Check(newReq(1000, "value", 0))
+	require.False(t, ok)
+	require.Equal(t, sip.StatusBadRequest, code)
+
+	code, _, ok = l.Check(newReq(1, strings.Repeat("a", 5000), 0))
+	require.False(t, ok)
+	require.Equal(t, sip.StatusBadRequest, code)
+
+	code, _, ok = l.Check(newReq(1, "value", 100*1024))
+	require.False(t, ok)
+	require.Equal(t, sip.StatusRequestEntityTooLarge, code)
+
+	// Limits can be disabled.
+	l = newRequestLimits(config.SIPLimitsConfig{MaxMessageSize: -1, MaxHeaders: -1, MaxHeaderLength: -1})
+	_, _, ok = l.Check(newReq(1000, strings.Repeat("a", 5000), 100*1024))
+	require.True(t, ok)
+}
+
+func TestSanitizeValue(t *testing.T) {
+	require.Equal(t, "Bob Smith", sanitizeValue("Bob Smith"))
+	require.Equal(t, "Zoë\t1", sanitizeValue("Zoë\t1"))
+	require.Equal(t, "bad�value", sanitizeValue("bad\xffvalue"))
+	require.Equal(t, "newline", sanitizeValue("new\r\nline\x00"))
+}
+
+func TestLimitRequestsSanitize(t *testing.T) {
+	uri := sip.Uri{Scheme: "sip", User: "%2B1000", Host: "example.com"}
+	req := sip.NewRequest(sip.INVITE, uri)
+	req.AppendHeader(&sip.ViaHeader{ProtocolName: "SIP", ProtocolVersion: "2.0", Transport: "UDP", Host: "192.0.2.2", Port: 5060, Params: sip.NewParams()})
+	from := &sip.FromHeader{DisplayName: "Bob\x00", Address: sip.Uri{Scheme: "sip", User: "%2B2000", Host: "example.com"}, Params: sip.NewParams()}
+	from.Params.Add("tag", "abc")
+	req.AppendHeader(from)
+	to := &sip.ToHeader{Address: uri, Params: sip.NewParams()}
+	req.AppendHeader(to)
+	callID := sip.CallIDHeader("sanitize-1")
+	req.AppendHeader(&callID)
+	req.AppendHeader(&sip.CSeqHeader{SeqNo: 1, MethodName: sip.INVITE})
+
+	s := &Server{limits: newRequestLimits(config.SIPLimitsConfig{})}
+	var got *sip.Request
+	s.limitRequests(func(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
+		got = req
+	})(nil, req, nil)
+	require.NotNil(t, got)
+
+	// The request is passed as received, so From and To are echoed back verbatim.
+	require.Equal(t, "%2B1000", got.Recipient.User)
+	require.Same(t, from, got.From())
+	require.Same(t, to, got.To())
+	require.Equal(t, "Bob\x00", got.From().DisplayName)
+	require.Equal(t, "%2B2000", got.From().Address.User)
+
+	// Values exposed to LiveKit and logs are unescaped and sanitized.
+	cc := &sipInbound{invite: got, from: got.From(), to: got.To()}
+	require.Equal(t, "+1000", cc.Address().User)
+	require.Equal(t, "+2000", cc.From().User)
+	require.Equal(t, "+1000", cc.To().User)
+	require.Equal(t, "%2B2000", from.Address.User)
+	require.Equal(t, "%2B1000", to.Address.User)
+}
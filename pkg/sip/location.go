@@ -0,0 +1,165 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	headerGeolocation        = "Geolocation"
+	headerGeolocationRouting = "Geolocation-Routing"
+
+	contentTypePIDF = "application/pidf+xml"
+
+	// maxLocationPIDF limits the size of PIDF-LO documents supplied with the call request.
+	maxLocationPIDF = 16 << 10
+)
+
+// requestLocation returns the caller location supplied in the outbound call request attributes, or nil if it's not set.
+// See AttrSIPLocationPIDF, AttrSIPLocationURI and AttrSIPLocationRouting.
+func requestLocation(attrs map[string]string) (*config.LocationConfig, error) {
+	conf := &config.LocationConfig{
+		PIDF: attrs[AttrSIPLocationPIDF],
+		URI:  attrs[AttrSIPLocationURI],
+	}
+	if conf.PIDF == "" && conf.URI == "" {
+		return nil, nil
+	}
+	if v := attrs[AttrSIPLocationRouting]; v != "" {
+		routing, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "invalid location routing %q", v)
+		}
+		conf.Routing = routing
+	}
+	if len(conf.PIDF) > maxLocationPIDF {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "location pidf is too large")
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	if conf.PIDF != "" {
+		if err := validatePIDF(conf.PIDF); err != nil {
+			return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+		}
+	}
+	return conf, nil
+}
+
+// validatePIDF checks that the document is well-formed XML with a PIDF presence root element (RFC 3863).
+func validatePIDF(doc string) error {
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	root := false
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return errors.New("location pidf is not valid xml: " + err.Error())
+		}
+		if el, ok := tok.(xml.StartElement); ok && !root {
+			root = true
+			if el.Name.Local != "presence" {
+				return errors.New("location pidf must have a presence root element")
+			}
+		}
+	}
+	if !root {
+		return errors.New("location pidf is empty")
+	}
+	return nil
+}
+
+// callLocation conveys the caller location in the INVITE, see RFC 6442.
+// The location is either sent by value, as a PIDF-LO body part, or by reference, as a URI in the Geolocation header.
+type callLocation struct {
+	pidf    []byte
+	cid     string // Content-ID of the PIDF-LO body part
+	uri     string
+	routing bool
+}
+
+func newCallLocation(conf *config.LocationConfig, host string) *callLocation {
+	if conf == nil {
+		return nil
+	}
+	l := &callLocation{uri: conf.URI, routing: conf.Routing}
+	if conf.PIDF != "" {
+		l.pidf = []byte(conf.PIDF)
+		l.cid = guid.HashedID(conf.PIDF) + "@" + host
+	}
+	return l
+}
+
+// Headers returns Geolocation headers for the INVITE.
+func (l *callLocation) Headers() map[string]string {
+	if l == nil {
+		return nil
+	}
+	var refs []string
+	if l.cid != "" {
+		refs = append(refs, "<cid:"+l.cid+">")
+	}
+	if l.uri != "" {
+		refs = append(refs, "<"+l.uri+">")
+	}
+	h := map[string]string{headerGeolocation: strings.Join(refs, ", ")}
+	if l.routing {
+		h[headerGeolocationRouting] = "yes"
+	} else {
+		h[headerGeolocationRouting] = "no"
+	}
+	return h
+}
+
+// Size returns an estimate of the bytes added to the INVITE body.
+func (l *callLocation) Size() int {
+	if l == nil || l.pidf == nil {
+		return 0
+	}
+	return len(l.pidf) + 256
+}
+
+// Body returns the INVITE body and its content type. If the location is sent by value,
+// SDP offer and PIDF-LO are combined into a multipart body. Otherwise, the offer is returned as is.
+func (l *callLocation) Body(sdpOffer []byte) ([]byte, string) {
+	if l == nil || l.pidf == nil {
+		return sdpOffer, "application/sdp"
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, _ := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/sdp"}})
+	_, _ = part.Write(sdpOffer)
+	part, _ = w.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {contentTypePIDF},
+		"Content-ID":   {"<" + l.cid + ">"},
+	})
+	_, _ = part.Write(l.pidf)
+	_ = w.Close()
+	return buf.Bytes(), "multipart/mixed;boundary=" + w.Boundary()
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const testPIDF = `<?xml version="1.0"?><presence xmlns="urn:ietf:params:xml:ns:pidf" entity="pres:caller@example.com"/>`
+
+func TestRequestLocation(t *testing.T) {
+	conf, err := requestLocation(map[string]string{"other": "1"})
+	require.NoError(t, err)
+	require.Nil(t, conf)
+
+	conf, err = requestLocation(map[string]string{
+		AttrSIPLocationPIDF:    testPIDF,
+		AttrSIPLocationURI:     "https://lis.example.com/1",
+		AttrSIPLocationRouting: "true",
+	})
+	require.NoError(t, err)
+	require.Equal(t, &config.LocationConfig{PIDF: testPIDF, URI: "https://lis.example.com/1", Routing: true}, conf)
+
+	for _, attrs := range []map[string]string{
+		{AttrSIPLocationPIDF: "not xml"},
+		{AttrSIPLocationPIDF: "<presence>"},
+		{AttrSIPLocationPIDF: "<location/>"},
+		{AttrSIPLocationURI: "<https://lis.example.com/1>"},
+		{AttrSIPLocationURI: "https://lis.example.com/1", AttrSIPLocationRouting: "maybe"},
+	} {
+		_, err = requestLocation(attrs)
+		require.Error(t, err, "%v", attrs)
+	}
+}
+
+func TestCallLocation(t *testing.T) {
+	var loc *callLocation
+	body, ctype := loc.Body([]byte("v=0"))
+	require.Equal(t, "v=0", string(body))
+	require.Equal(t, "application/sdp", ctype)
+	require.Nil(t, newCallLocation(nil, "example.com"))
+
+	loc = newCallLocation(&config.LocationConfig{URI: "https://lis.example.com/1"}, "example.com")
+	require.Equal(t, map[string]string{
+		"Geolocation":         "<https://lis.example.com/1>",
+		"Geolocation-Routing": "no",
+	}, loc.Headers())
+	body, ctype = loc.Body([]byte("v=0"))
+	require.Equal(t, "v=0", string(body))
+	require.Equal(t, "application/sdp", ctype)
+
+	loc = newCallLocation(&config.LocationConfig{PIDF: testPIDF, Routing: true}, "example.com")
+	h := loc.Headers()
+	require.Equal(t, "yes", h["Geolocation-Routing"])
+	require.Equal(t, "<cid:"+loc.cid+">", h["Geolocation"])
+	require.Contains(t, loc.cid, "@example.com")
+
+	body, ctype = loc.Body([]byte("v=0"))
+	require.Greater(t, loc.Size(), 0)
+	mt, params, err := mime.ParseMediaType(ctype)
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mt)
+	r := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+
+	part, err := r.NextPart()
+	require.NoError(t, err)
+	require.Equal(t, "application/sdp", part.Header.Get("Content-Type"))
+	data, err := io.ReadAll(part)
+	require.NoError(t, err)
+	require.Equal(t, "v=0", string(data))
+
+	part, err = r.NextPart()
+	require.NoError(t, err)
+	require.Equal(t, "application/pidf+xml", part.Header.Get("Content-Type"))
+	require.Equal(t, "<"+loc.cid+">", part.Header.Get("Content-ID"))
+	data, err = io.ReadAll(part)
+	require.NoError(t, err)
+	require.Equal(t, testPIDF, string(data))
+
+	_, err = r.NextPart()
+	require.ErrorIs(t, err, io.EOF)
+}
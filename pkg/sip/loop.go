@@ -0,0 +1,172 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"sync"
+
+	"github.com/livekit/sipgo/sip"
+
+	siperrors "github.com/livekit/sip/pkg/errors"
+)
+
+// inviteLoopKind is the way an INVITE which looped back to this node was detected.
+type inviteLoopKind string
+
+const (
+	// inviteLoopVia has a Via header added by this node to a pending outbound INVITE.
+	inviteLoopVia = inviteLoopKind("via")
+	// inviteLoopCallID has the Call-ID of a pending outbound INVITE.
+	inviteLoopCallID = inviteLoopKind("call_id")
+	// inviteLoopMaxForwards has no hops left (RFC 3261, 8.2.2).
+	inviteLoopMaxForwards = inviteLoopKind("max_forwards")
+	// inviteSpiral is a pending outbound INVITE which came back with a different Request-URI,
+	// for example after the number was translated by the carrier. It's not a loop and is accepted.
+	inviteSpiral = inviteLoopKind("spiral")
+)
+
+// outboundInvite is an outbound INVITE which waits for a final response.
+type outboundInvite struct {
+	callID string
+	branch string
+	user   string // user of the Request-URI
+}
+
+// loopDetector tracks pending outbound INVITEs, to recognize them if a misconfigured route sends them back to us.
+//
+// Outbound INVITEs are only tracked until they get a final response: a looped INVITE arrives while
+// the original is still pending, since the original can't be answered before the looped one is.
+type loopDetector struct {
+	mu       sync.Mutex
+	byBranch map[string]*outboundInvite
+	byCallID map[string]*outboundInvite
+}
+
+func newLoopDetector() *loopDetector {
+	return &loopDetector{
+		byBranch: make(map[string]*outboundInvite),
+		byCallID: make(map[string]*outboundInvite),
+	}
+}
+
+// Add starts tracking an outbound INVITE. It must be called after the Via header is added to the request.
+func (d *loopDetector) Add(req *sip.Request) *outboundInvite {
+	if d == nil {
+		return nil
+	}
+	inv := &outboundInvite{branch: viaBranch(req), user: req.Recipient.User}
+	if h := req.CallID(); h != nil {
+		inv.callID = h.Value()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if inv.branch != "" {
+		d.byBranch[inv.branch] = inv
+	}
+	if inv.callID != "" {
+		d.byCallID[inv.callID] = inv
+	}
+	return inv
+}
+
+// Remove stops tracking an outbound INVITE once it gets a final response.
+func (d *loopDetector) Remove(inv *outboundInvite) {
+	if d == nil || inv == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.byBranch[inv.branch] == inv {
+		delete(d.byBranch, inv.branch)
+	}
+	if d.byCallID[inv.callID] == inv {
+		delete(d.byCallID, inv.callID)
+	}
+}
+
+// Len returns the number of tracked outbound INVITEs.
+func (d *loopDetector) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.byCallID)
+}
+
+// Check tells if an inbound INVITE looped back to this node. It returns an empty kind for regular calls.
+func (d *loopDetector) Check(req *sip.Request) inviteLoopKind {
+	if mf := req.MaxForwards(); mf != nil && mf.Val() == 0 {
+		return inviteLoopMaxForwards
+	}
+	if d == nil {
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	kind := inviteLoopVia
+	var inv *outboundInvite
+	for _, h := range req.GetHeaders("Via") {
+		via, ok := h.(*sip.ViaHeader)
+		if !ok {
+			continue
+		}
+		if branch, _ := via.Params.Get("branch"); branch != "" {
+			if inv = d.byBranch[branch]; inv != nil {
+				break
+			}
+		}
+	}
+	if inv == nil {
+		kind = inviteLoopCallID
+		if h := req.CallID(); h != nil {
+			inv = d.byCallID[h.Value()]
+		}
+	}
+	switch {
+	case inv == nil:
+		return ""
+	case inv.user != req.Recipient.User:
+		return inviteSpiral
+	default:
+		return kind
+	}
+}
+
+// checkInviteLoop rejects an inbound INVITE which looped back to this node, before it consumes a room or a trunk channel.
+func (s *Server) checkInviteLoop(req *sip.Request, tx sip.ServerTransaction) error {
+	kind := s.loops.Check(req)
+	if kind == "" {
+		return nil
+	}
+	s.mon.InviteLoop(string(kind))
+	log := s.log.WithValues(
+		"kind", kind,
+		"toUser", sanitizeValue(req.Recipient.User),
+		"fromIP", req.Source(),
+	)
+	if h := req.CallID(); h != nil {
+		log = log.WithValues("sipCallID", h.Value())
+	}
+	switch kind {
+	case inviteSpiral:
+		log.Infow("outbound invite spiraled back with a different destination")
+		return nil
+	case inviteLoopMaxForwards:
+		log.Warnw("rejecting invite with no hops left", nil)
+		_ = tx.Respond(sip.NewResponseFromRequest(req, sip.StatusTooManyHops, "Too Many Hops", nil))
+	default:
+		log.Warnw("rejecting invite which looped back to this node, check the routes of outbound trunks", nil)
+		_ = tx.Respond(sip.NewResponseFromRequest(req, sip.StatusLoopDetected, "Loop Detected", nil))
+	}
+	return siperrors.ErrCallLoop
+}
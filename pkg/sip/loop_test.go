@@ -0,0 +1,94 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/sipgo/sip"
+
+	siperrors "github.com/livekit/sip/pkg/errors"
+)
+
+func TestLoopDetector(t *testing.T) {
+	d := newLoopDetector()
+
+	out := newDedupInvite("out-1", 1, "z9hG4bK-ours", 5060)
+	inv := d.Add(out)
+	require.Equal(t, 1, d.Len())
+
+	// Regular inbound call.
+	require.Empty(t, d.Check(newDedupInvite("in-1", 1, "z9hG4bK-theirs", 5060)))
+
+	// Routed back through a proxy, which adds its own Via on top of ours.
+	looped := newDedupInvite("in-2", 1, "z9hG4bK-proxy", 5060)
+	via := out.Via().Clone()
+	looped.AppendHeader(via)
+	require.Equal(t, inviteLoopVia, d.Check(looped))
+
+	// Sent straight back, with a new Via, but the same Call-ID.
+	require.Equal(t, inviteLoopCallID, d.Check(newDedupInvite("out-1", 1, "z9hG4bK-new", 5060)))
+
+	// Same request, but for a different number.
+	spiral := newDedupInvite("out-1", 1, "z9hG4bK-new", 5060)
+	spiral.Recipient.User = "carol"
+	require.Equal(t, inviteSpiral, d.Check(spiral))
+
+	// No hops left, regardless of the origin.
+	mf := sip.MaxForwardsHeader(0)
+	hops := newDedupInvite("in-3", 1, "z9hG4bK-theirs", 5060)
+	hops.AppendHeader(&mf)
+	require.Equal(t, inviteLoopMaxForwards, d.Check(hops))
+	var nilLoops *loopDetector
+	require.Equal(t, inviteLoopMaxForwards, nilLoops.Check(hops))
+	require.Empty(t, nilLoops.Check(looped))
+
+	// Once the outbound INVITE is answered, it's no longer tracked.
+	d.Remove(inv)
+	require.Zero(t, d.Len())
+	require.Empty(t, d.Check(looped))
+}
+
+func TestCheckInviteLoop(t *testing.T) {
+	s := &Server{log: logger.GetLogger(), loops: newLoopDetector()}
+	s.loops.Add(newDedupInvite("out-1", 1, "z9hG4bK-ours", 5060))
+
+	tx := &testServerTx{}
+	require.NoError(t, s.checkInviteLoop(newDedupInvite("in-1", 1, "z9hG4bK-theirs", 5060), tx))
+	require.Empty(t, tx.responses)
+
+	tx = &testServerTx{}
+	err := s.checkInviteLoop(newDedupInvite("out-1", 1, "z9hG4bK-new", 5060), tx)
+	require.ErrorIs(t, err, siperrors.ErrCallLoop)
+	require.Len(t, tx.responses, 1)
+	require.Equal(t, sip.StatusLoopDetected, tx.responses[0].StatusCode)
+
+	tx = &testServerTx{}
+	spiral := newDedupInvite("out-1", 1, "z9hG4bK-new", 5060)
+	spiral.Recipient.User = "carol"
+	require.NoError(t, s.checkInviteLoop(spiral, tx))
+	require.Empty(t, tx.responses)
+
+	tx = &testServerTx{}
+	mf := sip.MaxForwardsHeader(0)
+	hops := newDedupInvite("in-2", 1, "z9hG4bK-theirs", 5060)
+	hops.AppendHeader(&mf)
+	require.ErrorIs(t, s.checkInviteLoop(hops, tx), siperrors.ErrCallLoop)
+	require.Len(t, tx.responses, 1)
+	require.Equal(t, sip.StatusTooManyHops, tx.responses[0].StatusCode)
+}
@@ -47,6 +47,7 @@ type PortStatsSnapshot struct {
 	Packets        uint64 `json:"packets"`
 	IgnoredPackets uint64 `json:"packets_ignored"`
 	InputPackets   uint64 `json:"packets_input"`
+	SentPackets    uint64 `json:"packets_sent"`
 
 	MuxPackets uint64 `json:"mux_packets"`
 	MuxBytes   uint64 `json:"mux_bytes"`
@@ -56,6 +57,41 @@ type PortStatsSnapshot struct {
 
 	DTMFPackets uint64 `json:"dtmf_packets"`
 	DTMFBytes   uint64 `json:"dtmf_bytes"`
+
+	ComfortNoisePackets uint64 `json:"cn_packets"`
+	KeepAlivePackets    uint64 `json:"keep_alive_packets"`
+	RTCPPackets         uint64 `json:"rtcp_packets"`
+	ZRTPPackets         uint64 `json:"zrtp_packets"`
+
+	MediaTimeouts uint64 `json:"media_timeouts"`
+
+	SeqGaps          uint64 `json:"seq_gaps"`
+	DuplicatePackets uint64 `json:"duplicate_packets"`
+	ReorderedPackets uint64 `json:"reordered_packets"`
+	LatePackets      uint64 `json:"late_packets"`
+	Discontinuities  uint64 `json:"discontinuities"`
+
+	ClockDrift   int64 `json:"clock_drift_ppm"`
+	DriftSamples int64 `json:"drift_samples"`
+
+	PacedPackets uint64 `json:"paced_packets"`
+	PacerDrops   uint64 `json:"pacer_drops"`
+
+	SilenceFrames uint64 `json:"silence_frames"`
+	GatedFrames   uint64 `json:"gated_frames"`
+
+	ImpairedDrops      uint64 `json:"impaired_drops"`
+	ImpairedDuplicates uint64 `json:"impaired_duplicates"`
+	ImpairedReorders   uint64 `json:"impaired_reorders"`
+
+	StreamDrops uint64 `json:"stream_drops"`
+
+	SRTPAuthFailures uint64 `json:"srtp_auth_failures"`
+	SRTPReplays      uint64 `json:"srtp_replays"`
+
+	ICMPErrors uint64 `json:"icmp_errors"`
+
+	OneWayAudio uint64 `json:"one_way_audio"`
 }
 
 type RoomStatsSnapshot struct {
@@ -99,12 +135,48 @@ func (s *Stats) Load() StatsSnapshot {
 			Packets:        p.Packets.Load(),
 			IgnoredPackets: p.IgnoredPackets.Load(),
 			InputPackets:   p.InputPackets.Load(),
+			SentPackets:    p.SentPackets.Load(),
 			MuxPackets:     p.MuxPackets.Load(),
 			MuxBytes:       p.MuxBytes.Load(),
 			AudioPackets:   p.AudioPackets.Load(),
 			AudioBytes:     p.AudioBytes.Load(),
 			DTMFPackets:    p.DTMFPackets.Load(),
 			DTMFBytes:      p.DTMFBytes.Load(),
+
+			ComfortNoisePackets: p.ComfortNoisePackets.Load(),
+			KeepAlivePackets:    p.KeepAlivePackets.Load(),
+			RTCPPackets:         p.RTCPPackets.Load(),
+			ZRTPPackets:         p.ZRTPPackets.Load(),
+
+			MediaTimeouts: p.MediaTimeouts.Load(),
+
+			SeqGaps:          p.SeqGaps.Load(),
+			DuplicatePackets: p.DuplicatePackets.Load(),
+			ReorderedPackets: p.ReorderedPackets.Load(),
+			LatePackets:      p.LatePackets.Load(),
+			Discontinuities:  p.Discontinuities.Load(),
+
+			ClockDrift:   p.ClockDrift.Load(),
+			DriftSamples: p.DriftSamples.Load(),
+
+			PacedPackets: p.PacedPackets.Load(),
+			PacerDrops:   p.PacerDrops.Load(),
+
+			SilenceFrames: p.SilenceFrames.Load(),
+			GatedFrames:   p.GatedFrames.Load(),
+
+			ImpairedDrops:      p.ImpairedDrops.Load(),
+			ImpairedDuplicates: p.ImpairedDuplicates.Load(),
+			ImpairedReorders:   p.ImpairedReorders.Load(),
+
+			StreamDrops: p.StreamDrops.Load(),
+
+			SRTPAuthFailures: p.SRTPAuthFailures.Load(),
+			SRTPReplays:      p.SRTPReplays.Load(),
+
+			ICMPErrors: p.ICMPErrors.Load(),
+
+			OneWayAudio: p.OneWayAudio.Load(),
 		},
 		Room: RoomStatsSnapshot{
 			InputPackets:  r.InputPackets.Load(),
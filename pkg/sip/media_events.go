@@ -0,0 +1,191 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"net/netip"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// MediaState is a coarse state of the media stream, suitable for participant attributes.
+type MediaState int
+
+const (
+	// MediaStateNone means no media was received yet.
+	MediaStateNone = MediaState(iota)
+	// MediaStateOK means media is flowing.
+	MediaStateOK
+	// MediaStateDegraded means media timeout was detected, but the call is kept (see MediaOptions.MediaTimeoutWarnOnly).
+	MediaStateDegraded
+	// MediaStateLost means media timed out and the call is going to be terminated.
+	MediaStateLost
+)
+
+func (s MediaState) String() string {
+	switch s {
+	case MediaStateNone:
+		return ""
+	case MediaStateOK:
+		return "ok"
+	case MediaStateDegraded:
+		return "degraded"
+	case MediaStateLost:
+		return "lost"
+	}
+	return strconv.Itoa(int(s))
+}
+
+type MediaEventType int
+
+const (
+	// MediaEventReceived is emitted once, when the first RTP stream is accepted.
+	MediaEventReceived = MediaEventType(iota)
+	// MediaEventDegraded is emitted when media timeout is detected in warn-only mode.
+	MediaEventDegraded
+	// MediaEventRestored is emitted when media is received again after MediaEventDegraded.
+	MediaEventRestored
+	// MediaEventTimeout is emitted right before Timeout channel is closed.
+	MediaEventTimeout
+	// MediaEventSourceChanged is emitted when media is received from a new address.
+	MediaEventSourceChanged
+	// MediaEventDestChanged is emitted when media destination address is set or changed.
+	MediaEventDestChanged
+	// MediaEventKeyExpiring is emitted when the remote SRTP key is close to the end of its lifetime.
+	// The call should renegotiate the keys with a re-INVITE.
+	MediaEventKeyExpiring
+	// MediaEventZRTP is emitted once, when the remote attempts ZRTP negotiation (in SDP or in-band).
+	MediaEventZRTP
+	// MediaEventHold is emitted when the remote puts the media on hold (sendonly, inactive or 0.0.0.0 address).
+	MediaEventHold
+	// MediaEventResume is emitted when the remote resumes the media after MediaEventHold.
+	MediaEventResume
+	// MediaEventSRTPFailures is emitted once, when the number of SRTP packets failing authentication
+	// without a pause reaches MediaOptions.SRTPMaxFailures.
+	MediaEventSRTPFailures
+	// MediaEventOneWay is emitted once, when audio flows in only one direction after the call is answered.
+	// See MediaOptions.OneWayTimeout.
+	MediaEventOneWay
+	// MediaEventUnreachable is emitted each time the number of ICMP errors for RTP sent to the remote
+	// reaches MediaOptions.ICMPMaxErrors. Addr is the media destination.
+	MediaEventUnreachable
+)
+
+func (t MediaEventType) String() string {
+	switch t {
+	case MediaEventReceived:
+		return "received"
+	case MediaEventDegraded:
+		return "degraded"
+	case MediaEventRestored:
+		return "restored"
+	case MediaEventTimeout:
+		return "timeout"
+	case MediaEventSourceChanged:
+		return "source-changed"
+	case MediaEventDestChanged:
+		return "dest-changed"
+	case MediaEventKeyExpiring:
+		return "key-expiring"
+	case MediaEventZRTP:
+		return "zrtp"
+	case MediaEventHold:
+		return "hold"
+	case MediaEventResume:
+		return "resume"
+	case MediaEventSRTPFailures:
+		return "srtp-failures"
+	case MediaEventOneWay:
+		return "one-way"
+	case MediaEventUnreachable:
+		return "unreachable"
+	}
+	return strconv.Itoa(int(t))
+}
+
+// MediaEvent describes a media state transition on MediaPort.
+type MediaEvent struct {
+	Type MediaEventType
+	// State is the media state after this event.
+	State MediaState
+	// Addr is set for source and destination change and unreachable events.
+	Addr netip.AddrPort
+	// Reason is set for timeout and degraded events.
+	Reason MediaTimeoutReason
+	// OneWay is set for one-way audio events.
+	OneWay OneWayAudio
+}
+
+type mediaEventHandler struct {
+	fnc func(ev MediaEvent)
+}
+
+// mediaEvents is a registry of media event handlers.
+type mediaEvents struct {
+	mu       sync.Mutex
+	state    MediaState
+	handlers []*mediaEventHandler
+}
+
+func (e *mediaEvents) State() MediaState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state
+}
+
+// OnEvent registers an event handler and returns a function that removes it.
+func (e *mediaEvents) OnEvent(fnc func(ev MediaEvent)) func() {
+	h := &mediaEventHandler{fnc: fnc}
+	e.mu.Lock()
+	e.handlers = append(e.handlers, h)
+	e.mu.Unlock()
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if i := slices.Index(e.handlers, h); i >= 0 {
+			e.handlers = slices.Delete(e.handlers, i, i+1)
+		}
+	}
+}
+
+// emit updates the state (if set in the event) and notifies all handlers.
+func (e *mediaEvents) emit(ev MediaEvent) {
+	e.mu.Lock()
+	if ev.State != MediaStateNone {
+		e.state = ev.State
+	} else {
+		ev.State = e.state
+	}
+	handlers := slices.Clone(e.handlers)
+	e.mu.Unlock()
+	for _, h := range handlers {
+		h.fnc(ev)
+	}
+}
+
+// mediaStateAttrs returns participant attributes for a media event, or nil if the event doesn't change media state.
+func mediaStateAttrs(ev MediaEvent) map[string]string {
+	switch ev.Type {
+	default:
+		return nil
+	case MediaEventZRTP:
+		return map[string]string{AttrSIPZRTP: "true"}
+	case MediaEventOneWay:
+		return map[string]string{AttrSIPOneWayAudio: ev.OneWay.String()}
+	case MediaEventReceived, MediaEventDegraded, MediaEventRestored, MediaEventTimeout:
+	}
+	return map[string]string{AttrSIPMediaState: ev.State.String()}
+}
@@ -15,6 +15,9 @@
 package sip
 
 import (
+	"fmt"
+	"os"
+
 	msdk "github.com/livekit/media-sdk"
 
 	"github.com/livekit/sip/res"
@@ -24,6 +27,8 @@ type mediaRes struct {
 	enterPin []msdk.PCM16Sample
 	roomJoin []msdk.PCM16Sample
 	wrongPin []msdk.PCM16Sample
+	// announcements for unmatched calls and dispatch rules, and busy queue music, by file path
+	announcements map[string][]msdk.PCM16Sample
 }
 
 func (s *Server) initMediaRes() {
@@ -31,3 +36,43 @@ func (s *Server) initMediaRes() {
 	s.res.roomJoin = res.ReadOggAudioFile(res.RoomJoinOgg)
 	s.res.wrongPin = res.ReadOggAudioFile(res.WrongPinOgg)
 }
+
+// loadAnnouncements reads announcement files configured for unmatched calls and dispatch rules, and busy queue music.
+func (s *Server) loadAnnouncements() error {
+	files := []string{s.conf.UnmatchedCall.AnnouncementFile}
+	for _, t := range s.conf.Trunks {
+		if t != nil && t.UnmatchedCall != nil {
+			files = append(files, t.UnmatchedCall.AnnouncementFile)
+		}
+	}
+	for _, r := range s.conf.DispatchRules {
+		if r != nil && r.Announce != nil {
+			files = append(files, r.Announce.File)
+		}
+		if r != nil && r.Busy != nil {
+			files = append(files, r.Busy.MusicFile)
+		}
+		if r != nil && r.Queue != nil {
+			files = append(files, r.Queue.MusicFile)
+		}
+	}
+	s.res.announcements = make(map[string][]msdk.PCM16Sample)
+	for _, path := range files {
+		if path == "" {
+			continue
+		}
+		if _, ok := s.res.announcements[path]; ok {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("cannot read announcement: %w", err)
+		}
+		frames, err := res.DecodeOggAudio(data)
+		if err != nil {
+			return fmt.Errorf("cannot decode announcement %q: %w", path, err)
+		}
+		s.res.announcements[path] = frames
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"slices"
+
+	"github.com/livekit/media-sdk/sdp"
+	psdp "github.com/pion/sdp/v3"
+)
+
+// Media directions (RFC 3264, section 5.1).
+const (
+	dirSendRecv = "sendrecv"
+	dirSendOnly = "sendonly"
+	dirRecvOnly = "recvonly"
+	dirInactive = "inactive"
+)
+
+func sdpDirection(attrs []psdp.Attribute) string {
+	for _, a := range attrs {
+		switch a.Key {
+		case dirSendRecv, dirSendOnly, dirRecvOnly, dirInactive:
+			return a.Key
+		}
+	}
+	return ""
+}
+
+// sdpMediaDirection returns the direction of the media line, as seen by its sender.
+// Media-level direction overrides the session-level one.
+func sdpMediaDirection(s *psdp.SessionDescription, m *psdp.MediaDescription) string {
+	dir := sdpDirection(m.Attributes)
+	if dir == "" {
+		dir = sdpDirection(s.Attributes)
+	}
+	if dir == "" {
+		dir = dirSendRecv
+	}
+	// Legacy hold (RFC 2543) sets connection address to 0.0.0.0: the sender does not want to receive media.
+	if addr, err := sdp.GetAudioDest(s, m); err == nil && addr.Addr().IsUnspecified() {
+		switch dir {
+		case dirSendRecv:
+			dir = dirSendOnly
+		case dirRecvOnly:
+			dir = dirInactive
+		}
+	}
+	return dir
+}
+
+// isHoldDirection checks if the remote direction puts the call on hold, so that we must not send media.
+func isHoldDirection(dir string) bool {
+	return dir == dirSendOnly || dir == dirInactive
+}
+
+// answerDirection returns our direction for the direction offered by the remote.
+func answerDirection(offer string) string {
+	switch offer {
+	case dirSendOnly:
+		return dirRecvOnly
+	case dirRecvOnly:
+		return dirSendOnly
+	case dirInactive:
+		return dirInactive
+	}
+	return dirSendRecv
+}
+
+// setMediaDirection replaces the direction attribute of the media line.
+func setMediaDirection(m *psdp.MediaDescription, dir string) {
+	m.Attributes = slices.DeleteFunc(slices.Clone(m.Attributes), func(a psdp.Attribute) bool {
+		return sdpDirection([]psdp.Attribute{a}) != ""
+	})
+	m.Attributes = append(m.Attributes, psdp.Attribute{Key: dir})
+}
+
+// setDirection applies the media direction of the remote. While the remote holds the call, audio is not sent to it.
+// Media timeout is suspended while the remote is not expected to send media.
+func (p *MediaPort) setDirection(dir string) {
+	if dir == "" {
+		dir = dirSendRecv
+	}
+	held := isHoldDirection(dir)
+	p.pauseTimeout(timeoutPauseDirection, dir != dirSendRecv)
+
+	p.outMu.Lock()
+	p.outHeld = held
+	if held {
+		p.audioOut.Disable()
+	} else if !p.outDisabled && !p.outMuted {
+		p.audioOut.Enable()
+	}
+	p.outMu.Unlock()
+
+	if p.held.Swap(held) == held {
+		return
+	}
+	if held {
+		p.log.Infow("media put on hold by the remote", "direction", dir)
+		p.events.emit(MediaEvent{Type: MediaEventHold})
+	} else {
+		p.log.Infow("media resumed by the remote", "direction", dir)
+		p.events.emit(MediaEvent{Type: MediaEventResume})
+	}
+}
+
+// Held reports if the remote put the media on hold.
+func (p *MediaPort) Held() bool {
+	return p.held.Load()
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/livekit/media-sdk/sdp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestMediaPortHold(t *testing.T) {
+	m1 := newLocalMediaPort(t, "one", config.MediaTransportUDP)
+	m2 := newLocalMediaPort(t, "two", config.MediaTransportUDP)
+
+	var events []MediaEventType
+	m2.OnEvent(func(ev MediaEvent) {
+		if ev.Type == MediaEventHold || ev.Type == MediaEventResume {
+			events = append(events, ev.Type)
+		}
+	})
+
+	negotiate := func(dir string) (string, *MediaConf) {
+		offer, err := m1.NewOffer(sdp.EncryptionNone)
+		require.NoError(t, err)
+		setMediaDirection(offer.SDP.MediaDescriptions[0], dir)
+		offerData, err := offer.SDP.Marshal()
+		require.NoError(t, err)
+		answer, conf, err := m2.SetOffer(offerData, sdp.EncryptionNone)
+		require.NoError(t, err)
+		return sdpDirection(answer.SDP.MediaDescriptions[0].Attributes), conf
+	}
+
+	ans, conf := negotiate(dirSendRecv)
+	require.Equal(t, dirSendRecv, ans)
+	require.NoError(t, m2.SetConfig(conf))
+	require.False(t, m2.Held())
+
+	ans, conf = negotiate(dirSendOnly)
+	require.Equal(t, dirRecvOnly, ans)
+	require.NoError(t, m2.UpdateConfig(conf, true))
+	require.True(t, m2.Held())
+	require.True(t, m2.timeoutPaused.Load())
+
+	ans, conf = negotiate(dirInactive)
+	require.Equal(t, dirInactive, ans)
+	require.NoError(t, m2.UpdateConfig(conf, true))
+	require.True(t, m2.Held())
+
+	ans, conf = negotiate(dirSendRecv)
+	require.Equal(t, dirSendRecv, ans)
+	require.NoError(t, m2.UpdateConfig(conf, true))
+	require.False(t, m2.Held())
+	require.False(t, m2.timeoutPaused.Load())
+
+	require.Equal(t, []MediaEventType{MediaEventHold, MediaEventResume}, events)
+}
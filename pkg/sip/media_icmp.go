@@ -0,0 +1,90 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"errors"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// icmpErrorWindow is the maximal interval between ICMP errors counted towards MediaOptions.ICMPMaxErrors.
+const icmpErrorWindow = 5 * time.Second
+
+// isICMPError checks if a socket error was caused by an ICMP error for a packet sent earlier.
+func isICMPError(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, syscall.ENETUNREACH)
+}
+
+// icmpCounter counts consecutive ICMP errors, less than icmpErrorWindow apart.
+type icmpCounter struct {
+	max   int
+	count int
+	last  time.Time
+}
+
+// Add counts n errors and reports if the count reached the limit. The count restarts after that.
+func (c *icmpCounter) Add(now time.Time, n int) bool {
+	if now.Sub(c.last) > icmpErrorWindow {
+		c.count = 0
+	}
+	c.last = now
+	c.count += n
+	if c.max <= 0 || c.count < c.max {
+		return false
+	}
+	c.count = 0
+	return true
+}
+
+// handleICMPError accounts for socket errors caused by ICMP errors. It returns false for all other errors.
+func (c *udpConn) handleICMPError(err error) bool {
+	if !isICMPError(err) {
+		return false
+	}
+	// The socket only reports one error, but there may be more of them queued.
+	n := max(drainICMPErrors(c.UDPConn), 1)
+	if c.stats.ICMPErrors.Add(uint64(n)) == uint64(n) {
+		c.log.Infow("media destination is unreachable", "error", err, "addr", c.dstAddr().String())
+	}
+	c.icmpMu.Lock()
+	limit := c.icmp.Add(time.Now(), n)
+	c.icmpMu.Unlock()
+	if limit {
+		addr := c.dstAddr()
+		c.log.Warnw("too many ICMP errors for media", err, "addr", addr.String(), "total", c.stats.ICMPErrors.Load())
+		c.events.emit(MediaEvent{Type: MediaEventUnreachable, Addr: addr})
+	}
+	return true
+}
+
+func (c *udpConn) dstAddr() netip.AddrPort {
+	if dst := c.dst.Load(); dst != nil {
+		return *dst
+	}
+	return netip.AddrPort{}
+}
+
+// writeTo sends a packet to the destination. Pending ICMP errors of earlier packets are accounted and the packet is retried.
+func (c *udpConn) writeTo(b []byte, dst netip.AddrPort) (int, error) {
+	n, err := c.WriteToUDPAddrPort(b, dst)
+	if err != nil && c.handleICMPError(err) {
+		return c.WriteToUDPAddrPort(b, dst)
+	}
+	return n, err
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package sip
+
+import (
+	"errors"
+	"syscall"
+)
+
+// maxICMPDrain limits the number of queued ICMP errors read at once.
+const maxICMPDrain = 64
+
+// enableICMPErrors makes the socket report ICMP errors for packets sent with WriteTo.
+// By default, Linux only reports them for connected UDP sockets.
+func enableICMPErrors(conn UDPConn) error {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	if err = raw.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_RECVERR, 1)
+	}); err != nil {
+		return err
+	}
+	return serr
+}
+
+// drainICMPErrors reads ICMP errors queued on the socket and returns their number.
+// Queued errors are accounted in the receive buffer of the socket, so they must be read to not block RTP.
+func drainICMPErrors(conn UDPConn) int {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return 0
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return 0
+	}
+	n := 0
+	_ = raw.Control(func(fd uintptr) {
+		var (
+			buf [1]byte
+			oob [256]byte
+		)
+		for n < maxICMPDrain {
+			if _, _, _, _, err := syscall.Recvmsg(int(fd), buf[:], oob[:], syscall.MSG_ERRQUEUE|syscall.MSG_DONTWAIT); err != nil {
+				return
+			}
+			n++
+		}
+	})
+	return n
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+)
+
+func TestICMPCounter(t *testing.T) {
+	t0 := time.Now()
+	c := icmpCounter{max: 3}
+	require.False(t, c.Add(t0, 1))
+	require.False(t, c.Add(t0.Add(time.Second), 1))
+	require.True(t, c.Add(t0.Add(2*time.Second), 1))
+	// Restarts after reaching the limit.
+	require.False(t, c.Add(t0.Add(3*time.Second), 1))
+	// Errors too far apart are not counted together.
+	require.False(t, c.Add(t0.Add(10*time.Second), 2))
+	require.False(t, c.Add(t0.Add(20*time.Second), 2))
+	require.True(t, c.Add(t0.Add(21*time.Second), 1))
+
+	c = icmpCounter{}
+	require.False(t, c.Add(t0, 100))
+}
+
+func TestUDPConnICMPErrors(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+	if err := enableICMPErrors(conn); err != nil {
+		t.Skip("ICMP errors are not supported:", err)
+	}
+
+	// Find a closed port.
+	closed, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	dst := closed.LocalAddr().(*net.UDPAddr).AddrPort()
+	require.NoError(t, closed.Close())
+
+	var st PortStats
+	events := new(mediaEvents)
+	var got []MediaEvent
+	events.OnEvent(func(ev MediaEvent) {
+		got = append(got, ev)
+	})
+	c := newUDPConn(logger.GetLogger(), conn, &st, events)
+	c.icmp.max = 2
+	c.SetDst(dst)
+	got = nil
+
+	pkt := make([]byte, 20)
+	pkt[0] = 0x80
+	for i := 0; i < 4; i++ {
+		_, err = c.Write(pkt)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Reading handles errors and waits for actual packets.
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, err = c.Read(make([]byte, 1500))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	require.NotZero(t, st.ICMPErrors.Load())
+	require.NotEmpty(t, got)
+	require.Equal(t, MediaEventUnreachable, got[0].Type)
+	require.Equal(t, dst, got[0].Addr)
+	require.Zero(t, drainICMPErrors(conn))
+}
@@ -0,0 +1,304 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/livekit/media-sdk/rtp"
+	"github.com/livekit/psrpc"
+)
+
+const (
+	// maxImpairmentDelay limits delay and jitter of injected impairments.
+	maxImpairmentDelay = 10 * time.Second
+	// impairReorderHold is how long a packet is held back for reordering if no other packet follows it.
+	impairReorderHold = 200 * time.Millisecond
+	// impairQueueSize is the number of received packets buffered for the RTP reader.
+	impairQueueSize = 256
+)
+
+// MediaImpairment simulates a bad network on RTP of one direction, for testing. Probabilities are from 0 to 1.
+type MediaImpairment struct {
+	// Loss is the probability of dropping a packet.
+	Loss float64 `json:"loss,omitempty"`
+	// DelayMs delays all packets.
+	DelayMs int `json:"delay_ms,omitempty"`
+	// JitterMs adds a random delay of up to the given value to each packet. Packets may be reordered as a result.
+	JitterMs int `json:"jitter_ms,omitempty"`
+	// Reorder is the probability of holding a packet back, so that it's delivered after the next one.
+	Reorder float64 `json:"reorder,omitempty"`
+	// Duplicate is the probability of delivering a packet twice.
+	Duplicate float64 `json:"duplicate,omitempty"`
+}
+
+func (m *MediaImpairment) Validate() error {
+	for _, p := range []struct {
+		name string
+		val  float64
+	}{{"loss", m.Loss}, {"reorder", m.Reorder}, {"duplicate", m.Duplicate}} {
+		if p.val < 0 || p.val > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", p.name)
+		}
+	}
+	if m.DelayMs < 0 || m.JitterMs < 0 {
+		return fmt.Errorf("delay and jitter must not be negative")
+	}
+	if time.Duration(m.DelayMs+m.JitterMs)*time.Millisecond > maxImpairmentDelay {
+		return fmt.Errorf("delay and jitter must not exceed %v in total", maxImpairmentDelay)
+	}
+	return nil
+}
+
+func (m *MediaImpairment) isZero() bool {
+	return m == nil || *m == MediaImpairment{}
+}
+
+// mediaImpairer applies impairments to packets of one direction.
+type mediaImpairer struct {
+	conf  MediaImpairment
+	stats *PortStats
+
+	mu        sync.Mutex
+	held      []byte // packet held back for reordering
+	heldSend  func(b []byte)
+	heldTimer *time.Timer
+}
+
+func newMediaImpairer(conf MediaImpairment, st *PortStats) *mediaImpairer {
+	return &mediaImpairer{conf: conf, stats: st}
+}
+
+func chance(p float64) bool {
+	return p > 0 && rand.Float64() < p
+}
+
+// apply passes the packet through impairments. Send is called for each delivered copy, possibly later.
+// The packet must not be modified after the call.
+func (m *mediaImpairer) apply(b []byte, send func(b []byte)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if chance(m.conf.Loss) {
+		m.stats.ImpairedDrops.Add(1)
+		return
+	}
+	dup := chance(m.conf.Duplicate)
+	if dup {
+		m.stats.ImpairedDuplicates.Add(1)
+	}
+	if m.held != nil {
+		held, heldSend := m.takeHeld()
+		m.deliver(b, send)
+		if dup {
+			m.deliver(b, send)
+		}
+		m.deliver(held, heldSend)
+		return
+	}
+	if chance(m.conf.Reorder) {
+		m.stats.ImpairedReorders.Add(1)
+		if dup {
+			m.deliver(b, send)
+		}
+		m.held, m.heldSend = b, send
+		m.heldTimer = time.AfterFunc(impairReorderHold, m.release)
+		return
+	}
+	m.deliver(b, send)
+	if dup {
+		m.deliver(b, send)
+	}
+}
+
+// Must be called holding the lock.
+func (m *mediaImpairer) takeHeld() ([]byte, func(b []byte)) {
+	held, send := m.held, m.heldSend
+	m.heldTimer.Stop()
+	m.held, m.heldSend, m.heldTimer = nil, nil, nil
+	return held, send
+}
+
+// release delivers the packet held back for reordering, if any.
+func (m *mediaImpairer) release() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.held != nil {
+		m.deliver(m.takeHeld())
+	}
+}
+
+// Must be called holding the lock.
+func (m *mediaImpairer) deliver(b []byte, send func(b []byte)) {
+	delay := time.Duration(m.conf.DelayMs) * time.Millisecond
+	if m.conf.JitterMs > 0 {
+		delay += rand.N(time.Duration(m.conf.JitterMs) * time.Millisecond)
+	}
+	if delay <= 0 {
+		send(b)
+		return
+	}
+	time.AfterFunc(delay, func() {
+		send(b)
+	})
+}
+
+// setImpairer replaces the impairer of one direction. A packet held back by the previous one is delivered.
+func setImpairer(ptr *atomic.Pointer[mediaImpairer], conf *MediaImpairment, st *PortStats) {
+	var m *mediaImpairer
+	if !conf.isZero() {
+		m = newMediaImpairer(*conf, st)
+	}
+	if prev := ptr.Swap(m); prev != nil {
+		prev.release()
+	}
+}
+
+// impairedPacket is a received packet on its way to the RTP reader.
+type impairedPacket struct {
+	data []byte
+	addr netip.AddrPort
+}
+
+// impairPump reads packets from the socket in the background, so that delayed packets can be delivered
+// to the RTP reader while it would otherwise be blocked on the socket.
+type impairPump struct {
+	ready chan impairedPacket
+	done  chan struct{}
+	err   error
+}
+
+// readFrom reads a packet from the socket directly, or from the pump if inbound impairment was ever enabled.
+// Must only be called by the reader.
+func (c *udpConn) readFrom(b []byte) (int, netip.AddrPort, error) {
+	if c.pump == nil {
+		if c.impairIn.Load() == nil {
+			return c.ReadFromUDPAddrPort(b)
+		}
+		c.pump = &impairPump{
+			ready: make(chan impairedPacket, impairQueueSize),
+			done:  make(chan struct{}),
+		}
+		go c.pumpLoop(c.pump)
+	}
+	select {
+	case p := <-c.pump.ready:
+		return copy(b, p.data), p.addr, nil
+	case <-c.pump.done:
+		return 0, netip.AddrPort{}, c.pump.err
+	}
+}
+
+func (c *udpConn) pumpLoop(p *impairPump) {
+	defer close(p.done)
+	for {
+		buf := make([]byte, rtp.MTUSize+1)
+		n, addr, err := c.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if c.handleICMPError(err) {
+				continue
+			}
+			p.err = err
+			return
+		}
+		send := func(b []byte) {
+			select {
+			case p.ready <- impairedPacket{data: b, addr: addr}:
+			case <-p.done:
+			}
+		}
+		if m := c.impairIn.Load(); m != nil && classifyMediaPacket(buf[:n]) == mediaPacketRTP {
+			m.apply(buf[:n], send)
+		} else {
+			send(buf[:n])
+		}
+	}
+}
+
+// writeImpaired sends a packet which passed outbound impairments.
+func (c *udpConn) writeImpaired(b []byte) {
+	if dst := c.dst.Load(); dst != nil {
+		_, _ = c.writeTo(b, *dst)
+	}
+}
+
+// SetImpairment injects impairments into RTP received from the remote (in) and sent to it (out).
+// Nil or empty impairments disable them for the direction.
+func (p *MediaPort) SetImpairment(in, out *MediaImpairment) {
+	setImpairer(&p.port.impairIn, in, p.stats)
+	setImpairer(&p.port.impairOut, out, p.stats)
+}
+
+// Impairment returns impairments of received and sent RTP.
+func (p *MediaPort) Impairment() (in, out *MediaImpairment) {
+	if m := p.port.impairIn.Load(); m != nil {
+		in = &m.conf
+	}
+	if m := p.port.impairOut.Load(); m != nil {
+		out = &m.conf
+	}
+	return in, out
+}
+
+// SetMediaImpairmentRequest injects network impairments into RTP of an active call.
+type SetMediaImpairmentRequest struct {
+	CallID string `json:"call_id"`
+	// Inbound impairs RTP received from the remote, Outbound impairs RTP sent to it. Omitted directions are reset.
+	Inbound  *MediaImpairment `json:"inbound,omitempty"`
+	Outbound *MediaImpairment `json:"outbound,omitempty"`
+}
+
+// SetMediaImpairmentResponse lists impairments of the call after the change.
+type SetMediaImpairmentResponse struct {
+	Inbound  *MediaImpairment `json:"inbound,omitempty"`
+	Outbound *MediaImpairment `json:"outbound,omitempty"`
+}
+
+// SetMediaImpairment injects packet loss, jitter, reordering and duplication into RTP of an active call.
+// It requires enable_media_impairment in the config.
+func (s *Service) SetMediaImpairment(ctx context.Context, req *SetMediaImpairmentRequest) (*SetMediaImpairmentResponse, error) {
+	if !s.conf.EnableMediaImpairment {
+		return nil, psrpc.NewErrorf(psrpc.FailedPrecondition, "media impairment is not enabled")
+	}
+	for _, m := range []*MediaImpairment{req.Inbound, req.Outbound} {
+		if m == nil {
+			continue
+		}
+		if err := m.Validate(); err != nil {
+			return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "invalid impairment: %v", err)
+		}
+	}
+	id := LocalTag(req.CallID)
+	media, env, ok := s.srv.mediaStageCall(id)
+	if !ok {
+		media, env, ok = s.cli.mediaStageCall(id)
+	}
+	if !ok {
+		return nil, psrpc.NewErrorf(psrpc.NotFound, "call %q not found", req.CallID)
+	}
+	media.SetImpairment(req.Inbound, req.Outbound)
+	in, out := media.Impairment()
+	if in != nil || out != nil {
+		env.Log.Warnw("media impairment enabled", nil, "inbound", in, "outbound", out)
+	} else {
+		env.Log.Infow("media impairment disabled")
+	}
+	return &SetMediaImpairmentResponse{Inbound: in, Outbound: out}, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestMediaImpairmentValidate(t *testing.T) {
+	require.NoError(t, (&MediaImpairment{}).Validate())
+	require.NoError(t, (&MediaImpairment{Loss: 0.1, DelayMs: 50, JitterMs: 30, Reorder: 0.05, Duplicate: 1}).Validate())
+	require.Error(t, (&MediaImpairment{Loss: 1.5}).Validate())
+	require.Error(t, (&MediaImpairment{Reorder: -0.1}).Validate())
+	require.Error(t, (&MediaImpairment{JitterMs: -1}).Validate())
+	require.Error(t, (&MediaImpairment{DelayMs: 9000, JitterMs: 2000}).Validate())
+}
+
+// impairRecorder collects packets delivered by an impairer.
+type impairRecorder struct {
+	mu   sync.Mutex
+	got  []byte
+	sent chan struct{}
+}
+
+func newImpairRecorder() *impairRecorder {
+	return &impairRecorder{sent: make(chan struct{}, 100)}
+}
+
+func (r *impairRecorder) send(b []byte) {
+	r.mu.Lock()
+	r.got = append(r.got, b[0])
+	r.mu.Unlock()
+	r.sent <- struct{}{}
+}
+
+func (r *impairRecorder) packets() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.got
+}
+
+func TestMediaImpairer(t *testing.T) {
+	run := func(conf MediaImpairment, n int) (*impairRecorder, *PortStats) {
+		var st PortStats
+		r := newImpairRecorder()
+		m := newMediaImpairer(conf, &st)
+		for i := 1; i <= n; i++ {
+			m.apply([]byte{byte(i)}, r.send)
+		}
+		return r, &st
+	}
+
+	r, st := run(MediaImpairment{}, 3)
+	require.Equal(t, []byte{1, 2, 3}, r.packets())
+
+	r, st = run(MediaImpairment{Loss: 1}, 3)
+	require.Empty(t, r.packets())
+	require.EqualValues(t, 3, st.ImpairedDrops.Load())
+
+	r, st = run(MediaImpairment{Duplicate: 1}, 2)
+	require.Equal(t, []byte{1, 1, 2, 2}, r.packets())
+	require.EqualValues(t, 2, st.ImpairedDuplicates.Load())
+
+	r, st = run(MediaImpairment{Reorder: 1}, 4)
+	require.Equal(t, []byte{2, 1, 4, 3}, r.packets())
+	require.EqualValues(t, 2, st.ImpairedReorders.Load())
+
+	// A held packet is released if nothing follows it.
+	r, _ = run(MediaImpairment{Reorder: 1}, 1)
+	require.Empty(t, r.packets())
+	select {
+	case <-r.sent:
+	case <-time.After(time.Second):
+		t.Fatal("held packet not released")
+	}
+	require.Equal(t, []byte{1}, r.packets())
+
+	start := time.Now()
+	r, _ = run(MediaImpairment{DelayMs: 50, JitterMs: 20}, 2)
+	require.Empty(t, r.packets())
+	for range 2 {
+		select {
+		case <-r.sent:
+		case <-time.After(time.Second):
+			t.Fatal("delayed packet not delivered")
+		}
+	}
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.ElementsMatch(t, []byte{1, 2}, r.packets())
+}
+
+func TestUDPConnImpairment(t *testing.T) {
+	c1, c2 := newUDPPipe()
+	defer c2.Close()
+	var st PortStats
+	conn := newUDPConn(logger.GetLogger(), c1, &st, new(mediaEvents))
+	conn.SetDst(c2.addr)
+
+	rtpPkt := []byte{0x80, 0, 0, 1, 0, 0, 0, 1, 1, 2, 3, 4}
+	rtcpPkt := []byte{0x80, 201, 0, 1, 1, 2, 3, 4}
+
+	// Inbound RTP is duplicated, RTCP is not impaired.
+	setImpairer(&conn.impairIn, &MediaImpairment{Duplicate: 1}, &st)
+	_, err := c2.WriteToUDPAddrPort(rtpPkt, c1.addr)
+	require.NoError(t, err)
+	buf := make([]byte, 1500)
+	for range 2 {
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, rtpPkt, buf[:n])
+	}
+	_, err = c2.WriteToUDPAddrPort(rtcpPkt, c1.addr)
+	require.NoError(t, err)
+	_, err = c2.WriteToUDPAddrPort(rtpPkt, c1.addr)
+	require.NoError(t, err)
+	for range 2 {
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, rtpPkt, buf[:n])
+	}
+	require.EqualValues(t, 1, st.RTCPPackets.Load())
+	require.EqualValues(t, 2, st.ImpairedDuplicates.Load())
+
+	// Outbound RTP is dropped, RTCP is sent.
+	setImpairer(&conn.impairOut, &MediaImpairment{Loss: 1}, &st)
+	_, err = conn.Write(rtpPkt)
+	require.NoError(t, err)
+	_, err = conn.Write(rtcpPkt)
+	require.NoError(t, err)
+	n, _, err := c2.ReadFromUDPAddrPort(buf)
+	require.NoError(t, err)
+	require.Equal(t, rtcpPkt, buf[:n])
+	require.EqualValues(t, 1, st.ImpairedDrops.Load())
+
+	// Disabled impairments don't stop the pump, but packets pass unchanged.
+	setImpairer(&conn.impairIn, nil, &st)
+	setImpairer(&conn.impairOut, &MediaImpairment{}, &st)
+	require.Nil(t, conn.impairOut.Load())
+	_, err = c2.WriteToUDPAddrPort(rtpPkt, c1.addr)
+	require.NoError(t, err)
+	n, err = conn.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, rtpPkt, buf[:n])
+
+	require.NoError(t, c1.Close())
+	_, err = conn.Read(buf)
+	require.Error(t, err)
+}
+
+func TestSetMediaImpairment(t *testing.T) {
+	s := &Service{conf: &config.Config{}}
+	_, err := s.SetMediaImpairment(context.Background(), &SetMediaImpairmentRequest{CallID: "SCL_test"})
+	require.Equal(t, psrpc.FailedPrecondition, errCode(t, err))
+
+	s.conf.EnableMediaImpairment = true
+	_, err = s.SetMediaImpairment(context.Background(), &SetMediaImpairmentRequest{CallID: "SCL_test", Inbound: &MediaImpairment{Loss: 2}})
+	require.Equal(t, psrpc.InvalidArgument, errCode(t, err))
+}
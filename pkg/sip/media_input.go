@@ -0,0 +1,168 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"sync/atomic"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/media-sdk/rtp"
+
+	"github.com/livekit/sip/pkg/mixer"
+)
+
+// rtpStreamIdle is the time without packets after which the RTP stream no longer counts towards MaxInputStreams,
+// and a new stream can replace it as primary. Streams are never closed by the session,
+// so a stream replaced by a new SSRC stays idle.
+const rtpStreamIdle = 10 * time.Second
+
+// rtpInput is an RTP stream received from the remote.
+//
+// The primary stream is decoded by the MediaPort pipeline. Additional concurrent streams
+// have their own decoding pipelines and are mixed with the primary one.
+type rtpInput struct {
+	last atomic.Int64 // unix nanoseconds of the last packet
+	// primary is set for streams decoded by the MediaPort pipeline. It's cleared once another stream replaces it.
+	primary atomic.Bool
+
+	// Only set for additional streams.
+	mix     *mixer.Input
+	gen     uint64
+	hnd     rtp.HandlerCloser
+	release func() // releases memory reserved for the stream
+}
+
+func (in *rtpInput) Close() {
+	if in.hnd != nil {
+		in.hnd.Close()
+		in.hnd = nil
+	}
+	if in.mix != nil {
+		_ = in.mix.Close()
+	}
+	if in.release != nil {
+		in.release()
+		in.release = nil
+	}
+}
+
+func (in *rtpInput) idle(now time.Time) bool {
+	return now.Sub(time.Unix(0, in.last.Load())) >= rtpStreamIdle
+}
+
+// activeRTPInputs counts streams that received packets recently.
+func activeRTPInputs(inputs []*rtpInput, now time.Time) int {
+	n := 0
+	for _, in := range inputs {
+		if !in.idle(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// rtpInputKind tells how a new RTP stream is handled.
+type rtpInputKind int
+
+const (
+	rtpInputIgnored rtpInputKind = iota
+	rtpInputPrimary              // decoded by the MediaPort pipeline
+	rtpInputShared               // mixing is disabled, decoded by the MediaPort pipeline together with the primary
+	rtpInputMixed                // has its own pipeline, mixed with the primary
+)
+
+// rtpInputs tracks RTP streams of the session. It's only used by the RTP loop.
+type rtpInputs struct {
+	list    []*rtpInput
+	primary *rtpInput
+}
+
+// kind decides how a new stream is handled. A new stream becomes primary if the current primary stream is idle,
+// for example, when the remote switched to a new SSRC.
+func (s *rtpInputs) kind(now time.Time, maxInputs int) rtpInputKind {
+	switch {
+	case s.primary == nil || s.primary.idle(now):
+		return rtpInputPrimary
+	case maxInputs <= 1:
+		return rtpInputShared
+	case activeRTPInputs(s.list, now) >= maxInputs:
+		return rtpInputIgnored
+	default:
+		return rtpInputMixed
+	}
+}
+
+// add starts tracking a new stream. If it's primary, it replaces the current primary stream.
+func (s *rtpInputs) add(in *rtpInput, kind rtpInputKind, now time.Time) {
+	in.last.Store(now.UnixNano())
+	switch kind {
+	case rtpInputPrimary:
+		if s.primary != nil {
+			s.primary.primary.Store(false)
+		}
+		s.primary = in
+		in.primary.Store(true)
+	case rtpInputShared:
+		in.primary.Store(true)
+	}
+	s.list = append(s.list, in)
+}
+
+// newMixInput starts mixing inbound RTP streams, if it's not started yet, and returns a new mixer input.
+// It returns nil if the media is closed.
+func (p *MediaPort) newMixInput() *mixer.Input {
+	p.inMu.Lock()
+	defer p.inMu.Unlock()
+	if p.closed.IsBroken() {
+		return nil
+	}
+	if p.inMixer == nil {
+		p.inMixOut = msdk.NewSwitchWriter(p.audioIn.SampleRate())
+		p.inMixer = mixer.NewMixer(p.inMixOut, rtp.DefFrameDur, nil)
+		// Redirect the primary stream to the mixer as well.
+		if w := p.audioIn.Swap(p.inMixer.NewInput()); w != nil {
+			p.inMixOut.Swap(w)
+		}
+	}
+	return p.inMixer.NewInput()
+}
+
+// inputHandler returns the decoding pipeline for the stream. Pipelines of additional streams are recreated
+// when the primary one changes, for example, after codec change.
+func (p *MediaPort) inputHandler(in *rtpInput) rtp.HandlerCloser {
+	if in.mix == nil {
+		ptr := p.hnd.Load()
+		if ptr == nil {
+			return nil
+		}
+		return *ptr
+	}
+	gen := p.inGen.Load()
+	if in.hnd != nil && in.gen == gen {
+		return in.hnd
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conf == nil || p.closed.IsBroken() {
+		return nil
+	}
+	if in.hnd != nil {
+		in.hnd.Close()
+	}
+	_, in.hnd = p.newInputHandler(in.mix)
+	in.gen = p.inGen.Load()
+	return in.hnd
+}
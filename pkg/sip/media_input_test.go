@@ -0,0 +1,64 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRTPInputsSSRCSwitch(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var s rtpInputs
+	add := func(maxInputs int) (*rtpInput, rtpInputKind) {
+		in := &rtpInput{}
+		kind := s.kind(now, maxInputs)
+		if kind != rtpInputIgnored {
+			s.add(in, kind, now)
+		}
+		return in, kind
+	}
+
+	first, kind := add(2)
+	require.Equal(t, rtpInputPrimary, kind)
+	require.True(t, first.primary.Load())
+
+	// Concurrent streams are mixed with the primary one, up to the limit.
+	second, kind := add(2)
+	require.Equal(t, rtpInputMixed, kind)
+	require.False(t, second.primary.Load())
+	_, kind = add(2)
+	require.Equal(t, rtpInputIgnored, kind)
+
+	// The remote switched to a new SSRC: once the primary stream is idle, the new one replaces it.
+	now = now.Add(rtpStreamIdle / 2)
+	second.last.Store(now.UnixNano())
+	_, kind = add(2)
+	require.Equal(t, rtpInputIgnored, kind)
+	now = now.Add(rtpStreamIdle / 2)
+	third, kind := add(2)
+	require.Equal(t, rtpInputPrimary, kind)
+	require.True(t, third.primary.Load())
+	require.False(t, first.primary.Load())
+	require.Same(t, third, s.primary)
+
+	// Without mixing, concurrent streams share the primary pipeline.
+	fourth, kind := add(1)
+	require.Equal(t, rtpInputShared, kind)
+	require.True(t, fourth.primary.Load())
+	require.True(t, third.primary.Load())
+}
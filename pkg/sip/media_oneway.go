@@ -0,0 +1,140 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"strconv"
+	"time"
+)
+
+// oneWayTickInterval is how often one-way audio detection checks packet counters.
+const oneWayTickInterval = time.Second
+
+// OneWayAudio describes which direction of audio is missing.
+type OneWayAudio int
+
+const (
+	// OneWayNone means audio flows in both directions, or is not flowing at all.
+	OneWayNone = OneWayAudio(iota)
+	// OneWayNoInbound means we send audio, but nothing is received from the remote.
+	OneWayNoInbound
+	// OneWayNoOutbound means the remote sends audio, but we send nothing.
+	OneWayNoOutbound
+)
+
+func (d OneWayAudio) String() string {
+	switch d {
+	case OneWayNone:
+		return ""
+	case OneWayNoInbound:
+		return "no-inbound"
+	case OneWayNoOutbound:
+		return "no-outbound"
+	}
+	return strconv.Itoa(int(d))
+}
+
+// oneWayDetector checks that audio flows in both directions within a timeout after the call is answered.
+// The check stops once audio was seen in both directions or one-way audio was reported.
+// Calls without any audio are left for media timeout.
+type oneWayDetector struct {
+	timeout time.Duration
+	since   time.Time // start of the current window, zero while inactive
+	sent    uint64    // sent packets at the start of the window
+	recv    uint64    // received packets at the start of the window
+	done    bool
+}
+
+// Check updates the detector with packet counters. Active must only be set while the call is answered and not held.
+func (d *oneWayDetector) Check(now time.Time, active bool, sent, recv uint64) OneWayAudio {
+	if d.done {
+		return OneWayNone
+	}
+	if !active {
+		d.since = time.Time{}
+		return OneWayNone
+	}
+	if d.since.IsZero() {
+		d.since, d.sent, d.recv = now, sent, recv
+		return OneWayNone
+	}
+	dsent, drecv := sent-d.sent, recv-d.recv
+	if dsent != 0 && drecv != 0 {
+		d.done = true
+		return OneWayNone
+	}
+	if now.Sub(d.since) < d.timeout {
+		return OneWayNone
+	}
+	d.done = true
+	switch {
+	case dsent != 0:
+		return OneWayNoInbound
+	case drecv != 0:
+		return OneWayNoOutbound
+	}
+	return OneWayNone
+}
+
+func (p *MediaPort) oneWayLoop() {
+	ticker := time.NewTicker(min(oneWayTickInterval, p.opts.OneWayTimeout))
+	defer ticker.Stop()
+	defer p.opts.resources.Acquire(leakTimer)()
+
+	d := oneWayDetector{timeout: p.opts.OneWayTimeout}
+	for !d.done {
+		select {
+		case <-p.closed.Watch():
+			return
+		case <-ticker.C:
+		}
+		active := p.timeoutStart.Load() != nil && !p.timeoutPaused.Load()
+		dir := d.Check(time.Now(), active, p.stats.SentPackets.Load(), p.packetCount.Load())
+		if dir != OneWayNone {
+			p.onOneWayAudio(dir)
+		}
+	}
+}
+
+func (p *MediaPort) onOneWayAudio(dir OneWayAudio) {
+	p.log.Warnw("one-way audio detected", nil,
+		"missing", dir.String(),
+		"timeout", p.opts.OneWayTimeout,
+		"sent", p.stats.SentPackets.Load(),
+		"received", p.packetCount.Load(),
+	)
+	p.stats.OneWayAudio.Store(1)
+	if p.mon != nil {
+		p.mon.OneWayAudio(dir.String())
+	}
+	if p.opts.OneWayLatch {
+		p.latchDst()
+	}
+	p.events.emit(MediaEvent{Type: MediaEventOneWay, OneWay: dir})
+}
+
+// latchDst sends media to the address it's received from, if it differs from the destination in SDP.
+// This fixes calls with remotes behind NAT, which advertise a private address in SDP.
+func (p *MediaPort) latchDst() {
+	src, ok := p.port.GetSrc()
+	if !ok {
+		return
+	}
+	if dst := p.port.dst.Load(); dst != nil && *dst == src {
+		return
+	}
+	p.log.Infow("latching media destination to the source address", "addr", src.String())
+	p.port.SetDst(src)
+}
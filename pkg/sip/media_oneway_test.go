@@ -0,0 +1,80 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOneWayDetector(t *testing.T) {
+	const timeout = 5 * time.Second
+	t0 := time.Now()
+	at := func(sec int) time.Time {
+		return t0.Add(time.Duration(sec) * time.Second)
+	}
+
+	cases := []struct {
+		name string
+		sent uint64
+		recv uint64
+		exp  OneWayAudio
+	}{
+		{name: "two-way", sent: 250, recv: 250, exp: OneWayNone},
+		{name: "no inbound", sent: 250, recv: 0, exp: OneWayNoInbound},
+		{name: "no outbound", sent: 0, recv: 250, exp: OneWayNoOutbound},
+		{name: "no media", sent: 0, recv: 0, exp: OneWayNone},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := oneWayDetector{timeout: timeout}
+			require.Equal(t, OneWayNone, d.Check(at(0), true, 10, 10))
+			require.Equal(t, OneWayNone, d.Check(at(4), true, 10+c.sent/2, 10+c.recv/2))
+			require.Equal(t, c.exp, d.Check(at(5), true, 10+c.sent, 10+c.recv))
+			require.True(t, d.done)
+			// Reported only once.
+			require.Equal(t, OneWayNone, d.Check(at(10), true, 20+c.sent, 10+c.recv))
+		})
+	}
+
+	t.Run("not answered", func(t *testing.T) {
+		d := oneWayDetector{timeout: timeout}
+		require.Equal(t, OneWayNone, d.Check(at(0), false, 100, 0))
+		require.Equal(t, OneWayNone, d.Check(at(10), false, 200, 0))
+		// Early media doesn't count, the window starts at answer.
+		require.Equal(t, OneWayNone, d.Check(at(11), true, 200, 0))
+		require.Equal(t, OneWayNone, d.Check(at(15), true, 400, 0))
+		require.Equal(t, OneWayNoInbound, d.Check(at(16), true, 450, 0))
+	})
+
+	t.Run("held", func(t *testing.T) {
+		d := oneWayDetector{timeout: timeout}
+		require.Equal(t, OneWayNone, d.Check(at(0), true, 0, 0))
+		require.Equal(t, OneWayNone, d.Check(at(3), true, 150, 0))
+		// Hold restarts the window.
+		require.Equal(t, OneWayNone, d.Check(at(4), false, 200, 0))
+		require.Equal(t, OneWayNone, d.Check(at(6), true, 300, 0))
+		require.Equal(t, OneWayNone, d.Check(at(10), true, 500, 0))
+		require.False(t, d.done)
+		require.Equal(t, OneWayNoInbound, d.Check(at(11), true, 550, 0))
+	})
+}
+
+func TestOneWayAudioAttrs(t *testing.T) {
+	attrs := mediaStateAttrs(MediaEvent{Type: MediaEventOneWay, OneWay: OneWayNoInbound})
+	require.Equal(t, map[string]string{AttrSIPOneWayAudio: "no-inbound"}, attrs)
+}
@@ -15,11 +15,19 @@
 package sip
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	"math/rand/v2"
 	"net"
 	"net/netip"
+	"runtime/pprof"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -31,10 +39,14 @@ import (
 	"github.com/livekit/media-sdk/dtmf"
 	"github.com/livekit/media-sdk/rtp"
 	"github.com/livekit/media-sdk/sdp"
-	"github.com/livekit/media-sdk/srtp"
 	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
 	"github.com/livekit/protocol/logger"
+	psdp "github.com/pion/sdp/v3"
 
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/media/drift"
+	"github.com/livekit/sip/pkg/media/vad"
 	"github.com/livekit/sip/pkg/mixer"
 	"github.com/livekit/sip/pkg/stats"
 )
@@ -44,6 +56,8 @@ type PortStats struct {
 	Packets        atomic.Uint64
 	IgnoredPackets atomic.Uint64
 	InputPackets   atomic.Uint64
+	// SentPackets is the number of RTP packets sent to the remote.
+	SentPackets atomic.Uint64
 
 	MuxPackets atomic.Uint64
 	MuxBytes   atomic.Uint64
@@ -53,6 +67,55 @@ type PortStats struct {
 
 	DTMFPackets atomic.Uint64
 	DTMFBytes   atomic.Uint64
+
+	ComfortNoisePackets atomic.Uint64
+	KeepAlivePackets    atomic.Uint64
+	RTCPPackets         atomic.Uint64
+	ZRTPPackets         atomic.Uint64
+
+	MediaTimeouts atomic.Uint64
+
+	// Sequence number gaps, duplicate packets, packets reordered within the reorder window
+	// and packets that arrived too late to be accounted as received.
+	SeqGaps          atomic.Uint64
+	DuplicatePackets atomic.Uint64
+	ReorderedPackets atomic.Uint64
+	LatePackets      atomic.Uint64
+	// Discontinuities is the number of times the audio pipeline was reset due to a sequence or timestamp jump.
+	Discontinuities atomic.Uint64
+
+	// ClockDrift is the estimated clock drift of the remote, in ppm.
+	ClockDrift atomic.Int64
+	// DriftSamples is the number of samples inserted (positive) or dropped (negative) to correct clock drift.
+	DriftSamples atomic.Int64
+
+	// PacedPackets is the number of sent packets delayed by the pacer, PacerDrops is the number of packets it dropped.
+	PacedPackets atomic.Uint64
+	PacerDrops   atomic.Uint64
+
+	// SilenceFrames is the number of silence frames sent while the room had no audio,
+	// GatedFrames is the number of quiet room frames replaced with silence.
+	SilenceFrames atomic.Uint64
+	GatedFrames   atomic.Uint64
+
+	// ImpairedDrops, ImpairedDuplicates and ImpairedReorders count RTP packets affected by injected impairments.
+	ImpairedDrops      atomic.Uint64
+	ImpairedDuplicates atomic.Uint64
+	ImpairedReorders   atomic.Uint64
+
+	// StreamDrops is the number of RTP packets dropped because their stream was over the limit of concurrent
+	// streams or new streams arrived too fast.
+	StreamDrops atomic.Uint64
+
+	// SRTPAuthFailures and SRTPReplays count received SRTP packets that failed authentication or replay checks.
+	SRTPAuthFailures atomic.Uint64
+	SRTPReplays      atomic.Uint64
+
+	// ICMPErrors is the number of ICMP errors (port or host unreachable) reported for RTP sent to the remote.
+	ICMPErrors atomic.Uint64
+
+	// OneWayAudio is set to 1 if audio was flowing in only one direction after the call was answered.
+	OneWayAudio atomic.Uint64
 }
 
 type UDPConn interface {
@@ -61,15 +124,25 @@ type UDPConn interface {
 	WriteToUDPAddrPort(b []byte, addr netip.AddrPort) (int, error)
 }
 
-func newUDPConn(log logger.Logger, conn UDPConn) *udpConn {
-	return &udpConn{UDPConn: conn, log: log}
+func newUDPConn(log logger.Logger, conn UDPConn, st *PortStats, events *mediaEvents) *udpConn {
+	return &udpConn{UDPConn: conn, log: log, stats: st, events: events}
 }
 
 type udpConn struct {
 	UDPConn
-	log logger.Logger
-	src atomic.Pointer[netip.AddrPort]
-	dst atomic.Pointer[netip.AddrPort]
+	log    logger.Logger
+	stats  *PortStats
+	events *mediaEvents
+	src    atomic.Pointer[netip.AddrPort]
+	dst    atomic.Pointer[netip.AddrPort]
+	zrtp   atomic.Bool
+
+	icmpMu sync.Mutex
+	icmp   icmpCounter
+
+	impairIn  atomic.Pointer[mediaImpairer] // optional, impairs received RTP
+	impairOut atomic.Pointer[mediaImpairer] // optional, impairs sent RTP
+	pump      *impairPump                   // reads packets once inbound impairment is enabled, owned by the reader
 }
 
 func (c *udpConn) GetSrc() (netip.AddrPort, bool) {
@@ -88,19 +161,96 @@ func (c *udpConn) SetDst(addr netip.AddrPort) {
 			c.log.Infow("setting media destination", "addr", addr.String())
 		} else if *prev != addr {
 			c.log.Infow("changing media destination", "addr", addr.String())
+		} else {
+			return
 		}
+		c.events.emit(MediaEvent{Type: MediaEventDestChanged, Addr: addr})
 	}
 }
 
 func (c *udpConn) Read(b []byte) (n int, err error) {
-	n, addr, err := c.ReadFromUDPAddrPort(b)
-	prev := c.src.Swap(&addr)
-	if prev == nil || !prev.IsValid() {
-		c.log.Infow("setting media source", "addr", addr.String())
-	} else if *prev != addr {
-		c.log.Infow("changing media source", "addr", addr.String())
+	for {
+		n, addr, err := c.readFrom(b)
+		if err != nil {
+			if c.handleICMPError(err) {
+				continue
+			}
+			return n, err
+		}
+		prev := c.src.Swap(&addr)
+		if prev == nil || !prev.IsValid() {
+			c.log.Infow("setting media source", "addr", addr.String())
+			c.events.emit(MediaEvent{Type: MediaEventSourceChanged, Addr: addr})
+		} else if *prev != addr {
+			c.log.Infow("changing media source", "addr", addr.String())
+			c.events.emit(MediaEvent{Type: MediaEventSourceChanged, Addr: addr})
+		}
+		// RTCP and keep-alives never reach the RTP session, but they still count for media timeout.
+		switch classifyMediaPacket(b[:n]) {
+		case mediaPacketRTCP:
+			c.stats.RTCPPackets.Add(1)
+			continue
+		case mediaPacketKeepAlive:
+			c.stats.KeepAlivePackets.Add(1)
+			continue
+		case mediaPacketInvalid:
+			continue
+		case mediaPacketZRTP:
+			c.stats.ZRTPPackets.Add(1)
+			c.reportZRTP("rtp", zrtpMessageType(b[:n]))
+			continue
+		}
+		return n, nil
+	}
+}
+
+const rtpPayloadCN = 13 // RFC 3389
+
+type mediaPacketType int
+
+const (
+	mediaPacketRTP = mediaPacketType(iota)
+	mediaPacketRTCP
+	mediaPacketKeepAlive
+	mediaPacketZRTP
+	mediaPacketInvalid
+)
+
+// stunMagicCookie is the magic cookie of STUN messages (RFC 5389).
+const stunMagicCookie = 0x2112A442
+
+// classifyMediaPacket separates RTP from RTCP (RFC 5761), ZRTP and from keep-alives (empty datagrams, STUN, CRLF).
+// Other datagrams which are not RTP are invalid.
+func classifyMediaPacket(b []byte) mediaPacketType {
+	if len(b) >= 8 && b[0]>>6 == 2 && b[1] >= 192 && b[1] <= 223 {
+		return mediaPacketRTCP
+	}
+	if isZRTPPacket(b) {
+		return mediaPacketZRTP
+	}
+	if isMediaKeepAlive(b) {
+		return mediaPacketKeepAlive
+	}
+	if len(b) < 12 || b[0]>>6 != 2 {
+		return mediaPacketInvalid
+	}
+	return mediaPacketRTP
+}
+
+// isMediaKeepAlive checks if the datagram is an empty, CRLF or STUN keep-alive.
+func isMediaKeepAlive(b []byte) bool {
+	if len(b) >= 20 && b[0]>>6 == 0 && binary.BigEndian.Uint32(b[4:]) == stunMagicCookie {
+		return true
+	}
+	if len(b) > 4 {
+		return false
+	}
+	for _, c := range b {
+		if c != '\r' && c != '\n' {
+			return false
+		}
 	}
-	return n, err
+	return true
 }
 
 func (c *udpConn) Write(b []byte) (n int, err error) {
@@ -108,12 +258,29 @@ func (c *udpConn) Write(b []byte) (n int, err error) {
 	if dst == nil {
 		return len(b), nil // ignore
 	}
-	return c.WriteToUDPAddrPort(b, *dst)
+	isRTP := classifyMediaPacket(b) == mediaPacketRTP
+	if isRTP {
+		c.stats.SentPackets.Add(1)
+	}
+	if m := c.impairOut.Load(); m != nil && isRTP {
+		m.apply(bytes.Clone(b), c.writeImpaired)
+		return len(b), nil
+	}
+	return c.writeTo(b, *dst)
 }
 
 type MediaConf struct {
 	sdp.MediaConfig
-	Processor msdk.PCM16Processor
+	// RemoteSRTP contains optional lifetime and MKI of the remote SRTP key.
+	RemoteSRTP *SRTPKeyParams
+	// Downgraded is set when encryption was offered, but the session uses unencrypted RTP.
+	Downgraded bool
+	// TCP is set when RTP is framed over TCP (RFC 4571) instead of UDP.
+	TCP *TCPMediaConf
+	// Direction is the media direction of the remote (RFC 3264). Sendonly and inactive put the call on hold.
+	Direction string
+	// RemoteRTCP is the RTCP address of the remote. It's not set for RTP over TCP.
+	RemoteRTCP netip.AddrPort
 }
 
 type MediaOptions struct {
@@ -123,8 +290,76 @@ type MediaOptions struct {
 	MediaTimeout        time.Duration
 	Stats               *PortStats
 	EnableJitterBuffer  bool
+	// Allocator, if set, is used to allocate a port instead of picking one from Ports range.
+	Allocator *PortAllocator
+	// TrunkID selects reserved Allocator ports for the trunk.
+	TrunkID string
+	// CallID labels media goroutines in CPU profiles, together with TrunkID.
+	CallID string
+	// resources of the call, checked by the leak watchdog once the call ends. Optional.
+	resources *callResources
+	// memory accounts buffers of the call. The jitter buffer is disabled and additional streams are ignored
+	// if they are over the memory budget. Optional.
+	memory *callMemory
+
+	// MediaTimeoutWarnOnly makes media timeout only log and flag the call in stats, instead of ending it.
+	MediaTimeoutWarnOnly bool
+	// MediaTimeoutIgnoreCN stops comfort noise packets from resetting media timeout.
+	// When set, a remote that only sends silence will time out.
+	MediaTimeoutIgnoreCN bool
+	// MediaTimeoutKeepAlive allows keep-alive packets to reset media timeout.
+	MediaTimeoutKeepAlive bool
+	// MediaTimeoutRTCP allows RTCP packets to reset media timeout.
+	MediaTimeoutRTCP bool
+	// RTCPXRInterval is how often RTCP-XR VoIP metrics of the received stream are sent to the remote.
+	// Zero disables reports. They are not sent on encrypted media, since SRTCP is not supported.
+	RTCPXRInterval time.Duration
+	// OneWayTimeout is how long audio may flow in only one direction after the call is answered,
+	// before MediaEventOneWay is emitted. Zero disables one-way audio detection.
+	OneWayTimeout time.Duration
+	// OneWayLatch sends media to the address it's received from once one-way audio is detected (symmetric RTP).
+	OneWayLatch bool
+
+	// SRTPSuites lists allowed SRTP crypto suites in the order of preference. Defaults to DefaultSRTPSuites.
+	SRTPSuites []string
+	// SRTPRejectDisallowed rejects the offer if encryption is allowed, but the remote only offers disallowed crypto suites.
+	SRTPRejectDisallowed bool
+	// SRTPMaxFailures is the number of SRTP packets failing authentication, each within a few seconds
+	// from the previous one, after which MediaEventSRTPFailures is emitted. Zero disables the event.
+	SRTPMaxFailures int
+	// ICMPMaxErrors is the number of ICMP errors for RTP sent to the remote after which MediaEventUnreachable
+	// is emitted. Zero disables the event and ICMP error reporting for unconnected sockets.
+	ICMPMaxErrors int
+	// OfferPlainRTP adds an unencrypted RTP/AVP alternative to encrypted offers, letting the remote decline SRTP.
+	OfferPlainRTP bool
+	// RejectEncrypted rejects offers that only contain encrypted media, instead of answering with unencrypted RTP.
+	RejectEncrypted bool
+	// StripZRTP drops ZRTP packets without counting them as media activity.
+	// By default, ZRTP packets are treated as keep-alives, since the remote may not send audio until ZRTP fails.
+	StripZRTP bool
+	// EnableDriftCorrection corrects clock drift of the remote. Otherwise, drift is only estimated.
+	EnableDriftCorrection bool
+	// MaxInputStreams is the maximal number of concurrent RTP streams mixed together. Defaults to DefaultMaxInputStreams.
+	// Setting it to 1 disables mixing: all streams are decoded by the same pipeline.
+	// It also limits the total number of RTP streams accepted during the call, see maxRTPStreams.
+	MaxInputStreams int
+	// StreamRate limits the number of new RTP streams accepted from the remote per second. Defaults to DefaultStreamRate.
+	StreamRate float64
+	// Pacer smooths bursts of packets sent to SIP and caps their bitrate. Disabled by default.
+	Pacer PacerConfig
+	// Silence fills gaps in audio sent to SIP with silence or comfort noise. Disabled by default.
+	Silence config.SilenceConfig
+	// RTPTransport allows RTP framed over TCP (RFC 4571) on the same port number. Defaults to UDP only.
+	RTPTransport config.MediaTransport
+	// SDP overrides session name and origin username of SDP offers and answers.
+	SDP config.SDPConfig
+	// SignalingAddr is the address of the SIP peer. It is preferred when resolving host names from remote SDP.
+	SignalingAddr netip.Addr
 }
 
+// DefaultMaxInputStreams is the default maximal number of concurrent RTP streams received from the remote.
+const DefaultMaxInputStreams = 4
+
 func NewMediaPort(log logger.Logger, mon *stats.CallMonitor, opts *MediaOptions, sampleRate int) (*MediaPort, error) {
 	return NewMediaPortWith(log, mon, nil, opts, sampleRate)
 }
@@ -142,30 +377,86 @@ func NewMediaPortWith(log logger.Logger, mon *stats.CallMonitor, conn UDPConn, o
 	if opts.Stats == nil {
 		opts.Stats = &PortStats{}
 	}
-	if conn == nil {
+	if len(opts.SRTPSuites) == 0 {
+		opts.SRTPSuites = DefaultSRTPSuites
+	}
+	if opts.MaxInputStreams <= 0 {
+		opts.MaxInputStreams = DefaultMaxInputStreams
+	}
+	if opts.StreamRate <= 0 {
+		opts.StreamRate = DefaultStreamRate
+	}
+	if conn == nil && opts.Allocator != nil {
+		c, err := opts.Allocator.Listen(opts.TrunkID)
+		if err != nil {
+			return nil, err
+		}
+		conn = c
+	} else if conn == nil {
 		c, err := rtp.ListenUDPPortRange(opts.Ports.Start, opts.Ports.End, netip.AddrFrom4([4]byte{0, 0, 0, 0}))
 		if err != nil {
 			return nil, err
 		}
 		conn = c
 	}
+	var tcpLn net.Listener
+	if opts.RTPTransport == config.MediaTransportTCP || opts.RTPTransport == config.MediaTransportAuto {
+		ln, err := listenTCPMedia(conn)
+		if err != nil && opts.RTPTransport == config.MediaTransportTCP {
+			_ = conn.Close()
+			return nil, fmt.Errorf("cannot listen for rtp over tcp: %w", err)
+		} else if err != nil {
+			log.Warnw("cannot listen for rtp over tcp, using udp only", err)
+			opts.RTPTransport = config.MediaTransportUDP
+		}
+		tcpLn = ln
+	}
+	jitterEnabled := opts.EnableJitterBuffer
+	if jitterEnabled && !opts.memory.Reserve(memJitter, jitterBufferMem) {
+		log.Warnw("disabling jitter buffer, over memory budget", nil)
+		jitterEnabled = false
+	}
 	mediaTimeout := make(chan struct{})
+	events := new(mediaEvents)
+	packetLog := newSampledLogger(log)
+	port := newUDPConn(packetLog, conn, opts.Stats, events)
+	port.icmp.max = opts.ICMPMaxErrors
+	if opts.ICMPMaxErrors > 0 {
+		if err := enableICMPErrors(conn); err != nil {
+			log.Debugw("cannot enable ICMP errors on media socket", "error", err)
+		}
+		drainICMPErrors(conn) // errors left from the previous call on this port
+	}
 	p := &MediaPort{
 		log:           log,
+		packetLog:     packetLog,
 		opts:          opts,
 		mon:           mon,
 		externalIP:    opts.IP,
 		mediaTimeout:  mediaTimeout,
 		timeoutReset:  make(chan struct{}, 1),
-		jitterEnabled: opts.EnableJitterBuffer,
-		port:          newUDPConn(log, conn),
+		jitterEnabled: jitterEnabled,
+		events:        events,
+		port:          port,
+		streams:       newRTPStreamGuard(maxRTPStreams(opts.MaxInputStreams), opts.StreamRate),
+		releaseSocket: opts.resources.Acquire(leakSocket),
+		tcpLn:         tcpLn,
 		audioOut:      msdk.NewSwitchWriter(sampleRate),
 		audioIn:       msdk.NewSwitchWriter(sampleRate),
+		dtmfOpts:      DTMFOptions{}.withDefaults(),
 		stats:         opts.Stats,
+		talk:          vad.NewTalkStats(vad.Config{}),
+		drift:         drift.NewEstimator(),
+		rtcpSSRC:      rand.Uint32(),
 	}
-	go p.timeoutLoop(func() {
-		close(mediaTimeout)
+	p.goLabeled("timeoutLoop", "", func() {
+		p.timeoutLoop(func() {
+			close(mediaTimeout)
+		})
 	})
+	if opts.OneWayTimeout > 0 {
+		p.goLabeled("oneWayLoop", "", p.oneWayLoop)
+	}
 	p.log.Debugw("listening for media on UDP", "port", p.Port())
 	return p, nil
 }
@@ -173,40 +464,101 @@ func NewMediaPortWith(log logger.Logger, mon *stats.CallMonitor, conn UDPConn, o
 // MediaPort combines all functionality related to sending and accepting SIP media.
 type MediaPort struct {
 	log              logger.Logger
+	packetLog        logger.Logger // sampled, for lines logged per packet or stream
 	opts             *MediaOptions
 	mon              *stats.CallMonitor
 	externalIP       netip.Addr
 	port             *udpConn
+	releaseSocket    func()
+	tcpLn            net.Listener // optional, accepts RTP over TCP
+	events           *mediaEvents
 	mediaReceived    core.Fuse
 	packetCount      atomic.Uint64
 	mediaTimeout     <-chan struct{}
 	timeoutStart     atomic.Pointer[time.Time]
 	timeoutReset     chan struct{}
+	timeoutPaused    timeoutPauses // set while the remote is not expected to send media
+	held             atomic.Bool   // set while the remote holds the call
 	closed           core.Fuse
 	stats            *PortStats
 	dtmfAudioEnabled bool
+	dtmfMu           sync.Mutex // serializes DTMF sequences
 	jitterEnabled    bool
+	recv             rtpRecvStats
+	talk             *vad.TalkStats
+	speech           atomic.Pointer[vad.Detector] // optional, detects speech of the remote for barge-in
+	drift            *drift.Estimator
+	cont             rtpContinuity
+	rtcpSSRC         uint32        // SSRC of RTCP reports until RTP is sent
+	sendSSRC         atomic.Uint64 // SSRC of sent RTP, see rtpSSRCWriter
 
 	mu           sync.Mutex
 	conf         *MediaConf
+	started      time.Time
 	sess         rtp.Session
+	srtp         *srtpConn
+	streams      *rtpStreamGuard // drops packets of streams over the limits, kept across re-INVITEs
+	sdpOrigin    *psdp.Origin
+	sdpBody      []byte // last SDP without origin, to detect changes
+	remoteHost   string // host name from remote SDP, if it had one instead of an IP
 	hnd          atomic.Pointer[rtp.HandlerCloser]
 	dtmfOutRTP   *rtp.Stream
 	dtmfOutAudio msdk.PCM16Writer
+	dtmfOpts     DTMFOptions
 
 	audioOutRTP    *rtp.Stream
+	pacer          *rtpPacer          // optional, paces RTP sent to SIP
 	audioOut       *msdk.SwitchWriter // LK PCM -> SIP RTP
 	audioIn        *msdk.SwitchWriter // SIP RTP -> LK PCM
 	audioInHandler rtp.Handler        // for debug only
-	dtmfIn         atomic.Pointer[func(ev dtmf.Event)]
+	dtmfIn         atomic.Pointer[func(ev DTMFEvent)]
+
+	outMu       sync.Mutex
+	outDisabled bool
+	outMuted    bool
+	outHeld     bool
+
+	inMu     sync.Mutex
+	inMixer  *mixer.Mixer       // mixes concurrent RTP streams, if the remote sends more than one
+	inMixOut *msdk.SwitchWriter // mixer -> LK PCM
+	inGen    atomic.Uint64      // incremented when the primary decoding pipeline is recreated
+	stages   []MediaStage       // process decoded audio before it's sent to LK
+	pipe     *mediaPipeline     // runs stages for the current audio writer
 }
 
 func (p *MediaPort) DisableOut() {
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+	p.outDisabled = true
 	p.audioOut.Disable()
 }
 
 func (p *MediaPort) EnableOut() {
-	p.audioOut.Enable()
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+	p.outDisabled = false
+	if !p.outMuted && !p.outHeld {
+		p.audioOut.Enable()
+	}
+}
+
+// outSending reports if audio can be sent to SIP: the call is answered and not on hold. Muted calls still send silence.
+func (p *MediaPort) outSending() bool {
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+	return !p.outDisabled && !p.outHeld
+}
+
+// SetMuted mutes audio sent to SIP. Unlike DisableOut, mute is requested from the room and is kept when output is re-enabled.
+func (p *MediaPort) SetMuted(muted bool) {
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+	p.outMuted = muted
+	if muted {
+		p.audioOut.Disable()
+	} else if !p.outDisabled && !p.outHeld {
+		p.audioOut.Enable()
+	}
 }
 
 func (p *MediaPort) EnableTimeout(enabled bool) {
@@ -225,15 +577,98 @@ func (p *MediaPort) EnableTimeout(enabled bool) {
 	)
 }
 
+// MediaTimeoutReason describes which kind of media was missing when media timeout triggered.
+type MediaTimeoutReason int
+
+const (
+	// MediaTimeoutNoMedia means nothing was received: no RTP, no RTCP and no keep-alives.
+	MediaTimeoutNoMedia = MediaTimeoutReason(iota)
+	// MediaTimeoutSilence means remote only sent comfort noise or keep-alives.
+	MediaTimeoutSilence
+	// MediaTimeoutRTPLost means remote stopped sending RTP, but RTCP is still received.
+	MediaTimeoutRTPLost
+)
+
+func (r MediaTimeoutReason) String() string {
+	switch r {
+	case MediaTimeoutNoMedia:
+		return "no-media"
+	case MediaTimeoutSilence:
+		return "silence"
+	case MediaTimeoutRTPLost:
+		return "rtp-lost"
+	}
+	return strconv.Itoa(int(r))
+}
+
+type mediaActivity struct {
+	Audio     uint64
+	Silence   uint64
+	KeepAlive uint64
+	RTCP      uint64
+}
+
+func (p *MediaPort) mediaActivity() mediaActivity {
+	cn := p.stats.ComfortNoisePackets.Load()
+	a := mediaActivity{
+		Audio:     p.packetCount.Load() - cn,
+		Silence:   cn,
+		KeepAlive: p.stats.KeepAlivePackets.Load(),
+		RTCP:      p.stats.RTCPPackets.Load(),
+	}
+	if !p.opts.StripZRTP {
+		a.Silence += p.stats.ZRTPPackets.Load()
+	}
+	return a
+}
+
+// livePackets returns the number of packets that count as media activity according to timeout policy.
+func (p *MediaPort) livePackets(a mediaActivity) uint64 {
+	n := a.Audio
+	if !p.opts.MediaTimeoutIgnoreCN {
+		n += a.Silence
+	}
+	if p.opts.MediaTimeoutKeepAlive {
+		n += a.KeepAlive
+	}
+	if p.opts.MediaTimeoutRTCP {
+		n += a.RTCP
+	}
+	return n
+}
+
+// goLabeled starts a media goroutine with pprof labels of the call, so that CPU profiles can be attributed
+// to calls, trunks and codecs. Labels are set once: a goroutine keeps the codec it was started with.
+func (p *MediaPort) goLabeled(loop, codec string, fn func()) {
+	labels := pprof.Labels("loop", loop, "call_id", p.opts.CallID, "trunk_id", p.opts.TrunkID, "codec", codec)
+	go pprof.Do(context.Background(), labels, func(context.Context) {
+		fn()
+	})
+}
+
+// codecName returns the name of the negotiated audio codec, or an empty string if media is not configured yet.
+func (p *MediaPort) codecName() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conf == nil || p.conf.Audio.Codec == nil {
+		return ""
+	}
+	return p.conf.Audio.Codec.Info().SDPName
+}
+
 func (p *MediaPort) timeoutLoop(timeoutCallback func()) {
 	tickInterval := p.opts.MediaTimeout
 	ticker := time.NewTicker(tickInterval)
 	defer ticker.Stop()
+	defer p.opts.resources.Acquire(leakTimer)()
 
 	var (
 		lastPackets  uint64
 		startPackets uint64
 		lastTime     time.Time
+		last         mediaActivity
+		lastSilence  time.Time
+		lastRTCP     time.Time
 	)
 	for {
 		select {
@@ -241,19 +676,36 @@ func (p *MediaPort) timeoutLoop(timeoutCallback func()) {
 			return
 		case <-p.timeoutReset:
 			ticker.Reset(tickInterval)
-			startPackets = p.packetCount.Load()
+			startPackets = p.livePackets(p.mediaActivity())
 			lastTime = time.Now()
 		case <-ticker.C:
-			curPackets := p.packetCount.Load()
+			now := time.Now()
+			cur := p.mediaActivity()
+			if cur.Silence != last.Silence || cur.KeepAlive != last.KeepAlive {
+				lastSilence = now
+			}
+			if cur.RTCP != last.RTCP {
+				lastRTCP = now
+			}
+			last = cur
+			curPackets := p.livePackets(cur)
 			if curPackets != lastPackets {
 				lastPackets = curPackets
-				lastTime = time.Now()
+				lastTime = now
+				if p.events.State() == MediaStateDegraded {
+					p.log.Infow("media restored")
+					p.events.emit(MediaEvent{Type: MediaEventRestored, State: MediaStateOK})
+				}
 				continue // wait for the next tick
 			}
 			startPtr := p.timeoutStart.Load()
 			if startPtr == nil {
 				continue // timeout disabled
 			}
+			if p.timeoutPaused.Load() {
+				lastTime = now // remote is not sending, restart the timeout when it resumes
+				continue
+			}
 
 			// First timeout is allowed to be longer. Skip ticks if it's too early.
 			sinceStart := time.Since(*startPtr)
@@ -266,7 +718,21 @@ func (p *MediaPort) timeoutLoop(timeoutCallback func()) {
 			if sinceLast < p.opts.MediaTimeout {
 				continue
 			}
-			p.log.Infow("triggering media timeout",
+			reason := MediaTimeoutNoMedia
+			if now.Sub(lastSilence) < p.opts.MediaTimeout {
+				reason = MediaTimeoutSilence
+			} else if now.Sub(lastRTCP) < p.opts.MediaTimeout {
+				reason = MediaTimeoutRTPLost
+			}
+			p.stats.MediaTimeouts.Add(1)
+			if reason == MediaTimeoutNoMedia {
+				// Resolved address may be stale, let the next call resolve it again.
+				p.mu.Lock()
+				sdpHosts.Forget(p.remoteHost)
+				p.mu.Unlock()
+			}
+			log := p.log.WithValues(
+				"reason", reason.String(),
 				"packets", lastPackets,
 				"startPackets", startPackets,
 				"sinceStart", sinceStart,
@@ -274,6 +740,15 @@ func (p *MediaPort) timeoutLoop(timeoutCallback func()) {
 				"initial", p.opts.MediaTimeoutInitial,
 				"timeout", p.opts.MediaTimeout,
 			)
+			if p.opts.MediaTimeoutWarnOnly {
+				log.Warnw("media timeout detected, ignoring", nil)
+				p.events.emit(MediaEvent{Type: MediaEventDegraded, State: MediaStateDegraded, Reason: reason})
+				// Do not warn again until the next full timeout interval.
+				lastTime = now
+				continue
+			}
+			log.Infow("triggering media timeout")
+			p.events.emit(MediaEvent{Type: MediaEventTimeout, State: MediaStateLost, Reason: reason})
 			timeoutCallback()
 			return
 		}
@@ -291,6 +766,10 @@ func (p *MediaPort) Close() {
 			_ = w.Close()
 		}
 		p.audioOutRTP = nil
+		if p.pacer != nil {
+			p.pacer.Close()
+			p.pacer = nil
+		}
 		p.audioInHandler = nil
 		p.dtmfOutRTP = nil
 		if p.dtmfOutAudio != nil {
@@ -301,12 +780,28 @@ func (p *MediaPort) Close() {
 		if p.sess != nil {
 			_ = p.sess.Close()
 		}
+		if p.tcpLn != nil {
+			_ = p.tcpLn.Close()
+		}
 		_ = p.port.Close()
+		p.releaseSocket()
+		if p.jitterEnabled {
+			p.opts.memory.Release(jitterBufferMem)
+		}
 
 		hnd := p.hnd.Load()
 		if hnd != nil {
 			(*hnd).Close()
 		}
+
+		p.inMu.Lock()
+		defer p.inMu.Unlock()
+		if p.inMixer != nil {
+			p.inMixer.Stop()
+			_ = p.inMixOut.Close()
+		}
+		closeMediaStages(p.stages)
+		p.stages, p.pipe = nil, nil
 	})
 }
 
@@ -322,56 +817,373 @@ func (p *MediaPort) Timeout() <-chan struct{} {
 	return p.mediaTimeout
 }
 
+// OnEvent registers a handler for media state transitions. See MediaEvent.
+//
+// Handlers are called synchronously from media goroutines and must not block. The returned function removes the handler.
+func (p *MediaPort) OnEvent(fnc func(ev MediaEvent)) func() {
+	return p.events.OnEvent(fnc)
+}
+
+// MediaState returns current state of the media stream.
+func (p *MediaPort) MediaState() MediaState {
+	return p.events.State()
+}
+
+// ZRTPDetected checks if the remote attempted ZRTP negotiation.
+func (p *MediaPort) ZRTPDetected() bool {
+	return p.port.zrtp.Load()
+}
+
+// VoIPMetrics returns reception quality metrics of the call (RFC 3611).
+func (p *MediaPort) VoIPMetrics() VoIPMetrics {
+	return p.recv.Metrics(rtp.DefFrameDur)
+}
+
+// Started returns the time when media was configured, or zero time if it's not configured yet.
+func (p *MediaPort) Started() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.started
+}
+
+// JitterBufferEnabled reports if the jitter buffer is used for incoming audio.
+func (p *MediaPort) JitterBufferEnabled() bool {
+	return p.jitterEnabled
+}
+
 func (p *MediaPort) Config() *MediaConf {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	return p.conf
 }
 
+// TalkStats returns talk time statistics of the call. Remote side is SIP, local side is the room.
+func (p *MediaPort) TalkStats() vad.TalkSummary {
+	return p.talk.Summary()
+}
+
+// ResetTalkStats discards talk time statistics collected before the call was answered.
+func (p *MediaPort) ResetTalkStats() {
+	p.talk.Reset()
+}
+
 // WriteAudioTo sets audio writer that will receive decoded PCM from incoming RTP packets.
 func (p *MediaPort) WriteAudioTo(w msdk.PCM16Writer) {
-	if processor := p.conf.Processor; processor != nil {
-		w = processor(w)
+	p.inMu.Lock()
+	defer p.inMu.Unlock()
+	if p.opts.EnableDriftCorrection {
+		w = drift.NewCorrector(w, p.drift.Drift, &p.stats.DriftSamples)
+	}
+	p.pipe = newMediaPipeline(w, p.stages)
+	w = p.talk.Writer(vad.SideRemote, &speechTap{p: p, w: p.pipe})
+	dst := p.audioIn
+	if p.inMixOut != nil {
+		dst = p.inMixOut
 	}
-	if pw := p.audioIn.Swap(w); pw != nil {
+	if pw := dst.Swap(w); pw != nil {
 		_ = pw.Close()
 	}
 }
 
+// SetStages replaces media stages processing audio received from SIP. It can be called during the call.
+// Stages that are not in the new list are closed.
+func (p *MediaPort) SetStages(stages []MediaStage) {
+	p.updateStages(func([]MediaStage) []MediaStage {
+		return slices.Clone(stages)
+	})
+}
+
+func (p *MediaPort) updateStages(fnc func(stages []MediaStage) []MediaStage) {
+	p.inMu.Lock()
+	defer p.inMu.Unlock()
+	stages := fnc(slices.Clone(p.stages))
+	var removed []MediaStage
+	for _, st := range p.stages {
+		if !slices.Contains(stages, st) {
+			removed = append(removed, st)
+		}
+	}
+	p.stages = stages
+	if p.pipe != nil {
+		p.pipe.SetStages(stages)
+	}
+	closeMediaStages(removed)
+}
+
+// Stages returns media stages processing audio received from SIP.
+func (p *MediaPort) Stages() []MediaStage {
+	p.inMu.Lock()
+	defer p.inMu.Unlock()
+	return slices.Clone(p.stages)
+}
+
+// DetectSpeech passes audio received from SIP to the detector, until the returned function is called.
+// Only one detector is active, the last one replaces the previous.
+func (p *MediaPort) DetectSpeech(d *vad.Detector) func() {
+	p.speech.Store(d)
+	return func() {
+		p.speech.CompareAndSwap(d, nil)
+	}
+}
+
+// speechTap passes audio to the speech detector of the port, if any. It doesn't change the audio.
+type speechTap struct {
+	p *MediaPort
+	w msdk.PCM16Writer
+}
+
+func (t *speechTap) String() string {
+	return t.w.String()
+}
+
+func (t *speechTap) SampleRate() int {
+	return t.w.SampleRate()
+}
+
+func (t *speechTap) WriteSample(sample msdk.PCM16Sample) error {
+	if d := t.p.speech.Load(); d != nil {
+		d.Process(sample, t.w.SampleRate())
+	}
+	return t.w.WriteSample(sample)
+}
+
+func (t *speechTap) Close() error {
+	return t.w.Close()
+}
+
 // GetAudioWriter returns audio writer that will send PCM to the destination via RTP.
 func (p *MediaPort) GetAudioWriter() msdk.PCM16Writer {
 	return p.audioOut
 }
 
 // NewOffer generates an SDP offer for the media.
+//
+// It can be called again on an established session to generate a re-INVITE offer with new SRTP keys.
 func (p *MediaPort) NewOffer(encrypted sdp.Encryption) (*sdp.Offer, error) {
-	return sdp.NewOffer(p.externalIP, p.Port(), encrypted)
+	// Crypto is negotiated here instead of media-sdk, since we need control over suites and their order.
+	offer, err := sdp.NewOffer(p.externalIP, p.Port(), sdp.EncryptionNone)
+	if err != nil {
+		return nil, err
+	}
+	if encrypted != sdp.EncryptionNone {
+		crypto, err := newSRTPOffer(p.opts.SRTPSuites)
+		if err != nil {
+			return nil, err
+		}
+		setSRTPCrypto(offer.SDP.MediaDescriptions[0], crypto)
+		offer.CryptoProfiles = srtpToProfiles(crypto)
+		if p.opts.OfferPlainRTP {
+			addPlainAudioMedia(&offer.SDP)
+		}
+	}
+	switch p.opts.RTPTransport {
+	case config.MediaTransportTCP:
+		for _, m := range offer.SDP.MediaDescriptions {
+			setTCPMedia(m, tcpSetupActPass)
+		}
+	case config.MediaTransportAuto:
+		addTCPAudioMedia(&offer.SDP)
+	}
+	p.setOrigin(&offer.SDP)
+	return offer, nil
+}
+
+// setOrigin keeps SDP origin the same for the whole session, as required for re-INVITEs (RFC 3264, section 8).
+// Session version is only incremented if the session description changes.
+func (p *MediaPort) setOrigin(s *psdp.SessionDescription) {
+	if p.opts.SDP.SessionName != "" {
+		s.SessionName = psdp.SessionName(p.opts.SDP.SessionName)
+	}
+	if p.opts.SDP.Username != "" {
+		s.Origin.Username = p.opts.SDP.Username
+	}
+	body := sdpWithoutOrigin(s)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sdpOrigin == nil {
+		// Some carriers parse these as signed integers, keep them far from overflow.
+		s.Origin.SessionID &= math.MaxInt64 >> 1
+		s.Origin.SessionVersion &= math.MaxInt64 >> 1
+	} else {
+		version := p.sdpOrigin.SessionVersion
+		if !bytes.Equal(body, p.sdpBody) {
+			version++
+		}
+		s.Origin = *p.sdpOrigin
+		s.Origin.SessionVersion = version
+	}
+	cp := s.Origin
+	p.sdpOrigin = &cp
+	p.sdpBody = body
+}
+
+// sdpWithoutOrigin returns the session description with an empty origin line.
+func sdpWithoutOrigin(s *psdp.SessionDescription) []byte {
+	cp := *s
+	cp.Origin = psdp.Origin{}
+	data, _ := cp.Marshal()
+	return data
+}
+
+// resolveHosts replaces host names in SDP connection lines with IP addresses.
+func (p *MediaPort) resolveHosts(data []byte) ([]byte, error) {
+	data, host, err := resolveSDPHosts(sdpHosts, data, p.opts.SignalingAddr)
+	if err != nil {
+		return nil, err
+	}
+	if host != "" {
+		p.log.Debugw("resolved host name in sdp", "host", host)
+		p.mu.Lock()
+		p.remoteHost = host
+		p.mu.Unlock()
+	}
+	return data, nil
+}
+
+// lenientSDP fixes malformed remote SDP if lenient parsing is enabled, and logs fixed anomalies.
+func (p *MediaPort) lenientSDP(data []byte) []byte {
+	if !p.opts.SDP.Lenient {
+		return data
+	}
+	data, anomalies := sanitizeSDP(data)
+	if len(anomalies) != 0 {
+		p.log.Infow("fixed malformed sdp", "anomalies", anomalies)
+	}
+	return data
 }
 
 // SetAnswer decodes and applies SDP answer for offer from NewOffer. SetConfig must be called with the decoded configuration.
 func (p *MediaPort) SetAnswer(offer *sdp.Offer, answerData []byte, enc sdp.Encryption) (*MediaConf, error) {
+	answerData, err := p.resolveHosts(p.lenientSDP(answerData))
+	if err != nil {
+		return nil, err
+	}
+	answerData, _, _, err = selectAudioMedia(answerData, true, p.opts.RTPTransport)
+	if err != nil {
+		return nil, err
+	}
+	if hasZRTPHash(answerData) {
+		p.port.reportZRTP("sdp", "")
+	}
+	answerData, crypto := stripSRTPCrypto(answerData)
 	answer, err := sdp.ParseAnswer(answerData)
 	if err != nil {
 		return nil, err
 	}
-	mc, err := answer.Apply(offer, enc)
+	mc, err := answer.Apply(offer, sdp.EncryptionNone)
 	if err != nil {
 		return nil, err
 	}
-	return &MediaConf{MediaConfig: *mc}, nil
+	c := &MediaConf{MediaConfig: *mc}
+	audio := sdp.GetAudio(&answer.SDP)
+	if !allowsMedia(p.opts.RTPTransport, audio) {
+		return nil, siperrors.ErrMediaTransportForbidden
+	}
+	c.Direction = sdpMediaDirection(&answer.SDP, audio)
+	if isTCPMedia(audio) {
+		c.TCP = &TCPMediaConf{Active: tcpMediaSetup(audio) == tcpSetupPassive}
+	} else {
+		c.RemoteRTCP = rtcpRemoteAddr(audio, c.Remote)
+	}
+	if enc != sdp.EncryptionNone {
+		remote, local := findSRTPAnswer(srtpFromProfiles(offer.CryptoProfiles), crypto)
+		if remote != nil {
+			if c.Crypto, err = newSRTPConfig(local, remote); err != nil {
+				return nil, err
+			}
+			c.RemoteSRTP = srtpKeyParams(remote)
+		}
+		c.Downgraded = c.Crypto == nil
+	}
+	if c.Crypto == nil && enc == sdp.EncryptionRequire {
+		return nil, sdp.ErrNoCommonCrypto
+	}
+	return c, nil
 }
 
 // SetOffer decodes the offer from another party and returns encoded answer. To accept the offer, call SetConfig.
 func (p *MediaPort) SetOffer(offerData []byte, enc sdp.Encryption) (*sdp.Answer, *MediaConf, error) {
+	offerData, err := p.resolveHosts(p.lenientSDP(offerData))
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.setOffer(offerData, enc)
+}
+
+func (p *MediaPort) setOffer(fullOffer []byte, enc sdp.Encryption) (*sdp.Answer, *MediaConf, error) {
+	offerData, origOffer, sel, err := selectAudioMedia(fullOffer, enc != sdp.EncryptionNone, p.opts.RTPTransport)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hasZRTPHash(offerData) {
+		p.port.reportZRTP("sdp", "")
+	}
+	offerData, crypto := stripSRTPCrypto(offerData)
 	offer, err := sdp.ParseOffer(offerData)
 	if err != nil {
 		return nil, nil, err
 	}
-	answer, mc, err := offer.Answer(p.externalIP, p.Port(), enc)
+	audio := sdp.GetAudio(&offer.SDP)
+	if p.opts.RejectEncrypted && isSecureMedia(audio) {
+		return nil, nil, siperrors.ErrMediaEncryptionForbidden
+	}
+	if !allowsMedia(p.opts.RTPTransport, audio) {
+		return nil, nil, siperrors.ErrMediaTransportForbidden
+	}
+	answer, mc, err := offer.Answer(p.externalIP, p.Port(), sdp.EncryptionNone)
 	if err != nil {
 		return nil, nil, err
 	}
-	return answer, &MediaConf{MediaConfig: *mc}, nil
+	c := &MediaConf{MediaConfig: *mc}
+	c.Direction = sdpMediaDirection(&offer.SDP, audio)
+	if c.Direction != dirSendRecv {
+		setMediaDirection(answer.SDP.MediaDescriptions[0], answerDirection(c.Direction))
+	}
+	if isTCPMedia(audio) {
+		setup := tcpAnswerSetup(tcpMediaSetup(audio))
+		setTCPMedia(answer.SDP.MediaDescriptions[0], setup)
+		c.TCP = &TCPMediaConf{Active: setup == tcpSetupActive}
+	} else {
+		c.RemoteRTCP = rtcpRemoteAddr(audio, c.Remote)
+	}
+	if enc != sdp.EncryptionNone && len(crypto) != 0 {
+		remote, local, err := selectSRTPAnswer(crypto, p.opts.SRTPSuites)
+		if err != nil {
+			return nil, nil, err
+		}
+		if remote != nil {
+			if c.Crypto, err = newSRTPConfig(local, remote); err != nil {
+				return nil, nil, err
+			}
+			c.RemoteSRTP = srtpKeyParams(remote)
+			setSRTPCrypto(answer.SDP.MediaDescriptions[0], []srtpCrypto{*local})
+			answer.CryptoProfiles = srtpToProfiles([]srtpCrypto{*local})
+		} else if p.opts.SRTPRejectDisallowed {
+			p.log.Infow("rejecting offer with disallowed crypto suites", "suites", srtpSuiteNames(crypto))
+			return nil, nil, sdp.ErrNoCommonCrypto
+		}
+		c.Downgraded = c.Crypto == nil
+	}
+	if c.Crypto == nil && enc == sdp.EncryptionRequire {
+		return nil, nil, sdp.ErrNoCommonCrypto
+	}
+	if c.Crypto == nil && enc != sdp.EncryptionNone && isSecureMedia(audio) {
+		// Plain RTP is not a valid answer to an encrypted media line. Accept the unencrypted alternative instead, if any.
+		if !hasPlainAudioMedia(origOffer, p.opts.RTPTransport) {
+			p.log.Infow("rejecting encrypted offer without usable crypto suites", "suites", srtpSuiteNames(crypto))
+			return nil, nil, sdp.ErrNoCommonCrypto
+		}
+		answer, c, err := p.setOffer(fullOffer, sdp.EncryptionNone)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.Downgraded = true
+		return answer, c, nil
+	}
+	answerAudioMedia(&answer.SDP, origOffer, sel)
+	p.setOrigin(&answer.SDP)
+	return answer, c, nil
 }
 
 func (p *MediaPort) SetConfig(c *MediaConf) error {
@@ -389,33 +1201,179 @@ func (p *MediaPort) SetConfig(c *MediaConf) error {
 	)
 
 	p.port.SetDst(c.Remote)
+	p.setTransport(c)
+	p.setDirection(c.Direction)
+	p.recv.SetCodec(c.Audio.Codec.Info().SDPName, c.Audio.Codec.Info().RTPClockRate)
+	p.drift.SetCodec(c.Audio.Type, c.Audio.Codec.Info().RTPClockRate)
+	p.cont.SetCodec(c.Audio.Type, c.Audio.Codec.Info().RTPClockRate)
 	var (
 		sess rtp.Session
 		err  error
 	)
+	var sconn *srtpConn
 	if c.Crypto != nil {
-		sess, err = srtp.NewSession(p.log, p.port, c.Crypto)
+		sconn = newSRTPConn(p.log, p.port, p.stats)
+		sconn.onExpire = p.onKeyExpiring
+		sconn.onFailures = p.onSRTPFailures
+		sconn.maxFailures = uint64(max(p.opts.SRTPMaxFailures, 0))
+		if err = sconn.SetKeys(c.Crypto, c.RemoteSRTP, 0); err != nil {
+			return err
+		}
+		sess = rtp.NewSession(p.log, newStreamGuardConn(p.packetLog, sconn, p.stats, p.streams))
 	} else {
-		sess = rtp.NewSession(p.log, p.port)
-	}
-	if err != nil {
-		return err
+		sess = rtp.NewSession(p.log, newStreamGuardConn(p.packetLog, p.port, p.stats, p.streams))
 	}
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.port.SetDst(c.Remote)
 	p.conf = c
+	p.started = time.Now()
 	p.sess = sess
+	p.srtp = sconn
 
 	if err = p.setupOutput(); err != nil {
 		return err
 	}
+	p.goLabeled("rtpLoop", c.Audio.Codec.Info().SDPName, func() {
+		p.rtpLoop(sess)
+	})
+	if p.opts.RTCPXRInterval > 0 && sconn == nil {
+		p.goLabeled("rtcpLoop", c.Audio.Codec.Info().SDPName, p.rtcpLoop)
+	}
 	p.setupInput()
 	return nil
 }
 
+// rtcpLoop periodically sends RTCP-XR VoIP metrics of the received stream to the remote.
+func (p *MediaPort) rtcpLoop() {
+	ticker := time.NewTicker(p.opts.RTCPXRInterval)
+	defer ticker.Stop()
+	defer p.opts.resources.Acquire(leakTimer)()
+	for {
+		select {
+		case <-p.closed.Watch():
+			return
+		case <-ticker.C:
+		}
+		if err := p.sendRTCPXR(); err != nil {
+			p.packetLog.Debugw("cannot send rtcp-xr report", "error", err)
+		}
+	}
+}
+
+// sendRTCPXR sends a compound RTCP packet with VoIP metrics to the RTCP address of the remote.
+// Nothing is sent until RTP is received.
+func (p *MediaPort) sendRTCPXR() error {
+	p.mu.Lock()
+	var dst netip.AddrPort
+	if p.conf != nil {
+		dst = p.conf.RemoteRTCP
+	}
+	p.mu.Unlock()
+	if !dst.IsValid() {
+		return nil
+	}
+	m := p.VoIPMetrics()
+	if m.Expected == 0 {
+		return nil
+	}
+	ssrc := p.rtcpSSRC
+	if v := p.sendSSRC.Load(); v != 0 {
+		ssrc = uint32(v)
+	}
+	data, err := rtcpXRPacket(ssrc, &m, p.jitterEnabled)
+	if err != nil {
+		return err
+	}
+	_, err = p.port.WriteToUDPAddrPort(data, dst)
+	return err
+}
+
+// UpdateConfig applies media configuration renegotiated on an established session (re-INVITE).
+//
+// Unlike SetConfig, it keeps the RTP session running, so new SRTP keys and codecs are applied without gaps.
+// If the configuration is a result of answering a remote offer, local SRTP key switch is delayed slightly
+// to let the remote install our new key first.
+func (p *MediaPort) UpdateConfig(c *MediaConf, answered bool) error {
+	if err := p.updateConfig(c, answered); err != nil {
+		return err
+	}
+	p.setDirection(c.Direction)
+	return nil
+}
+
+func (p *MediaPort) updateConfig(c *MediaConf, answered bool) error {
+	if p.closed.IsBroken() {
+		return errors.New("media is already closed")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sess == nil {
+		return errors.New("media is not configured")
+	}
+	if (c.Crypto == nil) != (p.srtp == nil) {
+		return errors.New("cannot change media encryption during the call")
+	}
+	if (c.TCP == nil) != (p.conf.TCP == nil) {
+		return errors.New("cannot change media transport during the call")
+	}
+	if c.Crypto != nil {
+		var delay time.Duration
+		if answered {
+			delay = srtpAnswerSendDelay
+		}
+		if err := p.srtp.SetKeys(c.Crypto, c.RemoteSRTP, delay); err != nil {
+			return err
+		}
+	}
+	p.port.SetDst(c.Remote)
+	prev := p.conf
+	p.conf = c
+	if prev.Audio.Type == c.Audio.Type && prev.Audio.DTMFType == c.Audio.DTMFType &&
+		prev.Audio.Codec.Info().SDPName == c.Audio.Codec.Info().SDPName {
+		return nil
+	}
+	p.log.Infow("changing codecs",
+		"audio-codec", c.Audio.Codec.Info().SDPName, "audio-rtp", c.Audio.Type,
+		"dtmf-rtp", c.Audio.DTMFType,
+	)
+	p.recv.SetCodec(c.Audio.Codec.Info().SDPName, c.Audio.Codec.Info().RTPClockRate)
+	p.drift.SetCodec(c.Audio.Type, c.Audio.Codec.Info().RTPClockRate)
+	p.cont.SetCodec(c.Audio.Type, c.Audio.Codec.Info().RTPClockRate)
+	if err := p.setupOutput(); err != nil {
+		return err
+	}
+	p.setupInput()
+	return nil
+}
+
+// setTransport switches the media port to RTP over TCP, if it was negotiated. Otherwise, TCP listener is closed.
+func (p *MediaPort) setTransport(c *MediaConf) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tcpLn == nil {
+		return
+	}
+	if c.TCP == nil {
+		_ = p.tcpLn.Close()
+		p.tcpLn = nil
+		return
+	}
+	p.log.Infow("using rtp over tcp", "active", c.TCP.Active)
+	p.port.UDPConn = newTCPMediaConn(p.log, p.port.UDPConn, p.tcpLn, c.TCP, c.Remote, p.opts.SignalingAddr)
+}
+
+func (p *MediaPort) onKeyExpiring() {
+	p.events.emit(MediaEvent{Type: MediaEventKeyExpiring})
+}
+
+func (p *MediaPort) onSRTPFailures() {
+	p.events.emit(MediaEvent{Type: MediaEventSRTPFailures})
+}
+
 func (p *MediaPort) rtpLoop(sess rtp.Session) {
+	var inputs rtpInputs
 	// Need a loop to process all incoming packets.
 	for {
 		r, ssrc, err := sess.AcceptStream()
@@ -426,17 +1384,59 @@ func (p *MediaPort) rtpLoop(sess rtp.Session) {
 			return
 		}
 		p.stats.Streams.Add(1)
-		p.mediaReceived.Break()
-		log := p.log.WithValues("ssrc", ssrc)
-		log.Infow("accepting RTP stream")
-		go p.rtpReadLoop(log, r)
+		if p.mediaReceived.Break() {
+			if started := p.Started(); p.mon != nil && !started.IsZero() {
+				p.mon.SetupPhaseObserve(stats.SetupFirstMedia, time.Since(started))
+			}
+			p.events.emit(MediaEvent{Type: MediaEventReceived, State: MediaStateOK})
+		}
+		log := p.packetLog.WithValues("ssrc", ssrc)
+		in := &rtpInput{}
+		now := time.Now()
+		active := activeRTPInputs(inputs.list, now)
+		kind := inputs.kind(now, p.opts.MaxInputStreams)
+		switch kind {
+		case rtpInputPrimary:
+			if inputs.primary != nil {
+				log.Infow("accepting RTP stream, replacing idle primary stream")
+			} else {
+				log.Infow("accepting RTP stream")
+			}
+		case rtpInputShared:
+			log.Infow("accepting RTP stream")
+		case rtpInputIgnored:
+			log.Warnw("ignoring RTP stream, too many concurrent streams", nil, "streams", active, "max", p.opts.MaxInputStreams)
+			continue
+		case rtpInputMixed:
+			size := mixer.InputBufferSize(p.audioIn.SampleRate(), rtp.DefFrameDur)
+			if p.jitterEnabled {
+				size += jitterBufferMem
+			}
+			if !p.opts.memory.Reserve(memMixer, size) {
+				log.Warnw("ignoring RTP stream, over memory budget", nil, "streams", active)
+				continue
+			}
+			in.release = func() {
+				p.opts.memory.Release(size)
+			}
+			log.Infow("accepting RTP stream, mixing with other streams", "streams", active)
+			in.mix = p.newMixInput()
+			if in.mix == nil {
+				in.release()
+				continue // closed
+			}
+		}
+		inputs.add(in, kind, now)
+		p.goLabeled("rtpReadLoop", p.codecName(), func() {
+			p.rtpReadLoop(log, r, in)
+		})
 	}
 }
 
-func (p *MediaPort) rtpReadLoop(log logger.Logger, r rtp.ReadStream) {
+func (p *MediaPort) rtpReadLoop(log logger.Logger, r rtp.ReadStream, in *rtpInput) {
+	defer in.Close()
 	const maxErrors = 50 // 1 sec, given 20 ms frames
 	buf := make([]byte, rtp.MTUSize+1)
-	overflow := false
 	var (
 		h        rtp.Header
 		pipeline string
@@ -453,21 +1453,44 @@ func (p *MediaPort) rtpReadLoop(log logger.Logger, r rtp.ReadStream) {
 		}
 		p.packetCount.Add(1)
 		p.stats.Packets.Add(1)
-		if n > rtp.MTUSize {
-			overflow = true
-			if !overflow {
-				log.Errorw("RTP packet is larger than MTU limit", nil, "payloadSize", n)
+		now := time.Now()
+		in.last.Store(now.UnixNano())
+		if in.mix == nil && !in.primary.Load() {
+			// Replaced by a new primary stream.
+			p.stats.IgnoredPackets.Add(1)
+			continue
+		}
+		if in.mix == nil {
+			// Reception quality is only measured for the primary stream.
+			switch p.recv.Update(&h, now) {
+			case rtpRecvGap:
+				p.stats.SeqGaps.Add(1)
+			case rtpRecvDuplicate:
+				p.stats.DuplicatePackets.Add(1)
+			case rtpRecvReordered:
+				p.stats.ReorderedPackets.Add(1)
+			case rtpRecvLate:
+				p.stats.LatePackets.Add(1)
+			}
+			if ppm, ok := p.drift.Update(&h, now); ok {
+				p.stats.ClockDrift.Store(int64(math.Round(ppm)))
+			}
+			if p.cont.Check(&h) {
+				p.stats.Discontinuities.Add(1)
+				log.Infow("RTP stream discontinuity, resetting audio pipeline", "ssrc", h.SSRC, "seq", h.SequenceNumber, "ts", h.Timestamp)
+				p.resetInput()
 			}
+		}
+		if h.PayloadType == rtpPayloadCN || n == 0 {
+			p.stats.ComfortNoisePackets.Add(1)
+		}
+		if n > rtp.MTUSize {
+			log.Errorw("RTP packet is larger than MTU limit", nil, "payloadSize", n)
 			p.stats.IgnoredPackets.Add(1)
 			continue // ignore partial messages
 		}
 
-		ptr := p.hnd.Load()
-		if ptr == nil {
-			p.stats.IgnoredPackets.Add(1)
-			continue
-		}
-		hnd := *ptr
+		hnd := p.inputHandler(in)
 		if hnd == nil {
 			p.stats.IgnoredPackets.Add(1)
 			continue
@@ -502,14 +1525,22 @@ func (p *MediaPort) setupOutput() error {
 	if p.closed.IsBroken() {
 		return errors.New("media is already closed")
 	}
-	go p.rtpLoop(p.sess)
 	w, err := p.sess.OpenWriteStream()
 	if err != nil {
 		return err
 	}
 
 	// TODO: this says "audio", but actually includes DTMF too
-	s := rtp.NewSeqWriter(newRTPStatsWriter(p.mon, "audio", w))
+	var out rtp.Writer = newRTPStatsWriter(p.mon, "audio", &rtpSSRCWriter{w: w, ssrc: &p.sendSSRC})
+	if p.pacer != nil {
+		p.pacer.Close()
+		p.pacer = nil
+	}
+	if p.opts.Pacer.active() {
+		p.pacer = newRTPPacer(out, p.opts.Pacer, p.conf.Audio.Type, p.stats)
+		out = p.pacer
+	}
+	s := rtp.NewSeqWriter(out)
 	p.audioOutRTP = s.NewStream(p.conf.Audio.Type, p.conf.Audio.Codec.Info().RTPClockRate)
 
 	// Encoding pipeline (LK PCM -> SIP RTP)
@@ -525,6 +1556,10 @@ func (p *MediaPort) setupOutput() error {
 			p.dtmfOutAudio = mix.NewInput()
 		}
 	}
+	audioOut = p.talk.Writer(vad.SideLocal, audioOut)
+	if silenceFillActive(p.opts.Silence) {
+		audioOut = newSilenceFiller(audioOut, p.opts.Silence, p.outSending, p.stats)
+	}
 
 	if w := p.audioOut.Swap(audioOut); w != nil {
 		_ = w.Close()
@@ -533,9 +1568,17 @@ func (p *MediaPort) setupOutput() error {
 }
 
 func (p *MediaPort) setupInput() {
-	// Decoding pipeline (SIP RTP -> LK PCM)
-	audioHandler := p.conf.Audio.Codec.DecodeRTP(p.audioIn, p.conf.Audio.Type)
+	audioHandler, hnd := p.newInputHandler(p.audioIn)
 	p.audioInHandler = audioHandler
+	p.hnd.Store(&hnd)
+	p.inGen.Add(1)
+}
+
+// newInputHandler creates a decoding pipeline (SIP RTP -> LK PCM) that writes audio to w.
+//
+// Must be called holding the lock
+func (p *MediaPort) newInputHandler(w msdk.PCM16Writer) (rtp.Handler, rtp.HandlerCloser) {
+	audioHandler := p.conf.Audio.Codec.DecodeRTP(w, p.conf.Audio.Type)
 
 	mux := rtp.NewMux(nil)
 	mux.SetDefault(newRTPStatsHandler(p.mon, "", nil))
@@ -548,16 +1591,10 @@ func (p *MediaPort) setupInput() {
 	if p.conf.Audio.DTMFType != 0 {
 		mux.Register(
 			p.conf.Audio.DTMFType, newRTPHandlerCount(
-				newRTPStatsHandler(p.mon, dtmf.SDPName, rtp.HandlerFunc(func(h *rtp.Header, payload []byte) error {
-					ptr := p.dtmfIn.Load()
-					if ptr == nil {
-						return nil
+				newRTPStatsHandler(p.mon, dtmf.SDPName, newDTMFReceiver(func(ev DTMFEvent) {
+					if ptr := p.dtmfIn.Load(); ptr != nil && *ptr != nil {
+						(*ptr)(ev)
 					}
-					fnc := *ptr
-					if ev, ok := dtmf.DecodeRTP(h, payload); ok && fnc != nil {
-						fnc(ev)
-					}
-					return nil
 				})),
 				&p.stats.DTMFPackets, &p.stats.DTMFBytes,
 			),
@@ -567,7 +1604,21 @@ func (p *MediaPort) setupInput() {
 	if p.jitterEnabled {
 		hnd = rtp.HandleJitter(hnd)
 	}
-	p.hnd.Store(&hnd)
+	return audioHandler, hnd
+}
+
+// resetInput recreates the decoding pipeline and the jitter buffer after a discontinuity in the RTP stream.
+func (p *MediaPort) resetInput() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conf == nil || p.closed.IsBroken() {
+		return
+	}
+	old := p.hnd.Load()
+	p.setupInput()
+	if old != nil && *old != nil {
+		(*old).Close()
+	}
 }
 
 // SetDTMFAudio forces SIP to generate audio dTMF tones in addition to digital signals.
@@ -575,8 +1626,8 @@ func (p *MediaPort) SetDTMFAudio(enabled bool) {
 	p.dtmfAudioEnabled = enabled
 }
 
-// HandleDTMF sets an incoming DTMF handler.
-func (p *MediaPort) HandleDTMF(h func(ev dtmf.Event)) {
+// HandleDTMF sets an incoming DTMF handler. Events are reported when the key is released, with the press duration.
+func (p *MediaPort) HandleDTMF(h func(ev DTMFEvent)) {
 	if h == nil {
 		p.dtmfIn.Store(nil)
 	} else {
@@ -584,10 +1635,33 @@ func (p *MediaPort) HandleDTMF(h func(ev dtmf.Event)) {
 	}
 }
 
+// SetDTMFOptions sets default pacing of DTMF digits sent with WriteDTMF.
+func (p *MediaPort) SetDTMFOptions(opts DTMFOptions) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dtmfOpts = opts
+}
+
+// WriteDTMF sends DTMF digits to SIP using default pacing options. See WriteDTMFWith.
 func (p *MediaPort) WriteDTMF(ctx context.Context, digits string) error {
+	p.mu.Lock()
+	opts := p.dtmfOpts
+	p.mu.Unlock()
+	_, err := p.WriteDTMFWith(ctx, digits, opts)
+	return err
+}
+
+// WriteDTMFWith sends DTMF digits to SIP and waits until all of them are sent.
+// Digits may contain 'w' and ',' characters for a short and a long pause. Hook flash is sent for '!' or 'f'.
+//
+// Sequences are sent one at a time. Cancelling the context stops the sequence after the current digit is ended.
+// It returns the number of digits and pauses sent.
+func (p *MediaPort) WriteDTMFWith(ctx context.Context, digits string, opts DTMFOptions) (int, error) {
 	if len(digits) == 0 {
-		return nil
+		return 0, nil
 	}
+	p.dtmfMu.Lock()
+	defer p.dtmfMu.Unlock()
 	p.mu.Lock()
 	dtmfOut := p.dtmfOutRTP
 	audioOut := p.dtmfOutAudio
@@ -597,13 +1671,14 @@ func (p *MediaPort) WriteDTMF(ctx context.Context, digits string) error {
 		audioOut = nil
 	}
 	if dtmfOut == nil && audioOut == nil {
-		return nil
+		return 0, nil
 	}
 
-	var rtpTs uint32
-	if audioOutRTP != nil {
-		rtpTs = audioOutRTP.GetCurrentTimestamp()
+	rtpTs := func() uint32 {
+		if audioOutRTP == nil {
+			return 0
+		}
+		return audioOutRTP.GetCurrentTimestamp()
 	}
-
-	return dtmf.Write(ctx, audioOut, dtmfOut, rtpTs, digits)
+	return writeDTMF(ctx, audioOut, dtmfOut, rtpTs, digits, opts.withDefaults())
 }
@@ -15,11 +15,13 @@
 package sip
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"math"
 	"net"
 	"net/netip"
+	"runtime/pprof"
 	"slices"
 	"strconv"
 	"strings"
@@ -33,6 +35,8 @@ import (
 	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
 	"github.com/livekit/protocol/logger"
 	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
 )
 
 type testUDPConn struct {
@@ -259,19 +263,19 @@ func TestMediaPort(t *testing.T) {
 
 					writes := 1
 					if tconf.Rate == nativeRate {
-						expChain := fmt.Sprintf("Switch(%d) -> %s(encode) -> RTP(%d)", nativeRate, name, nativeRate)
+						expChain := fmt.Sprintf("Switch(%d) -> TalkStats(local) -> %s(encode) -> RTP(%d)", nativeRate, name, nativeRate)
 						require.Equal(t, expChain, w1.String())
 						require.Equal(t, expChain, w2.String())
 
-						expChain = fmt.Sprintf("RTP(%d) -> %s(decode) -> Switch(%d) -> Buffer(%d)", nativeRate, name, nativeRate, nativeRate)
+						expChain = fmt.Sprintf("RTP(%d) -> %s(decode) -> Switch(%d) -> TalkStats(remote) -> Buffer(%d)", nativeRate, name, nativeRate, nativeRate)
 						require.Equal(t, expChain, PrintAudioInWriter(m1))
 						require.Equal(t, expChain, PrintAudioInWriter(m2))
 					} else {
-						expChain := fmt.Sprintf("Switch(48000) -> Resample(48000->%d) -> %s(encode) -> RTP(%d)", nativeRate, name, nativeRate)
+						expChain := fmt.Sprintf("Switch(48000) -> Resample(48000->%d) -> TalkStats(local) -> %s(encode) -> RTP(%d)", nativeRate, name, nativeRate)
 						require.Equal(t, expChain, w1.String())
 						require.Equal(t, expChain, w2.String())
 
-						expChain = fmt.Sprintf("RTP(%d) -> %s(decode) -> Resample(%d->48000) -> Switch(48000) -> Buffer(48000)", nativeRate, name, nativeRate)
+						expChain = fmt.Sprintf("RTP(%d) -> %s(decode) -> Resample(%d->48000) -> Switch(48000) -> TalkStats(remote) -> Buffer(48000)", nativeRate, name, nativeRate)
 						require.Equal(t, expChain, PrintAudioInWriter(m1))
 						require.Equal(t, expChain, PrintAudioInWriter(m2))
 
@@ -326,3 +330,234 @@ func checkPCM(t testing.TB, exp, got msdk.PCM16Sample) {
 	expHit := int(float64(len(expSamples)) * percHit)
 	require.True(t, hits >= expHit, "min=%v, max=%v\ngot:\n%v", slices.Min(got), slices.Max(got), got)
 }
+
+func TestClassifyMediaPacket(t *testing.T) {
+	rtpPkt := make([]byte, 172)
+	rtpPkt[0] = 0x80
+	rtpPkt[1] = 0
+	rtcpPkt := []byte{0x80, 201, 0, 1, 1, 2, 3, 4}                                                  // empty RR
+	stun := []byte{0x00, 0x01, 0, 0, 0x21, 0x12, 0xA4, 0x42, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12} // binding request
+
+	require.Equal(t, mediaPacketRTP, classifyMediaPacket(rtpPkt))
+	require.Equal(t, mediaPacketRTCP, classifyMediaPacket(rtcpPkt))
+	require.Equal(t, mediaPacketKeepAlive, classifyMediaPacket(nil))
+	require.Equal(t, mediaPacketKeepAlive, classifyMediaPacket([]byte("\r\n\r\n")))
+	require.Equal(t, mediaPacketKeepAlive, classifyMediaPacket(stun))
+	// Junk is not a keep-alive.
+	require.Equal(t, mediaPacketInvalid, classifyMediaPacket([]byte("ping")))
+	require.Equal(t, mediaPacketInvalid, classifyMediaPacket(make([]byte, 20)))
+	require.Equal(t, mediaPacketInvalid, classifyMediaPacket([]byte{0x80, 0, 1}))
+}
+
+func TestMediaTimeoutPolicy(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	rtcpPkt := []byte{0x80, 201, 0, 1, 1, 2, 3, 4}
+
+	for _, c := range []struct {
+		name    string
+		opts    MediaOptions
+		timeout bool
+		state   MediaState
+	}{
+		{"rtcp ignored", MediaOptions{}, true, MediaStateLost},
+		{"rtcp liveness", MediaOptions{MediaTimeoutRTCP: true}, false, MediaStateNone},
+		{"warn only", MediaOptions{MediaTimeoutWarnOnly: true}, false, MediaStateDegraded},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c1, c2 := newUDPPipe()
+			opts := c.opts
+			opts.IP = newIP("1.1.1.1")
+			opts.MediaTimeout = timeout
+			opts.MediaTimeoutInitial = timeout
+
+			m, err := NewMediaPortWith(logger.GetLogger(), nil, c1, &opts, 8000)
+			require.NoError(t, err)
+			defer m.Close()
+			defer c2.Close()
+
+			var reason atomic.Pointer[MediaTimeoutReason]
+			m.OnEvent(func(ev MediaEvent) {
+				switch ev.Type {
+				case MediaEventTimeout, MediaEventDegraded:
+					reason.Store(&ev.Reason)
+				}
+			})
+
+			offer, err := m.NewOffer(sdp.EncryptionNone)
+			require.NoError(t, err)
+			offerData, err := offer.SDP.Marshal()
+			require.NoError(t, err)
+			_, conf, err := m.SetOffer(offerData, sdp.EncryptionNone)
+			require.NoError(t, err)
+			conf.Remote = c2.addr
+			require.NoError(t, m.SetConfig(conf))
+			m.EnableTimeout(true)
+
+			deadline := time.After(6 * timeout)
+			ticker := time.NewTicker(timeout / 4)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					_, _ = c2.WriteToUDPAddrPort(rtcpPkt, c1.addr)
+					continue
+				case <-m.Timeout():
+					require.True(t, c.timeout, "unexpected timeout")
+				case <-deadline:
+					require.False(t, c.timeout, "expected timeout")
+				}
+				break
+			}
+			require.NotZero(t, opts.Stats.RTCPPackets.Load())
+			require.Equal(t, c.state, m.MediaState())
+			if c.state != MediaStateNone {
+				require.NotZero(t, opts.Stats.MediaTimeouts.Load())
+				require.Equal(t, MediaTimeoutRTPLost, *reason.Load())
+			}
+		})
+	}
+}
+
+func TestMediaTimeoutKeepAlive(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+
+	for _, c := range []struct {
+		name    string
+		opts    MediaOptions
+		pkt     []byte
+		timeout bool
+		reason  MediaTimeoutReason
+	}{
+		{"keep-alive ignored", MediaOptions{}, []byte("\r\n"), true, MediaTimeoutSilence},
+		{"keep-alive liveness", MediaOptions{MediaTimeoutKeepAlive: true}, []byte("\r\n"), false, 0},
+		{"junk", MediaOptions{MediaTimeoutKeepAlive: true}, []byte("junk"), true, MediaTimeoutNoMedia},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c1, c2 := newUDPPipe()
+			opts := c.opts
+			opts.IP = newIP("1.1.1.1")
+			opts.MediaTimeout = timeout
+			opts.MediaTimeoutInitial = timeout
+
+			m, err := NewMediaPortWith(logger.GetLogger(), nil, c1, &opts, 8000)
+			require.NoError(t, err)
+			defer m.Close()
+			defer c2.Close()
+
+			var reason atomic.Pointer[MediaTimeoutReason]
+			m.OnEvent(func(ev MediaEvent) {
+				if ev.Type == MediaEventTimeout {
+					reason.Store(&ev.Reason)
+				}
+			})
+
+			offer, err := m.NewOffer(sdp.EncryptionNone)
+			require.NoError(t, err)
+			offerData, err := offer.SDP.Marshal()
+			require.NoError(t, err)
+			_, conf, err := m.SetOffer(offerData, sdp.EncryptionNone)
+			require.NoError(t, err)
+			conf.Remote = c2.addr
+			require.NoError(t, m.SetConfig(conf))
+			m.EnableTimeout(true)
+
+			deadline := time.After(6 * timeout)
+			ticker := time.NewTicker(timeout / 4)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					_, _ = c2.WriteToUDPAddrPort(c.pkt, c1.addr)
+					continue
+				case <-m.Timeout():
+					require.True(t, c.timeout, "unexpected timeout")
+					require.Equal(t, c.reason, *reason.Load())
+				case <-deadline:
+					require.False(t, c.timeout, "expected timeout")
+				}
+				break
+			}
+		})
+	}
+}
+
+func TestMediaPortSDPOrigin(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	m, err := NewMediaPortWith(logger.GetLogger(), nil, conn, &MediaOptions{
+		IP:  newIP("127.0.0.1"),
+		SDP: config.SDPConfig{SessionName: "Carrier", Username: "lk"},
+	}, 8000)
+	require.NoError(t, err)
+	defer m.Close()
+
+	offer1, err := m.NewOffer(sdp.EncryptionNone)
+	require.NoError(t, err)
+	o1 := offer1.SDP.Origin
+	require.Equal(t, "Carrier", string(offer1.SDP.SessionName))
+	require.Equal(t, "lk", o1.Username)
+	require.LessOrEqual(t, o1.SessionID, uint64(math.MaxInt64>>1))
+	require.LessOrEqual(t, o1.SessionVersion, uint64(math.MaxInt64>>1))
+
+	// Same session description keeps the version.
+	offer2, err := m.NewOffer(sdp.EncryptionNone)
+	require.NoError(t, err)
+	require.Equal(t, o1, offer2.SDP.Origin)
+
+	// Changed session description increments it, keeping the rest of the origin.
+	offer3, err := m.NewOffer(sdp.EncryptionRequire)
+	require.NoError(t, err)
+	o3 := offer3.SDP.Origin
+	require.Equal(t, o1.SessionVersion+1, o3.SessionVersion)
+	o3.SessionVersion = o1.SessionVersion
+	require.Equal(t, o1, o3)
+}
+
+func TestMediaPortPProfLabels(t *testing.T) {
+	c1, _ := newUDPPipe()
+	m, err := NewMediaPortWith(logger.GetLogger(), nil, c1, &MediaOptions{
+		IP:      newIP("1.1.1.1"),
+		CallID:  "SCL_labels",
+		TrunkID: "ST_labels",
+	}, 8000)
+	require.NoError(t, err)
+	defer m.Close()
+
+	// Labels are set once the goroutine starts.
+	require.Eventually(t, func() bool {
+		var buf bytes.Buffer
+		require.NoError(t, pprof.Lookup("goroutine").WriteTo(&buf, 1))
+		return strings.Contains(buf.String(), `"call_id":"SCL_labels", "codec":"", "loop":"timeoutLoop", "trunk_id":"ST_labels"`)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestUDPConnReadError(t *testing.T) {
+	c1, c2 := newUDPPipe()
+	events := new(mediaEvents)
+	var got []MediaEvent
+	events.OnEvent(func(ev MediaEvent) {
+		got = append(got, ev)
+	})
+	u := newUDPConn(logger.GetLogger(), c1, new(PortStats), events)
+
+	pkt := make([]byte, 172)
+	pkt[0] = 0x80
+	_, err := c2.WriteToUDPAddrPort(pkt, c1.addr)
+	require.NoError(t, err)
+
+	buf := make([]byte, 1500)
+	_, err = u.Read(buf)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, MediaEventSourceChanged, got[0].Type)
+	require.Equal(t, c2.addr, got[0].Addr)
+
+	// Read errors must not reset the source address.
+	_ = c1.Close()
+	_, err = u.Read(buf)
+	require.Error(t, err)
+	require.Len(t, got, 1)
+	src, ok := u.GetSrc()
+	require.True(t, ok)
+	require.Equal(t, c2.addr, src)
+}
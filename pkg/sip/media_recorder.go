@@ -0,0 +1,234 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// wavHeaderSize is the size of a canonical WAV header for PCM audio.
+const wavHeaderSize = 44
+
+// recorderMemRetry is how often a recording paused by the memory budget tries to resume.
+const recorderMemRetry = time.Second
+
+// wavWriter writes 16-bit mono PCM to a WAV file. Sizes in the header are set when the writer is closed.
+type wavWriter struct {
+	f          io.WriteSeeker
+	sampleRate int
+	size       int // of audio data, in bytes
+	buf        []byte
+}
+
+func newWAVWriter(f io.WriteSeeker, sampleRate int) (*wavWriter, error) {
+	w := &wavWriter{f: f, sampleRate: sampleRate}
+	if _, err := f.Write(w.header()); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *wavWriter) header() []byte {
+	const (
+		channels = 1
+		bits     = 16
+	)
+	h := make([]byte, 0, wavHeaderSize)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(wavHeaderSize-8+w.size))
+	h = append(h, "WAVEfmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16) // fmt chunk size
+	h = binary.LittleEndian.AppendUint16(h, 1)  // PCM
+	h = binary.LittleEndian.AppendUint16(h, channels)
+	h = binary.LittleEndian.AppendUint32(h, uint32(w.sampleRate))
+	h = binary.LittleEndian.AppendUint32(h, uint32(w.sampleRate*channels*bits/8))
+	h = binary.LittleEndian.AppendUint16(h, channels*bits/8)
+	h = binary.LittleEndian.AppendUint16(h, bits)
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(w.size))
+	return h
+}
+
+func (w *wavWriter) WriteSample(sample msdk.PCM16Sample) error {
+	w.buf = w.buf[:0]
+	for _, v := range sample {
+		w.buf = binary.LittleEndian.AppendUint16(w.buf, uint16(v))
+	}
+	n, err := w.f.Write(w.buf)
+	w.size += n
+	return err
+}
+
+// Finish updates the header with the size of written audio.
+func (w *wavWriter) Finish() error {
+	if _, err := w.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := w.f.Write(w.header())
+	return err
+}
+
+// recorderStage writes a copy of the audio to a WAV file named after the call.
+// The file is created on the first frame, once the sample rate is known.
+// If artifacts are encrypted, the data key is prepared when the stage is created and ".enc" is added to the name.
+//
+// Buffers of the recording are reserved in the memory budget of the call before the file is created.
+// While they are over the budget, the recording is paused: audio is not written, but keeps flowing to the room.
+type recorderStage struct {
+	log     logger.Logger
+	path    string
+	key     *artifactKey
+	mem     *callMemory
+	memSize int
+
+	mu       sync.Mutex
+	f        ArtifactFile
+	wav      *wavWriter
+	failed   bool
+	closed   bool
+	reserved bool
+	paused   bool
+	retry    time.Time // of the memory reservation, while paused
+}
+
+func newRecorderStage(env MediaStageEnv, conf config.MediaStageConfig) (MediaStage, error) {
+	if env.CallID == "" {
+		return nil, errors.New("recorder requires call id")
+	}
+	key, err := env.Artifacts.newKey(context.Background(), env.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	memSize := recorderBufferMem
+	if key != nil {
+		memSize += artifactMaxRecord
+	}
+	return &recorderStage{
+		log:     env.Log,
+		path:    key.Path(filepath.Join(conf.Dir, filepath.Base(env.CallID)+".wav")),
+		key:     key,
+		mem:     env.mem,
+		memSize: memSize,
+	}, nil
+}
+
+func (s *recorderStage) Name() string {
+	return config.MediaStageRecorder
+}
+
+func (s *recorderStage) Process(w msdk.PCM16Writer) msdk.PCM16Writer {
+	return &stageWriter{name: "Recorder", w: w, fnc: s.record}
+}
+
+func (s *recorderStage) record(sample msdk.PCM16Sample, sampleRate int) msdk.PCM16Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed || s.closed {
+		return sample
+	}
+	if s.wav == nil {
+		if !s.reserve() {
+			return sample
+		}
+		if err := s.open(sampleRate); err != nil {
+			s.fail("cannot create recording", err)
+			return sample
+		}
+	}
+	if err := s.wav.WriteSample(sample); err != nil {
+		s.fail("cannot write recording", err)
+	}
+	return sample
+}
+
+func (s *recorderStage) open(sampleRate int) error {
+	f, err := s.key.Create(s.path)
+	if err != nil {
+		return err
+	}
+	wav, err := newWAVWriter(f, sampleRate)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	s.f, s.wav = f, wav
+	return nil
+}
+
+// reserve reserves buffers of the recording in the memory budget. It pauses the recording if they are over the budget,
+// and retries periodically.
+func (s *recorderStage) reserve() bool {
+	if s.reserved {
+		return true
+	}
+	now := time.Now()
+	if s.paused && now.Before(s.retry) {
+		return false
+	}
+	if !s.mem.Reserve(memRecorder, s.memSize) {
+		if !s.paused && s.log != nil {
+			s.log.Warnw("pausing recording, over memory budget", nil, "path", s.path)
+		}
+		s.paused = true
+		s.retry = now.Add(recorderMemRetry)
+		return false
+	}
+	if s.paused && s.log != nil {
+		s.log.Infow("resuming recording", "path", s.path)
+	}
+	s.paused = false
+	s.reserved = true
+	return true
+}
+
+// fail stops the recording, but keeps the audio flowing to the room.
+func (s *recorderStage) fail(msg string, err error) {
+	s.failed = true
+	if s.log != nil {
+		s.log.Warnw(msg, err, "path", s.path)
+	}
+}
+
+func (s *recorderStage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.reserved {
+		s.mem.Release(s.memSize)
+		s.reserved = false
+	}
+	if s.f == nil {
+		return nil
+	}
+	err := s.wav.Finish()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,491 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/media/vad"
+	"github.com/livekit/sip/pkg/stt"
+)
+
+// MediaStage processes audio received from SIP before it is sent to the room.
+//
+// A stage that holds resources for the whole call, for example an open file, may also implement io.Closer.
+// It is closed when the stage is removed from the pipeline or when the call ends.
+// Stages are compared when the pipeline changes, thus implementations are usually pointers.
+type MediaStage interface {
+	// Name identifies the stage in the pipeline.
+	Name() string
+	// Process returns a writer that processes the audio and passes it to w.
+	// It is called each time the pipeline is rebuilt, closing the returned writer must not release the stage itself.
+	Process(w msdk.PCM16Writer) msdk.PCM16Writer
+}
+
+// MediaStageEnv is the call a media stage is created for.
+type MediaStageEnv struct {
+	Log    logger.Logger
+	CallID string
+	// ProjectID of the call, which selects the key for encrypted artifacts.
+	ProjectID string
+	// Artifacts creates files written by the stage. Files are not encrypted if it's nil.
+	Artifacts *Artifacts
+	// OnDTMF is called for digits detected by the dtmf stage.
+	OnDTMF func(ev DTMFEvent)
+	// OnSpeech is called when the vad stage detects that the remote starts or stops speaking.
+	OnSpeech func(speaking bool)
+	// STT is the engine used by the stt stage, nil if speech-to-text is not configured.
+	STT stt.Engine
+	// OnTranscript is called for transcripts of the stt stage.
+	OnTranscript func(t stt.Transcript)
+
+	// mem accounts buffers of the call, nil if the memory budget is disabled.
+	mem *callMemory
+}
+
+// MediaStageFactory creates a media stage for a call.
+type MediaStageFactory func(env MediaStageEnv, conf config.MediaStageConfig) (MediaStage, error)
+
+var (
+	mediaStagesMu sync.RWMutex
+	mediaStages   = map[string]MediaStageFactory{
+		config.MediaStageGain:     newGainStage,
+		config.MediaStageVAD:      newVADStage,
+		config.MediaStageNS:       newNoiseGateStage,
+		config.MediaStageRecorder: newRecorderStage,
+		config.MediaStageDTMF:     newDTMFDetectStage,
+		config.MediaStageSTT:      newSTTStage,
+	}
+)
+
+// RegisterMediaStage makes a media stage available by name in the config of dispatch rules.
+// It panics if the name is already taken.
+func RegisterMediaStage(name string, f MediaStageFactory) {
+	mediaStagesMu.Lock()
+	defer mediaStagesMu.Unlock()
+	if _, ok := mediaStages[name]; ok {
+		panic(fmt.Sprintf("media stage %q is already registered", name))
+	}
+	mediaStages[name] = f
+}
+
+// NewMediaStage creates a built-in or registered media stage.
+func NewMediaStage(env MediaStageEnv, conf config.MediaStageConfig) (MediaStage, error) {
+	mediaStagesMu.RLock()
+	f := mediaStages[conf.Name]
+	mediaStagesMu.RUnlock()
+	if f == nil {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "unknown media stage %q", conf.Name)
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	return f(env, conf)
+}
+
+// newMediaStages creates stages in the given order. On error, stages created so far are closed.
+func newMediaStages(env MediaStageEnv, confs []config.MediaStageConfig) ([]MediaStage, error) {
+	stages := make([]MediaStage, 0, len(confs))
+	for _, conf := range confs {
+		st, err := NewMediaStage(env, conf)
+		if err != nil {
+			closeMediaStages(stages)
+			return nil, err
+		}
+		stages = append(stages, st)
+	}
+	return stages, nil
+}
+
+func closeMediaStages(stages []MediaStage) {
+	for _, st := range stages {
+		if c, ok := st.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+}
+
+// ProcessorStage wraps a processor, for example one returned by Handler.GetMediaProcessor, as a media stage.
+func ProcessorStage(name string, p msdk.PCM16Processor) MediaStage {
+	return &processorStage{name: name, p: p}
+}
+
+type processorStage struct {
+	name string
+	p    msdk.PCM16Processor
+}
+
+func (s *processorStage) Name() string {
+	return s.name
+}
+
+func (s *processorStage) Process(w msdk.PCM16Writer) msdk.PCM16Writer {
+	return s.p(w)
+}
+
+// handlerStageName is the name of the stage for the processor returned by the handler.
+const handlerStageName = "handler"
+
+func isHandlerStage(st MediaStage) bool {
+	s, ok := st.(*processorStage)
+	return ok && s.name == handlerStageName
+}
+
+// setHandlerStage sets the processor returned by the handler as the last stage.
+func (p *MediaPort) setHandlerStage(proc msdk.PCM16Processor) {
+	p.updateStages(func(stages []MediaStage) []MediaStage {
+		stages = slices.DeleteFunc(stages, isHandlerStage)
+		if proc != nil {
+			stages = append(stages, ProcessorStage(handlerStageName, proc))
+		}
+		return stages
+	})
+}
+
+// replaceStages replaces stages created from the config. The handler stage is kept last.
+func (p *MediaPort) replaceStages(next []MediaStage) {
+	p.updateStages(func(stages []MediaStage) []MediaStage {
+		out := slices.Clone(next)
+		if i := slices.IndexFunc(stages, isHandlerStage); i >= 0 {
+			out = append(out, stages[i])
+		}
+		return out
+	})
+}
+
+func mediaStageNames(stages []MediaStage) []string {
+	names := make([]string, 0, len(stages))
+	for _, st := range stages {
+		names = append(names, st.Name())
+	}
+	return names
+}
+
+// mediaPipeline passes audio through an ordered list of stages. Stages can be replaced while audio flows.
+type mediaPipeline struct {
+	out msdk.PCM16Writer
+
+	mu     sync.Mutex
+	stages []MediaStage
+	head   msdk.PCM16Writer
+	closed bool
+}
+
+func newMediaPipeline(out msdk.PCM16Writer, stages []MediaStage) *mediaPipeline {
+	p := &mediaPipeline{out: out}
+	p.SetStages(stages)
+	return p
+}
+
+// SetStages rebuilds the pipeline with given stages. Writers of the previous stages are closed.
+func (p *mediaPipeline) SetStages(stages []MediaStage) {
+	var w msdk.PCM16Writer = noCloseWriter{p.out}
+	for _, st := range slices.Backward(stages) {
+		w = st.Process(w)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		_ = w.Close()
+		return
+	}
+	prev := p.head
+	p.stages, p.head = stages, w
+	if prev != nil {
+		_ = prev.Close()
+	}
+}
+
+// String describes writers of the stages. The pipeline without stages is transparent.
+func (p *mediaPipeline) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.head.String()
+}
+
+func (p *mediaPipeline) SampleRate() int {
+	return p.out.SampleRate()
+}
+
+func (p *mediaPipeline) WriteSample(sample msdk.PCM16Sample) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return io.ErrClosedPipe
+	}
+	return p.head.WriteSample(sample)
+}
+
+func (p *mediaPipeline) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	head := p.head
+	p.mu.Unlock()
+	_ = head.Close()
+	return p.out.Close()
+}
+
+// noCloseWriter keeps the pipeline output open when stage writers are closed.
+type noCloseWriter struct {
+	msdk.PCM16Writer
+}
+
+func (w noCloseWriter) Close() error {
+	return nil
+}
+
+// stageWriter is a writer of a simple stage, which processes each frame.
+// The frame may be shared with other writers, thus stages must return a copy instead of changing it.
+type stageWriter struct {
+	name string
+	w    msdk.PCM16Writer
+	fnc  func(sample msdk.PCM16Sample, sampleRate int) msdk.PCM16Sample
+}
+
+func (w *stageWriter) String() string {
+	return w.name + " -> " + w.w.String()
+}
+
+func (w *stageWriter) SampleRate() int {
+	return w.w.SampleRate()
+}
+
+func (w *stageWriter) WriteSample(sample msdk.PCM16Sample) error {
+	return w.w.WriteSample(w.fnc(sample, w.w.SampleRate()))
+}
+
+func (w *stageWriter) Close() error {
+	return w.w.Close()
+}
+
+// gainStage amplifies or attenuates the audio.
+type gainStage struct {
+	gain float64 // linear
+}
+
+func newGainStage(_ MediaStageEnv, conf config.MediaStageConfig) (MediaStage, error) {
+	return &gainStage{gain: math.Pow(10, conf.Gain/20)}, nil
+}
+
+func (s *gainStage) Name() string {
+	return config.MediaStageGain
+}
+
+func (s *gainStage) Process(w msdk.PCM16Writer) msdk.PCM16Writer {
+	return &stageWriter{name: "Gain", w: w, fnc: s.apply}
+}
+
+func (s *gainStage) apply(sample msdk.PCM16Sample, _ int) msdk.PCM16Sample {
+	out := make(msdk.PCM16Sample, len(sample))
+	for i, v := range sample {
+		out[i] = int16(min(max(math.Round(float64(v)*s.gain), math.MinInt16), math.MaxInt16))
+	}
+	return out
+}
+
+// audioSpeech tracks if a frame level stays above a threshold, with a hangover after speech ends.
+type audioSpeech struct {
+	level     uint8 // in -dBov, see vad.AudioLevel
+	minSpeech time.Duration
+	hangover  time.Duration
+
+	loud     time.Duration // continuous audio above the threshold
+	quiet    time.Duration // continuous audio below the threshold
+	speaking bool
+}
+
+const (
+	defaultNoiseGateThreshold = -50.0 // dBFS
+	// speechHangover is the time of quiet audio after which the speech is considered ended.
+	speechHangover = 500 * time.Millisecond
+)
+
+func newAudioSpeech(threshold float64, def float64, minSpeech time.Duration) audioSpeech {
+	if threshold == 0 {
+		threshold = def
+	}
+	return audioSpeech{
+		level:     uint8(math.Round(min(-threshold, vad.SilenceLevel))),
+		minSpeech: minSpeech,
+		hangover:  speechHangover,
+	}
+}
+
+// Update processes a frame and reports if the remote is speaking.
+func (s *audioSpeech) Update(sample msdk.PCM16Sample, sampleRate int) bool {
+	if len(sample) == 0 || sampleRate <= 0 {
+		return s.speaking
+	}
+	dur := time.Duration(len(sample)) * time.Second / time.Duration(sampleRate)
+	if vad.AudioLevel(sample) <= s.level {
+		s.loud += dur
+		s.quiet = 0
+		if s.loud >= s.minSpeech {
+			s.speaking = true
+		}
+	} else {
+		s.quiet += dur
+		s.loud = 0
+		if s.quiet >= s.hangover {
+			s.speaking = false
+		}
+	}
+	return s.speaking
+}
+
+// vadStage reports when the remote starts and stops speaking.
+type vadStage struct {
+	log      logger.Logger
+	onSpeech func(speaking bool)
+
+	mu     sync.Mutex
+	speech audioSpeech
+}
+
+func newVADStage(env MediaStageEnv, conf config.MediaStageConfig) (MediaStage, error) {
+	return &vadStage{
+		log:      env.Log,
+		onSpeech: env.OnSpeech,
+		speech:   newAudioSpeech(conf.Threshold, vad.DefaultThreshold, vad.DefaultMinSpeech),
+	}, nil
+}
+
+func (s *vadStage) Name() string {
+	return config.MediaStageVAD
+}
+
+func (s *vadStage) Process(w msdk.PCM16Writer) msdk.PCM16Writer {
+	return &stageWriter{name: "VAD", w: w, fnc: s.detect}
+}
+
+func (s *vadStage) detect(sample msdk.PCM16Sample, sampleRate int) msdk.PCM16Sample {
+	s.mu.Lock()
+	was := s.speech.speaking
+	speaking := s.speech.Update(sample, sampleRate)
+	s.mu.Unlock()
+	if speaking == was {
+		return sample
+	}
+	if s.log != nil {
+		s.log.Debugw("remote speech changed", "speaking", speaking)
+	}
+	if s.onSpeech != nil {
+		s.onSpeech(speaking)
+	}
+	return sample
+}
+
+// noiseGateStage silences the audio while the remote is not speaking, removing background noise between speech.
+type noiseGateStage struct {
+	mu     sync.Mutex
+	speech audioSpeech
+}
+
+func newNoiseGateStage(_ MediaStageEnv, conf config.MediaStageConfig) (MediaStage, error) {
+	return &noiseGateStage{
+		speech: newAudioSpeech(conf.Threshold, defaultNoiseGateThreshold, 0),
+	}, nil
+}
+
+func (s *noiseGateStage) Name() string {
+	return config.MediaStageNS
+}
+
+func (s *noiseGateStage) Process(w msdk.PCM16Writer) msdk.PCM16Writer {
+	return &stageWriter{name: "NoiseGate", w: w, fnc: s.apply}
+}
+
+func (s *noiseGateStage) apply(sample msdk.PCM16Sample, sampleRate int) msdk.PCM16Sample {
+	s.mu.Lock()
+	speaking := s.speech.Update(sample, sampleRate)
+	s.mu.Unlock()
+	if !speaking {
+		return make(msdk.PCM16Sample, len(sample))
+	}
+	return sample
+}
+
+// speakingAttrs returns participant attributes reported by the vad stage.
+func speakingAttrs(speaking bool) map[string]string {
+	return map[string]string{AttrSIPSpeaking: strconv.FormatBool(speaking)}
+}
+
+// SetMediaStagesRequest replaces media stages of an active call.
+type SetMediaStagesRequest struct {
+	CallID string                    `json:"call_id"`
+	Stages []config.MediaStageConfig `json:"stages"`
+}
+
+// SetMediaStagesResponse lists media stages of the call after the change.
+type SetMediaStagesResponse struct {
+	Stages []string `json:"stages"`
+}
+
+// mediaStageCall finds the media and the stage environment of an active inbound call.
+func (s *Server) mediaStageCall(id LocalTag) (*MediaPort, MediaStageEnv, bool) {
+	s.cmu.RLock()
+	c := s.byLocal[id]
+	s.cmu.RUnlock()
+	if c == nil || c.media == nil {
+		return nil, MediaStageEnv{}, false
+	}
+	return c.media, c.mediaStageEnv(), true
+}
+
+// mediaStageCall finds the media and the stage environment of an active outbound call.
+func (c *Client) mediaStageCall(id LocalTag) (*MediaPort, MediaStageEnv, bool) {
+	c.cmu.Lock()
+	call := c.activeCalls[id]
+	c.cmu.Unlock()
+	if call == nil || call.media == nil {
+		return nil, MediaStageEnv{}, false
+	}
+	return call.media, call.mediaStageEnv(), true
+}
+
+// SetMediaStages replaces media stages of an active call. The stage of the handler processor is kept.
+func (s *Service) SetMediaStages(ctx context.Context, req *SetMediaStagesRequest) (*SetMediaStagesResponse, error) {
+	id := LocalTag(req.CallID)
+	media, env, ok := s.srv.mediaStageCall(id)
+	if !ok {
+		media, env, ok = s.cli.mediaStageCall(id)
+	}
+	if !ok {
+		return nil, psrpc.NewErrorf(psrpc.NotFound, "call %q not found", req.CallID)
+	}
+	stages, err := newMediaStages(env, req.Stages)
+	if err != nil {
+		return nil, err
+	}
+	media.replaceStages(stages)
+	env.Log.Infow("media stages changed", "stages", mediaStageNames(media.Stages()))
+	return &SetMediaStagesResponse{Stages: mediaStageNames(media.Stages())}, nil
+}
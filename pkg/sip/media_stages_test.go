@@ -0,0 +1,249 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+type stageTestWriter struct {
+	rate    int
+	samples []msdk.PCM16Sample
+	closed  int
+}
+
+func (w *stageTestWriter) String() string  { return "Test" }
+func (w *stageTestWriter) SampleRate() int { return w.rate }
+func (w *stageTestWriter) Close() error {
+	w.closed++
+	return nil
+}
+func (w *stageTestWriter) WriteSample(s msdk.PCM16Sample) error {
+	w.samples = append(w.samples, s)
+	return nil
+}
+
+// addStage doubles each sample, adds a constant and counts closed writers.
+type addStage struct {
+	add    int16
+	closed int
+}
+
+func (s *addStage) Name() string { return "add" }
+
+func (s *addStage) Process(w msdk.PCM16Writer) msdk.PCM16Writer {
+	return &addStageWriter{stageWriter{name: "Add", w: w, fnc: func(sample msdk.PCM16Sample, _ int) msdk.PCM16Sample {
+		out := make(msdk.PCM16Sample, len(sample))
+		for i, v := range sample {
+			out[i] = v*2 + s.add
+		}
+		return out
+	}}, s}
+}
+
+type addStageWriter struct {
+	stageWriter
+	s *addStage
+}
+
+func (w *addStageWriter) Close() error {
+	w.s.closed++
+	return w.stageWriter.Close()
+}
+
+func TestMediaPipeline(t *testing.T) {
+	out := &stageTestWriter{rate: 8000}
+	a, b := &addStage{add: 1}, &addStage{add: 10}
+	p := newMediaPipeline(out, []MediaStage{a, b})
+	require.Equal(t, "Add -> Add -> Test", p.String())
+
+	in := msdk.PCM16Sample{1, 2}
+	require.NoError(t, p.WriteSample(in))
+	require.Equal(t, msdk.PCM16Sample{1, 2}, in, "input must not change")
+	// (x*2+1)*2+10
+	require.Equal(t, msdk.PCM16Sample{16, 20}, out.samples[0])
+
+	// Reorder while audio flows.
+	p.SetStages([]MediaStage{b, a})
+	require.Equal(t, 1, a.closed)
+	require.Equal(t, 1, b.closed)
+	require.Zero(t, out.closed)
+	require.NoError(t, p.WriteSample(in))
+	// (x*2+10)*2+1
+	require.Equal(t, msdk.PCM16Sample{25, 29}, out.samples[1])
+
+	p.SetStages(nil)
+	require.NoError(t, p.WriteSample(in))
+	require.Equal(t, msdk.PCM16Sample{1, 2}, out.samples[2])
+
+	require.NoError(t, p.Close())
+	require.NoError(t, p.Close())
+	require.Equal(t, 1, out.closed)
+	require.Error(t, p.WriteSample(in))
+}
+
+type closerStage struct {
+	addStage
+	released bool
+}
+
+func (s *closerStage) Close() error {
+	s.released = true
+	return nil
+}
+
+func TestMediaPortStages(t *testing.T) {
+	p := &MediaPort{}
+	a, b := &closerStage{}, &closerStage{}
+	p.SetStages([]MediaStage{a})
+	p.setHandlerStage(func(w msdk.PCM16Writer) msdk.PCM16Writer { return w })
+	require.Equal(t, []string{"add", handlerStageName}, mediaStageNames(p.Stages()))
+
+	// Config stages are replaced, the handler stays last.
+	p.replaceStages([]MediaStage{b})
+	require.Equal(t, []string{"add", handlerStageName}, mediaStageNames(p.Stages()))
+	require.True(t, a.released)
+	require.False(t, b.released)
+
+	p.setHandlerStage(nil)
+	require.Equal(t, []string{"add"}, mediaStageNames(p.Stages()))
+	require.False(t, b.released)
+}
+
+func TestMediaStageRegistry(t *testing.T) {
+	env := MediaStageEnv{Log: logger.GetLogger(), CallID: "SCL_test"}
+
+	_, err := NewMediaStage(env, config.MediaStageConfig{Name: "unknown"})
+	require.Error(t, err)
+	_, err = NewMediaStage(env, config.MediaStageConfig{Name: config.MediaStageGain, Gain: 100})
+	require.Error(t, err)
+
+	RegisterMediaStage("test-add", func(_ MediaStageEnv, conf config.MediaStageConfig) (MediaStage, error) {
+		return &addStage{add: int16(len(conf.Options["add"]))}, nil
+	})
+	t.Cleanup(func() {
+		mediaStagesMu.Lock()
+		delete(mediaStages, "test-add")
+		mediaStagesMu.Unlock()
+	})
+	require.Panics(t, func() {
+		RegisterMediaStage(config.MediaStageGain, nil)
+	})
+
+	stages, err := newMediaStages(env, []config.MediaStageConfig{
+		{Name: config.MediaStageGain, Gain: 6},
+		{Name: "test-add", Options: map[string]string{"add": "xx"}},
+		{Name: config.MediaStageDTMF},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{config.MediaStageGain, "add", config.MediaStageDTMF}, mediaStageNames(stages))
+	require.Equal(t, int16(2), stages[1].(*addStage).add)
+}
+
+func TestGainStage(t *testing.T) {
+	st, err := newGainStage(MediaStageEnv{}, config.MediaStageConfig{Gain: 6})
+	require.NoError(t, err)
+	out := &stageTestWriter{rate: 8000}
+	w := st.Process(out)
+	require.NoError(t, w.WriteSample(msdk.PCM16Sample{0, 1000, -1000, 30000, -30000}))
+	require.Equal(t, msdk.PCM16Sample{0, 1995, -1995, 32767, -32768}, out.samples[0])
+}
+
+func TestNoiseGateStage(t *testing.T) {
+	st, err := newNoiseGateStage(MediaStageEnv{}, config.MediaStageConfig{})
+	require.NoError(t, err)
+	out := &stageTestWriter{rate: 8000}
+	w := st.Process(out)
+
+	frame := func(v int16) msdk.PCM16Sample {
+		s := make(msdk.PCM16Sample, 160) // 20ms
+		for i := range s {
+			s[i] = v
+		}
+		return s
+	}
+	noise, speech := frame(20), frame(5000)
+
+	require.NoError(t, w.WriteSample(noise))
+	require.Equal(t, frame(0), out.samples[0])
+	require.NoError(t, w.WriteSample(speech))
+	require.Equal(t, speech, out.samples[1])
+	// Tail of the speech is kept until the hangover expires.
+	for range int(speechHangover / (20 * time.Millisecond)) {
+		require.NoError(t, w.WriteSample(noise))
+	}
+	require.Equal(t, noise, out.samples[2])
+	require.Equal(t, frame(0), out.samples[len(out.samples)-1])
+}
+
+func TestVADStage(t *testing.T) {
+	var events []bool
+	st, err := newVADStage(MediaStageEnv{OnSpeech: func(speaking bool) {
+		events = append(events, speaking)
+	}}, config.MediaStageConfig{})
+	require.NoError(t, err)
+	w := st.Process(&stageTestWriter{rate: 8000})
+
+	quiet, loud := make(msdk.PCM16Sample, 160), make(msdk.PCM16Sample, 160)
+	for i := range loud {
+		loud[i] = 5000
+	}
+	for range 20 {
+		require.NoError(t, w.WriteSample(loud))
+	}
+	require.Equal(t, []bool{true}, events)
+	for range 50 {
+		require.NoError(t, w.WriteSample(quiet))
+	}
+	require.Equal(t, []bool{true, false}, events)
+}
+
+func TestRecorderStage(t *testing.T) {
+	dir := t.TempDir()
+	st, err := newRecorderStage(MediaStageEnv{CallID: "SCL_test"}, config.MediaStageConfig{Dir: dir})
+	require.NoError(t, err)
+
+	out := &stageTestWriter{rate: 16000}
+	// Recording continues across pipeline rebuilds.
+	for range 2 {
+		w := st.Process(out)
+		require.NoError(t, w.WriteSample(msdk.PCM16Sample{1, -2, 3}))
+		require.NoError(t, w.Close())
+	}
+	require.Len(t, out.samples, 2)
+	require.NoError(t, st.(*recorderStage).Close())
+
+	data, err := os.ReadFile(filepath.Join(dir, "SCL_test.wav"))
+	require.NoError(t, err)
+	require.Len(t, data, wavHeaderSize+12)
+	require.Equal(t, "RIFF", string(data[0:4]))
+	require.Equal(t, uint32(len(data)-8), binary.LittleEndian.Uint32(data[4:]))
+	require.Equal(t, "WAVE", string(data[8:12]))
+	require.Equal(t, uint32(16000), binary.LittleEndian.Uint32(data[24:]))
+	require.Equal(t, "data", string(data[36:40]))
+	require.Equal(t, uint32(12), binary.LittleEndian.Uint32(data[40:]))
+	require.Equal(t, int16(-2), int16(binary.LittleEndian.Uint16(data[wavHeaderSize+2:])))
+}
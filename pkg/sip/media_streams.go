@@ -0,0 +1,120 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	// DefaultStreamRate is the default rate of new RTP streams accepted from the remote, per second.
+	DefaultStreamRate = 1.0
+	// rtpStreamBurst is the number of new RTP streams accepted at once, before the rate limit applies.
+	rtpStreamBurst = 4
+	// rtpStreamsPerInput is the number of RTP streams accepted during the call for each stream that can be mixed,
+	// so that the remote can switch SSRCs a few times.
+	rtpStreamsPerInput = 4
+	// rtpMinStreams is the minimal number of RTP streams accepted during the call.
+	rtpMinStreams = 16
+)
+
+// maxRTPStreams returns the number of RTP streams accepted during the call, given the MaxInputStreams limit.
+func maxRTPStreams(maxInputs int) int {
+	return max(rtpMinStreams, maxInputs*rtpStreamsPerInput)
+}
+
+// rtpStreamGuard protects the RTP session from floods of new streams (SSRCs). Each stream accepted by the session
+// keeps a buffer and a reader goroutine until the end of the call, so packets of new streams are dropped
+// once the call received too many streams in total, or when new streams arrive too fast.
+type rtpStreamGuard struct {
+	max  int
+	rate float64 // new streams per second
+
+	mu     sync.Mutex
+	known  map[uint32]struct{} // streams accepted by the session, never forgotten
+	tokens float64
+	refill time.Time
+}
+
+func newRTPStreamGuard(maxStreams int, rate float64) *rtpStreamGuard {
+	return &rtpStreamGuard{
+		max:    maxStreams,
+		rate:   rate,
+		known:  make(map[uint32]struct{}),
+		tokens: rtpStreamBurst,
+	}
+}
+
+// Allow checks if the RTP packet can be passed to the session. It must only be called for RTP packets.
+func (g *rtpStreamGuard) Allow(b []byte, now time.Time) bool {
+	ssrc := rtpSSRC(b)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.known[ssrc]; ok {
+		return true
+	}
+	if len(g.known) >= g.max {
+		return false
+	}
+	if !g.refill.IsZero() {
+		g.tokens = min(g.tokens+now.Sub(g.refill).Seconds()*g.rate, rtpStreamBurst)
+	}
+	g.refill = now
+	if g.tokens < 1 {
+		return false
+	}
+	g.tokens--
+	g.known[ssrc] = struct{}{}
+	return true
+}
+
+// streamGuardConn drops packets of RTP streams rejected by the guard. For SRTP, it wraps the decrypting connection,
+// so that only streams of authenticated packets are registered, and forged packets can't use up the limits.
+type streamGuardConn struct {
+	net.Conn
+	log   logger.Logger
+	stats *PortStats
+	guard *rtpStreamGuard
+}
+
+func newStreamGuardConn(log logger.Logger, conn net.Conn, st *PortStats, guard *rtpStreamGuard) *streamGuardConn {
+	return &streamGuardConn{Conn: conn, log: log, stats: st, guard: guard}
+}
+
+// Read reads the next RTP packet accepted by the guard. It must not be called concurrently.
+func (c *streamGuardConn) Read(b []byte) (int, error) {
+	for {
+		n, err := c.Conn.Read(b)
+		if err != nil {
+			return n, err
+		}
+		if n < 12 || c.guard.Allow(b[:n], time.Now()) {
+			return n, nil
+		}
+		if c.stats.StreamDrops.Add(1) == 1 {
+			c.log.Warnw("dropping RTP packets, too many streams", nil, "ssrc", rtpSSRC(b[:n]))
+		}
+	}
+}
+
+// rtpSSRC returns the SSRC of the RTP packet.
+func rtpSSRC(b []byte) uint32 {
+	return binary.BigEndian.Uint32(b[8:12])
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/pion/rtp"
+	"github.com/pion/srtp/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRTPStreamGuard(t *testing.T) {
+	packet := func(ssrc uint32) []byte {
+		p := rtp.Packet{Header: rtp.Header{Version: 2, SSRC: ssrc}, Payload: []byte{1}}
+		b, err := p.Marshal()
+		require.NoError(t, err)
+		return b
+	}
+	now := time.Unix(0, 0)
+	g := newRTPStreamGuard(6, 2)
+
+	// Burst of new streams is accepted at once.
+	for ssrc := range uint32(rtpStreamBurst) {
+		require.True(t, g.Allow(packet(ssrc), now))
+	}
+	require.False(t, g.Allow(packet(100), now))
+	// Known streams are not limited.
+	require.True(t, g.Allow(packet(0), now))
+
+	// New streams are accepted at the configured rate.
+	now = now.Add(500 * time.Millisecond)
+	require.True(t, g.Allow(packet(100), now))
+	require.False(t, g.Allow(packet(101), now))
+	now = now.Add(500 * time.Millisecond)
+	require.True(t, g.Allow(packet(101), now))
+
+	// Limit of streams per call.
+	now = now.Add(5 * time.Second)
+	require.False(t, g.Allow(packet(102), now))
+
+	// Idle streams still count, but keep working when they resume.
+	now = now.Add(time.Hour)
+	require.False(t, g.Allow(packet(102), now))
+	require.True(t, g.Allow(packet(0), now))
+	require.True(t, g.Allow(packet(101), now))
+}
+
+func TestMaxRTPStreams(t *testing.T) {
+	require.Equal(t, rtpMinStreams, maxRTPStreams(1))
+	require.Equal(t, rtpMinStreams, maxRTPStreams(DefaultMaxInputStreams))
+	require.Equal(t, 40, maxRTPStreams(10))
+}
+
+func TestStreamGuardConnSRTP(t *testing.T) {
+	c1, c2 := newUDPPipe()
+	st := new(PortStats)
+	u1 := newUDPConn(logger.GetLogger(), c1, st, new(mediaEvents))
+	u2 := newUDPConn(logger.GetLogger(), c2, st, new(mediaEvents))
+	u1.SetDst(c2.addr)
+	u2.SetDst(c1.addr)
+	s2 := newSRTPConn(logger.GetLogger(), u2, st)
+	conf := newTestSRTPConfig(t, 1)
+	require.NoError(t, s2.SetKeys(conf, nil, 0))
+	enc, err := srtp.CreateContext(conf.Keys.LocalMasterKey, conf.Keys.LocalMasterSalt, conf.Profile)
+	require.NoError(t, err)
+	g := newStreamGuardConn(logger.GetLogger(), s2, st, newRTPStreamGuard(2, 100))
+
+	seq := uint16(0)
+	encrypt := func(t testing.TB, ssrc uint32) []byte {
+		seq++
+		pkt := &rtp.Packet{
+			Header:  rtp.Header{Version: 2, SSRC: ssrc, SequenceNumber: seq, Timestamp: uint32(seq) * 160},
+			Payload: []byte{1, 2, 3, 4},
+		}
+		data, err := pkt.Marshal()
+		require.NoError(t, err)
+		out, err := enc.EncryptRTP(nil, data, nil)
+		require.NoError(t, err)
+		return out
+	}
+	send := func(t testing.TB, data []byte) {
+		_, err := u1.Write(data)
+		require.NoError(t, err)
+	}
+	recv := func(t testing.TB, ssrc uint32) {
+		buf := make([]byte, 1500)
+		n, err := g.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, ssrc, rtpSSRC(buf[:n]))
+	}
+
+	// Forged packets with new SSRCs fail authentication and don't use up the limit of streams.
+	// They are sent in batches, followed by a valid packet, so that reads drain the pipe.
+	for batch := range uint32(4) {
+		for i := range uint32(5) {
+			forged := encrypt(t, 100+batch*5+i)
+			forged[len(forged)-1] ^= 0xff
+			send(t, forged)
+		}
+		send(t, encrypt(t, 1))
+		recv(t, 1)
+	}
+	send(t, encrypt(t, 2))
+	recv(t, 2)
+	require.EqualValues(t, 20, st.SRTPAuthFailures.Load())
+	require.Zero(t, st.StreamDrops.Load())
+
+	// Authenticated streams are still limited.
+	send(t, encrypt(t, 3))
+	send(t, encrypt(t, 1))
+	recv(t, 1)
+	require.EqualValues(t, 1, st.StreamDrops.Load())
+}
@@ -0,0 +1,340 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	psdp "github.com/pion/sdp/v3"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	tcpMediaDialTimeout  = 5 * time.Second
+	tcpMediaWriteTimeout = time.Second
+	tcpMediaRetry        = time.Second
+)
+
+// Values of the setup attribute for connection-oriented media (RFC 4145).
+const (
+	tcpSetupActive  = "active"
+	tcpSetupPassive = "passive"
+	tcpSetupActPass = "actpass"
+)
+
+var errTCPFrameTooLarge = errors.New("rtp over tcp frame is too large")
+
+// TCPMediaConf describes RTP over TCP (RFC 4571) negotiated with the remote.
+type TCPMediaConf struct {
+	// Active is set if we connect to the remote, instead of accepting the connection from it.
+	Active bool
+}
+
+func isTCPMedia(m *psdp.MediaDescription) bool {
+	return m != nil && len(m.MediaName.Protos) != 0 && m.MediaName.Protos[0] == "TCP"
+}
+
+// allowsMedia checks if the media line can be used with the RTP transport.
+func allowsMedia(t config.MediaTransport, m *psdp.MediaDescription) bool {
+	switch t {
+	case config.MediaTransportTCP:
+		return isTCPMedia(m)
+	case config.MediaTransportAuto:
+		return true
+	}
+	return !isTCPMedia(m)
+}
+
+// tcpMediaSetup returns the setup attribute of the media line. Default is active, as defined by RFC 4145.
+func tcpMediaSetup(m *psdp.MediaDescription) string {
+	if v, ok := m.Attribute("setup"); ok {
+		return v
+	}
+	return tcpSetupActive
+}
+
+// tcpAnswerSetup returns our setup role for the role offered by the remote. We prefer to accept the connection,
+// since remotes in restrictive networks can usually connect out, but cannot accept connections.
+func tcpAnswerSetup(offer string) string {
+	if offer == tcpSetupPassive {
+		return tcpSetupActive
+	}
+	return tcpSetupPassive
+}
+
+// setTCPMedia switches the media line to RTP over TCP with a given setup role.
+func setTCPMedia(m *psdp.MediaDescription, setup string) {
+	if !isTCPMedia(m) {
+		m.MediaName.Protos = append([]string{"TCP"}, m.MediaName.Protos...)
+	}
+	m.Attributes = slices.DeleteFunc(slices.Clone(m.Attributes), func(a psdp.Attribute) bool {
+		return a.Key == "setup" || a.Key == "connection"
+	})
+	m.Attributes = append(m.Attributes,
+		psdp.Attribute{Key: "setup", Value: setup},
+		psdp.Attribute{Key: "connection", Value: "new"},
+	)
+}
+
+// addTCPAudioMedia adds a copy of the audio media line as an RTP over TCP alternative.
+func addTCPAudioMedia(s *psdp.SessionDescription) {
+	if len(s.MediaDescriptions) == 0 {
+		return
+	}
+	tcp := *s.MediaDescriptions[0]
+	tcp.MediaName.Protos = slices.Clone(tcp.MediaName.Protos)
+	setTCPMedia(&tcp, tcpSetupActPass)
+	s.MediaDescriptions = append(s.MediaDescriptions, &tcp)
+}
+
+// listenTCPMedia opens a TCP listener on the same port number as the UDP media port.
+func listenTCPMedia(conn UDPConn) (net.Listener, error) {
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil, errors.New("media port is not UDP")
+	}
+	return net.ListenTCP("tcp", &net.TCPAddr{IP: addr.IP, Port: addr.Port})
+}
+
+type tcpMediaLink struct {
+	conn   net.Conn
+	remote netip.AddrPort
+	broken chan struct{}
+}
+
+// tcpMediaConn transports RTP and RTCP framed over TCP (RFC 4571). It implements UDPConn beneath rtp.Session,
+// so that the rest of the media pipeline (stats, SRTP, timeouts) works the same way for both transports.
+//
+// The UDP socket is kept, since it reserves the port number and provides the local address.
+// The connection is re-established if it breaks, packets are dropped in the meantime.
+type tcpMediaConn struct {
+	UDPConn
+	log    logger.Logger
+	ln     net.Listener
+	active bool
+	remote netip.AddrPort
+	// signaling is the address of the SIP peer. In passive mode, connections are only accepted
+	// from it or from the remote media address.
+	signaling netip.Addr
+	closed    core.Fuse
+
+	mu    sync.Mutex
+	link  *tcpMediaLink
+	ready chan struct{} // closed when the link is established
+
+	rhdr [2]byte // only used by the reader
+
+	wmu  sync.Mutex
+	wbuf []byte
+}
+
+func newTCPMediaConn(log logger.Logger, udp UDPConn, ln net.Listener, conf *TCPMediaConf, remote netip.AddrPort, signaling netip.Addr) *tcpMediaConn {
+	c := &tcpMediaConn{
+		UDPConn:   udp,
+		log:       log,
+		ln:        ln,
+		active:    conf.Active,
+		remote:    remote,
+		signaling: signaling,
+		ready:     make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *tcpMediaConn) run() {
+	for {
+		conn, err := c.connect()
+		if err != nil {
+			if c.closed.IsBroken() {
+				return
+			}
+			c.log.Infow("cannot establish rtp over tcp", "error", err, "active", c.active)
+			select {
+			case <-c.closed.Watch():
+				return
+			case <-time.After(tcpMediaRetry):
+			}
+			continue
+		}
+		l := &tcpMediaLink{conn: conn, broken: make(chan struct{})}
+		if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+			l.remote = addr.AddrPort()
+		}
+		c.mu.Lock()
+		if c.closed.IsBroken() {
+			c.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		c.link = l
+		close(c.ready)
+		c.mu.Unlock()
+		c.log.Infow("rtp over tcp connected", "remote", l.remote.String(), "active", c.active)
+
+		select {
+		case <-c.closed.Watch():
+			return
+		case <-l.broken:
+		}
+	}
+}
+
+func (c *tcpMediaConn) connect() (net.Conn, error) {
+	if c.active {
+		d := net.Dialer{Timeout: tcpMediaDialTimeout}
+		return d.Dial("tcp", c.remote.String())
+	}
+	for {
+		conn, err := c.ln.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if c.expectedPeer(conn.RemoteAddr()) {
+			return conn, nil
+		}
+		c.log.Infow("rejecting rtp over tcp connection from unexpected address", "remote", conn.RemoteAddr().String(), "expected", c.remote.Addr())
+		_ = conn.Close()
+	}
+}
+
+// expectedPeer checks if the accepted connection comes from the remote media address or from the SIP peer.
+// Any address is accepted if neither of them is known.
+func (c *tcpMediaConn) expectedPeer(addr net.Addr) bool {
+	var expected []netip.Addr
+	if ip := c.remote.Addr().Unmap(); ip.IsValid() && !ip.IsUnspecified() {
+		expected = append(expected, ip)
+	}
+	if ip := c.signaling.Unmap(); ip.IsValid() && !ip.IsUnspecified() {
+		expected = append(expected, ip)
+	}
+	if len(expected) == 0 {
+		return true
+	}
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	return slices.Contains(expected, tcp.AddrPort().Addr().Unmap())
+}
+
+// getLink waits for the connection to be established.
+func (c *tcpMediaConn) getLink() (*tcpMediaLink, error) {
+	for {
+		c.mu.Lock()
+		l, ready := c.link, c.ready
+		c.mu.Unlock()
+		if l != nil {
+			return l, nil
+		}
+		select {
+		case <-c.closed.Watch():
+			return nil, net.ErrClosed
+		case <-ready:
+		}
+	}
+}
+
+// drop closes a broken connection and lets run re-establish it.
+func (c *tcpMediaConn) drop(l *tcpMediaLink, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.link != l {
+		return
+	}
+	c.link = nil
+	c.ready = make(chan struct{})
+	_ = l.conn.Close()
+	close(l.broken)
+	if !c.closed.IsBroken() {
+		c.log.Infow("rtp over tcp disconnected", "error", err)
+	}
+}
+
+func (c *tcpMediaConn) ReadFromUDPAddrPort(b []byte) (int, netip.AddrPort, error) {
+	for {
+		l, err := c.getLink()
+		if err != nil {
+			return 0, netip.AddrPort{}, err
+		}
+		n, err := readTCPFrame(l.conn, c.rhdr[:], b)
+		if err == nil {
+			return n, l.remote, nil
+		} else if errors.Is(err, errTCPFrameTooLarge) {
+			continue // skipped, the stream is still in sync
+		}
+		c.drop(l, err)
+	}
+}
+
+// readTCPFrame reads a single frame with a 16-bit length prefix (RFC 4571).
+func readTCPFrame(r io.Reader, hdr []byte, b []byte) (int, error) {
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return 0, err
+	}
+	size := int(binary.BigEndian.Uint16(hdr))
+	if size > len(b) {
+		if _, err := io.CopyN(io.Discard, r, int64(size)); err != nil {
+			return 0, err
+		}
+		return 0, errTCPFrameTooLarge
+	}
+	return io.ReadFull(r, b[:size])
+}
+
+func (c *tcpMediaConn) WriteToUDPAddrPort(b []byte, _ netip.AddrPort) (int, error) {
+	if len(b) > math.MaxUint16 {
+		return 0, errTCPFrameTooLarge
+	}
+	c.mu.Lock()
+	l := c.link
+	c.mu.Unlock()
+	if l == nil {
+		return len(b), nil // not connected yet, drop as UDP would
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.wbuf = binary.BigEndian.AppendUint16(c.wbuf[:0], uint16(len(b)))
+	c.wbuf = append(c.wbuf, b...)
+	// Partially written frame breaks the framing, so the connection is dropped on any error.
+	_ = l.conn.SetWriteDeadline(time.Now().Add(tcpMediaWriteTimeout))
+	if _, err := l.conn.Write(c.wbuf); err != nil {
+		c.drop(l, err)
+	}
+	return len(b), nil
+}
+
+func (c *tcpMediaConn) Close() error {
+	c.closed.Break()
+	_ = c.ln.Close()
+	c.mu.Lock()
+	if l := c.link; l != nil {
+		_ = l.conn.Close()
+	}
+	c.mu.Unlock()
+	return c.UDPConn.Close()
+}
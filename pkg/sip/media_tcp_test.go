@@ -0,0 +1,182 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/media-sdk/sdp"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+)
+
+func TestReadTCPFrame(t *testing.T) {
+	data := []byte{0, 3, 'a', 'b', 'c', 0, 5, 1, 2, 3, 4, 5, 0, 1, 'x'}
+	r := bytes.NewReader(data)
+	var hdr [2]byte
+	buf := make([]byte, 4)
+
+	n, err := readTCPFrame(r, hdr[:], buf)
+	require.NoError(t, err)
+	require.Equal(t, "abc", string(buf[:n]))
+
+	_, err = readTCPFrame(r, hdr[:], buf)
+	require.ErrorIs(t, err, errTCPFrameTooLarge)
+
+	n, err = readTCPFrame(r, hdr[:], buf)
+	require.NoError(t, err)
+	require.Equal(t, "x", string(buf[:n]))
+}
+
+func TestTCPMediaConnRejectsStranger(t *testing.T) {
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	ln, err := listenTCPMedia(udp)
+	require.NoError(t, err)
+	remote := netip.MustParseAddrPort("127.0.0.2:30000")
+	c := newTCPMediaConn(logger.GetLogger(), udp, ln, &TCPMediaConf{Active: false}, remote, netip.Addr{})
+	t.Cleanup(func() { _ = c.Close() })
+
+	// A stranger connects first and is disconnected.
+	stranger, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer stranger.Close()
+	_ = stranger.SetReadDeadline(time.Now().Add(3 * time.Second))
+	_, err = stranger.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+
+	// The remote from SDP is accepted.
+	d := net.Dialer{LocalAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 2)}}
+	peer, err := d.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer peer.Close()
+	_, err = peer.Write([]byte{0, 3, 'a', 'b', 'c'})
+	require.NoError(t, err)
+	buf := make([]byte, 16)
+	n, addr, err := c.ReadFromUDPAddrPort(buf)
+	require.NoError(t, err)
+	require.Equal(t, "abc", string(buf[:n]))
+	require.Equal(t, remote.Addr(), addr.Addr())
+}
+
+type countPCMWriter struct {
+	samples atomic.Int64
+}
+
+func (w *countPCMWriter) String() string  { return "Count" }
+func (w *countPCMWriter) SampleRate() int { return 8000 }
+func (w *countPCMWriter) Close() error    { return nil }
+
+func (w *countPCMWriter) WriteSample(s msdk.PCM16Sample) error {
+	w.samples.Add(int64(len(s)))
+	return nil
+}
+
+func newLocalMediaPort(t *testing.T, name string, transport config.MediaTransport) *MediaPort {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	m, err := NewMediaPortWith(logger.GetLogger().WithName(name), nil, conn, &MediaOptions{
+		IP:           newIP("127.0.0.1"),
+		RTPTransport: transport,
+	}, 8000)
+	require.NoError(t, err)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func TestMediaPortTCP(t *testing.T) {
+	m1 := newLocalMediaPort(t, "one", config.MediaTransportTCP)
+	m2 := newLocalMediaPort(t, "two", config.MediaTransportAuto)
+
+	offer, err := m1.NewOffer(sdp.EncryptionNone)
+	require.NoError(t, err)
+	offerData, err := offer.SDP.Marshal()
+	require.NoError(t, err)
+	require.Contains(t, string(offerData), " TCP/RTP/AVP ")
+	require.Contains(t, string(offerData), "a=setup:actpass")
+
+	answer, conf2, err := m2.SetOffer(offerData, sdp.EncryptionNone)
+	require.NoError(t, err)
+	require.Equal(t, &TCPMediaConf{Active: false}, conf2.TCP)
+	answerData, err := answer.SDP.Marshal()
+	require.NoError(t, err)
+	require.Contains(t, string(answerData), " TCP/RTP/AVP ")
+	require.Contains(t, string(answerData), "a=setup:passive")
+
+	conf1, err := m1.SetAnswer(offer, answerData, sdp.EncryptionNone)
+	require.NoError(t, err)
+	require.Equal(t, &TCPMediaConf{Active: true}, conf1.TCP)
+
+	require.NoError(t, m1.SetConfig(conf1))
+	require.NoError(t, m2.SetConfig(conf2))
+
+	var recv countPCMWriter
+	m2.WriteAudioTo(&recv)
+	w := m1.GetAudioWriter()
+	frame := make(msdk.PCM16Sample, w.SampleRate()/50)
+	for i := range frame {
+		frame[i] = 5116
+	}
+	require.Eventually(t, func() bool {
+		_ = w.WriteSample(frame)
+		return recv.samples.Load() > 0
+	}, 3*time.Second, 20*time.Millisecond)
+}
+
+func TestMediaPortTCPForbidden(t *testing.T) {
+	m1 := newLocalMediaPort(t, "one", config.MediaTransportTCP)
+	m2 := newLocalMediaPort(t, "two", config.MediaTransportUDP)
+
+	offer, err := m1.NewOffer(sdp.EncryptionNone)
+	require.NoError(t, err)
+	offerData, err := offer.SDP.Marshal()
+	require.NoError(t, err)
+
+	_, _, err = m2.SetOffer(offerData, sdp.EncryptionNone)
+	require.ErrorIs(t, err, siperrors.ErrMediaTransportForbidden)
+}
+
+func TestMediaPortTCPAuto(t *testing.T) {
+	m1 := newLocalMediaPort(t, "one", config.MediaTransportAuto)
+	m2 := newLocalMediaPort(t, "two", config.MediaTransportUDP)
+
+	offer, err := m1.NewOffer(sdp.EncryptionNone)
+	require.NoError(t, err)
+	require.Len(t, offer.SDP.MediaDescriptions, 2)
+	offerData, err := offer.SDP.Marshal()
+	require.NoError(t, err)
+
+	// Remote without TCP support picks the UDP line.
+	answer, conf2, err := m2.SetOffer(offerData, sdp.EncryptionNone)
+	require.NoError(t, err)
+	require.Nil(t, conf2.TCP)
+	answerData, err := answer.SDP.Marshal()
+	require.NoError(t, err)
+
+	conf1, err := m1.SetAnswer(offer, answerData, sdp.EncryptionNone)
+	require.NoError(t, err)
+	require.Nil(t, conf1.TCP)
+}
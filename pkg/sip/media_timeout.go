@@ -0,0 +1,101 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// timeoutPause is a reason to pause media timeout, while the remote is not expected to send media.
+type timeoutPause uint32
+
+const (
+	// timeoutPauseDirection is set when the remote SDP direction says it doesn't send media.
+	timeoutPauseDirection timeoutPause = 1 << iota
+	// timeoutPauseHeld is set while the dialog is on hold.
+	timeoutPauseHeld
+	// timeoutPauseTransfer is set while the call is being transferred.
+	timeoutPauseTransfer
+)
+
+func (r timeoutPause) String() string {
+	var names []string
+	if r&timeoutPauseDirection != 0 {
+		names = append(names, "direction")
+	}
+	if r&timeoutPauseHeld != 0 {
+		names = append(names, "held")
+	}
+	if r&timeoutPauseTransfer != 0 {
+		names = append(names, "transfer")
+	}
+	return strings.Join(names, ",")
+}
+
+// timeoutPauses is a set of reasons to pause media timeout. The timeout restarts when all of them are cleared.
+type timeoutPauses struct {
+	v atomic.Uint32
+}
+
+// Set adds or removes the reason and returns the previous set.
+func (t *timeoutPauses) Set(r timeoutPause, paused bool) timeoutPause {
+	if paused {
+		return timeoutPause(t.v.Or(uint32(r)))
+	}
+	return timeoutPause(t.v.And(^uint32(r)))
+}
+
+// Load reports if the timeout is paused for any reason.
+func (t *timeoutPauses) Load() bool {
+	return t.v.Load() != 0
+}
+
+// Reasons returns the current set of reasons.
+func (t *timeoutPauses) Reasons() timeoutPause {
+	return timeoutPause(t.v.Load())
+}
+
+// pauseTimeout pauses media timeout for a given reason. The full timeout applies again once all reasons are cleared.
+func (p *MediaPort) pauseTimeout(r timeoutPause, paused bool) {
+	prev := p.timeoutPaused.Set(r, paused)
+	if (prev&r != 0) == paused {
+		return
+	}
+	p.log.Debugw("media timeout pause changed", "reason", r.String(), "paused", paused, "reasons", p.timeoutPaused.Reasons().String())
+}
+
+// followDialog drives media timeout from the state of the call: it's disabled until the call is answered,
+// paused while the call is held, and disabled again when the call ends.
+func (p *MediaPort) followDialog(f *callFSM) {
+	f.OnChange(p.onDialogState)
+	if st, _ := f.State(); st != DialogIdle {
+		p.onDialogState(DialogIdle, st)
+	}
+}
+
+func (p *MediaPort) onDialogState(from, to DialogState) {
+	switch to {
+	case DialogAnswered:
+		p.pauseTimeout(timeoutPauseHeld, false)
+		if from < DialogAnswered {
+			p.EnableTimeout(true)
+		}
+	case DialogHeld:
+		p.pauseTimeout(timeoutPauseHeld, true)
+	default:
+		p.EnableTimeout(false)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestTimeoutPauses(t *testing.T) {
+	var p timeoutPauses
+	require.False(t, p.Load())
+	p.Set(timeoutPauseDirection, true)
+	p.Set(timeoutPauseTransfer, true)
+	require.True(t, p.Load())
+	require.Equal(t, "direction,transfer", p.Reasons().String())
+
+	p.Set(timeoutPauseDirection, false)
+	require.True(t, p.Load(), "transfer is still in progress")
+	p.Set(timeoutPauseTransfer, false)
+	require.False(t, p.Load())
+}
+
+func TestMediaTimeoutFollowsDialog(t *testing.T) {
+	m := newLocalMediaPort(t, "one", config.MediaTransportUDP)
+	f := newCallFSM(logger.GetLogger(), newDialogHistory(), DialogProceeding)
+	m.EnableTimeout(true)
+	m.followDialog(f)
+	require.Nil(t, m.timeoutStart.Load(), "disabled until answered")
+
+	f.Transition(DialogEarly)
+	require.Nil(t, m.timeoutStart.Load())
+
+	f.Transition(DialogAnswered)
+	start := m.timeoutStart.Load()
+	require.NotNil(t, start)
+	require.False(t, m.timeoutPaused.Load())
+
+	f.Transition(DialogHeld)
+	require.True(t, m.timeoutPaused.Load())
+	f.Transition(DialogAnswered)
+	require.False(t, m.timeoutPaused.Load())
+	require.Same(t, start, m.timeoutStart.Load(), "resume doesn't restart the initial timeout")
+
+	f.Transition(DialogTerminating)
+	require.Nil(t, m.timeoutStart.Load())
+}
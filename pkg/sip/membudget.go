@@ -0,0 +1,159 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/livekit/media-sdk/rtp"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+// Kinds of call buffers accounted by the memory budget.
+const (
+	memJitter   = "jitter"
+	memMixer    = "mixer"
+	memRecorder = "recorder"
+)
+
+const (
+	// jitterBufferMem estimates memory of a jitter buffer. It holds packets for up to 60 ms,
+	// which is a few full packets even for short frames and reordering.
+	jitterBufferMem = 16 * rtp.MTUSize
+	// recorderBufferMem estimates memory of an unencrypted recording. Encrypted ones also buffer a record.
+	recorderBufferMem = 16 << 10
+)
+
+// memBudget accounts memory used by buffers of calls: jitter buffers, mixers of concurrent RTP streams and recordings.
+// Instead of allocating buffers over the budget, calls degrade: see config.MemoryBudgetConfig.
+type memBudget struct {
+	mon     *stats.Monitor
+	max     int64 // zero means no limit
+	perCall int64 // zero means no limit
+	used    atomic.Int64
+}
+
+// newMemBudget creates a memory budget from the config. It returns nil if the budget is disabled.
+func newMemBudget(mon *stats.Monitor, conf *config.MemoryBudgetConfig) *memBudget {
+	if conf == nil {
+		return nil
+	}
+	return &memBudget{mon: mon, max: conf.Max, perCall: conf.PerCall}
+}
+
+// NewCall starts accounting buffers of a call. The returned callMemory must be closed when the call ends.
+func (b *memBudget) NewCall() *callMemory {
+	if b == nil {
+		return nil
+	}
+	return &callMemory{b: b}
+}
+
+// Used returns memory reserved by all calls.
+func (b *memBudget) Used() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.used.Load()
+}
+
+func (b *memBudget) reserve(n int64) bool {
+	for {
+		used := b.used.Load()
+		if b.max > 0 && used+n > b.max {
+			return false
+		}
+		if b.used.CompareAndSwap(used, used+n) {
+			b.mon.MemoryBudgetUsed(used + n)
+			return true
+		}
+	}
+}
+
+func (b *memBudget) release(n int64) {
+	b.mon.MemoryBudgetUsed(b.used.Add(-n))
+}
+
+// callMemory accounts buffers of a single call. A nil callMemory allows all buffers.
+type callMemory struct {
+	b *memBudget
+
+	mu     sync.Mutex
+	used   int64
+	closed bool
+}
+
+// Reserve reserves n bytes for a buffer of the kind. It returns false if the buffer is over the budget
+// of the call or of the node, in which case the buffer must not be allocated.
+func (m *callMemory) Reserve(kind string, n int) bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return false
+	}
+	if (m.b.perCall > 0 && m.used+int64(n) > m.b.perCall) || !m.b.reserve(int64(n)) {
+		m.b.mon.MemoryBudgetDenied(kind)
+		return false
+	}
+	m.used += int64(n)
+	return true
+}
+
+// Release releases n bytes reserved before. It's a no-op once the call memory is closed.
+func (m *callMemory) Release(n int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.used -= int64(n)
+	m.b.release(int64(n))
+}
+
+// Used returns memory reserved by the call.
+func (m *callMemory) Used() int64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.used
+}
+
+// Close releases all memory of the call, including buffers which are not released explicitly.
+func (m *callMemory) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	if m.used != 0 {
+		m.b.release(m.used)
+		m.used = 0
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestMemoryBudgetConfig(t *testing.T) {
+	require.NoError(t, (&config.MemoryBudgetConfig{Max: 1 << 20}).Validate())
+	require.NoError(t, (&config.MemoryBudgetConfig{PerCall: 1 << 10}).Validate())
+	require.Error(t, (&config.MemoryBudgetConfig{}).Validate())
+	require.Error(t, (&config.MemoryBudgetConfig{Max: -1, PerCall: 1}).Validate())
+}
+
+func TestMemBudget(t *testing.T) {
+	require.Nil(t, newMemBudget(nil, nil))
+	var none *callMemory
+	require.True(t, none.Reserve(memJitter, 1<<30))
+	none.Release(1 << 30)
+	none.Close()
+
+	b := newMemBudget(nil, &config.MemoryBudgetConfig{Max: 100, PerCall: 60})
+	c1, c2 := b.NewCall(), b.NewCall()
+
+	require.True(t, c1.Reserve(memJitter, 40))
+	// Over the budget of the call.
+	require.False(t, c1.Reserve(memMixer, 30))
+	require.True(t, c1.Reserve(memMixer, 20))
+	require.EqualValues(t, 60, c1.Used())
+
+	// Over the budget of the node.
+	require.False(t, c2.Reserve(memRecorder, 50))
+	require.True(t, c2.Reserve(memRecorder, 40))
+	require.EqualValues(t, 100, b.Used())
+
+	c1.Release(20)
+	require.True(t, c2.Reserve(memMixer, 20))
+	require.EqualValues(t, 100, b.Used())
+
+	// Memory which is not released explicitly is released when the call ends.
+	c1.Close()
+	require.EqualValues(t, 60, b.Used())
+	c1.Release(40)
+	require.False(t, c1.Reserve(memJitter, 1))
+	require.EqualValues(t, 60, b.Used())
+	c2.Close()
+	require.EqualValues(t, 0, b.Used())
+}
+
+func TestMediaPortMemoryBudget(t *testing.T) {
+	b := newMemBudget(nil, &config.MemoryBudgetConfig{PerCall: jitterBufferMem})
+	newPort := func(mem *callMemory) *MediaPort {
+		c, _ := newUDPPipe()
+		m, err := NewMediaPortWith(logger.GetLogger(), nil, c, &MediaOptions{
+			IP:                 newIP("1.1.1.1"),
+			EnableJitterBuffer: true,
+			memory:             mem,
+		}, 8000)
+		require.NoError(t, err)
+		return m
+	}
+	mem := b.NewCall()
+	m1 := newPort(mem)
+	require.True(t, m1.JitterBufferEnabled())
+	// The jitter buffer is disabled instead of going over the budget.
+	m2 := newPort(mem)
+	require.False(t, m2.JitterBufferEnabled())
+	m2.Close()
+	require.EqualValues(t, jitterBufferMem, b.Used())
+	m1.Close()
+	require.EqualValues(t, 0, b.Used())
+}
+
+func TestRecorderStageMemoryBudget(t *testing.T) {
+	dir := t.TempDir()
+	b := newMemBudget(nil, &config.MemoryBudgetConfig{Max: recorderBufferMem})
+	other := b.NewCall()
+	require.True(t, other.Reserve(memMixer, 1))
+
+	st, err := newRecorderStage(MediaStageEnv{CallID: "SCL_test", mem: b.NewCall()}, config.MediaStageConfig{Dir: dir})
+	require.NoError(t, err)
+	rec := st.(*recorderStage)
+	out := &stageTestWriter{rate: 16000}
+	w := st.Process(out)
+
+	// Recording is paused, but the audio keeps flowing.
+	require.NoError(t, w.WriteSample(msdk.PCM16Sample{1, 2}))
+	require.Len(t, out.samples, 1)
+	require.True(t, rec.paused)
+	_, err = os.Stat(filepath.Join(dir, "SCL_test.wav"))
+	require.True(t, os.IsNotExist(err))
+
+	// Recording resumes on the next retry, once there is enough memory.
+	other.Close()
+	rec.retry = rec.retry.Add(-recorderMemRetry)
+	require.NoError(t, w.WriteSample(msdk.PCM16Sample{3, 4}))
+	require.False(t, rec.paused)
+	require.EqualValues(t, recorderBufferMem, b.Used())
+	require.NoError(t, rec.Close())
+	require.EqualValues(t, 0, b.Used())
+
+	data, err := os.ReadFile(filepath.Join(dir, "SCL_test.wav"))
+	require.NoError(t, err)
+	require.Len(t, data, wavHeaderSize+4)
+}
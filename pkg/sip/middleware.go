@@ -0,0 +1,63 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/livekit/sipgo"
+	"github.com/livekit/sipgo/sip"
+)
+
+// Middleware intercepts SIP requests received by the server, similar to HTTP middleware.
+//
+// It returns a handler that may inspect or modify the request and then call next to continue the chain.
+// To short-circuit the request, the handler responds to the transaction itself and does not call next.
+// ACK requests have no transaction to respond to.
+type Middleware func(next sipgo.RequestHandler) sipgo.RequestHandler
+
+type middleware struct {
+	wrap    Middleware
+	methods []sip.RequestMethod // all methods if empty
+}
+
+func (m *middleware) matches(method sip.RequestMethod) bool {
+	return len(m.methods) == 0 || slices.Contains(m.methods, method)
+}
+
+// Use registers a middleware for requests with given methods, or for all requests if no methods are given.
+//
+// Middlewares run in the order of registration, after request limits are checked and before built-in handlers.
+// Use must be called before the server is started.
+func (s *Server) Use(mw Middleware, methods ...sip.RequestMethod) {
+	s.middlewares = append(s.middlewares, middleware{wrap: mw, methods: methods})
+}
+
+// intercept wraps the built-in handler with middlewares matching the method of the request.
+func (s *Server) intercept(h sipgo.RequestHandler) sipgo.RequestHandler {
+	if len(s.middlewares) == 0 {
+		return h
+	}
+	return func(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
+		next := h
+		for _, m := range slices.Backward(s.middlewares) {
+			if m.matches(req.Method) {
+				next = m.wrap(next)
+			}
+		}
+		next(log, req, tx)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/livekit/sipgo"
+	"github.com/livekit/sipgo/sip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerMiddleware(t *testing.T) {
+	var calls []string
+	named := func(name string) Middleware {
+		return func(next sipgo.RequestHandler) sipgo.RequestHandler {
+			return func(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
+				calls = append(calls, name)
+				next(log, req, tx)
+			}
+		}
+	}
+	s := &Server{}
+	s.Use(named("all"))
+	s.Use(named("invite"), sip.INVITE)
+	s.Use(named("bye"), sip.BYE)
+	s.Use(func(next sipgo.RequestHandler) sipgo.RequestHandler {
+		return func(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
+			if req.GetHeader("X-Block") != nil {
+				calls = append(calls, "blocked")
+				return
+			}
+			req.AppendHeader(sip.NewHeader("X-Seen", "1"))
+			next(log, req, tx)
+		}
+	})
+	h := s.intercept(func(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
+		require.NotNil(t, req.GetHeader("X-Seen"))
+		calls = append(calls, "handler")
+	})
+
+	newReq := func(method sip.RequestMethod) *sip.Request {
+		return sip.NewRequest(method, sip.Uri{User: "bob", Host: "example.com"})
+	}
+
+	h(nil, newReq(sip.INVITE), nil)
+	require.Equal(t, []string{"all", "invite", "handler"}, calls)
+
+	calls = nil
+	h(nil, newReq(sip.OPTIONS), nil)
+	require.Equal(t, []string{"all", "handler"}, calls)
+
+	calls = nil
+	req := newReq(sip.BYE)
+	req.AppendHeader(sip.NewHeader("X-Block", "1"))
+	h(nil, req, nil)
+	require.Equal(t, []string{"all", "bye", "blocked"}, calls)
+}
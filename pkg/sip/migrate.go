@@ -0,0 +1,164 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+)
+
+// migrationTimeout limits how long a single call waits for the other instance to take it over.
+const migrationTimeout = failoverSetupTimeout + 10*time.Second
+
+// MigrateCallsRequest moves active calls to another instance, for example before a restart.
+type MigrateCallsRequest struct {
+	// To is the SIP address of the instance taking over the calls. It defaults to the failover peer.
+	To string `json:"to"`
+	// CallIDs selects calls to migrate. All active calls are migrated if it's empty.
+	CallIDs []string `json:"call_ids,omitempty"`
+}
+
+// MigrateCallsResponse lists migrated calls and the reasons why other calls were not migrated.
+type MigrateCallsResponse struct {
+	Migrated []string          `json:"migrated"`
+	Failed   map[string]string `json:"failed,omitempty"`
+}
+
+// callHandoff is an active call which can be passed to another instance.
+type callHandoff struct {
+	id       LocalTag
+	migrated *atomic.Bool
+	snapshot func() *CallSnapshot
+	close    func()
+}
+
+// handoffCalls returns active inbound calls with given IDs, or all of them if ids is empty.
+func (s *Server) handoffCalls(ids map[LocalTag]bool) []callHandoff {
+	s.cmu.RLock()
+	defer s.cmu.RUnlock()
+	var out []callHandoff
+	for id, c := range s.byLocal {
+		if (len(ids) != 0 && !ids[id]) || !c.started.IsBroken() || c.done.Load() {
+			continue
+		}
+		out = append(out, callHandoff{id: id, migrated: &c.migrated, snapshot: c.snapshot, close: c.closeMigrated})
+	}
+	return out
+}
+
+// handoffCalls returns active outbound calls with given IDs, or all of them if ids is empty.
+func (c *Client) handoffCalls(ids map[LocalTag]bool) []callHandoff {
+	c.cmu.Lock()
+	defer c.cmu.Unlock()
+	var out []callHandoff
+	for id, call := range c.activeCalls {
+		if (len(ids) != 0 && !ids[id]) || !call.started.IsBroken() || call.stopped.IsBroken() {
+			continue
+		}
+		out = append(out, callHandoff{id: id, migrated: &call.migrated, snapshot: call.snapshot, close: call.closeMigrated})
+	}
+	return out
+}
+
+// closeMigrated releases the call after another instance took it over. The dialog is not terminated.
+func (c *inboundCall) closeMigrated() {
+	c.close(false, callMigrated, "migrated")
+}
+
+// closeMigrated releases the call after another instance took it over. The dialog is not terminated.
+func (c *outboundCall) closeMigrated() {
+	c.CloseWithReason(callMigrated, "migrated", livekit.DisconnectReason_MIGRATION)
+}
+
+// MigrateCalls passes active calls to another instance sharing the call state store, and releases them locally.
+//
+// The other instance re-INVITEs the remote party toward its own media address, takes over the dialog
+// and joins the room as the same participant. Calls are released here without a BYE once the other instance
+// reports them as taken over. Calls which were not taken over stay on this instance.
+func (s *Service) MigrateCalls(ctx context.Context, req *MigrateCallsRequest) (*MigrateCallsResponse, error) {
+	f := s.srv.failover
+	if f == nil {
+		return nil, psrpc.NewErrorf(psrpc.FailedPrecondition, "failover is not enabled")
+	}
+	to := req.To
+	if to == "" {
+		to = f.peer
+	}
+	if to == f.address {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "calls cannot be migrated to the same instance")
+	}
+	var ids map[LocalTag]bool
+	if len(req.CallIDs) != 0 {
+		ids = make(map[LocalTag]bool, len(req.CallIDs))
+		for _, id := range req.CallIDs {
+			ids[LocalTag(id)] = true
+		}
+	}
+	calls := append(s.srv.handoffCalls(ids), s.cli.handoffCalls(ids)...)
+
+	resp := &MigrateCallsResponse{Migrated: []string{}}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	fail := func(id LocalTag, err string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if resp.Failed == nil {
+			resp.Failed = make(map[string]string)
+		}
+		resp.Failed[string(id)] = err
+	}
+	for id := range ids {
+		if !slices.ContainsFunc(calls, func(c callHandoff) bool { return c.id == id }) {
+			fail(id, "call not found")
+		}
+	}
+	for _, c := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			snap := c.snapshot()
+			if snap == nil {
+				fail(c.id, "call is not established")
+				return
+			}
+			log := s.log.WithValues("callID", c.id, "sipCallID", snap.CallID, "to", to)
+			// Set before the handoff, since the other instance replaces the participant before it reports the result.
+			c.migrated.Store(true)
+			ctx, cancel := context.WithTimeout(ctx, migrationTimeout)
+			defer cancel()
+			if err := f.Handoff(ctx, to, snap); err != nil {
+				c.migrated.Store(false)
+				log.Warnw("cannot migrate call", err)
+				fail(c.id, err.Error())
+				return
+			}
+			log.Infow("call migrated")
+			c.close()
+			mu.Lock()
+			resp.Migrated = append(resp.Migrated, string(c.id))
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return resp, nil
+}
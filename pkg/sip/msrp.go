@@ -0,0 +1,679 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	psdp "github.com/pion/sdp/v3"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+)
+
+const (
+	// msrpMaxChunk is the maximal size of the body of MSRP SEND requests we send.
+	msrpMaxChunk = 2048
+	// msrpMaxMessage limits the size of reassembled messages received from SIP.
+	msrpMaxMessage = 64 << 10
+	// msrpMaxPending limits the number of messages reassembled at the same time.
+	msrpMaxPending   = 16
+	msrpWriteTimeout = 5 * time.Second
+	msrpListenTries  = 10
+	// msrpMaxLine limits the length of the start line and header lines.
+	msrpMaxLine = 4096
+	// msrpAcceptTimeout limits the time for the remote to send the first request after connecting.
+	msrpAcceptTimeout = 5 * time.Second
+
+	msrpContentType = "text/plain"
+)
+
+var (
+	errMSRPTooLarge  = errors.New("msrp message is too large")
+	errMSRPMalformed = errors.New("malformed msrp message")
+)
+
+// isMSRPMedia checks if the media line describes an MSRP session over TCP. MSRP over TLS is not supported.
+func isMSRPMedia(m *psdp.MediaDescription) bool {
+	return m.MediaName.Media == "message" && m.MediaName.Port.Value != 0 &&
+		strings.EqualFold(strings.Join(m.MediaName.Protos, "/"), "TCP/MSRP")
+}
+
+// findMSRPMedia returns the index of the first MSRP media line, or -1.
+func findMSRPMedia(s *psdp.SessionDescription) int {
+	for i, m := range s.MediaDescriptions {
+		if isMSRPMedia(m) {
+			return i
+		}
+	}
+	return -1
+}
+
+// msrpAcceptsText checks if the remote accepts plain text messages.
+func msrpAcceptsText(m *psdp.MediaDescription) bool {
+	v, ok := m.Attribute("accept-types")
+	if !ok {
+		return false
+	}
+	for _, t := range strings.Fields(v) {
+		if t == "*" || strings.EqualFold(t, msrpContentType) || strings.EqualFold(t, "text/*") {
+			return true
+		}
+	}
+	return false
+}
+
+// answerExtraMedia expands the answer with a non-audio media line (MSRP, text), keeping the order of media lines in the offer.
+// Other media lines, that are not in the answer yet, are rejected. If media is nil, the line at idx is rejected as well.
+func answerExtraMedia(answer *psdp.SessionDescription, offer *psdp.SessionDescription, idx int, media *psdp.MediaDescription) {
+	if len(answer.MediaDescriptions) == len(offer.MediaDescriptions) {
+		// Already expanded, other lines are rejected there.
+		if media != nil {
+			answer.MediaDescriptions[idx] = media
+		}
+		return
+	}
+	// Answer only contains the audio line.
+	var audio *psdp.MediaDescription
+	if len(answer.MediaDescriptions) != 0 {
+		audio = answer.MediaDescriptions[0]
+	}
+	out := make([]*psdp.MediaDescription, 0, len(offer.MediaDescriptions))
+	for i, m := range offer.MediaDescriptions {
+		switch {
+		case i == idx && media != nil:
+			out = append(out, media)
+		case m.MediaName.Media == "audio" && audio != nil:
+			out = append(out, audio)
+			audio = nil
+		default:
+			out = append(out, &psdp.MediaDescription{
+				MediaName: psdp.MediaName{
+					Media:   m.MediaName.Media,
+					Port:    psdp.RangedPort{Value: 0},
+					Protos:  m.MediaName.Protos,
+					Formats: m.MediaName.Formats[:min(1, len(m.MediaName.Formats))],
+				},
+			})
+		}
+	}
+	answer.MediaDescriptions = out
+}
+
+// msrpMessage is an MSRP request or response (RFC 4975).
+type msrpMessage struct {
+	TID    string
+	Method string // empty for responses
+	Status int
+	Phrase string
+	Header []msrpHeader
+	Body   []byte
+	Flag   byte // continuation flag: '$' for the last chunk, '+' for more chunks, '#' for aborted message
+}
+
+type msrpHeader struct {
+	Name  string
+	Value string
+}
+
+func (m *msrpMessage) Get(name string) string {
+	for _, h := range m.Header {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func (m *msrpMessage) Add(name, value string) {
+	m.Header = append(m.Header, msrpHeader{Name: name, Value: value})
+}
+
+func (m *msrpMessage) Marshal() []byte {
+	var b bytes.Buffer
+	if m.Method != "" {
+		fmt.Fprintf(&b, "MSRP %s %s\r\n", m.TID, m.Method)
+	} else {
+		fmt.Fprintf(&b, "MSRP %s %03d %s\r\n", m.TID, m.Status, m.Phrase)
+	}
+	for _, h := range m.Header {
+		fmt.Fprintf(&b, "%s: %s\r\n", h.Name, h.Value)
+	}
+	if len(m.Body) != 0 {
+		b.WriteString("\r\n")
+		b.Write(m.Body)
+		b.WriteString("\r\n")
+	}
+	flag := m.Flag
+	if flag == 0 {
+		flag = '$'
+	}
+	fmt.Fprintf(&b, "-------%s%c\r\n", m.TID, flag)
+	return b.Bytes()
+}
+
+// readMSRPLine reads a single line. Lines that don't fit into the reader buffer are rejected.
+func readMSRPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", errMSRPTooLarge
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// readMSRPMessage reads a single MSRP request or response.
+func readMSRPMessage(r *bufio.Reader) (*msrpMessage, error) {
+	line, err := readMSRPLine(r)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(line, " ", 4)
+	if len(parts) < 3 || parts[0] != "MSRP" || parts[1] == "" {
+		return nil, errMSRPMalformed
+	}
+	m := &msrpMessage{TID: parts[1]}
+	if code, err := strconv.Atoi(parts[2]); err == nil && len(parts[2]) == 3 {
+		m.Status = code
+		if len(parts) > 3 {
+			m.Phrase = parts[3]
+		}
+	} else if len(parts) == 3 {
+		m.Method = parts[2]
+	} else {
+		return nil, errMSRPMalformed
+	}
+	end := "-------" + m.TID
+	size := 0
+	for {
+		line, err = readMSRPLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if size += len(line); size > msrpMaxMessage {
+			return nil, errMSRPTooLarge
+		}
+		if strings.HasPrefix(line, end) && len(line) == len(end)+1 {
+			m.Flag = line[len(end)]
+			return m, nil
+		}
+		if line == "" {
+			break // body follows
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, errMSRPMalformed
+		}
+		m.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	// Body ends with CRLF, followed by the end-line.
+	var body []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			return nil, err
+		}
+		if len(body)+len(chunk) > msrpMaxMessage {
+			return nil, errMSRPTooLarge
+		}
+		body = append(body, chunk...)
+		if !bytes.HasSuffix(body, []byte("\n")) {
+			continue
+		}
+		i := bytes.LastIndex(body[:len(body)-1], []byte("\n"))
+		last := strings.TrimRight(string(body[i+1:]), "\r\n")
+		if strings.HasPrefix(last, end) && len(last) == len(end)+1 {
+			m.Flag = last[len(end)]
+			body = body[:i+1]
+			m.Body = bytes.TrimSuffix(bytes.TrimSuffix(body, []byte("\n")), []byte("\r"))
+			return m, nil
+		}
+	}
+}
+
+// parseByteRange parses the Byte-Range header. Total is -1 if unknown.
+func parseByteRange(v string) (start, end, total int, _ error) {
+	if v == "" {
+		return 1, -1, -1, nil
+	}
+	rng, tot, ok := strings.Cut(v, "/")
+	if !ok {
+		return 0, 0, 0, errMSRPMalformed
+	}
+	s, e, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, 0, errMSRPMalformed
+	}
+	var err error
+	if start, err = strconv.Atoi(s); err != nil || start < 1 {
+		return 0, 0, 0, errMSRPMalformed
+	}
+	end, total = -1, -1
+	if e != "*" {
+		if end, err = strconv.Atoi(e); err != nil {
+			return 0, 0, 0, errMSRPMalformed
+		}
+	}
+	if tot != "*" {
+		if total, err = strconv.Atoi(tot); err != nil {
+			return 0, 0, 0, errMSRPMalformed
+		}
+	}
+	return start, end, total, nil
+}
+
+// listenTCPRange opens a TCP listener on a random port from the range.
+func listenTCPRange(ports rtcconfig.PortRange) (net.Listener, error) {
+	if ports.Start == 0 || ports.End <= ports.Start {
+		return net.Listen("tcp", ":0")
+	}
+	var err error
+	for range msrpListenTries {
+		port := int(ports.Start) + rand.IntN(int(ports.End-ports.Start)+1)
+		var ln net.Listener
+		ln, err = net.Listen("tcp", ":"+strconv.Itoa(port))
+		if err == nil {
+			return ln, nil
+		}
+	}
+	return nil, err
+}
+
+func newMSRPSessionID() string {
+	return strings.ToLower(guid.New(""))
+}
+
+func newMSRPTransactionID() string {
+	return strconv.FormatUint(rand.Uint64(), 36)
+}
+
+// msrpSession relays text messages between SIP and the room over MSRP (RFC 4975).
+//
+// We only answer MSRP offers, so the remote is the active party and connects to our listener (RFC 4975, section 5.4).
+type msrpSession struct {
+	log        logger.Logger
+	ln         net.Listener
+	localPath  string
+	remotePath string
+	remoteIP   netip.Addr // connections from other addresses are rejected, if set
+	onText     func(text string)
+	closed     core.Fuse
+
+	mu      sync.Mutex
+	conn    net.Conn
+	pending map[string][]byte // partially received messages, by Message-ID
+
+	wmu sync.Mutex
+}
+
+// newMSRPSession listens for a connection from the remote and returns the session with the path for the SDP answer.
+// If remoteIP is set, only connections from this address are accepted.
+func newMSRPSession(log logger.Logger, ip netip.Addr, ports rtcconfig.PortRange, remotePath string, remoteIP netip.Addr, onText func(text string)) (*msrpSession, error) {
+	ln, err := listenTCPRange(ports)
+	if err != nil {
+		return nil, err
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	s := &msrpSession{
+		log:        log,
+		ln:         ln,
+		localPath:  fmt.Sprintf("msrp://%s/%s;tcp", netip.AddrPortFrom(ip, uint16(port)), newMSRPSessionID()),
+		remotePath: remotePath,
+		remoteIP:   remoteIP.Unmap(),
+		onText:     onText,
+		pending:    make(map[string][]byte),
+	}
+	return s, nil
+}
+
+// Media returns the MSRP media line for the SDP answer.
+func (s *msrpSession) Media() *psdp.MediaDescription {
+	port := s.ln.Addr().(*net.TCPAddr).Port
+	return &psdp.MediaDescription{
+		MediaName: psdp.MediaName{
+			Media:   "message",
+			Port:    psdp.RangedPort{Value: port},
+			Protos:  []string{"TCP", "MSRP"},
+			Formats: []string{"*"},
+		},
+		Attributes: []psdp.Attribute{
+			{Key: "accept-types", Value: msrpContentType},
+			{Key: "path", Value: s.localPath},
+		},
+	}
+}
+
+// Start waits for the remote to connect and starts reading messages.
+func (s *msrpSession) Start() {
+	go s.run()
+}
+
+func (s *msrpSession) run() {
+	defer s.Close()
+	// The listener is closed with the session, if the remote never connects.
+	conn, r, first, err := s.accept()
+	_ = s.ln.Close()
+	if err != nil {
+		if !s.closed.IsBroken() {
+			s.log.Infow("msrp connection was not established", "error", err)
+		}
+		return
+	}
+	s.mu.Lock()
+	if s.closed.IsBroken() {
+		s.mu.Unlock()
+		_ = conn.Close()
+		return
+	}
+	s.conn = conn
+	s.mu.Unlock()
+	s.log.Infow("msrp connected", "remote", conn.RemoteAddr().String())
+
+	s.handle(first)
+	for {
+		m, err := readMSRPMessage(r)
+		if err != nil {
+			if !s.closed.IsBroken() && !errors.Is(err, io.EOF) {
+				s.log.Infow("msrp connection failed", "error", err)
+			}
+			return
+		}
+		s.handle(m)
+	}
+}
+
+// accept waits for the remote to connect and returns the connection with its first request.
+// Connections from unexpected addresses, or with the first request not matching session paths, are closed.
+func (s *msrpSession) accept() (net.Conn, *bufio.Reader, *msrpMessage, error) {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		remote := conn.RemoteAddr().String()
+		if addr, err := netip.ParseAddrPort(remote); s.remoteIP.IsValid() && (err != nil || addr.Addr().Unmap() != s.remoteIP) {
+			s.log.Infow("rejecting msrp connection from unexpected address", "remote", remote, "expected", s.remoteIP)
+			_ = conn.Close()
+			continue
+		}
+		r := bufio.NewReaderSize(conn, msrpMaxLine)
+		_ = conn.SetReadDeadline(time.Now().Add(msrpAcceptTimeout))
+		m, err := readMSRPMessage(r)
+		_ = conn.SetReadDeadline(time.Time{})
+		if err != nil || !s.matchPaths(m) {
+			s.log.Infow("rejecting msrp connection for unexpected session", "remote", remote, "error", err)
+			_ = conn.Close()
+			continue
+		}
+		return conn, r, m, nil
+	}
+}
+
+// matchPaths checks that the request is sent to our path from the path in the remote SDP (RFC 4975, section 7.3).
+func (s *msrpSession) matchPaths(m *msrpMessage) bool {
+	if m.Method == "" {
+		return false
+	}
+	toPath := strings.Fields(m.Get("To-Path"))
+	fromPath := strings.Fields(m.Get("From-Path"))
+	return len(toPath) != 0 && toPath[0] == s.localPath &&
+		slices.Equal(fromPath, strings.Fields(s.remotePath))
+}
+
+func (s *msrpSession) handle(m *msrpMessage) {
+	switch m.Method {
+	case "":
+		if m.Status >= 300 {
+			s.log.Infow("msrp request failed", "status", m.Status, "reason", m.Phrase)
+		}
+	case "SEND":
+		s.handleSend(m)
+	case "REPORT":
+		// Reports for our messages are not requested.
+	default:
+		s.respond(m, 501, "Not Implemented")
+	}
+}
+
+func (s *msrpSession) handleSend(m *msrpMessage) {
+	toPath := strings.Fields(m.Get("To-Path"))
+	if len(toPath) == 0 || toPath[0] != s.localPath {
+		s.respond(m, 481, "Session Does Not Exist")
+		return
+	}
+	id := m.Get("Message-ID")
+	if id == "" {
+		s.respond(m, 400, "Bad Request")
+		return
+	}
+	start, _, total, err := parseByteRange(m.Get("Byte-Range"))
+	if err != nil {
+		s.respond(m, 400, "Bad Request")
+		return
+	}
+	if len(m.Body) != 0 {
+		ct, _, _ := strings.Cut(m.Get("Content-Type"), ";")
+		if !strings.EqualFold(strings.TrimSpace(ct), msrpContentType) {
+			s.respond(m, 415, "Unsupported Media Type")
+			return
+		}
+	}
+	if total > msrpMaxMessage || start-1+len(m.Body) > msrpMaxMessage {
+		s.respond(m, 413, "Message Too Large")
+		return
+	}
+	s.respond(m, 200, "OK")
+
+	s.mu.Lock()
+	buf, ok := s.pending[id]
+	if !ok && len(s.pending) >= msrpMaxPending {
+		s.mu.Unlock()
+		s.log.Infow("too many pending msrp messages, dropping", "messageID", id)
+		return
+	}
+	if need := start - 1 + len(m.Body); need > len(buf) {
+		buf = append(buf, make([]byte, need-len(buf))...)
+	}
+	copy(buf[start-1:], m.Body)
+	done := m.Flag != '+'
+	if done {
+		delete(s.pending, id)
+	} else {
+		s.pending[id] = buf
+	}
+	s.mu.Unlock()
+	if !done || m.Flag == '#' {
+		return
+	}
+	if m.Get("Success-Report") == "yes" {
+		s.report(m, id, len(buf))
+	}
+	if len(buf) != 0 && s.onText != nil {
+		s.onText(string(buf))
+	}
+}
+
+// respond sends a transaction response, unless the sender does not want them.
+func (s *msrpSession) respond(req *msrpMessage, status int, phrase string) {
+	switch req.Get("Failure-Report") {
+	case "no":
+		return
+	case "partial":
+		if status == 200 {
+			return
+		}
+	}
+	fromPath, _, _ := strings.Cut(req.Get("From-Path"), " ")
+	resp := &msrpMessage{TID: req.TID, Status: status, Phrase: phrase}
+	resp.Add("To-Path", fromPath)
+	resp.Add("From-Path", s.localPath)
+	s.write(resp)
+}
+
+// report sends a success report for a completely received message.
+func (s *msrpSession) report(req *msrpMessage, id string, size int) {
+	rep := &msrpMessage{TID: newMSRPTransactionID(), Method: "REPORT"}
+	rep.Add("To-Path", req.Get("From-Path"))
+	rep.Add("From-Path", s.localPath)
+	rep.Add("Message-ID", id)
+	rep.Add("Byte-Range", fmt.Sprintf("1-%d/%d", size, size))
+	rep.Add("Status", "000 200 OK")
+	s.write(rep)
+}
+
+// SendText sends a text message to SIP, split into chunks if necessary.
+func (s *msrpSession) SendText(text string) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	connected := s.conn != nil
+	s.mu.Unlock()
+	if !connected {
+		return errors.New("msrp is not connected")
+	}
+	data := []byte(text)
+	id := guid.New("MSG_")
+	for off := 0; off < len(data); off += msrpMaxChunk {
+		end := min(off+msrpMaxChunk, len(data))
+		m := &msrpMessage{TID: newMSRPTransactionID(), Method: "SEND", Body: data[off:end], Flag: '$'}
+		if end < len(data) {
+			m.Flag = '+'
+		}
+		m.Add("To-Path", s.remotePath)
+		m.Add("From-Path", s.localPath)
+		m.Add("Message-ID", id)
+		m.Add("Byte-Range", fmt.Sprintf("%d-%d/%d", off+1, end, len(data)))
+		m.Add("Content-Type", msrpContentType)
+		if err := s.write(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *msrpSession) write(m *msrpMessage) error {
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+	if conn == nil {
+		return errors.New("msrp is not connected")
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(msrpWriteTimeout))
+	_, err := conn.Write(m.Marshal())
+	if err != nil {
+		s.log.Infow("cannot send msrp message", "error", err)
+	}
+	return err
+}
+
+func (s *msrpSession) Close() {
+	if s == nil {
+		return
+	}
+	s.closed.Break()
+	_ = s.ln.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+}
+
+// msrpRemoteIP returns the address the remote connects from: the host of the remote path, if it's an IP,
+// or the connection address from SDP. It returns an invalid address if neither is known.
+func msrpRemoteIP(offer *psdp.SessionDescription, m *psdp.MediaDescription, remotePath string) netip.Addr {
+	fields := strings.Fields(remotePath)
+	if len(fields) != 0 {
+		// Only the first hop of the path connects to us.
+		if u, err := url.Parse(fields[0]); err == nil {
+			if ip, err := netip.ParseAddr(u.Hostname()); err == nil {
+				return ip.Unmap()
+			}
+		}
+	}
+	conn := m.ConnectionInformation
+	if conn == nil {
+		conn = offer.ConnectionInformation
+	}
+	if conn != nil && conn.Address != nil {
+		if ip, err := netip.ParseAddr(conn.Address.Address); err == nil {
+			return ip.Unmap()
+		}
+	}
+	return netip.Addr{}
+}
+
+// setupMSRP answers the MSRP media line of the offer, if there is one, and relays text messages between SIP and the room.
+func (c *inboundCall) setupMSRP(offerData []byte, answer *psdp.SessionDescription, ports rtcconfig.PortRange) {
+	offer := new(psdp.SessionDescription)
+	if err := offer.Unmarshal(offerData); err != nil {
+		return // already validated by the media port
+	}
+	idx := findMSRPMedia(offer)
+	if idx < 0 {
+		return
+	}
+	m := offer.MediaDescriptions[idx]
+	remotePath, _ := m.Attribute("path")
+	if remotePath == "" || !msrpAcceptsText(m) {
+		c.log.Infow("rejecting msrp media without text support", "path", remotePath)
+		answerExtraMedia(answer, offer, idx, nil)
+		return
+	}
+	s, err := newMSRPSession(c.log, c.s.sconf.mediaIP(), ports, remotePath, msrpRemoteIP(offer, m, remotePath), func(text string) {
+		if err := c.lkRoom.SendChat(text); err != nil {
+			c.log.Infow("cannot send chat message to the room", "error", err)
+		}
+	})
+	if err != nil {
+		c.log.Warnw("cannot listen for msrp", err)
+		answerExtraMedia(answer, offer, idx, nil)
+		return
+	}
+	answerExtraMedia(answer, offer, idx, s.Media())
+	c.msrp = s
+	c.lkRoom.OnChat(c.sendChatToSIP)
+	s.Start()
+	c.log.Infow("msrp chat accepted", "localPath", s.localPath, "remotePath", remotePath)
+}
+
+// sendChatToSIP relays a chat message from the room to all text sessions negotiated with SIP.
+func (c *inboundCall) sendChatToSIP(from, text string) {
+	if err := c.msrp.SendText(text); err != nil {
+		c.log.Infow("cannot send chat message to sip", "error", err, "from", from)
+	}
+	if err := c.rtt.SendText(text + string(t140LineSeparator)); err != nil {
+		c.log.Infow("cannot send real-time text to sip", "error", err, "from", from)
+	}
+}
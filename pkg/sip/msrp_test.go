@@ -0,0 +1,218 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	psdp "github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	"github.com/livekit/protocol/logger"
+)
+
+func TestMSRPMessage(t *testing.T) {
+	cases := []*msrpMessage{
+		{TID: "a1", Method: "SEND", Header: []msrpHeader{
+			{Name: "To-Path", Value: "msrp://127.0.0.1:1/a;tcp"},
+			{Name: "Content-Type", Value: "text/plain"},
+		}, Body: []byte("hello\r\nworld"), Flag: '+'},
+		{TID: "b2", Method: "SEND", Header: []msrpHeader{{Name: "Message-ID", Value: "m"}}, Flag: '$'},
+		{TID: "c3", Status: 200, Phrase: "OK", Header: []msrpHeader{{Name: "To-Path", Value: "x"}}, Flag: '$'},
+	}
+	var buf bytes.Buffer
+	for _, m := range cases {
+		buf.Write(m.Marshal())
+	}
+	r := bufio.NewReader(&buf)
+	for _, exp := range cases {
+		got, err := readMSRPMessage(r)
+		require.NoError(t, err)
+		require.Equal(t, exp, got)
+	}
+
+	// Header lines must fit into the reader buffer.
+	long := &msrpMessage{TID: "d4", Method: "SEND", Header: []msrpHeader{{Name: "To-Path", Value: strings.Repeat("x", 100)}}, Flag: '$'}
+	_, err := readMSRPMessage(bufio.NewReaderSize(bytes.NewReader(long.Marshal()), 64))
+	require.ErrorIs(t, err, errMSRPTooLarge)
+}
+
+func TestParseByteRange(t *testing.T) {
+	cases := []struct {
+		v                 string
+		start, end, total int
+		err               bool
+	}{
+		{v: "", start: 1, end: -1, total: -1},
+		{v: "1-10/10", start: 1, end: 10, total: 10},
+		{v: "11-*/*", start: 11, end: -1, total: -1},
+		{v: "0-1/1", err: true},
+		{v: "1-2", err: true},
+	}
+	for _, c := range cases {
+		t.Run(c.v, func(t *testing.T) {
+			start, end, total, err := parseByteRange(c.v)
+			if c.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, []int{c.start, c.end, c.total}, []int{start, end, total})
+		})
+	}
+}
+
+func TestAnswerExtraMedia(t *testing.T) {
+	media := func(typ string) *psdp.MediaDescription {
+		return &psdp.MediaDescription{MediaName: psdp.MediaName{Media: typ, Port: psdp.RangedPort{Value: 1000}}}
+	}
+	offer := &psdp.SessionDescription{MediaDescriptions: []*psdp.MediaDescription{
+		media("message"), media("video"), media("audio"),
+	}}
+	audio := media("audio")
+	msrp := media("message")
+
+	answer := &psdp.SessionDescription{MediaDescriptions: []*psdp.MediaDescription{audio}}
+	answerExtraMedia(answer, offer, 0, msrp)
+	require.Len(t, answer.MediaDescriptions, 3)
+	require.Same(t, msrp, answer.MediaDescriptions[0])
+	require.Equal(t, 0, answer.MediaDescriptions[1].MediaName.Port.Value)
+	require.Same(t, audio, answer.MediaDescriptions[2])
+
+	answer = &psdp.SessionDescription{MediaDescriptions: []*psdp.MediaDescription{audio}}
+	answerExtraMedia(answer, offer, 0, nil)
+	require.Len(t, answer.MediaDescriptions, 3)
+	require.Equal(t, "message", answer.MediaDescriptions[0].MediaName.Media)
+	require.Equal(t, 0, answer.MediaDescriptions[0].MediaName.Port.Value)
+	require.Same(t, audio, answer.MediaDescriptions[2])
+}
+
+// msrpTestAddr returns the loopback address of the session listener, which listens on all interfaces.
+func msrpTestAddr(s *msrpSession) string {
+	return netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), uint16(s.ln.Addr().(*net.TCPAddr).Port)).String()
+}
+
+func TestMSRPSession(t *testing.T) {
+	const remotePath = "msrp://127.0.0.1:2855/remote;tcp"
+	texts := make(chan string, 1)
+	s, err := newMSRPSession(logger.GetLogger(), netip.MustParseAddr("127.0.0.1"), rtcconfig.PortRange{}, remotePath, netip.MustParseAddr("127.0.0.1"), func(text string) {
+		texts <- text
+	})
+	require.NoError(t, err)
+	defer s.Close()
+	m := s.Media()
+	path, _ := m.Attribute("path")
+	require.Equal(t, s.localPath, path)
+	s.Start()
+
+	conn, err := net.Dial("tcp", msrpTestAddr(s))
+	require.NoError(t, err)
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	send := func(tid string, body string, rng string, flag byte) {
+		req := &msrpMessage{TID: tid, Method: "SEND", Body: []byte(body), Flag: flag}
+		req.Add("To-Path", s.localPath)
+		req.Add("From-Path", remotePath)
+		req.Add("Message-ID", "msg1")
+		req.Add("Byte-Range", rng)
+		req.Add("Success-Report", "yes")
+		req.Add("Content-Type", "text/plain")
+		_, err := conn.Write(req.Marshal())
+		require.NoError(t, err)
+	}
+	send("t1", "hello ", "1-6/11", '+')
+	send("t2", "world", "7-11/11", '$')
+
+	for _, tid := range []string{"t1", "t2"} {
+		resp, err := readMSRPMessage(r)
+		require.NoError(t, err)
+		require.Equal(t, tid, resp.TID)
+		require.Equal(t, 200, resp.Status)
+	}
+	rep, err := readMSRPMessage(r)
+	require.NoError(t, err)
+	require.Equal(t, "REPORT", rep.Method)
+	require.Equal(t, "msg1", rep.Get("Message-ID"))
+	require.Equal(t, "1-11/11", rep.Get("Byte-Range"))
+	require.Equal(t, "hello world", <-texts)
+
+	text := strings.Repeat("x", msrpMaxChunk+10)
+	require.NoError(t, s.SendText(text))
+	var got []byte
+	for i := 0; i < 2; i++ {
+		m, err := readMSRPMessage(r)
+		require.NoError(t, err)
+		require.Equal(t, "SEND", m.Method)
+		require.Equal(t, remotePath, m.Get("To-Path"))
+		got = append(got, m.Body...)
+		if i == 0 {
+			require.Equal(t, byte('+'), m.Flag)
+		} else {
+			require.Equal(t, byte('$'), m.Flag)
+		}
+	}
+	require.Equal(t, text, string(got))
+}
+
+func TestMSRPSessionAccept(t *testing.T) {
+	const remotePath = "msrp://127.0.0.2:2855/remote;tcp"
+	s, err := newMSRPSession(logger.GetLogger(), netip.MustParseAddr("127.0.0.1"), rtcconfig.PortRange{}, remotePath, netip.MustParseAddr("127.0.0.2"), nil)
+	require.NoError(t, err)
+	defer s.Close()
+	s.Start()
+
+	dial := func(t *testing.T, from, toPath string) *bufio.Reader {
+		d := net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP(from)}}
+		conn, err := d.Dial("tcp", msrpTestAddr(s))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		req := &msrpMessage{TID: "t1", Method: "SEND", Flag: '$'}
+		req.Add("To-Path", toPath)
+		req.Add("From-Path", remotePath)
+		req.Add("Message-ID", "msg1")
+		_, _ = conn.Write(req.Marshal())
+		return bufio.NewReader(conn)
+	}
+	// Connections from other addresses and for other sessions are closed.
+	_, err = readMSRPMessage(dial(t, "127.0.0.1", s.localPath))
+	require.Error(t, err)
+	_, err = readMSRPMessage(dial(t, "127.0.0.2", "msrp://127.0.0.1:1/other;tcp"))
+	require.Error(t, err)
+
+	resp, err := readMSRPMessage(dial(t, "127.0.0.2", s.localPath))
+	require.NoError(t, err)
+	require.Equal(t, "t1", resp.TID)
+	require.Equal(t, 200, resp.Status)
+}
+
+func TestMSRPRemoteIP(t *testing.T) {
+	m := &psdp.MediaDescription{}
+	offer := &psdp.SessionDescription{ConnectionInformation: &psdp.ConnectionInformation{
+		NetworkType: "IN", AddressType: "IP4", Address: &psdp.Address{Address: "192.0.2.2"},
+	}}
+	require.Equal(t, netip.MustParseAddr("192.0.2.1"), msrpRemoteIP(offer, m, "msrp://192.0.2.1:2855/a;tcp"))
+	require.Equal(t, netip.MustParseAddr("192.0.2.2"), msrpRemoteIP(offer, m, "msrp://example.com:2855/a;tcp"))
+	require.False(t, msrpRemoteIP(&psdp.SessionDescription{}, m, "msrp://example.com:2855/a;tcp").IsValid())
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+
+	"github.com/livekit/sipgo/sip"
+)
+
+// maxBodyParts limits the number of parts accepted in a multipart body, including nested ones.
+const maxBodyParts = 16
+
+// BodyPart is a part of a multipart SIP message body (RFC 5621), other than SDP.
+// For example, ISUP (application/isup) or PIDF-LO location (application/pidf+xml).
+type BodyPart struct {
+	ContentType string // media type without parameters, in lower case
+	Params      map[string]string
+	ContentID   string
+	Disposition string
+	Body        []byte
+}
+
+// splitBody extracts the SDP from a message body. Multipart bodies are searched for the SDP part,
+// and the other parts are returned separately. Any other body is assumed to be SDP.
+func splitBody(contentType string, body []byte) ([]byte, []BodyPart, error) {
+	if len(body) == 0 {
+		return nil, nil, nil
+	}
+	mt, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mt, "multipart/") {
+		return body, nil, nil
+	}
+	var (
+		sdpBody []byte
+		parts   []BodyPart
+	)
+	if err = readParts(body, params["boundary"], 0, &sdpBody, &parts); err != nil {
+		return nil, nil, err
+	}
+	return sdpBody, parts, nil
+}
+
+func readParts(body []byte, boundary string, depth int, sdpBody *[]byte, parts *[]BodyPart) error {
+	if boundary == "" {
+		return errors.New("multipart body without boundary")
+	}
+	if depth > 1 {
+		return errors.New("multipart body is nested too deep")
+	}
+	r := multipart.NewReader(bytes.NewReader(body), boundary)
+	for n := 0; ; n++ {
+		p, err := r.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("invalid multipart body: %w", err)
+		}
+		if n >= maxBodyParts || len(*parts) >= maxBodyParts {
+			return errors.New("too many parts in multipart body")
+		}
+		data, err := io.ReadAll(p)
+		if err != nil {
+			return fmt.Errorf("invalid multipart body: %w", err)
+		}
+		ct := p.Header.Get("Content-Type")
+		if ct == "" {
+			ct = "text/plain" // default for MIME parts
+		}
+		mt, params, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return fmt.Errorf("invalid content type of a body part: %w", err)
+		}
+		switch {
+		case strings.HasPrefix(mt, "multipart/"):
+			if err := readParts(data, params["boundary"], depth+1, sdpBody, parts); err != nil {
+				return err
+			}
+		case mt == "application/sdp" && *sdpBody == nil:
+			*sdpBody = data
+		default:
+			*parts = append(*parts, BodyPart{
+				ContentType: mt,
+				Params:      params,
+				ContentID:   strings.Trim(p.Header.Get("Content-ID"), "<>"),
+				Disposition: p.Header.Get("Content-Disposition"),
+				Body:        data,
+			})
+		}
+	}
+}
+
+// bodyContentType returns the Content-Type header value of a message.
+func bodyContentType(m interface{ GetHeader(string) sip.Header }) string {
+	if h := m.GetHeader("Content-Type"); h != nil {
+		return h.Value()
+	}
+	return ""
+}
+
+// requestSDP returns the SDP of a request and other parts of its body, if it's multipart.
+func requestSDP(req *sip.Request) ([]byte, []BodyPart, error) {
+	return splitBody(bodyContentType(req), req.Body())
+}
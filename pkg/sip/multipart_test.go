@@ -0,0 +1,108 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const testSDP = "v=0\r\no=- 1 1 IN IP4 1.1.1.1\r\ns=-\r\nc=IN IP4 1.1.1.1\r\nt=0 0\r\nm=audio 5000 RTP/AVP 0\r\n"
+
+func TestSplitBody(t *testing.T) {
+	body, parts, err := splitBody("application/sdp", []byte(testSDP))
+	require.NoError(t, err)
+	require.Equal(t, testSDP, string(body))
+	require.Empty(t, parts)
+
+	body, parts, err = splitBody("", nil)
+	require.NoError(t, err)
+	require.Nil(t, body)
+	require.Empty(t, parts)
+
+	isup := []byte{0x01, 0x00, 0x49, 0x00, 0x00, 0x03, 0x02, 0x00, 0x07}
+	mixed := strings.Join([]string{
+		"--unique-boundary-1",
+		"Content-Type: application/SDP",
+		"",
+		strings.TrimSuffix(testSDP, "\r\n"),
+		"--unique-boundary-1",
+		"Content-Type: application/ISUP; version=itu-t92+",
+		"Content-Disposition: signal; handling=optional",
+		"",
+		string(isup),
+		"--unique-boundary-1--",
+		"",
+	}, "\r\n")
+	body, parts, err = splitBody(`multipart/mixed; boundary="unique-boundary-1"`, []byte(mixed))
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimSuffix(testSDP, "\r\n"), string(body))
+	require.Equal(t, []BodyPart{{
+		ContentType: "application/isup",
+		Params:      map[string]string{"version": "itu-t92+"},
+		Disposition: "signal; handling=optional",
+		Body:        isup,
+	}}, parts)
+
+	// Location sent by value, in a nested multipart.
+	loc := newCallLocation(&config.LocationConfig{PIDF: testPIDF}, "example.com")
+	inner, ctype := loc.Body([]byte(testSDP))
+	outer := "--outer\r\nContent-Type: " + ctype + "\r\n\r\n" + string(inner) + "\r\n--outer--\r\n"
+	body, parts, err = splitBody("multipart/related;boundary=outer", []byte(outer))
+	require.NoError(t, err)
+	require.Equal(t, testSDP, string(body))
+	require.Len(t, parts, 1)
+	require.Equal(t, contentTypePIDF, parts[0].ContentType)
+	require.Equal(t, loc.cid, parts[0].ContentID)
+	require.Equal(t, testPIDF, string(parts[0].Body))
+
+	// No SDP in the body.
+	body, parts, err = splitBody("multipart/mixed;boundary=b", []byte("--b\r\nContent-Type: application/pidf+xml\r\n\r\n<presence/>\r\n--b--\r\n"))
+	require.NoError(t, err)
+	require.Nil(t, body)
+	require.Len(t, parts, 1)
+
+	_, _, err = splitBody("multipart/mixed", []byte(mixed))
+	require.Error(t, err, "no boundary")
+	_, _, err = splitBody("multipart/mixed;boundary=b", []byte("--b\r\nContent-Type: application/sdp\r\n\r\nv=0"))
+	require.Error(t, err, "truncated")
+}
+
+func TestRequestSDP(t *testing.T) {
+	loc := newCallLocation(&config.LocationConfig{PIDF: testPIDF}, "example.com")
+	body, ctype := loc.Body([]byte(testSDP))
+	req := sip.NewRequest(sip.INVITE, sip.Uri{User: "callee", Host: "example.com"})
+	req.AppendHeader(sip.NewHeader("Content-Type", ctype))
+	req.SetBody(body)
+
+	offer, parts, err := requestSDP(req)
+	require.NoError(t, err)
+	require.Equal(t, testSDP, string(offer))
+	require.Len(t, parts, 1)
+
+	// Bodies without a content type are assumed to be SDP.
+	req = sip.NewRequest(sip.INVITE, sip.Uri{User: "callee", Host: "example.com"})
+	req.SetBody([]byte(testSDP))
+	offer, parts, err = requestSDP(req)
+	require.NoError(t, err)
+	require.Equal(t, testSDP, string(offer))
+	require.Empty(t, parts)
+}
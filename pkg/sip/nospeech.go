@@ -0,0 +1,45 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"time"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// watchNoSpeech ends or transfers the call if the caller doesn't speak within the timeout after answer.
+func (c *inboundCall) watchNoSpeech(conf *config.NoSpeechConfig) {
+	t := time.NewTimer(conf.Timeout)
+	defer t.Stop()
+	select {
+	case <-c.ctx.Done():
+		return
+	case <-c.vad.Detected():
+		c.log.Debugw("caller speech detected")
+		return
+	case <-t.C:
+	}
+	if conf.Action == config.NoSpeechTransfer {
+		c.log.Infow("no speech detected, transferring the call", "timeout", conf.Timeout, "transferTo", conf.TransferTo)
+		if err := c.transferCall(c.ctx, conf.TransferTo, nil, false); err == nil {
+			return
+		}
+		// Hang up anyway, the call is not useful.
+	} else {
+		c.log.Infow("no speech detected, hanging up", "timeout", conf.Timeout)
+	}
+	c.close(false, CallHangup, "no-speech")
+}
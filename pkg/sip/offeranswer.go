@@ -0,0 +1,132 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+
+	"github.com/livekit/media-sdk/sdp"
+	"github.com/livekit/sipgo/sip"
+	psdp "github.com/pion/sdp/v3"
+)
+
+// Offer/answer violations reported by sdpNegotiation.
+const (
+	// sdpViolationProvisionalChanged is reported when unreliable provisional responses carry different answers.
+	sdpViolationProvisionalChanged = "provisional-changed"
+	// sdpViolationFinalChanged is reported when 200 OK carries an answer different from the provisional one.
+	sdpViolationFinalChanged = "final-changed"
+	// sdpViolationNoFinalAnswer is reported when 200 OK has no SDP, but a provisional response had it.
+	sdpViolationNoFinalAnswer = "no-final-answer"
+	// sdpViolationForked is reported when a provisional answer comes from a different dialog than 200 OK.
+	sdpViolationForked = "forked"
+)
+
+var errNoSDPAnswer = errors.New("no SDP answer in INVITE response")
+
+// sdpNegotiation tracks offer/answer state for an INVITE transaction where the offer was sent in INVITE
+// (RFC 3261, section 13.2.1, and RFC 6337).
+//
+// The answer may arrive in an unreliable provisional response (usually 183) and must be repeated in 200 OK.
+// Provisional answers are tracked per early dialog (To tag), so that answers from other forks are never used.
+// We don't advertise UPDATE or 100rel, so the answer can't change before 200 OK in a compliant UAS.
+type sdpNegotiation struct {
+	provisional map[string][]byte // by To tag
+	violations  []string
+}
+
+// OnResponse records SDP from a response to INVITE.
+func (n *sdpNegotiation) OnResponse(resp *sip.Response) {
+	if resp.StatusCode/100 != 1 {
+		return
+	}
+	body := resp.Body()
+	if len(body) == 0 {
+		return
+	}
+	tag := responseToTag(resp)
+	if n.provisional == nil {
+		n.provisional = make(map[string][]byte)
+	}
+	if prev, ok := n.provisional[tag]; ok && !sameSDPAnswer(prev, body) {
+		n.violations = append(n.violations, sdpViolationProvisionalChanged)
+	}
+	n.provisional[tag] = body
+}
+
+// Answer returns the final SDP answer for a 200 OK response, following answer precedence rules:
+// SDP from 200 OK always wins; provisional answer from the same dialog is only used if 200 OK has none.
+func (n *sdpNegotiation) Answer(resp *sip.Response) ([]byte, error) {
+	tag := responseToTag(resp)
+	prov, hasProv := n.provisional[tag]
+	for t := range n.provisional {
+		if t != tag {
+			n.violations = append(n.violations, sdpViolationForked)
+			break
+		}
+	}
+	if body := resp.Body(); len(body) != 0 {
+		if hasProv && !sameSDPAnswer(prov, body) {
+			n.violations = append(n.violations, sdpViolationFinalChanged)
+		}
+		return body, nil
+	}
+	if hasProv {
+		n.violations = append(n.violations, sdpViolationNoFinalAnswer)
+		return prov, nil
+	}
+	return nil, errNoSDPAnswer
+}
+
+// Violations returns offer/answer state violations seen so far.
+func (n *sdpNegotiation) Violations() []string {
+	return n.violations
+}
+
+func responseToTag(resp *sip.Response) string {
+	if to := resp.To(); to != nil {
+		tag, _ := getTagFrom(to.Params)
+		return string(tag)
+	}
+	return ""
+}
+
+// sameSDPAnswer checks if two SDP answers describe the same session: same origin version and the same remote audio address.
+func sameSDPAnswer(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	oa, addrA, errA := sdpAnswerIdentity(a)
+	ob, addrB, errB := sdpAnswerIdentity(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return oa.SessionID == ob.SessionID && oa.SessionVersion == ob.SessionVersion && addrA == addrB
+}
+
+func sdpAnswerIdentity(data []byte) (psdp.Origin, netip.AddrPort, error) {
+	var s psdp.SessionDescription
+	if err := s.Unmarshal(data); err != nil {
+		return psdp.Origin{}, netip.AddrPort{}, err
+	}
+	audio := sdp.GetAudio(&s)
+	if audio == nil {
+		return s.Origin, netip.AddrPort{}, nil
+	}
+	addr, err := sdp.GetAudioDest(&s, audio)
+	return s.Origin, addr, err
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/livekit/media-sdk/sdp"
+	"github.com/livekit/sipgo/sip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSDPNegotiation(t *testing.T) {
+	req := sip.NewRequest(sip.INVITE, sip.Uri{User: "bob", Host: "example.com"})
+	req.AppendHeader(&sip.ToHeader{Address: sip.Uri{User: "bob", Host: "example.com"}, Params: sip.NewParams()})
+	resp := func(code sip.StatusCode, tag string, body []byte) *sip.Response {
+		r := sip.NewResponseFromRequest(req, code, "", body)
+		r.To().Params = sip.NewParams()
+		r.To().Params.Add("tag", tag)
+		return r
+	}
+	answer := func(port int) []byte {
+		a, err := sdp.NewOffer(netip.MustParseAddr("10.0.0.1"), port, sdp.EncryptionNone)
+		require.NoError(t, err)
+		data, err := a.SDP.Marshal()
+		require.NoError(t, err)
+		return data
+	}
+	a, b := answer(10000), answer(20000)
+
+	cases := []struct {
+		name       string
+		resp       []*sip.Response
+		exp        []byte
+		err        bool
+		violations []string
+	}{
+		{
+			name: "final only",
+			resp: []*sip.Response{resp(180, "x", nil), resp(200, "x", a)},
+			exp:  a,
+		},
+		{
+			name: "same answer",
+			resp: []*sip.Response{resp(183, "x", a), resp(183, "x", a), resp(200, "x", a)},
+			exp:  a,
+		},
+		{
+			name:       "provisional only",
+			resp:       []*sip.Response{resp(183, "x", a), resp(200, "x", nil)},
+			exp:        a,
+			violations: []string{sdpViolationNoFinalAnswer},
+		},
+		{
+			name:       "final changed",
+			resp:       []*sip.Response{resp(183, "x", a), resp(200, "x", b)},
+			exp:        b,
+			violations: []string{sdpViolationFinalChanged},
+		},
+		{
+			name:       "provisional changed",
+			resp:       []*sip.Response{resp(183, "x", a), resp(183, "x", b), resp(200, "x", b)},
+			exp:        b,
+			violations: []string{sdpViolationProvisionalChanged},
+		},
+		{
+			name:       "forked",
+			resp:       []*sip.Response{resp(183, "x", a), resp(200, "y", nil)},
+			err:        true,
+			violations: []string{sdpViolationForked},
+		},
+		{
+			name: "no answer",
+			resp: []*sip.Response{resp(200, "x", nil)},
+			err:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var n sdpNegotiation
+			for _, r := range c.resp {
+				n.OnResponse(r)
+			}
+			got, err := n.Answer(c.resp[len(c.resp)-1])
+			if c.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, c.exp, got)
+			}
+			require.Equal(t, c.violations, n.Violations())
+		})
+	}
+}
@@ -17,21 +17,18 @@ package sip
 import (
 	"context"
 	"fmt"
-	"math"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/frostbyte73/core"
-	"github.com/icholy/digest"
 	"github.com/pkg/errors"
 	"golang.org/x/exp/maps"
 
 	msdk "github.com/livekit/media-sdk"
 
-	"github.com/livekit/media-sdk/dtmf"
 	"github.com/livekit/media-sdk/sdp"
-	"github.com/livekit/media-sdk/tones"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/tracer"
@@ -39,12 +36,16 @@ import (
 	"github.com/livekit/psrpc"
 	lksdk "github.com/livekit/server-sdk-go/v2"
 	"github.com/livekit/sipgo/sip"
+	"github.com/livekit/sipgo/transaction"
 
 	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/media/tonegen"
 	"github.com/livekit/sip/pkg/stats"
+	"github.com/livekit/sip/pkg/stt"
 )
 
 type sipOutboundConfig struct {
+	trunkID         string
 	address         string
 	transport       livekit.SIPTransport
 	host            string
@@ -62,6 +63,10 @@ type sipOutboundConfig struct {
 	maxCallDuration time.Duration
 	enabledFeatures []livekit.SIPFeature
 	mediaEncryption sdp.Encryption
+	callerID        *callerIDPolicy
+	location        *callLocation // caller location sent with the INVITE, if any
+	wsUrl           string
+	prewarm         bool // subscribe to the room while ringing, see AttrSIPPrewarm
 }
 
 type outboundCall struct {
@@ -73,9 +78,23 @@ type outboundCall struct {
 	started   core.Fuse
 	stopped   core.Fuse
 	closing   core.Fuse
+	ctx       context.Context // cancelled when the call is closed
+	cancel    func()
 	stats     Stats
 	jitterBuf bool
 	projectID string
+	quota     *ProjectLease
+	capacity  *TrunkLease
+	res       *callResources // nil if leak watchdog is disabled
+	mem       *callMemory    // nil if memory budget is disabled
+	reinvite  atomic.Bool
+	recovery  atomic.Bool       // set once a re-INVITE was sent to recover unreachable media
+	talkAttrs map[string]string // protected by state lock
+	menu      *dtmfMenu
+	speaker   *callSpeaker
+	attrUpd   *attrUpdater   // set if attribute changes are sent to the callee
+	migrated  atomic.Bool    // set while the call is handed off to another instance
+	recSync   *recordingSync // set if call legs are published in room metadata
 
 	mu       sync.RWMutex
 	mon      *stats.CallMonitor
@@ -106,8 +125,14 @@ func (c *Client) newCall(ctx context.Context, conf *config.Config, log logger.Lo
 		state:     state,
 		jitterBuf: jitterBuf,
 		projectID: projectID,
+		menu:      newDTMFMenu(conf.DTMF),
+		res:       c.leaks.NewCall(state.callInfo.CallId),
+		mem:       c.mem.NewCall(),
 	}
 	call.log = call.log.WithValues("jitterBuf", call.jitterBuf)
+	call.ctx, call.cancel = context.WithCancel(context.Background())
+	// Created before media events are handled, since they update room attributes. Connected in connectToRoom.
+	call.lkRoom = NewRoom(call.log, &call.stats.Room)
 	call.cc = c.newOutbound(log, id, URI{
 		User:      sipConf.from,
 		Host:      sipConf.host,
@@ -124,29 +149,75 @@ func (c *Client) newCall(ctx context.Context, conf *config.Config, log logger.Lo
 		}
 		return AttrsToHeaders(r.LocalParticipant.Attributes(), c.sipConf.attrsToHeaders, headers)
 	})
+	call.cc.location = sipConf.location
+	call.cc.caps = c.capabilities(sipConf.trunkID)
+	call.cc.timers = c.timers(sipConf.trunkID)
 
 	call.mon = c.mon.NewCall(stats.Outbound, sipConf.host, sipConf.address)
+	call.mon.SetTrunk(sipConf.trunkID)
+	call.mon.SetTags(CallTags(room.Participant.Attributes))
+	call.cc.fsm.SetMonitor(call.mon)
+	call.cc.fsm.OnChange(call.onDialogState)
 	var err error
 
-	call.media, err = NewMediaPort(call.log, call.mon, &MediaOptions{
-		IP:                  c.sconf.MediaIP,
-		Ports:               conf.RTPPort,
-		MediaTimeoutInitial: c.conf.MediaTimeoutInitial,
-		MediaTimeout:        c.conf.MediaTimeout,
-		EnableJitterBuffer:  call.jitterBuf,
-		Stats:               &call.stats.Port,
-	}, RoomSampleRate)
+	srtpConf := c.conf.TrunkSRTP(sipConf.trunkID)
+	opts := &MediaOptions{
+		IP:                    c.sconf.mediaIP(),
+		Ports:                 conf.RTPPort,
+		MediaTimeoutInitial:   c.conf.MediaTimeoutInitial,
+		MediaTimeout:          c.conf.MediaTimeout,
+		MediaTimeoutWarnOnly:  c.conf.MediaTimeoutWarnOnly,
+		MediaTimeoutIgnoreCN:  c.conf.MediaTimeoutIgnoreCN,
+		MediaTimeoutRTCP:      c.conf.MediaTimeoutRTCP,
+		MediaTimeoutKeepAlive: c.conf.MediaTimeoutKeepAlive,
+		RTCPXRInterval:        c.conf.RTCPXRInterval,
+		OneWayTimeout:         c.conf.OneWayAudio.Timeout,
+		OneWayLatch:           c.conf.OneWayAudio.Latch,
+		StripZRTP:             c.conf.StripZRTP,
+		EnableDriftCorrection: c.conf.EnableDriftCorrection,
+		MaxInputStreams:       c.conf.MaxInputStreams,
+		StreamRate:            c.conf.MediaStreamRate,
+		Pacer:                 pacerConfig(c.conf.TrunkPacing(sipConf.trunkID)),
+		Silence:               c.conf.TrunkSilence(sipConf.trunkID),
+		RTPTransport:          c.conf.TrunkRTPTransport(sipConf.trunkID),
+		SDP:                   c.conf.TrunkSDP(sipConf.trunkID),
+		EnableJitterBuffer:    call.jitterBuf,
+		Stats:                 &call.stats.Port,
+		Allocator:             c.ports,
+		TrunkID:               sipConf.trunkID,
+		CallID:                state.callInfo.CallId,
+		resources:             call.res,
+		memory:                call.mem,
+		SRTPSuites:            srtpConf.Suites,
+		SRTPRejectDisallowed:  srtpConf.RejectDisallowed,
+		SRTPMaxFailures:       srtpConf.MaxFailures,
+		ICMPMaxErrors:         c.conf.MediaUnreachable.MaxErrors,
+	}
+	call.sipConf.mediaEncryption = applyEncryptionPolicy(c.conf.TrunkEncryption(sipConf.trunkID), sipConf.mediaEncryption, opts)
+	call.media, err = NewMediaPort(call.log, call.mon, opts, RoomSampleRate)
 	if err != nil {
 		call.close(errors.Wrap(err, "media failed"), callDropped, "media-failed", livekit.DisconnectReason_UNKNOWN_REASON)
 		return nil, err
 	}
+	call.media.OnEvent(call.onMediaEvent)
+	call.speaker = newCallSpeaker(call.log, c.tts, call.playAudio, call.media.DetectSpeech)
+	call.recSync = newRecordingSync(call.log, c.conf, recordingLeg{
+		CallID:    state.callInfo.CallId,
+		Direction: "outbound",
+		TrunkID:   sipConf.trunkID,
+	})
+	call.attrUpd = newAttrUpdater(call.log, c.conf.TrunkAttributeUpdates(sipConf.trunkID), sipConf.attrsToHeaders, func() map[string]string {
+		return call.lkRoom.LocalAttributes()
+	}, call.cc.SendHeaders)
 	call.media.SetDTMFAudio(conf.AudioDTMF)
-	call.media.EnableTimeout(false)
+	call.media.SetDTMFOptions(dtmfOptions(conf.DTMF))
+	call.media.followDialog(call.cc.fsm)
 	call.media.DisableOut() // disabled until we get 200
 	if err := call.connectToRoom(ctx, room); err != nil {
 		call.close(errors.Wrap(err, "room join failed"), callDropped, "join-failed", livekit.DisconnectReason_UNKNOWN_REASON)
 		return nil, fmt.Errorf("update room failed: %w", err)
 	}
+	call.applyCallerID()
 
 	c.cmu.Lock()
 	defer c.cmu.Unlock()
@@ -167,6 +238,7 @@ func (c *outboundCall) ensureClosed(ctx context.Context) {
 				info.ParticipantAttributes = p.Attributes()
 			}
 		}
+		setTalkStats(info, c.talkAttrs)
 		info.EndedAtNs = time.Now().UnixNano()
 	})
 }
@@ -187,6 +259,7 @@ func (c *outboundCall) setErrStatus(ctx context.Context, err error) {
 func (c *outboundCall) Dial(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, c.sipConf.maxCallDuration)
 	defer cancel()
+	c.cc.history.SetTimer(timerMaxCallDuration, time.Now().Add(c.sipConf.maxCallDuration))
 	c.mon.CallStart()
 	defer c.mon.CallEnd()
 
@@ -198,7 +271,9 @@ func (c *outboundCall) Dial(ctx context.Context) error {
 	}
 
 	c.state.Update(ctx, func(info *livekit.SIPCallInfo) {
-		info.RoomId = c.lkRoom.room.SID()
+		if room := c.lkRoom.Room(); room != nil {
+			info.RoomId = room.SID()
+		}
 		info.StartedAtNs = time.Now().UnixNano()
 		info.CallStatus = livekit.SIPCallStatus_SCS_ACTIVE
 	})
@@ -219,6 +294,10 @@ func (c *outboundCall) WaitClose(ctx context.Context) error {
 		case <-c.Disconnected():
 			c.CloseWithReason(callDropped, "removed", livekit.DisconnectReason_CLIENT_INITIATED)
 			return nil
+		case <-c.quota.Exhausted():
+			c.log.Infow("project call minutes exhausted")
+			c.CloseWithReason(CallHangup, "quota-exhausted", livekit.DisconnectReason_UNKNOWN_REASON)
+			return nil
 		case <-c.media.Timeout():
 			c.closeWithTimeout()
 			err := psrpc.NewErrorf(psrpc.DeadlineExceeded, "media timeout")
@@ -270,19 +349,33 @@ func (c *outboundCall) closeWithTimeout() {
 
 func (c *outboundCall) close(err error, status CallStatus, description string, reason livekit.DisconnectReason) {
 	c.stopped.Once(func() {
+		c.cancel()
+		migrated := c.migrated.Load()
+		if migrated {
+			// The other instance continues the dialog and the session.
+			status, description, reason = callMigrated, "migrated", livekit.DisconnectReason_MIGRATION
+			c.cc.Drop()
+		}
 		c.setStatus(status)
 		if err != nil {
 			c.log.Warnw("Closing outbound call with error", nil, "reason", description)
 		} else {
 			c.log.Infow("Closing outbound call", "reason", description)
 		}
+		talkAttrs := talkStatsAttrs(c.media.TalkStats())
 		c.state.Update(context.Background(), func(info *livekit.SIPCallInfo) {
 			if err != nil && info.Error == "" {
 				info.Error = err.Error()
 				info.CallStatus = livekit.SIPCallStatus_SCS_ERROR
 			}
 			info.DisconnectReason = reason
+			c.talkAttrs = talkAttrs
+			setTalkStats(info, talkAttrs)
 		})
+		c.c.vq.Report(c.log, c.media, c.cc, stats.Outbound)
+		c.speaker.Close()
+		c.attrUpd.Close()
+		c.recSync.Mark(recordingEnded)
 		c.media.Close()
 		_ = c.lkRoom.CloseOutput()
 
@@ -292,6 +385,8 @@ func (c *outboundCall) close(err error, status CallStatus, description string, r
 		c.stopSIP(description)
 
 		c.log.Infow("call statistics", "stats", c.stats.Load())
+		c.quota.Release()
+		c.capacity.Release()
 
 		c.c.cmu.Lock()
 		delete(c.c.activeCalls, c.cc.ID())
@@ -299,17 +394,22 @@ func (c *outboundCall) close(err error, status CallStatus, description string, r
 			delete(c.c.byRemote, tag)
 		}
 		c.c.cmu.Unlock()
+		c.cc.fsm.Transition(DialogDone)
 
 		c.c.DeregisterTransferSIPParticipant(string(c.cc.ID()))
 
 		// Call the handler asynchronously to avoid blocking
-		if c.c.handler != nil {
+		if c.c.handler != nil && !migrated {
 			go c.c.handler.OnSessionEnd(context.Background(), &CallIdentifier{
 				ProjectID: c.projectID,
 				CallID:    c.state.callInfo.CallId,
 				SipCallID: c.cc.CallID(),
 			}, c.state.callInfo, description)
 		}
+		c.mem.Close()
+		c.c.owners.Remove(c.cc.CallID())
+		c.c.failover.Delete(c.cc.CallID())
+		c.c.leaks.CallEnded(c.res)
 	})
 }
 
@@ -319,6 +419,33 @@ func (c *outboundCall) Participant() ParticipantInfo {
 	return c.lkRoom.Participant()
 }
 
+// failTone selects a call progress tone for the SIP status of a failed call.
+func failTone(e *livekit.SIPStatus) tonegen.Kind {
+	switch int(e.Code) {
+	case int(sip.StatusBusyHere), int(sip.StatusGlobalBusyEverywhere):
+		return tonegen.Busy
+	case int(sip.StatusNotFound), int(sip.StatusGone), int(sip.StatusAddressIncomplete), int(sip.StatusGlobalDoesNotExistAnywhere):
+		return tonegen.SIT
+	}
+	return tonegen.Reorder
+}
+
+// playFailTone plays a tone to the room when the call fails, so that participants hear why the call ended.
+// It is only played if the ringback is enabled for the call, since the room hears it in place of silence.
+func (c *outboundCall) playFailTone(ctx context.Context, k tonegen.Kind) {
+	dur := c.c.conf.Tones.FailDuration
+	if dur == 0 {
+		dur = config.DefaultToneFailDuration
+	}
+	if !c.sipConf.dialtone || dur < 0 || c.lkRoomIn == nil {
+		return
+	}
+	c.log.Infow("playing fail tone", "tone", k, "duration", dur)
+	if err := c.c.tones.PlayFor(ctx, c.lkRoomIn, k, dur); err != nil && !errors.Is(err, context.Canceled) {
+		c.log.Infow("cannot play fail tone", "error", err)
+	}
+}
+
 func (c *outboundCall) ConnectSIP(ctx context.Context) error {
 	ctx, span := tracer.Start(ctx, "outboundCall.ConnectSIP")
 	defer span.End()
@@ -339,17 +466,53 @@ func (c *outboundCall) ConnectSIP(ctx context.Context) error {
 				status, desc, reason = callRejected, "busy", livekit.DisconnectReason_USER_REJECTED
 				reportErr = nil
 			}
+			c.playFailTone(ctx, failTone(e))
 		}
 		c.close(reportErr, status, desc, reason)
 		return err
 	}
 	c.connectMedia()
 	c.started.Break()
+	go syncQualityAttr(c.stopped.Watch(), c.media, c.lkRoom)
 	c.lkRoom.Subscribe()
+	c.c.failover.Save(c.snapshot())
 	c.log.Infow("Outbound SIP call established")
 	return nil
 }
 
+// applyCallerID sets caller ID headers of the INVITE. It must be called after joining the room,
+// since the number may come from the room metadata.
+func (c *outboundCall) applyCallerID() {
+	meta := ""
+	if r := c.lkRoom.Room(); r != nil {
+		meta = r.Metadata()
+	}
+	id := c.sipConf.callerID.Select(c.log, c.sipConf.from, meta)
+	c.cc.setCallerID(id)
+	if id.Asserted != "" || id.Anonymous {
+		headers := make(map[string]string, len(c.sipConf.headers)+2)
+		// Headers set explicitly in the request take priority.
+		if id.Asserted != "" {
+			headers["P-Asserted-Identity"] = "<" + (&sip.Uri{User: id.Asserted, Host: c.sipConf.host}).String() + ">"
+		}
+		if id.Anonymous {
+			headers["Privacy"] = "id"
+		}
+		for k, v := range c.sipConf.headers {
+			headers[k] = v
+		}
+		c.sipConf.headers = headers
+	}
+	if id.User != c.sipConf.from {
+		c.log.Infow("selected caller id", "fromUser", id.User, "anonymous", id.Anonymous)
+		c.state.DeferUpdate(func(info *livekit.SIPCallInfo) {
+			if info.FromUri != nil {
+				info.FromUri.User = id.User
+			}
+		})
+	}
+}
+
 func (c *outboundCall) connectToRoom(ctx context.Context, lkNew RoomConfig) error {
 	ctx, span := tracer.Start(ctx, "outboundCall.connectToRoom")
 	defer span.End()
@@ -364,8 +527,23 @@ func (c *outboundCall) connectToRoom(ctx context.Context, lkNew RoomConfig) erro
 	}
 
 	attrs[livekit.AttrSIPCallStatus] = CallDialing.Attribute()
+	if st, _ := c.cc.fsm.State(); st != DialogDone {
+		attrs[AttrSIPDialogState] = st.String()
+	}
 	lkNew.Participant.Attributes = attrs
-	r := NewRoom(c.log, &c.stats.Room)
+	r := c.lkRoom
+	r.OnAttributesChanged((&attrRequests{
+		Hangup: func() {
+			c.log.Infow("hangup requested by participant attributes")
+			c.CloseWithReason(CallHangup, "requested-hangup", livekit.DisconnectReason_CLIENT_INITIATED)
+		},
+		Mute: func(muted bool) {
+			c.log.Infow("mute requested by participant attributes", "muted", muted)
+			c.media.SetMuted(muted)
+		},
+		Update: c.attrUpd.OnChanged,
+	}).OnChanged)
+	roomDur := c.mon.SetupPhaseDur(stats.SetupRoomJoin)
 	if err := r.Connect(c.c.conf, lkNew); err != nil {
 		return err
 	}
@@ -376,14 +554,14 @@ func (c *outboundCall) connectToRoom(ctx context.Context, lkNew RoomConfig) erro
 		_ = r.Close()
 		return err
 	}
-	c.lkRoom = r
+	roomDur()
 	c.lkRoomIn = local
+	c.recSync.Joined(r, lkNew.WsUrl)
 	return nil
 }
 
 func (c *outboundCall) dialSIP(ctx context.Context) error {
 	if c.sipConf.dialtone {
-		const ringVolume = math.MaxInt16 / 2
 		rctx, rcancel := context.WithCancel(ctx)
 		defer rcancel()
 
@@ -398,7 +576,7 @@ func (c *outboundCall) dialSIP(ctx context.Context) error {
 				c.log.Infow("room is not ready, ignoring dial tone")
 				return
 			}
-			err := tones.Play(rctx, dst, ringVolume, tones.ETSIRinging)
+			err := c.c.tones.Play(rctx, dst, tonegen.Ringing)
 			if err != nil && !errors.Is(err, context.Canceled) {
 				c.log.Infow("cannot play dial tone", "error", err)
 			}
@@ -415,8 +593,10 @@ func (c *outboundCall) dialSIP(ctx context.Context) error {
 		if err := c.media.WriteDTMF(ctx, digits); err != nil {
 			return err
 		}
+		c.log.Infow("initial dtmf sent", "digits", digits)
 	}
 	c.setStatus(CallActive)
+	go c.cc.keepAlive(c.stopped.Watch())
 
 	return nil
 }
@@ -433,7 +613,7 @@ func (c *outboundCall) connectMedia() {
 
 type sipRespFunc func(code sip.StatusCode, hdrs Headers)
 
-func sipResponse(ctx context.Context, tx sip.ClientTransaction, stop <-chan struct{}, setState sipRespFunc) (*sip.Response, error) {
+func sipResponse(ctx context.Context, tx sip.ClientTransaction, stop <-chan struct{}, onResp func(resp *sip.Response)) (*sip.Response, error) {
 	cnt := 0
 	for {
 		select {
@@ -444,11 +624,19 @@ func sipResponse(ctx context.Context, tx sip.ClientTransaction, stop <-chan stru
 			_ = tx.Cancel()
 			return nil, psrpc.NewErrorf(psrpc.Canceled, "canceled")
 		case <-tx.Done():
+			if err := tx.Err(); errors.Is(err, transaction.ErrTimeout) {
+				// Transaction timeouts are handled like a 408 response (RFC 3261, section 8.1.3.1).
+				return nil, fmt.Errorf("transaction timed out (%d intermediate responses): %w: %w", cnt,
+					&livekit.SIPStatus{Code: livekit.SIPStatusCode_SIP_STATUS_REQUEST_TIMEOUT}, err)
+			}
 			return nil, psrpc.NewErrorf(psrpc.Canceled, "transaction failed to complete (%d intermediate responses)", cnt)
 		case res := <-tx.Responses():
+			if r, ok := tx.(responseRecorder); ok {
+				r.recordResponse(res)
+			}
 			status := res.StatusCode
-			if setState != nil {
-				setState(res.StatusCode, res.Headers())
+			if onResp != nil {
+				onResp(res)
 			}
 			if status/100 != 1 { // != 1xx
 				return res, nil
@@ -464,6 +652,69 @@ func (c *outboundCall) stopSIP(reason string) {
 	c.cc.Close()
 }
 
+func (c *outboundCall) onMediaEvent(ev MediaEvent) {
+	if attrs := mediaStateAttrs(ev); attrs != nil {
+		c.lkRoom.SetAttributes(attrs)
+	}
+	switch ev.Type {
+	case MediaEventReceived:
+		c.recSync.Mark(recordingMedia)
+	case MediaEventKeyExpiring:
+		go c.rekey()
+	case MediaEventHold:
+		c.cc.fsm.Transition(DialogHeld)
+	case MediaEventResume:
+		c.cc.fsm.Transition(DialogAnswered)
+	case MediaEventSRTPFailures:
+		if c.c.conf.TrunkSRTP(c.sipConf.trunkID).FailureAction == config.SRTPFailureHangup {
+			c.log.Warnw("ending call due to invalid SRTP packets", nil)
+			// Called from the media read loop, which is stopped when the call is closed.
+			go c.CloseWithReason(callDropped, "srtp-failures", livekit.DisconnectReason_MEDIA_FAILURE)
+		}
+	case MediaEventOneWay:
+		if c.c.conf.OneWayAudio.ReInvite {
+			go c.refreshMedia()
+		}
+	case MediaEventUnreachable:
+		if c.c.conf.MediaUnreachable.Action != config.MediaUnreachableHangup && !c.recovery.Swap(true) {
+			go c.refreshMedia()
+			break
+		}
+		c.log.Warnw("ending call, media destination is unreachable", nil, "addr", ev.Addr.String())
+		// Called from the media loops, which are stopped when the call is closed.
+		go c.CloseWithReason(callDropped, "media-unreachable", livekit.DisconnectReason_MEDIA_FAILURE)
+	}
+}
+
+// onDialogState reports the dialog state of the call to the room.
+func (c *outboundCall) onDialogState(_, to DialogState) {
+	// Called with the call lock held when the call is closed.
+	c.lkRoom.SetAttributes(map[string]string{AttrSIPDialogState: to.String()})
+}
+
+// onRinging is called on the first provisional response from the callee.
+func (c *outboundCall) onRinging() {
+	c.setStatus(CallRinging)
+	if c.sipConf.prewarm {
+		// Room audio is decoded and mixed, but not sent to SIP until the call is answered.
+		// If the call fails, subscriptions are closed together with the room.
+		c.log.Infow("subscribing to the room while ringing")
+		c.lkRoom.Subscribe()
+	}
+}
+
+// requestPrewarm tells if AttrSIPPrewarm is set in the request, and returns attributes without it.
+// The attribute only controls the call, so it's not published to the room.
+func requestPrewarm(attrs map[string]string) (bool, map[string]string) {
+	v, ok := attrs[AttrSIPPrewarm]
+	if !ok {
+		return false, attrs
+	}
+	attrs = maps.Clone(attrs)
+	delete(attrs, AttrSIPPrewarm)
+	return v == "true", attrs
+}
+
 func (c *outboundCall) setStatus(v CallStatus) {
 	attr := v.Attribute()
 	if attr == "" {
@@ -494,10 +745,10 @@ func (c *outboundCall) sipSignal(ctx context.Context) error {
 	ctx, span := tracer.Start(ctx, "outboundCall.sipSignal")
 	defer span.End()
 
+	var ringTimeout <-chan struct{}
 	if c.sipConf.ringingTimeout > 0 {
-		var cancel func()
-		ctx, cancel = context.WithTimeout(ctx, c.sipConf.ringingTimeout)
-		defer cancel()
+		// Ringing timeout is owned by the setup states and stops once the call is answered or terminated.
+		ringTimeout = c.cc.fsm.Timeout(timerRinging, c.sipConf.ringingTimeout, DialogIdle, DialogProceeding, DialogEarly)
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -507,12 +758,15 @@ func (c *outboundCall) sipSignal(ctx context.Context) error {
 		case <-ctx.Done():
 			// parent context cancellation or success
 			return
+		case <-ringTimeout:
 		case <-c.Disconnected():
 		case <-c.Closed():
 		}
 		cancel()
 	}()
 
+	// SDP phase only includes local processing of the offer and the answer, not the time waiting for the answer.
+	sdpStart := time.Now()
 	sdpOffer, err := c.media.NewOffer(c.sipConf.mediaEncryption)
 	if err != nil {
 		return err
@@ -521,6 +775,7 @@ func (c *outboundCall) sipSignal(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	sdpDur := time.Since(sdpStart)
 	c.mon.SDPSize(len(sdpOfferData), true)
 	c.log.Debugw("SDP offer", "sdp", string(sdpOfferData))
 	joinDur := c.mon.JoinDur()
@@ -536,7 +791,7 @@ func (c *outboundCall) sipSignal(ctx context.Context) error {
 		}
 		if !ringing && code >= sip.StatusRinging && code < sip.StatusOK {
 			ringing = true
-			c.setStatus(CallRinging)
+			c.onRinging()
 		}
 		c.setExtraAttrs(nil, 0, nil, hdrs)
 	})
@@ -545,29 +800,50 @@ func (c *outboundCall) sipSignal(ctx context.Context) error {
 		var e *livekit.SIPStatus
 		if errors.As(err, &e) {
 			c.mon.InviteError(statusName(int(e.Code)))
+			c.mon.CallFailed(int(e.Code))
 			c.state.DeferUpdate(func(info *livekit.SIPCallInfo) {
 				info.CallStatusCode = e
 			})
 		} else {
 			c.mon.InviteError("other")
+			c.mon.CallFailed(0)
 		}
 		c.cc.Close()
 		c.log.Infow("SIP invite failed", "error", err)
 		return err
 	}
+	for _, v := range c.cc.SDPViolations() {
+		c.mon.SDPViolation(v)
+	}
+	if tr := c.cc.Transport(); tr != toUri.Transport {
+		// Record the transport that was selected by the fallback.
+		uri := toUri
+		uri.Transport = tr
+		c.state.DeferUpdate(func(info *livekit.SIPCallInfo) {
+			if info.ToUri != nil {
+				info.ToUri.Transport = SIPTransportFrom(tr)
+				info.ToUri.Port = uint32(uri.GetPort())
+			}
+		})
+	}
 	c.mon.SDPSize(len(sdpResp), false)
 	c.log.Debugw("SDP answer", "sdp", string(sdpResp))
 
 	c.log = LoggerWithHeaders(c.log, c.cc)
 
+	sdpStart = time.Now()
 	mc, err := c.media.SetAnswer(sdpOffer, sdpResp, c.sipConf.mediaEncryption)
+	if res := mediaEncryptionResult(mc, err); res != "" {
+		c.mon.MediaEncryption(res)
+	}
 	if err != nil {
 		return err
 	}
-	mc.Processor = c.c.handler.GetMediaProcessor(c.sipConf.enabledFeatures)
 	if err = c.media.SetConfig(mc); err != nil {
 		return err
 	}
+	c.media.setHandlerStage(c.c.handler.GetMediaProcessor(c.sipConf.enabledFeatures))
+	c.mon.SetupPhaseObserve(stats.SetupSDP, sdpDur+time.Since(sdpStart))
 
 	c.c.cmu.Lock()
 	c.c.byRemote[c.cc.Tag()] = c
@@ -575,12 +851,14 @@ func (c *outboundCall) sipSignal(ctx context.Context) error {
 
 	c.mon.InviteAccept()
 	c.media.EnableOut()
-	c.media.EnableTimeout(true)
 	err = c.cc.AckInviteOK(ctx)
 	if err != nil {
 		c.log.Infow("SIP accept failed", "error", err)
 		return err
 	}
+	c.mon.CallAnswered()
+	c.recSync.SetSIPCallID(c.cc.CallID())
+	c.recSync.Mark(recordingAnswered)
 	joinDur()
 
 	c.setExtraAttrs(c.sipConf.headersToAttrs, c.sipConf.includeHeaders, c.cc, nil)
@@ -593,23 +871,55 @@ func (c *outboundCall) sipSignal(ctx context.Context) error {
 	return nil
 }
 
-func (c *outboundCall) handleDTMF(ev dtmf.Event) {
+func (c *outboundCall) mediaStageEnv() MediaStageEnv {
+	return MediaStageEnv{
+		Log:       c.log,
+		CallID:    string(c.cc.ID()),
+		ProjectID: c.projectID,
+		Artifacts: c.c.artifacts,
+		mem:       c.mem,
+		OnDTMF:    c.handleDTMF,
+		OnSpeech: func(speaking bool) {
+			c.lkRoom.SetAttributes(speakingAttrs(speaking))
+		},
+		STT: c.c.stt,
+		OnTranscript: func(t stt.Transcript) {
+			publishTranscript(c.log, c.lkRoom, t)
+		},
+	}
+}
+
+func (c *outboundCall) handleDTMF(ev DTMFEvent) {
+	c.log.Debugw("received dtmf", "digit", string([]byte{ev.Digit}), "duration", ev.Duration, "volume", ev.Volume)
+	c.speaker.BargeIn("dtmf")
+	c.lkRoom.SetAttributes(dtmfAttrs(ev))
+	if it, ok := c.menu.Feed(ev.Digit, time.Now()); ok {
+		go c.handleDTMFMenu(it)
+	}
 	_ = c.lkRoom.SendData(&livekit.SipDTMF{
 		Code:  uint32(ev.Code),
 		Digit: string([]byte{ev.Digit}),
 	}, lksdk.WithDataPublishReliable(true))
 }
 
+func (c *outboundCall) playAudio(ctx context.Context, frames []msdk.PCM16Sample) {
+	playToRoom(ctx, c.lkRoom, frames)
+}
+
 func (c *outboundCall) transferCall(ctx context.Context, transferTo string, headers map[string]string, dialtone bool) (retErr error) {
 	var err error
 
 	tID := c.state.StartTransfer(ctx, transferTo)
+	c.lkRoom.SetAttributes(transferStateAttrs(transferStateInProgress))
 	defer func() {
 		c.state.EndTransfer(ctx, tID, retErr)
+		c.lkRoom.SetAttributes(transferResultAttrs(retErr))
 	}()
+	// The remote may stop sending media while it connects the transfer target.
+	c.media.pauseTimeout(timeoutPauseTransfer, true)
+	defer c.media.pauseTimeout(timeoutPauseTransfer, false)
 
 	if dialtone && c.started.IsBroken() && !c.stopped.IsBroken() {
-		const ringVolume = math.MaxInt16 / 2
 		rctx, rcancel := context.WithCancel(ctx)
 		defer rcancel()
 
@@ -627,7 +937,7 @@ func (c *outboundCall) transferCall(ctx context.Context, transferTo string, head
 		go func() {
 			aw := c.media.GetAudioWriter()
 
-			err := tones.Play(rctx, aw, ringVolume, tones.ETSIRinging)
+			err := c.c.tones.Play(rctx, aw, tonegen.Ringing)
 			if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
 				c.log.Infow("cannot play dial tone", "error", err)
 			}
@@ -659,6 +969,7 @@ func (c *Client) newOutbound(log logger.Logger, id LocalTag, from, contact URI,
 		Address: *contact.GetContactURI(),
 	}
 	fromHeader.Params.Add("tag", string(id))
+	history := newDialogHistory()
 	return &sipOutbound{
 		log:        log,
 		c:          c,
@@ -668,6 +979,8 @@ func (c *Client) newOutbound(log logger.Logger, id LocalTag, from, contact URI,
 		referDone:  make(chan error), // Do not buffer the channel to avoid reading a result for an old request
 		nextCSeq:   1,
 		getHeaders: getHeaders,
+		history:    history,
+		fsm:        newCallFSM(log, history, DialogIdle),
 	}
 }
 
@@ -677,6 +990,8 @@ type sipOutbound struct {
 	id      LocalTag
 	from    *sip.FromHeader
 	contact *sip.ContactHeader
+	history *dialogHistory
+	fsm     *callFSM
 
 	mu         sync.RWMutex
 	tag        RemoteTag
@@ -689,6 +1004,25 @@ type sipOutbound struct {
 
 	referCseq uint32
 	referDone chan error
+	location  *callLocation
+	caps      sipCapabilities
+	timers    sipTimers
+
+	sdpViolations []string
+	transport     Transport
+}
+
+// setCallerID updates From and Contact headers. It must be called before the INVITE is sent.
+func (c *sipOutbound) setCallerID(id callerID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.from.DisplayName = id.DisplayName
+	if id.Anonymous {
+		c.from.Address = sip.Uri{User: anonymousUser, Host: anonymousHost}
+	} else {
+		c.from.Address.User = id.User
+	}
+	c.contact.Address.User = id.ContactUser
 }
 
 func (c *sipOutbound) From() sip.Uri {
@@ -743,19 +1077,17 @@ func (c *sipOutbound) Invite(ctx context.Context, to URI, user, pass string, hea
 	defer span.End()
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	toHeader := &sip.ToHeader{Address: *to.GetURI()}
 
-	dest := to.GetDest()
-	c.callID = guid.HashedID(fmt.Sprintf("%s-%s", string(c.id), toHeader.Address.String()))
+	c.callID = guid.HashedID(fmt.Sprintf("%s-%s", string(c.id), to.GetURI().String()))
 	c.log = c.log.WithValues("sipCallID", c.callID)
+	c.c.owners.Add(c.callID)
 
 	var (
-		sipHeaders         Headers
-		authHeader         = ""
-		authHeaderRespName string
-		req                *sip.Request
-		resp               *sip.Response
-		err                error
+		sipHeaders Headers
+		req        *sip.Request
+		resp       *sip.Response
+		neg        *sdpNegotiation
+		err        error
 	)
 	if keys := maps.Keys(headers); len(keys) != 0 {
 		sort.Strings(keys)
@@ -763,74 +1095,44 @@ func (c *sipOutbound) Invite(ctx context.Context, to URI, user, pass string, hea
 			sipHeaders = append(sipHeaders, sip.NewHeader(key, headers[key]))
 		}
 	}
-authLoop:
-	for try := 0; ; try++ {
-		if try >= 5 {
-			return nil, fmt.Errorf("max auth retry attemps reached")
-		}
-		req, resp, err = c.attemptInvite(ctx, sip.CallIDHeader(c.callID), dest, toHeader, sdpOffer, authHeaderRespName, authHeader, sipHeaders, setState)
-		if err != nil {
-			return nil, err
+	now := time.Now()
+	last := c.c.transports.Get(to.GetHost(), now)
+	transports := transportCandidates(to.Transport, len(sdpOffer)+c.location.Size()+inviteHeadersSize, last, c.c.conf.TLS != nil)
+	for i, tr := range transports {
+		cur := to
+		if tr != TransportUDP || to.Transport != "" {
+			// Keep the URI unchanged if UDP is used by default.
+			cur.Transport = tr
 		}
-		var authHeaderName string
-		switch resp.StatusCode {
-		case sip.StatusOK:
-			break authLoop
-		default:
-			return nil, fmt.Errorf("unexpected status from INVITE response: %w", &livekit.SIPStatus{Code: livekit.SIPStatusCode(resp.StatusCode)})
-		case sip.StatusBadRequest,
-			sip.StatusNotFound,
-			sip.StatusTemporarilyUnavailable,
-			sip.StatusNotAcceptableHere,
-			sip.StatusBusyHere:
-			err := &livekit.SIPStatus{Code: livekit.SIPStatusCode(resp.StatusCode)}
-			if body := resp.Body(); len(body) != 0 {
-				err.Status = string(body)
-			} else if s := resp.GetHeader("X-Twillio-Error"); s != nil {
-				err.Status = s.Value()
+		c.setTransport(cur.Transport)
+		fallback := time.Duration(-1) // last transport, don't fall back
+		if i < len(transports)-1 {
+			fallback = c.c.conf.SIPTransportFallbackTimeout
+			if fallback == 0 {
+				fallback = config.DefaultSIPTransportFallbackTimeout
+			} else if fallback < 0 {
+				fallback = 0 // only fall back on connection errors
 			}
-			return nil, fmt.Errorf("INVITE failed: %w", err)
-		case sip.StatusUnauthorized:
-			authHeaderName = "WWW-Authenticate"
-			authHeaderRespName = "Authorization"
-		case sip.StatusProxyAuthRequired:
-			authHeaderName = "Proxy-Authenticate"
-			authHeaderRespName = "Proxy-Authorization"
-		}
-		c.log.Infow("auth requested", "status", resp.StatusCode, "body", string(resp.Body()))
-		// auth required
-		if user == "" || pass == "" {
-			return nil, errors.New("server required auth, but no username or password was provided")
-		}
-		headerVal := resp.GetHeader(authHeaderName)
-		if headerVal == nil {
-			return nil, errors.New("no auth header in response")
 		}
-		challengeStr := headerVal.Value()
-		challenge, err := digest.ParseChallenge(challengeStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid challenge %q: %w", challengeStr, err)
-		}
-		toHeader := resp.To()
-		if toHeader == nil {
-			return nil, errors.New("no 'To' header on Response")
-		}
-
-		cred, err := digest.Digest(challenge, digest.Options{
-			Method:   req.Method.String(),
-			URI:      toHeader.Address.String(),
-			Username: user,
-			Password: pass,
-		})
-		if err != nil {
+		req, resp, neg, err = c.inviteWithAuth(ctx, cur, user, pass, sipHeaders, sdpOffer, setState, fallback)
+		if err == nil {
+			c.transport = tr
+			break
+		} else if i == len(transports)-1 || !errors.Is(err, errTransportFailed) {
 			return nil, err
 		}
-		authHeader = cred.String()
-		// Try again with a computed digest
+		c.log.Infow("INVITE failed, trying next transport", "error", err, "transport", tr, "next", transports[i+1])
+	}
+	if len(transports) > 1 {
+		if c.transport != last {
+			c.log.Infow("INVITE transport selected", "transport", c.transport)
+		}
+		c.c.transports.Set(to.GetHost(), c.transport, now)
 	}
 
 	c.invite, c.inviteOk = req, resp
-	toHeader = resp.To()
+	c.fsm.Transition(DialogAnswered)
+	toHeader := resp.To()
 	if toHeader == nil {
 		return nil, errors.New("no To header in INVITE response")
 	}
@@ -851,10 +1153,113 @@ authLoop:
 		req.AppendHeader(&sip.RouteHeader{Address: recordRouteHeader.Address})
 	}
 
-	return c.inviteOk.Body(), nil
+	answer, err := neg.Answer(resp)
+	c.sdpViolations = neg.Violations()
+	for _, v := range c.sdpViolations {
+		c.log.Warnw("SDP offer/answer violation", nil, "violation", v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+// setTransport updates From and Contact headers for a given transport. It must be called before the INVITE is sent.
+func (c *sipOutbound) setTransport(tr Transport) {
+	if c.from.Address.Host != anonymousHost {
+		if tr == "" {
+			if c.from.Address.UriParams != nil {
+				c.from.Address.UriParams.Remove("transport")
+			}
+		} else {
+			if c.from.Address.UriParams == nil {
+				c.from.Address.UriParams = make(sip.HeaderParams)
+			}
+			c.from.Address.UriParams.Add("transport", string(tr))
+		}
+	}
+	user := c.contact.Address.User
+	c.contact.Address = *c.c.ContactURI(tr).GetContactURI()
+	c.contact.Address.User = user
+}
+
+// Transport returns the transport that was used for the INVITE.
+func (c *sipOutbound) Transport() Transport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.transport
+}
+
+// inviteWithAuth sends the INVITE to a given URI, responding to auth challenges.
+//
+// If fallback is not negative, the request fails with errTransportFailed if the connection cannot be established,
+// or if there's no response over the transport during that time (zero disables the timeout).
+func (c *sipOutbound) inviteWithAuth(ctx context.Context, to URI, user, pass string, headers Headers, sdpOffer []byte, setState sipRespFunc, fallback time.Duration) (*sip.Request, *sip.Response, *sdpNegotiation, error) {
+	toHeader := &sip.ToHeader{Address: *to.GetURI()}
+	dest := to.GetDest()
+
+	var (
+		authHeader         = ""
+		authHeaderRespName string
+		req                *sip.Request
+		resp               *sip.Response
+		neg                *sdpNegotiation
+		err                error
+	)
+	for try := 0; ; try++ {
+		if try >= 5 {
+			return nil, nil, nil, fmt.Errorf("max auth retry attemps reached")
+		}
+		neg = &sdpNegotiation{}
+		if try == 0 && fallback >= 0 {
+			req, resp, err = c.attemptInviteOrFallback(ctx, dest, toHeader, sdpOffer, headers, neg, setState, fallback)
+		} else {
+			req, resp, err = c.attemptInvite(ctx, sip.CallIDHeader(c.callID), dest, toHeader, sdpOffer, authHeaderRespName, authHeader, headers, neg, setState)
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		switch resp.StatusCode {
+		case sip.StatusOK:
+			return req, resp, neg, nil
+		default:
+			return nil, nil, nil, fmt.Errorf("unexpected status from INVITE response: %w", &livekit.SIPStatus{Code: livekit.SIPStatusCode(resp.StatusCode)})
+		case sip.StatusBadRequest,
+			sip.StatusNotFound,
+			sip.StatusTemporarilyUnavailable,
+			sip.StatusNotAcceptableHere,
+			sip.StatusBusyHere:
+			err := &livekit.SIPStatus{Code: livekit.SIPStatusCode(resp.StatusCode)}
+			if cause := isupCause(c.log, bodyContentType(resp), resp.Body()); cause != nil {
+				// SIP-I carriers send the release reason as binary ISUP REL.
+				err.Status = "isup cause " + cause.String()
+			} else if body := resp.Body(); len(body) != 0 {
+				err.Status = string(body)
+			} else if s := resp.GetHeader("X-Twillio-Error"); s != nil {
+				err.Status = s.Value()
+			}
+			return nil, nil, nil, fmt.Errorf("INVITE failed: %w", err)
+		case sip.StatusUnauthorized, sip.StatusProxyAuthRequired:
+			// auth required
+		}
+		c.log.Infow("auth requested", "status", resp.StatusCode, "body", string(resp.Body()))
+		authHeaderRespName, authHeader, err = digestAuth(req, resp, user, pass)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		// Try again with a computed digest
+	}
+}
+
+// SDPViolations returns offer/answer violations detected during the INVITE transaction.
+func (c *sipOutbound) SDPViolations() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sdpViolations
 }
 
 func (c *sipOutbound) AcceptBye(req *sip.Request, tx sip.ServerTransaction) {
+	tx = c.history.ServerTx(req, tx)
 	_ = tx.Respond(sip.NewResponseFromRequest(req, 200, "OK", nil))
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -869,25 +1274,46 @@ func (c *sipOutbound) AckInviteOK(ctx context.Context) error {
 	if c.invite == nil || c.inviteOk == nil {
 		return errors.New("call already closed")
 	}
-	return c.c.sipCli.WriteRequest(sip.NewAckRequest(c.invite, c.inviteOk, nil))
+	ack := sip.NewAckRequest(c.invite, c.inviteOk, nil)
+	c.history.Request(dialogOut, ack)
+	return c.c.sipCli.WriteRequest(ack)
 }
 
-func (c *sipOutbound) attemptInvite(ctx context.Context, callID sip.CallIDHeader, dest string, to *sip.ToHeader, offer []byte, authHeaderName, authHeader string, headers Headers, setState sipRespFunc) (*sip.Request, *sip.Response, error) {
+func (c *sipOutbound) attemptInvite(ctx context.Context, callID sip.CallIDHeader, dest string, to *sip.ToHeader, offer []byte, authHeaderName, authHeader string, headers Headers, neg *sdpNegotiation, setState sipRespFunc) (*sip.Request, *sip.Response, error) {
 	ctx, span := tracer.Start(ctx, "sipOutbound.attemptInvite")
 	defer span.End()
+	// Timer B of the trunk may be longer than the transaction layer allows. In this case the transaction layer
+	// gives up first, and the INVITE is sent again in a new transaction, until Timer B expires.
+	deadline := time.Now().Add(c.timers.TimerB)
+	for {
+		req, resp, err := c.sendInvite(ctx, callID, dest, to, offer, authHeaderName, authHeader, headers, neg, setState, time.Until(deadline))
+		if err == nil || ctx.Err() != nil || !errors.Is(err, transaction.ErrTimeout) || errors.Is(err, errTimerExpired) {
+			return req, resp, err
+		}
+		if time.Until(deadline) < transaction.T1 {
+			return req, resp, err
+		}
+		c.log.Infow("no response to INVITE, sending it again", "timerB", c.timers.TimerB, "remaining", time.Until(deadline))
+	}
+}
+
+// sendInvite sends an INVITE in a new transaction, and waits for the final response.
+// The transaction fails if there's no response during the timeout.
+func (c *sipOutbound) sendInvite(ctx context.Context, callID sip.CallIDHeader, dest string, to *sip.ToHeader, offer []byte, authHeaderName, authHeader string, headers Headers, neg *sdpNegotiation, setState sipRespFunc, timeout time.Duration) (*sip.Request, *sip.Response, error) {
 	req := sip.NewRequest(sip.INVITE, to.Address)
 	c.setCSeq(req)
 	req.RemoveHeader("Call-ID")
 	req.AppendHeader(&callID)
 
+	body, contentType := c.location.Body(offer)
 	req.SetDestination(dest)
-	req.SetBody(offer)
+	req.SetBody(body)
 	req.AppendHeader(to)
 	req.AppendHeader(c.from)
 	req.AppendHeader(c.contact)
 
-	req.AppendHeader(sip.NewHeader("Content-Type", "application/sdp"))
-	req.AppendHeader(sip.NewHeader("Allow", "INVITE, ACK, CANCEL, BYE, NOTIFY, REFER, MESSAGE, OPTIONS, INFO, SUBSCRIBE"))
+	req.AppendHeader(sip.NewHeader("Content-Type", contentType))
+	c.caps.SetHeaders(req, false)
 
 	if authHeader != "" {
 		req.AppendHeader(sip.NewHeader(authHeaderName, authHeader))
@@ -896,22 +1322,91 @@ func (c *sipOutbound) attemptInvite(ctx context.Context, callID sip.CallIDHeader
 		req.AppendHeader(h)
 	}
 
+	// Via is added before sending, so that the request is recognized if it loops back to us.
+	c.c.addVia(req)
+	inv := c.c.loops.Add(req)
+	defer c.c.loops.Remove(inv)
+
 	tx, err := c.c.sipCli.TransactionRequest(req)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer tx.Terminate()
+	c.fsm.Transition(DialogProceeding)
 
-	resp, err := sipResponse(ctx, tx, c.c.closing.Watch(), setState)
+	rtx := newTimerClientTx(c.history, c.history.ClientTx(req, tx), timerB, timeout)
+	resp, err := sipResponse(ctx, rtx, c.c.closing.Watch(), func(resp *sip.Response) {
+		neg.OnResponse(resp)
+		if resp.StatusCode == sip.StatusRinging || resp.StatusCode == sip.StatusSessionInProgress {
+			c.fsm.Transition(DialogEarly)
+		}
+		if setState != nil {
+			setState(resp.StatusCode, resp.Headers())
+		}
+	})
+	return req, resp, err
+}
+
+// attemptInviteOrFallback sends the first INVITE over a transport that may not work for the destination.
+// It fails with errTransportFailed if the connection cannot be established, or if there's no response
+// during the timeout. Zero timeout waits for the transaction to complete.
+func (c *sipOutbound) attemptInviteOrFallback(ctx context.Context, dest string, to *sip.ToHeader, offer []byte, headers Headers, neg *sdpNegotiation, setState sipRespFunc, timeout time.Duration) (*sip.Request, *sip.Response, error) {
+	actx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var gotResp atomic.Bool
+	if timeout > 0 {
+		t := time.AfterFunc(timeout, func() {
+			if !gotResp.Load() {
+				cancel(errTransportFailed)
+			}
+		})
+		defer t.Stop()
+	}
+	req, resp, err := c.attemptInvite(actx, sip.CallIDHeader(c.callID), dest, to, offer, "", "", headers, neg, func(code sip.StatusCode, hdrs Headers) {
+		gotResp.Store(true)
+		if setState != nil {
+			setState(code, hdrs)
+		}
+	})
+	if err == nil || gotResp.Load() || ctx.Err() != nil {
+		return req, resp, err
+	}
+	if errors.Is(context.Cause(actx), errTransportFailed) {
+		return nil, nil, fmt.Errorf("%w: no response in %v", errTransportFailed, timeout)
+	}
+	if isTransportError(err) {
+		return nil, nil, fmt.Errorf("%w: %w", errTransportFailed, err)
+	}
 	return req, resp, err
 }
 
 func (c *sipOutbound) WriteRequest(req *sip.Request) error {
-	return c.c.sipCli.WriteRequest(req)
+	c.history.Request(dialogOut, req)
+	return retryTransport(c.log, req.Transport(), func() error {
+		return c.c.sipCli.WriteRequest(req)
+	})
 }
 
-func (c *sipOutbound) Transaction(req *sip.Request) (sip.ClientTransaction, error) {
-	return c.c.sipCli.TransactionRequest(req)
+func (c *sipOutbound) Transaction(req *sip.Request) (tx sip.ClientTransaction, err error) {
+	err = retryTransport(c.log, req.Transport(), func() error {
+		tx, err = c.c.sipCli.TransactionRequest(req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.timers.ClientTx(c.history, req, c.history.ClientTx(req, tx)), nil
+}
+
+// keepAlive sends keep-alives over the connection of the call, if it uses TCP or TLS.
+func (c *sipOutbound) keepAlive(done <-chan struct{}) {
+	c.mu.RLock()
+	invite := c.invite
+	c.mu.RUnlock()
+	if invite == nil {
+		return
+	}
+	keepAliveConn(done, c.log, c.c.sipCli.TransportLayer(), invite.Transport(), invite.Destination(), c.c.conf.SIPKeepAliveInterval)
 }
 
 func (c *sipOutbound) setCSeq(req *sip.Request) {
@@ -945,6 +1440,7 @@ func (c *sipOutbound) sendBye() {
 }
 
 func (c *sipOutbound) drop() {
+	c.fsm.Transition(DialogTerminating)
 	c.invite = nil
 	c.inviteOk = nil
 	c.nextCSeq = 0
@@ -974,6 +1470,7 @@ func (c *sipOutbound) transferCall(ctx context.Context, transferTo string, heade
 	}
 
 	req := NewReferRequest(c.invite, c.inviteOk, c.contact, transferTo, headers)
+	c.caps.SetHeaders(req, false)
 	c.setCSeq(req)
 	cseq := req.CSeq()
 
@@ -1001,6 +1498,27 @@ func (c *sipOutbound) transferCall(ctx context.Context, transferTo string, heade
 	return nil
 }
 
+// SendHeaders sends headers to the callee in an in-dialog request without a body, such as INFO or UPDATE.
+func (c *sipOutbound) SendHeaders(ctx context.Context, method sip.RequestMethod, headers map[string]string) error {
+	c.mu.Lock()
+	if c.invite == nil || c.inviteOk == nil {
+		c.mu.Unlock()
+		return psrpc.NewErrorf(psrpc.FailedPrecondition, "can't send %s in non established call", method)
+	}
+	if c.c.closing.IsBroken() {
+		c.mu.Unlock()
+		return psrpc.NewErrorf(psrpc.FailedPrecondition, "can't send %s in hung up call", method)
+	}
+	req := newDialogRequest(method, c.invite, c.inviteOk, c.contact)
+	for k, v := range headers {
+		req.AppendHeader(sip.NewHeader(k, v))
+	}
+	c.setCSeq(req)
+	c.mu.Unlock()
+
+	return sendDialogRequest(ctx, c, req, c.c.closing.Watch())
+}
+
 func (c *sipOutbound) handleNotify(req *sip.Request, tx sip.ServerTransaction) error {
 	method, cseq, status, err := handleNotify(req)
 	if err != nil {
@@ -0,0 +1,55 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+)
+
+func TestRequestPrewarm(t *testing.T) {
+	attrs := map[string]string{"a": "1"}
+	prewarm, got := requestPrewarm(attrs)
+	require.False(t, prewarm)
+	require.Equal(t, attrs, got)
+
+	attrs = map[string]string{"a": "1", AttrSIPPrewarm: "true"}
+	prewarm, got = requestPrewarm(attrs)
+	require.True(t, prewarm)
+	require.Equal(t, map[string]string{"a": "1"}, got)
+	require.Contains(t, attrs, AttrSIPPrewarm, "request attributes must not be modified")
+
+	prewarm, got = requestPrewarm(map[string]string{AttrSIPPrewarm: "false"})
+	require.False(t, prewarm)
+	require.Empty(t, got)
+}
+
+func TestOutboundPrewarm(t *testing.T) {
+	log := logger.GetLogger()
+	for _, prewarm := range []bool{false, true} {
+		c := &outboundCall{
+			log:     log,
+			lkRoom:  NewRoom(log, nil),
+			sipConf: sipOutboundConfig{prewarm: prewarm},
+		}
+		// Subscription starts when the callee is ringing, not after the answer.
+		c.onRinging()
+		require.Equal(t, prewarm, c.lkRoom.subscribe.Load())
+		require.NoError(t, c.lkRoom.Close())
+	}
+}
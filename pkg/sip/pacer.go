@@ -0,0 +1,214 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"io"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostbyte73/core"
+
+	"github.com/livekit/media-sdk/rtp"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	// DefaultPacerMaxDelay is the default time packets can wait in the pacer queue before they are dropped.
+	DefaultPacerMaxDelay = 200 * time.Millisecond
+	// pacerOverhead is the size of IPv4 and UDP headers, accounted towards the bitrate cap.
+	pacerOverhead = 28
+)
+
+// PacerConfig controls pacing of RTP packets sent to SIP.
+type PacerConfig struct {
+	// Enabled sends audio packets with strict Interval spacing, smoothing bursts from the room.
+	Enabled bool
+	// Interval between audio packets. Defaults to the RTP frame duration.
+	Interval time.Duration
+	// MaxBitrate caps the bitrate of sent packets in bits per second, including RTP, UDP and IP headers. Zero disables the cap.
+	MaxBitrate int
+	// MaxDelay is the maximal time packets are queued before they are dropped. Defaults to DefaultPacerMaxDelay.
+	MaxDelay time.Duration
+}
+
+func pacerConfig(c config.PacingConfig) PacerConfig {
+	return PacerConfig{
+		Enabled:    c.Enabled,
+		MaxBitrate: c.MaxBitrate,
+		MaxDelay:   c.MaxDelay,
+	}
+}
+
+func (c PacerConfig) active() bool {
+	return c.Enabled || c.MaxBitrate > 0
+}
+
+type pacedPacket struct {
+	at      time.Time
+	h       rtp.Header
+	payload []byte
+}
+
+// rtpPacer queues outgoing RTP packets and sends them with a fixed spacing of audio packets and a bitrate cap.
+// Packets of other payload types (DTMF) keep their order, but are not spaced.
+type rtpPacer struct {
+	w         rtp.Writer
+	conf      PacerConfig
+	audioType byte
+	paced     *atomic.Uint64
+	drops     *atomic.Uint64
+
+	queue  chan pacedPacket
+	closed core.Fuse
+
+	// owned by the run goroutine
+	next   time.Time // earliest time of the next audio packet
+	tokens float64   // bitrate cap budget, in bytes
+	last   time.Time // last update of tokens
+}
+
+func newRTPPacer(w rtp.Writer, conf PacerConfig, audioType byte, st *PortStats) *rtpPacer {
+	if conf.Interval <= 0 {
+		conf.Interval = rtp.DefFrameDur
+	}
+	if conf.MaxDelay <= 0 {
+		conf.MaxDelay = DefaultPacerMaxDelay
+	}
+	if st == nil {
+		st = &PortStats{}
+	}
+	size := max(int(conf.MaxDelay/conf.Interval)*2, 8)
+	p := &rtpPacer{
+		w:         w,
+		conf:      conf,
+		audioType: audioType,
+		paced:     &st.PacedPackets,
+		drops:     &st.PacerDrops,
+		queue:     make(chan pacedPacket, size),
+	}
+	go p.run()
+	return p
+}
+
+func (p *rtpPacer) String() string {
+	return "Pacer -> " + p.w.String()
+}
+
+func (p *rtpPacer) WriteRTP(h *rtp.Header, payload []byte) (int, error) {
+	if p.closed.IsBroken() {
+		return 0, io.ErrClosedPipe
+	}
+	// Both the header and the payload are reused by the writer.
+	pkt := pacedPacket{at: time.Now(), h: *h, payload: slices.Clone(payload)}
+	pkt.h.CSRC = slices.Clone(h.CSRC)
+	pkt.h.Extensions = slices.Clone(h.Extensions)
+	select {
+	case p.queue <- pkt:
+	default:
+		p.drops.Add(1)
+	}
+	return len(payload), nil
+}
+
+func (p *rtpPacer) Close() {
+	p.closed.Break()
+}
+
+func (p *rtpPacer) run() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	for {
+		var pkt pacedPacket
+		select {
+		case <-p.closed.Watch():
+			return
+		case pkt = <-p.queue:
+		}
+		now := time.Now()
+		if now.Sub(pkt.at) > p.conf.MaxDelay {
+			p.drops.Add(1)
+			continue
+		}
+		at := p.sendTime(&pkt, now)
+		if d := at.Sub(now); d > 0 {
+			p.paced.Add(1)
+			timer.Reset(d)
+			select {
+			case <-p.closed.Watch():
+				return
+			case <-timer.C:
+			}
+		}
+		p.consume(&pkt, at)
+		if _, err := p.w.WriteRTP(&pkt.h, pkt.payload); err != nil {
+			return
+		}
+	}
+}
+
+// sendTime returns the time when the packet can be sent.
+func (p *rtpPacer) sendTime(pkt *pacedPacket, now time.Time) time.Time {
+	at := now
+	if p.conf.Enabled && pkt.h.PayloadType == p.audioType && p.next.After(at) {
+		at = p.next
+	}
+	if p.conf.MaxBitrate > 0 {
+		p.refill(at)
+		if need := float64(packetSize(pkt)) - p.tokens; need > 0 {
+			at = at.Add(time.Duration(need / p.byteRate() * float64(time.Second)))
+		}
+	}
+	return at
+}
+
+// consume accounts the packet sent at a given time.
+func (p *rtpPacer) consume(pkt *pacedPacket, at time.Time) {
+	if p.conf.Enabled && pkt.h.PayloadType == p.audioType {
+		// Schedule from the planned time rather than the actual one, so that timer delays do not accumulate.
+		p.next = at.Add(p.conf.Interval)
+	}
+	if p.conf.MaxBitrate > 0 {
+		p.refill(at)
+		p.tokens -= float64(packetSize(pkt))
+	}
+}
+
+func (p *rtpPacer) byteRate() float64 {
+	return float64(p.conf.MaxBitrate) / 8
+}
+
+// refill adds the budget accumulated since the last update. The budget is capped to a few packets,
+// so that the cap is enforced on short intervals as well.
+func (p *rtpPacer) refill(now time.Time) {
+	rate := p.byteRate()
+	burst := rate * (2 * p.conf.Interval).Seconds()
+	if p.last.IsZero() {
+		p.tokens = burst
+	} else if now.After(p.last) {
+		p.tokens += rate * now.Sub(p.last).Seconds()
+	}
+	p.tokens = min(p.tokens, burst)
+	if now.After(p.last) {
+		p.last = now
+	}
+}
+
+func packetSize(pkt *pacedPacket) int {
+	return pkt.h.MarshalSize() + len(pkt.payload) + pacerOverhead
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/media-sdk/rtp"
+)
+
+type timedRTPWriter struct {
+	mu   sync.Mutex
+	seq  []uint16
+	sent []time.Time
+}
+
+func (w *timedRTPWriter) String() string { return "Timed" }
+
+func (w *timedRTPWriter) WriteRTP(h *rtp.Header, payload []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.seq = append(w.seq, h.SequenceNumber)
+	w.sent = append(w.sent, time.Now())
+	return len(payload), nil
+}
+
+func (w *timedRTPWriter) result() ([]uint16, []time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]uint16(nil), w.seq...), append([]time.Time(nil), w.sent...)
+}
+
+func writeBurst(t *testing.T, w rtp.Writer, typ byte, n int, size int) {
+	h := &rtp.Header{Version: 2, PayloadType: typ}
+	payload := make([]byte, size)
+	for i := 0; i < n; i++ {
+		h.SequenceNumber = uint16(i)
+		_, err := w.WriteRTP(h, payload)
+		require.NoError(t, err)
+	}
+}
+
+func TestRTPPacer(t *testing.T) {
+	const (
+		typ      = 0
+		interval = 10 * time.Millisecond
+		n        = 5
+	)
+	t.Run("spacing", func(t *testing.T) {
+		var out timedRTPWriter
+		var st PortStats
+		p := newRTPPacer(&out, PacerConfig{Enabled: true, Interval: interval}, typ, &st)
+		defer p.Close()
+
+		writeBurst(t, p, typ, n, 160)
+		require.Eventually(t, func() bool {
+			seq, _ := out.result()
+			return len(seq) == n
+		}, time.Second, time.Millisecond)
+		seq, sent := out.result()
+		require.Equal(t, []uint16{0, 1, 2, 3, 4}, seq)
+		require.GreaterOrEqual(t, sent[n-1].Sub(sent[0]), (n-1)*interval-time.Millisecond)
+		require.NotZero(t, st.PacedPackets.Load())
+		require.Zero(t, st.PacerDrops.Load())
+	})
+	t.Run("other types", func(t *testing.T) {
+		var out timedRTPWriter
+		p := newRTPPacer(&out, PacerConfig{Enabled: true, Interval: time.Second}, typ, nil)
+		defer p.Close()
+
+		writeBurst(t, p, 101, n, 4)
+		require.Eventually(t, func() bool {
+			seq, _ := out.result()
+			return len(seq) == n
+		}, 500*time.Millisecond, time.Millisecond)
+	})
+	t.Run("bitrate", func(t *testing.T) {
+		var out timedRTPWriter
+		// 200 bytes per packet with headers, 4 packets per 100ms.
+		p := newRTPPacer(&out, PacerConfig{MaxBitrate: 64000, Interval: interval}, typ, nil)
+		defer p.Close()
+
+		writeBurst(t, p, typ, n, 160-12)
+		require.Eventually(t, func() bool {
+			seq, _ := out.result()
+			return len(seq) == n
+		}, time.Second, time.Millisecond)
+		_, sent := out.result()
+		// Burst budget allows the first packet immediately, the rest are limited to 25ms per packet.
+		require.GreaterOrEqual(t, sent[n-1].Sub(sent[0]), (n-1)*25*time.Millisecond-10*time.Millisecond)
+	})
+	t.Run("max delay", func(t *testing.T) {
+		var out timedRTPWriter
+		var st PortStats
+		p := newRTPPacer(&out, PacerConfig{Enabled: true, Interval: interval, MaxDelay: 2 * interval}, typ, &st)
+		defer p.Close()
+
+		writeBurst(t, p, typ, 10, 160)
+		require.Eventually(t, func() bool {
+			seq, _ := out.result()
+			return len(seq)+int(st.PacerDrops.Load()) == 10
+		}, time.Second, time.Millisecond)
+		require.NotZero(t, st.PacerDrops.Load())
+	})
+	t.Run("closed", func(t *testing.T) {
+		var out timedRTPWriter
+		p := newRTPPacer(&out, PacerConfig{Enabled: true}, typ, nil)
+		p.Close()
+		_, err := p.WriteRTP(&rtp.Header{}, nil)
+		require.Error(t, err)
+	})
+}
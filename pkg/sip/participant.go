@@ -15,6 +15,7 @@
 package sip
 
 import (
+	"strings"
 	"time"
 
 	"github.com/livekit/protocol/livekit"
@@ -35,8 +36,115 @@ const (
 const (
 	AttrSIPCallIDFull = livekit.AttrSIPPrefix + "callIDFull"
 	AttrSIPCallTag    = livekit.AttrSIPPrefix + "callTag"
+	// AttrSIPMediaState reports SIP media state: "ok", "degraded" or "lost". See MediaState.
+	AttrSIPMediaState = livekit.AttrSIPPrefix + "mediaState"
+	// AttrSIPZRTP is set to "true" when the remote attempts ZRTP key negotiation, which is not supported.
+	AttrSIPZRTP = livekit.AttrSIPPrefix + "zrtp"
+	// AttrSIPOneWayAudio is set when audio flows in only one direction after the call is answered:
+	// "no-inbound" if nothing is received from the SIP side, "no-outbound" if nothing is sent to it.
+	AttrSIPOneWayAudio = livekit.AttrSIPPrefix + "oneWayAudio"
+	// AttrSIPHoldState reports whether the remote put the call on hold: "active" or "held".
+	AttrSIPHoldState = livekit.AttrSIPPrefix + "holdState"
+	// AttrSIPTransferState reports the state of the last call transfer: "in-progress", "completed" or "failed".
+	AttrSIPTransferState = livekit.AttrSIPPrefix + "transferState"
+	// AttrSIPDialogState reports the state of the call signaling: "proceeding", "early", "answered", "held" or "terminating".
+	AttrSIPDialogState = livekit.AttrSIPPrefix + "dialogState"
+	// AttrSIPLastDTMF is the last DTMF digit received from the SIP side.
+	// Digits A-D are reported in lower case, hook flash is reported as "!".
+	AttrSIPLastDTMF = livekit.AttrSIPPrefix + "lastDTMF"
+	// AttrSIPLastDTMFDuration is the duration of the last DTMF key press in milliseconds.
+	AttrSIPLastDTMFDuration = livekit.AttrSIPPrefix + "lastDTMFDuration"
+	// AttrSIPLastDTMFVolume is the volume of the last DTMF event in -dBm0, as reported by the remote.
+	AttrSIPLastDTMFVolume = livekit.AttrSIPPrefix + "lastDTMFVolume"
+	// AttrSIPQuality is the estimated MOS of the audio received from the SIP side, updated periodically. Only set for G.711.
+	AttrSIPQuality = livekit.AttrSIPPrefix + "quality"
+	// AttrSIPSpeaking is "true" while the vad media stage detects speech from the SIP side, and "false" otherwise.
+	AttrSIPSpeaking = livekit.AttrSIPPrefix + "speaking"
+
+	// AttrSIPCallerName is the caller ID name resolved with CNAM lookup.
+	AttrSIPCallerName = livekit.AttrSIPPrefix + "callerName"
+	// AttrSIPCallerIDMode can be set in the outbound call request to override the trunk caller ID mode.
+	AttrSIPCallerIDMode = livekit.AttrSIPPrefix + "callerIDMode"
+	// AttrSIPCallerID can be set in the outbound call request to present a number in fixed or passthrough caller ID mode.
+	AttrSIPCallerID = livekit.AttrSIPPrefix + "callerID"
+	// AttrSIPResourcePriority is the Resource-Priority (RFC 4412) of the inbound call, like "ets.0, wps.2".
+	// It can be set in the outbound call request to send the header, overriding the trunk default.
+	AttrSIPResourcePriority = livekit.AttrSIPPrefix + "resourcePriority"
+
+	// AttrSIPEmergency is set to "true" on calls to emergency numbers.
+	AttrSIPEmergency = livekit.AttrSIPPrefix + "emergency"
+	// AttrSIPLocationPIDF can be set in the outbound call request to send the caller location as a PIDF-LO document (RFC 4119).
+	// It overrides the location configured for emergency calls, and is not published as a participant attribute.
+	AttrSIPLocationPIDF = livekit.AttrSIPPrefix + "locationPIDF"
+	// AttrSIPLocationURI can be set in the outbound call request to send a caller location reference in the Geolocation header.
+	AttrSIPLocationURI = livekit.AttrSIPPrefix + "locationURI"
+	// AttrSIPLocationRouting can be set to "true" in the outbound call request to allow location-based routing of the call.
+	AttrSIPLocationRouting = livekit.AttrSIPPrefix + "locationRouting"
+
+	// AttrSIPISUPCallingPartyCategory is the calling party's category from the ISUP IAM of a SIP-I call,
+	// like "ordinary", "priority", "payphone", "operator", "test" or "data".
+	AttrSIPISUPCallingPartyCategory = livekit.AttrSIPPrefix + "isup.callingPartyCategory"
+	// AttrSIPISUPOriginalCalledNumber is the original called number from the ISUP IAM of a SIP-I call, if the call was redirected.
+	AttrSIPISUPOriginalCalledNumber = livekit.AttrSIPPrefix + "isup.originalCalledNumber"
+	// AttrSIPISUPRedirectingNumber is the redirecting number from the ISUP IAM of a SIP-I call.
+	AttrSIPISUPRedirectingNumber = livekit.AttrSIPPrefix + "isup.redirectingNumber"
+	// AttrSIPISUPCause is the ISUP release cause value (ITU-T Q.850) sent by the remote, set in the final call info.
+	AttrSIPISUPCause = livekit.AttrSIPPrefix + "isup.cause"
+
+	// AttrSIPPickup is set on calls which picked up another call: "replaces" or "join".
+	AttrSIPPickup = livekit.AttrSIPPrefix + "pickup"
+	// AttrSIPPickupCallID is the LiveKit call ID of the call which was picked up.
+	AttrSIPPickupCallID = livekit.AttrSIPPrefix + "pickupCallID"
+
+	// AttrSIPDialerJob is the ID of the dialer job which placed the outbound call.
+	AttrSIPDialerJob = livekit.AttrSIPPrefix + "dialerJob"
+	// AttrSIPDialerAttempt is the attempt number of the dialer job, starting from 1.
+	AttrSIPDialerAttempt = livekit.AttrSIPPrefix + "dialerAttempt"
+	// AttrSIPCallbackCallID is the ID of the queued inbound call, which requested the callback.
+	AttrSIPCallbackCallID = livekit.AttrSIPPrefix + "callbackCallID"
+
+	// AttrSIPRequestHangup can be set to "true" by other parties to hang up the call.
+	AttrSIPRequestHangup = livekit.AttrSIPPrefix + "requestHangup"
+	// AttrSIPRequestMute can be set to "true" or "false" by other parties to mute or unmute audio sent to SIP.
+	AttrSIPRequestMute = livekit.AttrSIPPrefix + "requestMute"
+	// AttrSIPPrewarm can be set to "true" in the outbound call request to subscribe to room audio as soon as
+	// the callee is ringing, instead of waiting for the answer. Only the subscription moves earlier: tracks are
+	// subscribed and mixed while ringing, so audio flows without waiting for track setup once the call is answered.
+	// Room audio is still sent to SIP only after the answer. It uses room resources for calls that may not be answered.
+	// The attribute is removed from participant attributes.
+	AttrSIPPrewarm = livekit.AttrSIPPrefix + "prewarm"
+
+	// Talk time analytics, set in the final call info. Durations are in seconds.
+	// Talk time is reported for each side: "sip" is the remote SIP party, "room" is the audio sent from the room.
+	AttrSIPTalkTimeSIP    = livekit.AttrSIPPrefix + "talkTime.sip"
+	AttrSIPTalkTimeRoom   = livekit.AttrSIPPrefix + "talkTime.room"
+	AttrSIPTalkBurstsSIP  = livekit.AttrSIPPrefix + "talkBursts.sip"
+	AttrSIPTalkBurstsRoom = livekit.AttrSIPPrefix + "talkBursts.room"
+	AttrSIPTalkOverlap    = livekit.AttrSIPPrefix + "talkOverlap"
+	AttrSIPLongestSilence = livekit.AttrSIPPrefix + "longestSilence"
+
+	// AttrSIPTagPrefix is a prefix of participant attributes used as call tags, for example "sip.tag.campaignID".
+	// Tags can be set by dispatch rules, header mappings or in the outbound call request.
+	// They are added to logs, metric exemplars and call info, under the name without the prefix.
+	AttrSIPTagPrefix = livekit.AttrSIPPrefix + "tag."
 )
 
+// CallTags returns call tags from participant attributes, keyed by the name without AttrSIPTagPrefix.
+func CallTags(attrs map[string]string) map[string]string {
+	var tags map[string]string
+	for k, v := range attrs {
+		name, ok := strings.CutPrefix(k, AttrSIPTagPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		tags[name] = v
+	}
+	return tags
+}
+
 var headerToLog = map[string]string{
 	"X-Twilio-AccountSid": "twilioAccSID",
 	"X-Twilio-CallSid":    "twilioCallSID",
@@ -82,6 +190,8 @@ func (v CallStatus) DisconnectReason() livekit.DisconnectReason {
 		return livekit.DisconnectReason_USER_UNAVAILABLE
 	case callRejected:
 		return livekit.DisconnectReason_USER_REJECTED
+	case callMigrated:
+		return livekit.DisconnectReason_MIGRATION
 	}
 }
 
@@ -105,4 +215,5 @@ const (
 	callUnavailable
 	callRejected
 	callMediaFailed
+	callMigrated
 )
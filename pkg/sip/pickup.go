@@ -0,0 +1,252 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// Headers of an INVITE identifying an existing dialog to pick up.
+const (
+	headerReplaces     = "Replaces"      // RFC 3891
+	headerJoin         = "Join"          // RFC 3911
+	headerTargetDialog = "Target-Dialog" // RFC 4538
+)
+
+// pickupMode selects what happens with the existing call when a new INVITE picks it up.
+type pickupMode string
+
+const (
+	// pickupReplaces hangs up the existing call once the new call is answered.
+	pickupReplaces = pickupMode("replaces")
+	// pickupJoin adds the new call to the room of the existing call.
+	pickupJoin = pickupMode("join")
+)
+
+// dialogRef identifies a dialog from the perspective of the recipient:
+// ToTag is the local tag of the dialog, FromTag is the remote tag.
+type dialogRef struct {
+	CallID    string
+	ToTag     LocalTag
+	FromTag   RemoteTag
+	EarlyOnly bool
+}
+
+// parseDialogRef parses a dialog reference like "call-id;to-tag=a;from-tag=b".
+// Names of tag parameters differ between headers.
+func parseDialogRef(v, localParam, remoteParam string) (dialogRef, error) {
+	callID, params, _ := strings.Cut(v, ";")
+	ref := dialogRef{CallID: strings.TrimSpace(callID)}
+	if ref.CallID == "" {
+		return dialogRef{}, errors.New("no call id")
+	}
+	for _, p := range strings.Split(params, ";") {
+		name, val, _ := strings.Cut(strings.TrimSpace(p), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case localParam:
+			ref.ToTag = LocalTag(strings.TrimSpace(val))
+		case remoteParam:
+			ref.FromTag = RemoteTag(strings.TrimSpace(val))
+		case "early-only":
+			ref.EarlyOnly = true
+		}
+	}
+	if ref.ToTag == "" || ref.FromTag == "" {
+		return dialogRef{}, fmt.Errorf("no %s or %s", localParam, remoteParam)
+	}
+	return ref, nil
+}
+
+// parsePickup parses Replaces, Join or Target-Dialog header of an INVITE. It returns false if there are none.
+// Target-Dialog alone associates the call with an existing dialog (RFC 4538), and is handled as Join.
+func parsePickup(req *sip.Request) (pickupMode, dialogRef, bool, error) {
+	replaces, join := req.GetHeaders(headerReplaces), req.GetHeaders(headerJoin)
+	var (
+		mode pickupMode
+		h    sip.Header
+	)
+	switch {
+	case len(replaces)+len(join) > 1:
+		return "", dialogRef{}, false, errors.New("multiple Replaces or Join headers")
+	case len(replaces) != 0:
+		mode, h = pickupReplaces, replaces[0]
+	case len(join) != 0:
+		mode, h = pickupJoin, join[0]
+	default:
+		td := req.GetHeaders(headerTargetDialog)
+		if len(td) == 0 {
+			return "", dialogRef{}, false, nil
+		} else if len(td) > 1 {
+			return "", dialogRef{}, false, errors.New("multiple Target-Dialog headers")
+		}
+		// Tags are named from the perspective of the sender.
+		ref, err := parseDialogRef(td[0].Value(), "remote-tag", "local-tag")
+		if err != nil {
+			return "", dialogRef{}, false, fmt.Errorf("invalid Target-Dialog header: %w", err)
+		}
+		return pickupJoin, ref, true, nil
+	}
+	ref, err := parseDialogRef(h.Value(), "to-tag", "from-tag")
+	if err != nil {
+		return "", dialogRef{}, false, fmt.Errorf("invalid %s header: %w", h.Name(), err)
+	}
+	return mode, ref, true, nil
+}
+
+// pickupTarget is an active call which can be joined or replaced by another INVITE.
+type pickupTarget interface {
+	pickupInfo() pickupInfo
+	closeReplaced()
+}
+
+// pickupInfo describes a call which is picked up.
+type pickupInfo struct {
+	CallID    string // LiveKit call ID
+	ProjectID string
+	TrunkID   string
+	RoomName  string
+	State     DialogState
+	Outgoing  bool // the call was initiated by us
+}
+
+// callPickup is an authorized request to join or replace an existing call.
+type callPickup struct {
+	mode   pickupMode
+	target pickupTarget
+	info   pickupInfo
+}
+
+// findPickup matches Replaces, Join or Target-Dialog headers of an INVITE to an active call and checks
+// that the caller is allowed to pick it up. It returns nil if the INVITE has none of these headers.
+// On error, it also returns the status to reject the INVITE with.
+func (s *Server) findPickup(req *sip.Request, auth AuthInfo) (*callPickup, sip.StatusCode, error) {
+	mode, ref, ok, err := parsePickup(req)
+	if err != nil {
+		return nil, sip.StatusBadRequest, err
+	} else if !ok {
+		return nil, 0, nil
+	}
+	var target pickupTarget
+	if c := s.findDialog(ref); c != nil {
+		target = c
+	} else if c := s.cli.findDialog(ref); c != nil {
+		target = c
+	} else {
+		return nil, sip.StatusCallTransactionDoesNotExists, errors.New("no matching dialog")
+	}
+	info := target.pickupInfo()
+	established := info.State == DialogAnswered || info.State == DialogHeld
+	switch {
+	case info.State >= DialogTerminating:
+		return nil, sip.StatusCallTransactionDoesNotExists, errors.New("dialog is terminated")
+	case !established && !info.Outgoing:
+		// Only early dialogs initiated by us can be picked up (RFC 3891, RFC 3911).
+		return nil, sip.StatusCallTransactionDoesNotExists, errors.New("dialog is not established")
+	case established && ref.EarlyOnly:
+		return nil, sip.StatusBusyHere, errors.New("dialog is already established")
+	case info.RoomName == "":
+		return nil, sip.StatusCallTransactionDoesNotExists, errors.New("call is not in a room")
+	}
+	group := s.conf.TrunkPickupGroup(auth.TrunkID)
+	if group == "" || group != s.conf.TrunkPickupGroup(info.TrunkID) || auth.ProjectID != info.ProjectID {
+		return nil, sip.StatusForbidden, errors.New("call pickup is not allowed")
+	}
+	return &callPickup{mode: mode, target: target, info: info}, 0, nil
+}
+
+// dispatch returns a dispatch result which joins the room of the picked up call as a new participant.
+// The call is answered right away, since the room is already active.
+func (p *callPickup) dispatch(cc *sipInbound, trunkID string) CallDispatch {
+	return CallDispatch{
+		Result:    DispatchAccept,
+		ProjectID: p.info.ProjectID,
+		TrunkID:   trunkID,
+		Room: RoomConfig{
+			RoomName: p.info.RoomName,
+			Participant: ParticipantConfig{
+				Identity: "sip_" + string(cc.ID()),
+				Name:     cc.From().User,
+				Attributes: map[string]string{
+					AttrSIPPickup:       string(p.mode),
+					AttrSIPPickupCallID: p.info.CallID,
+				},
+			},
+		},
+		Provisional: config.ProvisionalAnswer,
+	}
+}
+
+// findDialog returns an active inbound call matching the dialog reference.
+func (s *Server) findDialog(ref dialogRef) *inboundCall {
+	s.cmu.RLock()
+	c := s.byLocal[ref.ToTag]
+	s.cmu.RUnlock()
+	if c == nil || c.cc.Tag() != ref.FromTag || c.cc.CallID() != ref.CallID {
+		return nil
+	}
+	return c
+}
+
+// findDialog returns an active outbound call matching the dialog reference.
+func (c *Client) findDialog(ref dialogRef) *outboundCall {
+	if c == nil {
+		return nil
+	}
+	c.cmu.Lock()
+	call := c.activeCalls[ref.ToTag]
+	c.cmu.Unlock()
+	if call == nil || call.cc.Tag() != ref.FromTag || call.cc.CallID() != ref.CallID {
+		return nil
+	}
+	return call
+}
+
+func (c *inboundCall) pickupInfo() pickupInfo {
+	info := pickupInfo{ProjectID: c.projectID}
+	info.State, _ = c.cc.fsm.State()
+	c.state.mu.Lock()
+	info.CallID = c.state.callInfo.GetCallId()
+	info.TrunkID = c.state.callInfo.GetTrunkId()
+	info.RoomName = c.state.callInfo.GetRoomName()
+	c.state.mu.Unlock()
+	return info
+}
+
+// closeReplaced hangs up the call after another call replaced it.
+func (c *inboundCall) closeReplaced() {
+	c.close(false, CallHangup, "replaced")
+}
+
+func (c *outboundCall) pickupInfo() pickupInfo {
+	info := pickupInfo{ProjectID: c.projectID, TrunkID: c.sipConf.trunkID, Outgoing: true}
+	info.State, _ = c.cc.fsm.State()
+	c.state.mu.Lock()
+	info.CallID = c.state.callInfo.GetCallId()
+	info.RoomName = c.state.callInfo.GetRoomName()
+	c.state.mu.Unlock()
+	return info
+}
+
+// closeReplaced hangs up or cancels the call after another call replaced it.
+func (c *outboundCall) closeReplaced() {
+	c.CloseWithReason(CallHangup, "replaced", livekit.DisconnectReason_CLIENT_INITIATED)
+}
@@ -0,0 +1,211 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func newPickupInvite(headers ...sip.Header) *sip.Request {
+	req := sip.NewRequest(sip.INVITE, sip.Uri{User: "pickup", Host: "example.com"})
+	for _, h := range headers {
+		req.AppendHeader(h)
+	}
+	return req
+}
+
+func TestParsePickup(t *testing.T) {
+	cases := []struct {
+		name    string
+		headers []sip.Header
+		mode    pickupMode
+		ref     dialogRef
+		ok      bool
+		err     bool
+	}{
+		{name: "none"},
+		{
+			name:    "replaces",
+			headers: []sip.Header{sip.NewHeader("Replaces", "abc@host;to-tag=SCL_1;from-tag=remote")},
+			mode:    pickupReplaces,
+			ref:     dialogRef{CallID: "abc@host", ToTag: "SCL_1", FromTag: "remote"},
+			ok:      true,
+		},
+		{
+			name:    "replaces early",
+			headers: []sip.Header{sip.NewHeader("Replaces", "abc; From-Tag=remote ;to-tag=SCL_1;early-only")},
+			mode:    pickupReplaces,
+			ref:     dialogRef{CallID: "abc", ToTag: "SCL_1", FromTag: "remote", EarlyOnly: true},
+			ok:      true,
+		},
+		{
+			name:    "join",
+			headers: []sip.Header{sip.NewHeader("Join", "abc;to-tag=SCL_1;from-tag=remote")},
+			mode:    pickupJoin,
+			ref:     dialogRef{CallID: "abc", ToTag: "SCL_1", FromTag: "remote"},
+			ok:      true,
+		},
+		{
+			name:    "target dialog",
+			headers: []sip.Header{sip.NewHeader("Target-Dialog", "abc;local-tag=remote;remote-tag=SCL_1")},
+			mode:    pickupJoin,
+			ref:     dialogRef{CallID: "abc", ToTag: "SCL_1", FromTag: "remote"},
+			ok:      true,
+		},
+		{
+			name: "join with target dialog",
+			headers: []sip.Header{
+				sip.NewHeader("Join", "abc;to-tag=SCL_1;from-tag=remote"),
+				sip.NewHeader("Target-Dialog", "def;local-tag=a;remote-tag=b"),
+			},
+			mode: pickupJoin,
+			ref:  dialogRef{CallID: "abc", ToTag: "SCL_1", FromTag: "remote"},
+			ok:   true,
+		},
+		{
+			name: "replaces and join",
+			headers: []sip.Header{
+				sip.NewHeader("Replaces", "abc;to-tag=SCL_1;from-tag=remote"),
+				sip.NewHeader("Join", "abc;to-tag=SCL_1;from-tag=remote"),
+			},
+			err: true,
+		},
+		{
+			name:    "no tags",
+			headers: []sip.Header{sip.NewHeader("Replaces", "abc;to-tag=SCL_1")},
+			err:     true,
+		},
+		{
+			name:    "no call id",
+			headers: []sip.Header{sip.NewHeader("Join", ";to-tag=SCL_1;from-tag=remote")},
+			err:     true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mode, ref, ok, err := parsePickup(newPickupInvite(c.headers...))
+			if c.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.ok, ok)
+			require.Equal(t, c.mode, mode)
+			require.Equal(t, c.ref, ref)
+		})
+	}
+}
+
+func TestFindPickup(t *testing.T) {
+	conf := &config.Config{Trunks: map[string]*config.TrunkConfig{
+		"in":    {PickupGroup: "desk"},
+		"out":   {PickupGroup: "desk"},
+		"other": {PickupGroup: "lobby"},
+	}}
+	s := NewServer("", conf, nil, nil, nil)
+	s.cli = NewClient("", conf, nil, nil, nil)
+
+	log := logger.GetLogger()
+	in := &inboundCall{
+		cc: &sipInbound{
+			id:     "SCL_in",
+			tag:    "remote-in",
+			callID: "call-in",
+			fsm:    newCallFSM(log, nil, DialogAnswered),
+		},
+		projectID: "p1",
+		state:     NewCallState(nil, &livekit.SIPCallInfo{CallId: "SCL_in", TrunkId: "in", RoomName: "room-in"}),
+	}
+	s.byLocal[in.cc.ID()] = in
+	out := &outboundCall{
+		cc: &sipOutbound{
+			id:     "SCL_out",
+			tag:    "remote-out",
+			callID: "call-out",
+			fsm:    newCallFSM(log, nil, DialogEarly),
+		},
+		projectID: "p1",
+		sipConf:   sipOutboundConfig{trunkID: "out"},
+		state:     NewCallState(nil, &livekit.SIPCallInfo{CallId: "SCL_out", RoomName: "room-out"}),
+	}
+	s.cli.activeCalls[out.cc.ID()] = out
+
+	auth := AuthInfo{ProjectID: "p1", TrunkID: "in"}
+	find := func(auth AuthInfo, name, value string) (*callPickup, sip.StatusCode, error) {
+		return s.findPickup(newPickupInvite(sip.NewHeader(name, value)), auth)
+	}
+
+	p, code, err := s.findPickup(newPickupInvite(), auth)
+	require.NoError(t, err)
+	require.Zero(t, code)
+	require.Nil(t, p)
+
+	p, _, err = find(auth, "Replaces", "call-in;to-tag=SCL_in;from-tag=remote-in")
+	require.NoError(t, err)
+	require.Equal(t, pickupReplaces, p.mode)
+	require.Equal(t, pickupInfo{CallID: "SCL_in", ProjectID: "p1", TrunkID: "in", RoomName: "room-in", State: DialogAnswered}, p.info)
+
+	disp := p.dispatch(&sipInbound{id: "SCL_new", from: &sip.FromHeader{Address: sip.Uri{User: "1001"}}}, "in")
+	require.Equal(t, DispatchAccept, disp.Result)
+	require.Equal(t, "room-in", disp.Room.RoomName)
+	require.Equal(t, "sip_SCL_new", disp.Room.Participant.Identity)
+	require.Equal(t, map[string]string{
+		AttrSIPPickup:       "replaces",
+		AttrSIPPickupCallID: "SCL_in",
+	}, disp.Room.Participant.Attributes)
+
+	// Ringing outbound calls can be picked up.
+	p, _, err = find(auth, "Replaces", "call-out;to-tag=SCL_out;from-tag=remote-out;early-only")
+	require.NoError(t, err)
+	require.Equal(t, "room-out", p.info.RoomName)
+	require.True(t, p.info.Outgoing)
+
+	p, _, err = find(auth, "Target-Dialog", "call-in;local-tag=remote-in;remote-tag=SCL_in")
+	require.NoError(t, err)
+	require.Equal(t, pickupJoin, p.mode)
+
+	for _, c := range []struct {
+		auth  AuthInfo
+		name  string
+		value string
+		code  sip.StatusCode
+	}{
+		{auth, "Replaces", "call-in;to-tag=SCL_in", sip.StatusBadRequest},
+		{auth, "Replaces", "call-in;to-tag=SCL_in;from-tag=wrong", sip.StatusCallTransactionDoesNotExists},
+		{auth, "Join", "wrong;to-tag=SCL_in;from-tag=remote-in", sip.StatusCallTransactionDoesNotExists},
+		{auth, "Replaces", "call-in;to-tag=SCL_in;from-tag=remote-in;early-only", sip.StatusBusyHere},
+		{AuthInfo{ProjectID: "p1", TrunkID: "other"}, "Join", "call-in;to-tag=SCL_in;from-tag=remote-in", sip.StatusForbidden},
+		{AuthInfo{ProjectID: "p1", TrunkID: "none"}, "Join", "call-in;to-tag=SCL_in;from-tag=remote-in", sip.StatusForbidden},
+		{AuthInfo{ProjectID: "p2", TrunkID: "in"}, "Join", "call-in;to-tag=SCL_in;from-tag=remote-in", sip.StatusForbidden},
+	} {
+		_, code, err = find(c.auth, c.name, c.value)
+		require.Error(t, err, c.value)
+		require.Equal(t, c.code, code, c.value)
+	}
+
+	// Inbound calls which are not answered yet cannot be picked up.
+	in.cc.fsm = newCallFSM(log, nil, DialogEarly)
+	_, code, err = find(auth, "Replaces", "call-in;to-tag=SCL_in;from-tag=remote-in")
+	require.Error(t, err)
+	require.Equal(t, sip.StatusCallTransactionDoesNotExists, code)
+}
@@ -0,0 +1,368 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+// PortPoolShared is the name of the port pool that is shared by all trunks without a reservation.
+const PortPoolShared = "shared"
+
+// warmDrainTimeout is how long we wait for packets queued on a pre-bound port before handing it to a call.
+const warmDrainTimeout = time.Millisecond
+
+type portPool struct {
+	name  string
+	ports []int
+	used  map[int]struct{}
+	next  int
+}
+
+func newPortPool(name string, ports []int) *portPool {
+	p := &portPool{
+		name:  name,
+		ports: ports,
+		used:  make(map[int]struct{}),
+	}
+	if len(ports) != 0 {
+		p.next = rand.IntN(len(ports))
+	}
+	return p
+}
+
+func (p *portPool) available() bool {
+	return len(p.used) < len(p.ports)
+}
+
+// PortAllocator tracks media ports used by all calls on this node.
+//
+// Ports from the configured range are split into a shared pool and optional per-trunk reserved pools.
+// Calls for a trunk with a reservation use the reserved pool first and fall back to the shared pool.
+//
+// Optionally, a few ports of the shared pool are bound in advance (see Prewarm), so that calls don't wait for
+// socket allocation under bursty load. Pre-bound ports are refilled in the background.
+// Only sockets are pre-bound: MediaPort and the SDP answer still depend on the offer and are created per call.
+//
+// Ports are claimed with the lock held, but bound without it, so that calls don't wait for each other's syscalls.
+type PortAllocator struct {
+	ip  netip.Addr
+	mon *stats.Monitor
+
+	mu       sync.Mutex
+	shared   *portPool
+	reserved map[string]*portPool
+	warm     []*allocatedConn
+	warmSize int
+	filling  bool
+	closed   bool
+}
+
+// NewPortAllocator creates an allocator for a port range. Reserved sub-ranges are keyed by trunk ID
+// and must be within the main range and must not overlap.
+func NewPortAllocator(mon *stats.Monitor, ports rtcconfig.PortRange, reserved map[string]rtcconfig.PortRange) (*PortAllocator, error) {
+	start, end := ports.Start, ports.End
+	if start <= 0 {
+		start = 1
+	}
+	if end <= 0 {
+		end = 0xFFFF
+	}
+	if start > end {
+		return nil, fmt.Errorf("invalid RTP port range: %d-%d", start, end)
+	}
+	owner := make(map[int]string)
+	a := &PortAllocator{
+		ip:       netip.AddrFrom4([4]byte{0, 0, 0, 0}),
+		mon:      mon,
+		reserved: make(map[string]*portPool, len(reserved)),
+	}
+	for trunkID, r := range reserved {
+		if r.Start < start || r.End > end || r.Start > r.End {
+			return nil, fmt.Errorf("reserved RTP port range %d-%d for trunk %q is outside of %d-%d", r.Start, r.End, trunkID, start, end)
+		}
+		list := make([]int, 0, r.End-r.Start+1)
+		for port := r.Start; port <= r.End; port++ {
+			if prev, ok := owner[port]; ok {
+				return nil, fmt.Errorf("reserved RTP port ranges for trunks %q and %q overlap", prev, trunkID)
+			}
+			owner[port] = trunkID
+			list = append(list, port)
+		}
+		a.reserved[trunkID] = newPortPool(trunkID, list)
+	}
+	list := make([]int, 0, end-start+1-len(owner))
+	for port := start; port <= end; port++ {
+		if _, ok := owner[port]; !ok {
+			list = append(list, port)
+		}
+	}
+	a.shared = newPortPool(PortPoolShared, list)
+	return a, nil
+}
+
+// pools returns port pools that can be used by a given trunk, in order of preference.
+func (a *PortAllocator) pools(trunkID string) []*portPool {
+	if p := a.reserved[trunkID]; p != nil && trunkID != "" {
+		return []*portPool{p, a.shared}
+	}
+	return []*portPool{a.shared}
+}
+
+// Unallocated checks if there are media ports for a given trunk which are not allocated by this service.
+// It doesn't check if they can be bound: ports bound by other processes are skipped by Listen,
+// so the call may still fail with ErrMediaPortsExhausted on hosts where the range is shared.
+func (a *PortAllocator) Unallocated(trunkID string) bool {
+	if a == nil {
+		return true
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, p := range a.pools(trunkID) {
+		if p.available() || (p == a.shared && len(a.warm) != 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// Listen allocates and binds a media port for a given trunk. Port is released when the connection is closed.
+func (a *PortAllocator) Listen(trunkID string) (UDPConn, error) {
+	if c := a.takeWarm(trunkID); c != nil {
+		drainUDP(c.UDPConn)
+		return c, nil
+	}
+	for _, p := range a.pools(trunkID) {
+		if p == a.shared {
+			a.mu.Lock()
+			warm := a.warmSize > 0
+			a.mu.Unlock()
+			if warm {
+				a.mon.MediaPortWarmRequest(false)
+			}
+		}
+		if c := a.bind(p); c != nil {
+			a.mu.Lock()
+			a.report(p)
+			a.mu.Unlock()
+			return c, nil
+		}
+	}
+	// The last free port may have been bound in the background meanwhile.
+	if c := a.takeWarm(trunkID); c != nil {
+		drainUDP(c.UDPConn)
+		return c, nil
+	}
+	a.mon.MediaPortsExhausted()
+	return nil, siperrors.ErrMediaPortsExhausted
+}
+
+// bind claims a free port of the pool and binds it, or returns nil if there are none. Must be called without the lock.
+func (a *PortAllocator) bind(p *portPool) *allocatedConn {
+	a.mu.Lock()
+	n := len(p.ports) - len(p.used)
+	a.mu.Unlock()
+	for range n {
+		a.mu.Lock()
+		port, ok := a.nextFree(p)
+		if ok {
+			p.used[port] = struct{}{}
+		}
+		a.mu.Unlock()
+		if !ok {
+			return nil
+		}
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: a.ip.AsSlice(), Port: port})
+		if err == nil {
+			return &allocatedConn{UDPConn: conn, a: a, pool: p, port: port}
+		}
+		// Used by something else.
+		a.mu.Lock()
+		delete(p.used, port)
+		a.mu.Unlock()
+	}
+	return nil
+}
+
+// nextFree returns the next port in the pool that is not tracked as used. Must be called with the lock.
+func (a *PortAllocator) nextFree(p *portPool) (int, bool) {
+	for range len(p.ports) {
+		port := p.ports[p.next]
+		p.next = (p.next + 1) % len(p.ports)
+		if _, used := p.used[port]; !used {
+			return port, true
+		}
+	}
+	return 0, false
+}
+
+func (a *PortAllocator) release(p *portPool, port int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(p.used, port)
+	a.report(p)
+	if p == a.shared {
+		a.refill()
+	}
+}
+
+// Must be called with the lock.
+func (a *PortAllocator) report(p *portPool) {
+	used := len(p.used)
+	if p == a.shared {
+		used -= len(a.warm) // pre-bound ports are still available for calls
+	}
+	a.mon.MediaPorts(p.name, used, len(p.ports))
+}
+
+// Prewarm keeps n ports of the shared pool bound in advance. Zero disables it.
+func (a *PortAllocator) Prewarm(n int) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.warmSize = max(n, 0)
+	for len(a.warm) > a.warmSize {
+		c := a.warm[len(a.warm)-1]
+		a.warm = a.warm[:len(a.warm)-1]
+		c.closeLocked()
+	}
+	a.refill()
+}
+
+// Close releases all pre-bound ports and stops refilling them. Ports used by calls are not affected.
+func (a *PortAllocator) Close() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closed = true
+	for _, c := range a.warm {
+		c.closeLocked()
+	}
+	a.warm = nil
+	a.report(a.shared)
+	a.mon.MediaPortsWarm(0)
+}
+
+// takeWarm returns a pre-bound port for a given trunk, or nil if there are none.
+// Trunks with a reservation only get one when their reserved pool is exhausted.
+func (a *PortAllocator) takeWarm(trunkID string) *allocatedConn {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.warm) == 0 {
+		return nil
+	}
+	for _, p := range a.pools(trunkID) {
+		if p != a.shared && p.available() {
+			return nil
+		}
+	}
+	c := a.warm[0]
+	a.warm = a.warm[1:]
+	a.mon.MediaPortWarmRequest(true)
+	a.mon.MediaPortsWarm(len(a.warm))
+	a.report(a.shared)
+	a.refill()
+	return c
+}
+
+// refill starts binding pre-bound ports in the background, if needed. Must be called with the lock.
+func (a *PortAllocator) refill() {
+	if a.filling || a.closed || len(a.warm) >= a.warmSize {
+		return
+	}
+	a.filling = true
+	go a.fill()
+}
+
+func (a *PortAllocator) fill() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for !a.closed && len(a.warm) < a.warmSize {
+		a.mu.Unlock()
+		c := a.bind(a.shared)
+		a.mu.Lock()
+		if c == nil {
+			break
+		}
+		if a.closed || len(a.warm) >= a.warmSize {
+			c.closeLocked()
+			break
+		}
+		a.warm = append(a.warm, c)
+		a.mon.MediaPortsWarm(len(a.warm))
+	}
+	a.filling = false
+	a.mon.MediaPortsWarm(len(a.warm))
+}
+
+// drainUDP drops packets received on a pre-bound port before it was handed to the call.
+func drainUDP(conn *net.UDPConn) {
+	var buf [1]byte
+	_ = conn.SetReadDeadline(time.Now().Add(warmDrainTimeout))
+	for {
+		if _, _, err := conn.ReadFromUDPAddrPort(buf[:]); err != nil {
+			break
+		}
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+}
+
+// ReportUsage updates utilization metrics for all port pools.
+func (a *PortAllocator) ReportUsage() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.report(a.shared)
+	for _, p := range a.reserved {
+		a.report(p)
+	}
+}
+
+type allocatedConn struct {
+	*net.UDPConn
+	a    *PortAllocator
+	pool *portPool
+	port int
+	once sync.Once
+}
+
+func (c *allocatedConn) Close() error {
+	err := c.UDPConn.Close()
+	c.once.Do(func() {
+		c.a.release(c.pool, c.port)
+	})
+	return err
+}
+
+// closeLocked closes a pre-bound port. Must be called with the allocator lock.
+func (c *allocatedConn) closeLocked() {
+	_ = c.UDPConn.Close()
+	c.once.Do(func() {
+		delete(c.pool.used, c.port)
+	})
+}
@@ -0,0 +1,177 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	"github.com/stretchr/testify/require"
+
+	siperrors "github.com/livekit/sip/pkg/errors"
+)
+
+func TestPortAllocator(t *testing.T) {
+	_, err := NewPortAllocator(nil, rtcconfig.PortRange{Start: 30200, End: 30203}, map[string]rtcconfig.PortRange{
+		"a": {Start: 30200, End: 30201},
+		"b": {Start: 30201, End: 30202},
+	})
+	require.Error(t, err, "overlapping reservations")
+
+	a, err := NewPortAllocator(nil, rtcconfig.PortRange{Start: 30200, End: 30203}, map[string]rtcconfig.PortRange{
+		"a": {Start: 30200, End: 30201},
+	})
+	require.NoError(t, err)
+
+	port := func(c UDPConn) int {
+		return c.LocalAddr().(*net.UDPAddr).Port
+	}
+
+	// Shared pool is not allowed to use reserved ports.
+	s1, err := a.Listen("")
+	require.NoError(t, err)
+	defer s1.Close()
+	s2, err := a.Listen("b")
+	require.NoError(t, err)
+	defer s2.Close()
+	require.ElementsMatch(t, []int{30202, 30203}, []int{port(s1), port(s2)})
+	require.False(t, a.Unallocated(""))
+	_, err = a.Listen("")
+	require.ErrorIs(t, err, siperrors.ErrMediaPortsExhausted)
+
+	// Reserved trunk can still get ports.
+	require.True(t, a.Unallocated("a"))
+	r1, err := a.Listen("a")
+	require.NoError(t, err)
+	r2, err := a.Listen("a")
+	require.NoError(t, err)
+	defer r2.Close()
+	require.ElementsMatch(t, []int{30200, 30201}, []int{port(r1), port(r2)})
+	require.False(t, a.Unallocated("a"))
+
+	// Closing the connection returns the port.
+	require.NoError(t, r1.Close())
+	_ = r1.Close() // must not release the port twice
+	require.True(t, a.Unallocated("a"))
+	require.False(t, a.Unallocated(""))
+	require.NoError(t, s1.Close())
+	require.True(t, a.Unallocated(""))
+}
+
+func TestPortAllocatorPrewarm(t *testing.T) {
+	a, err := NewPortAllocator(nil, rtcconfig.PortRange{Start: 30210, End: 30213}, map[string]rtcconfig.PortRange{
+		"a": {Start: 30210, End: 30210},
+	})
+	require.NoError(t, err)
+	defer a.Close()
+
+	warm := func() []int {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		var ports []int
+		for _, c := range a.warm {
+			ports = append(ports, c.port)
+		}
+		return ports
+	}
+	a.Prewarm(2)
+	require.Eventually(t, func() bool { return len(warm()) == 2 }, time.Second, time.Millisecond)
+	require.NotContains(t, warm(), 30210, "reserved ports must not be pre-bound")
+
+	// Packets received before the call must be dropped.
+	ports := warm()
+	cli, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: ports[0]})
+	require.NoError(t, err)
+	defer cli.Close()
+	_, err = cli.Write([]byte("stale"))
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+
+	c1, err := a.Listen("")
+	require.NoError(t, err)
+	defer c1.Close()
+	require.Equal(t, ports[0], c1.LocalAddr().(*net.UDPAddr).Port)
+	require.NoError(t, c1.SetReadDeadline(time.Now().Add(10*time.Millisecond)))
+	_, err = c1.Read(make([]byte, 16))
+	require.Error(t, err, "expected stale packet to be drained")
+
+	// Only one free port is left for refill.
+	require.Eventually(t, func() bool { return len(warm()) == 2 }, time.Second, time.Millisecond)
+	require.NotContains(t, warm(), ports[0])
+
+	// Reserved pool is used first.
+	r1, err := a.Listen("a")
+	require.NoError(t, err)
+	defer r1.Close()
+	require.Equal(t, 30210, r1.LocalAddr().(*net.UDPAddr).Port)
+	require.Len(t, warm(), 2)
+
+	c2, err := a.Listen("a")
+	require.NoError(t, err)
+	defer c2.Close()
+	c3, err := a.Listen("")
+	require.NoError(t, err)
+	require.Empty(t, warm())
+	require.False(t, a.Unallocated(""))
+	_, err = a.Listen("")
+	require.ErrorIs(t, err, siperrors.ErrMediaPortsExhausted)
+
+	// Released ports are pre-bound again.
+	require.NoError(t, c3.Close())
+	require.Eventually(t, func() bool { return len(warm()) == 1 }, time.Second, time.Millisecond)
+	require.True(t, a.Unallocated(""))
+	c4, err := a.Listen("")
+	require.NoError(t, err)
+	defer c4.Close()
+	require.Empty(t, warm())
+
+	a.Close()
+	require.Empty(t, warm())
+}
+
+func TestPortAllocatorConcurrent(t *testing.T) {
+	a, err := NewPortAllocator(nil, rtcconfig.PortRange{Start: 30220, End: 30235}, nil)
+	require.NoError(t, err)
+	defer a.Close()
+	a.Prewarm(4)
+
+	// Calls and the background refill bind ports concurrently, but never the same one.
+	// There are enough ports for all calls and pre-bound ports.
+	conns := make(chan UDPConn, 12)
+	var wg sync.WaitGroup
+	for range 12 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := a.Listen("")
+			if err == nil {
+				conns <- c
+			}
+		}()
+	}
+	wg.Wait()
+	close(conns)
+	ports := make(map[int]struct{})
+	for c := range conns {
+		port := c.LocalAddr().(*net.UDPAddr).Port
+		require.NotContains(t, ports, port)
+		ports[port] = struct{}{}
+		require.NoError(t, c.Close())
+	}
+	require.Len(t, ports, 12)
+}
@@ -0,0 +1,237 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	// portMapRetry is the delay before retrying a failed gateway discovery or mapping.
+	portMapRetry = time.Minute
+	// portMapTimeout limits discovery, and mapping or unmapping of all ports.
+	portMapTimeout = 2 * time.Minute
+)
+
+// portMapping is a port forwarded from the gateway to this host.
+type portMapping struct {
+	proto    string // UDP or TCP
+	internal int
+	external int
+}
+
+// portMapper creates port mappings on a NAT gateway.
+type portMapper interface {
+	Name() string
+	ExternalIP(ctx context.Context) (netip.Addr, error)
+	// Map creates or renews the mapping and returns the external port assigned by the gateway.
+	Map(ctx context.Context, m portMapping, lifetime time.Duration) (int, error)
+	Unmap(ctx context.Context, m portMapping) error
+}
+
+// portMappings maps SIP and RTP ports on the NAT gateway, renews mappings and removes them on shutdown.
+type portMappings struct {
+	log      logger.Logger
+	lifetime time.Duration
+	ports    []portMapping
+	discover func(ctx context.Context) (portMapper, error)
+	stop     core.Fuse
+	done     chan struct{}
+
+	mu     sync.Mutex
+	mapper portMapper
+	mapped []portMapping
+}
+
+func newPortMappings(log logger.Logger, conf *config.Config, sconf *ServiceConfig) *portMappings {
+	pc := conf.PortMapping
+	if pc == nil {
+		return nil
+	}
+	p := &portMappings{
+		log:      log,
+		lifetime: pc.Lifetime,
+		done:     make(chan struct{}),
+		discover: func(ctx context.Context) (portMapper, error) {
+			return discoverPortMapper(ctx, pc, sconf.SignalingIPLocal)
+		},
+	}
+	if p.lifetime == 0 {
+		p.lifetime = config.DefaultPortMappingLifetime
+	}
+	if conf.SIPPort != 0 {
+		p.ports = append(p.ports,
+			portMapping{proto: "UDP", internal: conf.SIPPortListen, external: conf.SIPPort},
+			portMapping{proto: "TCP", internal: conf.SIPPortListen, external: conf.SIPPort},
+		)
+	}
+	if tc := conf.TLS; tc != nil && tc.Port != 0 {
+		p.ports = append(p.ports, portMapping{proto: "TCP", internal: tc.ListenPort, external: tc.Port})
+	}
+	for port := conf.RTPPort.Start; port <= conf.RTPPort.End; port++ {
+		p.ports = append(p.ports, portMapping{proto: "UDP", internal: port, external: port})
+	}
+	return p
+}
+
+// discoverPortMapper finds a gateway supporting the configured protocol. NAT-PMP is preferred if both are allowed.
+func discoverPortMapper(ctx context.Context, conf *config.PortMappingConfig, local netip.Addr) (portMapper, error) {
+	var errs []error
+	if conf.Protocol != "upnp" {
+		c, err := newNATPMP(conf.Gateway)
+		if err == nil {
+			_, err = c.ExternalIP(ctx)
+		}
+		if err == nil {
+			return c, nil
+		}
+		errs = append(errs, err)
+	}
+	if conf.Protocol != "natpmp" {
+		c, err := discoverUPnP(ctx, local)
+		if err == nil {
+			return c, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Start maps ports in the background until Stop is called.
+func (p *portMappings) Start() {
+	if p == nil {
+		return
+	}
+	go p.run()
+}
+
+// Stop stops renewals and removes mappings from the gateway.
+func (p *portMappings) Stop() {
+	if p == nil {
+		return
+	}
+	p.stop.Break()
+	<-p.done
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.mapper == nil || len(p.mapped) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), portMapTimeout)
+	defer cancel()
+	var failed int
+	for _, m := range p.mapped {
+		if err := p.mapper.Unmap(ctx, m); err != nil {
+			if failed == 0 {
+				p.log.Warnw("cannot remove port mapping", err, "proto", m.proto, "port", m.external)
+			}
+			failed++
+		}
+	}
+	p.log.Infow("port mappings removed", "gateway", p.mapper.Name(), "count", len(p.mapped)-failed, "failed", failed)
+	p.mapped = nil
+}
+
+func (p *portMappings) run() {
+	defer close(p.done)
+	for {
+		delay := p.lifetime / 2
+		if !p.update() {
+			delay = portMapRetry
+		}
+		select {
+		case <-p.stop.Watch():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// update discovers the gateway if necessary, and creates or renews all mappings.
+// It reports false if mappings must be retried sooner than the regular renewal.
+func (p *portMappings) update() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), portMapTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop.Watch():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.mapper == nil {
+		mapper, err := p.discover(ctx)
+		if err != nil {
+			p.log.Errorw("cannot find a gateway for port mapping", err,
+				"hint", "enable NAT-PMP or UPnP on the router, or forward SIP and RTP ports manually",
+				"retry", portMapRetry)
+			return false
+		}
+		p.mapper = mapper
+		p.log.Infow("found port mapping gateway", "gateway", mapper.Name())
+	}
+	var (
+		mapped  []portMapping
+		lastErr error
+		moved   int
+	)
+	for _, m := range p.ports {
+		ext, err := p.mapper.Map(ctx, m, p.lifetime)
+		if err != nil {
+			if lastErr == nil {
+				p.log.Warnw("cannot map port", err, "gateway", p.mapper.Name(), "proto", m.proto, "port", m.external)
+			}
+			lastErr = err
+			continue
+		}
+		if ext != m.external {
+			// Other hosts cannot reach this port, since SIP and SDP advertise the configured one.
+			if moved == 0 {
+				p.log.Warnw("gateway assigned a different external port", nil,
+					"gateway", p.mapper.Name(), "proto", m.proto, "port", m.external, "assigned", ext)
+			}
+			moved++
+			m.external = ext
+		}
+		mapped = append(mapped, m)
+	}
+	p.mapped = mapped
+	if len(mapped) == 0 {
+		// The gateway may have changed or disabled mappings, discover it again.
+		p.log.Errorw("port mapping failed", lastErr, "gateway", p.mapper.Name(),
+			"hint", "check that the router allows port mapping requests from this host, or forward ports manually",
+			"retry", portMapRetry)
+		p.mapper = nil
+		return false
+	}
+	ip, err := p.mapper.ExternalIP(ctx)
+	if err != nil {
+		p.log.Warnw("cannot get external IP of the gateway", err, "gateway", p.mapper.Name())
+	}
+	p.log.Infow("ports mapped", "gateway", p.mapper.Name(), "externalIP", ip,
+		"mapped", len(mapped), "failed", len(p.ports)-len(mapped), "reassigned", moved, "lifetime", p.lifetime)
+	return lastErr == nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// natPMPPort is the port of NAT-PMP gateways, see RFC 6886.
+	natPMPPort = 5351
+	// natPMPRetries is the number of requests sent before giving up. Timeouts start at 250ms and double,
+	// as recommended by the RFC, but the RFC's 9 attempts would take more than a minute.
+	natPMPRetries = 4
+)
+
+// natPMPResults describes NAT-PMP result codes.
+var natPMPResults = map[uint16]string{
+	1: "unsupported version",
+	2: "not authorized or refused",
+	3: "network failure",
+	4: "out of resources",
+	5: "unsupported opcode",
+}
+
+// procNetRoute is the routing table of the host, used to find the default gateway.
+var procNetRoute = "/proc/net/route"
+
+// natPMP maps ports with NAT-PMP (RFC 6886).
+type natPMP struct {
+	addr string // gateway host:port
+}
+
+func newNATPMP(gateway string) (*natPMP, error) {
+	ip, err := netip.ParseAddr(gateway)
+	if gateway == "" {
+		ip, err = defaultGateway()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &natPMP{addr: netip.AddrPortFrom(ip, natPMPPort).String()}, nil
+}
+
+func (c *natPMP) Name() string {
+	return "NAT-PMP"
+}
+
+// request sends the request to the gateway and returns a successful response.
+func (c *natPMP) request(ctx context.Context, req []byte, respLen int) ([]byte, error) {
+	conn, err := net.Dial("udp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	buf := make([]byte, 16)
+	timeout := 250 * time.Millisecond
+	for i := 0; i < natPMPRetries; i++ {
+		if _, err = conn.Write(req); err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		_ = conn.SetReadDeadline(deadline)
+		n, err := conn.Read(buf)
+		var nerr net.Error
+		if errors.As(err, &nerr) && nerr.Timeout() {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			timeout *= 2
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("gateway %s doesn't support NAT-PMP: %w", c.addr, err)
+		}
+		if n < respLen || buf[0] != 0 || buf[1] != req[1]|0x80 {
+			return nil, fmt.Errorf("invalid NAT-PMP response from %s", c.addr)
+		}
+		if code := binary.BigEndian.Uint16(buf[2:4]); code != 0 {
+			reason := natPMPResults[code]
+			if reason == "" {
+				reason = "result code " + strconv.Itoa(int(code))
+			}
+			return nil, fmt.Errorf("NAT-PMP gateway %s: %s", c.addr, reason)
+		}
+		return buf[:n], nil
+	}
+	return nil, fmt.Errorf("no NAT-PMP response from gateway %s", c.addr)
+}
+
+func (c *natPMP) ExternalIP(ctx context.Context) (netip.Addr, error) {
+	resp, err := c.request(ctx, []byte{0, 0}, 12)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return netip.AddrFrom4([4]byte(resp[8:12])), nil
+}
+
+func (c *natPMP) mapPort(ctx context.Context, m portMapping, external int, lifetime time.Duration) (int, error) {
+	op := byte(1)
+	if m.proto == "TCP" {
+		op = 2
+	}
+	req := make([]byte, 12)
+	req[1] = op
+	binary.BigEndian.PutUint16(req[4:], uint16(m.internal))
+	binary.BigEndian.PutUint16(req[6:], uint16(external))
+	binary.BigEndian.PutUint32(req[8:], uint32(lifetime/time.Second))
+	resp, err := c.request(ctx, req, 16)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint16(resp[10:12])), nil
+}
+
+func (c *natPMP) Map(ctx context.Context, m portMapping, lifetime time.Duration) (int, error) {
+	return c.mapPort(ctx, m, m.external, lifetime)
+}
+
+func (c *natPMP) Unmap(ctx context.Context, m portMapping) error {
+	_, err := c.mapPort(ctx, m, 0, 0)
+	return err
+}
+
+// defaultGateway returns the gateway of the default IPv4 route.
+func defaultGateway() (netip.Addr, error) {
+	f, err := os.Open(procNetRoute)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("cannot find the default gateway, set it in the config: %w", err)
+	}
+	defer f.Close()
+	return parseDefaultGateway(f)
+}
+
+// parseDefaultGateway finds the default gateway in a Linux routing table, where addresses are hex
+// in host byte order, which is little-endian on supported platforms.
+func parseDefaultGateway(r io.Reader) (netip.Addr, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		b, err := hex.DecodeString(fields[2])
+		if err != nil || len(b) != 4 {
+			continue
+		}
+		ip := netip.AddrFrom4([4]byte(binary.BigEndian.AppendUint32(nil, binary.LittleEndian.Uint32(b))))
+		if ip.IsUnspecified() {
+			continue
+		}
+		return ip, nil
+	}
+	if err := s.Err(); err != nil {
+		return netip.Addr{}, err
+	}
+	return netip.Addr{}, errors.New("no default gateway, set it in the config")
+}
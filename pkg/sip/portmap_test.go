@@ -0,0 +1,335 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestPortMappingConfig(t *testing.T) {
+	rtp := rtcconfig.PortRange{Start: 10000, End: 10099}
+	require.NoError(t, (&config.PortMappingConfig{}).Validate(rtp))
+	require.NoError(t, (&config.PortMappingConfig{Protocol: "natpmp", Gateway: "192.168.1.1", Lifetime: time.Hour}).Validate(rtp))
+	require.NoError(t, (&config.PortMappingConfig{Protocol: "upnp"}).Validate(rtp))
+	require.Error(t, (&config.PortMappingConfig{Protocol: "pcp"}).Validate(rtp))
+	require.Error(t, (&config.PortMappingConfig{Gateway: "router.local"}).Validate(rtp))
+	require.Error(t, (&config.PortMappingConfig{Lifetime: time.Second}).Validate(rtp))
+	require.Error(t, (&config.PortMappingConfig{Lifetime: -time.Hour}).Validate(rtp))
+	// Each RTP port is mapped separately, so the default range is too large.
+	require.Error(t, (&config.PortMappingConfig{}).Validate(config.DefaultRTPPortRange))
+}
+
+func TestParseDefaultGateway(t *testing.T) {
+	const table = "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth0\t0000A8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n" +
+		"eth0\t00000000\t0101A8C0\t0003\t0\t0\t0\t00000000\t0\t0\t0\n"
+	ip, err := parseDefaultGateway(strings.NewReader(table))
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParseAddr("192.168.1.1"), ip)
+
+	_, err = parseDefaultGateway(strings.NewReader(table[:strings.LastIndex(table, "eth0")]))
+	require.Error(t, err)
+}
+
+// newFakeNATPMP starts a NAT-PMP gateway which assigns external ports shifted by offset,
+// and refuses mappings of the refused port.
+func newFakeNATPMP(t testing.TB, offset, refused int) *natPMP {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < 2 {
+				continue
+			}
+			op := buf[1]
+			resp := make([]byte, 16)
+			resp[1] = op | 0x80
+			switch op {
+			case 0:
+				copy(resp[8:12], []byte{203, 0, 113, 7})
+				resp = resp[:12]
+			case 1, 2:
+				internal := binary.BigEndian.Uint16(buf[4:6])
+				external := binary.BigEndian.Uint16(buf[6:8])
+				copy(resp[8:10], buf[4:6])
+				if external != 0 {
+					external += uint16(offset)
+				}
+				binary.BigEndian.PutUint16(resp[10:12], external)
+				copy(resp[12:16], buf[8:12])
+				if int(internal) == refused {
+					binary.BigEndian.PutUint16(resp[2:4], 2)
+				}
+			default:
+				binary.BigEndian.PutUint16(resp[2:4], 5)
+			}
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+	return &natPMP{addr: conn.LocalAddr().String()}
+}
+
+func TestNATPMP(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeNATPMP(t, 0, 5070)
+	ip, err := c.ExternalIP(ctx)
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParseAddr("203.0.113.7"), ip)
+
+	ext, err := c.Map(ctx, portMapping{proto: "UDP", internal: 10000, external: 10000}, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 10000, ext)
+	require.NoError(t, c.Unmap(ctx, portMapping{proto: "UDP", internal: 10000, external: 10000}))
+
+	_, err = c.Map(ctx, portMapping{proto: "TCP", internal: 5070, external: 5060}, time.Hour)
+	require.ErrorContains(t, err, "not authorized")
+
+	c = newFakeNATPMP(t, 1, 0)
+	ext, err = c.Map(ctx, portMapping{proto: "UDP", internal: 10000, external: 10000}, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 10001, ext)
+
+	// Nothing listens on the port, so the gateway doesn't support NAT-PMP.
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := conn.LocalAddr().String()
+	_ = conn.Close()
+	_, err = (&natPMP{addr: addr}).ExternalIP(ctx)
+	require.Error(t, err)
+}
+
+const testIGDDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+        <deviceList>
+          <device>
+            <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+            <serviceList>
+              <service>
+                <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+                <controlURL>/ctl/IPConn</controlURL>
+              </service>
+            </serviceList>
+          </device>
+        </deviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+const testIGDFault = `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>
+<faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring>
+<detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0">
+<errorCode>%d</errorCode><errorDescription>%s</errorDescription>
+</UPnPError></detail></s:Fault></s:Body></s:Envelope>`
+
+func TestUPnP(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	lease := regexp.MustCompile(`<NewLeaseDuration>(\d+)</NewLeaseDuration>`)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/desc.xml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, testIGDDescription)
+	})
+	mux.HandleFunc("/ctl/IPConn", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		action := r.Header.Get("SOAPAction")
+		action = strings.Trim(action[strings.Index(action, "#")+1:], `"`)
+		mu.Lock()
+		calls = append(calls, action)
+		mu.Unlock()
+		switch action {
+		case "GetExternalIPAddress":
+			_, _ = io.WriteString(w, `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>`+
+				`<u:GetExternalIPAddressResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1">`+
+				`<NewExternalIPAddress>203.0.113.9</NewExternalIPAddress></u:GetExternalIPAddressResponse></s:Body></s:Envelope>`)
+		case "AddPortMapping":
+			if !strings.Contains(string(body), "<NewInternalClient>10.0.0.1</NewInternalClient>") {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = fmt.Fprintf(w, testIGDFault, 402, "Invalid Args")
+				return
+			}
+			if m := lease.FindStringSubmatch(string(body)); m == nil || m[1] != "0" {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = fmt.Fprintf(w, testIGDFault, upnpOnlyPermanentLeases, "OnlyPermanentLeasesSupported")
+				return
+			}
+		case "DeletePortMapping":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintf(w, testIGDFault, 714, "NoSuchEntryInArray")
+			return
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	c, err := newUPnPIGD(ctx, srv.URL+"/desc.xml", netip.MustParseAddr("10.0.0.1"))
+	require.NoError(t, err)
+	require.Equal(t, srv.URL+"/ctl/IPConn", c.control)
+	require.Equal(t, "urn:schemas-upnp-org:service:WANIPConnection:1", c.service)
+
+	ip, err := c.ExternalIP(ctx)
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParseAddr("203.0.113.9"), ip)
+
+	m := portMapping{proto: "UDP", internal: 5070, external: 5060}
+	ext, err := c.Map(ctx, m, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 5060, ext)
+	require.True(t, c.permanent)
+
+	err = c.Unmap(ctx, m)
+	var uerr *upnpError
+	require.ErrorAs(t, err, &uerr)
+	require.Equal(t, 714, uerr.code)
+	require.Equal(t, []string{"GetExternalIPAddress", "AddPortMapping", "AddPortMapping", "DeletePortMapping"}, calls)
+
+	_, err = newUPnPIGD(ctx, srv.URL+"/missing.xml", netip.MustParseAddr("10.0.0.1"))
+	require.Error(t, err)
+}
+
+type fakePortMapper struct {
+	mu       sync.Mutex
+	mapped   map[portMapping]int
+	unmapped int
+	fail     int // internal port which can't be mapped
+}
+
+func (f *fakePortMapper) Name() string {
+	return "fake"
+}
+
+func (f *fakePortMapper) ExternalIP(ctx context.Context) (netip.Addr, error) {
+	return netip.MustParseAddr("203.0.113.1"), nil
+}
+
+func (f *fakePortMapper) Map(ctx context.Context, m portMapping, lifetime time.Duration) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if m.internal == f.fail {
+		return 0, errors.New("refused")
+	}
+	f.mapped[m]++
+	return m.external, nil
+}
+
+func (f *fakePortMapper) Unmap(ctx context.Context, m portMapping) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.mapped, m)
+	f.unmapped++
+	return nil
+}
+
+func (f *fakePortMapper) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.mapped)
+}
+
+func TestPortMappings(t *testing.T) {
+	log := logger.GetLogger()
+	require.Nil(t, newPortMappings(log, &config.Config{}, &ServiceConfig{}))
+	var none *portMappings
+	none.Start()
+	none.Stop()
+
+	conf := &config.Config{
+		SIPPort:       5060,
+		SIPPortListen: 5070,
+		TLS:           &config.TLSConfig{Port: 5061, ListenPort: 5071},
+		RTPPort:       rtcconfig.PortRange{Start: 10000, End: 10003},
+		PortMapping:   &config.PortMappingConfig{},
+	}
+	p := newPortMappings(log, conf, &ServiceConfig{})
+	require.Equal(t, config.DefaultPortMappingLifetime, p.lifetime)
+	require.Equal(t, []portMapping{
+		{proto: "UDP", internal: 5070, external: 5060},
+		{proto: "TCP", internal: 5070, external: 5060},
+		{proto: "TCP", internal: 5071, external: 5061},
+		{proto: "UDP", internal: 10000, external: 10000},
+		{proto: "UDP", internal: 10001, external: 10001},
+		{proto: "UDP", internal: 10002, external: 10002},
+		{proto: "UDP", internal: 10003, external: 10003},
+	}, p.ports)
+
+	// Discovery failures are retried.
+	p.discover = func(ctx context.Context) (portMapper, error) {
+		return nil, errors.New("no gateway")
+	}
+	require.False(t, p.update())
+	require.Nil(t, p.mapper)
+
+	// A gateway refusing all mappings is discovered again.
+	gw := &fakePortMapper{mapped: make(map[portMapping]int), fail: 5070}
+	p.ports = p.ports[:2]
+	p.discover = func(ctx context.Context) (portMapper, error) {
+		return gw, nil
+	}
+	require.False(t, p.update())
+	require.Nil(t, p.mapper)
+
+	// Mappings are renewed, and the ones which failed are retried.
+	p = newPortMappings(log, conf, &ServiceConfig{})
+	p.discover = func(ctx context.Context) (portMapper, error) {
+		return gw, nil
+	}
+	require.False(t, p.update())
+	require.Equal(t, gw, p.mapper)
+	require.Equal(t, 5, gw.count())
+	gw.fail = 0
+	require.True(t, p.update())
+	require.Equal(t, 7, gw.count())
+	require.Equal(t, 2, gw.mapped[portMapping{proto: "UDP", internal: 10000, external: 10000}])
+
+	p.Start()
+	p.Stop()
+	require.Equal(t, 0, gw.count())
+	require.Equal(t, 7, gw.unmapped)
+}
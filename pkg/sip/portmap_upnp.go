@@ -0,0 +1,293 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// upnpDiscoverTimeout is how long SSDP responses of gateways are collected.
+	upnpDiscoverTimeout = 2 * time.Second
+	// upnpDescription describes port mappings on the gateway.
+	upnpDescription = "livekit-sip"
+	// upnpOnlyPermanentLeases is the UPnP error of gateways which don't support lease durations.
+	upnpOnlyPermanentLeases = 725
+)
+
+var upnpSSDPAddr = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+// upnpServiceTypes lists supported services of gateways, in the order of preference.
+var upnpServiceTypes = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:2",
+	"urn:schemas-upnp-org:service:WANIPConnection:1",
+	"urn:schemas-upnp-org:service:WANPPPConnection:1",
+}
+
+// upnpIGD maps ports with the WAN connection service of a UPnP Internet Gateway Device.
+type upnpIGD struct {
+	client    *http.Client
+	control   string     // control URL of the service
+	service   string     // service type
+	local     netip.Addr // internal client of mappings
+	permanent bool       // gateway only supports permanent mappings
+}
+
+// discoverUPnP finds a gateway on the local network with SSDP.
+func discoverUPnP(ctx context.Context, local netip.Addr) (*upnpIGD, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: local.AsSlice()})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	req := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n\r\n"
+	if _, err = conn.WriteTo([]byte(req), upnpSSDPAddr); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(upnpDiscoverTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetReadDeadline(deadline)
+	var errs []error
+	seen := make(map[string]bool)
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		_ = resp.Body.Close()
+		loc := resp.Header.Get("Location")
+		if loc == "" || seen[loc] {
+			continue
+		}
+		seen[loc] = true
+		igd, err := newUPnPIGD(ctx, loc, local)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return igd, nil
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	return nil, errors.New("no UPnP gateway responded")
+}
+
+type upnpRoot struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+type upnpDevice struct {
+	Services []upnpService `xml:"serviceList>service"`
+	Devices  []upnpDevice  `xml:"deviceList>device"`
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// find returns a service of the given type, searching embedded devices.
+func (d *upnpDevice) find(typ string) *upnpService {
+	for i := range d.Services {
+		if d.Services[i].ServiceType == typ {
+			return &d.Services[i]
+		}
+	}
+	for i := range d.Devices {
+		if s := d.Devices[i].find(typ); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// newUPnPIGD loads the device description of a gateway and finds its WAN connection service.
+func newUPnPIGD(ctx context.Context, location string, local netip.Addr) (*upnpIGD, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load UPnP gateway description: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot load UPnP gateway description from %s: %s", location, resp.Status)
+	}
+	var root upnpRoot
+	if err = xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&root); err != nil {
+		return nil, fmt.Errorf("invalid UPnP gateway description from %s: %w", location, err)
+	}
+	base, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if root.URLBase != "" {
+		if base, err = url.Parse(root.URLBase); err != nil {
+			return nil, err
+		}
+	}
+	for _, typ := range upnpServiceTypes {
+		s := root.Device.find(typ)
+		if s == nil {
+			continue
+		}
+		control, err := base.Parse(s.ControlURL)
+		if err != nil {
+			return nil, err
+		}
+		return &upnpIGD{client: client, control: control.String(), service: typ, local: local}, nil
+	}
+	return nil, fmt.Errorf("UPnP device at %s is not an internet gateway", location)
+}
+
+func (c *upnpIGD) Name() string {
+	return "UPnP"
+}
+
+type upnpFault struct {
+	Code        int    `xml:"Body>Fault>detail>UPnPError>errorCode"`
+	Description string `xml:"Body>Fault>detail>UPnPError>errorDescription"`
+}
+
+// upnpError is an error returned by the gateway.
+type upnpError struct {
+	action      string
+	code        int
+	description string
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("UPnP %s failed: %d %s", e.action, e.code, e.description)
+}
+
+// call invokes a SOAP action of the service. Arguments are name and value pairs.
+func (c *upnpIGD) call(ctx context.Context, action string, args ...string) ([]byte, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, c.service)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&body, "<%s>", args[i])
+		_ = xml.EscapeText(&body, []byte(args[i+1]))
+		fmt.Fprintf(&body, "</%s>", args[i])
+	}
+	fmt.Fprintf(&body, `</u:%s></s:Body></s:Envelope>`, action)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.control, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", `"`+c.service+"#"+action+`"`)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var fault upnpFault
+		if xml.Unmarshal(data, &fault) == nil && fault.Code != 0 {
+			return nil, &upnpError{action: action, code: fault.Code, description: fault.Description}
+		}
+		return nil, fmt.Errorf("UPnP %s failed: %s", action, resp.Status)
+	}
+	return data, nil
+}
+
+func (c *upnpIGD) ExternalIP(ctx context.Context) (netip.Addr, error) {
+	data, err := c.call(ctx, "GetExternalIPAddress")
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	var resp struct {
+		IP string `xml:"Body>GetExternalIPAddressResponse>NewExternalIPAddress"`
+	}
+	if err = xml.Unmarshal(data, &resp); err != nil {
+		return netip.Addr{}, err
+	}
+	return netip.ParseAddr(strings.TrimSpace(resp.IP))
+}
+
+func (c *upnpIGD) Map(ctx context.Context, m portMapping, lifetime time.Duration) (int, error) {
+	lease := int(lifetime / time.Second)
+	if c.permanent {
+		lease = 0
+	}
+	add := func(lease int) error {
+		_, err := c.call(ctx, "AddPortMapping",
+			"NewRemoteHost", "",
+			"NewExternalPort", strconv.Itoa(m.external),
+			"NewProtocol", m.proto,
+			"NewInternalPort", strconv.Itoa(m.internal),
+			"NewInternalClient", c.local.String(),
+			"NewEnabled", "1",
+			"NewPortMappingDescription", upnpDescription,
+			"NewLeaseDuration", strconv.Itoa(lease),
+		)
+		return err
+	}
+	err := add(lease)
+	var uerr *upnpError
+	if errors.As(err, &uerr) && uerr.code == upnpOnlyPermanentLeases {
+		// Mappings are removed on shutdown, and renewals are harmless.
+		c.permanent = true
+		err = add(0)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return m.external, nil
+}
+
+func (c *upnpIGD) Unmap(ctx context.Context, m portMapping) error {
+	_, err := c.call(ctx, "DeletePortMapping",
+		"NewRemoteHost", "",
+		"NewExternalPort", strconv.Itoa(m.external),
+		"NewProtocol", m.proto,
+	)
+	return err
+}
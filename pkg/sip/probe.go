@@ -0,0 +1,236 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	lksip "github.com/livekit/protocol/sip"
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/sipgo/sip"
+)
+
+const defaultProbeTimeout = 5 * time.Second
+
+// TrunkProbeRequest describes an outbound trunk that should be probed with SIP OPTIONS.
+type TrunkProbeRequest struct {
+	// TrunkID selects a trunk from the local config. Only trunks with an address there can be probed:
+	// trunks known only to the LiveKit API have no address until it's added to the config.
+	TrunkID   string               `json:"trunk_id"`
+	Transport livekit.SIPTransport `json:"transport,omitempty"`
+	// Hostname and Number are used in the From header, same as for outbound calls.
+	Hostname string `json:"hostname,omitempty"`
+	Number   string `json:"number,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// TimeoutMs limits the time for the probe. Default is 5 seconds.
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+}
+
+// TrunkProbeResult reports reachability and capabilities of the trunk.
+type TrunkProbeResult struct {
+	// Reachable is set if the trunk sent any final response to OPTIONS.
+	Reachable bool `json:"reachable"`
+	// OK is set if the trunk responded with 200 OK.
+	OK         bool    `json:"ok"`
+	StatusCode int     `json:"status_code,omitempty"`
+	Status     string  `json:"status,omitempty"`
+	LatencyMs  float64 `json:"latency_ms,omitempty"`
+	// AuthRequired is set if the trunk challenged the request with 401 or 407.
+	AuthRequired bool     `json:"auth_required,omitempty"`
+	Allow        []string `json:"allow,omitempty"`
+	Accept       []string `json:"accept,omitempty"`
+	Supported    []string `json:"supported,omitempty"`
+	UserAgent    string   `json:"user_agent,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// ProbeTrunk sends OPTIONS to the trunk and reports its reachability, latency and capabilities.
+// Errors on the SIP level are reported in the result, not as an error.
+func (c *Client) ProbeTrunk(ctx context.Context, req *TrunkProbeRequest) (*TrunkProbeResult, error) {
+	if req.TrunkID == "" {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "trunk id is required")
+	}
+	t := c.conf.Trunks[req.TrunkID]
+	if t == nil || t.Address == "" {
+		return nil, psrpc.NewErrorf(psrpc.NotFound, "trunk %q is not configured with an address", req.TrunkID)
+	}
+	addr := t.Address
+	timeout := defaultProbeTimeout
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tr := TransportFrom(req.Transport)
+	contact := c.ContactURI(tr)
+	host := req.Hostname
+	if host == "" {
+		host = contact.GetHost()
+	}
+	from := URI{User: req.Number, Host: host, Addr: contact.Addr, Transport: tr}.Normalize()
+	fromHeader := &sip.FromHeader{
+		DisplayName: from.User,
+		Address:     *from.GetURI(),
+		Params:      sip.NewParams(),
+	}
+	fromHeader.Params.Add("tag", guid.HashedID(addr))
+	to := CreateURIFromUserAndAddress("", addr, tr)
+	toHeader := &sip.ToHeader{Address: *to.GetURI()}
+	callID := sip.CallIDHeader(guid.New("SPR_"))
+
+	log := c.log.WithValues("trunkID", req.TrunkID, "address", addr, "transport", tr)
+	res := &TrunkProbeResult{}
+	var authName, authValue string
+	for cseq := uint32(1); ; cseq++ {
+		r := sip.NewRequest(sip.OPTIONS, toHeader.Address)
+		setCSeq(r, cseq)
+		r.RemoveHeader("Call-ID")
+		r.AppendHeader(&callID)
+		r.SetDestination(to.GetDest())
+		r.AppendHeader(toHeader)
+		r.AppendHeader(fromHeader)
+		r.AppendHeader(&sip.ContactHeader{Address: *contact.GetContactURI()})
+		c.capabilities(req.TrunkID).SetHeaders(r, true)
+		if authValue != "" {
+			r.AppendHeader(sip.NewHeader(authName, authValue))
+		}
+
+		start := time.Now()
+		resp, err := c.sendProbe(ctx, r)
+		if err != nil {
+			log.Infow("trunk probe failed", "error", err)
+			res.Error = err.Error()
+			return res, nil
+		}
+		res.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+		res.Reachable = true
+		res.StatusCode = int(resp.StatusCode)
+		res.Status = resp.Reason
+		res.OK = resp.StatusCode == sip.StatusOK
+		res.Allow = probeHeaderList(resp, "Allow")
+		res.Accept = probeHeaderList(resp, "Accept")
+		res.Supported = probeHeaderList(resp, "Supported")
+		if h := resp.GetHeader("Server"); h != nil {
+			res.UserAgent = h.Value()
+		} else if h = resp.GetHeader("User-Agent"); h != nil {
+			res.UserAgent = h.Value()
+		}
+		switch resp.StatusCode {
+		case sip.StatusUnauthorized, sip.StatusProxyAuthRequired:
+			res.AuthRequired = true
+			if authValue != "" {
+				res.Error = "authentication failed"
+				return res, nil
+			}
+			authName, authValue, err = digestAuth(r, resp, req.Username, req.Password)
+			if err != nil {
+				res.Error = err.Error()
+				return res, nil
+			}
+			continue
+		}
+		log.Infow("trunk probe completed", "status", res.StatusCode, "latency", res.LatencyMs)
+		return res, nil
+	}
+}
+
+func (c *Client) sendProbe(ctx context.Context, req *sip.Request) (*sip.Response, error) {
+	c.addVia(req)
+	tx, err := c.sipCli.TransactionRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Terminate()
+	return sipResponse(ctx, tx, c.closing.Watch(), nil)
+}
+
+// probeHeaderList collects comma-separated values from all headers with a given name.
+func probeHeaderList(resp *sip.Response, name string) []string {
+	var out []string
+	for _, h := range resp.GetHeaders(name) {
+		for _, v := range strings.Split(h.Value(), ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+func (s *Server) onOptions(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {
+	var trunkID string
+	if s.conf.OptionsAuth {
+		var ok bool
+		if trunkID, ok = s.authOptions(req, tx); !ok {
+			return
+		}
+	}
+	r := sip.NewResponseFromRequest(req, 200, "OK", nil)
+	s.capabilities(trunkID).SetHeaders(r, true)
+	_ = tx.Respond(r)
+}
+
+// authOptions checks inbound OPTIONS against trunk auth, the same way as INVITE.
+// If the request is not authorized, it sends a response (if any) and returns false. Otherwise, it returns the trunk ID.
+func (s *Server) authOptions(req *sip.Request, tx sip.ServerTransaction) (string, bool) {
+	from, to := req.From(), req.To()
+	src, err := netip.ParseAddrPort(req.Source())
+	if from == nil || to == nil || err != nil {
+		_ = tx.Respond(sip.NewResponseFromRequest(req, sip.StatusBadRequest, "Bad request", nil))
+		return "", false
+	}
+	fromURI, toURI := sanitizeUserURI(from.Address), sanitizeUserURI(to.Address)
+	log := s.log.WithValues(
+		"fromIP", src.Addr(),
+		"fromUser", fromURI.User,
+		"toUser", toURI.User,
+	)
+	r, err := s.handler.GetAuthCredentials(context.Background(), &rpc.SIPCall{
+		LkCallId: lksip.NewCallID(),
+		SourceIp: src.Addr().String(),
+		Address:  ToSIPUri("", sanitizeUserURI(req.Recipient)),
+		From:     ToSIPUri("", fromURI),
+		To:       ToSIPUri("", toURI),
+	})
+	if err != nil {
+		log.Warnw("Rejecting OPTIONS, auth check failed", err)
+		_ = tx.Respond(sip.NewResponseFromRequest(req, sip.StatusServiceUnavailable, "Try again later", nil))
+		return "", false
+	}
+	switch r.Result {
+	case AuthAccept:
+		return r.TrunkID, true
+	case AuthPassword:
+		return r.TrunkID, s.handleInviteAuth(log, req, tx, from.Address.User, r.Username, r.Password)
+	case AuthNotFound:
+		log.Debugw("Rejecting OPTIONS, doesn't match any Trunks")
+		if !s.conf.HideInboundPort {
+			_ = tx.Respond(sip.NewResponseFromRequest(req, sip.StatusNotFound, "Does not match any SIP Trunks", nil))
+		}
+		return "", false
+	default:
+		log.Debugw("Dropping OPTIONS")
+		return "", false
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/icholy/digest"
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/sipgo/sip"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+func TestProbeTrunkOptionsAuth(t *testing.T) {
+	sipPort := rand.Intn(testPortSIPMax-testPortSIPMin) + testPortSIPMin
+	localIP, err := config.GetLocalIP()
+	require.NoError(t, err)
+	addr := fmt.Sprintf("%s:%d", localIP, sipPort)
+
+	mon, err := stats.NewMonitor(&config.Config{MaxCpuUtilization: 0.9})
+	require.NoError(t, err)
+
+	s, err := NewService("", &config.Config{
+		OptionsAuth:   true,
+		SIPPort:       sipPort,
+		SIPPortListen: sipPort,
+		RTPPort:       rtcconfig.PortRange{Start: testPortRTPMin, End: testPortRTPMax},
+		Trunks: map[string]*config.TrunkConfig{
+			"ST_probe": {Address: addr},
+			"ST_caps": {Capabilities: &config.CapabilitiesConfig{
+				Allow:     []string{"INVITE", "ACK", "CANCEL", "BYE", "OPTIONS"},
+				Supported: []string{},
+			}},
+		},
+	}, mon, logger.NewTestLogger(t), func(projectID string) rpc.IOInfoClient { return nil })
+	require.NoError(t, err)
+	t.Cleanup(s.Stop)
+	s.SetHandler(&TestHandler{
+		GetAuthCredentialsFunc: func(ctx context.Context, call *rpc.SIPCall) (AuthInfo, error) {
+			switch call.From.User {
+			case "trunk":
+				return AuthInfo{Result: AuthPassword, Username: "user", Password: "pass"}, nil
+			case "caps":
+				return AuthInfo{Result: AuthAccept, TrunkID: "ST_caps"}, nil
+			}
+			return AuthInfo{Result: AuthNotFound}, nil
+		},
+	})
+	require.NoError(t, s.Start())
+
+	probe := func(t *testing.T, number, pass string) *TrunkProbeResult {
+		res, err := s.ProbeTrunk(context.Background(), &TrunkProbeRequest{
+			TrunkID:  "ST_probe",
+			Number:   number,
+			Username: "user",
+			Password: pass,
+		})
+		require.NoError(t, err)
+		require.True(t, res.Reachable)
+		return res
+	}
+	t.Run("ok", func(t *testing.T) {
+		res := probe(t, "trunk", "pass")
+		require.True(t, res.OK, "%+v", res)
+		require.True(t, res.AuthRequired)
+		require.Equal(t, defaultAllow, res.Allow)
+		require.Equal(t, defaultAccept, res.Accept)
+		require.Equal(t, defaultSupported, res.Supported)
+		require.Empty(t, res.Error)
+	})
+	t.Run("trunk capabilities", func(t *testing.T) {
+		res := probe(t, "caps", "")
+		require.True(t, res.OK, "%+v", res)
+		require.Equal(t, []string{"INVITE", "ACK", "CANCEL", "BYE", "OPTIONS"}, res.Allow)
+		require.Equal(t, defaultAccept, res.Accept)
+		require.Empty(t, res.Supported)
+	})
+	t.Run("bad password", func(t *testing.T) {
+		res := probe(t, "trunk", "wrong")
+		require.False(t, res.OK)
+		require.Equal(t, 401, res.StatusCode)
+		require.NotEmpty(t, res.Error)
+	})
+	t.Run("no trunk", func(t *testing.T) {
+		res := probe(t, "other", "pass")
+		require.False(t, res.OK)
+		require.Equal(t, 404, res.StatusCode)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := s.ProbeTrunk(context.Background(), &TrunkProbeRequest{})
+		require.Error(t, err)
+	})
+	t.Run("not configured", func(t *testing.T) {
+		// Only configured trunk addresses can be probed.
+		for _, id := range []string{"ST_unknown", "ST_caps"} {
+			_, err := s.ProbeTrunk(context.Background(), &TrunkProbeRequest{TrunkID: id})
+			require.Error(t, err, id)
+		}
+	})
+}
+
+func TestProbeAuthKeepsInviteChallenge(t *testing.T) {
+	s := &Server{log: logger.GetLogger(), conf: &config.Config{}}
+	log := logger.GetLogger()
+
+	challenge := func(t *testing.T, req *sip.Request) *digest.Challenge {
+		tx := &testServerTx{}
+		require.False(t, s.handleInviteAuth(log, req, tx, "alice", "user", "pass"))
+		require.Len(t, tx.responses, 1)
+		require.Equal(t, sip.StatusProxyAuthRequired, tx.responses[0].StatusCode)
+		h := tx.responses[0].GetHeader("Proxy-Authenticate")
+		require.NotNil(t, h)
+		c, err := digest.ParseChallenge(h.Value())
+		require.NoError(t, err)
+		return c
+	}
+	authorize := func(t *testing.T, req *sip.Request, c *digest.Challenge) *sip.Request {
+		cred, err := digest.Digest(c, digest.Options{
+			Method:   req.Method.String(),
+			URI:      req.Recipient.String(),
+			Username: "user",
+			Password: "pass",
+		})
+		require.NoError(t, err)
+		req.AppendHeader(sip.NewHeader("Proxy-Authorization", cred.String()))
+		return req
+	}
+
+	invite := newDedupInvite("call-1", 1, "z9hG4bK-1", 5060)
+	options := sip.NewRequest(sip.OPTIONS, sip.Uri{User: "bob", Host: "example.com"})
+	via := &sip.ViaHeader{ProtocolName: "SIP", ProtocolVersion: "2.0", Transport: "UDP", Host: "1.1.1.1", Port: 5060, Params: sip.NewParams()}
+	via.Params.Add("branch", "z9hG4bK-2")
+	options.AppendHeader(via)
+	from := &sip.FromHeader{Address: sip.Uri{User: "alice", Host: "example.com"}, Params: sip.NewParams()}
+	from.Params.Add("tag", "def")
+	options.AppendHeader(from)
+	options.AppendHeader(&sip.ToHeader{Address: sip.Uri{User: "bob", Host: "example.com"}, Params: sip.NewParams()})
+	cid := sip.CallIDHeader("probe-1")
+	options.AppendHeader(&cid)
+	options.AppendHeader(&sip.CSeqHeader{SeqNo: 1, MethodName: sip.OPTIONS})
+
+	inviteChal := challenge(t, invite)
+	time.Sleep(time.Millisecond) // nonces are timestamps
+	optionsChal := challenge(t, options)
+	require.NotEqual(t, inviteChal.Nonce, optionsChal.Nonce)
+
+	// Both requests from the same caller must still pass with their own nonce.
+	require.True(t, s.handleInviteAuth(log, authorize(t, invite, inviteChal), &testServerTx{}, "alice", "user", "pass"))
+	require.True(t, s.handleInviteAuth(log, authorize(t, options, optionsChal), &testServerTx{}, "alice", "user", "pass"))
+}
@@ -23,6 +23,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/icholy/digest"
 	"github.com/pkg/errors"
 
 	"github.com/livekit/protocol/livekit"
@@ -83,6 +84,7 @@ var statusNamesMap = map[int]string{
 	486: "BusyHere",
 	487: "RequestTerminated",
 	488: "NotAcceptableHere",
+	491: "RequestPending",
 
 	500: "InternalServerError",
 	501: "NotImplemented",
@@ -150,13 +152,32 @@ func transportPort(c *config.Config, t Transport) int {
 	return c.SIPPort
 }
 
-func getContactURI(c *config.Config, ip netip.Addr, t Transport) URI {
-	hostname := "" // use signaling IP by default, it's more robust
+// advertisedHost returns the hostname advertised in Contact and Via headers for the transport.
+// It returns an empty string if the signaling IP should be used instead, which is the default, since it's more robust.
+func advertisedHost(c *config.Config, t Transport) string {
+	if h := c.SIPHostnames; h != nil {
+		var name string
+		switch t {
+		case TransportUDP, "":
+			name = h.UDP
+		case TransportTCP:
+			name = h.TCP
+		case TransportTLS:
+			name = h.TLS
+		}
+		if name != "" {
+			return name
+		}
+	}
 	if t == TransportTLS {
-		hostname = c.SIPHostname
+		return c.SIPHostname
 	}
+	return ""
+}
+
+func getContactURI(c *config.Config, ip netip.Addr, t Transport) URI {
 	return URI{
-		Host:      hostname,
+		Host:      advertisedHost(c, t),
 		Addr:      netip.AddrPortFrom(ip, uint16(transportPort(c, t))),
 		Transport: t,
 	}
@@ -178,7 +199,23 @@ func sendAndACK(ctx context.Context, c Signaling, req *sip.Request) {
 }
 
 func NewReferRequest(inviteRequest *sip.Request, inviteResponse *sip.Response, contactHeader *sip.ContactHeader, referToUrl string, headers map[string]string) *sip.Request {
-	req := sip.NewRequest(sip.REFER, inviteRequest.Recipient)
+	req := newDialogRequest(sip.REFER, inviteRequest, inviteResponse, contactHeader)
+
+	// Set Refer-To header
+	referTo := sip.NewHeader("Refer-To", referToUrl)
+	req.AppendHeader(referTo)
+	defaultCapabilities.SetHeaders(req, false)
+
+	for k, v := range headers {
+		req.AppendHeader(sip.NewHeader(k, v))
+	}
+	return req
+}
+
+// newDialogRequest creates an in-dialog request without a body, with dialog headers taken from the INVITE and its response.
+// The caller must set the CSeq number.
+func newDialogRequest(method sip.RequestMethod, inviteRequest *sip.Request, inviteResponse *sip.Response, contactHeader *sip.ContactHeader) *sip.Request {
+	req := sip.NewRequest(method, inviteRequest.Recipient)
 
 	req.SipVersion = inviteRequest.SipVersion
 	sip.CopyHeaders("Via", inviteRequest, req)
@@ -226,26 +263,35 @@ func NewReferRequest(inviteRequest *sip.Request, inviteResponse *sip.Response, c
 
 	cseq := req.CSeq()
 	cseq.SeqNo = cseq.SeqNo + 1
-	cseq.MethodName = sip.REFER
-
-	// Set Refer-To header
-	referTo := sip.NewHeader("Refer-To", referToUrl)
-	req.AppendHeader(referTo)
-	req.AppendHeader(sip.NewHeader("Allow", "INVITE, ACK, CANCEL, BYE, NOTIFY, REFER, MESSAGE, OPTIONS, INFO, SUBSCRIBE"))
+	cseq.MethodName = method
 
 	req.SetTransport(inviteRequest.Transport())
 	req.SetSource(inviteRequest.Source())
 	req.SetDestination(inviteRequest.Destination())
 
-	for k, v := range headers {
-		req.AppendHeader(sip.NewHeader(k, v))
-	}
-
 	req.SetBody(nil)
 
 	return req
 }
 
+// sendDialogRequest sends an in-dialog request and waits for a final response. Non-2xx responses are returned as errors.
+func sendDialogRequest(ctx context.Context, c Signaling, req *sip.Request, stop <-chan struct{}) error {
+	tx, err := c.Transaction(req)
+	if err != nil {
+		return err
+	}
+	defer tx.Terminate()
+
+	resp, err := sipResponse(ctx, tx, stop, nil)
+	if err != nil {
+		return err
+	}
+	if !resp.IsSuccess() {
+		return &livekit.SIPStatus{Code: livekit.SIPStatusCode(resp.StatusCode)}
+	}
+	return nil
+}
+
 func sendRefer(ctx context.Context, c Signaling, req *sip.Request, stop <-chan struct{}) (*sip.Response, error) {
 	tx, err := c.Transaction(req)
 	if err != nil {
@@ -367,6 +413,49 @@ func sipCodeAndMessageFromError(err error) (code sip.StatusCode, msg string) {
 	return code, msg
 }
 
+// digestAuth computes a response for the digest auth challenge in 401 or 407 response.
+// It returns the name and the value of the header that must be added to the retried request.
+func digestAuth(req *sip.Request, resp *sip.Response, user, pass string) (string, string, error) {
+	var authHeaderName, authHeaderRespName string
+	switch resp.StatusCode {
+	case sip.StatusUnauthorized:
+		authHeaderName = "WWW-Authenticate"
+		authHeaderRespName = "Authorization"
+	case sip.StatusProxyAuthRequired:
+		authHeaderName = "Proxy-Authenticate"
+		authHeaderRespName = "Proxy-Authorization"
+	default:
+		return "", "", fmt.Errorf("unexpected auth status %d", resp.StatusCode)
+	}
+	if user == "" || pass == "" {
+		return "", "", errors.New("server required auth, but no username or password was provided")
+	}
+	headerVal := resp.GetHeader(authHeaderName)
+	if headerVal == nil {
+		return "", "", errors.New("no auth header in response")
+	}
+	challengeStr := headerVal.Value()
+	challenge, err := digest.ParseChallenge(challengeStr)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid challenge %q: %w", challengeStr, err)
+	}
+	toHeader := resp.To()
+	if toHeader == nil {
+		return "", "", errors.New("no 'To' header on Response")
+	}
+
+	cred, err := digest.Digest(challenge, digest.Options{
+		Method:   req.Method.String(),
+		URI:      toHeader.Address.String(),
+		Username: user,
+		Password: pass,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return authHeaderRespName, cred.String(), nil
+}
+
 func setCSeq(req *sip.Request, cseq uint32) {
 	h := &sip.CSeqHeader{
 		MethodName: req.Method,
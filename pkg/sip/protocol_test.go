@@ -1,11 +1,15 @@
 package sip
 
 import (
+	"net/netip"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/livekit/protocol/logger"
 	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
 )
 
 func TestHandleNotify(t *testing.T) {
@@ -68,3 +72,48 @@ func TestHandleNotify(t *testing.T) {
 	m, c, s, err = handleNotify(req)
 	require.Error(t, err)
 }
+
+func TestAdvertisedHost(t *testing.T) {
+	conf := &config.Config{SIPPort: 5060, SIPHostname: "sip.example.com", TLS: &config.TLSConfig{Port: 5061}}
+	ip := netip.MustParseAddr("10.0.0.1")
+
+	// Only TLS uses the hostname by default.
+	require.Equal(t, "sip:10.0.0.1:5060;transport=udp", getContactURI(conf, ip, TransportUDP).GetContactURI().String())
+	require.Equal(t, "sip:10.0.0.1:5060;transport=tcp", getContactURI(conf, ip, TransportTCP).GetContactURI().String())
+	require.Equal(t, "sip:sip.example.com:5061;transport=tls", getContactURI(conf, ip, TransportTLS).GetContactURI().String())
+
+	conf.SIPHostnames = &config.SIPHostnamesConfig{UDP: "udp.example.com", TCP: "tcp.example.com"}
+	require.Equal(t, "sip:udp.example.com:5060;transport=udp", getContactURI(conf, ip, TransportUDP).GetContactURI().String())
+	require.Equal(t, "udp.example.com", advertisedHost(conf, ""))
+	require.Equal(t, "sip:tcp.example.com:5060;transport=tcp", getContactURI(conf, ip, TransportTCP).GetContactURI().String())
+	require.Equal(t, "sip.example.com", advertisedHost(conf, TransportTLS))
+	conf.SIPHostnames.TLS = "tls.example.com"
+	require.Equal(t, "tls.example.com", advertisedHost(conf, TransportTLS))
+
+	require.NoError(t, conf.SIPHostnames.Validate())
+	require.NoError(t, (&config.SIPHostnamesConfig{UDP: "sip-${IP}.example.com"}).Validate())
+	require.Error(t, (&config.SIPHostnamesConfig{TCP: "sip.example.com:5060"}).Validate())
+
+	cli := NewClient("", conf, logger.GetLogger(), nil, nil)
+	require.NoError(t, cli.Start(nil, &ServiceConfig{SignalingIP: ip, SignalingIPLocal: ip}))
+	t.Cleanup(cli.Stop)
+	req := sip.NewRequest(sip.OPTIONS, sip.Uri{Host: "carrier.example.com", UriParams: sip.HeaderParams{"transport": "tcp"}})
+	cli.addVia(req)
+	require.Equal(t, "tcp.example.com", req.Via().Host)
+	req = sip.NewRequest(sip.OPTIONS, sip.Uri{Host: "carrier.example.com"})
+	conf.SIPHostnames.UDP = ""
+	cli.addVia(req)
+	require.Equal(t, "10.0.0.1", req.Via().Host)
+}
+
+func TestExpandHostname(t *testing.T) {
+	s := &Service{log: logger.GetLogger(), sconf: &ServiceConfig{SignalingIP: netip.MustParseAddr("127.0.0.1")}}
+	name, err := s.expandHostname("localhost")
+	require.NoError(t, err)
+	require.Equal(t, "localhost", name)
+	name, err = s.expandHostname("sip-${IP}.invalid")
+	require.NoError(t, err)
+	require.Equal(t, "sip-127-0-0-1.invalid", name)
+	_, err = s.expandHostname("sip.example.com/path")
+	require.Error(t, err)
+}
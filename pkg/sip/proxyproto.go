@@ -0,0 +1,321 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	// proxyV1MaxLen is the maximal length of a PROXY protocol v1 header, including CRLF.
+	proxyV1MaxLen = 107
+	// proxyV2HeaderLen is the length of the fixed part of a PROXY protocol v2 header.
+	proxyV2HeaderLen = 16
+)
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyConns tracks connections accepted from load balancers. It maps client addresses, as seen in
+// sources of SIP requests, to addresses the clients connected to.
+type proxyConns struct {
+	mu    sync.RWMutex
+	local map[string]netip.AddrPort
+}
+
+func newProxyConns() *proxyConns {
+	return &proxyConns{local: make(map[string]netip.AddrPort)}
+}
+
+// Local returns the address the client connected to, if the connection came through a load balancer.
+func (p *proxyConns) Local(remote string) (netip.AddrPort, bool) {
+	if p == nil {
+		return netip.AddrPort{}, false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	addr, ok := p.local[remote]
+	return addr, ok
+}
+
+func (p *proxyConns) add(remote string, local netip.AddrPort) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.local[remote] = local
+}
+
+func (p *proxyConns) remove(remote string, local netip.AddrPort) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.local[remote] == local {
+		delete(p.local, remote)
+	}
+}
+
+// proxyListener reads PROXY protocol headers of connections accepted from trusted load balancers.
+// Headers are read concurrently, so that a slow connection doesn't block accepting other connections.
+type proxyListener struct {
+	net.Listener
+	log     logger.Logger
+	trusted []netip.Prefix
+	timeout time.Duration
+	conns   *proxyConns
+
+	ready chan net.Conn
+	done  chan struct{}
+	err   error
+}
+
+func newProxyListener(log logger.Logger, ln net.Listener, conf *config.ProxyProtocolConfig, conns *proxyConns) *proxyListener {
+	l := &proxyListener{
+		Listener: ln,
+		log:      log,
+		timeout:  conf.Timeout,
+		conns:    conns,
+		ready:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	if l.timeout == 0 {
+		l.timeout = config.DefaultProxyProtocolTimeout
+	}
+	for _, s := range conf.Trusted {
+		l.trusted = append(l.trusted, netip.MustParsePrefix(s))
+	}
+	go l.acceptLoop()
+	return l
+}
+
+func (l *proxyListener) acceptLoop() {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			l.err = err
+			close(l.done)
+			return
+		}
+		go l.handshake(c)
+	}
+}
+
+// isTrusted checks if the connection comes from a load balancer. Nothing is trusted if no networks are configured.
+func (l *proxyListener) isTrusted(addr net.Addr) bool {
+	ap, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return false
+	}
+	ip := ap.Addr().Unmap()
+	for _, p := range l.trusted {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *proxyListener) handshake(c net.Conn) {
+	conn := c
+	if l.isTrusted(c.RemoteAddr()) {
+		pc, err := l.readHeader(c)
+		if err != nil {
+			l.log.Warnw("cannot read proxy protocol header", err, "remote", c.RemoteAddr())
+			_ = c.Close()
+			return
+		}
+		conn = pc
+	}
+	select {
+	case l.ready <- conn:
+	case <-l.done:
+		_ = conn.Close()
+	}
+}
+
+func (l *proxyListener) readHeader(c net.Conn) (net.Conn, error) {
+	if err := c.SetReadDeadline(time.Now().Add(l.timeout)); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReaderSize(c, 256)
+	src, dst, err := readProxyHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	pc := &proxyConn{Conn: c, r: r, remote: c.RemoteAddr(), local: c.LocalAddr()}
+	if src.IsValid() && dst.IsValid() {
+		pc.remote = net.TCPAddrFromAddrPort(src)
+		pc.local = net.TCPAddrFromAddrPort(dst)
+		pc.conns = l.conns
+		pc.dst = dst
+		l.conns.add(pc.remote.String(), dst)
+	}
+	return pc, nil
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.ready:
+		return c, nil
+	case <-l.done:
+		return nil, l.err
+	}
+}
+
+// proxyConn is a connection from a load balancer. Addresses are the ones of the client connection.
+type proxyConn struct {
+	net.Conn
+	r      *bufio.Reader
+	remote net.Addr
+	local  net.Addr
+
+	conns *proxyConns // set if addresses came from the header
+	dst   netip.AddrPort
+	once  sync.Once
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *proxyConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *proxyConn) Close() error {
+	c.once.Do(func() {
+		if c.conns != nil {
+			c.conns.remove(c.remote.String(), c.dst)
+		}
+	})
+	return c.Conn.Close()
+}
+
+// readProxyHeader reads a PROXY protocol v1 or v2 header. Addresses are not set for LOCAL and UNKNOWN
+// connections, as well as for non-TCP protocols.
+func readProxyHeader(r *bufio.Reader) (src, dst netip.AddrPort, _ error) {
+	sig, err := r.Peek(len(proxyV2Signature))
+	if err != nil {
+		return src, dst, fmt.Errorf("cannot read header: %w", err)
+	}
+	if bytes.Equal(sig, proxyV2Signature) {
+		return readProxyHeaderV2(r)
+	}
+	if bytes.HasPrefix(sig, []byte("PROXY ")) {
+		return readProxyHeaderV1(r)
+	}
+	return src, dst, errors.New("missing proxy protocol header")
+}
+
+func readProxyHeaderV1(r *bufio.Reader) (src, dst netip.AddrPort, _ error) {
+	var line []byte
+	for len(line) < proxyV1MaxLen {
+		b, err := r.ReadByte()
+		if err != nil {
+			return src, dst, fmt.Errorf("cannot read header: %w", err)
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	s, ok := strings.CutSuffix(string(line), "\r\n")
+	if !ok {
+		return src, dst, errors.New("invalid proxy protocol v1 header")
+	}
+	fields := strings.Split(s, " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return src, dst, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return src, dst, fmt.Errorf("invalid proxy protocol v1 header %q", s)
+	}
+	parse := func(ip, port string) (netip.AddrPort, error) {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return netip.AddrPort{}, err
+		}
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return netip.AddrPort{}, err
+		}
+		return netip.AddrPortFrom(addr, uint16(p)), nil
+	}
+	var err error
+	if src, err = parse(fields[2], fields[4]); err != nil {
+		return src, dst, fmt.Errorf("invalid proxy protocol v1 source: %w", err)
+	}
+	if dst, err = parse(fields[3], fields[5]); err != nil {
+		return src, dst, fmt.Errorf("invalid proxy protocol v1 destination: %w", err)
+	}
+	return src, dst, nil
+}
+
+func readProxyHeaderV2(r *bufio.Reader) (src, dst netip.AddrPort, _ error) {
+	var hdr [proxyV2HeaderLen]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return src, dst, fmt.Errorf("cannot read header: %w", err)
+	}
+	verCmd, fam := hdr[12], hdr[13]
+	if verCmd>>4 != 2 {
+		return src, dst, fmt.Errorf("unsupported proxy protocol version %d", verCmd>>4)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(hdr[14:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return src, dst, fmt.Errorf("cannot read header: %w", err)
+	}
+	switch verCmd & 0xf {
+	case 0: // LOCAL, for example health checks of the load balancer
+		return src, dst, nil
+	case 1: // PROXY
+	default:
+		return src, dst, fmt.Errorf("unsupported proxy protocol command %d", verCmd&0xf)
+	}
+	var n int
+	switch fam {
+	case 0x11: // TCP over IPv4
+		n = 4
+	case 0x21: // TCP over IPv6
+		n = 16
+	default:
+		return src, dst, nil
+	}
+	if len(body) < 2*n+4 {
+		return src, dst, errors.New("proxy protocol v2 header is too short")
+	}
+	srcIP, _ := netip.AddrFromSlice(body[:n])
+	dstIP, _ := netip.AddrFromSlice(body[n : 2*n])
+	src = netip.AddrPortFrom(srcIP, binary.BigEndian.Uint16(body[2*n:]))
+	dst = netip.AddrPortFrom(dstIP, binary.BigEndian.Uint16(body[2*n+2:]))
+	return src, dst, nil
+}
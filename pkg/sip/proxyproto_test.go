@@ -0,0 +1,182 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func proxyHeaderV2(cmd byte, src, dst netip.AddrPort) []byte {
+	b := append([]byte{}, proxyV2Signature...)
+	b = append(b, 0x20|cmd, 0x11, 0, 12)
+	b = append(b, src.Addr().AsSlice()...)
+	b = append(b, dst.Addr().AsSlice()...)
+	b = binary.BigEndian.AppendUint16(b, src.Port())
+	return binary.BigEndian.AppendUint16(b, dst.Port())
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	src := netip.MustParseAddrPort("192.0.2.10:40000")
+	dst := netip.MustParseAddrPort("198.51.100.1:5060")
+	cases := []struct {
+		name     string
+		data     string
+		src, dst netip.AddrPort
+		err      bool
+	}{
+		{name: "v1", data: "PROXY TCP4 192.0.2.10 198.51.100.1 40000 5060\r\n", src: src, dst: dst},
+		{name: "v1 ipv6", data: "PROXY TCP6 2001:db8::1 2001:db8::2 40000 5061\r\n",
+			src: netip.MustParseAddrPort("[2001:db8::1]:40000"), dst: netip.MustParseAddrPort("[2001:db8::2]:5061")},
+		{name: "v1 unknown", data: "PROXY UNKNOWN\r\n"},
+		{name: "v1 invalid", data: "PROXY TCP4 192.0.2.10 40000\r\n", err: true},
+		{name: "v1 no crlf", data: "PROXY TCP4 " + strings.Repeat("1", 200), err: true},
+		{name: "v2", data: string(proxyHeaderV2(1, src, dst)), src: src, dst: dst},
+		{name: "v2 local", data: string(proxyHeaderV2(0, src, dst))},
+		{name: "missing", data: "INVITE sip:a@example.com SIP/2.0\r\n", err: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(c.data + "rest"))
+			gsrc, gdst, err := readProxyHeader(r)
+			if c.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.src, gsrc)
+			require.Equal(t, c.dst, gdst)
+			rest, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, "rest", string(rest))
+		})
+	}
+}
+
+func TestProxyProtocolConfig(t *testing.T) {
+	require.Error(t, (&config.ProxyProtocolConfig{}).Validate())
+	require.NoError(t, (&config.ProxyProtocolConfig{Trusted: []string{"10.0.0.0/8", "2001:db8::/32"}, Timeout: time.Second}).Validate())
+	require.Error(t, (&config.ProxyProtocolConfig{Trusted: []string{"10.0.0.1"}}).Validate())
+	require.Error(t, (&config.ProxyProtocolConfig{Trusted: []string{"10.0.0.0/8"}, Timeout: -time.Second}).Validate())
+}
+
+func TestProxyListener(t *testing.T) {
+	loopback := &config.ProxyProtocolConfig{Trusted: []string{"127.0.0.0/8"}}
+	listen := func(t *testing.T, conf *config.ProxyProtocolConfig) (*proxyListener, *proxyConns, net.Conn) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		conns := newProxyConns()
+		pl := newProxyListener(logger.GetLogger(), ln, conf, conns)
+		t.Cleanup(func() { _ = pl.Close() })
+
+		c, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+		return pl, conns, c
+	}
+	accept := func(t *testing.T, conf *config.ProxyProtocolConfig, data string) (net.Conn, *proxyConns) {
+		pl, conns, c := listen(t, conf)
+		_, err := c.Write([]byte(data))
+		require.NoError(t, err)
+
+		conn, err := pl.Accept()
+		require.NoError(t, err)
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		require.Equal(t, "SIP!", string(buf))
+		return conn, conns
+	}
+
+	t.Run("proxied", func(t *testing.T) {
+		conn, conns := accept(t, loopback, "PROXY TCP4 192.0.2.10 198.51.100.1 40000 5060\r\nSIP!")
+		require.Equal(t, "192.0.2.10:40000", conn.RemoteAddr().String())
+		require.Equal(t, "198.51.100.1:5060", conn.LocalAddr().String())
+		addr, ok := conns.Local("192.0.2.10:40000")
+		require.True(t, ok)
+		require.Equal(t, netip.MustParseAddrPort("198.51.100.1:5060"), addr)
+
+		_ = conn.Close()
+		_, ok = conns.Local("192.0.2.10:40000")
+		require.False(t, ok)
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		conn, conns := accept(t, &config.ProxyProtocolConfig{Trusted: []string{"10.0.0.0/8"}}, "SIP!")
+		require.Equal(t, "127.0.0.1", conn.RemoteAddr().(*net.TCPAddr).IP.String())
+		_, ok := conns.Local(conn.RemoteAddr().String())
+		require.False(t, ok)
+	})
+
+	t.Run("untrusted header", func(t *testing.T) {
+		// Headers from senders outside of trusted networks are not interpreted, so they can't spoof the address.
+		const header = "PROXY TCP4 192.0.2.10 198.51.100.1 40000 5060\r\n"
+		pl, conns, c := listen(t, &config.ProxyProtocolConfig{Trusted: []string{"10.0.0.0/8"}})
+		_, err := c.Write([]byte(header))
+		require.NoError(t, err)
+		conn, err := pl.Accept()
+		require.NoError(t, err)
+		require.Equal(t, "127.0.0.1", conn.RemoteAddr().(*net.TCPAddr).IP.String())
+		buf := make([]byte, len(header))
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		require.Equal(t, header, string(buf))
+		_, ok := conns.Local("192.0.2.10:40000")
+		require.False(t, ok)
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		pl, _, c := listen(t, loopback)
+		_, err := c.Write([]byte("INVITE sip:a@example.com SIP/2.0\r\n"))
+		require.NoError(t, err)
+		// The connection is dropped without being accepted.
+		_ = c.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = c.Read(make([]byte, 1))
+		require.ErrorIs(t, err, io.EOF)
+
+		_ = pl.Close()
+		_, err = pl.Accept()
+		require.ErrorIs(t, err, net.ErrClosed)
+	})
+}
+
+func TestServerContactFor(t *testing.T) {
+	s := &Server{
+		conf:    &config.Config{SIPPort: 5060},
+		sconf:   &ServiceConfig{SignalingIP: newIP("10.0.0.1")},
+		proxied: newProxyConns(),
+	}
+	s.proxied.add("192.0.2.10:40000", netip.MustParseAddrPort("198.51.100.1:5070"))
+
+	req := sip.NewRequest(sip.INVITE, sip.Uri{User: "to", Host: "example.com"})
+	req.SetSource("192.0.2.10:40000")
+	require.Equal(t, "198.51.100.1:5070", s.contactFor(req, TransportTCP).Addr.String())
+
+	req.SetSource("192.0.2.11:40000")
+	require.Equal(t, "10.0.0.1:5060", s.contactFor(req, TransportTCP).Addr.String())
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+// publicIPTimeout limits a single discovery of the public IP.
+const publicIPTimeout = 10 * time.Second
+
+// publicIPs discovers the public IP via STUN periodically, and replaces signaling and media IPs
+// of the service config which came from STUN at startup.
+type publicIPs struct {
+	log       logger.Logger
+	mon       *stats.Monitor
+	interval  time.Duration
+	lookup    func(ctx context.Context) (netip.Addr, error)
+	signaling bool // signaling IP is discovered
+	media     bool // media IP is discovered
+	stop      core.Fuse
+
+	mu           sync.RWMutex
+	signalingIP  netip.Addr
+	mediaIP      netip.Addr
+	lookupFailed bool
+}
+
+func newPublicIPs(log logger.Logger, mon *stats.Monitor, conf *config.Config, sconf *ServiceConfig) *publicIPs {
+	if conf.PublicIP == nil {
+		return nil
+	}
+	servers := stunServers(conf)
+	p := &publicIPs{
+		log:         log,
+		mon:         mon,
+		interval:    conf.PublicIP.Interval,
+		signaling:   conf.UseExternalIP,
+		media:       conf.MediaUseExternalIP || (conf.UseExternalIP && sconf.MediaIP == sconf.SignalingIP),
+		signalingIP: sconf.SignalingIP,
+		mediaIP:     sconf.MediaIP,
+		lookup: func(ctx context.Context) (netip.Addr, error) {
+			ip, err := rtcconfig.GetExternalIP(ctx, servers, nil)
+			if err != nil {
+				return netip.Addr{}, err
+			}
+			return netip.ParseAddr(ip)
+		},
+	}
+	if p.interval == 0 {
+		p.interval = config.DefaultPublicIPInterval
+	}
+	sconf.public = p
+	return p
+}
+
+// Start checks the public IP periodically until Stop is called.
+func (p *publicIPs) Start() {
+	if p == nil {
+		return
+	}
+	go p.run()
+}
+
+func (p *publicIPs) Stop() {
+	if p == nil {
+		return
+	}
+	p.stop.Break()
+}
+
+func (p *publicIPs) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop.Watch():
+			return
+		case <-ticker.C:
+			p.check()
+		}
+	}
+}
+
+// check discovers the public IP and replaces addresses if it changed.
+func (p *publicIPs) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), publicIPTimeout)
+	defer cancel()
+	ip, err := p.lookup(ctx)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		// Keep the last known address, but only warn once until the discovery recovers.
+		if !p.lookupFailed {
+			p.log.Warnw("cannot discover public IP", err)
+		}
+		p.lookupFailed = true
+		return
+	}
+	p.lookupFailed = false
+	if p.signaling && ip != p.signalingIP {
+		p.log.Warnw("public signaling IP changed", nil, "previous", p.signalingIP, "ip", ip)
+		p.mon.PublicIPChanged("signaling")
+		p.signalingIP = ip
+	}
+	if p.media && ip != p.mediaIP {
+		p.log.Warnw("public media IP changed", nil, "previous", p.mediaIP, "ip", ip)
+		p.mon.PublicIPChanged("media")
+		p.mediaIP = ip
+	}
+}
+
+// SignalingIP returns the last discovered signaling IP.
+func (p *publicIPs) SignalingIP() netip.Addr {
+	if p == nil {
+		return netip.Addr{}
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.signalingIP
+}
+
+// MediaIP returns the last discovered media IP.
+func (p *publicIPs) MediaIP() netip.Addr {
+	if p == nil {
+		return netip.Addr{}
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.mediaIP
+}
+
+// signalingIP returns the signaling IP advertised in Contact and Via headers of new dialogs.
+func (c *ServiceConfig) signalingIP() netip.Addr {
+	if ip := c.public.SignalingIP(); ip.IsValid() {
+		return ip
+	}
+	return c.SignalingIP
+}
+
+// mediaIP returns the media IP advertised in SDP of new calls.
+func (c *ServiceConfig) mediaIP() netip.Addr {
+	if ip := c.public.MediaIP(); ip.IsValid() {
+		return ip
+	}
+	return c.MediaIP
+}
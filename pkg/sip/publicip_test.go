@@ -0,0 +1,104 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestPublicIPConfig(t *testing.T) {
+	require.NoError(t, (&config.PublicIPConfig{}).Validate())
+	require.NoError(t, (&config.PublicIPConfig{StunServers: []string{"stun.example.com:3478"}, Interval: time.Minute}).Validate())
+	require.Error(t, (&config.PublicIPConfig{StunServers: []string{"stun.example.com"}}).Validate())
+	require.Error(t, (&config.PublicIPConfig{Interval: -time.Second}).Validate())
+
+	require.Equal(t, []string{"stun.example.com:3478"}, stunServers(&config.Config{PublicIP: &config.PublicIPConfig{StunServers: []string{"stun.example.com:3478"}}}))
+	require.NotEmpty(t, stunServers(&config.Config{}))
+}
+
+func TestPublicIPs(t *testing.T) {
+	require.Nil(t, newPublicIPs(logger.GetLogger(), nil, &config.Config{}, &ServiceConfig{}))
+	var none *publicIPs
+	none.Start()
+	none.Stop()
+	static := &ServiceConfig{SignalingIP: netip.MustParseAddr("203.0.113.1"), MediaIP: netip.MustParseAddr("203.0.113.2")}
+	require.Equal(t, static.SignalingIP, static.signalingIP())
+	require.Equal(t, static.MediaIP, static.mediaIP())
+
+	var (
+		mu     sync.Mutex
+		public = netip.MustParseAddr("203.0.113.1")
+		err    error
+	)
+	set := func(ip netip.Addr, e error) {
+		mu.Lock()
+		defer mu.Unlock()
+		public, err = ip, e
+	}
+	newIPs := func(conf *config.Config, sconf *ServiceConfig) *publicIPs {
+		conf.PublicIP = &config.PublicIPConfig{Interval: 10 * time.Millisecond}
+		p := newPublicIPs(logger.GetLogger(), nil, conf, sconf)
+		p.lookup = func(ctx context.Context) (netip.Addr, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return public, err
+		}
+		return p
+	}
+
+	// Media follows the signaling IP discovered via STUN.
+	sconf := &ServiceConfig{SignalingIP: public, SignalingIPLocal: netip.MustParseAddr("10.0.0.1"), MediaIP: public}
+	p := newIPs(&config.Config{UseExternalIP: true}, sconf)
+	p.Start()
+	t.Cleanup(p.Stop)
+
+	changed := netip.MustParseAddr("203.0.113.5")
+	set(changed, nil)
+	require.Eventually(t, func() bool {
+		return sconf.signalingIP() == changed && sconf.mediaIP() == changed
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "203.0.113.5", getContactURI(&config.Config{SIPPort: 5060}, sconf.signalingIP(), TransportUDP).GetHost())
+
+	// The last known address is kept while discovery fails.
+	set(netip.Addr{}, errors.New("timeout"))
+	p.check()
+	require.Equal(t, changed, sconf.signalingIP())
+
+	// Static media IP is not replaced.
+	sconf = &ServiceConfig{SignalingIP: changed, MediaIP: netip.MustParseAddr("198.51.100.1")}
+	p = newIPs(&config.Config{UseExternalIP: true, MediaNAT1To1IP: "198.51.100.1"}, sconf)
+	set(netip.MustParseAddr("203.0.113.6"), nil)
+	p.check()
+	require.Equal(t, netip.MustParseAddr("203.0.113.6"), sconf.signalingIP())
+	require.Equal(t, netip.MustParseAddr("198.51.100.1"), sconf.mediaIP())
+
+	// Only media IP is discovered.
+	sconf = &ServiceConfig{SignalingIP: netip.MustParseAddr("10.0.0.1"), MediaIP: changed}
+	p = newIPs(&config.Config{MediaUseExternalIP: true}, sconf)
+	p.check()
+	require.Equal(t, netip.MustParseAddr("10.0.0.1"), sconf.signalingIP())
+	require.Equal(t, netip.MustParseAddr("203.0.113.6"), sconf.mediaIP())
+}
@@ -0,0 +1,297 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+const (
+	defaultQuotaPeriod = 24 * time.Hour
+	// quotaSweepInterval is how often idle projects are dropped from the usage map.
+	quotaSweepInterval = time.Minute
+	// quotaOtherProject is the metric label for projects without a configured quota.
+	quotaOtherProject = "other"
+)
+
+// Reasons for the project quota rejection metric.
+const (
+	quotaReasonCalls   = "calls"
+	quotaReasonRate    = "rate"
+	quotaReasonMinutes = "minutes"
+)
+
+type projectUsage struct {
+	leases map[*ProjectLease]struct{}
+	// Token bucket for calls per second.
+	tokens   float64
+	lastCall time.Time
+	// Call minutes used in the current period, including active calls up to the charged time.
+	periodStart time.Time
+	used        time.Duration
+	charged     time.Time
+	// timer fires when active calls are expected to use up the minutes quota.
+	timer *time.Timer
+}
+
+// charge accounts the time active calls ran since the last charge, and moves to a new period if needed.
+// Calls running across the period boundary are charged to the new period for the time after the boundary.
+func (u *projectUsage) charge(now time.Time, period time.Duration) {
+	n := time.Duration(len(u.leases))
+	if elapsed := now.Sub(u.periodStart); elapsed >= period {
+		if n == 0 {
+			u.periodStart = now
+		} else {
+			u.periodStart = u.periodStart.Add(elapsed / period * period)
+		}
+		u.used = 0
+		if u.charged.Before(u.periodStart) {
+			u.charged = u.periodStart
+		}
+	}
+	if n > 0 && now.After(u.charged) {
+		u.used += n * now.Sub(u.charged)
+	}
+	u.charged = now
+}
+
+// idle reports if the usage is the same as for a new entry, which means it can be dropped.
+func (u *projectUsage) idle(now time.Time, quota config.ProjectQuota) bool {
+	if len(u.leases) > 0 {
+		return false
+	}
+	if u.used > 0 && now.Sub(u.periodStart) < quotaPeriod(quota) {
+		return false
+	}
+	if quota.MaxCallsPerSecond > 0 {
+		burst := max(1, quota.MaxCallsPerSecond)
+		if u.tokens+now.Sub(u.lastCall).Seconds()*quota.MaxCallsPerSecond < burst {
+			return false
+		}
+	}
+	return true
+}
+
+func quotaPeriod(quota config.ProjectQuota) time.Duration {
+	if quota.Period <= 0 {
+		return defaultQuotaPeriod
+	}
+	return quota.Period
+}
+
+// ProjectQuotas enforces per-project resource limits for calls on this node.
+type ProjectQuotas struct {
+	log  logger.Logger
+	mon  *stats.Monitor
+	conf *config.Config
+	now  func() time.Time
+
+	mu        sync.Mutex
+	usage     map[string]*projectUsage
+	lastSweep time.Time
+}
+
+func NewProjectQuotas(log logger.Logger, mon *stats.Monitor, conf *config.Config) *ProjectQuotas {
+	return &ProjectQuotas{
+		log:   log,
+		mon:   mon,
+		conf:  conf,
+		now:   time.Now,
+		usage: make(map[string]*projectUsage),
+	}
+}
+
+// Acquire checks project limits and reserves a call slot. The returned lease must be released when the call ends.
+//
+// It returns a nil lease if there's nothing to track. Lease methods are safe to call on nil.
+func (q *ProjectQuotas) Acquire(projectID string, dir stats.CallDir) (*ProjectLease, error) {
+	if q == nil || projectID == "" {
+		return nil, nil
+	}
+	quota := q.conf.QuotaForProject(projectID)
+	period := quotaPeriod(quota)
+	label := q.metricLabel(projectID)
+	now := q.now()
+
+	q.mu.Lock()
+	if now.Sub(q.lastSweep) >= quotaSweepInterval {
+		q.sweep(now)
+	}
+	u := q.usage[projectID]
+	if u == nil {
+		u = &projectUsage{leases: make(map[*ProjectLease]struct{}), periodStart: now}
+		q.usage[projectID] = u
+	}
+	u.charge(now, period)
+	var (
+		reason string
+		err    error
+	)
+	if quota.MaxCallsPerSecond > 0 {
+		burst := max(1, quota.MaxCallsPerSecond)
+		u.tokens = min(burst, u.tokens+now.Sub(u.lastCall).Seconds()*quota.MaxCallsPerSecond)
+	}
+	maxMinutes := time.Duration(quota.MaxMinutes * float64(time.Minute))
+	switch {
+	case quota.MaxConcurrentCalls > 0 && len(u.leases) >= quota.MaxConcurrentCalls:
+		reason, err = quotaReasonCalls, siperrors.ErrProjectCallLimit
+	case quota.MaxCallsPerSecond > 0 && u.tokens < 1:
+		reason, err = quotaReasonRate, siperrors.ErrProjectRateLimit
+	case maxMinutes > 0 && u.used >= maxMinutes:
+		reason, err = quotaReasonMinutes, siperrors.ErrProjectMinutesExhausted
+	}
+	if quota.MaxCallsPerSecond > 0 {
+		u.lastCall = now
+	}
+	if err != nil {
+		active := len(u.leases)
+		q.mu.Unlock()
+		q.mon.ProjectQuotaRejected(label, dir, reason)
+		q.log.Infow("project quota exceeded", "projectID", projectID, "dir", dir, "reason", reason, "active", active)
+		return nil, err
+	}
+	if quota.MaxCallsPerSecond > 0 {
+		u.tokens--
+	}
+	l := &ProjectLease{q: q, projectID: projectID, label: label, dir: dir, start: now}
+	if maxMinutes > 0 {
+		l.remaining = maxMinutes - u.used
+	}
+	u.leases[l] = struct{}{}
+	q.checkMinutes(projectID, u, quota)
+	q.mu.Unlock()
+	q.mon.ProjectCallStart(label, dir)
+	return l, nil
+}
+
+func (q *ProjectQuotas) release(l *ProjectLease) {
+	now := q.now()
+	dur := now.Sub(l.start)
+	quota := q.conf.QuotaForProject(l.projectID)
+	q.mu.Lock()
+	if u := q.usage[l.projectID]; u != nil {
+		u.charge(now, quotaPeriod(quota))
+		delete(u.leases, l)
+		q.checkMinutes(l.projectID, u, quota)
+		if u.idle(now, quota) {
+			delete(q.usage, l.projectID)
+		}
+	}
+	q.mu.Unlock()
+	q.mon.ProjectCallEnd(l.label, l.dir, dur)
+}
+
+// checkMinutes ends active calls if the project used up its minutes, or schedules the next check
+// for the time when active calls will use up what's left. Must be called with the lock held, after charging the usage.
+func (q *ProjectQuotas) checkMinutes(projectID string, u *projectUsage, quota config.ProjectQuota) {
+	if u.timer != nil {
+		u.timer.Stop()
+		u.timer = nil
+	}
+	maxMinutes := time.Duration(quota.MaxMinutes * float64(time.Minute))
+	if maxMinutes <= 0 || len(u.leases) == 0 {
+		return
+	}
+	left := maxMinutes - u.used
+	if left <= 0 {
+		for l := range u.leases {
+			l.exhausted.Break()
+		}
+		return
+	}
+	// Active calls share what's left, each of them consumes it at the same rate.
+	u.timer = time.AfterFunc(left/time.Duration(len(u.leases)), func() {
+		q.onMinutesTimer(projectID)
+	})
+}
+
+func (q *ProjectQuotas) onMinutesTimer(projectID string) {
+	quota := q.conf.QuotaForProject(projectID)
+	now := q.now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if u := q.usage[projectID]; u != nil {
+		u.charge(now, quotaPeriod(quota))
+		q.checkMinutes(projectID, u, quota)
+	}
+}
+
+// sweep drops idle projects from the usage map. Must be called with the lock held.
+func (q *ProjectQuotas) sweep(now time.Time) {
+	q.lastSweep = now
+	for id, u := range q.usage {
+		if u.idle(now, q.conf.QuotaForProject(id)) {
+			delete(q.usage, id)
+		}
+	}
+}
+
+// metricLabel returns the project label for metrics. Only projects with a configured quota get their own label,
+// to keep the number of series bounded.
+func (q *ProjectQuotas) metricLabel(projectID string) string {
+	if _, ok := q.conf.ProjectQuotas[projectID]; ok {
+		return projectID
+	}
+	return quotaOtherProject
+}
+
+// ProjectLease is a call slot acquired from ProjectQuotas.
+type ProjectLease struct {
+	q         *ProjectQuotas
+	projectID string
+	label     string
+	dir       stats.CallDir
+	start     time.Time
+	remaining time.Duration
+	exhausted core.Fuse
+	released  atomic.Bool
+}
+
+// MaxDuration returns the call duration allowed by the project minutes quota, or zero if unlimited.
+//
+// It's the limit for a call running alone. Concurrent calls of the project share the minutes,
+// so the call may need to end earlier, see Exhausted.
+func (l *ProjectLease) MaxDuration() time.Duration {
+	if l == nil {
+		return 0
+	}
+	return l.remaining
+}
+
+// Exhausted returns a channel that is closed when active calls of the project use up its minutes quota.
+// The call must end when it happens. The channel is nil for a nil lease.
+func (l *ProjectLease) Exhausted() <-chan struct{} {
+	if l == nil {
+		return nil
+	}
+	return l.exhausted.Watch()
+}
+
+// Release returns the call slot and accounts call duration. It's safe to call it multiple times.
+func (l *ProjectLease) Release() {
+	if l == nil || !l.released.CompareAndSwap(false, true) {
+		return
+	}
+	l.q.release(l)
+}
@@ -0,0 +1,194 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+	siperrors "github.com/livekit/sip/pkg/errors"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+func newTestQuotas(conf *config.Config) (*ProjectQuotas, *time.Time) {
+	now := time.Unix(1000, 0)
+	q := NewProjectQuotas(logger.GetLogger(), nil, conf)
+	q.now = func() time.Time { return now }
+	return q, &now
+}
+
+func TestProjectQuotas(t *testing.T) {
+	t.Run("unlimited", func(t *testing.T) {
+		q, _ := newTestQuotas(&config.Config{})
+		for range 10 {
+			l, err := q.Acquire("p1", stats.Inbound)
+			require.NoError(t, err)
+			require.Zero(t, l.MaxDuration())
+		}
+		l, err := q.Acquire("", stats.Inbound)
+		require.NoError(t, err)
+		require.Nil(t, l)
+		l.Release()
+	})
+	t.Run("concurrent", func(t *testing.T) {
+		q, _ := newTestQuotas(&config.Config{
+			ProjectQuota:  config.ProjectQuota{MaxConcurrentCalls: 2},
+			ProjectQuotas: map[string]*config.ProjectQuota{"p2": {MaxConcurrentCalls: 1}},
+		})
+		l1, err := q.Acquire("p1", stats.Inbound)
+		require.NoError(t, err)
+		_, err = q.Acquire("p1", stats.Outbound)
+		require.NoError(t, err)
+		_, err = q.Acquire("p1", stats.Inbound)
+		require.ErrorIs(t, err, siperrors.ErrProjectCallLimit)
+
+		// Projects are isolated.
+		_, err = q.Acquire("p2", stats.Inbound)
+		require.NoError(t, err)
+		_, err = q.Acquire("p2", stats.Inbound)
+		require.ErrorIs(t, err, siperrors.ErrProjectCallLimit)
+
+		l1.Release()
+		l1.Release()
+		_, err = q.Acquire("p1", stats.Inbound)
+		require.NoError(t, err)
+		_, err = q.Acquire("p1", stats.Inbound)
+		require.ErrorIs(t, err, siperrors.ErrProjectCallLimit)
+	})
+	t.Run("rate", func(t *testing.T) {
+		q, now := newTestQuotas(&config.Config{
+			ProjectQuota: config.ProjectQuota{MaxCallsPerSecond: 2},
+		})
+		for range 2 {
+			_, err := q.Acquire("p1", stats.Inbound)
+			require.NoError(t, err)
+		}
+		_, err := q.Acquire("p1", stats.Inbound)
+		require.ErrorIs(t, err, siperrors.ErrProjectRateLimit)
+
+		*now = now.Add(500 * time.Millisecond)
+		_, err = q.Acquire("p1", stats.Inbound)
+		require.NoError(t, err)
+		_, err = q.Acquire("p1", stats.Inbound)
+		require.ErrorIs(t, err, siperrors.ErrProjectRateLimit)
+	})
+	t.Run("minutes", func(t *testing.T) {
+		q, now := newTestQuotas(&config.Config{
+			ProjectQuota: config.ProjectQuota{MaxMinutes: 10, Period: time.Hour},
+		})
+		l, err := q.Acquire("p1", stats.Inbound)
+		require.NoError(t, err)
+		require.Equal(t, 10*time.Minute, l.MaxDuration())
+		*now = now.Add(6 * time.Minute)
+		l.Release()
+
+		l, err = q.Acquire("p1", stats.Inbound)
+		require.NoError(t, err)
+		require.Equal(t, 4*time.Minute, l.MaxDuration())
+		*now = now.Add(4 * time.Minute)
+		l.Release()
+
+		_, err = q.Acquire("p1", stats.Inbound)
+		require.ErrorIs(t, err, siperrors.ErrProjectMinutesExhausted)
+
+		// New period resets the usage.
+		*now = now.Add(time.Hour)
+		l, err = q.Acquire("p1", stats.Inbound)
+		require.NoError(t, err)
+		require.Equal(t, 10*time.Minute, l.MaxDuration())
+	})
+	t.Run("evict", func(t *testing.T) {
+		q, now := newTestQuotas(&config.Config{
+			ProjectQuota: config.ProjectQuota{MaxCallsPerSecond: 1, MaxMinutes: 10, Period: time.Hour},
+		})
+		l1, err := q.Acquire("p1", stats.Inbound)
+		require.NoError(t, err)
+		l2, err := q.Acquire("p2", stats.Inbound)
+		require.NoError(t, err)
+		*now = now.Add(time.Minute)
+		l2.Release()
+		// Minutes used by p2 are kept until the period ends.
+		require.Len(t, q.usage, 2)
+
+		*now = now.Add(time.Hour)
+		_, err = q.Acquire("p3", stats.Inbound)
+		require.NoError(t, err)
+		require.Len(t, q.usage, 2)
+		require.Nil(t, q.usage["p2"])
+
+		// The call ran into the current period, so its minutes are kept until the period ends.
+		*now = now.Add(2 * time.Hour)
+		l1.Release()
+		require.Equal(t, time.Minute, q.usage["p1"].used)
+
+		// Entries are dropped as soon as they don't hold any state.
+		*now = now.Add(time.Hour)
+		q.sweep(*now)
+		require.Nil(t, q.usage["p1"])
+	})
+	t.Run("minutes shared", func(t *testing.T) {
+		q, now := newTestQuotas(&config.Config{
+			ProjectQuota: config.ProjectQuota{MaxMinutes: 1, Period: time.Hour},
+		})
+		l1, err := q.Acquire("p1", stats.Inbound)
+		require.NoError(t, err)
+		l2, err := q.Acquire("p1", stats.Outbound)
+		require.NoError(t, err)
+		require.Equal(t, time.Minute, l2.MaxDuration())
+		require.NotNil(t, q.usage["p1"].timer)
+
+		*now = now.Add(20 * time.Second)
+		q.onMinutesTimer("p1")
+		require.False(t, l1.exhausted.IsBroken())
+
+		// Both calls consume the budget, so it's used up in half the time.
+		*now = now.Add(10 * time.Second)
+		_, err = q.Acquire("p1", stats.Inbound)
+		require.ErrorIs(t, err, siperrors.ErrProjectMinutesExhausted)
+		q.onMinutesTimer("p1")
+		<-l1.Exhausted()
+		<-l2.Exhausted()
+
+		l1.Release()
+		l2.Release()
+		require.Nil(t, q.usage["p1"].timer)
+	})
+	t.Run("minutes across periods", func(t *testing.T) {
+		q, now := newTestQuotas(&config.Config{
+			ProjectQuota: config.ProjectQuota{MaxMinutes: 5, Period: 10 * time.Minute},
+		})
+		l1, err := q.Acquire("p1", stats.Inbound)
+		require.NoError(t, err)
+		*now = now.Add(12 * time.Minute)
+
+		// The call is still running, so the first 2 minutes of the new period are charged.
+		l2, err := q.Acquire("p1", stats.Inbound)
+		require.NoError(t, err)
+		require.Equal(t, 3*time.Minute, l2.MaxDuration())
+		l1.Release()
+		l2.Release()
+	})
+	t.Run("metric label", func(t *testing.T) {
+		q, _ := newTestQuotas(&config.Config{
+			ProjectQuotas: map[string]*config.ProjectQuota{"p1": {}},
+		})
+		require.Equal(t, "p1", q.metricLabel("p1"))
+		require.Equal(t, quotaOtherProject, q.metricLabel("p2"))
+	})
+}
@@ -0,0 +1,225 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	lksdk "github.com/livekit/server-sdk-go/v2"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	defaultRecordingSyncKey = "sip_calls"
+	// recordingSyncTimeout limits how long a single room metadata update may take.
+	recordingSyncTimeout = 10 * time.Second
+)
+
+// recordingEvent is a point in time of the call leg, published as an offset from the start of the leg.
+type recordingEvent string
+
+const (
+	recordingAnswered = recordingEvent("answered")
+	recordingJoined   = recordingEvent("joined")
+	recordingMedia    = recordingEvent("first_media")
+	recordingEnded    = recordingEvent("ended")
+)
+
+// recordingLeg describes a SIP call leg in room metadata.
+type recordingLeg struct {
+	CallID    string `json:"call_id"`
+	SIPCallID string `json:"sip_call_id"`
+	Direction string `json:"direction"`
+	TrunkID   string `json:"trunk_id,omitempty"`
+	// StartedAtMs is the time the INVITE was received or sent, in Unix milliseconds.
+	StartedAtMs int64 `json:"started_at_ms"`
+	// OffsetsMs are offsets of call events from StartedAtMs, in milliseconds.
+	OffsetsMs map[recordingEvent]int64 `json:"offsets_ms,omitempty"`
+}
+
+// recordingSync publishes a call leg in room metadata, so that room recordings can be aligned with SIP CDRs.
+//
+// Events are recorded from the start of the call, but only published once the participant joins the room.
+// Updates are sent one at a time. Events recorded while an update is in flight are sent with the next one.
+type recordingSync struct {
+	log   logger.Logger
+	conf  *config.Config
+	key   string
+	start time.Time
+
+	mu      sync.Mutex
+	leg     recordingLeg
+	room    *lksdk.Room
+	rooms   *lksdk.RoomServiceClient
+	pending bool
+	running bool
+}
+
+// newRecordingSync returns a publisher for the call leg, or nil if recording sync is disabled.
+func newRecordingSync(log logger.Logger, conf *config.Config, leg recordingLeg) *recordingSync {
+	if !conf.RecordingSync.Enabled {
+		return nil
+	}
+	start := time.Now()
+	leg.StartedAtMs = start.UnixMilli()
+	leg.OffsetsMs = make(map[recordingEvent]int64)
+	return &recordingSync{
+		log:   log,
+		conf:  conf,
+		key:   cmp.Or(conf.RecordingSync.MetadataKey, defaultRecordingSyncKey),
+		start: start,
+		leg:   leg,
+	}
+}
+
+// SetTrunk sets the trunk ID of the call leg, once it's known.
+func (s *recordingSync) SetTrunk(trunkID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leg.TrunkID = trunkID
+}
+
+// SetSIPCallID sets the SIP Call-ID of the call leg, once it's known.
+func (s *recordingSync) SetSIPCallID(callID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leg.SIPCallID = callID
+}
+
+// Joined starts publishing the call leg to the room. The URL is used for room service requests.
+func (s *recordingSync) Joined(room *Room, wsURL string) {
+	if s == nil {
+		return
+	}
+	lk := room.Room()
+	if lk == nil {
+		return
+	}
+	if s.conf.ApiKey == "" || s.conf.ApiSecret == "" {
+		s.log.Infow("cannot publish call to room metadata, api key is not set")
+		return
+	}
+	s.mu.Lock()
+	s.rooms = lksdk.NewRoomServiceClient(cmp.Or(wsURL, s.conf.WsUrl), s.conf.ApiKey, s.conf.ApiSecret)
+	s.room = lk
+	s.mu.Unlock()
+	s.Mark(recordingJoined)
+}
+
+// Mark records the event at the current time, and publishes it if the participant is in the room.
+func (s *recordingSync) Mark(ev recordingEvent) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.leg.OffsetsMs[ev]; ok {
+		return
+	}
+	s.leg.OffsetsMs[ev] = time.Since(s.start).Milliseconds()
+	if s.room == nil {
+		return
+	}
+	if s.running {
+		s.pending = true
+		return
+	}
+	s.running = true
+	go s.publishLoop()
+}
+
+func (s *recordingSync) publishLoop() {
+	for {
+		s.mu.Lock()
+		leg := s.leg
+		leg.OffsetsMs = maps.Clone(s.leg.OffsetsMs)
+		room, rooms := s.room, s.rooms
+		s.mu.Unlock()
+
+		if err := publishRecordingLeg(rooms, room, s.key, &leg); err != nil {
+			s.log.Warnw("cannot publish call to room metadata", err)
+		}
+
+		s.mu.Lock()
+		if !s.pending {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		s.pending = false
+		s.mu.Unlock()
+	}
+}
+
+func publishRecordingLeg(rooms *lksdk.RoomServiceClient, room *lksdk.Room, key string, leg *recordingLeg) error {
+	meta, err := mergeRecordingLeg(room.Metadata(), key, room.LocalParticipant.Identity(), leg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), recordingSyncTimeout)
+	defer cancel()
+	_, err = rooms.UpdateRoomMetadata(ctx, &livekit.UpdateRoomMetadataRequest{Room: room.Name(), Metadata: meta})
+	return err
+}
+
+// mergeRecordingLeg sets the call leg of the participant in room metadata, keeping all other keys and legs.
+func mergeRecordingLeg(meta, key, identity string, leg *recordingLeg) (string, error) {
+	root := make(map[string]json.RawMessage)
+	if meta != "" {
+		if err := json.Unmarshal([]byte(meta), &root); err != nil {
+			return "", fmt.Errorf("room metadata is not a JSON object: %w", err)
+		}
+	}
+	var legs map[string]json.RawMessage
+	if v, ok := root[key]; ok {
+		if err := json.Unmarshal(v, &legs); err != nil {
+			return "", fmt.Errorf("room metadata key %q is not a JSON object: %w", key, err)
+		}
+	}
+	if root == nil { // metadata was null
+		root = make(map[string]json.RawMessage)
+	}
+	if legs == nil {
+		legs = make(map[string]json.RawMessage)
+	}
+	v, err := json.Marshal(leg)
+	if err != nil {
+		return "", err
+	}
+	legs[identity] = v
+	if root[key], err = json.Marshal(legs); err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(root)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
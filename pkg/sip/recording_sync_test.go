@@ -0,0 +1,79 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestMergeRecordingLeg(t *testing.T) {
+	leg := &recordingLeg{
+		CallID:      "SCL_1",
+		SIPCallID:   "abc@host",
+		Direction:   "inbound",
+		StartedAtMs: 1000,
+		OffsetsMs:   map[recordingEvent]int64{recordingAnswered: 20, recordingJoined: 150},
+	}
+	const legJSON = `{"call_id":"SCL_1","sip_call_id":"abc@host","direction":"inbound","started_at_ms":1000,"offsets_ms":{"answered":20,"joined":150}}`
+
+	meta, err := mergeRecordingLeg("", "sip_calls", "sip_1", leg)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"sip_calls":{"sip_1":`+legJSON+`}}`, meta)
+
+	meta, err = mergeRecordingLeg(`{"caller_id":"+15550100","sip_calls":{"sip_2":{"call_id":"SCL_2"}}}`, "sip_calls", "sip_1", leg)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"caller_id":"+15550100","sip_calls":{"sip_2":{"call_id":"SCL_2"},"sip_1":`+legJSON+`}}`, meta)
+
+	meta, err = mergeRecordingLeg(`null`, "sip_calls", "sip_1", leg)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"sip_calls":{"sip_1":`+legJSON+`}}`, meta)
+
+	_, err = mergeRecordingLeg("plain text", "sip_calls", "sip_1", leg)
+	require.Error(t, err)
+	_, err = mergeRecordingLeg(`{"sip_calls":"x"}`, "sip_calls", "sip_1", leg)
+	require.Error(t, err)
+}
+
+func TestRecordingSync(t *testing.T) {
+	var s *recordingSync
+	s.Mark(recordingAnswered) // disabled, must not panic
+
+	conf := &config.Config{}
+	require.Nil(t, newRecordingSync(logger.GetLogger(), conf, recordingLeg{}))
+
+	conf.RecordingSync.Enabled = true
+	s = newRecordingSync(logger.GetLogger(), conf, recordingLeg{CallID: "SCL_1", Direction: "outbound"})
+	require.NotNil(t, s)
+	s.SetSIPCallID("abc@host")
+	s.Mark(recordingAnswered)
+	s.Mark(recordingMedia)
+	s.Mark(recordingAnswered) // only the first time is kept
+
+	// Events are kept until the participant joins the room.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.False(t, s.running)
+	require.Equal(t, "abc@host", s.leg.SIPCallID)
+	require.NotZero(t, s.leg.StartedAtMs)
+	require.Len(t, s.leg.OffsetsMs, 2)
+	require.Contains(t, s.leg.OffsetsMs, recordingAnswered)
+	require.Contains(t, s.leg.OffsetsMs, recordingMedia)
+}
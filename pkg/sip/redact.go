@@ -0,0 +1,377 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const redactMask = '*'
+
+var (
+	// redactLogFields are log fields with phone numbers or SIP URIs.
+	redactLogFields = []string{
+		"from", "to", "fromUser", "toUser", "toUserOrig", "caller", "callee", "callerID",
+		"number", "uri", "transferTo", "redirectTo", "originalCalled",
+	}
+	// redactLogNames are log fields with names of people, which are masked completely.
+	redactLogNames = []string{"callerName"}
+	// redactAttrs are participant attributes with phone numbers or SIP URIs.
+	redactAttrs = []string{
+		livekit.AttrSIPPhoneNumber, livekit.AttrSIPTrunkNumber, AttrSIPCallerID,
+	}
+)
+
+// redactor masks personal data in logs and call info.
+type redactor struct {
+	conf     *config.RedactionConfig
+	fields   map[string]bool // log field -> masked completely
+	attrs    map[string]bool // attribute -> masked completely
+	patterns []*regexp.Regexp
+}
+
+// newRedactor creates a redactor from the config. It returns nil if redaction is disabled.
+func newRedactor(conf *config.RedactionConfig) (*redactor, error) {
+	if conf == nil {
+		return nil, nil
+	}
+	r := &redactor{
+		conf:   conf,
+		fields: make(map[string]bool),
+		attrs:  make(map[string]bool),
+	}
+	for _, f := range slices.Concat(redactLogFields, conf.Fields) {
+		r.fields[f] = false
+	}
+	for _, f := range redactLogNames {
+		r.fields[f] = true
+	}
+	for _, a := range redactAttrs {
+		r.attrs[a] = false
+	}
+	r.attrs[AttrSIPCallerName] = true
+	for _, h := range conf.Headers {
+		r.attrs[livekit.AttrSIPHeaderPrefix+strings.ToLower(h)] = false
+	}
+	for _, p := range conf.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Mask replaces all characters of the value with "*".
+func (r *redactor) Mask(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.Repeat(string(redactMask), len([]rune(s)))
+}
+
+// Number masks digits of a phone number, except the trailing ones configured with keep_digits.
+// Values without digits, such as user names, are masked completely.
+func (r *redactor) Number(s string) string {
+	digits := 0
+	for _, c := range s {
+		if isDigit(c) {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return r.Mask(s)
+	}
+	mask := digits - r.conf.KeepDigits
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, c := range s {
+		if isDigit(c) && mask > 0 {
+			mask--
+			c = redactMask
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Value masks a phone number or the user part of a SIP or tel URI, keeping the scheme, host and parameters.
+// Other values are only masked if they contain digits, so that states logged in the same fields remain readable.
+func (r *redactor) Value(s string) string {
+	scheme, rest, ok := strings.Cut(s, ":")
+	switch lower := strings.ToLower(scheme); {
+	case ok && (lower == "sip" || lower == "sips"):
+		user, host, ok := strings.Cut(rest, "@")
+		if !ok {
+			return s
+		}
+		return scheme + ":" + r.Number(user) + "@" + host
+	case ok && lower == "tel":
+		num, params, _ := strings.Cut(rest, ";")
+		if params != "" {
+			params = ";" + params
+		}
+		return scheme + ":" + r.Number(num) + params
+	}
+	if user, host, ok := strings.Cut(s, "@"); ok {
+		return r.Number(user) + "@" + host
+	}
+	if strings.ContainsFunc(s, isDigit) {
+		return r.Number(s)
+	}
+	return s
+}
+
+// Patterns masks parts of the value which match configured patterns.
+func (r *redactor) Patterns(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllStringFunc(s, r.Number)
+	}
+	return s
+}
+
+// URI masks the user of a SIP URI.
+func (r *redactor) URI(u sip.Uri) sip.Uri {
+	if u.User != "" {
+		u.User = r.Number(u.User)
+	}
+	return u
+}
+
+// LogValues masks values of log fields with personal data.
+func (r *redactor) LogValues(keysAndValues []any) []any {
+	var out []any
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, _ := keysAndValues[i].(string)
+		v := keysAndValues[i+1]
+		full, known := r.fields[key]
+		var nv any
+		switch v := v.(type) {
+		case string:
+			s := v
+			if full {
+				s = r.Mask(s)
+			} else if known {
+				s = r.Value(s)
+			}
+			if s = r.Patterns(s); s != v {
+				nv = s
+			}
+		case sip.Uri:
+			if known {
+				nv = r.URI(v)
+			}
+		case *sip.Uri:
+			if known && v != nil {
+				u := r.URI(*v)
+				nv = &u
+			}
+		case fmt.Stringer:
+			if known {
+				nv = r.Value(v.String())
+			}
+		}
+		if nv == nil {
+			continue
+		}
+		if out == nil {
+			out = append([]any(nil), keysAndValues...)
+		}
+		out[i+1] = nv
+	}
+	if out == nil {
+		return keysAndValues
+	}
+	return out
+}
+
+// Error masks parts of the error message which match configured patterns. The original error is still unwrapped.
+func (r *redactor) Error(err error) error {
+	if err == nil || len(r.patterns) == 0 {
+		return err
+	}
+	msg := r.Patterns(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// Logger returns a logger which masks personal data, if redaction of logs is enabled.
+func (r *redactor) Logger(log logger.Logger) logger.Logger {
+	if r == nil || !r.conf.Logs {
+		return log
+	}
+	if rl, ok := log.(*redactedLogger); ok {
+		return rl
+	}
+	return &redactedLogger{Logger: log.WithCallDepth(1), r: r}
+}
+
+// redactedLogger masks personal data in log fields. Loggers derived from it are redacted as well.
+//
+// Values of WithUnlikelyValues are redacted, but fields of lines logged through the returned logger are not.
+type redactedLogger struct {
+	logger.Logger
+	r *redactor
+}
+
+func (l *redactedLogger) wrap(log logger.Logger) logger.Logger {
+	return &redactedLogger{Logger: log, r: l.r}
+}
+
+func (l *redactedLogger) Debugw(msg string, keysAndValues ...any) {
+	l.Logger.Debugw(msg, l.r.LogValues(keysAndValues)...)
+}
+
+func (l *redactedLogger) Infow(msg string, keysAndValues ...any) {
+	l.Logger.Infow(msg, l.r.LogValues(keysAndValues)...)
+}
+
+func (l *redactedLogger) Warnw(msg string, err error, keysAndValues ...any) {
+	l.Logger.Warnw(msg, l.r.Error(err), l.r.LogValues(keysAndValues)...)
+}
+
+func (l *redactedLogger) Errorw(msg string, err error, keysAndValues ...any) {
+	l.Logger.Errorw(msg, l.r.Error(err), l.r.LogValues(keysAndValues)...)
+}
+
+func (l *redactedLogger) WithValues(keysAndValues ...any) logger.Logger {
+	return l.wrap(l.Logger.WithValues(l.r.LogValues(keysAndValues)...))
+}
+
+func (l *redactedLogger) WithUnlikelyValues(keysAndValues ...any) logger.UnlikelyLogger {
+	return l.Logger.WithUnlikelyValues(l.r.LogValues(keysAndValues)...)
+}
+
+func (l *redactedLogger) WithName(name string) logger.Logger {
+	return l.wrap(l.Logger.WithName(name))
+}
+
+func (l *redactedLogger) WithComponent(component string) logger.Logger {
+	return l.wrap(l.Logger.WithComponent(component))
+}
+
+func (l *redactedLogger) WithCallDepth(depth int) logger.Logger {
+	return l.wrap(l.Logger.WithCallDepth(depth))
+}
+
+func (l *redactedLogger) WithItemSampler() logger.Logger {
+	return l.wrap(l.Logger.WithItemSampler())
+}
+
+func (l *redactedLogger) WithoutSampler() logger.Logger {
+	return l.wrap(l.Logger.WithoutSampler())
+}
+
+func (l *redactedLogger) WithDeferredValues() (logger.Logger, logger.DeferredFieldResolver) {
+	log, res := l.Logger.WithDeferredValues()
+	return l.wrap(log), &redactedResolver{DeferredFieldResolver: res, r: l.r}
+}
+
+type redactedResolver struct {
+	logger.DeferredFieldResolver
+	r *redactor
+}
+
+func (res *redactedResolver) Resolve(args ...any) {
+	res.DeferredFieldResolver.Resolve(res.r.LogValues(args)...)
+}
+
+// CallInfo returns a copy of the call info with personal data masked.
+func (r *redactor) CallInfo(info *livekit.SIPCallInfo) *livekit.SIPCallInfo {
+	info = proto.Clone(info).(*livekit.SIPCallInfo)
+	for _, u := range []*livekit.SIPUri{info.FromUri, info.ToUri} {
+		if u != nil && u.User != "" {
+			u.User = r.Number(u.User)
+		}
+	}
+	for k, v := range info.ParticipantAttributes {
+		if full, ok := r.attrs[k]; ok && full {
+			v = r.Mask(v)
+		} else if ok {
+			v = r.Value(v)
+		}
+		info.ParticipantAttributes[k] = r.Patterns(v)
+	}
+	info.Error = r.Patterns(info.Error)
+	return info
+}
+
+// TransferInfo returns a copy of the transfer info with personal data masked.
+func (r *redactor) TransferInfo(info *livekit.SIPTransferInfo) *livekit.SIPTransferInfo {
+	info = proto.Clone(info).(*livekit.SIPTransferInfo)
+	info.TransferTo = r.Value(info.TransferTo)
+	info.Error = r.Patterns(info.Error)
+	return info
+}
+
+// IOInfoClient returns IO clients which mask personal data in call info, if redaction of call info is enabled.
+func (r *redactor) IOInfoClient(get GetIOInfoClient) GetIOInfoClient {
+	if r == nil || !r.conf.CallInfo || get == nil {
+		return get
+	}
+	return func(projectID string) rpc.IOInfoClient {
+		cli := get(projectID)
+		if cli == nil {
+			return nil
+		}
+		return &redactedIOClient{IOInfoClient: cli, r: r}
+	}
+}
+
+type redactedIOClient struct {
+	rpc.IOInfoClient
+	r *redactor
+}
+
+func (c *redactedIOClient) UpdateSIPCallState(ctx context.Context, req *rpc.UpdateSIPCallStateRequest, opts ...psrpc.RequestOption) (*emptypb.Empty, error) {
+	out := &rpc.UpdateSIPCallStateRequest{}
+	if req.CallInfo != nil {
+		out.CallInfo = c.r.CallInfo(req.CallInfo)
+	}
+	if req.TransferInfo != nil {
+		out.TransferInfo = c.r.TransferInfo(req.TransferInfo)
+	}
+	return c.IOInfoClient.UpdateSIPCallState(ctx, out, opts...)
+}
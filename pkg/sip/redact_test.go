@@ -0,0 +1,186 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func newTestRedactor(t testing.TB, conf config.RedactionConfig) *redactor {
+	require.NoError(t, conf.Validate())
+	r, err := newRedactor(&conf)
+	require.NoError(t, err)
+	return r
+}
+
+func TestRedactValue(t *testing.T) {
+	r := newTestRedactor(t, config.RedactionConfig{Logs: true, KeepDigits: 4})
+	for _, c := range []struct {
+		in, exp string
+	}{
+		{"+14155550123", "+*******0123"},
+		{"123", "123"},
+		{"sip:+14155550123@sip.example.com;transport=tcp", "sip:+*******0123@sip.example.com;transport=tcp"},
+		{"sips:alice@example.com", "sips:*****@example.com"},
+		{"tel:+1-415-555-0123;phone-context=example.com", "tel:+*-***-***-0123;phone-context=example.com"},
+		{"sip:example.com", "sip:example.com"},
+		{"alice@example.com", "*****@example.com"},
+		// Values without digits are left as is, for example dialog states logged as from and to.
+		{"answered", "answered"},
+		{"", ""},
+	} {
+		require.Equal(t, c.exp, r.Value(c.in), c.in)
+	}
+
+	r = newTestRedactor(t, config.RedactionConfig{CallInfo: true})
+	require.Equal(t, "+***********", r.Number("+14155550123"))
+	require.Equal(t, "*****", r.Number("alice"))
+	require.Equal(t, "****", r.Mask("Jose"))
+
+	require.Error(t, (&config.RedactionConfig{}).Validate())
+	require.Error(t, (&config.RedactionConfig{Logs: true, KeepDigits: -1}).Validate())
+	require.Error(t, (&config.RedactionConfig{Logs: true, Patterns: []string{"("}}).Validate())
+	require.Error(t, (&config.RedactionConfig{Logs: true, Headers: []string{""}}).Validate())
+	nilR, err := newRedactor(nil)
+	require.NoError(t, err)
+	require.Nil(t, nilR)
+}
+
+type testLogLine struct {
+	msg    string
+	err    error
+	fields []any
+}
+
+// testRecordLogger records lines, including fields added with WithValues.
+type testRecordLogger struct {
+	logger.Logger
+	fields []any
+	lines  *[]testLogLine
+}
+
+func newTestRecordLogger() *testRecordLogger {
+	return &testRecordLogger{Logger: logger.GetLogger(), lines: new([]testLogLine)}
+}
+
+func (l *testRecordLogger) Infow(msg string, keysAndValues ...any) {
+	*l.lines = append(*l.lines, testLogLine{msg: msg, fields: append(append([]any(nil), l.fields...), keysAndValues...)})
+}
+
+func (l *testRecordLogger) Warnw(msg string, err error, keysAndValues ...any) {
+	*l.lines = append(*l.lines, testLogLine{msg: msg, err: err, fields: append(append([]any(nil), l.fields...), keysAndValues...)})
+}
+
+func (l *testRecordLogger) WithValues(keysAndValues ...any) logger.Logger {
+	return &testRecordLogger{Logger: l.Logger, fields: append(append([]any(nil), l.fields...), keysAndValues...), lines: l.lines}
+}
+
+func (l *testRecordLogger) WithCallDepth(int) logger.Logger {
+	return l
+}
+
+func TestRedactedLogger(t *testing.T) {
+	r := newTestRedactor(t, config.RedactionConfig{
+		Logs:       true,
+		KeepDigits: 2,
+		Fields:     []string{"pai"},
+		Patterns:   []string{`\b\d{3}-\d{2}-\d{4}\b`},
+	})
+	rec := newTestRecordLogger()
+	log := r.Logger(rec)
+	require.Same(t, log, r.Logger(log))
+
+	log = log.WithValues("fromUser", "+15550100", "sipCallID", "abc-123")
+	log.Infow("call",
+		"toUser", sip.Uri{User: "+15550199", Host: "example.com"},
+		"uri", &sip.Uri{User: "bob", Host: "example.com"},
+		"callerName", "Alice Smith",
+		"pai", "<sip:+15550111@example.com>",
+		"note", "ssn 123-45-6789",
+		"port", 5060,
+	)
+	log.Warnw("failed", errors.New("caller 123-45-6789 rejected"))
+
+	lines := *rec.lines
+	require.Len(t, lines, 2)
+	require.Equal(t, []any{
+		"fromUser", "+******00", "sipCallID", "abc-123",
+		"toUser", sip.Uri{User: "+******99", Host: "example.com"},
+		"uri", &sip.Uri{User: "***", Host: "example.com"},
+		"callerName", "***********",
+		"pai", "<sip:+******11@example.com>",
+		"note", "ssn ***-**-**89",
+		"port", 5060,
+	}, lines[0].fields)
+	require.EqualError(t, lines[1].err, "caller ***-**-**89 rejected")
+
+	// Loggers are not wrapped if only call info is redacted.
+	r = newTestRedactor(t, config.RedactionConfig{CallInfo: true})
+	require.Same(t, rec, r.Logger(rec))
+	require.Same(t, rec, (*redactor)(nil).Logger(rec))
+}
+
+func TestRedactCallInfo(t *testing.T) {
+	r := newTestRedactor(t, config.RedactionConfig{CallInfo: true, KeepDigits: 4, Headers: []string{"P-Asserted-Identity"}})
+	upd := &testStateUpdater{infos: make(chan *livekit.SIPCallInfo, 1)}
+	get := r.IOInfoClient(func(projectID string) rpc.IOInfoClient { return upd })
+
+	info := &livekit.SIPCallInfo{
+		CallId:  "SCL_test",
+		FromUri: &livekit.SIPUri{User: "+14155550123", Host: "example.com"},
+		ToUri:   &livekit.SIPUri{User: "+14155550199", Host: "example.com"},
+		ParticipantAttributes: map[string]string{
+			livekit.AttrSIPPhoneNumber:                          "+14155550123",
+			AttrSIPCallerName:                                   "Alice",
+			livekit.AttrSIPHeaderPrefix + "p-asserted-identity": "<sip:+14155550123@example.com>",
+			livekit.AttrSIPCallStatus:                           "active",
+		},
+	}
+	_, err := get("p1").UpdateSIPCallState(context.Background(), &rpc.UpdateSIPCallStateRequest{CallInfo: info})
+	require.NoError(t, err)
+	got := <-upd.infos
+	require.Equal(t, "SCL_test", got.CallId)
+	require.Equal(t, "+*******0123", got.FromUri.User)
+	require.Equal(t, "example.com", got.FromUri.Host)
+	require.Equal(t, "+*******0199", got.ToUri.User)
+	require.Equal(t, map[string]string{
+		livekit.AttrSIPPhoneNumber:                          "+*******0123",
+		AttrSIPCallerName:                                   "*****",
+		livekit.AttrSIPHeaderPrefix + "p-asserted-identity": "<sip:+*******0123@example.com>",
+		livekit.AttrSIPCallStatus:                           "active",
+	}, got.ParticipantAttributes)
+	// The state of the call is not modified.
+	require.Equal(t, "+14155550123", info.FromUri.User)
+	require.Equal(t, "Alice", info.ParticipantAttributes[AttrSIPCallerName])
+
+	ti := r.TransferInfo(&livekit.SIPTransferInfo{TransferTo: "tel:+14155550100"})
+	require.Equal(t, "tel:+*******0100", ti.TransferTo)
+
+	// Clients are not wrapped if only logs are redacted.
+	require.Nil(t, r.IOInfoClient(func(string) rpc.IOInfoClient { return nil })("p1"))
+	r = newTestRedactor(t, config.RedactionConfig{Logs: true})
+	require.Same(t, upd, r.IOInfoClient(func(string) rpc.IOInfoClient { return upd })("p1"))
+}
@@ -0,0 +1,355 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/livekit/media-sdk/sdp"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/sipgo/sip"
+)
+
+const (
+	reInviteTimeout = 10 * time.Second
+
+	statusRequestPending sip.StatusCode = 491
+)
+
+var errReInvitePending = psrpc.NewErrorf(psrpc.Aborted, "another re-INVITE is in progress")
+
+// NewReInviteRequest creates an in-dialog INVITE that updates the session with a new SDP offer.
+func NewReInviteRequest(inviteRequest *sip.Request, inviteResponse *sip.Response, contactHeader *sip.ContactHeader, sdpOffer []byte, headers map[string]string) *sip.Request {
+	// BYE request already copies all the dialog headers we need.
+	req := sip.NewByeRequest(inviteRequest, inviteResponse, sdpOffer)
+	req.Method = sip.INVITE
+	if cseq := req.CSeq(); cseq != nil {
+		cseq.MethodName = sip.INVITE
+	}
+	req.AppendHeader(contactHeader)
+	req.AppendHeader(&contentTypeHeaderSDP)
+	defaultCapabilities.SetHeaders(req, false)
+	for k, v := range headers {
+		req.AppendHeader(sip.NewHeader(k, v))
+	}
+	return req
+}
+
+// sendReInvite sends re-INVITE, waits for the final response and acknowledges it.
+func sendReInvite(ctx context.Context, c Signaling, req *sip.Request, stop <-chan struct{}) (*sip.Response, error) {
+	tx, err := c.Transaction(req)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Terminate()
+
+	resp, err := sipResponse(ctx, tx, stop, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != sip.StatusOK {
+		return resp, &livekit.SIPStatus{Code: livekit.SIPStatusCode(resp.StatusCode)}
+	}
+	if err = c.WriteRequest(sip.NewAckRequest(req, resp, nil)); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// respondReInvite sends a response to remote re-INVITE, optionally with SDP answer.
+func respondReInvite(req *sip.Request, tx sip.ServerTransaction, contact *sip.ContactHeader, caps sipCapabilities, code sip.StatusCode, answer []byte) {
+	r := sip.NewResponseFromRequest(req, code, sipStatus(code), answer)
+	if code == sip.StatusOK {
+		r.AppendHeader(contact)
+		r.AppendHeader(&contentTypeHeaderSDP)
+		caps.SetHeaders(r, false)
+	}
+	_ = tx.Respond(r)
+}
+
+// mediaEncryption returns encryption mode that keeps current media encryption during renegotiation.
+func mediaEncryption(media *MediaPort) sdp.Encryption {
+	if conf := media.Config(); conf != nil && conf.Crypto != nil {
+		return sdp.EncryptionRequire
+	}
+	return sdp.EncryptionNone
+}
+
+// renegotiateMedia generates a new SDP offer (including new SRTP keys), sends it in a re-INVITE and applies the answer.
+func renegotiateMedia(ctx context.Context, media *MediaPort, busy *atomic.Bool, send func(ctx context.Context, offer []byte) ([]byte, error)) error {
+	if media == nil {
+		return psrpc.NewErrorf(psrpc.FailedPrecondition, "media is not established")
+	}
+	if !busy.CompareAndSwap(false, true) {
+		return errReInvitePending
+	}
+	defer busy.Store(false)
+
+	enc := mediaEncryption(media)
+	offer, err := media.NewOffer(enc)
+	if err != nil {
+		return err
+	}
+	offerData, err := offer.SDP.Marshal()
+	if err != nil {
+		return err
+	}
+	answerData, err := send(ctx, offerData)
+	if err != nil {
+		return err
+	}
+	mc, err := media.SetAnswer(offer, answerData, enc)
+	if err != nil {
+		return err
+	}
+	return media.UpdateConfig(mc, false)
+}
+
+// answerMediaReInvite applies SDP offer from a remote re-INVITE. It returns SDP answer or SIP status code for the error.
+func answerMediaReInvite(log logger.Logger, media *MediaPort, busy *atomic.Bool, offerData []byte) ([]byte, sip.StatusCode) {
+	if media == nil {
+		return nil, statusRequestPending
+	}
+	if len(offerData) == 0 {
+		// TODO: support offerless re-INVITE; we need to send an offer in 200 and accept an answer in ACK
+		log.Warnw("re-INVITE without SDP offer is not supported", nil)
+		return nil, sip.StatusNotAcceptableHere
+	}
+	if !busy.CompareAndSwap(false, true) {
+		return nil, statusRequestPending
+	}
+	defer busy.Store(false)
+
+	enc := mediaEncryption(media)
+	answer, mc, err := media.SetOffer(offerData, enc)
+	if err != nil {
+		log.Warnw("cannot accept re-INVITE offer", err)
+		return nil, sip.StatusNotAcceptableHere
+	}
+	answerData, err := answer.SDP.Marshal()
+	if err != nil {
+		log.Errorw("cannot marshal re-INVITE answer", err)
+		return nil, sip.StatusInternalServerError
+	}
+	if err = media.UpdateConfig(mc, true); err != nil {
+		log.Warnw("cannot apply re-INVITE offer", err)
+		return nil, sip.StatusNotAcceptableHere
+	}
+	return answerData, sip.StatusOK
+}
+
+// isReInvite checks if INVITE is sent within an existing dialog.
+func isReInvite(req *sip.Request) bool {
+	to := req.To()
+	if to == nil || to.Params == nil {
+		return false
+	}
+	tag, _ := to.Params.Get("tag")
+	return tag != ""
+}
+
+func (s *Server) onReInvite(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) bool {
+	tag, err := getFromTag(req)
+	if err != nil {
+		return false
+	}
+	s.cmu.RLock()
+	c := s.activeCalls[tag]
+	s.cmu.RUnlock()
+	if c != nil {
+		c.handleReInvite(req, tx)
+		return true
+	}
+	if s.sipUnhandled != nil {
+		return s.sipUnhandled(req, tx)
+	}
+	return false
+}
+
+func (c *inboundCall) handleReInvite(req *sip.Request, tx sip.ServerTransaction) {
+	c.log.Infow("re-INVITE")
+	if !c.cc.Established() {
+		c.cc.RespondReInvite(req, tx, statusRequestPending, nil)
+		return
+	}
+	offer, _, err := requestSDP(req)
+	if err != nil {
+		c.log.Infow("Rejecting re-INVITE with invalid body", "error", err)
+		c.cc.RespondReInvite(req, tx, sip.StatusBadRequest, nil)
+		return
+	}
+	answer, code := answerMediaReInvite(c.log, c.media, &c.reinvite, offer)
+	c.cc.RespondReInvite(req, tx, code, answer)
+	if code == sip.StatusOK {
+		c.lkRoom.SetAttributes(holdStateAttrs(offer))
+	}
+}
+
+// rekey renegotiates SRTP keys with a re-INVITE.
+func (c *inboundCall) rekey() {
+	ctx, cancel := context.WithTimeout(c.ctx, reInviteTimeout)
+	defer cancel()
+	c.log.Infow("renegotiating SRTP keys")
+	if err := renegotiateMedia(ctx, c.media, &c.reinvite, c.cc.ReInvite); err != nil {
+		c.log.Warnw("cannot renegotiate SRTP keys", err)
+	}
+}
+
+// refreshMedia renegotiates the media session with a re-INVITE, to recover from one-way audio.
+func (c *inboundCall) refreshMedia() {
+	ctx, cancel := context.WithTimeout(c.ctx, reInviteTimeout)
+	defer cancel()
+	c.log.Infow("refreshing media session")
+	if err := renegotiateMedia(ctx, c.media, &c.reinvite, c.cc.ReInvite); err != nil {
+		c.log.Warnw("cannot refresh media session", err)
+	}
+}
+
+// RespondReInvite sends a response to in-dialog INVITE from the remote.
+func (c *sipInbound) RespondReInvite(req *sip.Request, tx sip.ServerTransaction, code sip.StatusCode, answer []byte) {
+	tx = c.history.ServerTx(req, tx)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	respondReInvite(req, tx, c.contact, c.caps, code, answer)
+}
+
+// Established checks if the call was accepted and not yet closed.
+func (c *sipInbound) Established() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.invite != nil && c.inviteOk != nil
+}
+
+// ReInvite sends in-dialog INVITE with a new SDP offer and returns SDP answer.
+func (c *sipInbound) ReInvite(ctx context.Context, offer []byte) ([]byte, error) {
+	c.mu.Lock()
+	if c.invite == nil || c.inviteOk == nil {
+		c.mu.Unlock()
+		return nil, psrpc.NewErrorf(psrpc.FailedPrecondition, "can't re-invite non established call")
+	}
+	var headers map[string]string
+	if c.setHeaders != nil {
+		headers = c.setHeaders(nil)
+	}
+	req := NewReInviteRequest(c.invite, c.inviteOk, c.contact, offer, headers)
+	c.caps.SetHeaders(req, false)
+	c.setCSeq(req)
+	c.swapSrcDst(req)
+	c.mu.Unlock()
+
+	resp, err := sendReInvite(ctx, c, req, c.s.closing.Watch())
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body(), nil
+}
+
+func (c *Client) onReInvite(req *sip.Request, tx sip.ServerTransaction) bool {
+	tag, _ := getFromTag(req)
+	c.cmu.Lock()
+	call := c.byRemote[tag]
+	c.cmu.Unlock()
+	if call == nil {
+		return false
+	}
+	go call.handleReInvite(req, tx)
+	return true
+}
+
+func (c *outboundCall) handleReInvite(req *sip.Request, tx sip.ServerTransaction) {
+	c.log.Infow("re-INVITE")
+	if !c.cc.Established() {
+		c.cc.RespondReInvite(req, tx, statusRequestPending, nil)
+		return
+	}
+	offer, _, err := requestSDP(req)
+	if err != nil {
+		c.log.Infow("Rejecting re-INVITE with invalid body", "error", err)
+		c.cc.RespondReInvite(req, tx, sip.StatusBadRequest, nil)
+		return
+	}
+	answer, code := answerMediaReInvite(c.log, c.media, &c.reinvite, offer)
+	c.cc.RespondReInvite(req, tx, code, answer)
+	if code == sip.StatusOK {
+		c.lkRoom.SetAttributes(holdStateAttrs(offer))
+	}
+}
+
+// rekey renegotiates SRTP keys with a re-INVITE.
+func (c *outboundCall) rekey() {
+	ctx, cancel := context.WithTimeout(c.ctx, reInviteTimeout)
+	defer cancel()
+	c.log.Infow("renegotiating SRTP keys")
+	if err := renegotiateMedia(ctx, c.media, &c.reinvite, c.cc.ReInvite); err != nil {
+		c.log.Warnw("cannot renegotiate SRTP keys", err)
+	}
+}
+
+// refreshMedia renegotiates the media session with a re-INVITE, to recover from one-way audio.
+func (c *outboundCall) refreshMedia() {
+	ctx, cancel := context.WithTimeout(c.ctx, reInviteTimeout)
+	defer cancel()
+	c.log.Infow("refreshing media session")
+	if err := renegotiateMedia(ctx, c.media, &c.reinvite, c.cc.ReInvite); err != nil {
+		c.log.Warnw("cannot refresh media session", err)
+	}
+}
+
+// RespondReInvite sends a response to in-dialog INVITE from the remote.
+func (c *sipOutbound) RespondReInvite(req *sip.Request, tx sip.ServerTransaction, code sip.StatusCode, answer []byte) {
+	tx = c.history.ServerTx(req, tx)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	respondReInvite(req, tx, c.contact, c.caps, code, answer)
+}
+
+// Established checks if the call was accepted and not yet closed.
+func (c *sipOutbound) Established() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.invite != nil && c.inviteOk != nil
+}
+
+// ReInvite sends in-dialog INVITE with a new SDP offer and returns SDP answer.
+func (c *sipOutbound) ReInvite(ctx context.Context, offer []byte) ([]byte, error) {
+	c.mu.Lock()
+	if c.invite == nil || c.inviteOk == nil {
+		c.mu.Unlock()
+		return nil, psrpc.NewErrorf(psrpc.FailedPrecondition, "can't re-invite non established call")
+	}
+	if c.c.closing.IsBroken() {
+		c.mu.Unlock()
+		return nil, psrpc.NewErrorf(psrpc.FailedPrecondition, "can't re-invite hung up call")
+	}
+	var headers map[string]string
+	if c.getHeaders != nil {
+		headers = c.getHeaders(nil)
+	}
+	req := NewReInviteRequest(c.invite, c.inviteOk, c.contact, offer, headers)
+	c.caps.SetHeaders(req, false)
+	c.setCSeq(req)
+	c.mu.Unlock()
+
+	resp, err := sendReInvite(ctx, c, req, c.c.closing.Watch())
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body(), nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/livekit/psrpc"
+)
+
+// headerResourcePriority marks priority calls, see RFC 4412.
+const headerResourcePriority = "Resource-Priority"
+
+// ResourcePriority is a single value of the Resource-Priority header, like "ets.0".
+// Both parts are case-insensitive and are stored in lower case.
+type ResourcePriority struct {
+	Namespace string
+	Priority  string
+}
+
+func (r ResourcePriority) String() string {
+	return r.Namespace + "." + r.Priority
+}
+
+// Match checks the value against a filter, which is either a namespace ("ets") or a full value ("ets.0").
+func (r ResourcePriority) Match(filter string) bool {
+	ns, prio, ok := strings.Cut(filter, ".")
+	if !strings.EqualFold(ns, r.Namespace) {
+		return false
+	}
+	return !ok || strings.EqualFold(prio, r.Priority)
+}
+
+func isResourcePriorityToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("-!%*_+`'~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ParseResourcePriority parses Resource-Priority header values, each of which may contain a comma-separated list.
+// Invalid and duplicate values are skipped.
+func ParseResourcePriority(values ...string) []ResourcePriority {
+	var out []ResourcePriority
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			ns, prio, ok := strings.Cut(strings.TrimSpace(s), ".")
+			if !ok || !isResourcePriorityToken(ns) || !isResourcePriorityToken(prio) {
+				continue
+			}
+			rp := ResourcePriority{Namespace: strings.ToLower(ns), Priority: strings.ToLower(prio)}
+			if !slices.Contains(out, rp) {
+				out = append(out, rp)
+			}
+		}
+	}
+	return out
+}
+
+// FormatResourcePriority formats values as a single Resource-Priority header value.
+func FormatResourcePriority(list []ResourcePriority) string {
+	parts := make([]string, 0, len(list))
+	for _, r := range list {
+		parts = append(parts, r.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matchResourcePriority checks if any of the values matches any of the filters. See ResourcePriority.Match.
+// Any value matches if filters are empty.
+func matchResourcePriority(list []ResourcePriority, filters []string) bool {
+	for _, r := range list {
+		if len(filters) == 0 || slices.ContainsFunc(filters, r.Match) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasResourcePriority checks Resource-Priority header values, like "ets.0, wps.2", against allowed namespaces.
+// Any value is accepted if namespaces are empty.
+func hasResourcePriority(values []string, namespaces []string) bool {
+	return matchResourcePriority(ParseResourcePriority(values...), namespaces)
+}
+
+// outboundResourcePriority adds the Resource-Priority header to outbound INVITE headers, unless it's already set.
+// The value is taken from the sip.resourcePriority attribute of the request, or from the trunk default.
+func outboundResourcePriority(headers map[string]string, attrs map[string]string, trunkDefault string) (map[string]string, error) {
+	if len(headerValues(headers, headerResourcePriority)) != 0 {
+		return headers, nil
+	}
+	v := attrs[AttrSIPResourcePriority]
+	if v == "" {
+		v = trunkDefault
+	}
+	if v == "" {
+		return headers, nil
+	}
+	rp := ParseResourcePriority(v)
+	if len(rp) == 0 {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "invalid resource priority %q", v)
+	}
+	headers = maps.Clone(headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	headers[headerResourcePriority] = FormatResourcePriority(rp)
+	return headers, nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestParseResourcePriority(t *testing.T) {
+	rp := ParseResourcePriority("ETS.0, wps.2", "", "ets.0", "bad", "dsn.", "esnet.1;x")
+	require.Equal(t, []ResourcePriority{
+		{Namespace: "ets", Priority: "0"},
+		{Namespace: "wps", Priority: "2"},
+	}, rp)
+	require.Equal(t, "ets.0, wps.2", FormatResourcePriority(rp))
+
+	require.True(t, rp[0].Match("ets"))
+	require.True(t, rp[0].Match("ETS.0"))
+	require.False(t, rp[0].Match("ets.1"))
+	require.False(t, rp[0].Match("wps"))
+
+	require.True(t, matchResourcePriority(rp, nil))
+	require.True(t, matchResourcePriority(rp, []string{"esnet", "wps.2"}))
+	require.False(t, matchResourcePriority(rp, []string{"esnet", "wps.1"}))
+	require.False(t, matchResourcePriority(nil, nil))
+}
+
+func TestDispatchResourcePriority(t *testing.T) {
+	conf := &config.Config{DispatchRules: map[string]*config.DispatchRuleConfig{
+		"ets": {ResourcePriority: []string{"ets"}},
+	}}
+	rp := ParseResourcePriority("ets.2")
+
+	d := CallDispatch{Result: DispatchAccept, DispatchRuleID: "ets"}
+	require.True(t, d.checkResourcePriority(conf, rp))
+	require.Equal(t, DispatchAccept, d.Result)
+
+	d = CallDispatch{Result: DispatchRequestPin, DispatchRuleID: "ets"}
+	require.False(t, d.checkResourcePriority(conf, ParseResourcePriority("wps.1")))
+	require.Equal(t, DispatchNoRuleReject, d.Result)
+
+	d = CallDispatch{Result: DispatchAccept, DispatchRuleID: "other"}
+	require.True(t, d.checkResourcePriority(conf, nil))
+	require.Equal(t, DispatchAccept, d.Result)
+
+	d = CallDispatch{Result: DispatchNoRuleDrop, DispatchRuleID: "ets"}
+	require.True(t, d.checkResourcePriority(conf, nil))
+	require.Equal(t, DispatchNoRuleDrop, d.Result)
+}
+
+func TestOutboundResourcePriority(t *testing.T) {
+	h, err := outboundResourcePriority(nil, nil, "")
+	require.NoError(t, err)
+	require.Nil(t, h)
+
+	h, err = outboundResourcePriority(nil, nil, "ETS.0")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"Resource-Priority": "ets.0"}, h)
+
+	orig := map[string]string{"X-Test": "1"}
+	h, err = outboundResourcePriority(orig, map[string]string{AttrSIPResourcePriority: "wps.1"}, "ets.0")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"X-Test": "1", "Resource-Priority": "wps.1"}, h)
+	require.Len(t, orig, 1, "request headers must not be modified")
+
+	// Headers in the request take priority.
+	orig = map[string]string{"resource-priority": "esnet.0"}
+	h, err = outboundResourcePriority(orig, map[string]string{AttrSIPResourcePriority: "wps.1"}, "ets.0")
+	require.NoError(t, err)
+	require.Equal(t, orig, h)
+
+	_, err = outboundResourcePriority(nil, map[string]string{AttrSIPResourcePriority: "invalid"}, "")
+	require.Error(t, err)
+}
@@ -16,9 +16,11 @@ package sip
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"sync/atomic"
+	"time"
 
 	"github.com/frostbyte73/core"
 	"github.com/pion/webrtc/v4"
@@ -30,6 +32,7 @@ import (
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/logger/medialogutils"
 	"github.com/livekit/protocol/sip"
+	"github.com/livekit/protocol/utils/guid"
 	lksdk "github.com/livekit/server-sdk-go/v2"
 
 	"github.com/livekit/sip/pkg/config"
@@ -37,6 +40,15 @@ import (
 	"github.com/livekit/sip/pkg/mixer"
 )
 
+// chatTopic is the data topic of chat messages, as used by LiveKit components.
+const chatTopic = "lk-chat-topic"
+
+type chatMessage struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
 type RoomStats struct {
 	InputPackets atomic.Uint64
 	InputBytes   atomic.Uint64
@@ -59,11 +71,13 @@ type ParticipantInfo struct {
 
 type Room struct {
 	log        logger.Logger
-	roomLog    logger.Logger // deferred logger
-	room       *lksdk.Room
+	roomLog    logger.Logger              // deferred logger
+	room       atomic.Pointer[lksdk.Room] // nil until connected and after close
 	mix        *mixer.Mixer
 	out        *msdk.SwitchWriter
 	outDtmf    atomic.Pointer[dtmf.Writer]
+	onAttrs    atomic.Pointer[func(changed map[string]string)]
+	onChat     atomic.Pointer[func(from, text string)]
 	p          ParticipantInfo
 	ready      core.Fuse
 	subscribe  atomic.Bool
@@ -104,8 +118,8 @@ func NewRoom(log logger.Logger, st *RoomStats) *Room {
 	go func() {
 		select {
 		case <-r.ready.Watch():
-			if r.room != nil {
-				resolve.Resolve("room", r.room.Name(), "roomID", r.room.SID())
+			if room := r.room.Load(); room != nil {
+				resolve.Resolve("room", room.Name(), "roomID", room.SID())
 			} else {
 				resolve.Resolve()
 			}
@@ -137,7 +151,7 @@ func (r *Room) Room() *lksdk.Room {
 	if r == nil {
 		return nil
 	}
-	return r.room
+	return r.room.Load()
 }
 
 func (r *Room) participantJoin(rp *lksdk.RemoteParticipant) {
@@ -195,6 +209,14 @@ func (r *Room) Connect(conf *config.Config, rconf RoomConfig) error {
 			r.participantLeft(rp)
 		},
 		ParticipantCallback: lksdk.ParticipantCallback{
+			OnAttributesChanged: func(changed map[string]string, p lksdk.Participant) {
+				if _, ok := p.(*lksdk.LocalParticipant); !ok {
+					return
+				}
+				if h := r.onAttrs.Load(); h != nil {
+					(*h)(changed)
+				}
+			},
 			OnTrackPublished: func(pub *lksdk.RemoteTrackPublication, rp *lksdk.RemoteParticipant) {
 				log := r.roomLog.WithValues("participant", rp.Identity(), "pID", rp.SID(), "trackID", pub.SID(), "trackName", pub.Name())
 				if !r.subscribe.Load() {
@@ -221,7 +243,7 @@ func (r *Room) Connect(conf *config.Config, rconf RoomConfig) error {
 					in := newRTPReaderCount(track, &r.stats.InputPackets, &r.stats.InputBytes)
 					out := newMediaWriterCount(mTrack, &r.stats.MixerFrames, &r.stats.MixerSamples)
 
-					odec, err := opus.Decode(out, channels, log)
+					odec, err := opus.Decode(out, channels, newSampledLogger(log))
 					if err != nil {
 						log.Errorw("cannot create opus decoder", err)
 						return
@@ -244,6 +266,10 @@ func (r *Room) Connect(conf *config.Config, rconf RoomConfig) error {
 					// TODO: Only generate audio DTMF if the message was a broadcast from another SIP participant.
 					//       DTMF audio tone will be automatically mixed in any case.
 					r.sendDTMF(data)
+				case *lksdk.UserDataPacket:
+					if data.Topic == chatTopic {
+						r.handleChat(params.SenderIdentity, data.Payload)
+					}
 				}
 			},
 		},
@@ -292,9 +318,9 @@ func (r *Room) Connect(conf *config.Config, rconf RoomConfig) error {
 	if err != nil {
 		return err
 	}
-	r.room = room
-	r.p.ID = r.room.LocalParticipant.SID()
-	r.p.Identity = r.room.LocalParticipant.Identity()
+	r.p.ID = room.LocalParticipant.SID()
+	r.p.Identity = room.LocalParticipant.Identity()
+	r.room.Store(room)
 	room.LocalParticipant.SetAttributes(partConf.Attributes)
 	r.ready.Break()
 	r.subscribe.Store(false) // already false, but keep for visibility
@@ -303,12 +329,17 @@ func (r *Room) Connect(conf *config.Config, rconf RoomConfig) error {
 	return nil
 }
 
+// Subscribe starts receiving audio from all current and future participants of the room.
+// It can be called multiple times, only the first call has an effect.
 func (r *Room) Subscribe() {
-	if r.room == nil {
+	if r.subscribe.Swap(true) {
 		return
 	}
-	r.subscribe.Store(true)
-	list := r.room.GetRemoteParticipants()
+	room := r.Room()
+	if room == nil {
+		return
+	}
+	list := room.GetRemoteParticipants()
 	r.log.Debugw("subscribing to existing room participants", "participants", len(list))
 	for _, rp := range list {
 		r.participantJoin(rp)
@@ -355,6 +386,94 @@ func (r *Room) SetDTMFOutput(w dtmf.Writer) {
 	r.outDtmf.Store(&w)
 }
 
+// OnAttributesChanged sets a handler for attribute changes of the SIP participant.
+// Changes include attributes set by the SIP service itself, as well as ones set by other parties via the server API.
+func (r *Room) OnAttributesChanged(h func(changed map[string]string)) {
+	if r == nil {
+		return
+	}
+	if h == nil {
+		r.onAttrs.Store(nil)
+		return
+	}
+	r.onAttrs.Store(&h)
+}
+
+// OnChat sets a handler for chat messages sent to the room by other participants.
+func (r *Room) OnChat(h func(from, text string)) {
+	if r == nil {
+		return
+	}
+	if h == nil {
+		r.onChat.Store(nil)
+		return
+	}
+	r.onChat.Store(&h)
+}
+
+func (r *Room) handleChat(from string, data []byte) {
+	h := r.onChat.Load()
+	if h == nil {
+		return
+	}
+	var msg chatMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		r.log.Debugw("cannot parse chat message", "error", err, "from", from)
+		return
+	}
+	if msg.Message != "" {
+		(*h)(from, msg.Message)
+	}
+}
+
+// SendChat sends a chat message to the room on behalf of the SIP participant.
+func (r *Room) SendChat(text string) error {
+	data, err := json.Marshal(chatMessage{
+		ID:        guid.New("CHAT_"),
+		Timestamp: time.Now().UnixMilli(),
+		Message:   text,
+	})
+	if err != nil {
+		return err
+	}
+	return r.SendData(&lksdk.UserDataPacket{Payload: data, Topic: chatTopic}, lksdk.WithDataPublishReliable(true))
+}
+
+// transcriptionPacket publishes a transcription as a data packet.
+type transcriptionPacket struct {
+	*livekit.Transcription
+}
+
+func (p transcriptionPacket) ToProto() *livekit.DataPacket {
+	return &livekit.DataPacket{Value: &livekit.DataPacket_Transcription{Transcription: p.Transcription}}
+}
+
+// SendTranscription publishes text of the SIP participant as a transcription of its audio track.
+// Segments with the same id replace each other, until the final one.
+func (r *Room) SendTranscription(id, text string, final bool) error {
+	room := r.Room()
+	if room == nil || !r.ready.IsBroken() || r.closed.IsBroken() {
+		return nil
+	}
+	p := room.LocalParticipant
+	var trackID string
+	for _, pub := range p.TrackPublications() {
+		if pub.Kind() == lksdk.TrackKindAudio {
+			trackID = pub.SID()
+			break
+		}
+	}
+	return r.SendData(transcriptionPacket{&livekit.Transcription{
+		TranscribedParticipantIdentity: p.Identity(),
+		TrackId:                        trackID,
+		Segments: []*livekit.TranscriptionSegment{{
+			Id:    id,
+			Text:  text,
+			Final: final,
+		}},
+	}}, lksdk.WithDataPublishReliable(true))
+}
+
 func (r *Room) sendDTMF(msg *livekit.SipDTMF) {
 	outDTMF := r.outDtmf.Load()
 	if outDTMF == nil {
@@ -364,8 +483,14 @@ func (r *Room) sendDTMF(msg *livekit.SipDTMF) {
 	// TODO: Separate goroutine?
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	r.log.Infow("forwarding dtmf to sip", "digit", msg.Digit)
-	_ = (*outDTMF).WriteDTMF(ctx, msg.Digit)
+	digits := msg.Digit
+	if digits == "" && msg.Code == dtmfCodeFlash {
+		digits = string([]byte{dtmfDigitFlash})
+	}
+	r.log.Infow("forwarding dtmf to sip", "digit", digits)
+	if err := (*outDTMF).WriteDTMF(ctx, digits); err != nil {
+		r.log.Warnw("failed to forward dtmf to sip", err, "digit", digits)
+	}
 }
 
 func (r *Room) Close() error {
@@ -381,9 +506,9 @@ func (r *Room) CloseWithReason(reason livekit.DisconnectReason) error {
 	r.subscribe.Store(false)
 	err := r.CloseOutput()
 	r.SetDTMFOutput(nil)
-	if r.room != nil {
-		r.room.DisconnectWithReason(reason)
-		r.room = nil
+	r.OnAttributesChanged(nil)
+	if room := r.room.Swap(nil); room != nil {
+		room.DisconnectWithReason(reason)
 	}
 	if r.mix != nil {
 		r.mix.Stop()
@@ -399,29 +524,63 @@ func (r *Room) Participant() ParticipantInfo {
 }
 
 func (r *Room) NewParticipantTrack(sampleRate int) (msdk.WriteCloser[msdk.PCM16Sample], error) {
-	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, "audio", "pion")
+	track, err := lksdk.NewLocalTrack(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus})
 	if err != nil {
 		return nil, err
 	}
-	p := r.room.LocalParticipant
+	room := r.Room()
+	if room == nil {
+		return nil, errors.New("room is not connected")
+	}
+	p := room.LocalParticipant
 	if _, err = p.PublishTrack(track, &lksdk.TrackPublicationOptions{
 		Name: p.Identity(),
 	}); err != nil {
 		return nil, err
 	}
-	ow := msdk.FromSampleWriter[opus.Sample](track, sampleRate, rtp.DefFrameDur)
+	// Audio level extension is required for the room to detect active speakers.
+	lt := newAudioLevelTrack(track)
+	ow := msdk.FromSampleWriter[opus.Sample](lt, sampleRate, rtp.DefFrameDur)
 	pw, err := opus.Encode(ow, channels, r.log)
 	if err != nil {
 		return nil, err
 	}
-	return pw, nil
+	return lt.Meter(pw), nil
 }
 
 func (r *Room) SendData(data lksdk.DataPacket, opts ...lksdk.DataPublishOption) error {
-	if r == nil || !r.ready.IsBroken() || r.closed.IsBroken() {
+	room := r.Room()
+	if room == nil || !r.ready.IsBroken() || r.closed.IsBroken() {
 		return nil
 	}
-	return r.room.LocalParticipant.PublishDataPacket(data, opts...)
+	return room.LocalParticipant.PublishDataPacket(data, opts...)
+}
+
+// SetAttributes updates attributes of the SIP participant. It does nothing if the room is not connected.
+func (r *Room) SetAttributes(attrs map[string]string) {
+	room := r.Room()
+	if room == nil || len(attrs) == 0 || !r.ready.IsBroken() || r.closed.IsBroken() {
+		return
+	}
+	room.LocalParticipant.SetAttributes(attrs)
+}
+
+// LocalAttributes returns current attributes of the SIP participant, or nil if the room is not connected.
+func (r *Room) LocalAttributes() map[string]string {
+	lr := r.Room()
+	if lr == nil || lr.LocalParticipant == nil {
+		return nil
+	}
+	return lr.LocalParticipant.Attributes()
+}
+
+// SetName updates the display name of the SIP participant. It does nothing if the room is not connected.
+func (r *Room) SetName(name string) {
+	room := r.Room()
+	if room == nil || !r.ready.IsBroken() || r.closed.IsBroken() {
+		return
+	}
+	room.LocalParticipant.SetName(name)
 }
 
 func (r *Room) NewTrack() *mixer.Input {
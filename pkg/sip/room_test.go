@@ -0,0 +1,49 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+	lksdk "github.com/livekit/server-sdk-go/v2"
+)
+
+// TestRoomCloseRace must be run with -race: attributes may be set by media and signaling goroutines
+// while the call hangs up.
+func TestRoomCloseRace(t *testing.T) {
+	r := NewRoom(logger.GetLogger(), nil)
+	r.room.Store(lksdk.NewRoom(nil)) // not joined, requests are dropped
+	r.ready.Break()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				r.SetAttributes(map[string]string{AttrSIPDialogState: "answered"})
+				r.SetName("caller")
+				_ = r.SendTranscription("1", "hello", false)
+			}
+		}()
+	}
+	require.NoError(t, r.Close())
+	wg.Wait()
+	require.Nil(t, r.Room())
+}
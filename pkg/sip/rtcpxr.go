@@ -0,0 +1,442 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/livekit/media-sdk/g711"
+	"github.com/livekit/media-sdk/rtp"
+	"github.com/pion/rtcp"
+	prtp "github.com/pion/rtp"
+	psdp "github.com/pion/sdp/v3"
+)
+
+const (
+	// rtcpXRGmin is the loss gap threshold from RFC 3611, in packets.
+	rtcpXRGmin = 16
+	// rtcpXRUnavailable is reported for metrics that are not measured.
+	rtcpXRUnavailable = 127
+	// maxSeqJump is the largest sequence number jump that is still considered a loss, not a stream restart.
+	maxSeqJump = 3000
+	// rtpReorderWindow is the number of the latest sequence numbers that are not yet considered lost.
+	// Packets reordered within the window are accounted as received. Must not exceed 64.
+	rtpReorderWindow = 8
+)
+
+// rtpRecvEvent classifies a received RTP packet.
+type rtpRecvEvent int
+
+const (
+	rtpRecvInOrder rtpRecvEvent = iota
+	rtpRecvGap                  // sequence number skipped ahead
+	rtpRecvDuplicate
+	rtpRecvReordered // arrived late, but within the reorder window
+	rtpRecvLate      // arrived too late, already accounted as lost
+	rtpRecvRestart   // new stream or a large sequence jump
+)
+
+// rtpRecvStats tracks reception quality of the incoming RTP stream, as described in RFC 3550 and RFC 3611.
+type rtpRecvStats struct {
+	mu        sync.Mutex
+	clockRate int
+	// noQuality is set for codecs that quality estimation doesn't model, see estimateQuality.
+	noQuality bool
+	rtpRecvState
+}
+
+type rtpRecvState struct {
+	started   bool
+	ssrc      uint32
+	baseSeq   uint64
+	maxSeq    uint64 // extended sequence number
+	received  uint64
+	discarded uint64 // duplicate and late packets
+	reordered uint64
+	// Reorder window: bit N is set if maxSeq-N was received. Only the last pending sequence numbers are tracked,
+	// older ones are already classified by the burst/gap state machine.
+	window  uint64
+	pending uint64
+	// Interarrival jitter in RTP timestamp units.
+	jitter      float64
+	lastTransit int64
+	start       time.Time
+
+	// Burst/gap state machine from RFC 3611, Appendix A.2.
+	// Bursts are classified when the following gap ends, so the last one is classified at report time.
+	pkt  uint64 // packets received since the last loss
+	lost uint64 // losses in the current burst, zero if there were no losses yet
+	c11  uint64 // packets received in gaps
+	c13  uint64 // bursts with more than one loss
+	c14  uint64 // isolated losses in gaps
+	c22  uint64 // packets received in bursts
+	c23  uint64 // losses in bursts, following a received packet
+	c33  uint64 // consecutive losses in bursts
+}
+
+func (s *rtpRecvStats) SetClockRate(rate int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clockRate = rate
+}
+
+// SetCodec sets the clock rate of the stream and enables quality estimation only for G.711.
+func (s *rtpRecvStats) SetCodec(sdpName string, rate int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clockRate = rate
+	s.noQuality = sdpName != g711.ULawSDPName && sdpName != g711.ALawSDPName
+}
+
+// Update accounts a received RTP packet.
+func (s *rtpRecvStats) Update(h *rtp.Header, now time.Time) rtpRecvEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started || h.SSRC != s.ssrc {
+		s.reset(h, now)
+		return rtpRecvRestart
+	}
+	delta := h.SequenceNumber - uint16(s.maxSeq)
+	switch {
+	case delta == 0:
+		s.discarded++
+		return rtpRecvDuplicate
+	case delta >= 0x8000:
+		back := uint64(uint16(s.maxSeq) - h.SequenceNumber)
+		if back >= s.pending {
+			// Already accounted as lost.
+			s.discarded++
+			return rtpRecvLate
+		}
+		if s.window&(1<<back) != 0 {
+			s.discarded++
+			return rtpRecvDuplicate
+		}
+		s.window |= 1 << back
+		s.received++
+		s.reordered++
+		return rtpRecvReordered
+	case delta > maxSeqJump:
+		s.reset(h, now)
+		return rtpRecvRestart
+	}
+	s.advance(uint64(delta))
+	s.window |= 1
+	s.maxSeq += uint64(delta)
+	s.received++
+	s.updateJitter(h, now)
+	if delta > 1 {
+		return rtpRecvGap
+	}
+	return rtpRecvInOrder
+}
+
+func (s *rtpRecvStats) reset(h *rtp.Header, now time.Time) {
+	s.rtpRecvState = rtpRecvState{
+		started:  true,
+		ssrc:     h.SSRC,
+		baseSeq:  uint64(h.SequenceNumber),
+		maxSeq:   uint64(h.SequenceNumber),
+		received: 1,
+		window:   1,
+		pending:  1,
+		start:    now,
+	}
+	s.lastTransit = s.transit(h, now)
+}
+
+// advance moves the reorder window by delta sequence numbers.
+// Sequence numbers leaving the window are passed to the burst/gap state machine.
+func (s *rtpRecvState) advance(delta uint64) {
+	for range delta {
+		if s.pending == rtpReorderWindow {
+			s.classify(s.window&(1<<(rtpReorderWindow-1)) != 0)
+			s.pending--
+		}
+		s.window <<= 1
+		s.pending++
+	}
+	s.window &= 1<<rtpReorderWindow - 1
+}
+
+// flush classifies all sequence numbers in the reorder window.
+func (s *rtpRecvState) flush() {
+	for i := s.pending; i > 0; i-- {
+		s.classify(s.window&(1<<(i-1)) != 0)
+	}
+	s.window, s.pending = 0, 0
+}
+
+func (s *rtpRecvState) classify(received bool) {
+	if received {
+		s.pkt++
+	} else {
+		s.onLoss()
+	}
+}
+
+func (s *rtpRecvStats) transit(h *rtp.Header, now time.Time) int64 {
+	if s.clockRate <= 0 {
+		return 0
+	}
+	arrival := now.Sub(s.start).Nanoseconds() * int64(s.clockRate) / int64(time.Second)
+	return arrival - int64(h.Timestamp)
+}
+
+func (s *rtpRecvStats) updateJitter(h *rtp.Header, now time.Time) {
+	if s.clockRate <= 0 {
+		return
+	}
+	transit := s.transit(h, now)
+	d := transit - s.lastTransit
+	s.lastTransit = transit
+	// Timestamps wrap around, ignore unreasonable values.
+	if d > math.MaxInt32 || d < -math.MaxInt32 {
+		return
+	}
+	s.jitter += (math.Abs(float64(d)) - s.jitter) / 16
+}
+
+func (s *rtpRecvState) onLoss() {
+	if s.pkt >= rtcpXRGmin {
+		s.c13, s.c14 = classifyLossBurst(s.lost, s.c13, s.c14)
+		s.lost = 1
+		s.c11 += s.pkt
+	} else {
+		s.lost++
+		if s.pkt == 0 {
+			s.c33++
+		} else {
+			s.c23++
+			s.c22 += s.pkt - 1
+		}
+	}
+	s.pkt = 0
+}
+
+func classifyLossBurst(lost, c13, c14 uint64) (uint64, uint64) {
+	switch {
+	case lost == 1:
+		c14++
+	case lost > 1:
+		c13++
+	}
+	return c13, c14
+}
+
+// VoIPMetrics summarizes reception quality of the call, following RFC 3611 VoIP Metrics Report Block.
+type VoIPMetrics struct {
+	SSRC      uint32 `json:"ssrc"`
+	Expected  uint64 `json:"expected"`
+	Lost      uint64 `json:"lost"`
+	Discarded uint64 `json:"discarded"`
+	Reordered uint64 `json:"reordered"`
+	// Rates and densities are fractions in [0, 1].
+	LossRate      float64       `json:"loss_rate"`
+	DiscardRate   float64       `json:"discard_rate"`
+	BurstDensity  float64       `json:"burst_density"`
+	GapDensity    float64       `json:"gap_density"`
+	BurstDuration time.Duration `json:"burst_duration"`
+	GapDuration   time.Duration `json:"gap_duration"`
+	Jitter        time.Duration `json:"jitter"`
+	// RFactor and MOS are listening quality estimates. They are zero if the codec is not G.711.
+	RFactor float64 `json:"r_factor"`
+	MOS     float64 `json:"mos"`
+}
+
+// Metrics computes VoIP metrics for the stream. Frame duration is used to estimate burst and gap durations.
+func (s *rtpRecvStats) Metrics(frameDur time.Duration) VoIPMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := VoIPMetrics{SSRC: s.ssrc, Discarded: s.discarded, Reordered: s.reordered}
+	if !s.started {
+		return m
+	}
+	m.Expected = s.maxSeq - s.baseSeq + 1
+	if s.received < m.Expected {
+		m.Lost = m.Expected - s.received
+	}
+	m.LossRate = float64(m.Lost) / float64(m.Expected)
+	m.DiscardRate = min(1, float64(m.Discarded)/float64(m.Expected))
+	if s.clockRate > 0 {
+		m.Jitter = time.Duration(s.jitter * float64(time.Second) / float64(s.clockRate))
+	}
+
+	// Classify packets in the reorder window, the last burst and finish the current gap.
+	st := s.rtpRecvState
+	st.flush()
+	c13, c14 := classifyLossBurst(st.lost, st.c13, st.c14)
+	c11 := st.c11 + st.pkt
+	c22, c23, c33 := st.c22, st.c23, st.c33
+	c31, c32 := c13, c23
+	ctotal := c11 + c14 + c13 + c22 + c23 + c31 + c32 + c33
+
+	var p32, p23 float64
+	if d := c31 + c32 + c33; d > 0 {
+		p32 = float64(c32) / float64(d)
+	}
+	if c22+c23 < 1 {
+		p23 = 1
+	} else {
+		p23 = 1 - float64(c22)/float64(c22+c23)
+	}
+	if c13 > 0 {
+		if p23+p32 > 0 {
+			m.BurstDensity = p23 / (p23 + p32)
+		}
+		gap := float64(c11+c14+c13) / float64(c13)
+		burst := float64(ctotal)/float64(c13) - gap
+		m.GapDuration = time.Duration(gap * float64(frameDur))
+		m.BurstDuration = time.Duration(burst * float64(frameDur))
+	} else {
+		m.GapDuration = time.Duration(float64(c11+c14) * float64(frameDur))
+	}
+	if c11+c14 > 0 {
+		m.GapDensity = float64(c14) / float64(c11+c14)
+	}
+	if !s.noQuality {
+		m.RFactor, m.MOS = estimateQuality(m.LossRate, m.BurstDensity > 0)
+	}
+	return m
+}
+
+// estimateQuality estimates R-factor and MOS from packet loss using the simplified E-model (ITU-T G.107).
+//
+// Codec impairment values are for G.711 with packet loss concealment (ITU-T G.113, Appendix I),
+// other codecs would need their own. Delay isn't accounted for, since it's not measured.
+func estimateQuality(lossRate float64, bursty bool) (r, mos float64) {
+	const (
+		r0  = 93.2
+		ie  = 0
+		bpl = 25.1
+	)
+	ppl := lossRate * 100
+	burstR := 1.0
+	if bursty {
+		burstR = 2
+	}
+	ieEff := ie + (95-ie)*ppl/(ppl/burstR+bpl)
+	r = max(0, r0-ieEff)
+	switch {
+	case r <= 0:
+		mos = 1
+	case r >= 100:
+		mos = 4.5
+	default:
+		mos = 1 + 0.035*r + r*(r-60)*(100-r)*7e-6
+	}
+	return r, mos
+}
+
+func scaleRTCPXR(v float64) uint8 {
+	return uint8(min(255, math.Round(v*256)))
+}
+
+func durationMs16(d time.Duration) uint16 {
+	return uint16(min(math.MaxUint16, d.Milliseconds()))
+}
+
+// ReportBlock returns an RTCP-XR VoIP Metrics Report Block (RFC 3611, section 4.7).
+func (m *VoIPMetrics) ReportBlock(jitterBuffer bool) *rtcp.VoIPMetricsReportBlock {
+	b := &rtcp.VoIPMetricsReportBlock{
+		SSRC:          m.SSRC,
+		LossRate:      scaleRTCPXR(m.LossRate),
+		DiscardRate:   scaleRTCPXR(m.DiscardRate),
+		BurstDensity:  scaleRTCPXR(m.BurstDensity),
+		GapDensity:    scaleRTCPXR(m.GapDensity),
+		BurstDuration: durationMs16(m.BurstDuration),
+		GapDuration:   durationMs16(m.GapDuration),
+		SignalLevel:   rtcpXRUnavailable,
+		NoiseLevel:    rtcpXRUnavailable,
+		RERL:          rtcpXRUnavailable,
+		Gmin:          rtcpXRGmin,
+		RFactor:       rtcpXRUnavailable,
+		ExtRFactor:    rtcpXRUnavailable,
+		MOSLQ:         rtcpXRUnavailable,
+		// Conversational quality depends on delay, which is not measured.
+		MOSCQ: rtcpXRUnavailable,
+	}
+	if m.MOS > 0 {
+		b.RFactor = uint8(math.Round(m.RFactor))
+		b.MOSLQ = uint8(math.Round(m.MOS * 10))
+	}
+	if jitterBuffer {
+		// Jitter buffer adaptive (JBA=3).
+		b.RXConfig = 3 << 4
+	}
+	return b
+}
+
+// rtcpXRPacket builds a compound RTCP packet with an RTCP-XR VoIP Metrics Report Block.
+// Compound packets must start with a report, so an empty receiver report is added first (RFC 3550, section 6.1).
+func rtcpXRPacket(ssrc uint32, m *VoIPMetrics, jitterBuffer bool) ([]byte, error) {
+	return rtcp.Marshal([]rtcp.Packet{
+		&rtcp.ReceiverReport{SSRC: ssrc},
+		&rtcp.ExtendedReport{SenderSSRC: ssrc, Reports: []rtcp.ReportBlock{m.ReportBlock(jitterBuffer)}},
+	})
+}
+
+// rtcpRemoteAddr returns the address the remote expects RTCP on: one from the a=rtcp attribute (RFC 3605),
+// or the next port after RTP (RFC 3550). RTCP is never multiplexed with RTP, since rtcp-mux is not negotiated.
+func rtcpRemoteAddr(m *psdp.MediaDescription, rtpAddr netip.AddrPort) netip.AddrPort {
+	if !rtpAddr.IsValid() || rtpAddr.Port() == 0 {
+		return netip.AddrPort{}
+	}
+	addr := rtpAddr.Addr()
+	if m != nil {
+		if v, ok := m.Attribute("rtcp"); ok {
+			// a=rtcp:<port> [<nettype> <addrtype> <connection-address>]
+			f := strings.Fields(v)
+			if len(f) == 0 {
+				return netip.AddrPort{}
+			}
+			port, err := strconv.ParseUint(f[0], 10, 16)
+			if err != nil || port == 0 {
+				return netip.AddrPort{}
+			}
+			if len(f) == 4 {
+				if ip, err := netip.ParseAddr(f[3]); err == nil {
+					addr = ip
+				}
+			}
+			return netip.AddrPortFrom(addr, uint16(port))
+		}
+	}
+	if rtpAddr.Port() == math.MaxUint16 {
+		return netip.AddrPort{}
+	}
+	return netip.AddrPortFrom(addr, rtpAddr.Port()+1)
+}
+
+// rtpSSRCWriter records SSRC of sent RTP packets, so that RTCP reports use the same SSRC.
+type rtpSSRCWriter struct {
+	w    rtp.WriteStream
+	ssrc *atomic.Uint64 // SSRC in the lower bits, the upper bit is set once a packet is sent
+}
+
+func (w *rtpSSRCWriter) String() string {
+	return w.w.String()
+}
+
+func (w *rtpSSRCWriter) WriteRTP(h *prtp.Header, payload []byte) (int, error) {
+	w.ssrc.Store(1<<32 | uint64(h.SSRC))
+	return w.w.WriteRTP(h, payload)
+}
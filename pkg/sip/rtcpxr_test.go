@@ -0,0 +1,295 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livekit/media-sdk/g722"
+	"github.com/livekit/media-sdk/rtp"
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	"github.com/livekit/protocol/logger"
+	"github.com/pion/rtcp"
+	prtp "github.com/pion/rtp"
+	psdp "github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func feedRecvStats(s *rtpRecvStats, n int, lost func(i int) bool) {
+	start := time.Unix(0, 0)
+	for i := range n {
+		if lost != nil && lost(i) {
+			continue
+		}
+		s.Update(&rtp.Header{
+			SSRC:           0x1234,
+			SequenceNumber: uint16(65000 + i), // wraps around
+			Timestamp:      uint32(i * 160),
+		}, start.Add(time.Duration(i)*rtp.DefFrameDur))
+	}
+}
+
+func TestRTPRecvStats(t *testing.T) {
+	t.Run("no loss", func(t *testing.T) {
+		var s rtpRecvStats
+		s.SetClockRate(8000)
+		feedRecvStats(&s, 1000, nil)
+		m := s.Metrics(rtp.DefFrameDur)
+		require.Equal(t, uint32(0x1234), m.SSRC)
+		require.Equal(t, uint64(1000), m.Expected)
+		require.Zero(t, m.Lost)
+		require.Zero(t, m.LossRate)
+		require.Zero(t, m.Jitter)
+		require.Zero(t, m.BurstDuration)
+		require.Equal(t, 20*time.Second, m.GapDuration)
+		require.InDelta(t, 93.2, m.RFactor, 0.01)
+		require.InDelta(t, 4.4, m.MOS, 0.05)
+	})
+	t.Run("isolated loss", func(t *testing.T) {
+		var s rtpRecvStats
+		s.SetClockRate(8000)
+		// Lose every 100th packet: all losses are isolated and belong to gaps.
+		feedRecvStats(&s, 1000, func(i int) bool { return i%100 == 50 })
+		m := s.Metrics(rtp.DefFrameDur)
+		require.Equal(t, uint64(10), m.Lost)
+		require.InDelta(t, 0.01, m.LossRate, 1e-9)
+		require.Zero(t, m.BurstDensity)
+		require.InDelta(t, 0.01, m.GapDensity, 0.001)
+		require.Less(t, m.MOS, 4.4)
+	})
+	t.Run("burst loss", func(t *testing.T) {
+		var s rtpRecvStats
+		s.SetClockRate(8000)
+		// Single burst of 10 lost packets in the middle of the call.
+		feedRecvStats(&s, 1000, func(i int) bool { return i >= 500 && i < 510 })
+		m := s.Metrics(rtp.DefFrameDur)
+		require.Equal(t, uint64(10), m.Lost)
+		require.Equal(t, 1.0, m.BurstDensity)
+		require.Equal(t, 200*time.Millisecond, m.BurstDuration)
+		require.Zero(t, m.GapDensity)
+	})
+	t.Run("reordered", func(t *testing.T) {
+		var s rtpRecvStats
+		s.SetClockRate(8000)
+		start := time.Unix(0, 0)
+		var events []rtpRecvEvent
+		for _, i := range []int{0, 1, 3, 4, 2, 5, 20, 6, 21, 2} {
+			events = append(events, s.Update(&rtp.Header{
+				SSRC:           0x1234,
+				SequenceNumber: uint16(65530 + i), // wraps around
+				Timestamp:      uint32(i * 160),
+			}, start.Add(time.Duration(i)*rtp.DefFrameDur)))
+		}
+		require.Equal(t, []rtpRecvEvent{
+			rtpRecvRestart, rtpRecvInOrder, rtpRecvGap, rtpRecvInOrder, rtpRecvReordered,
+			rtpRecvInOrder, rtpRecvGap, rtpRecvLate, rtpRecvInOrder, rtpRecvLate,
+		}, events)
+		m := s.Metrics(rtp.DefFrameDur)
+		require.Equal(t, uint64(22), m.Expected)
+		require.Equal(t, uint64(14), m.Lost) // 6 arrived too late, 6-19 are lost
+		require.Equal(t, uint64(1), m.Reordered)
+		require.Equal(t, uint64(2), m.Discarded)
+	})
+	t.Run("codec not modeled", func(t *testing.T) {
+		var s rtpRecvStats
+		s.SetCodec(g722.SDPName, 8000)
+		feedRecvStats(&s, 100, nil)
+		m := s.Metrics(rtp.DefFrameDur)
+		require.Equal(t, uint64(100), m.Expected)
+		require.Zero(t, m.RFactor)
+		require.Zero(t, m.MOS)
+		b := m.ReportBlock(false)
+		require.Equal(t, uint8(rtcpXRUnavailable), b.RFactor)
+		require.Equal(t, uint8(rtcpXRUnavailable), b.MOSLQ)
+	})
+	t.Run("duplicates", func(t *testing.T) {
+		var s rtpRecvStats
+		s.SetClockRate(8000)
+		feedRecvStats(&s, 10, nil)
+		feedRecvStats(&s, 5, nil) // late duplicates
+		m := s.Metrics(rtp.DefFrameDur)
+		require.Equal(t, uint64(10), m.Expected)
+		require.Equal(t, uint64(5), m.Discarded)
+		require.InDelta(t, 0.5, m.DiscardRate, 1e-9)
+	})
+}
+
+func TestVoIPMetricsReport(t *testing.T) {
+	m := VoIPMetrics{
+		SSRC:          0x1234,
+		LossRate:      0.05,
+		BurstDensity:  0.5,
+		BurstDuration: 100 * time.Millisecond,
+		GapDuration:   5 * time.Second,
+		Jitter:        12 * time.Millisecond,
+		RFactor:       80,
+		MOS:           4.02,
+	}
+	xr := &rtcp.ExtendedReport{
+		SenderSSRC: 1,
+		Reports:    []rtcp.ReportBlock{m.ReportBlock(true)},
+	}
+	data, err := xr.Marshal()
+	require.NoError(t, err)
+	var got rtcp.ExtendedReport
+	require.NoError(t, got.Unmarshal(data))
+	require.Len(t, got.Reports, 1)
+	b, ok := got.Reports[0].(*rtcp.VoIPMetricsReportBlock)
+	require.True(t, ok)
+	require.Equal(t, uint8(13), b.LossRate)
+	require.Equal(t, uint8(128), b.BurstDensity)
+	require.Equal(t, uint16(100), b.BurstDuration)
+	require.Equal(t, uint8(rtcpXRGmin), b.Gmin)
+	require.Equal(t, uint8(80), b.RFactor)
+	require.Equal(t, uint8(40), b.MOSLQ)
+	require.Equal(t, uint8(rtcpXRUnavailable), b.MOSCQ)
+	require.Equal(t, uint8(0x30), b.RXConfig)
+
+	rep := &vqReport{
+		CallID:       "abc",
+		LocalID:      "sip:+1000@lk.example.com",
+		RemoteID:     "sip:+2000@carrier.example.com",
+		OrigID:       "sip:+2000@carrier.example.com",
+		LocalAddr:    netip.MustParseAddrPort("1.1.1.1:10000"),
+		RemoteAddr:   netip.MustParseAddrPort("2.2.2.2:20000"),
+		Start:        time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+		Stop:         time.Date(2025, 1, 1, 10, 5, 0, 0, time.UTC),
+		PayloadType:  0,
+		Codec:        "PCMU/8000",
+		SampleRate:   8000,
+		JitterBuffer: true,
+		Metrics:      m,
+	}
+	body := rep.String()
+	require.True(t, strings.HasPrefix(body, "VQSessionReport: CallTerm\r\n"))
+	for _, line := range []string{
+		"CallID: abc",
+		"RemoteAddr: IP=2.2.2.2 PORT=20000 SSRC=0x00001234",
+		"Timestamps:START=2025-01-01T10:00:00Z STOP=2025-01-01T10:05:00Z",
+		"SessionDesc:PT=0 PD=PCMU SR=8000 FD=20",
+		"JitterBuffer:JBA=3",
+		"PacketLoss:NLR=5.0 JDR=0.0",
+		"BurstGapLoss:BLD=50.0 BD=100 GLD=0.0 GD=5000 GMIN=16",
+		"Delay:IAJ=12",
+		"QualityEst:RLQ=80 MOSLQ=4.0",
+	} {
+		require.Contains(t, body, line+"\r\n")
+	}
+	require.NotContains(t, body, "MOSCQ")
+}
+
+// rtcpTestConn captures packets sent to addresses other than the RTP peer.
+type rtcpTestConn struct {
+	*testUDPConn
+	sent chan rtcpTestPacket
+}
+
+type rtcpTestPacket struct {
+	data []byte
+	addr netip.AddrPort
+}
+
+func (c *rtcpTestConn) WriteToUDPAddrPort(buf []byte, addr netip.AddrPort) (int, error) {
+	if peer := c.peer.Load(); peer != nil && peer.addr == addr {
+		return c.testUDPConn.WriteToUDPAddrPort(buf, addr)
+	}
+	c.sent <- rtcpTestPacket{data: slices.Clone(buf), addr: addr}
+	return len(buf), nil
+}
+
+func TestMediaPortRTCPXR(t *testing.T) {
+	c1, c2 := newUDPPipe()
+	conn := &rtcpTestConn{testUDPConn: c1, sent: make(chan rtcpTestPacket, 10)}
+	m, err := NewMediaPortWith(logger.GetLogger(), nil, conn, &MediaOptions{
+		IP:             newIP("1.1.1.1"),
+		Ports:          rtcconfig.PortRange{Start: 10000},
+		RTCPXRInterval: time.Second,
+	}, 8000)
+	require.NoError(t, err)
+	defer m.Close()
+	m.port.SetDst(c2.addr)
+	rtcpAddr := netip.AddrPortFrom(c2.addr.Addr(), c2.addr.Port()+1)
+	m.conf = &MediaConf{RemoteRTCP: rtcpAddr}
+
+	// Nothing is reported before RTP is received.
+	require.NoError(t, m.sendRTCPXR())
+	require.Empty(t, conn.sent)
+
+	m.recv.SetClockRate(8000)
+	feedRecvStats(&m.recv, 100, func(i int) bool { return i%10 == 5 })
+	recv := func(t *testing.T) []byte {
+		require.NoError(t, m.sendRTCPXR())
+		select {
+		case p := <-conn.sent:
+			// RTCP is not multiplexed with RTP.
+			require.Equal(t, rtcpAddr, p.addr)
+			require.Empty(t, c2.buf)
+			return p.data
+		default:
+			t.Fatal("no rtcp sent")
+			return nil
+		}
+	}
+	data := recv(t)
+	require.Equal(t, mediaPacketRTCP, classifyMediaPacket(data))
+	pkts, err := rtcp.Unmarshal(data)
+	require.NoError(t, err)
+	require.Len(t, pkts, 2)
+	rr, ok := pkts[0].(*rtcp.ReceiverReport)
+	require.True(t, ok)
+	require.Equal(t, m.rtcpSSRC, rr.SSRC)
+	xr, ok := pkts[1].(*rtcp.ExtendedReport)
+	require.True(t, ok)
+	require.Equal(t, m.rtcpSSRC, xr.SenderSSRC)
+	require.Len(t, xr.Reports, 1)
+	b, ok := xr.Reports[0].(*rtcp.VoIPMetricsReportBlock)
+	require.True(t, ok)
+	require.Equal(t, uint32(0x1234), b.SSRC)
+	require.Equal(t, scaleRTCPXR(0.1), b.LossRate)
+	require.Equal(t, uint8(rtcpXRUnavailable), b.MOSCQ)
+
+	// Once RTP is sent, reports use its SSRC.
+	w := &rtpSSRCWriter{w: nopRTPWriteStream{}, ssrc: &m.sendSSRC}
+	_, err = w.WriteRTP(&prtp.Header{SSRC: 0xabcd}, nil)
+	require.NoError(t, err)
+	pkts, err = rtcp.Unmarshal(recv(t))
+	require.NoError(t, err)
+	require.Equal(t, uint32(0xabcd), pkts[0].(*rtcp.ReceiverReport).SSRC)
+	require.Equal(t, uint32(0xabcd), pkts[1].(*rtcp.ExtendedReport).SenderSSRC)
+}
+
+type nopRTPWriteStream struct{}
+
+func (nopRTPWriteStream) String() string { return "nop" }
+
+func (nopRTPWriteStream) WriteRTP(h *prtp.Header, payload []byte) (int, error) {
+	return len(payload), nil
+}
+
+func TestRTCPRemoteAddr(t *testing.T) {
+	rtpAddr := netip.MustParseAddrPort("2.2.2.2:20000")
+	media := func(attrs ...psdp.Attribute) *psdp.MediaDescription {
+		return &psdp.MediaDescription{Attributes: attrs}
+	}
+	require.Equal(t, netip.MustParseAddrPort("2.2.2.2:20001"), rtcpRemoteAddr(media(), rtpAddr))
+	require.Equal(t, netip.MustParseAddrPort("2.2.2.2:30000"), rtcpRemoteAddr(media(psdp.NewAttribute("rtcp", "30000")), rtpAddr))
+	require.Equal(t, netip.MustParseAddrPort("3.3.3.3:30000"), rtcpRemoteAddr(media(psdp.NewAttribute("rtcp", "30000 IN IP4 3.3.3.3")), rtpAddr))
+	require.False(t, rtcpRemoteAddr(media(psdp.NewAttribute("rtcp", "")), rtpAddr).IsValid())
+	require.False(t, rtcpRemoteAddr(media(), netip.AddrPort{}).IsValid())
+}
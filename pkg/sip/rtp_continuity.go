@@ -0,0 +1,100 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/media-sdk/rtp"
+)
+
+const (
+	// maxTSJump is the largest RTP timestamp jump in the audio stream that is not considered a discontinuity.
+	// Larger jumps usually happen after hold or long silence suppression.
+	maxTSJump = 10 * time.Second
+	// maxTSBack is the largest backward RTP timestamp change that is still considered a reordering.
+	maxTSBack = time.Second
+)
+
+// rtpContinuity detects discontinuities in the incoming RTP stream, such as sequence number resets after
+// hold/resume, or large timestamp jumps. The jitter buffer drops packets with sequence numbers that appear
+// to be in the past, so the decoding pipeline must be reset when it happens.
+type rtpContinuity struct {
+	mu          sync.Mutex
+	payloadType byte
+	clockRate   int
+
+	started bool
+	ssrc    uint32
+	seq     uint16
+	ts      uint32
+	hasTS   bool
+}
+
+func (c *rtpContinuity) SetCodec(payloadType byte, clockRate int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.payloadType = payloadType
+	c.clockRate = clockRate
+	c.hasTS = false
+}
+
+// Check accounts a received RTP packet and returns true if it starts a new discontinuous segment of the stream.
+// The first packet of the stream is not considered a discontinuity.
+func (c *rtpContinuity) Check(h *rtp.Header) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.started {
+		c.started = true
+		c.update(h)
+		return false
+	}
+	if h.SSRC != c.ssrc {
+		c.hasTS = false
+		c.update(h)
+		return true
+	}
+	discont := false
+	switch delta := h.SequenceNumber - c.seq; {
+	case delta > maxSeqJump && delta < 1<<16-maxSeqJump:
+		// Too far in either direction.
+		discont = true
+	case delta >= 0x8000 && h.Marker:
+		// Sequence number went back at the start of a talk spurt: the remote restarted the stream.
+		discont = true
+	case delta >= 0x8000:
+		// Reordered packet.
+		return false
+	}
+	if !discont && c.hasTS && h.PayloadType == c.payloadType && c.clockRate > 0 {
+		d := time.Duration(int32(h.Timestamp-c.ts)) * time.Second / time.Duration(c.clockRate)
+		discont = d > maxTSJump || d < -maxTSBack
+	}
+	if discont {
+		c.hasTS = false
+	}
+	c.update(h)
+	return discont
+}
+
+func (c *rtpContinuity) update(h *rtp.Header) {
+	c.ssrc = h.SSRC
+	c.seq = h.SequenceNumber
+	if h.PayloadType == c.payloadType {
+		c.ts = h.Timestamp
+		c.hasTS = true
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/livekit/media-sdk/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRTPContinuity(t *testing.T) {
+	const (
+		audioPT = 0
+		dtmfPT  = 101
+	)
+	var c rtpContinuity
+	c.SetCodec(audioPT, 8000)
+	cases := []struct {
+		name string
+		h    rtp.Header
+		exp  bool
+	}{
+		{name: "first", h: rtp.Header{SSRC: 1, SequenceNumber: 65534, Timestamp: 1000}},
+		{name: "next", h: rtp.Header{SSRC: 1, SequenceNumber: 65535, Timestamp: 1160}},
+		{name: "wrap", h: rtp.Header{SSRC: 1, SequenceNumber: 0, Timestamp: 1320}},
+		{name: "reordered", h: rtp.Header{SSRC: 1, SequenceNumber: 65535, Timestamp: 1160}},
+		{name: "dtmf", h: rtp.Header{SSRC: 1, SequenceNumber: 1, Timestamp: 0, PayloadType: dtmfPT}},
+		{name: "silence suppression", h: rtp.Header{SSRC: 1, SequenceNumber: 2, Timestamp: 1320 + 8000*5, Marker: true}},
+		{name: "timestamp jump", h: rtp.Header{SSRC: 1, SequenceNumber: 3, Timestamp: 1320 + 8000*60}, exp: true},
+		{name: "timestamp back", h: rtp.Header{SSRC: 1, SequenceNumber: 4, Timestamp: 1000}, exp: true},
+		{name: "after reset", h: rtp.Header{SSRC: 1, SequenceNumber: 5, Timestamp: 1160}},
+		{name: "seq restart", h: rtp.Header{SSRC: 1, SequenceNumber: 0, Timestamp: 1320, Marker: true}, exp: true},
+		{name: "seq jump", h: rtp.Header{SSRC: 1, SequenceNumber: 10000, Timestamp: 1480}, exp: true},
+		{name: "new ssrc", h: rtp.Header{SSRC: 2, SequenceNumber: 10001, Timestamp: 1640}, exp: true},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.exp, c.Check(&tc.h), tc.name)
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/frostbyte73/core"
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	prtp "github.com/pion/rtp"
+	psdp "github.com/pion/sdp/v3"
+
+	"github.com/livekit/media-sdk/rtp"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+)
+
+const (
+	t140Codec = "t140"
+	// t140ClockRate is the RTP clock rate of T.140 text (RFC 4103).
+	t140ClockRate = 1000
+	// t140MaxPayload limits the size of the text in a single RTP packet.
+	t140MaxPayload = 1000
+	// t140IdleTime is the time without text after which the marker bit is set on the next packet.
+	// It matches the transmission interval recommended by RFC 4103.
+	t140IdleTime = 300 * time.Millisecond
+	// rttMaxLine limits the number of characters kept for the current line of received text.
+	rttMaxLine = 4096
+)
+
+// Special characters of T.140 text.
+const (
+	t140Backspace     = '\u0008'
+	t140LineSeparator = '\u2028'
+	t140ParaSeparator = '\u2029'
+	t140BOM           = '\uFEFF'
+	t140Loss          = '\uFFFD' // marks text lost in transmission (RFC 4103, section 5.4)
+)
+
+// t140PayloadType returns the payload type of T.140 text offered in the media line.
+func t140PayloadType(m *psdp.MediaDescription) (uint8, bool) {
+	for _, a := range m.Attributes {
+		if a.Key != "rtpmap" {
+			continue
+		}
+		pt, codec, ok := strings.Cut(a.Value, " ")
+		name, _, _ := strings.Cut(codec, "/")
+		if !ok || !strings.EqualFold(name, t140Codec) || !slices.Contains(m.MediaName.Formats, pt) {
+			continue
+		}
+		if v, err := strconv.ParseUint(pt, 10, 7); err == nil {
+			return uint8(v), true
+		}
+	}
+	return 0, false
+}
+
+// findT140Media returns the index of the first text media line offering plain RTP with T.140, or -1.
+// Encrypted text is not supported. Redundant text (RFC 2198) is not selected, but T.140 is always offered along with it.
+func findT140Media(s *psdp.SessionDescription) (int, uint8) {
+	for i, m := range s.MediaDescriptions {
+		if m.MediaName.Media != "text" || m.MediaName.Port.Value == 0 {
+			continue
+		}
+		if protos := strings.Join(m.MediaName.Protos, "/"); protos != "RTP/AVP" && protos != "RTP/AVPF" {
+			continue
+		}
+		if pt, ok := t140PayloadType(m); ok {
+			return i, pt
+		}
+	}
+	return -1, 0
+}
+
+// mediaAddr returns the remote address of the media line.
+func mediaAddr(s *psdp.SessionDescription, m *psdp.MediaDescription) (netip.AddrPort, bool) {
+	ci := m.ConnectionInformation
+	if ci == nil {
+		ci = s.ConnectionInformation
+	}
+	if ci == nil || ci.Address == nil {
+		return netip.AddrPort{}, false
+	}
+	ip, err := netip.ParseAddr(ci.Address.Address)
+	if err != nil {
+		ip, err = sdpHosts.Resolve(ci.Address.Address, netip.Addr{})
+	}
+	if err != nil || ip.IsUnspecified() {
+		return netip.AddrPort{}, false
+	}
+	return netip.AddrPortFrom(ip, uint16(m.MediaName.Port.Value)), true
+}
+
+// rttLine accumulates received real-time text of the current line, applying erasures.
+type rttLine struct {
+	id   string
+	text []rune
+}
+
+// Write applies received T.140 text and returns lines completed by it.
+func (l *rttLine) Write(text string) []string {
+	var lines []string
+	for _, r := range text {
+		switch r {
+		case t140BOM, '\r':
+			// CRLF ends the line on LF.
+		case t140Backspace:
+			if n := len(l.text); n != 0 {
+				l.text = l.text[:n-1]
+			}
+		case t140LineSeparator, t140ParaSeparator, '\n':
+			lines = append(lines, string(l.text))
+			l.text = l.text[:0]
+		default:
+			// Control codes and presentation sequences are not relayed.
+			if unicode.IsControl(r) || len(l.text) >= rttMaxLine {
+				continue
+			}
+			l.text = append(l.text, r)
+		}
+	}
+	return lines
+}
+
+// rttSession relays real-time text (RFC 4103) between SIP and the room.
+//
+// Received text is reported line by line: the current line is reported on each change,
+// and once more as final when the line is completed.
+type rttSession struct {
+	log    logger.Logger
+	conn   *net.UDPConn
+	protos []string
+	pt     uint8
+	onText func(id, text string, final bool)
+	closed core.Fuse
+
+	mu     sync.Mutex
+	remote netip.AddrPort
+	ssrc   uint32
+	seq    uint16
+	start  time.Time
+	last   time.Time // time of the last sent packet
+}
+
+// newRTTSession listens for real-time text from the remote, which uses a given payload type for T.140.
+func newRTTSession(log logger.Logger, ports rtcconfig.PortRange, remote netip.AddrPort, protos []string, pt uint8, onText func(id, text string, final bool)) (*rttSession, error) {
+	conn, err := rtp.ListenUDPPortRange(int(ports.Start), int(ports.End), netip.AddrFrom4([4]byte{0, 0, 0, 0}))
+	if err != nil {
+		return nil, err
+	}
+	return &rttSession{
+		log:    log,
+		conn:   conn,
+		protos: protos,
+		pt:     pt,
+		onText: onText,
+		remote: remote,
+		ssrc:   rand.Uint32(),
+		seq:    uint16(rand.Uint32()),
+		start:  time.Now(),
+	}, nil
+}
+
+// Media returns the text media line for the SDP answer.
+func (s *rttSession) Media() *psdp.MediaDescription {
+	pt := strconv.Itoa(int(s.pt))
+	return &psdp.MediaDescription{
+		MediaName: psdp.MediaName{
+			Media:   "text",
+			Port:    psdp.RangedPort{Value: s.conn.LocalAddr().(*net.UDPAddr).Port},
+			Protos:  s.protos,
+			Formats: []string{pt},
+		},
+		Attributes: []psdp.Attribute{
+			{Key: "rtpmap", Value: pt + " " + t140Codec + "/" + strconv.Itoa(t140ClockRate)},
+			{Key: "sendrecv"},
+		},
+	}
+}
+
+// Start starts reading text from the remote.
+func (s *rttSession) Start() {
+	go s.run()
+}
+
+func (s *rttSession) run() {
+	line := rttLine{id: guid.New("RTT_")}
+	buf := make([]byte, 1500)
+	var (
+		started bool
+		lastSeq uint16
+		p       prtp.Packet
+	)
+	for {
+		n, addr, err := s.conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if !s.closed.IsBroken() {
+				s.log.Infow("cannot read real-time text", "error", err)
+			}
+			return
+		}
+		if err = p.Unmarshal(buf[:n]); err != nil || p.PayloadType != s.pt {
+			continue
+		}
+		lost := false
+		if started {
+			diff := p.SequenceNumber - lastSeq
+			if diff == 0 || diff >= 0x8000 {
+				continue // duplicate or late
+			}
+			lost = diff > 1
+		}
+		started, lastSeq = true, p.SequenceNumber
+		s.mu.Lock()
+		// Symmetric RTP: send text back to where it comes from.
+		s.remote = netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port())
+		s.mu.Unlock()
+
+		text := strings.ToValidUTF8(string(p.Payload), string(t140Loss))
+		if lost {
+			text = string(t140Loss) + text
+		}
+		if text == "" {
+			continue // keep-alive
+		}
+		prev := string(line.text)
+		for _, l := range line.Write(text) {
+			s.onText(line.id, l, true)
+			line.id = guid.New("RTT_")
+			prev = ""
+		}
+		if cur := string(line.text); cur != prev {
+			s.onText(line.id, cur, false)
+		}
+	}
+}
+
+// SendText sends real-time text to SIP.
+func (s *rttSession) SendText(text string) error {
+	if s == nil || text == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	h := prtp.Header{
+		Version:     2,
+		Marker:      s.last.IsZero() || now.Sub(s.last) >= t140IdleTime,
+		PayloadType: s.pt,
+		Timestamp:   uint32(now.Sub(s.start).Milliseconds()),
+		SSRC:        s.ssrc,
+	}
+	s.last = now
+	for text != "" {
+		n := min(len(text), t140MaxPayload)
+		for n < len(text) && !utf8.RuneStart(text[n]) {
+			n--
+		}
+		h.SequenceNumber = s.seq
+		data, err := (&prtp.Packet{Header: h, Payload: []byte(text[:n])}).Marshal()
+		if err != nil {
+			return err
+		}
+		if _, err = s.conn.WriteToUDPAddrPort(data, s.remote); err != nil {
+			return err
+		}
+		s.seq++
+		h.Marker = false
+		text = text[n:]
+	}
+	return nil
+}
+
+func (s *rttSession) Close() {
+	if s == nil {
+		return
+	}
+	s.closed.Break()
+	_ = s.conn.Close()
+}
+
+// setupRTT answers the real-time text media line of the offer, if there is one, and relays text between SIP and the room.
+func (c *inboundCall) setupRTT(offerData []byte, answer *psdp.SessionDescription, ports rtcconfig.PortRange) {
+	offer := new(psdp.SessionDescription)
+	if err := offer.Unmarshal(offerData); err != nil {
+		return // already validated by the media port
+	}
+	idx, pt := findT140Media(offer)
+	if idx < 0 {
+		return
+	}
+	m := offer.MediaDescriptions[idx]
+	remote, ok := mediaAddr(offer, m)
+	if !ok {
+		c.log.Infow("rejecting real-time text without remote address")
+		answerExtraMedia(answer, offer, idx, nil)
+		return
+	}
+	s, err := newRTTSession(c.log, ports, remote, m.MediaName.Protos, pt, c.publishRTT)
+	if err != nil {
+		c.log.Warnw("cannot listen for real-time text", err)
+		answerExtraMedia(answer, offer, idx, nil)
+		return
+	}
+	answerExtraMedia(answer, offer, idx, s.Media())
+	c.rtt = s
+	c.lkRoom.OnChat(c.sendChatToSIP)
+	s.Start()
+	c.log.Infow("real-time text accepted", "remote", remote.String(), "payloadType", pt)
+}
+
+// publishRTT publishes real-time text from SIP as a transcription of the SIP participant.
+// Completed lines are also sent as chat messages.
+func (c *inboundCall) publishRTT(id, text string, final bool) {
+	if err := c.lkRoom.SendTranscription(id, text, final); err != nil {
+		c.log.Infow("cannot send real-time text to the room", "error", err)
+	}
+	if final && text != "" {
+		if err := c.lkRoom.SendChat(text); err != nil {
+			c.log.Infow("cannot send chat message to the room", "error", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
+	prtp "github.com/pion/rtp"
+	psdp "github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+)
+
+func TestRTTLine(t *testing.T) {
+	var l rttLine
+	require.Empty(t, l.Write("\uFEFFhelo"))
+	require.Empty(t, l.Write("\bl\bl\blo"))
+	require.Equal(t, "hello", string(l.text))
+	require.Equal(t, []string{"hello world", "second"}, l.Write(" world\u2028second\r\nthi\x1b"))
+	require.Equal(t, "thi", string(l.text))
+}
+
+func TestFindT140Media(t *testing.T) {
+	const offer = "v=0\r\n" +
+		"o=- 1 1 IN IP4 10.0.0.1\r\n" +
+		"s=-\r\n" +
+		"c=IN IP4 10.0.0.1\r\n" +
+		"t=0 0\r\n" +
+		"m=audio 5000 RTP/AVP 0\r\n" +
+		"a=rtpmap:0 PCMU/8000\r\n" +
+		"m=text 5002 RTP/AVP 100 98\r\n" +
+		"a=rtpmap:100 red/1000\r\n" +
+		"a=fmtp:100 98/98/98\r\n" +
+		"a=rtpmap:98 t140/1000\r\n"
+	var s psdp.SessionDescription
+	require.NoError(t, s.Unmarshal([]byte(offer)))
+	idx, pt := findT140Media(&s)
+	require.Equal(t, 1, idx)
+	require.Equal(t, uint8(98), pt)
+	addr, ok := mediaAddr(&s, s.MediaDescriptions[idx])
+	require.True(t, ok)
+	require.Equal(t, netip.MustParseAddrPort("10.0.0.1:5002"), addr)
+
+	s.MediaDescriptions[1].MediaName.Protos = []string{"RTP", "SAVP"}
+	idx, _ = findT140Media(&s)
+	require.Equal(t, -1, idx)
+}
+
+type rttUpdate struct {
+	id    string
+	text  string
+	final bool
+}
+
+func TestRTTSession(t *testing.T) {
+	const pt = 98
+	remote, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer remote.Close()
+	_ = remote.SetDeadline(time.Now().Add(5 * time.Second))
+
+	updates := make(chan rttUpdate, 10)
+	s, err := newRTTSession(logger.GetLogger(), rtcconfig.PortRange{}, remote.LocalAddr().(*net.UDPAddr).AddrPort(), []string{"RTP", "AVP"}, pt,
+		func(id, text string, final bool) {
+			updates <- rttUpdate{id: id, text: text, final: final}
+		})
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t, []string{"98"}, s.Media().MediaName.Formats)
+	s.Start()
+
+	local := netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), uint16(s.conn.LocalAddr().(*net.UDPAddr).Port))
+	send := func(seq uint16, text string) {
+		data, err := (&prtp.Packet{Header: prtp.Header{Version: 2, PayloadType: pt, SequenceNumber: seq}, Payload: []byte(text)}).Marshal()
+		require.NoError(t, err)
+		_, err = remote.WriteToUDPAddrPort(data, local)
+		require.NoError(t, err)
+	}
+	send(10, "hi")
+	u := <-updates
+	require.Equal(t, "hi", u.text)
+	require.False(t, u.final)
+	id := u.id
+
+	send(10, "hi") // duplicate
+	send(12, "!\u2028")
+	u = <-updates
+	require.Equal(t, rttUpdate{id: id, text: "hi\uFFFD!", final: true}, u)
+
+	require.NoError(t, s.SendText("hello\u2028"))
+	buf := make([]byte, 1500)
+	n, _, err := remote.ReadFromUDPAddrPort(buf)
+	require.NoError(t, err)
+	var p prtp.Packet
+	require.NoError(t, p.Unmarshal(buf[:n]))
+	require.True(t, p.Marker)
+	require.Equal(t, uint8(pt), p.PayloadType)
+	require.Equal(t, "hello\u2028", string(p.Payload))
+}
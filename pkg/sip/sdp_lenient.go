@@ -0,0 +1,301 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Kinds of SDP anomalies fixed in lenient mode.
+const (
+	// sdpAnomalyMalformed is reported when a line cannot be parsed and is skipped.
+	sdpAnomalyMalformed = "malformed"
+	// sdpAnomalyDuplicate is reported when a session line or an rtpmap for the same payload type is repeated.
+	sdpAnomalyDuplicate = "duplicate"
+	// sdpAnomalyInvalidPort is reported when a media port is not a number.
+	sdpAnomalyInvalidPort = "invalid-port"
+	// sdpAnomalyMissing is reported when a required session line is missing and a default is used.
+	sdpAnomalyMissing = "missing"
+	// sdpAnomalyOrder is reported when lines are not in the order required by RFC 8866.
+	sdpAnomalyOrder = "order"
+)
+
+// Defaults for required session lines missing in lenient mode.
+const (
+	sdpDefaultVersion = "v=0"
+	sdpDefaultOrigin  = "o=- 0 0 IN IP4 0.0.0.0"
+	sdpDefaultSession = "s=-"
+	sdpDefaultTiming  = "t=0 0"
+)
+
+// Order of lines in the session and media sections (RFC 8866, section 5). Repeated time and repeat lines share a rank.
+const (
+	sdpSessionOrder = "vosiuepcbtzka"
+	sdpMediaOrder   = "mickba"
+)
+
+// sdpRank returns the rank of the line type in the order, or -1 if the type is not allowed.
+func sdpRank(order string, typ byte) int {
+	if typ == 'r' {
+		typ = 't'
+	}
+	return strings.IndexByte(order, typ)
+}
+
+type sdpLine struct {
+	typ   byte
+	value string
+}
+
+func (l sdpLine) String() string {
+	return string(l.typ) + "=" + l.value
+}
+
+type sdpSection struct {
+	lines  []sdpLine
+	rtpmap map[string]struct{}
+}
+
+// sdpSanitizer fixes SDP from remotes that don't follow the grammar closely enough for the strict parser.
+type sdpSanitizer struct {
+	anomalies []string
+}
+
+func (s *sdpSanitizer) report(kind string, line string) {
+	s.anomalies = append(s.anomalies, kind+": "+line)
+}
+
+// sanitizeSDP rewrites the SDP so that the strict parser accepts it. Malformed lines and repeated rtpmaps are skipped,
+// invalid media ports are fixed, missing session lines get defaults and lines are sorted in the required order.
+// It returns fixed anomalies for logging, or the original data if there were none.
+func sanitizeSDP(data []byte) ([]byte, []string) {
+	var (
+		s       sdpSanitizer
+		session = &sdpSection{}
+		media   []*sdpSection
+		skip    bool // skip lines of a dropped media section
+	)
+	cur := session
+	for _, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		if len(raw) < 2 || raw[1] != '=' || raw[0] < 'a' || raw[0] > 'z' {
+			s.report(sdpAnomalyMalformed, raw)
+			continue
+		}
+		line := sdpLine{typ: raw[0], value: strings.TrimSpace(raw[2:])}
+		if line.typ == 'm' {
+			m, ok := s.mediaLine(line)
+			skip = !ok
+			if !ok {
+				continue
+			}
+			cur = &sdpSection{lines: []sdpLine{m}}
+			media = append(media, cur)
+			continue
+		}
+		if skip {
+			continue
+		}
+		order := sdpSessionOrder
+		if cur != session {
+			order = sdpMediaOrder
+		}
+		if sdpRank(order, line.typ) < 0 {
+			s.report(sdpAnomalyMalformed, raw)
+			continue
+		}
+		if line, ok := s.checkLine(cur, line); ok {
+			cur.lines = append(cur.lines, line)
+		}
+	}
+	ordered := s.sortLines(sdpSessionOrder, session)
+	for _, m := range media {
+		ordered = s.sortLines(sdpMediaOrder, m) && ordered
+	}
+	if !ordered {
+		s.report(sdpAnomalyOrder, "lines reordered")
+	}
+	s.sessionDefaults(session)
+	s.sortLines(sdpSessionOrder, session)
+	if len(s.anomalies) == 0 {
+		return data, nil
+	}
+	var out bytes.Buffer
+	for _, sec := range append([]*sdpSection{session}, media...) {
+		for _, l := range sec.lines {
+			out.WriteString(l.String())
+			out.WriteString("\r\n")
+		}
+	}
+	return out.Bytes(), s.anomalies
+}
+
+// sortLines sorts lines of the section in the given order. It returns false if the lines were not sorted.
+func (s *sdpSanitizer) sortLines(order string, sec *sdpSection) bool {
+	cmp := func(a, b sdpLine) int {
+		return sdpRank(order, a.typ) - sdpRank(order, b.typ)
+	}
+	if slices.IsSortedFunc(sec.lines, cmp) {
+		return true
+	}
+	slices.SortStableFunc(sec.lines, cmp)
+	return false
+}
+
+// sessionDefaults keeps the first version, origin and session name lines, and adds missing required lines.
+func (s *sdpSanitizer) sessionDefaults(sec *sdpSection) {
+	seen := make(map[byte]bool)
+	sec.lines = slices.DeleteFunc(sec.lines, func(l sdpLine) bool {
+		switch l.typ {
+		case 'v', 'o', 's':
+			if seen[l.typ] {
+				s.report(sdpAnomalyDuplicate, l.String())
+				return true
+			}
+		}
+		seen[l.typ] = true
+		return false
+	})
+	for _, def := range []string{sdpDefaultVersion, sdpDefaultOrigin, sdpDefaultSession, sdpDefaultTiming} {
+		if !seen[def[0]] {
+			s.report(sdpAnomalyMissing, def)
+			sec.lines = append(sec.lines, sdpLine{typ: def[0], value: def[2:]})
+		}
+	}
+}
+
+// mediaLine checks the media line. Ports which are not a number are replaced by their numeric prefix,
+// or by zero which disables the stream. It returns false if the whole media section must be skipped.
+func (s *sdpSanitizer) mediaLine(line sdpLine) (sdpLine, bool) {
+	fields := strings.Fields(line.value)
+	if len(fields) < 4 || !slices.Contains([]string{"audio", "video", "text", "application", "message"}, fields[0]) {
+		s.report(sdpAnomalyMalformed, line.String())
+		return line, false
+	}
+	for _, p := range strings.Split(fields[2], "/") {
+		if !slices.Contains([]string{"UDP", "RTP", "AVP", "SAVP", "SAVPF", "TLS", "DTLS", "SCTP", "AVPF", "TCP", "MSRP", "BFCP", "UDT", "IX", "MRCPv2", "FEC"}, p) {
+			s.report(sdpAnomalyMalformed, line.String())
+			return line, false
+		}
+	}
+	port, rng, _ := strings.Cut(fields[1], "/")
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || (rng != "" && !isDigits(rng)) {
+		fixed := strconv.FormatUint(n, 10)
+		if err != nil {
+			fixed = "0"
+			if i := strings.IndexFunc(port, func(r rune) bool { return r < '0' || r > '9' }); i > 0 {
+				if n, err := strconv.ParseUint(port[:i], 10, 16); err == nil {
+					fixed = strconv.FormatUint(n, 10)
+				}
+			}
+		}
+		s.report(sdpAnomalyInvalidPort, line.String())
+		fields[1] = fixed
+	}
+	if strings.HasPrefix(fields[2], "RTP/") {
+		formats := slices.DeleteFunc(fields[3:], func(f string) bool { return !isPayloadType(f) })
+		if len(formats) == 0 {
+			s.report(sdpAnomalyMalformed, line.String())
+			return line, false
+		}
+		if len(formats) != len(fields)-3 {
+			s.report(sdpAnomalyMalformed, line.String())
+		}
+		fields = append(fields[:3], formats...)
+	}
+	return sdpLine{typ: 'm', value: strings.Join(fields, " ")}, true
+}
+
+// checkLine validates a session or media line which is not a media line. It returns false if the line must be skipped.
+func (s *sdpSanitizer) checkLine(sec *sdpSection, line sdpLine) (sdpLine, bool) {
+	fields := strings.Fields(line.value)
+	ok := true
+	switch line.typ {
+	case 'v':
+		ok = line.value == "0"
+	case 'o':
+		ok = len(fields) == 6 && isDigits(fields[1]) && isDigits(fields[2]) && fields[3] == "IN" && isAddrType(fields[4])
+	case 'c':
+		ok = len(fields) == 3 && fields[0] == "IN" && isAddrType(fields[1])
+	case 'b':
+		typ, bw, found := strings.Cut(line.value, ":")
+		ok = found && typ != "" && isDigits(bw)
+	case 't':
+		ok = len(fields) == 2 && isDigits(fields[0]) && isDigits(fields[1])
+	case 'a':
+		return s.checkAttribute(sec, line)
+	}
+	if !ok {
+		s.report(sdpAnomalyMalformed, line.String())
+	}
+	return line, ok
+}
+
+// checkAttribute validates rtpmap and fmtp attributes, and skips rtpmaps repeated for the same payload type.
+func (s *sdpSanitizer) checkAttribute(sec *sdpSection, line sdpLine) (sdpLine, bool) {
+	key, val, _ := strings.Cut(line.value, ":")
+	switch key {
+	case "rtpmap":
+		pt, enc, _ := strings.Cut(val, " ")
+		name, rate, _ := strings.Cut(strings.TrimSpace(enc), "/")
+		rate, _, _ = strings.Cut(rate, "/")
+		if !isPayloadType(pt) || name == "" || !isDigits(rate) {
+			s.report(sdpAnomalyMalformed, line.String())
+			return line, false
+		}
+		if _, ok := sec.rtpmap[pt]; ok {
+			s.report(sdpAnomalyDuplicate, line.String())
+			return line, false
+		}
+		if sec.rtpmap == nil {
+			sec.rtpmap = make(map[string]struct{})
+		}
+		sec.rtpmap[pt] = struct{}{}
+	case "fmtp":
+		pt, _, _ := strings.Cut(val, " ")
+		if !isPayloadType(pt) {
+			s.report(sdpAnomalyMalformed, line.String())
+			return line, false
+		}
+	}
+	return line, true
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isPayloadType(s string) bool {
+	n, err := strconv.ParseUint(s, 10, 8)
+	return err == nil && n < 128
+}
+
+func isAddrType(s string) bool {
+	return s == "IP4" || s == "IP6"
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/media-sdk/sdp"
+)
+
+func TestSanitizeSDP(t *testing.T) {
+	const valid = "v=0\r\n" +
+		"o=- 1 1 IN IP4 10.0.0.1\r\n" +
+		"s=-\r\n" +
+		"c=IN IP4 10.0.0.1\r\n" +
+		"t=0 0\r\n" +
+		"m=audio 5000 RTP/AVP 0 101\r\n" +
+		"a=rtpmap:0 PCMU/8000\r\n" +
+		"a=rtpmap:101 telephone-event/8000\r\n"
+
+	out, anomalies := sanitizeSDP([]byte(valid))
+	require.Empty(t, anomalies)
+	require.Equal(t, valid, string(out))
+
+	cases := []struct {
+		name      string
+		offer     string
+		anomalies []string
+		addr      string
+		contains  []string
+		excludes  []string
+	}{
+		{
+			name: "malformed lines",
+			offer: "v=0\r\n" +
+				"o=- 1 1 IN IP4 10.0.0.1\r\n" +
+				"s=-\r\n" +
+				"c=IN IP4 10.0.0.1\r\n" +
+				"t=0 0\r\n" +
+				"garbage\r\n" +
+				"m=audio 5000 RTP/AVP 0 101\r\n" +
+				"a=rtpmap:0 PCMU/8000\r\n" +
+				"a=rtpmap:x telephone-event\r\n" +
+				"a=fmtp:zz 0-16\r\n" +
+				"a=rtpmap:101 telephone-event/8000\r\n",
+			anomalies: []string{
+				"malformed: garbage",
+				"malformed: a=rtpmap:x telephone-event",
+				"malformed: a=fmtp:zz 0-16",
+			},
+			addr:     "10.0.0.1:5000",
+			excludes: []string{"garbage", "a=rtpmap:x", "a=fmtp:zz"},
+		},
+		{
+			name: "duplicate rtpmap",
+			offer: "v=0\r\n" +
+				"o=- 1 1 IN IP4 10.0.0.1\r\n" +
+				"s=-\r\n" +
+				"c=IN IP4 10.0.0.1\r\n" +
+				"t=0 0\r\n" +
+				"m=audio 5000 RTP/AVP 0\r\n" +
+				"a=rtpmap:0 PCMU/8000\r\n" +
+				"a=rtpmap:0 PCMA/8000\r\n",
+			anomalies: []string{"duplicate: a=rtpmap:0 PCMA/8000"},
+			addr:      "10.0.0.1:5000",
+			contains:  []string{"a=rtpmap:0 PCMU/8000\r\n"},
+			excludes:  []string{"PCMA"},
+		},
+		{
+			name: "invalid port",
+			offer: "v=0\r\n" +
+				"o=- 1 1 IN IP4 10.0.0.1\r\n" +
+				"s=-\r\n" +
+				"c=IN IP4 10.0.0.1\r\n" +
+				"t=0 0\r\n" +
+				"m=audio 5000abc RTP/AVP 0 foo\r\n" +
+				"a=rtpmap:0 PCMU/8000\r\n" +
+				"m=video none RTP/AVP 96\r\n" +
+				"a=rtpmap:96 H264/90000\r\n",
+			anomalies: []string{
+				"invalid-port: m=audio 5000abc RTP/AVP 0 foo",
+				"malformed: m=audio 5000abc RTP/AVP 0 foo",
+				"invalid-port: m=video none RTP/AVP 96",
+			},
+			addr:     "10.0.0.1:5000",
+			contains: []string{"m=audio 5000 RTP/AVP 0\r\n", "m=video 0 RTP/AVP 96\r\n"},
+		},
+		{
+			name: "missing and misplaced lines",
+			offer: "v=0\r\n" +
+				"o=- 1 1 IN IP4 10.0.0.1\r\n" +
+				"c=IN IP4 10.0.0.1\r\n" +
+				"m=audio 5000 RTP/AVP 0\r\n" +
+				"a=rtpmap:0 PCMU/8000\r\n" +
+				"c=IN IP4 10.0.0.2\r\n",
+			anomalies: []string{
+				"order: lines reordered",
+				"missing: s=-",
+				"missing: t=0 0",
+			},
+			addr:     "10.0.0.2:5000",
+			contains: []string{"s=-\r\nc=IN IP4 10.0.0.1\r\nt=0 0\r\n", "m=audio 5000 RTP/AVP 0\r\nc=IN IP4 10.0.0.2\r\n"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, anomalies := sanitizeSDP([]byte(c.offer))
+			require.Equal(t, c.anomalies, anomalies)
+			for _, s := range c.contains {
+				require.Contains(t, string(out), s)
+			}
+			for _, s := range c.excludes {
+				require.NotContains(t, string(out), s)
+			}
+			offer, err := sdp.ParseOffer(out)
+			require.NoError(t, err)
+			require.Equal(t, netip.MustParseAddrPort(c.addr), offer.Addr)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sdpResolveTimeout limits the time of a single lookup of a host name from SDP.
+	sdpResolveTimeout = 2 * time.Second
+	// sdpResolveTTL is how long resolved host names are cached.
+	sdpResolveTTL = time.Minute
+)
+
+// sdpHosts resolves host names found in SDP connection lines. Some PBXes put FQDNs there instead of IP addresses.
+var sdpHosts = newSDPResolver(func(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+})
+
+type sdpResolved struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+type sdpResolver struct {
+	lookup func(ctx context.Context, host string) ([]netip.Addr, error)
+
+	mu    sync.Mutex
+	cache map[string]sdpResolved
+}
+
+func newSDPResolver(lookup func(ctx context.Context, host string) ([]netip.Addr, error)) *sdpResolver {
+	return &sdpResolver{
+		lookup: lookup,
+		cache:  make(map[string]sdpResolved),
+	}
+}
+
+// Resolve returns the address of the host. Addresses matching the signaling peer are preferred,
+// since the peer usually sends media from the same host.
+func (r *sdpResolver) Resolve(host string, peer netip.Addr) (netip.Addr, error) {
+	addrs, err := r.lookupHost(host)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if peer.IsValid() {
+		peer = peer.Unmap()
+		for _, a := range addrs {
+			if a == peer {
+				return a, nil
+			}
+		}
+		for _, a := range addrs {
+			if a.Is4() == peer.Is4() {
+				return a, nil
+			}
+		}
+	}
+	return addrs[0], nil
+}
+
+func (r *sdpResolver) lookupHost(host string) ([]netip.Addr, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[host]
+	r.mu.Unlock()
+	now := time.Now()
+	if ok && now.Before(cached.expires) {
+		return cached.addrs, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sdpResolveTimeout)
+	defer cancel()
+	addrs, err := r.lookup(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = errors.New("no addresses")
+	}
+	if err != nil {
+		if ok {
+			// Better to try the last known address than to fail the call.
+			return cached.addrs, nil
+		}
+		return nil, fmt.Errorf("cannot resolve sdp address %q: %w", host, err)
+	}
+	for i := range addrs {
+		addrs[i] = addrs[i].Unmap()
+	}
+	r.mu.Lock()
+	r.cache[host] = sdpResolved{addrs: addrs, expires: now.Add(sdpResolveTTL)}
+	r.mu.Unlock()
+	return addrs, nil
+}
+
+// Forget drops the cached addresses of the host, so that the next call resolves it again.
+func (r *sdpResolver) Forget(host string) {
+	if host == "" {
+		return
+	}
+	r.mu.Lock()
+	delete(r.cache, host)
+	r.mu.Unlock()
+}
+
+// resolveSDPHosts replaces host names in connection lines of the SDP with resolved IP addresses,
+// since the media stack only accepts IP addresses there. It returns the last resolved host name.
+func resolveSDPHosts(r *sdpResolver, data []byte, peer netip.Addr) ([]byte, string, error) {
+	if !bytes.Contains(data, []byte("c=IN ")) {
+		return data, "", nil
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	var (
+		host    string
+		changed bool
+	)
+	for i, line := range lines {
+		if !bytes.HasPrefix(line, []byte("c=IN ")) {
+			continue
+		}
+		body := strings.TrimRight(string(line), "\r\n")
+		fields := strings.Fields(body)
+		if len(fields) != 3 {
+			continue
+		}
+		addr, ttl, _ := strings.Cut(fields[2], "/")
+		if _, err := netip.ParseAddr(addr); err == nil || ttl != "" {
+			continue // IP address or multicast
+		}
+		ip, err := r.Resolve(addr, peer)
+		if err != nil {
+			return nil, "", err
+		}
+		typ := "IP4"
+		if ip.Is6() {
+			typ = "IP6"
+		}
+		lines[i] = []byte("c=IN " + typ + " " + ip.String() + string(line[len(body):]))
+		host, changed = addr, true
+	}
+	if !changed {
+		return data, "", nil
+	}
+	return bytes.Join(lines, nil), host, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/media-sdk/sdp"
+)
+
+func TestResolveSDPHosts(t *testing.T) {
+	var (
+		lookups int
+		fail    bool
+	)
+	r := newSDPResolver(func(ctx context.Context, host string) ([]netip.Addr, error) {
+		lookups++
+		if fail || host != "pbx.example.com" {
+			return nil, errors.New("not found")
+		}
+		return []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")}, nil
+	})
+	const offer = "v=0\r\n" +
+		"o=- 1 1 IN IP4 pbx.example.com\r\n" +
+		"s=-\r\n" +
+		"c=IN IP4 pbx.example.com\r\n" +
+		"t=0 0\r\n" +
+		"m=audio 5000 RTP/AVP 0\r\n" +
+		"a=rtpmap:0 PCMU/8000\r\n"
+
+	out, host, err := resolveSDPHosts(r, []byte(offer), netip.MustParseAddr("10.0.0.2"))
+	require.NoError(t, err)
+	require.Equal(t, "pbx.example.com", host)
+	require.Contains(t, string(out), "c=IN IP4 10.0.0.2\r\n")
+	require.Contains(t, string(out), "o=- 1 1 IN IP4 pbx.example.com\r\n")
+	parsed, err := sdp.ParseOffer(out)
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParseAddrPort("10.0.0.2:5000"), parsed.Addr)
+
+	// Cached.
+	out, _, err = resolveSDPHosts(r, []byte(offer), netip.Addr{})
+	require.NoError(t, err)
+	require.Contains(t, string(out), "c=IN IP4 10.0.0.1\r\n")
+	require.Equal(t, 1, lookups)
+
+	// Last known address is used if the host cannot be resolved again.
+	r.cache["pbx.example.com"] = sdpResolved{addrs: r.cache["pbx.example.com"].addrs}
+	fail = true
+	_, _, err = resolveSDPHosts(r, []byte(offer), netip.Addr{})
+	require.NoError(t, err)
+	require.Equal(t, 2, lookups)
+
+	r.Forget("pbx.example.com")
+	_, _, err = resolveSDPHosts(r, []byte(offer), netip.Addr{})
+	require.Error(t, err)
+
+	// IP addresses are kept as-is, without lookups.
+	ipOffer := []byte("v=0\r\nc=IN IP4 10.1.1.1\r\nm=audio 5000 RTP/AVP 0\r\n")
+	out, host, err = resolveSDPHosts(r, ipOffer, netip.Addr{})
+	require.NoError(t, err)
+	require.Empty(t, host)
+	require.Equal(t, ipOffer, out)
+	require.Equal(t, 3, lookups)
+}
@@ -38,7 +38,9 @@ import (
 	"github.com/livekit/sipgo/sip"
 
 	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/media/tonegen"
 	"github.com/livekit/sip/pkg/stats"
+	"github.com/livekit/sip/pkg/stt"
 )
 
 const (
@@ -55,6 +57,12 @@ type CallInfo struct {
 	Call    *rpc.SIPCall
 	Pin     string
 	NoPin   bool
+	// CallerName is the caller ID name resolved with CNAM lookup, if enabled.
+	CallerName string
+	// ResourcePriority is parsed from Resource-Priority headers of the INVITE.
+	ResourcePriority []ResourcePriority
+	// BodyParts are parts of a multipart INVITE body other than SDP, like ISUP or PIDF-LO.
+	BodyParts []BodyPart
 }
 
 type AuthResult int
@@ -81,6 +89,7 @@ const (
 	DispatchRequestPin
 	DispatchNoRuleReject // reject the call with an error
 	DispatchNoRuleDrop   // silently drop the call
+	DispatchEcho         // answer the call with an echo test
 )
 
 type CallDispatch struct {
@@ -97,6 +106,53 @@ type CallDispatch struct {
 	RingingTimeout      time.Duration
 	MaxCallDuration     time.Duration
 	MediaEncryption     livekit.SIPMediaEncryption
+	// Provisional overrides provisional response mode configured for the dispatch rule.
+	Provisional config.ProvisionalMode
+}
+
+// ProvisionalMode returns provisional response mode for the call, falling back to the dispatch rule config.
+func (d *CallDispatch) ProvisionalMode(conf *config.Config) config.ProvisionalMode {
+	if d.Provisional != config.ProvisionalDefault {
+		return d.Provisional
+	}
+	return conf.DispatchProvisional(d.DispatchRuleID)
+}
+
+// EchoTest returns echo test settings if the call must be answered with an echo test instead of joining the room.
+// Accepted calls are turned into echo tests if the dispatch rule is configured for it.
+func (d *CallDispatch) EchoTest(conf *config.Config) (config.EchoConfig, bool) {
+	switch d.Result {
+	case DispatchAccept, DispatchEcho:
+	default:
+		return config.EchoConfig{}, false
+	}
+	if e := conf.DispatchEcho(d.DispatchRuleID); e != nil {
+		return *e, true
+	}
+	return config.EchoConfig{}, d.Result == DispatchEcho
+}
+
+// checkResourcePriority rejects a matched call if the dispatch rule requires a Resource-Priority the call doesn't have.
+func (d *CallDispatch) checkResourcePriority(conf *config.Config, rp []ResourcePriority) bool {
+	switch d.Result {
+	case DispatchAccept, DispatchEcho, DispatchRequestPin:
+	default:
+		return true
+	}
+	filters := conf.DispatchResourcePriority(d.DispatchRuleID)
+	if len(filters) == 0 || matchResourcePriority(rp, filters) {
+		return true
+	}
+	d.Result = DispatchNoRuleReject
+	return false
+}
+
+// Announcement returns announcement settings if the call must be answered with an announcement instead of joining the room.
+func (d *CallDispatch) Announcement(conf *config.Config) *config.AnnounceConfig {
+	if d.Result != DispatchAccept {
+		return nil
+	}
+	return conf.DispatchAnnounce(d.DispatchRuleID)
 }
 
 type CallIdentifier struct {
@@ -114,6 +170,9 @@ type Handler interface {
 	DeregisterTransferSIPParticipantTopic(sipCallId string)
 
 	OnSessionEnd(ctx context.Context, callIdentifier *CallIdentifier, callInfo *livekit.SIPCallInfo, reason string)
+	// OnDTMFMenu is called when the remote enters one of the configured in-call DTMF menu sequences.
+	// Built-in actions, like transfer or hangup, are taken after the handler returns.
+	OnDTMFMenu(ctx context.Context, callIdentifier *CallIdentifier, item config.DTMFMenuItem)
 }
 
 type Server struct {
@@ -124,23 +183,47 @@ type Server struct {
 	getIOClient  GetIOInfoClient
 	sipListeners []io.Closer
 	sipUnhandled RequestHandler
+	middlewares  []middleware
 
 	imu               sync.Mutex
 	inProgressInvites []*inProgressInvite
+	invites           *inviteDedup
 
 	closing     core.Fuse
 	cmu         sync.RWMutex
 	activeCalls map[RemoteTag]*inboundCall
 	byLocal     map[LocalTag]*inboundCall
 
-	handler Handler
-	conf    *config.Config
-	sconf   *ServiceConfig
-
+	handler  Handler
+	conf     *config.Config
+	sconf    *ServiceConfig
+	limits   requestLimits
+	ports    *PortAllocator // optional
+	quotas   *ProjectQuotas // optional
+	capacity *TrunkCapacity // optional
+	queues   *CallQueues    // calls waiting for an agent
+	vq       *vqReporter    // optional
+	cnam     *cnamResolver  // optional
+	stt      stt.Engine     // optional
+	cli      *Client        // optional; outbound calls can be picked up
+	dial     *dialer        // optional; places callbacks of queued calls
+	loops    *loopDetector  // optional; pending outbound invites
+	leaks    *leakWatchdog  // optional
+	mem      *memBudget     // optional
+	owners   *dialogOwners  // optional; dialogs shared by instances
+	failover *failover      // optional; calls taken over by the peer instance
+	proxied  *proxyConns    // optional; connections from load balancers
+
+	tts TTS // optional
 	res mediaRes
+
+	tones *tonegen.Profile // call progress tones, ETSI if nil
+
+	artifacts *Artifacts // recordings and other files written for calls
 }
 
 type inProgressInvite struct {
+	method    sip.RequestMethod
 	from      string
 	challenge digest.Challenge
 }
@@ -157,6 +240,18 @@ func NewServer(region string, conf *config.Config, log logger.Logger, mon *stats
 		getIOClient: getIOClient,
 		activeCalls: make(map[RemoteTag]*inboundCall),
 		byLocal:     make(map[LocalTag]*inboundCall),
+		queues:      NewCallQueues(log, mon),
+		invites:     newInviteDedup(),
+	}
+	if conf != nil {
+		s.cnam = newCNAMResolver(log, conf.CNAM)
+		if t := newTTSSynthesizer(conf.TTS); t != nil {
+			s.tts = t
+		}
+		s.limits = newRequestLimits(conf.SIPLimits)
+		if conf.ProxyProtocol != nil {
+			s.proxied = newProxyConns()
+		}
 	}
 	s.initMediaRes()
 	return s
@@ -167,7 +262,25 @@ func (s *Server) SetHandler(handler Handler) {
 }
 
 func (s *Server) ContactURI(tr Transport) URI {
-	return getContactURI(s.conf, s.sconf.SignalingIP, tr)
+	return getContactURI(s.conf, s.sconf.signalingIP(), tr)
+}
+
+// contactFor returns the Contact for a dialog created by the request. Requests received through a load balancer
+// sending PROXY protocol headers advertise the address the client connected to.
+func (s *Server) contactFor(req *sip.Request, tr Transport) URI {
+	u := s.ContactURI(tr)
+	if addr, ok := s.proxied.Local(req.Source()); ok {
+		u.Addr = addr
+	}
+	return u
+}
+
+// listenProxy reads PROXY protocol headers of connections accepted by the listener, if enabled.
+func (s *Server) listenProxy(ln net.Listener) net.Listener {
+	if s.proxied == nil {
+		return ln
+	}
+	return newProxyListener(s.log, ln, s.conf.ProxyProtocol, s.proxied)
 }
 
 func (s *Server) startUDP(addr netip.AddrPort) error {
@@ -186,7 +299,7 @@ func (s *Server) startUDP(addr netip.AddrPort) error {
 	)
 
 	go func() {
-		if err := s.sipSrv.ServeUDP(lis); err != nil {
+		if err := s.sipSrv.ServeUDP(unfoldPacketConn{lis}); err != nil {
 			panic(fmt.Errorf("SIP listen UDP error: %w", err))
 		}
 	}()
@@ -194,13 +307,14 @@ func (s *Server) startUDP(addr netip.AddrPort) error {
 }
 
 func (s *Server) startTCP(addr netip.AddrPort) error {
-	lis, err := net.ListenTCP("tcp", &net.TCPAddr{
+	tlis, err := net.ListenTCP("tcp", &net.TCPAddr{
 		IP:   addr.Addr().AsSlice(),
 		Port: int(addr.Port()),
 	})
 	if err != nil {
 		return fmt.Errorf("cannot listen on the TCP signaling port %d: %w", s.conf.SIPPortListen, err)
 	}
+	lis := s.listenProxy(tlis)
 	s.sipListeners = append(s.sipListeners, lis)
 	s.log.Infow("sip signaling listening on",
 		"local", s.sconf.SignalingIPLocal, "external", s.sconf.SignalingIP,
@@ -224,7 +338,7 @@ func (s *Server) startTLS(addr netip.AddrPort, conf *tls.Config) error {
 	if err != nil {
 		return fmt.Errorf("cannot listen on the TLS signaling port %d: %w", s.conf.SIPPortListen, err)
 	}
-	lis := tls.NewListener(tlis, conf)
+	lis := tls.NewListener(s.listenProxy(tlis), conf)
 	s.sipListeners = append(s.sipListeners, lis)
 	s.log.Infow("sip signaling listening on",
 		"local", s.sconf.SignalingIPLocal, "external", s.sconf.SignalingIP,
@@ -245,6 +359,9 @@ type RequestHandler func(req *sip.Request, tx sip.ServerTransaction) bool
 func (s *Server) Start(agent *sipgo.UserAgent, sc *ServiceConfig, unhandled RequestHandler) error {
 	s.sconf = sc
 	s.log.Infow("server starting", "local", s.sconf.SignalingIPLocal, "external", s.sconf.SignalingIP)
+	if err := s.loadAnnouncements(); err != nil {
+		return err
+	}
 
 	if agent == nil {
 		ua, err := sipgo.NewUA(
@@ -265,15 +382,14 @@ func (s *Server) Start(agent *sipgo.UserAgent, sc *ServiceConfig, unhandled Requ
 		return err
 	}
 
-	s.sipSrv.OnOptions(s.onOptions)
-	s.sipSrv.OnInvite(s.onInvite)
-	s.sipSrv.OnBye(s.onBye)
-	s.sipSrv.OnNotify(s.onNotify)
-	s.sipSrv.OnNoRoute(s.OnNoRoute)
+	s.sipSrv.OnOptions(s.limitRequests(s.intercept(s.onOptions)))
+	s.sipSrv.OnInvite(s.limitRequests(s.forwardDialogs(s.intercept(s.onInvite))))
+	s.sipSrv.OnBye(s.limitRequests(s.forwardDialogs(s.intercept(s.onBye))))
+	s.sipSrv.OnNotify(s.limitRequests(s.forwardDialogs(s.intercept(s.onNotify))))
+	s.sipSrv.OnNoRoute(s.limitRequests(s.forwardDialogs(s.intercept(s.OnNoRoute))))
 	s.sipUnhandled = unhandled
 
-	// Ignore ACKs
-	s.sipSrv.OnAck(func(log *slog.Logger, req *sip.Request, tx sip.ServerTransaction) {})
+	s.sipSrv.OnAck(s.limitRequests(s.forwardDialogs(s.intercept(s.onAck))))
 	listenIP := s.conf.ListenIP
 	if listenIP == "" {
 		listenIP = "0.0.0.0"
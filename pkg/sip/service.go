@@ -20,6 +20,7 @@ import (
 	"log/slog"
 	"net"
 	"net/netip"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -32,9 +33,12 @@ import (
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/psrpc"
 	"github.com/livekit/sipgo"
+	"github.com/livekit/sipgo/sip"
 
 	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/media/tonegen"
 	"github.com/livekit/sip/pkg/stats"
+	"github.com/livekit/sip/pkg/stt"
 	"github.com/livekit/sip/version"
 )
 
@@ -42,6 +46,8 @@ type ServiceConfig struct {
 	SignalingIP      netip.Addr
 	SignalingIPLocal netip.Addr
 	MediaIP          netip.Addr
+
+	public *publicIPs // optional; replaces SignalingIP and MediaIP discovered at startup
 }
 
 type Service struct {
@@ -51,6 +57,13 @@ type Service struct {
 	mon   *stats.Monitor
 	cli   *Client
 	srv   *Server
+	ports *PortAllocator
+	tests []*testCaller
+	stt   stt.Engine // optional
+	dial  *dialer
+	leaks *leakWatchdog // optional
+	pubIP *publicIPs    // optional
+	pmap  *portMappings // optional
 
 	mu               sync.Mutex
 	pendingTransfers map[transferKey]chan struct{}
@@ -67,6 +80,12 @@ func NewService(region string, conf *config.Config, mon *stats.Monitor, log logg
 	if log == nil {
 		log = logger.GetLogger()
 	}
+	redact, err := newRedactor(conf.Redaction)
+	if err != nil {
+		return nil, err
+	}
+	log = redact.Logger(log)
+	getIOClient = redact.IOInfoClient(getIOClient)
 	s := &Service{
 		conf:             conf,
 		log:              log,
@@ -75,40 +94,137 @@ func NewService(region string, conf *config.Config, mon *stats.Monitor, log logg
 		srv:              NewServer(region, conf, log, mon, getIOClient),
 		pendingTransfers: make(map[transferKey]chan struct{}),
 	}
-	var err error
 	s.sconf, err = GetServiceConfig(s.conf)
 	if err != nil {
 		return nil, err
 	}
+	s.pubIP = newPublicIPs(log, mon, conf, s.sconf)
+	s.pmap = newPortMappings(log, conf, s.sconf)
+	if err = ValidateSRTPSuites(conf.SRTP.Suites); err != nil {
+		return nil, err
+	}
+	if err = validateCallerIDFormats(conf); err != nil {
+		return nil, err
+	}
+	for id, t := range conf.Trunks {
+		if t != nil && t.SRTP != nil {
+			if err = ValidateSRTPSuites(t.SRTP.Suites); err != nil {
+				return nil, fmt.Errorf("trunk %q: %w", id, err)
+			}
+		}
+	}
+	s.ports, err = NewPortAllocator(mon, conf.RTPPort, conf.RTPPortReserved)
+	if err != nil {
+		return nil, err
+	}
+	s.cli.ports = s.ports
+	s.srv.ports = s.ports
+	quotas := NewProjectQuotas(log, mon, conf)
+	s.cli.quotas = quotas
+	s.srv.quotas = quotas
+	capacity := NewTrunkCapacity(log, mon, conf)
+	s.cli.capacity = capacity
+	s.srv.capacity = capacity
+	vq := newVQReporter(conf.VQReport, s.cli)
+	s.cli.vq = vq
+	s.srv.vq = vq
+	loops := newLoopDetector()
+	s.cli.loops = loops
+	s.srv.loops = loops
+	s.leaks = newLeakWatchdog(log, mon, conf.LeakWatchdog)
+	s.cli.leaks = s.leaks
+	s.srv.leaks = s.leaks
+	mem := newMemBudget(mon, conf.MemoryBudget)
+	s.cli.mem = mem
+	s.srv.mem = mem
+	owners := newDialogOwners(log, conf.DialogRegistry, s.sconf, conf.SIPPort)
+	s.cli.owners = owners
+	s.srv.owners = owners
+	fo := newFailover(log, mon, conf.Failover, s.sconf, conf.SIPPort)
+	s.cli.failover = fo
+	s.srv.failover = fo
+	artifacts := &Artifacts{}
+	if conf.ArtifactEncryption != nil {
+		if artifacts.km, err = newStaticKeyManager(conf.ArtifactEncryption); err != nil {
+			return nil, err
+		}
+	}
+	s.cli.artifacts = artifacts
+	s.srv.artifacts = artifacts
+	s.cli.dnc, err = newDNCPolicy(conf.DNC)
+	if err != nil {
+		return nil, err
+	}
+	tones, err := tonegen.NewProfile(conf.Tones.Country, conf.Tones.Custom)
+	if err != nil {
+		return nil, err
+	}
+	s.cli.tones = tones
+	s.srv.tones = tones
+	s.stt, err = stt.New(conf.STT, log)
+	if err != nil {
+		return nil, err
+	}
+	s.cli.stt = s.stt
+	s.srv.stt = s.stt
+	s.cli.tts = s.srv.tts
+	s.srv.cli = s.cli
+	s.dial = newDialer(log, conf, s.cli.CreateSIPParticipant)
+	s.srv.dial = s.dial
+
+	if s.conf.SIPHostname, err = s.expandHostname(s.conf.SIPHostname); err != nil {
+		return nil, err
+	}
+	if h := s.conf.SIPHostnames; h != nil {
+		for _, name := range []*string{&h.UDP, &h.TCP, &h.TLS} {
+			if *name, err = s.expandHostname(*name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if s.conf.SIPRingingInterval < 1*time.Second || s.conf.SIPRingingInterval > 60*time.Second {
+		s.conf.SIPRingingInterval = 1 * time.Second
+		log.Infow("ringing interval", "seconds", s.conf.SIPRingingInterval)
+	}
+	return s, nil
+}
 
+// hostnameLookupTimeout limits DNS checks of advertised hostnames at startup.
+const hostnameLookupTimeout = 5 * time.Second
+
+// expandHostname replaces the ${IP} placeholder in an advertised hostname and checks that the name resolves
+// to the signaling IP. Names which don't resolve are only logged, since DNS may not be updated yet.
+func (s *Service) expandHostname(hostname string) (string, error) {
+	if hostname == "" {
+		return "", nil
+	}
 	const placeholder = "${IP}"
-	if strings.Contains(s.conf.SIPHostname, placeholder) {
-		s.conf.SIPHostname = strings.ReplaceAll(
-			s.conf.SIPHostname,
+	if strings.Contains(hostname, placeholder) {
+		hostname = strings.ReplaceAll(
+			hostname,
 			placeholder,
 			strings.NewReplacer(
 				".", "-", // IPv4
 				"[", "", "]", "", ":", "-", // IPv6
 			).Replace(s.sconf.SignalingIP.String()),
 		)
-		addr, err := net.ResolveTCPAddr("tcp4", s.conf.SIPHostname)
-		if err != nil {
-			log.Errorw("cannot resolve node hostname", err, "hostname", s.conf.SIPHostname)
-		} else {
-			log.Infow("resolved node hostname", "hostname", s.conf.SIPHostname, "ip", addr.IP.String())
-		}
 	}
-	if strings.ContainsAny(s.conf.SIPHostname, "$%{}[]:/| ") {
-		return nil, fmt.Errorf("invalid hostname: %q", s.conf.SIPHostname)
+	if strings.ContainsAny(hostname, "$%{}[]:/| ") {
+		return "", fmt.Errorf("invalid hostname: %q", hostname)
 	}
-	if s.conf.SIPHostname != "" {
-		log.Infow("using hostname", "hostname", s.conf.SIPHostname)
+	ctx, cancel := context.WithTimeout(context.Background(), hostnameLookupTimeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", hostname)
+	switch {
+	case err != nil:
+		s.log.Errorw("cannot resolve node hostname", err, "hostname", hostname)
+	case !slices.ContainsFunc(ips, func(ip netip.Addr) bool { return ip.Unmap() == s.sconf.SignalingIP.Unmap() }):
+		s.log.Warnw("node hostname doesn't resolve to the signaling IP", nil, "hostname", hostname, "ips", ips, "signalingIP", s.sconf.SignalingIP)
+	default:
+		s.log.Infow("resolved node hostname", "hostname", hostname, "ips", ips)
 	}
-	if s.conf.SIPRingingInterval < 1*time.Second || s.conf.SIPRingingInterval > 60*time.Second {
-		s.conf.SIPRingingInterval = 1 * time.Second
-		log.Infow("ringing interval", "seconds", s.conf.SIPRingingInterval)
-	}
-	return s, nil
+	s.log.Infow("using hostname", "hostname", hostname)
+	return hostname, nil
 }
 
 type ActiveCalls struct {
@@ -164,9 +280,47 @@ func (s *Service) ActiveCalls() ActiveCalls {
 	return st
 }
 
+// ProbeTrunk sends OPTIONS to the trunk and reports its reachability and capabilities.
+func (s *Service) ProbeTrunk(ctx context.Context, req *TrunkProbeRequest) (*TrunkProbeResult, error) {
+	return s.cli.ProbeTrunk(ctx, req)
+}
+
+// DryRunInbound reports which trunk and dispatch rule would match given inbound calls.
+func (s *Service) DryRunInbound(ctx context.Context, req *InboundDryRunRequest) (*InboundDryRunResponse, error) {
+	return s.srv.DryRunInbound(ctx, req)
+}
+
+// SubmitDialJobs schedules a batch of outbound calls. See DialJob.
+func (s *Service) SubmitDialJobs(ctx context.Context, req *DialJobsRequest) (*DialJobsResponse, error) {
+	return s.dial.Submit(req)
+}
+
+// DialJobStatus returns the status of a dial job.
+func (s *Service) DialJobStatus(ctx context.Context, req *DialJobRequest) (*DialJobStatus, error) {
+	return s.dial.Status(req.ID)
+}
+
+// CancelDialJob stops a dial job. Calls which are already answered are not affected.
+func (s *Service) CancelDialJob(ctx context.Context, req *DialJobRequest) (*DialJobStatus, error) {
+	return s.dial.Cancel(req.ID)
+}
+
 func (s *Service) Stop() {
+	for _, t := range s.tests {
+		t.Stop()
+	}
+	s.dial.Stop()
+	s.leaks.Stop()
+	s.pubIP.Stop()
+	s.pmap.Stop()
+	s.srv.owners.Stop()
+	s.srv.failover.Stop()
 	s.cli.Stop()
 	s.srv.Stop()
+	s.ports.Close()
+	if s.stt != nil {
+		_ = s.stt.Close()
+	}
 	s.mon.Stop()
 }
 
@@ -175,6 +329,39 @@ func (s *Service) SetHandler(handler Handler) {
 	s.cli.SetHandler(handler)
 }
 
+// SetTTS replaces the speech synthesizer configured with the tts config. It must be called before the service starts.
+func (s *Service) SetTTS(t TTS) {
+	s.srv.tts = t
+	s.cli.tts = t
+}
+
+// SetKeyManager replaces the keys for artifact encryption configured with the artifact_encryption config,
+// for example with a KMS. It must be called before the service starts.
+func (s *Service) SetKeyManager(km KeyManager) {
+	s.srv.artifacts.km = km
+}
+
+// SetDialogStore sets the store shared by instances for the dialog_registry config, see NewRedisDialogStore.
+// It must be called before the service starts.
+func (s *Service) SetDialogStore(store DialogStore) {
+	if s.srv.owners != nil {
+		s.srv.owners.store = store
+	}
+}
+
+// SetCallStateStore sets the store shared with the peer instance for the failover config, see NewRedisCallStateStore.
+// It must be called before the service starts.
+func (s *Service) SetCallStateStore(store CallStateStore) {
+	if s.srv.failover != nil {
+		s.srv.failover.store = store
+	}
+}
+
+// Use registers a middleware for inbound SIP requests. See Server.Use.
+func (s *Service) Use(mw Middleware, methods ...sip.RequestMethod) {
+	s.srv.Use(mw, methods...)
+}
+
 func (s *Service) Start() error {
 	s.log.Debugw("starting sip service", "version", version.Version)
 	for name, enabled := range s.conf.Codecs {
@@ -185,10 +372,20 @@ func (s *Service) Start() error {
 		}
 	}
 	msdk.CodecsSetEnabled(s.conf.Codecs)
+	if s.srv.owners != nil && s.srv.owners.store == nil {
+		return fmt.Errorf("dialog registry requires a dialog store")
+	}
+	if s.srv.failover != nil && s.srv.failover.store == nil {
+		return fmt.Errorf("failover requires a call state store")
+	}
 
 	if err := s.mon.Start(s.conf); err != nil {
 		return err
 	}
+	s.ports.ReportUsage()
+	s.ports.Prewarm(s.conf.RTPPortPrewarm)
+	s.pubIP.Start()
+	s.pmap.Start()
 	// The UA must be shared between the client and the server.
 	// Otherwise, the client will have to listen on a random port, which must then be forwarded.
 	//
@@ -204,11 +401,19 @@ func (s *Service) Start() error {
 	if err := s.cli.Start(ua, s.sconf); err != nil {
 		return err
 	}
+	s.srv.owners.Start(s.cli.sipCli)
+	s.srv.failover.Start(s.cli.recoverCall)
 	// Server is responsible for answering all transactions. However, the client may also receive some (e.g. BYE).
 	// Thus, all unhandled transactions will be checked by the client.
 	if err := s.srv.Start(ua, s.sconf, s.cli.OnRequest); err != nil {
 		return err
 	}
+	s.tests = newTestCallers(s.conf, s.sconf, s.log, s.mon)
+	for _, t := range s.tests {
+		t.Start()
+	}
+	s.dial.Start()
+	s.leaks.Start()
 	s.log.Debugw("sip service ready")
 	return nil
 }
@@ -58,6 +58,8 @@ type TestHandler struct {
 	GetAuthCredentialsFunc func(ctx context.Context, call *rpc.SIPCall) (AuthInfo, error)
 	DispatchCallFunc       func(ctx context.Context, info *CallInfo) CallDispatch
 	OnSessionEndFunc       func(ctx context.Context, callIdentifier *CallIdentifier, callInfo *livekit.SIPCallInfo, reason string)
+	OnDTMFMenuFunc         func(ctx context.Context, callIdentifier *CallIdentifier, item config.DTMFMenuItem)
+	GetMediaProcessorFunc  func(features []livekit.SIPFeature) msdk.PCM16Processor
 }
 
 func (h TestHandler) GetAuthCredentials(ctx context.Context, call *rpc.SIPCall) (AuthInfo, error) {
@@ -68,7 +70,10 @@ func (h TestHandler) DispatchCall(ctx context.Context, info *CallInfo) CallDispa
 	return h.DispatchCallFunc(ctx, info)
 }
 
-func (h TestHandler) GetMediaProcessor(_ []livekit.SIPFeature) msdk.PCM16Processor {
+func (h TestHandler) GetMediaProcessor(features []livekit.SIPFeature) msdk.PCM16Processor {
+	if h.GetMediaProcessorFunc != nil {
+		return h.GetMediaProcessorFunc(features)
+	}
 	return nil
 }
 
@@ -87,7 +92,21 @@ func (h TestHandler) OnSessionEnd(ctx context.Context, callIdentifier *CallIdent
 	}
 }
 
+func (h TestHandler) OnDTMFMenu(ctx context.Context, callIdentifier *CallIdentifier, item config.DTMFMenuItem) {
+	if h.OnDTMFMenuFunc != nil {
+		h.OnDTMFMenuFunc(ctx, callIdentifier, item)
+	}
+}
+
 func testInvite(t *testing.T, h Handler, hidden bool, from, to string, test func(tx sip.ClientTransaction)) {
+	testInviteSDP(t, h, hidden, from, to, true, test)
+}
+
+func testInviteSDP(t *testing.T, h Handler, hidden bool, from, to string, withSDP bool, test func(tx sip.ClientTransaction)) {
+	testInviteConf(t, h, &config.Config{HideInboundPort: hidden}, from, to, withSDP, test)
+}
+
+func testInviteConf(t *testing.T, h Handler, conf *config.Config, from, to string, withSDP bool, test func(tx sip.ClientTransaction)) {
 	sipPort := rand.Intn(testPortSIPMax-testPortSIPMin) + testPortSIPMin
 	localIP, err := config.GetLocalIP()
 	require.NoError(t, err)
@@ -98,12 +117,10 @@ func testInvite(t *testing.T, h Handler, hidden bool, from, to string, test func
 	require.NoError(t, err)
 
 	log := logger.NewTestLogger(t)
-	s, err := NewService("", &config.Config{
-		HideInboundPort: hidden,
-		SIPPort:         sipPort,
-		SIPPortListen:   sipPort,
-		RTPPort:         rtcconfig.PortRange{Start: testPortRTPMin, End: testPortRTPMax},
-	}, mon, log, func(projectID string) rpc.IOInfoClient { return nil })
+	conf.SIPPort = sipPort
+	conf.SIPPortListen = sipPort
+	conf.RTPPort = rtcconfig.PortRange{Start: testPortRTPMin, End: testPortRTPMax}
+	s, err := NewService("", conf, mon, log, func(projectID string) rpc.IOInfoClient { return nil })
 	require.NoError(t, err)
 	require.NotNil(t, s)
 	t.Cleanup(s.Stop)
@@ -129,8 +146,10 @@ func testInvite(t *testing.T, h Handler, hidden bool, from, to string, test func
 	inviteRecipent := sip.Uri{User: to, Host: sipServerAddress}
 	inviteRequest := sip.NewRequest(sip.INVITE, inviteRecipent)
 	inviteRequest.SetDestination(sipServerAddress)
-	inviteRequest.SetBody(offerData)
-	inviteRequest.AppendHeader(sip.NewHeader("Content-Type", "application/sdp"))
+	if withSDP {
+		inviteRequest.SetBody(offerData)
+		inviteRequest.AppendHeader(sip.NewHeader("Content-Type", "application/sdp"))
+	}
 
 	tx, err := sipClient.TransactionRequest(inviteRequest)
 	require.NoError(t, err)
@@ -266,3 +285,208 @@ func TestService_OnSessionEnd(t *testing.T) {
 	require.Equal(t, expectedSipCallID, receivedCallInfo.ParticipantAttributes[AttrSIPCallIDFull], "CallInfo.ParticipantAttributes[sip.callIDFull] should match")
 	require.Equal(t, expectedReason, receivedReason, "Reason should match")
 }
+
+func TestService_EarlyMedia(t *testing.T) {
+	h := &TestHandler{
+		GetAuthCredentialsFunc: func(ctx context.Context, call *rpc.SIPCall) (AuthInfo, error) {
+			return AuthInfo{Result: AuthAccept}, nil
+		},
+		DispatchCallFunc: func(ctx context.Context, info *CallInfo) CallDispatch {
+			return CallDispatch{
+				Result:      DispatchAccept,
+				Provisional: config.ProvisionalEarlyMedia,
+				Room: RoomConfig{
+					RoomName: "test-room",
+					Participant: ParticipantConfig{
+						Identity: "test-participant",
+					},
+				},
+			}
+		},
+	}
+	testInvite(t, h, false, "foo", "bar", func(tx sip.ClientTransaction) {
+		var codes []sip.StatusCode
+		for {
+			res := getResponseOrFail(t, tx)
+			codes = append(codes, res.StatusCode)
+			if res.StatusCode == sip.StatusSessionInProgress {
+				require.NotEmpty(t, res.Body(), "183 must include SDP")
+				require.NotNil(t, res.Contact())
+				return
+			}
+			require.Less(t, int(res.StatusCode), 200, "unexpected final response: %v", codes)
+		}
+	})
+}
+
+func TestService_DelayedOffer(t *testing.T) {
+	h := &TestHandler{
+		GetAuthCredentialsFunc: func(ctx context.Context, call *rpc.SIPCall) (AuthInfo, error) {
+			return AuthInfo{Result: AuthAccept}, nil
+		},
+		DispatchCallFunc: func(ctx context.Context, info *CallInfo) CallDispatch {
+			return CallDispatch{
+				Result:      DispatchAccept,
+				Provisional: config.ProvisionalAnswer,
+				Room: RoomConfig{
+					RoomName: "test-room",
+					Participant: ParticipantConfig{
+						Identity: "test-participant",
+					},
+				},
+			}
+		},
+	}
+	testInviteSDP(t, h, false, "foo", "bar", false, func(tx sip.ClientTransaction) {
+		for {
+			res := getResponseOrFail(t, tx)
+			if res.StatusCode < 200 {
+				continue
+			}
+			require.Equal(t, sip.StatusOK, res.StatusCode)
+			offer, err := sdp.ParseOffer(res.Body())
+			require.NoError(t, err, "200 OK must include SDP offer")
+			require.NotNil(t, sdp.GetAudio(&offer.SDP))
+			return
+		}
+	})
+}
+
+// cancelInvite sends CANCEL once the call is ringing and expects 487 for the INVITE.
+func cancelInvite(t *testing.T, tx sip.ClientTransaction, ready <-chan struct{}) {
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("call did not reach the expected state")
+	}
+	require.NoError(t, tx.Cancel())
+	for {
+		res := getResponseOrFail(t, tx)
+		if res.StatusCode < 200 {
+			continue
+		}
+		require.Equal(t, sip.StatusRequestTerminated, res.StatusCode)
+		return
+	}
+}
+
+func expectSessionEnd(t *testing.T, ended <-chan string, reason string) {
+	select {
+	case got := <-ended:
+		require.Equal(t, reason, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnSessionEnd was not called")
+	}
+}
+
+func TestService_CancelDuringDispatch(t *testing.T) {
+	dispatching := make(chan struct{})
+	ended := make(chan string, 1)
+	status := make(chan string, 1)
+	h := &TestHandler{
+		GetAuthCredentialsFunc: func(ctx context.Context, call *rpc.SIPCall) (AuthInfo, error) {
+			return AuthInfo{Result: AuthAccept}, nil
+		},
+		DispatchCallFunc: func(ctx context.Context, info *CallInfo) CallDispatch {
+			close(dispatching)
+			<-ctx.Done()
+			return CallDispatch{Result: DispatchNoRuleReject}
+		},
+		GetMediaProcessorFunc: func(features []livekit.SIPFeature) msdk.PCM16Processor {
+			t.Error("media must not be started for cancelled calls")
+			return nil
+		},
+		OnSessionEndFunc: func(ctx context.Context, callIdentifier *CallIdentifier, callInfo *livekit.SIPCallInfo, reason string) {
+			status <- callInfo.GetCallStatusCode().GetStatus()
+			ended <- reason
+		},
+	}
+	testInvite(t, h, false, "foo", "bar", func(tx sip.ClientTransaction) {
+		cancelInvite(t, tx, dispatching)
+		expectSessionEnd(t, ended, "cancelled")
+		require.Equal(t, callerCancelled, <-status)
+	})
+}
+
+func TestService_CancelDuringMediaSetup(t *testing.T) {
+	starting := make(chan struct{})
+	resume := make(chan struct{})
+	ended := make(chan string, 1)
+	status := make(chan string, 1)
+	h := &TestHandler{
+		GetAuthCredentialsFunc: func(ctx context.Context, call *rpc.SIPCall) (AuthInfo, error) {
+			return AuthInfo{Result: AuthAccept}, nil
+		},
+		DispatchCallFunc: func(ctx context.Context, info *CallInfo) CallDispatch {
+			return CallDispatch{
+				Result: DispatchAccept,
+				Room: RoomConfig{
+					RoomName:    "test-room",
+					Participant: ParticipantConfig{Identity: "test-participant"},
+				},
+			}
+		},
+		GetMediaProcessorFunc: func(features []livekit.SIPFeature) msdk.PCM16Processor {
+			close(starting)
+			<-resume
+			return nil
+		},
+		OnSessionEndFunc: func(ctx context.Context, callIdentifier *CallIdentifier, callInfo *livekit.SIPCallInfo, reason string) {
+			status <- callInfo.GetCallStatusCode().GetStatus()
+			ended <- reason
+		},
+	}
+	testInvite(t, h, false, "foo", "bar", func(tx sip.ClientTransaction) {
+		cancelInvite(t, tx, starting)
+		close(resume)
+		expectSessionEnd(t, ended, "cancelled")
+		require.Equal(t, callerCancelled, <-status)
+	})
+}
+
+func TestService_UnmatchedCall(t *testing.T) {
+	h := &TestHandler{
+		GetAuthCredentialsFunc: func(ctx context.Context, call *rpc.SIPCall) (AuthInfo, error) {
+			return AuthInfo{Result: AuthAccept, TrunkID: "ST_1"}, nil
+		},
+		DispatchCallFunc: func(ctx context.Context, info *CallInfo) CallDispatch {
+			return CallDispatch{Result: DispatchNoRuleReject}
+		},
+	}
+	finalResponse := func(t *testing.T, tx sip.ClientTransaction) *sip.Response {
+		for {
+			res := getResponseOrFail(t, tx)
+			if res.StatusCode >= 200 {
+				return res
+			}
+		}
+	}
+	t.Run("default", func(t *testing.T) {
+		testInviteConf(t, h, &config.Config{}, "foo", "bar", true, func(tx sip.ClientTransaction) {
+			res := finalResponse(t, tx)
+			require.Equal(t, sip.StatusNotFound, res.StatusCode)
+		})
+	})
+	t.Run("status", func(t *testing.T) {
+		conf := &config.Config{UnmatchedCall: config.UnmatchedCallConfig{StatusCode: 480, Reason: "Nobody Home"}}
+		testInviteConf(t, h, conf, "foo", "bar", true, func(tx sip.ClientTransaction) {
+			res := finalResponse(t, tx)
+			require.Equal(t, sip.StatusTemporarilyUnavailable, res.StatusCode)
+			require.Equal(t, "Nobody Home", res.Reason)
+		})
+	})
+	t.Run("redirect", func(t *testing.T) {
+		conf := &config.Config{Trunks: map[string]*config.TrunkConfig{
+			"ST_1": {UnmatchedCall: &config.UnmatchedCallConfig{
+				Action:     config.UnmatchedRedirect,
+				RedirectTo: "sip:voicemail@example.com",
+			}},
+		}}
+		testInviteConf(t, h, conf, "foo", "bar", true, func(tx sip.ClientTransaction) {
+			res := finalResponse(t, tx)
+			require.Equal(t, sip.StatusMovedTemporarily, res.StatusCode)
+			require.NotNil(t, res.GetHeader("Contact"))
+			require.Equal(t, "<sip:voicemail@example.com>", res.GetHeader("Contact").Value())
+		})
+	})
+}
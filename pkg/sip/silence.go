@@ -0,0 +1,149 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostbyte73/core"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/media-sdk/rtp"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/media/vad"
+)
+
+// silenceFillDelay is how long the room may not send audio before silence is sent instead.
+// It tolerates jitter of the room mixer without sending extra frames.
+const silenceFillDelay = 2 * rtp.DefFrameDur
+
+func silenceFillActive(c config.SilenceConfig) bool {
+	return c.Enabled || c.GateThreshold != 0
+}
+
+// silenceFiller keeps audio flowing to SIP. It sends silence or comfort noise when the room doesn't send audio,
+// and optionally replaces quiet room audio with it.
+type silenceFiller struct {
+	w      msdk.PCM16Writer
+	active func() bool // reports if audio can be sent to SIP, for example it's not on hold
+	frame  int         // samples in a frame
+	noise  float64     // max amplitude of comfort noise, zero for digital silence
+	gate   uint8       // audio level in -dBov; quieter frames are replaced, zero disables the gate
+	filled *atomic.Uint64
+	gated  *atomic.Uint64
+
+	mu     sync.Mutex
+	last   time.Time // last frame from the room
+	rnd    *rand.Rand
+	closed core.Fuse
+}
+
+func newSilenceFiller(w msdk.PCM16Writer, conf config.SilenceConfig, active func() bool, st *PortStats) *silenceFiller {
+	if st == nil {
+		st = &PortStats{}
+	}
+	f := &silenceFiller{
+		w:      w,
+		active: active,
+		frame:  int(time.Duration(w.SampleRate()) * rtp.DefFrameDur / time.Second),
+		filled: &st.SilenceFrames,
+		gated:  &st.GatedFrames,
+		last:   time.Now(),
+		rnd:    rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+	if conf.ComfortNoise {
+		level := conf.NoiseLevel
+		if level == 0 {
+			level = config.DefaultComfortNoiseLevel
+		}
+		// Uniform noise in [-A, A] has RMS of A/sqrt(3).
+		f.noise = math.MaxInt16 * math.Pow(10, level/20) * math.Sqrt(3)
+	}
+	if conf.GateThreshold != 0 {
+		f.gate = uint8(math.Round(-conf.GateThreshold))
+	}
+	if conf.Enabled {
+		go f.run()
+	}
+	return f
+}
+
+func (f *silenceFiller) run() {
+	ticker := time.NewTicker(rtp.DefFrameDur)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.closed.Watch():
+			return
+		case now := <-ticker.C:
+			f.tick(now)
+		}
+	}
+}
+
+// tick sends a silence frame if the room didn't send audio recently.
+func (f *silenceFiller) tick(now time.Time) {
+	if f.active != nil && !f.active() {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed.IsBroken() || now.Sub(f.last) < silenceFillDelay {
+		return
+	}
+	f.filled.Add(1)
+	_ = f.w.WriteSample(f.fillLocked(f.frame))
+}
+
+// fillLocked returns a frame of silence or comfort noise.
+func (f *silenceFiller) fillLocked(n int) msdk.PCM16Sample {
+	out := make(msdk.PCM16Sample, n)
+	if f.noise == 0 {
+		return out
+	}
+	for i := range out {
+		out[i] = int16((f.rnd.Float64()*2 - 1) * f.noise)
+	}
+	return out
+}
+
+func (f *silenceFiller) String() string {
+	return "SilenceFill -> " + f.w.String()
+}
+
+func (f *silenceFiller) SampleRate() int {
+	return f.w.SampleRate()
+}
+
+func (f *silenceFiller) WriteSample(sample msdk.PCM16Sample) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.last = time.Now()
+	if f.gate != 0 && vad.AudioLevel(sample) >= f.gate {
+		f.gated.Add(1)
+		sample = f.fillLocked(len(sample))
+	}
+	return f.w.WriteSample(sample)
+}
+
+func (f *silenceFiller) Close() error {
+	f.closed.Break()
+	return f.w.Close()
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	msdk "github.com/livekit/media-sdk"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/media/vad"
+)
+
+func TestSilenceFillerTick(t *testing.T) {
+	out := &stageTestWriter{rate: 8000}
+	sending := true
+	var st PortStats
+	// Not enabled, so that only manual ticks send frames.
+	f := newSilenceFiller(out, config.SilenceConfig{ComfortNoise: true}, func() bool { return sending }, &st)
+	require.Equal(t, "SilenceFill -> Test", f.String())
+
+	now := time.Now()
+	f.tick(now)
+	require.Empty(t, out.samples, "room audio may still arrive")
+
+	f.tick(now.Add(silenceFillDelay))
+	f.tick(now.Add(silenceFillDelay + 20*time.Millisecond))
+	require.Len(t, out.samples, 2)
+	require.Len(t, out.samples[0], 160)
+	require.InDelta(t, -config.DefaultComfortNoiseLevel, float64(vad.AudioLevel(out.samples[0])), 2)
+	require.EqualValues(t, 2, st.SilenceFrames.Load())
+
+	// Room audio stops the filler.
+	frame := msdk.PCM16Sample{1000, -1000}
+	require.NoError(t, f.WriteSample(frame))
+	f.tick(time.Now())
+	require.Len(t, out.samples, 3)
+	require.Equal(t, frame, out.samples[2])
+
+	// Nothing is sent on hold.
+	sending = false
+	f.tick(time.Now().Add(time.Second))
+	require.Len(t, out.samples, 3)
+
+	require.NoError(t, f.Close())
+	require.Equal(t, 1, out.closed)
+}
+
+func TestSilenceFillerGate(t *testing.T) {
+	out := &stageTestWriter{rate: 8000}
+	var st PortStats
+	f := newSilenceFiller(out, config.SilenceConfig{GateThreshold: -50}, nil, &st)
+	defer f.Close()
+
+	quiet, loud := make(msdk.PCM16Sample, 160), make(msdk.PCM16Sample, 160)
+	for i := range quiet {
+		quiet[i] = 30 // about -61 dBov
+		loud[i] = 5000
+	}
+	require.NoError(t, f.WriteSample(quiet))
+	require.NoError(t, f.WriteSample(loud))
+	require.Equal(t, make(msdk.PCM16Sample, 160), out.samples[0])
+	require.Equal(t, loud, out.samples[1])
+	require.NotZero(t, quiet[0], "input must not change")
+	require.EqualValues(t, 1, st.GatedFrames.Load())
+	require.Zero(t, st.SilenceFrames.Load())
+}
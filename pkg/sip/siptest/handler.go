@@ -0,0 +1,215 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package siptest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/sip"
+)
+
+// SessionEnd describes a call which ended on the service.
+type SessionEnd struct {
+	ID     *sip.CallIdentifier
+	Info   *livekit.SIPCallInfo
+	Reason string
+}
+
+// DTMFMenuCall is a DTMF menu sequence entered on a call.
+type DTMFMenuCall struct {
+	ID   *sip.CallIdentifier
+	Item config.DTMFMenuItem
+}
+
+// Handler is a fake sip.Handler with scripted results, which records calls made by the service.
+// The zero value accepts all calls and answers them with an echo test. Fields must not be changed
+// while the service is running.
+type Handler struct {
+	// Auth results are returned in order for consecutive calls, and the last one is repeated.
+	// Calls are accepted if it's empty.
+	Auth []sip.AuthInfo
+	// AuthFunc overrides Auth.
+	AuthFunc func(ctx context.Context, call *rpc.SIPCall) (sip.AuthInfo, error)
+	// Dispatch results are returned in order for consecutive calls, and the last one is repeated.
+	// Calls are answered with an echo test if it's empty.
+	Dispatch []sip.CallDispatch
+	// DispatchFunc overrides Dispatch.
+	DispatchFunc func(ctx context.Context, info *sip.CallInfo) sip.CallDispatch
+	// AuthDelay and DispatchDelay delay results, for testing slow handlers. Dispatch returns early
+	// with a rejection if the call is cancelled while waiting.
+	AuthDelay     time.Duration
+	DispatchDelay time.Duration
+	// MediaProcessor processes audio of calls.
+	MediaProcessor func(features []livekit.SIPFeature) msdk.PCM16Processor
+	// OnDTMFMenuFunc is called when the remote enters one of the configured DTMF menu sequences.
+	OnDTMFMenuFunc func(ctx context.Context, id *sip.CallIdentifier, item config.DTMFMenuItem)
+
+	mu         sync.Mutex
+	authCalls  []*rpc.SIPCall
+	dispatched []*sip.CallInfo
+	topics     []string
+	menus      []DTMFMenuCall
+	ended      chan SessionEnd
+}
+
+var _ sip.Handler = (*Handler)(nil)
+
+// scripted returns the n-th result, repeating the last one.
+func scripted[T any](list []T, n int) T {
+	return list[min(n, len(list)-1)]
+}
+
+// wait sleeps for the delay, unless the context is done earlier.
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (h *Handler) GetAuthCredentials(ctx context.Context, call *rpc.SIPCall) (sip.AuthInfo, error) {
+	h.mu.Lock()
+	n := len(h.authCalls)
+	h.authCalls = append(h.authCalls, call)
+	h.mu.Unlock()
+	if err := wait(ctx, h.AuthDelay); err != nil {
+		return sip.AuthInfo{}, err
+	}
+	if h.AuthFunc != nil {
+		return h.AuthFunc(ctx, call)
+	}
+	if len(h.Auth) == 0 {
+		return sip.AuthInfo{Result: sip.AuthAccept}, nil
+	}
+	return scripted(h.Auth, n), nil
+}
+
+func (h *Handler) DispatchCall(ctx context.Context, info *sip.CallInfo) sip.CallDispatch {
+	h.mu.Lock()
+	n := len(h.dispatched)
+	h.dispatched = append(h.dispatched, info)
+	h.mu.Unlock()
+	if err := wait(ctx, h.DispatchDelay); err != nil {
+		return sip.CallDispatch{Result: sip.DispatchNoRuleReject}
+	}
+	if h.DispatchFunc != nil {
+		return h.DispatchFunc(ctx, info)
+	}
+	if len(h.Dispatch) == 0 {
+		return sip.CallDispatch{Result: sip.DispatchEcho}
+	}
+	return scripted(h.Dispatch, n)
+}
+
+func (h *Handler) GetMediaProcessor(features []livekit.SIPFeature) msdk.PCM16Processor {
+	if h.MediaProcessor != nil {
+		return h.MediaProcessor(features)
+	}
+	return nil
+}
+
+func (h *Handler) RegisterTransferSIPParticipantTopic(sipCallId string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.topics = append(h.topics, sipCallId)
+	return nil
+}
+
+func (h *Handler) DeregisterTransferSIPParticipantTopic(sipCallId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i := slices.Index(h.topics, sipCallId); i >= 0 {
+		h.topics = slices.Delete(h.topics, i, i+1)
+	}
+}
+
+func (h *Handler) endedChan() chan SessionEnd {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ended == nil {
+		h.ended = make(chan SessionEnd, 64)
+	}
+	return h.ended
+}
+
+func (h *Handler) OnSessionEnd(ctx context.Context, id *sip.CallIdentifier, info *livekit.SIPCallInfo, reason string) {
+	select {
+	case h.endedChan() <- SessionEnd{ID: id, Info: info, Reason: reason}:
+	default:
+		// Nobody waits for session ends.
+	}
+}
+
+func (h *Handler) OnDTMFMenu(ctx context.Context, id *sip.CallIdentifier, item config.DTMFMenuItem) {
+	h.mu.Lock()
+	h.menus = append(h.menus, DTMFMenuCall{ID: id, Item: item})
+	h.mu.Unlock()
+	if h.OnDTMFMenuFunc != nil {
+		h.OnDTMFMenuFunc(ctx, id, item)
+	}
+}
+
+// AuthCalls returns calls passed to GetAuthCredentials.
+func (h *Handler) AuthCalls() []*rpc.SIPCall {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.authCalls)
+}
+
+// DispatchCalls returns calls passed to DispatchCall.
+func (h *Handler) DispatchCalls() []*sip.CallInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.dispatched)
+}
+
+// TransferTopics returns SIP call IDs with registered transfer topics.
+func (h *Handler) TransferTopics() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.topics)
+}
+
+// DTMFMenuCalls returns DTMF menu sequences entered on calls.
+func (h *Handler) DTMFMenuCalls() []DTMFMenuCall {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.menus)
+}
+
+// WaitSessionEnd waits for the next call to end on the service.
+func (h *Handler) WaitSessionEnd(ctx context.Context) (SessionEnd, error) {
+	select {
+	case <-ctx.Done():
+		return SessionEnd{}, ctx.Err()
+	case e := <-h.endedChan():
+		return e, nil
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package siptest
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/sip"
+)
+
+// IOClient is a fake rpc.IOInfoClient which records call state updates sent by the service.
+// SIP trunk requests use optional functions, other requests are not implemented.
+type IOClient struct {
+	GetSIPTrunkAuthenticationFunc func(ctx context.Context, req *rpc.GetSIPTrunkAuthenticationRequest) (*rpc.GetSIPTrunkAuthenticationResponse, error)
+	EvaluateSIPDispatchRulesFunc  func(ctx context.Context, req *rpc.EvaluateSIPDispatchRulesRequest) (*rpc.EvaluateSIPDispatchRulesResponse, error)
+
+	mu      sync.Mutex
+	updates []*rpc.UpdateSIPCallStateRequest
+}
+
+var _ rpc.IOInfoClient = (*IOClient)(nil)
+
+// GetIOInfoClient returns the client for all projects, to be passed to sip.NewService.
+func (c *IOClient) GetIOInfoClient() sip.GetIOInfoClient {
+	return func(projectID string) rpc.IOInfoClient {
+		return c
+	}
+}
+
+// CallStates returns call state updates, in the order they were sent.
+func (c *IOClient) CallStates() []*rpc.UpdateSIPCallStateRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]*rpc.UpdateSIPCallStateRequest, len(c.updates))
+	for i, u := range c.updates {
+		out[i] = proto.Clone(u).(*rpc.UpdateSIPCallStateRequest)
+	}
+	return out
+}
+
+// LastCallState returns the last reported state of the call, or nil if it was never reported.
+func (c *IOClient) LastCallState(callID string) *livekit.SIPCallInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := len(c.updates) - 1; i >= 0; i-- {
+		if info := c.updates[i].CallInfo; info != nil && info.CallId == callID {
+			return proto.Clone(info).(*livekit.SIPCallInfo)
+		}
+	}
+	return nil
+}
+
+func (c *IOClient) UpdateSIPCallState(ctx context.Context, req *rpc.UpdateSIPCallStateRequest, opts ...psrpc.RequestOption) (*emptypb.Empty, error) {
+	// Requests may be changed by the caller afterwards.
+	req = proto.Clone(req).(*rpc.UpdateSIPCallStateRequest)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updates = append(c.updates, req)
+	return &emptypb.Empty{}, nil
+}
+
+func (c *IOClient) GetSIPTrunkAuthentication(ctx context.Context, req *rpc.GetSIPTrunkAuthenticationRequest, opts ...psrpc.RequestOption) (*rpc.GetSIPTrunkAuthenticationResponse, error) {
+	if c.GetSIPTrunkAuthenticationFunc == nil {
+		return nil, psrpc.NewErrorf(psrpc.Unimplemented, "not implemented")
+	}
+	return c.GetSIPTrunkAuthenticationFunc(ctx, req)
+}
+
+func (c *IOClient) EvaluateSIPDispatchRules(ctx context.Context, req *rpc.EvaluateSIPDispatchRulesRequest, opts ...psrpc.RequestOption) (*rpc.EvaluateSIPDispatchRulesResponse, error) {
+	if c.EvaluateSIPDispatchRulesFunc == nil {
+		return nil, psrpc.NewErrorf(psrpc.Unimplemented, "not implemented")
+	}
+	return c.EvaluateSIPDispatchRulesFunc(ctx, req)
+}
+
+func (c *IOClient) CreateEgress(ctx context.Context, req *livekit.EgressInfo, opts ...psrpc.RequestOption) (*emptypb.Empty, error) {
+	return nil, psrpc.NewErrorf(psrpc.Unimplemented, "not implemented")
+}
+
+func (c *IOClient) UpdateEgress(ctx context.Context, req *livekit.EgressInfo, opts ...psrpc.RequestOption) (*emptypb.Empty, error) {
+	return nil, psrpc.NewErrorf(psrpc.Unimplemented, "not implemented")
+}
+
+func (c *IOClient) GetEgress(ctx context.Context, req *rpc.GetEgressRequest, opts ...psrpc.RequestOption) (*livekit.EgressInfo, error) {
+	return nil, psrpc.NewErrorf(psrpc.Unimplemented, "not implemented")
+}
+
+func (c *IOClient) ListEgress(ctx context.Context, req *livekit.ListEgressRequest, opts ...psrpc.RequestOption) (*livekit.ListEgressResponse, error) {
+	return nil, psrpc.NewErrorf(psrpc.Unimplemented, "not implemented")
+}
+
+func (c *IOClient) UpdateMetrics(ctx context.Context, req *rpc.UpdateMetricsRequest, opts ...psrpc.RequestOption) (*emptypb.Empty, error) {
+	return nil, psrpc.NewErrorf(psrpc.Unimplemented, "not implemented")
+}
+
+func (c *IOClient) CreateIngress(ctx context.Context, req *livekit.IngressInfo, opts ...psrpc.RequestOption) (*emptypb.Empty, error) {
+	return nil, psrpc.NewErrorf(psrpc.Unimplemented, "not implemented")
+}
+
+func (c *IOClient) GetIngressInfo(ctx context.Context, req *rpc.GetIngressInfoRequest, opts ...psrpc.RequestOption) (*rpc.GetIngressInfoResponse, error) {
+	return nil, psrpc.NewErrorf(psrpc.Unimplemented, "not implemented")
+}
+
+func (c *IOClient) UpdateIngressState(ctx context.Context, req *rpc.UpdateIngressStateRequest, opts ...psrpc.RequestOption) (*emptypb.Empty, error) {
+	return nil, psrpc.NewErrorf(psrpc.Unimplemented, "not implemented")
+}
+
+func (c *IOClient) Close() {}
@@ -0,0 +1,200 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package siptest runs the SIP service in-process on the loopback interface, and drives it with a simulated phone,
+// so that handlers can be integration-tested without real phones or a SIP provider.
+//
+// Calls are answered with an echo test by default, which doesn't require a LiveKit server. Audio and DTMF sent by
+// the phone are played back, which allows asserting audio frames of the whole INVITE, answer, DTMF and BYE flow.
+package siptest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/media-sdk/dtmf"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/sip"
+	"github.com/livekit/sip/pkg/siptest"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+// loopback is the address the service and phones use.
+var loopback = netip.MustParseAddr("127.0.0.1")
+
+// ServerConfig configures the service under test.
+type ServerConfig struct {
+	// Config of the service. SIP addresses and ports are overridden to run on the loopback interface.
+	Config *config.Config
+	// Log of the service. Defaults to the test log.
+	Log logger.Logger
+	// Handler of the service. Defaults to a Handler which answers all calls with an echo test.
+	Handler sip.Handler
+	// IO receives call state updates. Defaults to a new IOClient.
+	IO *IOClient
+}
+
+// Server is the SIP service under test.
+type Server struct {
+	Service *sip.Service
+	// Addr is the SIP address of the service.
+	Addr    netip.AddrPort
+	Handler sip.Handler
+	IO      *IOClient
+}
+
+// NewServer starts the SIP service with the given config. It's stopped when the test ends.
+func NewServer(t testing.TB, conf ServerConfig) *Server {
+	t.Helper()
+	if conf.Config == nil {
+		conf.Config = &config.Config{}
+	}
+	if conf.Log == nil {
+		conf.Log = logger.NewTestLogger(t)
+	}
+	if conf.Handler == nil {
+		conf.Handler = &Handler{}
+	}
+	if conf.IO == nil {
+		conf.IO = &IOClient{}
+	}
+	port := freePort(t)
+	c := conf.Config
+	c.SIPPort = port
+	c.SIPPortListen = port
+	c.ListenIP = loopback.String()
+	c.NAT1To1IP = loopback.String()
+	c.UseExternalIP = false
+	c.MediaUseExternalIP = false
+	c.MediaNAT1To1IP = ""
+	if c.MaxCpuUtilization == 0 {
+		c.MaxCpuUtilization = 0.9
+	}
+
+	mon, err := stats.NewMonitor(c)
+	require.NoError(t, err)
+	svc, err := sip.NewService("", c, mon, conf.Log, conf.IO.GetIOInfoClient())
+	require.NoError(t, err)
+	svc.SetHandler(conf.Handler)
+	require.NoError(t, svc.Start())
+	t.Cleanup(svc.Stop)
+	return &Server{
+		Service: svc,
+		Addr:    netip.AddrPortFrom(loopback, uint16(port)),
+		Handler: conf.Handler,
+		IO:      conf.IO,
+	}
+}
+
+// freePort finds a port which is free for both UDP and TCP.
+func freePort(t testing.TB) int {
+	for range 10 {
+		udp, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(netip.AddrPortFrom(loopback, 0)))
+		require.NoError(t, err)
+		port := udp.LocalAddr().(*net.UDPAddr).Port
+		tcp, err := net.Listen("tcp", netip.AddrPortFrom(loopback, uint16(port)).String())
+		_ = udp.Close()
+		if err != nil {
+			continue
+		}
+		_ = tcp.Close()
+		return port
+	}
+	t.Fatal("cannot find a free SIP port")
+	return 0
+}
+
+// CallOptions configures the phone placing a call.
+type CallOptions struct {
+	// From is the number of the phone. Defaults to 1000.
+	From string
+	// AuthUser and AuthPass answer digest challenges of the service.
+	AuthUser string
+	AuthPass string
+	// Codec of audio, for example PCMU or PCMA. Defaults to PCMU.
+	Codec string
+	// Headers are added to the INVITE.
+	Headers map[string]string
+}
+
+// Call is an answered call from a simulated phone. The phone sends and receives audio and DTMF over RTP.
+type Call struct {
+	*siptest.Client
+	dtmf chan byte
+}
+
+// Dial calls the number on the service and waits for the call to be answered. The call is hung up when the test ends.
+func (s *Server) Dial(t testing.TB, number string, opts CallOptions) (*Call, error) {
+	t.Helper()
+	c := &Call{dtmf: make(chan byte, 64)}
+	cli, err := siptest.NewClient("", siptest.ClientConfig{
+		IP:             loopback,
+		Number:         opts.From,
+		AuthUser:       opts.AuthUser,
+		AuthPass:       opts.AuthPass,
+		Codec:          opts.Codec,
+		Log:            slog.New(logger.ToSlogHandler(logger.NewTestLogger(t))),
+		OnMediaTimeout: func() {},
+		OnDTMF: func(ev dtmf.Event) {
+			if ev.Digit == 0 {
+				return
+			}
+			select {
+			case c.dtmf <- ev.Digit:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.Client = cli
+	if err = cli.Dial(s.Addr.String(), s.Addr.String(), number, opts.Headers); err != nil {
+		cli.Close()
+		return nil, err
+	}
+	t.Cleanup(cli.Close)
+	return c, nil
+}
+
+// Hangup sends BYE and releases the phone.
+func (c *Call) Hangup() {
+	c.Close()
+}
+
+// WaitDTMF waits for the phone to receive the given DTMF digits, in order. Other digits are skipped.
+func (c *Call) WaitDTMF(ctx context.Context, digits string) error {
+	var got strings.Builder
+	for i := 0; i < len(digits); {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("received DTMF %q, expected %q: %w", got.String(), digits, ctx.Err())
+		case d := <-c.dtmf:
+			got.WriteByte(d)
+			if d == digits[i] {
+				i++
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package siptest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/sip/pkg/sip"
+)
+
+func TestEchoCall(t *testing.T) {
+	h := &Handler{}
+	srv := NewServer(t, ServerConfig{Handler: h})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	call, err := srv.Dial(t, "+15550100", CallOptions{From: "+15550199"})
+	require.NoError(t, err)
+	dispatched := h.DispatchCalls()
+	require.Len(t, dispatched, 1)
+	require.Equal(t, "+15550100", dispatched[0].Call.To.User)
+	require.Equal(t, "+15550199", dispatched[0].Call.From.User)
+	require.Len(t, h.AuthCalls(), 1)
+
+	// Audio sent by the phone is played back.
+	sctx, stop := context.WithCancel(ctx)
+	go func() {
+		_ = call.SendSignal(sctx, -1, 5)
+	}()
+	require.NoError(t, call.WaitSignals(ctx, []int{5}, nil))
+	stop()
+
+	require.NoError(t, call.SendDTMF("12#"))
+	require.NoError(t, call.WaitDTMF(ctx, "12#"))
+
+	call.Hangup()
+	end, err := h.WaitSessionEnd(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, end.ID.CallID)
+
+	require.Eventually(t, func() bool {
+		info := srv.IO.LastCallState(end.ID.CallID)
+		return info != nil && info.CallStatus == livekit.SIPCallStatus_SCS_DISCONNECTED
+	}, 5*time.Second, 50*time.Millisecond)
+	require.NotEmpty(t, srv.IO.CallStates())
+}
+
+func TestScriptedHandler(t *testing.T) {
+	h := &Handler{
+		Auth: []sip.AuthInfo{
+			{Result: sip.AuthPassword, Username: "user", Password: "secret"},
+		},
+		Dispatch: []sip.CallDispatch{
+			{Result: sip.DispatchNoRuleReject},
+			{Result: sip.DispatchEcho},
+		},
+	}
+	srv := NewServer(t, ServerConfig{Handler: h})
+
+	_, err := srv.Dial(t, "+15550100", CallOptions{})
+	require.Error(t, err)
+	require.Empty(t, h.DispatchCalls())
+
+	_, err = srv.Dial(t, "+15550100", CallOptions{AuthUser: "user", AuthPass: "secret"})
+	require.Error(t, err)
+
+	call, err := srv.Dial(t, "+15550100", CallOptions{AuthUser: "user", AuthPass: "secret"})
+	require.NoError(t, err)
+	call.Hangup()
+	require.Len(t, h.DispatchCalls(), 2)
+}
+
+func TestSlowDispatch(t *testing.T) {
+	const delay = 2 * time.Second
+	h := &Handler{DispatchDelay: delay}
+	srv := NewServer(t, ServerConfig{Handler: h})
+
+	start := time.Now()
+	call, err := srv.Dial(t, "+15550100", CallOptions{})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), delay)
+	call.Hangup()
+}
@@ -0,0 +1,257 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/media-sdk/rtp"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/sip/pkg/media/vad"
+	"github.com/livekit/sip/res"
+)
+
+const (
+	// maxSpeakQueue limits the number of texts waiting to be spoken to a call.
+	maxSpeakQueue = 16
+	// maxSpeakText limits the length of a single text.
+	maxSpeakText = 4096
+)
+
+// SpeakRequest synthesizes a text and plays it to the SIP side of an active call.
+type SpeakRequest struct {
+	CallID string       `json:"call_id"`
+	Text   string       `json:"text"`
+	Voice  VoiceOptions `json:"voice"`
+	// Interrupt stops the current text and drops queued ones before this text is queued.
+	// An empty text with Interrupt only stops the speech.
+	Interrupt bool `json:"interrupt"`
+	// BargeIn stops this text and drops queued ones when the caller sends DTMF or starts speaking.
+	BargeIn bool `json:"barge_in"`
+}
+
+// SpeakResponse reports the position of the text in the queue.
+type SpeakResponse struct {
+	// Ahead is the number of texts spoken before this one, including the one currently playing.
+	Ahead int `json:"ahead"`
+}
+
+type speakItem struct {
+	text    string
+	voice   VoiceOptions
+	bargeIn bool
+}
+
+// callSpeaker plays synthesized texts to the SIP side of the call, one after another.
+type callSpeaker struct {
+	log    logger.Logger
+	tts    TTS
+	play   func(ctx context.Context, frames []msdk.PCM16Sample)
+	detect func(d *vad.Detector) func() // optional, runs the detector on audio from SIP
+
+	mu      sync.Mutex
+	queue   []speakItem
+	running bool
+	cur     context.Context    // of the current text
+	cancel  context.CancelFunc // stops the current text
+	bargeIn bool               // the current text can be interrupted by the caller
+	closed  bool
+}
+
+func newCallSpeaker(log logger.Logger, tts TTS, play func(ctx context.Context, frames []msdk.PCM16Sample), detect func(d *vad.Detector) func()) *callSpeaker {
+	return &callSpeaker{log: log, tts: tts, play: play, detect: detect}
+}
+
+// Speak queues the text and returns the number of texts ahead of it.
+func (s *callSpeaker) Speak(it speakItem, interrupt bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, psrpc.NewErrorf(psrpc.NotFound, "call is closed")
+	}
+	if interrupt {
+		s.stopLocked()
+	}
+	if it.text == "" {
+		return 0, nil
+	}
+	if s.tts == nil {
+		return 0, psrpc.NewErrorf(psrpc.FailedPrecondition, "tts is not configured")
+	}
+	if len(s.queue) >= maxSpeakQueue {
+		return 0, psrpc.NewErrorf(psrpc.ResourceExhausted, "too many texts queued for the call")
+	}
+	ahead := len(s.queue)
+	if s.cur != nil {
+		ahead++
+	}
+	s.queue = append(s.queue, it)
+	if !s.running {
+		s.running = true
+		go s.run()
+	}
+	return ahead, nil
+}
+
+func (s *callSpeaker) run() {
+	for {
+		s.mu.Lock()
+		s.cur, s.cancel, s.bargeIn = nil, nil, false
+		if s.closed || len(s.queue) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		it := s.queue[0]
+		s.queue = slices.Delete(s.queue, 0, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		s.cur, s.cancel, s.bargeIn = ctx, cancel, it.bargeIn
+		s.mu.Unlock()
+
+		s.speak(ctx, it)
+		cancel()
+	}
+}
+
+func (s *callSpeaker) speak(ctx context.Context, it speakItem) {
+	frames, err := s.tts.Synthesize(ctx, it.text, it.voice)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			s.log.Warnw("Cannot synthesize speech", err)
+		}
+		return
+	}
+	if it.bargeIn && s.detect != nil {
+		d := vad.NewDetector(vad.Config{})
+		stop := s.detect(d)
+		defer stop()
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-d.Detected():
+				s.interrupt(ctx, "speech")
+			}
+		}()
+	}
+	s.play(ctx, frames)
+}
+
+// BargeIn stops the current text and drops queued ones, if the current text allows it.
+func (s *callSpeaker) BargeIn(reason string) {
+	if s == nil {
+		return
+	}
+	s.interrupt(nil, reason)
+}
+
+// interrupt stops the text with a given context, or the current one if ctx is nil.
+func (s *callSpeaker) interrupt(ctx context.Context, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cur == nil || !s.bargeIn || (ctx != nil && ctx != s.cur) {
+		return
+	}
+	s.log.Infow("Speech interrupted by the caller", "reason", reason, "dropped", len(s.queue))
+	s.stopLocked()
+}
+
+func (s *callSpeaker) stopLocked() {
+	s.queue = nil
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.cur, s.cancel, s.bargeIn = nil, nil, false
+}
+
+// Close stops the speech. Texts cannot be queued after the call ends.
+func (s *callSpeaker) Close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.stopLocked()
+}
+
+// playToRoom mixes audio frames with res.SampleRate into the room audio sent to SIP.
+func playToRoom(ctx context.Context, r *Room, frames []msdk.PCM16Sample) {
+	t := r.NewTrack()
+	if t == nil {
+		return // closed
+	}
+	defer t.Close()
+
+	sampleRate := res.SampleRate
+	if t.SampleRate() != sampleRate {
+		frames = slices.Clone(frames)
+		for i := range frames {
+			frames[i] = msdk.Resample(nil, t.SampleRate(), frames[i], sampleRate)
+		}
+	}
+	_ = msdk.PlayAudio[msdk.PCM16Sample](ctx, t, rtp.DefFrameDur, frames)
+}
+
+// speakerOf finds the speaker of an active inbound call.
+func (s *Server) speakerOf(id LocalTag) *callSpeaker {
+	s.cmu.RLock()
+	c := s.byLocal[id]
+	s.cmu.RUnlock()
+	if c == nil || c.media == nil {
+		return nil
+	}
+	return c.speaker
+}
+
+// speakerOf finds the speaker of an active outbound call.
+func (c *Client) speakerOf(id LocalTag) *callSpeaker {
+	c.cmu.Lock()
+	call := c.activeCalls[id]
+	c.cmu.Unlock()
+	if call == nil || call.media == nil {
+		return nil
+	}
+	return call.speaker
+}
+
+// SpeakToCall synthesizes the text and plays it to the SIP participant of an active call.
+// Texts are queued and played one after another.
+func (s *Service) SpeakToCall(ctx context.Context, req *SpeakRequest) (*SpeakResponse, error) {
+	if req.Text == "" && !req.Interrupt {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "text is required")
+	}
+	if len(req.Text) > maxSpeakText {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "text is too long")
+	}
+	id := LocalTag(req.CallID)
+	sp := s.srv.speakerOf(id)
+	if sp == nil {
+		sp = s.cli.speakerOf(id)
+	}
+	if sp == nil {
+		return nil, psrpc.NewErrorf(psrpc.NotFound, "call %q not found", req.CallID)
+	}
+	ahead, err := sp.Speak(speakItem{text: req.Text, voice: req.Voice, bargeIn: req.BargeIn}, req.Interrupt)
+	if err != nil {
+		return nil, err
+	}
+	return &SpeakResponse{Ahead: ahead}, nil
+}
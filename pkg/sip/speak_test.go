@@ -0,0 +1,191 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/media/vad"
+)
+
+// textTTS returns the text as a single frame, one sample per byte.
+type textTTS struct{}
+
+func (textTTS) Synthesize(_ context.Context, text string, _ VoiceOptions) ([]msdk.PCM16Sample, error) {
+	frame := make(msdk.PCM16Sample, len(text))
+	for i := range text {
+		frame[i] = int16(text[i])
+	}
+	return []msdk.PCM16Sample{frame}, nil
+}
+
+type speakEvent struct {
+	text        string
+	interrupted bool
+}
+
+// testSpeakerPlayer plays each text until it's released or interrupted.
+type testSpeakerPlayer struct {
+	started chan string
+	release chan struct{}
+	done    chan speakEvent
+	det     chan *vad.Detector
+}
+
+func newTestSpeaker(t *testing.T) (*callSpeaker, *testSpeakerPlayer) {
+	p := &testSpeakerPlayer{
+		started: make(chan string, 10),
+		release: make(chan struct{}),
+		done:    make(chan speakEvent, 10),
+		det:     make(chan *vad.Detector, 10),
+	}
+	play := func(ctx context.Context, frames []msdk.PCM16Sample) {
+		var text []byte
+		for _, v := range frames[0] {
+			text = append(text, byte(v))
+		}
+		p.started <- string(text)
+		select {
+		case <-ctx.Done():
+			p.done <- speakEvent{text: string(text), interrupted: true}
+		case <-p.release:
+			p.done <- speakEvent{text: string(text)}
+		}
+	}
+	detect := func(d *vad.Detector) func() {
+		p.det <- d
+		return func() {}
+	}
+	s := newCallSpeaker(logger.GetLogger(), textTTS{}, play, detect)
+	t.Cleanup(s.Close)
+	return s, p
+}
+
+func (p *testSpeakerPlayer) expectStarted(t *testing.T, text string) {
+	t.Helper()
+	select {
+	case got := <-p.started:
+		require.Equal(t, text, got)
+	case <-time.After(time.Second):
+		t.Fatal("text not played")
+	}
+}
+
+func (p *testSpeakerPlayer) expectDone(t *testing.T, ev speakEvent) {
+	t.Helper()
+	select {
+	case got := <-p.done:
+		require.Equal(t, ev, got)
+	case <-time.After(time.Second):
+		t.Fatal("text not finished")
+	}
+}
+
+func TestCallSpeakerQueue(t *testing.T) {
+	s, p := newTestSpeaker(t)
+
+	ahead, err := s.Speak(speakItem{text: "one"}, false)
+	require.NoError(t, err)
+	require.Equal(t, 0, ahead)
+	p.expectStarted(t, "one")
+	ahead, err = s.Speak(speakItem{text: "two"}, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, ahead)
+
+	// Texts without barge-in are not interrupted by the caller.
+	s.BargeIn("dtmf")
+	p.release <- struct{}{}
+	p.expectDone(t, speakEvent{text: "one"})
+	p.expectStarted(t, "two")
+
+	// Interrupt drops the current text and the queue.
+	_, err = s.Speak(speakItem{text: "three"}, false)
+	require.NoError(t, err)
+	ahead, err = s.Speak(speakItem{text: "four"}, true)
+	require.NoError(t, err)
+	require.Equal(t, 0, ahead)
+	p.expectDone(t, speakEvent{text: "two", interrupted: true})
+	p.expectStarted(t, "four")
+
+	// Empty text only stops the speech.
+	_, err = s.Speak(speakItem{}, true)
+	require.NoError(t, err)
+	p.expectDone(t, speakEvent{text: "four", interrupted: true})
+
+	_, err = s.Speak(speakItem{text: "x"}, false)
+	require.NoError(t, err)
+	p.expectStarted(t, "x")
+	for range maxSpeakQueue {
+		_, err = s.Speak(speakItem{text: "x"}, false)
+		require.NoError(t, err)
+	}
+	_, err = s.Speak(speakItem{text: "x"}, false)
+	require.Error(t, err)
+
+	s.Close()
+	_, err = s.Speak(speakItem{text: "x"}, false)
+	require.Error(t, err)
+}
+
+func TestCallSpeakerBargeIn(t *testing.T) {
+	s, p := newTestSpeaker(t)
+
+	_, err := s.Speak(speakItem{text: "menu", bargeIn: true}, false)
+	require.NoError(t, err)
+	_, err = s.Speak(speakItem{text: "more"}, false)
+	require.NoError(t, err)
+	p.expectStarted(t, "menu")
+	<-p.det
+
+	s.BargeIn("dtmf")
+	p.expectDone(t, speakEvent{text: "menu", interrupted: true})
+
+	// Remote speech stops the text too.
+	_, err = s.Speak(speakItem{text: "again", bargeIn: true}, false)
+	require.NoError(t, err)
+	p.expectStarted(t, "again")
+	d := <-p.det
+	speech := make(msdk.PCM16Sample, 160)
+	for i := range speech {
+		speech[i] = 5000
+	}
+	for range vad.DefaultMinSpeech/(20*time.Millisecond) + 1 {
+		d.Process(speech, 8000)
+	}
+	p.expectDone(t, speakEvent{text: "again", interrupted: true})
+
+	select {
+	case text := <-p.started:
+		t.Fatalf("unexpected text played: %q", text)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestCallSpeakerNoTTS(t *testing.T) {
+	s := newCallSpeaker(logger.GetLogger(), nil, nil, nil)
+	_, err := s.Speak(speakItem{text: "hello"}, false)
+	require.Error(t, err)
+
+	var none *callSpeaker
+	none.BargeIn("dtmf")
+	none.Close()
+}
@@ -0,0 +1,617 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/media-sdk/rtp"
+	msrtp "github.com/livekit/media-sdk/srtp"
+	"github.com/livekit/protocol/logger"
+	psdp "github.com/pion/sdp/v3"
+	"github.com/pion/srtp/v3"
+)
+
+const (
+	// srtpMaxOverhead is the maximal size of SRTP auth tag and MKI added to RTP packets.
+	srtpMaxOverhead = 16 + 128
+	// srtpRekeyThreshold is the fraction of the key lifetime (in percents) after which we request a new key.
+	srtpRekeyThreshold = 90
+	// srtpPrevKeyPackets is the number of packets we still accept with the previous remote key after a rekey.
+	srtpPrevKeyPackets = 250
+	// srtpAnswerSendDelay is how long we keep sending with the old key after answering a rekey offer.
+	// It gives the remote some time to receive the answer and install our new key.
+	srtpAnswerSendDelay = 250 * time.Millisecond
+	// srtpReplayWindow is the size of the SRTP replay protection window, in packets.
+	srtpReplayWindow = 128
+	// srtpFailureWindow is the maximal interval between auth failures counted towards srtpConn.maxFailures.
+	// Occasional failures during a long call restart the count, so only sustained failures trigger the policy.
+	srtpFailureWindow = 5 * time.Second
+)
+
+// SRTPKeyParams are optional key parameters from SDES "a=crypto" attribute (RFC 4568).
+type SRTPKeyParams struct {
+	// Lifetime of the master key in packets. Zero means the default for the suite.
+	Lifetime uint64
+	// MKI is the Master Key Identifier carried in each SRTP packet. Nil if not used.
+	MKI []byte
+}
+
+// srtpCrypto is a parsed SDES "a=crypto" attribute value.
+type srtpCrypto struct {
+	Tag   int
+	Suite string
+	// Key is a concatenation of the master key and master salt.
+	Key []byte
+	SRTPKeyParams
+}
+
+func (c *srtpCrypto) String() string {
+	return fmt.Sprintf("%d %s inline:%s", c.Tag, c.Suite, base64.StdEncoding.EncodeToString(c.Key))
+}
+
+// parseSRTPLifetime parses key lifetime in "2^n" or decimal form.
+func parseSRTPLifetime(s string) (uint64, error) {
+	if exp, ok := strings.CutPrefix(s, "2^"); ok {
+		n, err := strconv.ParseUint(exp, 10, 8)
+		if err != nil || n == 0 || n > 63 {
+			return 0, fmt.Errorf("invalid key lifetime %q", s)
+		}
+		return 1 << n, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || v == 0 {
+		return 0, fmt.Errorf("invalid key lifetime %q", s)
+	}
+	return v, nil
+}
+
+// parseSRTPMKI parses MKI in "value:length" form, where value is a decimal number and length is in bytes.
+func parseSRTPMKI(s string) ([]byte, error) {
+	sval, slen, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid MKI %q", s)
+	}
+	n, err := strconv.Atoi(slen)
+	if err != nil || n <= 0 || n > 128 {
+		return nil, fmt.Errorf("invalid MKI length %q", s)
+	}
+	v, ok := new(big.Int).SetString(sval, 10)
+	if !ok || v.Sign() < 0 || v.BitLen() > n*8 {
+		return nil, fmt.Errorf("invalid MKI value %q", s)
+	}
+	return v.FillBytes(make([]byte, n)), nil
+}
+
+// parseSRTPCrypto parses SDES "a=crypto" attribute value, including key lifetime and MKI.
+//
+// Format: <tag> <crypto-suite> inline:<key||salt>[|lifetime][|MKI:length][;inline:...] [session-params]
+func parseSRTPCrypto(val string) (*srtpCrypto, error) {
+	sub := strings.Fields(val)
+	if len(sub) < 3 {
+		return nil, fmt.Errorf("invalid crypto attribute %q", val)
+	}
+	tag, err := strconv.Atoi(sub[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid crypto tag %q", sub[0])
+	}
+	c := &srtpCrypto{Tag: tag, Suite: sub[1]}
+	// Multiple keys are allowed, but we only use the first one.
+	keyParams, _, _ := strings.Cut(sub[2], ";")
+	keyParams, ok := strings.CutPrefix(keyParams, "inline:")
+	if !ok {
+		return nil, fmt.Errorf("unsupported key method in %q", val)
+	}
+	parts := strings.Split(keyParams, "|")
+	c.Key, err = base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		// Some implementations omit the padding.
+		c.Key, err = base64.RawStdEncoding.DecodeString(parts[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse crypto key: %w", err)
+	}
+	for _, p := range parts[1:] {
+		if strings.Contains(p, ":") {
+			if c.MKI, err = parseSRTPMKI(p); err != nil {
+				return nil, err
+			}
+		} else {
+			if c.Lifetime, err = parseSRTPLifetime(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// stripSRTPCrypto rewrites all "a=crypto" lines in SDP to a simple form, without key lifetime, MKI and session parameters.
+// It returns the modified SDP and all crypto attributes that were parsed.
+//
+// SDP parser in media-sdk only understands the simple form.
+func stripSRTPCrypto(data []byte) ([]byte, []srtpCrypto) {
+	const prefix = "a=crypto:"
+	if !bytes.Contains(data, []byte(prefix)) {
+		return data, nil
+	}
+	var (
+		out  = make([]byte, 0, len(data))
+		list []srtpCrypto
+	)
+	for len(data) > 0 {
+		line, rest, found := bytes.Cut(data, []byte("\n"))
+		data = rest
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if val, ok := bytes.CutPrefix(line, []byte(prefix)); ok {
+			if c, err := parseSRTPCrypto(string(val)); err == nil {
+				list = append(list, *c)
+				line = []byte(prefix + c.String())
+			}
+		}
+		out = append(out, line...)
+		if found {
+			out = append(out, "\r\n"...)
+		}
+	}
+	return out, list
+}
+
+// srtpSuites maps SDES crypto suite names to SRTP protection profiles.
+var srtpSuites = map[string]srtp.ProtectionProfile{
+	"AES_CM_128_HMAC_SHA1_80": srtp.ProtectionProfileAes128CmHmacSha1_80,
+	"AES_CM_128_HMAC_SHA1_32": srtp.ProtectionProfileAes128CmHmacSha1_32,
+	"AES_256_CM_HMAC_SHA1_80": srtp.ProtectionProfileAes256CmHmacSha1_80,
+	"AES_256_CM_HMAC_SHA1_32": srtp.ProtectionProfileAes256CmHmacSha1_32,
+	"AEAD_AES_128_GCM":        srtp.ProtectionProfileAeadAes128Gcm,
+	"AEAD_AES_256_GCM":        srtp.ProtectionProfileAeadAes256Gcm,
+}
+
+// DefaultSRTPSuites is the list of crypto suites offered and accepted when no suites are configured.
+var DefaultSRTPSuites = []string{
+	"AES_CM_128_HMAC_SHA1_80",
+	"AES_CM_128_HMAC_SHA1_32",
+	"AES_256_CM_HMAC_SHA1_80",
+	"AES_256_CM_HMAC_SHA1_32",
+}
+
+// ValidateSRTPSuites checks that all crypto suites in the list are supported.
+func ValidateSRTPSuites(suites []string) error {
+	for _, name := range suites {
+		if _, ok := srtpSuites[name]; !ok {
+			return fmt.Errorf("unsupported SRTP crypto suite %q", name)
+		}
+	}
+	return nil
+}
+
+// srtpKeyLen returns the length of master key and salt for the crypto suite.
+func srtpKeyLen(suite string) (srtp.ProtectionProfile, int, int, error) {
+	prof, ok := srtpSuites[suite]
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("unsupported SRTP crypto suite %q", suite)
+	}
+	keyLen, err := prof.KeyLen()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	saltLen, err := prof.SaltLen()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return prof, keyLen, saltLen, nil
+}
+
+// newSRTPCrypto generates a new random master key and salt for the crypto suite.
+func newSRTPCrypto(tag int, suite string) (*srtpCrypto, error) {
+	_, keyLen, saltLen, err := srtpKeyLen(suite)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, keyLen+saltLen)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &srtpCrypto{Tag: tag, Suite: suite, Key: key}, nil
+}
+
+// newSRTPOffer generates crypto attributes for all suites, in the order of preference.
+func newSRTPOffer(suites []string) ([]srtpCrypto, error) {
+	list := make([]srtpCrypto, 0, len(suites))
+	for i, suite := range suites {
+		c, err := newSRTPCrypto(i+1, suite)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *c)
+	}
+	return list, nil
+}
+
+// selectSRTPAnswer picks the first remote crypto attribute (in the order of remote's preference) with an allowed suite.
+// It returns the selected remote attribute and a new local attribute with the same tag.
+// If none of the suites are allowed, it returns nil.
+func selectSRTPAnswer(offer []srtpCrypto, suites []string) (remote, local *srtpCrypto, _ error) {
+	for i := range offer {
+		c := &offer[i]
+		if !slices.Contains(suites, c.Suite) {
+			continue
+		}
+		_, keyLen, saltLen, err := srtpKeyLen(c.Suite)
+		if err != nil || len(c.Key) != keyLen+saltLen {
+			continue
+		}
+		local, err = newSRTPCrypto(c.Tag, c.Suite)
+		if err != nil {
+			return nil, nil, err
+		}
+		return c, local, nil
+	}
+	return nil, nil, nil
+}
+
+// findSRTPAnswer finds the offered crypto attribute that was accepted by the remote answer.
+func findSRTPAnswer(offer, answer []srtpCrypto) (remote, local *srtpCrypto) {
+	for i := range answer {
+		c := &answer[i]
+		j := slices.IndexFunc(offer, func(o srtpCrypto) bool {
+			return o.Tag == c.Tag && o.Suite == c.Suite
+		})
+		if j < 0 {
+			continue
+		}
+		_, keyLen, saltLen, err := srtpKeyLen(c.Suite)
+		if err != nil || len(c.Key) != keyLen+saltLen {
+			continue
+		}
+		return c, &offer[j]
+	}
+	return nil, nil
+}
+
+// newSRTPConfig creates SRTP session config for the negotiated local and remote keys.
+func newSRTPConfig(local, remote *srtpCrypto) (*srtp.Config, error) {
+	prof, keyLen, _, err := srtpKeyLen(local.Suite)
+	if err != nil {
+		return nil, err
+	}
+	return &srtp.Config{
+		Profile: prof,
+		Keys: srtp.SessionKeys{
+			LocalMasterKey:   local.Key[:keyLen],
+			LocalMasterSalt:  local.Key[keyLen:],
+			RemoteMasterKey:  remote.Key[:keyLen],
+			RemoteMasterSalt: remote.Key[keyLen:],
+		},
+		RemoteOptions: []srtp.ContextOption{srtp.SRTPReplayProtection(srtpReplayWindow)},
+	}, nil
+}
+
+// setSRTPCrypto replaces crypto attributes in the media description and updates the transport protocol accordingly.
+func setSRTPCrypto(md *psdp.MediaDescription, list []srtpCrypto) {
+	attrs := make([]psdp.Attribute, 0, len(md.Attributes)+len(list))
+	for _, a := range md.Attributes {
+		if a.Key == "crypto" {
+			continue
+		}
+		if a.Key == "ptime" {
+			// Keep the same attribute order as media-sdk.
+			for _, c := range list {
+				attrs = append(attrs, psdp.Attribute{Key: "crypto", Value: c.String()})
+			}
+			list = nil
+		}
+		attrs = append(attrs, a)
+	}
+	for _, c := range list {
+		attrs = append(attrs, psdp.Attribute{Key: "crypto", Value: c.String()})
+	}
+	md.Attributes = attrs
+	md.MediaName.Protos = []string{"RTP", "AVP"}
+	if slices.ContainsFunc(attrs, func(a psdp.Attribute) bool { return a.Key == "crypto" }) {
+		md.MediaName.Protos[1] = "SAVP"
+	}
+}
+
+// srtpToProfiles converts crypto attributes to media-sdk SRTP profiles.
+func srtpToProfiles(list []srtpCrypto) []msrtp.Profile {
+	out := make([]msrtp.Profile, 0, len(list))
+	for _, c := range list {
+		_, keyLen, _, err := srtpKeyLen(c.Suite)
+		if err != nil || len(c.Key) < keyLen {
+			continue
+		}
+		out = append(out, msrtp.Profile{
+			Index:   c.Tag,
+			Profile: msrtp.ProtectionProfile(c.Suite),
+			Key:     c.Key[:keyLen],
+			Salt:    c.Key[keyLen:],
+		})
+	}
+	return out
+}
+
+// srtpFromProfiles converts media-sdk SRTP profiles back to crypto attributes.
+func srtpFromProfiles(list []msrtp.Profile) []srtpCrypto {
+	out := make([]srtpCrypto, 0, len(list))
+	for _, p := range list {
+		out = append(out, srtpCrypto{
+			Tag:   p.Index,
+			Suite: string(p.Profile),
+			Key:   append(append([]byte{}, p.Key...), p.Salt...),
+		})
+	}
+	return out
+}
+
+// srtpSuiteNames returns suite names of crypto attributes, for logging.
+func srtpSuiteNames(list []srtpCrypto) []string {
+	out := make([]string, 0, len(list))
+	for _, c := range list {
+		out = append(out, c.Suite)
+	}
+	return out
+}
+
+// srtpKeyParams returns optional key parameters of the crypto attribute, or nil if none are set.
+func srtpKeyParams(c *srtpCrypto) *SRTPKeyParams {
+	if c == nil || (c.Lifetime == 0 && c.MKI == nil) {
+		return nil
+	}
+	p := c.SRTPKeyParams
+	return &p
+}
+
+// srtpRekeyAt returns the number of packets after which a key with a given lifetime should be replaced.
+func srtpRekeyAt(lifetime uint64) uint64 {
+	if lifetime < 1<<32 {
+		return lifetime * srtpRekeyThreshold / 100
+	}
+	return lifetime / 100 * srtpRekeyThreshold
+}
+
+// srtpConn encrypts and decrypts RTP packets on the underlying connection.
+//
+// Unlike the SRTP session from media-sdk, it allows replacing the keys without recreating the session,
+// which is required for rekeying (re-INVITE) without audio gaps.
+type srtpConn struct {
+	net.Conn
+	log   logger.Logger
+	stats *PortStats
+	// onExpire is called once per remote key, when its lifetime is close to the end.
+	onExpire func()
+	// onFailures is called once, when the number of packets failing authentication, each within
+	// srtpFailureWindow from the previous one, reaches maxFailures. Replays are only counted in stats,
+	// since duplicated packets are common in the network.
+	onFailures  func()
+	maxFailures uint64
+
+	rbuf []byte // owned by the reader, used without the read lock
+
+	rmu       sync.Mutex
+	dbuf      []byte
+	remote    *srtp.Context
+	remoteKey []byte
+	remoteMKI []byte
+	prev      *srtp.Context
+	prevLeft  int
+	recvCnt   uint64
+	lifetime  uint64
+	rekeyAt   uint64
+	expired   bool
+	failures  uint64
+	failedAt  time.Time
+	failed    bool
+
+	wmu      sync.Mutex
+	wbuf     []byte
+	local    *srtp.Context
+	localKey []byte
+	next     *srtp.Context
+	switchAt time.Time
+}
+
+func newSRTPConn(log logger.Logger, conn net.Conn, st *PortStats) *srtpConn {
+	return &srtpConn{
+		Conn:  conn,
+		log:   log,
+		stats: st,
+		rbuf:  make([]byte, rtp.MTUSize+1+srtpMaxOverhead),
+		dbuf:  make([]byte, rtp.MTUSize+1+srtpMaxOverhead),
+		wbuf:  make([]byte, 0, rtp.MTUSize+srtpMaxOverhead),
+	}
+}
+
+// SetKeys installs new SRTP keys. Keys that did not change are kept as-is, preserving the crypto state.
+//
+// Previous remote key is still accepted for a few packets, to handle reordering during the rekey.
+// The local key switch can be delayed to let the remote install the key first.
+func (c *srtpConn) SetKeys(conf *srtp.Config, remote *SRTPKeyParams, sendDelay time.Duration) error {
+	if conf == nil {
+		return errors.New("no SRTP config")
+	}
+	if remote == nil {
+		remote = &SRTPKeyParams{}
+	}
+	localKey := append(append([]byte{}, conf.Keys.LocalMasterKey...), conf.Keys.LocalMasterSalt...)
+	remoteKey := append(append([]byte{}, conf.Keys.RemoteMasterKey...), conf.Keys.RemoteMasterSalt...)
+
+	c.wmu.Lock()
+	if !bytes.Equal(c.localKey, localKey) {
+		local, err := srtp.CreateContext(conf.Keys.LocalMasterKey, conf.Keys.LocalMasterSalt, conf.Profile, conf.LocalOptions...)
+		if err != nil {
+			c.wmu.Unlock()
+			return err
+		}
+		c.localKey = localKey
+		if c.local == nil || sendDelay <= 0 {
+			c.local, c.next = local, nil
+		} else {
+			c.next = local
+			c.switchAt = time.Now().Add(sendDelay)
+		}
+	}
+	c.wmu.Unlock()
+
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	c.lifetime = remote.Lifetime
+	c.rekeyAt = srtpRekeyAt(remote.Lifetime)
+	if bytes.Equal(c.remoteKey, remoteKey) && bytes.Equal(c.remoteMKI, remote.MKI) {
+		return nil
+	}
+	opts := conf.RemoteOptions
+	if len(remote.MKI) != 0 {
+		opts = append(opts[:len(opts):len(opts)], srtp.MasterKeyIndicator(remote.MKI))
+	}
+	ctx, err := srtp.CreateContext(conf.Keys.RemoteMasterKey, conf.Keys.RemoteMasterSalt, conf.Profile, opts...)
+	if err != nil {
+		return err
+	}
+	if c.remote != nil {
+		c.prev, c.prevLeft = c.remote, srtpPrevKeyPackets
+	}
+	c.remote = ctx
+	c.remoteKey = remoteKey
+	c.remoteMKI = remote.MKI
+	c.recvCnt = 0
+	c.expired = false
+	return nil
+}
+
+// Read reads and decrypts the next SRTP packet. It must not be called concurrently.
+//
+// The read lock is only held while the packet is decrypted, so that SetKeys is not blocked while the remote is silent.
+func (c *srtpConn) Read(b []byte) (int, error) {
+	for {
+		n, err := c.Conn.Read(c.rbuf)
+		if err != nil {
+			return 0, err
+		}
+		c.rmu.Lock()
+		out, err := c.decrypt(c.rbuf[:n])
+		if err != nil {
+			c.countFailure(err, time.Now())
+			c.rmu.Unlock()
+			c.stats.IgnoredPackets.Add(1)
+			continue
+		}
+		n = copy(b, out)
+		c.rmu.Unlock()
+		return n, nil
+	}
+}
+
+// Must be called holding the read lock.
+func (c *srtpConn) decrypt(buf []byte) ([]byte, error) {
+	if c.remote == nil {
+		return nil, errors.New("no SRTP key")
+	}
+	out, err := c.remote.DecryptRTP(c.dbuf[:0], buf, nil)
+	if err == nil {
+		c.recvCnt++
+		if c.prev != nil {
+			if c.prevLeft--; c.prevLeft <= 0 {
+				c.prev = nil
+			}
+		}
+		if !c.expired && c.lifetime != 0 && c.recvCnt >= c.rekeyAt {
+			c.expired = true
+			c.log.Infow("SRTP key lifetime is about to expire", "packets", c.recvCnt, "lifetime", c.lifetime)
+			if c.onExpire != nil {
+				c.onExpire()
+			}
+		}
+		return out, nil
+	}
+	if c.prev != nil {
+		if out, err2 := c.prev.DecryptRTP(c.dbuf[:0], buf, nil); err2 == nil {
+			return out, nil
+		}
+	}
+	return nil, err
+}
+
+// countFailure accounts packets failing authentication or replay checks. Must be called holding the read lock.
+func (c *srtpConn) countFailure(err error, now time.Time) {
+	switch {
+	case errors.Is(err, srtp.ErrFailedToVerifyAuthTag):
+		if c.stats.SRTPAuthFailures.Add(1) == 1 {
+			c.log.Infow("SRTP packet failed authentication", "error", err)
+		}
+	case isSRTPReplay(err):
+		if c.stats.SRTPReplays.Add(1) == 1 {
+			c.log.Infow("SRTP packet replayed", "error", err)
+		}
+		return
+	default:
+		return
+	}
+	if c.maxFailures == 0 || c.failed {
+		return
+	}
+	if now.Sub(c.failedAt) > srtpFailureWindow {
+		c.failures = 0
+	}
+	c.failedAt = now
+	c.failures++
+	if c.failures < c.maxFailures {
+		return
+	}
+	c.failed = true
+	c.log.Warnw("too many invalid SRTP packets", nil,
+		"authFailures", c.stats.SRTPAuthFailures.Load(),
+		"replays", c.stats.SRTPReplays.Load(),
+	)
+	if c.onFailures != nil {
+		c.onFailures()
+	}
+}
+
+// isSRTPReplay checks if the decryption failed because the packet was already received.
+// The error is not exported by pion, so the message is matched instead.
+func isSRTPReplay(err error) bool {
+	return strings.Contains(err.Error(), "duplicated packet")
+}
+
+func (c *srtpConn) Write(b []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.next != nil && !time.Now().Before(c.switchAt) {
+		c.local, c.next = c.next, nil
+	}
+	if c.local == nil {
+		return 0, errors.New("no SRTP key")
+	}
+	out, err := c.local.EncryptRTP(c.wbuf[:0], b, nil)
+	if err != nil {
+		return 0, err
+	}
+	c.wbuf = out[:0]
+	if _, err = c.Conn.Write(out); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
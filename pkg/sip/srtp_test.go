@@ -0,0 +1,392 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/livekit/media-sdk/sdp"
+	"github.com/livekit/protocol/logger"
+	prtp "github.com/pion/rtp"
+	"github.com/pion/srtp/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSRTPCrypto(t *testing.T) {
+	const key = "WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz"
+	cases := []struct {
+		name string
+		val  string
+		exp  SRTPKeyParams
+		err  bool
+	}{
+		{name: "plain", val: "1 AES_CM_128_HMAC_SHA1_80 inline:" + key},
+		{name: "lifetime pow", val: "1 AES_CM_128_HMAC_SHA1_80 inline:" + key + "|2^20", exp: SRTPKeyParams{Lifetime: 1 << 20}},
+		{name: "lifetime dec", val: "1 AES_CM_128_HMAC_SHA1_80 inline:" + key + "|1000", exp: SRTPKeyParams{Lifetime: 1000}},
+		{name: "mki", val: "1 AES_CM_128_HMAC_SHA1_80 inline:" + key + "|1:4", exp: SRTPKeyParams{MKI: []byte{0, 0, 0, 1}}},
+		{name: "both", val: "1 AES_CM_128_HMAC_SHA1_80 inline:" + key + "|2^31|258:2", exp: SRTPKeyParams{Lifetime: 1 << 31, MKI: []byte{1, 2}}},
+		{name: "session params", val: "1 AES_CM_128_HMAC_SHA1_80 inline:" + key + "|2^31 UNENCRYPTED_SRTCP", exp: SRTPKeyParams{Lifetime: 1 << 31}},
+		{name: "bad lifetime", val: "1 AES_CM_128_HMAC_SHA1_80 inline:" + key + "|2^99", err: true},
+		{name: "mki too large", val: "1 AES_CM_128_HMAC_SHA1_80 inline:" + key + "|256:1", err: true},
+		{name: "no key", val: "1 AES_CM_128_HMAC_SHA1_80", err: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseSRTPCrypto(c.val)
+			if c.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, 1, got.Tag)
+			require.Equal(t, "AES_CM_128_HMAC_SHA1_80", got.Suite)
+			require.Len(t, got.Key, 30)
+			require.Equal(t, c.exp, got.SRTPKeyParams)
+			require.Equal(t, "1 AES_CM_128_HMAC_SHA1_80 inline:"+key, got.String())
+		})
+	}
+}
+
+func TestStripSRTPCrypto(t *testing.T) {
+	const offer = "v=0\r\n" +
+		"o=- 1 1 IN IP4 1.1.1.1\r\n" +
+		"s=-\r\n" +
+		"c=IN IP4 1.1.1.1\r\n" +
+		"t=0 0\r\n" +
+		"m=audio 10000 RTP/SAVP 0\r\n" +
+		"a=rtpmap:0 PCMU/8000\r\n" +
+		"a=crypto:1 AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz|2^20|1:4\r\n"
+
+	data, list := stripSRTPCrypto([]byte(offer))
+	require.Len(t, list, 1)
+	require.NotContains(t, string(data), "|")
+
+	p, err := NewMediaPortWith(logger.GetLogger(), nil, newTestConn(1), &MediaOptions{IP: newIP("2.2.2.2")}, 8000)
+	require.NoError(t, err)
+	defer p.Close()
+
+	_, conf, err := p.SetOffer([]byte(offer), sdp.EncryptionRequire)
+	require.NoError(t, err)
+	require.NotNil(t, conf.Crypto)
+	require.Equal(t, &SRTPKeyParams{Lifetime: 1 << 20, MKI: []byte{0, 0, 0, 1}}, conf.RemoteSRTP)
+}
+
+func newTestSRTPConfig(t testing.TB, key byte) *srtp.Config {
+	conf := &srtp.Config{
+		Profile: srtp.ProtectionProfileAes128CmHmacSha1_80,
+		Keys: srtp.SessionKeys{
+			LocalMasterKey:   bytes.Repeat([]byte{key}, 16),
+			LocalMasterSalt:  bytes.Repeat([]byte{key}, 14),
+			RemoteMasterKey:  bytes.Repeat([]byte{key}, 16),
+			RemoteMasterSalt: bytes.Repeat([]byte{key}, 14),
+		},
+	}
+	return conf
+}
+
+func TestSRTPConnRekey(t *testing.T) {
+	c1, c2 := newUDPPipe()
+	st := new(PortStats)
+	u1 := newUDPConn(logger.GetLogger(), c1, st, new(mediaEvents))
+	u2 := newUDPConn(logger.GetLogger(), c2, st, new(mediaEvents))
+	u1.SetDst(c2.addr)
+	u2.SetDst(c1.addr)
+	s1 := newSRTPConn(logger.GetLogger(), u1, st)
+	s2 := newSRTPConn(logger.GetLogger(), u2, st)
+	expired := 0
+	s2.onExpire = func() { expired++ }
+
+	mki := []byte{0, 1}
+	conf1 := newTestSRTPConfig(t, 1)
+	conf1.LocalOptions = []srtp.ContextOption{srtp.MasterKeyIndicator(mki)}
+	require.NoError(t, s1.SetKeys(conf1, nil, 0))
+	require.NoError(t, s2.SetKeys(newTestSRTPConfig(t, 1), &SRTPKeyParams{Lifetime: 10, MKI: mki}, 0))
+
+	seq := uint16(0)
+	send := func(t testing.TB) {
+		seq++
+		pkt := &prtp.Packet{
+			Header:  prtp.Header{Version: 2, SSRC: 1, SequenceNumber: seq, Timestamp: uint32(seq) * 160},
+			Payload: []byte{1, 2, 3, 4},
+		}
+		data, err := pkt.Marshal()
+		require.NoError(t, err)
+		_, err = s1.Write(data)
+		require.NoError(t, err)
+	}
+	recv := func(t testing.TB) {
+		buf := make([]byte, 1500)
+		n, err := s2.Read(buf)
+		require.NoError(t, err)
+		var pkt prtp.Packet
+		require.NoError(t, pkt.Unmarshal(buf[:n]))
+		require.Equal(t, seq, pkt.SequenceNumber)
+		require.Equal(t, []byte{1, 2, 3, 4}, pkt.Payload)
+	}
+	for range 9 {
+		send(t)
+		recv(t)
+	}
+	require.Equal(t, 1, expired, "expected rekey request at 90% of the lifetime")
+
+	// Remote key changes first, packets with an old key must still be accepted.
+	conf2 := newTestSRTPConfig(t, 2)
+	require.NoError(t, s2.SetKeys(conf2, nil, 0))
+	send(t)
+	recv(t)
+
+	// Sender switches to the new key.
+	require.NoError(t, s1.SetKeys(conf2, nil, 0))
+	send(t)
+	recv(t)
+	require.Zero(t, st.IgnoredPackets.Load())
+	require.Equal(t, 1, expired)
+}
+
+func TestSRTPConnRekeyIdle(t *testing.T) {
+	c1, c2 := newUDPPipe()
+	st := new(PortStats)
+	u1 := newUDPConn(logger.GetLogger(), c1, st, new(mediaEvents))
+	u2 := newUDPConn(logger.GetLogger(), c2, st, new(mediaEvents))
+	u1.SetDst(c2.addr)
+	u2.SetDst(c1.addr)
+	s1 := newSRTPConn(logger.GetLogger(), u1, st)
+	s2 := newSRTPConn(logger.GetLogger(), u2, st)
+	require.NoError(t, s1.SetKeys(newTestSRTPConfig(t, 1), nil, 0))
+	require.NoError(t, s2.SetKeys(newTestSRTPConfig(t, 1), nil, 0))
+
+	// Reader waits for packets while the remote is silent.
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	buf := make([]byte, 1500)
+	go func() {
+		n, err := s2.Read(buf)
+		done <- result{n, err}
+	}()
+
+	// Rekey must not wait for the next packet.
+	conf2 := newTestSRTPConfig(t, 2)
+	keyed := make(chan error, 1)
+	go func() {
+		keyed <- s2.SetKeys(conf2, nil, 0)
+	}()
+	select {
+	case err := <-keyed:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("SetKeys is blocked by Read")
+	}
+
+	require.NoError(t, s1.SetKeys(conf2, nil, 0))
+	pkt := &prtp.Packet{
+		Header:  prtp.Header{Version: 2, SSRC: 1, SequenceNumber: 1, Timestamp: 160},
+		Payload: []byte{1, 2, 3, 4},
+	}
+	data, err := pkt.Marshal()
+	require.NoError(t, err)
+	_, err = s1.Write(data)
+	require.NoError(t, err)
+	res := <-done
+	require.NoError(t, res.err)
+	require.Equal(t, data, buf[:res.n])
+}
+
+func TestSRTPConnFailures(t *testing.T) {
+	c1, c2 := newUDPPipe()
+	st := new(PortStats)
+	u1 := newUDPConn(logger.GetLogger(), c1, st, new(mediaEvents))
+	u2 := newUDPConn(logger.GetLogger(), c2, st, new(mediaEvents))
+	u1.SetDst(c2.addr)
+	u2.SetDst(c1.addr)
+	s2 := newSRTPConn(logger.GetLogger(), u2, st)
+	failed := 0
+	s2.onFailures = func() { failed++ }
+	s2.maxFailures = 3
+
+	conf := newTestSRTPConfig(t, 1)
+	conf.RemoteOptions = []srtp.ContextOption{srtp.SRTPReplayProtection(srtpReplayWindow)}
+	require.NoError(t, s2.SetKeys(conf, nil, 0))
+	enc, err := srtp.CreateContext(conf.Keys.LocalMasterKey, conf.Keys.LocalMasterSalt, conf.Profile)
+	require.NoError(t, err)
+
+	seq := uint16(0)
+	encrypt := func(t testing.TB) []byte {
+		seq++
+		pkt := &prtp.Packet{
+			Header:  prtp.Header{Version: 2, SSRC: 1, SequenceNumber: seq, Timestamp: uint32(seq) * 160},
+			Payload: []byte{1, 2, 3, 4},
+		}
+		data, err := pkt.Marshal()
+		require.NoError(t, err)
+		out, err := enc.EncryptRTP(nil, data, nil)
+		require.NoError(t, err)
+		return out
+	}
+	send := func(t testing.TB, data []byte) {
+		_, err := u1.Write(data)
+		require.NoError(t, err)
+	}
+	recv := func(t testing.TB) {
+		buf := make([]byte, 1500)
+		n, err := s2.Read(buf)
+		require.NoError(t, err)
+		var pkt prtp.Packet
+		require.NoError(t, pkt.Unmarshal(buf[:n]))
+		require.Equal(t, seq, pkt.SequenceNumber)
+	}
+
+	first := encrypt(t)
+	send(t, first)
+	recv(t)
+
+	// Replayed packet.
+	send(t, first)
+	// Packet with an invalid auth tag.
+	forged := encrypt(t)
+	forged[len(forged)-1] ^= 0xff
+	send(t, forged)
+	send(t, encrypt(t))
+	recv(t)
+	require.EqualValues(t, 1, st.SRTPReplays.Load())
+	require.EqualValues(t, 1, st.SRTPAuthFailures.Load())
+	require.Zero(t, failed)
+
+	forged = encrypt(t)
+	forged[len(forged)-1] ^= 0xff
+	send(t, forged)
+	send(t, forged)
+	send(t, encrypt(t))
+	recv(t)
+	require.EqualValues(t, 3, st.SRTPAuthFailures.Load())
+	require.EqualValues(t, 4, st.IgnoredPackets.Load())
+	require.Equal(t, 1, failed, "expected a single callback once the threshold is reached")
+}
+
+func TestSRTPConnFailuresScattered(t *testing.T) {
+	st := new(PortStats)
+	c := newSRTPConn(logger.GetLogger(), nil, st)
+	failed := 0
+	c.onFailures = func() { failed++ }
+	c.maxFailures = 3
+
+	authErr := fmt.Errorf("%w: test", srtp.ErrFailedToVerifyAuthTag)
+	replayErr := errors.New("srtp: duplicated packet")
+
+	// A long call with occasional failures and network duplicates is kept.
+	now := time.Unix(0, 0)
+	for range 1000 {
+		now = now.Add(srtpFailureWindow + time.Second)
+		c.countFailure(authErr, now)
+		c.countFailure(replayErr, now)
+		c.countFailure(replayErr, now)
+	}
+	require.EqualValues(t, 1000, st.SRTPAuthFailures.Load())
+	require.EqualValues(t, 2000, st.SRTPReplays.Load())
+	require.Zero(t, failed)
+
+	// Sustained failures trigger the policy once.
+	for range 10 {
+		now = now.Add(time.Second)
+		c.countFailure(authErr, now)
+	}
+	require.Equal(t, 1, failed)
+}
+
+func TestSRTPSuiteNegotiation(t *testing.T) {
+	newPort := func(t testing.TB, opts *MediaOptions) *MediaPort {
+		opts.IP = newIP("1.1.1.1")
+		p, err := NewMediaPortWith(logger.GetLogger(), nil, newTestConn(1), opts, 8000)
+		require.NoError(t, err)
+		t.Cleanup(p.Close)
+		return p
+	}
+	offerer := newPort(t, &MediaOptions{SRTPSuites: []string{"AEAD_AES_256_GCM", "AES_CM_128_HMAC_SHA1_80"}})
+	offer, err := offerer.NewOffer(sdp.EncryptionRequire)
+	require.NoError(t, err)
+	offerData, err := offer.SDP.Marshal()
+	require.NoError(t, err)
+	require.Contains(t, string(offerData), "RTP/SAVP")
+	i1 := bytes.Index(offerData, []byte("a=crypto:1 AEAD_AES_256_GCM inline:"))
+	i2 := bytes.Index(offerData, []byte("a=crypto:2 AES_CM_128_HMAC_SHA1_80 inline:"))
+	require.True(t, i1 >= 0 && i2 > i1, "unexpected crypto order:\n%s", offerData)
+
+	t.Run("offerer preference", func(t *testing.T) {
+		answerer := newPort(t, &MediaOptions{SRTPSuites: []string{"AES_CM_128_HMAC_SHA1_80", "AEAD_AES_256_GCM"}})
+		answer, conf, err := answerer.SetOffer(offerData, sdp.EncryptionRequire)
+		require.NoError(t, err)
+		require.NotNil(t, conf.Crypto)
+		require.Equal(t, srtp.ProtectionProfileAeadAes256Gcm, conf.Crypto.Profile)
+		answerData, err := answer.SDP.Marshal()
+		require.NoError(t, err)
+		require.Contains(t, string(answerData), "a=crypto:1 AEAD_AES_256_GCM inline:")
+
+		oconf, err := offerer.SetAnswer(offer, answerData, sdp.EncryptionRequire)
+		require.NoError(t, err)
+		require.NotNil(t, oconf.Crypto)
+		require.Equal(t, srtp.ProtectionProfileAeadAes256Gcm, oconf.Crypto.Profile)
+		require.Equal(t, conf.Crypto.Keys.LocalMasterKey, oconf.Crypto.Keys.RemoteMasterKey)
+		require.Equal(t, conf.Crypto.Keys.LocalMasterSalt, oconf.Crypto.Keys.RemoteMasterSalt)
+		require.Equal(t, conf.Crypto.Keys.RemoteMasterKey, oconf.Crypto.Keys.LocalMasterKey)
+		require.Equal(t, conf.Crypto.Keys.RemoteMasterSalt, oconf.Crypto.Keys.LocalMasterSalt)
+	})
+	t.Run("skip disallowed", func(t *testing.T) {
+		answerer := newPort(t, &MediaOptions{SRTPSuites: []string{"AES_CM_128_HMAC_SHA1_80"}})
+		_, conf, err := answerer.SetOffer(offerData, sdp.EncryptionAllow)
+		require.NoError(t, err)
+		require.NotNil(t, conf.Crypto)
+		require.Equal(t, srtp.ProtectionProfileAes128CmHmacSha1_80, conf.Crypto.Profile)
+	})
+	t.Run("no fallback", func(t *testing.T) {
+		// Offer has only RTP/SAVP, so it can't be answered with plain RTP.
+		answerer := newPort(t, &MediaOptions{SRTPSuites: []string{"AES_CM_128_HMAC_SHA1_32"}})
+		_, _, err := answerer.SetOffer(offerData, sdp.EncryptionAllow)
+		require.ErrorIs(t, err, sdp.ErrNoCommonCrypto)
+
+		_, _, err = answerer.SetOffer(offerData, sdp.EncryptionRequire)
+		require.ErrorIs(t, err, sdp.ErrNoCommonCrypto)
+	})
+	t.Run("fallback", func(t *testing.T) {
+		offerer := newPort(t, &MediaOptions{SRTPSuites: []string{"AEAD_AES_256_GCM"}, OfferPlainRTP: true})
+		offer, err := offerer.NewOffer(sdp.EncryptionAllow)
+		require.NoError(t, err)
+		offerData, err := offer.SDP.Marshal()
+		require.NoError(t, err)
+
+		answerer := newPort(t, &MediaOptions{SRTPSuites: []string{"AES_CM_128_HMAC_SHA1_32"}})
+		answer, conf, err := answerer.SetOffer(offerData, sdp.EncryptionAllow)
+		require.NoError(t, err)
+		require.Nil(t, conf.Crypto)
+		require.True(t, conf.Downgraded)
+		require.Len(t, answer.SDP.MediaDescriptions, 2)
+		require.Zero(t, answer.SDP.MediaDescriptions[0].MediaName.Port.Value, "encrypted line must be rejected")
+		require.Equal(t, []string{"RTP", "AVP"}, answer.SDP.MediaDescriptions[1].MediaName.Protos)
+		require.NotZero(t, answer.SDP.MediaDescriptions[1].MediaName.Port.Value)
+		answerData, err := answer.SDP.Marshal()
+		require.NoError(t, err)
+		require.NotContains(t, string(answerData), "a=crypto")
+	})
+	t.Run("reject", func(t *testing.T) {
+		answerer := newPort(t, &MediaOptions{SRTPSuites: []string{"AES_CM_128_HMAC_SHA1_32"}, SRTPRejectDisallowed: true})
+		_, _, err := answerer.SetOffer(offerData, sdp.EncryptionAllow)
+		require.ErrorIs(t, err, sdp.ErrNoCommonCrypto)
+	})
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/stt"
+)
+
+const (
+	// sttQueueSize is the number of frames buffered for the engine. Frames are dropped if the engine is slower.
+	sttQueueSize = 50
+	// sttCloseTimeout is how long to wait for the last transcripts after the audio ends.
+	sttCloseTimeout = 5 * time.Second
+	// sttSegmentPrefix separates transcript segments of the engine from other transcriptions, like real-time text.
+	sttSegmentPrefix = "stt-"
+)
+
+// sttStage streams the audio to the speech-to-text engine. The audio is passed on unchanged.
+type sttStage struct {
+	log     logger.Logger
+	engine  stt.Engine
+	handler func(t stt.Transcript)
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu      sync.Mutex
+	frames  chan msdk.PCM16Sample
+	dropped int
+	closed  bool
+}
+
+func newSTTStage(env MediaStageEnv, _ config.MediaStageConfig) (MediaStage, error) {
+	if env.STT == nil {
+		return nil, errors.New("stt is not configured")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &sttStage{
+		log:     env.Log,
+		engine:  env.STT,
+		handler: env.OnTranscript,
+		ctx:     ctx,
+		cancel:  cancel,
+	}, nil
+}
+
+func (s *sttStage) Name() string {
+	return config.MediaStageSTT
+}
+
+func (s *sttStage) Process(w msdk.PCM16Writer) msdk.PCM16Writer {
+	return &stageWriter{name: "STT", w: w, fnc: s.send}
+}
+
+func (s *sttStage) send(sample msdk.PCM16Sample, sampleRate int) msdk.PCM16Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return sample
+	}
+	if s.frames == nil {
+		s.frames = make(chan msdk.PCM16Sample, sttQueueSize)
+		go s.run(sampleRate, s.frames)
+	}
+	select {
+	case s.frames <- slices.Clone(sample):
+	default:
+		s.dropped++
+		if s.dropped == 1 {
+			s.log.Warnw("stt engine is too slow, dropping audio", nil)
+		}
+	}
+	return sample
+}
+
+func (s *sttStage) run(sampleRate int, frames <-chan msdk.PCM16Sample) {
+	defer func() {
+		for range frames {
+		}
+	}()
+	stream, err := s.engine.Start(s.ctx, sampleRate, s.onTranscript)
+	if err != nil {
+		s.log.Warnw("cannot start stt stream", err)
+		s.cancel()
+		return
+	}
+	defer func() {
+		_ = stream.Close()
+		time.AfterFunc(sttCloseTimeout, s.cancel)
+	}()
+	for f := range frames {
+		if err := stream.WriteSample(f); err != nil {
+			s.log.Warnw("cannot send audio to stt engine", err)
+			return
+		}
+	}
+}
+
+func (s *sttStage) onTranscript(t stt.Transcript) {
+	if s.handler == nil {
+		return
+	}
+	t.ID = sttSegmentPrefix + t.ID
+	s.handler(t)
+}
+
+func (s *sttStage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.frames != nil {
+		close(s.frames)
+	} else {
+		s.cancel()
+	}
+	if s.dropped != 0 {
+		s.log.Infow("stt audio dropped", "frames", s.dropped)
+	}
+	return nil
+}
+
+// publishTranscript publishes a transcript of the SIP audio as a transcription of the SIP participant.
+func publishTranscript(log logger.Logger, r *Room, t stt.Transcript) {
+	if t.Final {
+		log.Debugw("speech transcribed", "text", t.Text, "language", t.Language)
+	}
+	if err := r.SendTranscription(t.ID, t.Text, t.Final); err != nil {
+		log.Infow("cannot send transcription to the room", "error", err)
+	}
+}
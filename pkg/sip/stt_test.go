@@ -0,0 +1,100 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/stt"
+)
+
+// countingSTT reports the number of received samples as an interim transcript for each frame.
+type countingSTT struct {
+	rate   chan int
+	closed chan struct{}
+}
+
+func (e *countingSTT) Start(_ context.Context, sampleRate int, h func(t stt.Transcript)) (stt.Stream, error) {
+	e.rate <- sampleRate
+	return &countingSTTStream{e: e, h: h}, nil
+}
+
+func (e *countingSTT) Close() error { return nil }
+
+type countingSTTStream struct {
+	e     *countingSTT
+	h     func(t stt.Transcript)
+	total int
+}
+
+func (s *countingSTTStream) WriteSample(sample msdk.PCM16Sample) error {
+	s.total += len(sample)
+	s.h(stt.Transcript{ID: "1", Text: strconv.Itoa(s.total)})
+	return nil
+}
+
+func (s *countingSTTStream) Close() error {
+	s.h(stt.Transcript{ID: "1", Text: "done", Final: true})
+	close(s.e.closed)
+	return nil
+}
+
+func TestSTTStage(t *testing.T) {
+	_, err := NewMediaStage(MediaStageEnv{Log: logger.GetLogger()}, config.MediaStageConfig{Name: config.MediaStageSTT})
+	require.Error(t, err)
+
+	e := &countingSTT{rate: make(chan int, 1), closed: make(chan struct{})}
+	got := make(chan stt.Transcript, 10)
+	st, err := NewMediaStage(MediaStageEnv{
+		Log:          logger.GetLogger(),
+		STT:          e,
+		OnTranscript: func(t stt.Transcript) { got <- t },
+	}, config.MediaStageConfig{Name: config.MediaStageSTT})
+	require.NoError(t, err)
+
+	out := &stageTestWriter{rate: 16000}
+	w := st.Process(out)
+	in := msdk.PCM16Sample{1, 2, 3}
+	require.NoError(t, w.WriteSample(in))
+	require.NoError(t, w.WriteSample(in))
+	require.Equal(t, []msdk.PCM16Sample{in, in}, out.samples, "audio is passed on")
+	require.Equal(t, 16000, <-e.rate)
+
+	require.NoError(t, st.(*sttStage).Close())
+	select {
+	case <-e.closed:
+	case <-time.After(time.Second):
+		t.Fatal("stream not closed")
+	}
+	var texts []string
+	for range 3 {
+		tr := <-got
+		require.Equal(t, sttSegmentPrefix+"1", tr.ID)
+		texts = append(texts, tr.Text)
+	}
+	require.Equal(t, []string{"3", "6", "done"}, texts)
+
+	// Audio after close is not sent.
+	require.NoError(t, w.WriteSample(in))
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/sip/pkg/media/vad"
+)
+
+// talkStatsAttrs returns call info attributes for talk time statistics, or nil if no audio was processed.
+func talkStatsAttrs(sum vad.TalkSummary) map[string]string {
+	if sum == (vad.TalkSummary{}) {
+		return nil
+	}
+	secs := func(d time.Duration) string {
+		return strconv.FormatFloat(d.Seconds(), 'f', 2, 64)
+	}
+	return map[string]string{
+		AttrSIPTalkTimeSIP:    secs(sum.Remote.Talk),
+		AttrSIPTalkTimeRoom:   secs(sum.Local.Talk),
+		AttrSIPTalkBurstsSIP:  strconv.Itoa(sum.Remote.Bursts),
+		AttrSIPTalkBurstsRoom: strconv.Itoa(sum.Local.Bursts),
+		AttrSIPTalkOverlap:    secs(sum.Overlap),
+		AttrSIPLongestSilence: secs(sum.LongestSilence),
+	}
+}
+
+// setTalkStats adds talk time statistics to participant attributes of the call info.
+func setTalkStats(info *livekit.SIPCallInfo, attrs map[string]string) {
+	if len(attrs) == 0 {
+		return
+	}
+	// Attributes may be shared with the room participant, so always make a copy.
+	pattrs := make(map[string]string, len(info.ParticipantAttributes)+len(attrs))
+	maps.Copy(pattrs, info.ParticipantAttributes)
+	maps.Copy(pattrs, attrs)
+	info.ParticipantAttributes = pattrs
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/frostbyte73/core"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/siptest"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+const (
+	// testCallTone is the index of the test tone, see audiotest.GenSignal.
+	testCallTone = 3
+	// testCallFrom is the default caller number of test calls.
+	testCallFrom = "livekit-test"
+)
+
+// testCaller periodically places a synthetic call, sends a test tone and waits for the same tone to come back.
+type testCaller struct {
+	log  logger.Logger
+	mon  *stats.Monitor
+	conf config.TestCallConfig
+	ip   netip.Addr
+	stop core.Fuse
+}
+
+// newTestCallers creates callers for all configured test calls. Calls without an address are sent to this node.
+func newTestCallers(conf *config.Config, sconf *ServiceConfig, log logger.Logger, mon *stats.Monitor) []*testCaller {
+	var out []*testCaller
+	for _, tc := range conf.TestCalls {
+		ip := sconf.SignalingIP
+		if tc.Address == "" {
+			ip = sconf.SignalingIPLocal
+			tc.Address = netip.AddrPortFrom(ip, uint16(conf.SIPPortListen)).String()
+		}
+		if tc.From == "" {
+			tc.From = testCallFrom
+		}
+		if tc.Interval <= 0 {
+			tc.Interval = config.DefaultTestCallInterval
+		}
+		if tc.Timeout <= 0 {
+			tc.Timeout = config.DefaultTestCallTimeout
+		}
+		out = append(out, &testCaller{
+			log:  log.WithValues("testCall", tc.Name, "address", tc.Address, "number", tc.Number),
+			mon:  mon,
+			conf: tc,
+			ip:   ip,
+		})
+	}
+	return out
+}
+
+func (t *testCaller) Start() {
+	go t.run()
+}
+
+func (t *testCaller) Stop() {
+	t.stop.Break()
+}
+
+func (t *testCaller) run() {
+	ticker := time.NewTicker(t.conf.Interval)
+	defer ticker.Stop()
+	for {
+		t.runOnce()
+		select {
+		case <-t.stop.Watch():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *testCaller) runOnce() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-t.stop.Watch():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	result, setup, err := t.call(ctx)
+	if t.stop.IsBroken() {
+		return // interrupted by shutdown, do not report
+	}
+	t.mon.TestCallDone(t.conf.Name, result, setup)
+	if result != stats.TestCallPass {
+		t.log.Warnw("test call failed", err, "result", result, "setup", setup)
+		return
+	}
+	t.log.Infow("test call passed", "setup", setup)
+}
+
+// call places a single test call and reports its result and setup time.
+func (t *testCaller) call(ctx context.Context) (stats.TestCallResult, time.Duration, error) {
+	var bye core.Fuse
+	onBye := func() { bye.Break() }
+	cli, err := siptest.NewClient(guid.New("STC_"), siptest.ClientConfig{
+		IP:             t.ip,
+		Number:         t.conf.From,
+		AuthUser:       t.conf.Username,
+		AuthPass:       t.conf.Password,
+		Codec:          t.conf.Codec,
+		Log:            slog.New(logger.ToSlogHandler(t.log)),
+		OnBye:          onBye,
+		OnMediaTimeout: onBye,
+	})
+	if err != nil {
+		return stats.TestCallSetupFailed, 0, err
+	}
+	defer cli.Close()
+
+	host := t.conf.Address
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	start := time.Now()
+	if err = cli.Dial(t.conf.Address, host, t.conf.Number, t.conf.Headers); err != nil {
+		return stats.TestCallSetupFailed, 0, err
+	}
+	setup := time.Since(start)
+
+	ctx, cancel := context.WithTimeout(ctx, t.conf.Timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-bye.Watch():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	go func() {
+		_ = cli.SendSignal(ctx, -1, testCallTone)
+	}()
+	if err = cli.WaitSignals(ctx, []int{testCallTone}, nil); err != nil {
+		if bye.IsBroken() {
+			err = fmt.Errorf("call ended before the test tone was received: %w", err)
+		}
+		return stats.TestCallNoAudio, setup, err
+	}
+	return stats.TestCallPass, setup, nil
+}
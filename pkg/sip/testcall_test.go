@@ -0,0 +1,57 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestNewTestCallers(t *testing.T) {
+	conf := &config.Config{
+		SIPPortListen: 5080,
+		TestCalls: []config.TestCallConfig{
+			{Name: "local", Number: "100"},
+			{Name: "trunk", Number: "200", Address: "sip.example.com:5061", From: "300", Interval: time.Minute, Timeout: time.Second},
+		},
+	}
+	sconf := &ServiceConfig{
+		SignalingIP:      netip.MustParseAddr("1.2.3.4"),
+		SignalingIPLocal: netip.MustParseAddr("10.0.0.1"),
+	}
+	list := newTestCallers(conf, sconf, logger.GetLogger(), nil)
+	require.Len(t, list, 2)
+
+	local := list[0]
+	require.Equal(t, "10.0.0.1:5080", local.conf.Address)
+	require.Equal(t, sconf.SignalingIPLocal, local.ip)
+	require.Equal(t, testCallFrom, local.conf.From)
+	require.Equal(t, config.DefaultTestCallInterval, local.conf.Interval)
+	require.Equal(t, config.DefaultTestCallTimeout, local.conf.Timeout)
+
+	trunk := list[1]
+	require.Equal(t, "sip.example.com:5061", trunk.conf.Address)
+	require.Equal(t, sconf.SignalingIP, trunk.ip)
+	require.Equal(t, "300", trunk.conf.From)
+	require.Equal(t, time.Minute, trunk.conf.Interval)
+	require.Equal(t, time.Second, trunk.conf.Timeout)
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/livekit/sipgo/sip"
+	"github.com/livekit/sipgo/transaction"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// errTimerExpired is returned by client transactions when Timer B or Timer F of the trunk expires.
+// Timeouts of the transaction layer only match transaction.ErrTimeout.
+var errTimerExpired = fmt.Errorf("sip timer expired: %w", transaction.ErrTimeout)
+
+// sipTimers are transaction timers of requests sent on a trunk.
+//
+// Timers of the transaction layer are fixed, thus these are enforced on top of it:
+// shorter timers end the transaction early, and a longer Timer B sends the INVITE again.
+type sipTimers struct {
+	TimerB time.Duration
+	TimerF time.Duration
+}
+
+func newSIPTimers(conf config.SIPTimersConfig) sipTimers {
+	t1 := conf.T1
+	if t1 <= 0 {
+		t1 = transaction.T1
+	}
+	t := sipTimers{TimerB: conf.TimerB, TimerF: conf.TimerF}
+	if t.TimerB <= 0 {
+		t.TimerB = 64 * t1
+	}
+	if t.TimerF <= 0 {
+		t.TimerF = 64 * t1
+	}
+	// Requests other than INVITE are not sent again, since they may not be idempotent.
+	t.TimerF = min(t.TimerF, transaction.Timer_F)
+	return t
+}
+
+// timers returns transaction timers of a trunk. An empty trunk ID selects the global config.
+func (s *Server) timers(trunkID string) sipTimers {
+	return newSIPTimers(s.conf.TrunkSIPTimers(trunkID))
+}
+
+// timers returns transaction timers of a trunk. An empty trunk ID selects the global config.
+func (c *Client) timers(trunkID string) sipTimers {
+	return newSIPTimers(c.conf.TrunkSIPTimers(trunkID))
+}
+
+// ClientTx applies Timer B to an INVITE transaction, or Timer F to other client transactions.
+func (t sipTimers) ClientTx(h *dialogHistory, req *sip.Request, tx sip.ClientTransaction) sip.ClientTransaction {
+	if req.IsInvite() {
+		return newTimerClientTx(h, tx, timerB, t.TimerB)
+	}
+	return newTimerClientTx(h, tx, timerF, t.TimerF)
+}
+
+// timerClientTx is a client transaction which fails with errTimerExpired if it gets no response in time.
+// Timer B is stopped by any response, Timer F only by a final response.
+// The timer is reported in the dialog history while it's running.
+type timerClientTx struct {
+	sip.ClientTransaction
+	h       *dialogHistory
+	name    string
+	timer   *time.Timer
+	expired chan struct{}
+	done    chan struct{}
+}
+
+func newTimerClientTx(h *dialogHistory, tx sip.ClientTransaction, name string, dur time.Duration) *timerClientTx {
+	t := &timerClientTx{
+		ClientTransaction: tx,
+		h:                 h,
+		name:              name,
+		expired:           make(chan struct{}),
+		done:              make(chan struct{}),
+	}
+	h.SetTimer(name, time.Now().Add(dur))
+	t.timer = time.AfterFunc(dur, func() {
+		h.ClearTimer(name)
+		close(t.expired)
+		tx.Terminate()
+	})
+	go func() {
+		defer close(t.done)
+		select {
+		case <-tx.Done():
+			t.stop()
+		case <-t.expired:
+		}
+	}()
+	return t
+}
+
+func (t *timerClientTx) stop() {
+	if t.timer.Stop() {
+		t.h.ClearTimer(t.name)
+	}
+}
+
+func (t *timerClientTx) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *timerClientTx) Err() error {
+	select {
+	case <-t.expired:
+		return fmt.Errorf("%s: %w", t.name, errTimerExpired)
+	default:
+	}
+	return t.ClientTransaction.Err()
+}
+
+func (t *timerClientTx) recordResponse(res *sip.Response) {
+	if r, ok := t.ClientTransaction.(responseRecorder); ok {
+		r.recordResponse(res)
+	}
+	if t.name == timerB || !res.IsProvisional() {
+		t.stop()
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/sipgo/sip"
+	"github.com/livekit/sipgo/transaction"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+// testClientTx is a client transaction which only completes when terminated.
+type testClientTx struct {
+	resp chan *sip.Response
+	done chan struct{}
+	once sync.Once
+}
+
+func newTestClientTx() *testClientTx {
+	return &testClientTx{resp: make(chan *sip.Response, 1), done: make(chan struct{})}
+}
+
+func (tx *testClientTx) Terminate()                      { tx.once.Do(func() { close(tx.done) }) }
+func (tx *testClientTx) Done() <-chan struct{}           { return tx.done }
+func (tx *testClientTx) Err() error                      { return nil }
+func (tx *testClientTx) Responses() <-chan *sip.Response { return tx.resp }
+func (tx *testClientTx) Cancel() error                   { return nil }
+
+func TestSIPTimers(t *testing.T) {
+	require.Equal(t, sipTimers{TimerB: 32 * time.Second, TimerF: 32 * time.Second}, newSIPTimers(config.SIPTimersConfig{}))
+
+	conf := &config.Config{
+		SIPTimers: config.SIPTimersConfig{TimerF: 10 * time.Second},
+		Trunks: map[string]*config.TrunkConfig{
+			"slow": {SIPTimers: &config.SIPTimersConfig{T1: 2 * time.Second}},
+			"fast": {SIPTimers: &config.SIPTimersConfig{TimerB: 4 * time.Second}},
+		},
+	}
+	require.Equal(t, sipTimers{TimerB: 32 * time.Second, TimerF: 10 * time.Second}, newSIPTimers(conf.TrunkSIPTimers("")))
+	// Timer F is explicitly set in the global config.
+	require.Equal(t, sipTimers{TimerB: 128 * time.Second, TimerF: 10 * time.Second}, newSIPTimers(conf.TrunkSIPTimers("slow")))
+	require.Equal(t, sipTimers{TimerB: 4 * time.Second, TimerF: 10 * time.Second}, newSIPTimers(conf.TrunkSIPTimers("fast")))
+	// Timer F is capped by the transaction layer.
+	require.Equal(t, transaction.Timer_F, newSIPTimers(config.SIPTimersConfig{T1: 2 * time.Second}).TimerF)
+
+	for _, c := range []config.SIPTimersConfig{
+		{},
+		{T1: time.Second},
+		{TimerB: time.Minute, TimerF: 5 * time.Second},
+	} {
+		require.NoError(t, c.Validate(), "%+v", c)
+	}
+	for _, c := range []config.SIPTimersConfig{
+		{T1: -time.Second},
+		{T1: time.Millisecond},
+		{TimerB: -time.Second},
+		{T1: time.Second, TimerF: 500 * time.Millisecond},
+	} {
+		require.Error(t, c.Validate(), "%+v", c)
+	}
+}
+
+func TestTimerClientTx(t *testing.T) {
+	req := sip.NewRequest(sip.INVITE, sip.Uri{User: "callee", Host: "example.com"})
+	timers := sipTimers{TimerB: 50 * time.Millisecond, TimerF: 50 * time.Millisecond}
+
+	t.Run("expired", func(t *testing.T) {
+		h := newDialogHistory()
+		inner := newTestClientTx()
+		tx := timers.ClientTx(h, req, inner)
+		require.Len(t, h.Timers(), 1)
+		require.Equal(t, timerB, h.Timers()[0].Name)
+
+		_, err := sipResponse(context.Background(), tx, nil, nil)
+		require.ErrorIs(t, err, errTimerExpired)
+		require.ErrorIs(t, err, transaction.ErrTimeout)
+		var e *livekit.SIPStatus
+		require.True(t, errors.As(err, &e))
+		require.Equal(t, livekit.SIPStatusCode_SIP_STATUS_REQUEST_TIMEOUT, e.Code)
+		require.Empty(t, h.Timers())
+		// Expired transaction is terminated in the transaction layer.
+		<-inner.Done()
+	})
+
+	t.Run("provisional", func(t *testing.T) {
+		h := newDialogHistory()
+		inner := newTestClientTx()
+		tx := timers.ClientTx(h, req, inner)
+		inner.resp <- sip.NewResponse(sip.StatusRinging, "Ringing")
+		go func() {
+			time.Sleep(2 * timers.TimerB)
+			inner.resp <- sip.NewResponse(sip.StatusOK, "OK")
+		}()
+		// Timer B is stopped by the first response.
+		resp, err := sipResponse(context.Background(), tx, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, sip.StatusOK, resp.StatusCode)
+		require.Empty(t, h.Timers())
+		inner.Terminate()
+		<-tx.Done()
+		require.NoError(t, tx.Err())
+	})
+
+	t.Run("final", func(t *testing.T) {
+		bye := sip.NewRequest(sip.BYE, sip.Uri{User: "callee", Host: "example.com"})
+		h := newDialogHistory()
+		inner := newTestClientTx()
+		tx := timers.ClientTx(h, bye, inner)
+		require.Equal(t, timerF, h.Timers()[0].Name)
+		// Timer F is only stopped by a final response.
+		inner.resp <- sip.NewResponse(sip.StatusTrying, "Trying")
+		_, err := sipResponse(context.Background(), tx, nil, nil)
+		require.ErrorIs(t, err, errTimerExpired)
+	})
+}
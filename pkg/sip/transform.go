@@ -0,0 +1,205 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"fmt"
+	"strings"
+)
+
+// valueMapping is a target of a header-to-attribute or attribute-to-header mapping,
+// optionally followed by a pipeline of transforms, separated by "|". For example:
+//
+//	sip.phoneNumber | uri_user | e164
+//
+// Supported transforms:
+//
+//	lower, upper, trim    - change case or trim spaces
+//	uri_user              - user part of a SIP/TEL URI or name-addr (`"Bob" <sip:+1555@x>` -> `+1555`)
+//	display_name          - display name of a name-addr (`"Bob" <sip:+1555@x>` -> `Bob`)
+//	e164[:cc]             - normalize a phone number to E.164, adding country code cc to numbers without one
+//	strip_prefix:p        - remove prefix p, if present
+//	prefix:p, suffix:s    - add a literal prefix or suffix
+//	concat:name[:sep]     - append a value of another header (or attribute), optionally separated by sep
+//
+// A target without transforms maps the value as-is.
+type valueMapping struct {
+	Name  string
+	funcs []valueTransform
+}
+
+// valueLookup returns a value of another header or attribute, used by concat.
+type valueLookup func(name string) (string, bool)
+
+type valueTransform func(v string, lookup valueLookup) string
+
+func parseValueMapping(spec string) (*valueMapping, error) {
+	name, pipeline, hasPipeline := strings.Cut(spec, "|")
+	m := &valueMapping{Name: strings.TrimSpace(name)}
+	if m.Name == "" {
+		return nil, fmt.Errorf("empty mapping target in %q", spec)
+	}
+	if hasPipeline {
+		funcs, err := parseValueTransforms(pipeline)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mapping %q: %w", spec, err)
+		}
+		m.funcs = funcs
+	}
+	return m, nil
+}
+
+// parseValueTransforms parses a pipeline of transforms separated by "|", without a mapping target.
+func parseValueTransforms(pipeline string) ([]valueTransform, error) {
+	var funcs []valueTransform
+	for _, p := range strings.Split(pipeline, "|") {
+		fnc, err := parseValueTransform(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		funcs = append(funcs, fnc)
+	}
+	return funcs, nil
+}
+
+func parseValueTransform(s string) (valueTransform, error) {
+	name, arg, hasArg := strings.Cut(s, ":")
+	noArg := func(fnc func(v string) string) (valueTransform, error) {
+		if hasArg {
+			return nil, fmt.Errorf("transform %q has no arguments", name)
+		}
+		return func(v string, _ valueLookup) string { return fnc(v) }, nil
+	}
+	withArg := func(fnc func(v, arg string) string) (valueTransform, error) {
+		if arg == "" {
+			return nil, fmt.Errorf("transform %q requires an argument", name)
+		}
+		return func(v string, _ valueLookup) string { return fnc(v, arg) }, nil
+	}
+	switch name {
+	case "lower":
+		return noArg(strings.ToLower)
+	case "upper":
+		return noArg(strings.ToUpper)
+	case "trim":
+		return noArg(strings.TrimSpace)
+	case "uri_user":
+		return noArg(uriUser)
+	case "display_name":
+		return noArg(displayName)
+	case "e164":
+		return func(v string, _ valueLookup) string { return normalizeE164(v, arg) }, nil
+	case "strip_prefix":
+		return withArg(strings.TrimPrefix)
+	case "prefix":
+		return withArg(func(v, p string) string { return p + v })
+	case "suffix":
+		return withArg(func(v, s string) string { return v + s })
+	case "concat":
+		other, sep, _ := strings.Cut(arg, ":")
+		if other == "" {
+			return nil, fmt.Errorf("transform %q requires an argument", name)
+		}
+		return func(v string, lookup valueLookup) string {
+			if lookup == nil {
+				return v
+			}
+			ov, ok := lookup(other)
+			if !ok || ov == "" {
+				return v
+			}
+			if v == "" {
+				return ov
+			}
+			return v + sep + ov
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown transform %q", name)
+}
+
+// Apply runs all transforms on the value.
+func (m *valueMapping) Apply(v string, lookup valueLookup) string {
+	for _, fnc := range m.funcs {
+		v = fnc(v, lookup)
+	}
+	return v
+}
+
+// nameAddrURI returns the URI part of a name-addr or addr-spec, without header parameters.
+func nameAddrURI(v string) string {
+	v = strings.TrimSpace(v)
+	if i := strings.IndexByte(v, '<'); i >= 0 {
+		v = v[i+1:]
+		if j := strings.IndexByte(v, '>'); j >= 0 {
+			v = v[:j]
+		}
+		return v
+	}
+	// Parameters of addr-spec without angle brackets belong to the header, not the URI.
+	v, _, _ = strings.Cut(v, ";")
+	return v
+}
+
+func uriUser(v string) string {
+	u := nameAddrURI(v)
+	if scheme, rest, ok := strings.Cut(u, ":"); ok {
+		switch strings.ToLower(scheme) {
+		case "sip", "sips", "tel":
+			u = rest
+		}
+	}
+	u, _, _ = strings.Cut(u, "@")
+	u, _, _ = strings.Cut(u, ";")
+	return u
+}
+
+func displayName(v string) string {
+	v = strings.TrimSpace(v)
+	i := strings.IndexByte(v, '<')
+	if i < 0 {
+		return ""
+	}
+	name := strings.TrimSpace(v[:i])
+	if len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"' {
+		name = strings.ReplaceAll(name[1:len(name)-1], `\"`, `"`)
+	}
+	return name
+}
+
+// normalizeE164 converts a phone number to E.164 form, removing visual separators.
+// Numbers with "+" or international "00" prefix are kept as-is. Other numbers get country code cc,
+// unless they already start with it; a national trunk prefix "0" is dropped in that case.
+// Values that have no digits are returned unchanged.
+func normalizeE164(v, cc string) string {
+	v = strings.TrimSpace(v)
+	intl := strings.HasPrefix(v, "+")
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, v)
+	if digits == "" {
+		return v
+	}
+	switch {
+	case intl:
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case cc != "" && !strings.HasPrefix(digits, cc):
+		digits = cc + strings.TrimPrefix(digits, "0")
+	}
+	return "+" + digits
+}
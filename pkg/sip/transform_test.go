@@ -0,0 +1,103 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/sipgo/sip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueMapping(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "X-Ext" {
+			return "42", true
+		}
+		return "", false
+	}
+	cases := []struct {
+		spec string
+		in   string
+		name string
+		out  string
+		err  bool
+	}{
+		{spec: "attr", in: "Value", name: "attr", out: "Value"},
+		{spec: " attr | lower ", in: "Value", name: "attr", out: "value"},
+		{spec: "attr|upper", in: "Value", name: "attr", out: "VALUE"},
+		{spec: "attr|trim", in: "  v ", name: "attr", out: "v"},
+		{spec: "attr|uri_user", in: `"Bob" <sip:+15551234@x>;tag=1`, name: "attr", out: "+15551234"},
+		{spec: "attr|uri_user", in: `<tel:+15551234;phone-context=x>`, name: "attr", out: "+15551234"},
+		{spec: "attr|uri_user", in: `sip:bob@x;transport=tcp`, name: "attr", out: "bob"},
+		{spec: "attr|display_name", in: `"Bob \"B\"" <sip:+15551234@x>`, name: "attr", out: `Bob "B"`},
+		{spec: "attr|display_name", in: `sip:bob@x`, name: "attr", out: ""},
+		{spec: "attr|e164", in: "+1 (555) 123-4567", name: "attr", out: "+15551234567"},
+		{spec: "attr|e164", in: "0044 20 1234", name: "attr", out: "+44201234"},
+		{spec: "attr|e164:1", in: "555-123-4567", name: "attr", out: "+15551234567"},
+		{spec: "attr|e164:1", in: "15551234567", name: "attr", out: "+15551234567"},
+		{spec: "attr|e164:44", in: "020 1234", name: "attr", out: "+44201234"},
+		{spec: "attr|e164", in: "anonymous", name: "attr", out: "anonymous"},
+		{spec: "attr|strip_prefix:tel:", in: "tel:123", name: "attr", out: "123"},
+		{spec: "attr|prefix:+1|suffix:#", in: "555", name: "attr", out: "+1555#"},
+		{spec: "attr|concat:X-Ext:;ext=", in: "+1555", name: "attr", out: "+1555;ext=42"},
+		{spec: "attr|concat:X-Missing", in: "+1555", name: "attr", out: "+1555"},
+		{spec: "sip.phoneNumber | uri_user | e164", in: `"Bob" <sip:15551234@x>`, name: "sip.phoneNumber", out: "+15551234"},
+		{spec: "", err: true},
+		{spec: "attr|unknown", err: true},
+		{spec: "attr|lower:x", err: true},
+		{spec: "attr|prefix", err: true},
+		{spec: "attr|concat", err: true},
+	}
+	for _, c := range cases {
+		t.Run(c.spec, func(t *testing.T) {
+			m, err := parseValueMapping(c.spec)
+			if c.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.name, m.Name)
+			require.Equal(t, c.out, m.Apply(c.in, lookup))
+		})
+	}
+}
+
+func TestHeadersToAttrsTransform(t *testing.T) {
+	headers := Headers{
+		sip.NewHeader("P-Asserted-Identity", `"Bob" <sip:+1 555 1234@example.com>`),
+		sip.NewHeader("X-Region", "US-East"),
+	}
+	attrs := HeadersToAttrs(nil, map[string]string{
+		"P-Asserted-Identity": livekit.AttrSIPPhoneNumber + " | uri_user | e164",
+		"X-Region":            "region|lower",
+		"X-Missing":           "missing",
+		"X-Invalid":           "invalid|unknown",
+	}, livekit.SIPHeaderOptions_SIP_NO_HEADERS, nil, headers)
+	require.Equal(t, map[string]string{
+		livekit.AttrSIPPhoneNumber: "+15551234",
+		"region":                   "us-east",
+	}, attrs)
+
+	hdrs := AttrsToHeaders(attrs, map[string]string{
+		livekit.AttrSIPPhoneNumber: "X-Caller | prefix:tel: | concat:region:;region=",
+	}, nil)
+	require.Equal(t, map[string]string{
+		"X-Caller": "tel:+15551234;region=us-east",
+	}, hdrs)
+
+	require.Len(t, MappingErrors(map[string]string{"a": "b|lower", "c": "d|bad"}), 1)
+}
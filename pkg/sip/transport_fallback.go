@@ -0,0 +1,106 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// udpMaxRequestSize is the largest request that is sent over UDP without a path MTU estimate (RFC 3261, section 18.1.1).
+	udpMaxRequestSize = 1300
+	// inviteHeadersSize is an estimate of INVITE headers size, excluding the SDP offer.
+	inviteHeadersSize = 800
+	// transportCacheTTL is how long a transport that worked for a destination is preferred.
+	transportCacheTTL = time.Hour
+)
+
+// errTransportFailed is returned when the request got no response over a transport and the next one can be tried.
+var errTransportFailed = errors.New("sip transport failed")
+
+// transportCandidates returns transports to try for a request of a given size, in order.
+//
+// An explicit TCP or TLS transport is always used as-is. Requests that don't fit into a UDP datagram
+// are sent over TCP first, falling back to UDP if the connection cannot be established.
+// If the transport is not set, all transports are tried, starting from the one that worked last time.
+func transportCandidates(tr Transport, size int, last Transport, tls bool) []Transport {
+	large := size > udpMaxRequestSize
+	switch tr {
+	case TransportTCP, TransportTLS:
+		return []Transport{tr}
+	case TransportUDP:
+		if large {
+			return []Transport{TransportTCP, TransportUDP}
+		}
+		return []Transport{TransportUDP}
+	}
+	list := []Transport{TransportUDP, TransportTCP}
+	if large {
+		list = []Transport{TransportTCP, TransportUDP}
+	}
+	if tls {
+		list = append(list, TransportTLS)
+	}
+	if i := slices.Index(list, last); i > 0 {
+		list = slices.Delete(list, i, i+1)
+		list = slices.Insert(list, 0, last)
+	}
+	return list
+}
+
+type transportCacheEntry struct {
+	tr      Transport
+	expires time.Time
+}
+
+// transportCache remembers which transport worked for each destination host.
+type transportCache struct {
+	mu    sync.Mutex
+	hosts map[string]transportCacheEntry
+}
+
+func (c *transportCache) Get(host string, now time.Time) Transport {
+	host = strings.ToLower(host)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.hosts[host]
+	if !ok {
+		return ""
+	}
+	if now.After(e.expires) {
+		delete(c.hosts, host)
+		return ""
+	}
+	return e.tr
+}
+
+func (c *transportCache) Set(host string, tr Transport, now time.Time) {
+	host = strings.ToLower(host)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hosts == nil {
+		c.hosts = make(map[string]transportCacheEntry)
+	}
+	for h, e := range c.hosts {
+		if now.After(e.expires) {
+			delete(c.hosts, h)
+		}
+	}
+	c.hosts[host] = transportCacheEntry{tr: tr, expires: now.Add(transportCacheTTL)}
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportCandidates(t *testing.T) {
+	const (
+		small = 1000
+		large = 2000
+	)
+	cases := []struct {
+		name string
+		tr   Transport
+		size int
+		last Transport
+		tls  bool
+		exp  []Transport
+	}{
+		{name: "tcp", tr: TransportTCP, size: large, last: TransportUDP, exp: []Transport{TransportTCP}},
+		{name: "tls", tr: TransportTLS, size: small, exp: []Transport{TransportTLS}},
+		{name: "udp", tr: TransportUDP, size: small, last: TransportTCP, exp: []Transport{TransportUDP}},
+		{name: "udp large", tr: TransportUDP, size: large, exp: []Transport{TransportTCP, TransportUDP}},
+		{name: "auto", size: small, exp: []Transport{TransportUDP, TransportTCP}},
+		{name: "auto tls", size: small, tls: true, exp: []Transport{TransportUDP, TransportTCP, TransportTLS}},
+		{name: "auto large", size: large, tls: true, exp: []Transport{TransportTCP, TransportUDP, TransportTLS}},
+		{name: "auto last", size: small, last: TransportTLS, tls: true, exp: []Transport{TransportTLS, TransportUDP, TransportTCP}},
+		{name: "auto last no tls", size: small, last: TransportTLS, exp: []Transport{TransportUDP, TransportTCP}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.exp, transportCandidates(c.tr, c.size, c.last, c.tls))
+		})
+	}
+}
+
+func TestTransportCache(t *testing.T) {
+	var c transportCache
+	now := time.Now()
+	require.Equal(t, Transport(""), c.Get("sip.example.com", now))
+	c.Set("SIP.example.com", TransportTCP, now)
+	require.Equal(t, TransportTCP, c.Get("sip.example.com", now.Add(time.Minute)))
+	require.Equal(t, Transport(""), c.Get("sip.example.com", now.Add(2*transportCacheTTL)))
+}
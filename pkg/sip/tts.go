@@ -0,0 +1,150 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/res"
+)
+
+const (
+	defaultTTSTimeout = 5 * time.Second
+	// maxTTSResponse limits the size of synthesized audio.
+	maxTTSResponse = 16 << 20
+	// maxTTSCache limits the number of cached texts.
+	maxTTSCache = 64
+)
+
+// VoiceOptions select the voice of synthesized speech. Empty values use defaults of the TTS service.
+type VoiceOptions struct {
+	Voice    string  `json:"voice,omitempty"`
+	Language string  `json:"language,omitempty"`
+	Speed    float64 `json:"speed,omitempty"`
+}
+
+// TTS synthesizes speech for announcements and texts spoken to calls.
+type TTS interface {
+	// Synthesize returns 20ms frames of mono audio with res.SampleRate.
+	Synthesize(ctx context.Context, text string, opts VoiceOptions) ([]msdk.PCM16Sample, error)
+}
+
+type ttsKey struct {
+	text string
+	opts VoiceOptions
+}
+
+// ttsSynthesizer renders texts with an HTTP service. Recent results are cached,
+// since announcements and prompts are usually repeated.
+type ttsSynthesizer struct {
+	conf    *config.TTSConfig
+	cli     *http.Client
+	timeout time.Duration
+
+	mu    sync.Mutex
+	cache map[ttsKey][]msdk.PCM16Sample
+}
+
+// newTTSSynthesizer creates a synthesizer for the configured service. It returns nil if TTS is not configured.
+func newTTSSynthesizer(conf *config.TTSConfig) *ttsSynthesizer {
+	if conf == nil || conf.URL == "" {
+		return nil
+	}
+	t := &ttsSynthesizer{
+		conf:    conf,
+		cli:     &http.Client{},
+		timeout: defaultTTSTimeout,
+		cache:   make(map[ttsKey][]msdk.PCM16Sample),
+	}
+	if conf.Timeout > 0 {
+		t.timeout = conf.Timeout
+	}
+	return t
+}
+
+// Synthesize returns audio frames for the text.
+func (t *ttsSynthesizer) Synthesize(ctx context.Context, text string, opts VoiceOptions) ([]msdk.PCM16Sample, error) {
+	if t == nil {
+		return nil, fmt.Errorf("tts is not configured")
+	}
+	key := ttsKey{text: text, opts: opts}
+	t.mu.Lock()
+	frames, ok := t.cache[key]
+	t.mu.Unlock()
+	if ok {
+		return frames, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	frames, err := t.request(ctx, text, opts)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	if len(t.cache) >= maxTTSCache {
+		for k := range t.cache {
+			delete(t.cache, k)
+			break
+		}
+	}
+	t.cache[key] = frames
+	t.mu.Unlock()
+	return frames, nil
+}
+
+func (t *ttsSynthesizer) request(ctx context.Context, text string, opts VoiceOptions) ([]msdk.PCM16Sample, error) {
+	body, err := json.Marshal(struct {
+		Text string `json:"text"`
+		VoiceOptions
+	}{Text: text, VoiceOptions: opts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.conf.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTTSResponse))
+	if err != nil {
+		return nil, err
+	}
+	frames, err := res.DecodeOggAudio(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode synthesized audio: %w", err)
+	}
+	return frames, nil
+}
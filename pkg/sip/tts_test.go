@@ -0,0 +1,77 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/res"
+)
+
+func TestTTSSynthesizer(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		var body struct {
+			Text  string `json:"text"`
+			Voice string `json:"voice"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.Text == "voice" {
+			require.Equal(t, "alloy", body.Voice)
+		}
+		if body.Text == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(res.EnterPinOgg)
+	}))
+	defer srv.Close()
+
+	tts := newTTSSynthesizer(&config.TTSConfig{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+	frames, err := tts.Synthesize(context.Background(), "We are closed", VoiceOptions{})
+	require.NoError(t, err)
+	require.NotEmpty(t, frames)
+
+	// Cached.
+	frames2, err := tts.Synthesize(context.Background(), "We are closed", VoiceOptions{})
+	require.NoError(t, err)
+	require.Equal(t, len(frames), len(frames2))
+	require.EqualValues(t, 1, requests.Load())
+
+	// Voice options are sent and cached separately.
+	_, err = tts.Synthesize(context.Background(), "voice", VoiceOptions{Voice: "alloy"})
+	require.NoError(t, err)
+	_, err = tts.Synthesize(context.Background(), "voice", VoiceOptions{Voice: "alloy", Speed: 1.5})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, requests.Load())
+
+	_, err = tts.Synthesize(context.Background(), "fail", VoiceOptions{})
+	require.Error(t, err)
+
+	var none *ttsSynthesizer
+	_, err = none.Synthesize(context.Background(), "text", VoiceOptions{})
+	require.Error(t, err)
+}
@@ -40,6 +40,13 @@ func (h Headers) GetHeader(name string) sip.Header {
 	return nil
 }
 
+func (h Headers) lookup(name string) (string, bool) {
+	if v := h.GetHeader(name); v != nil {
+		return v.Value(), true
+	}
+	return "", false
+}
+
 func TransportFrom(t livekit.SIPTransport) Transport {
 	switch t {
 	case livekit.SIPTransport_SIP_TRANSPORT_UDP:
@@ -160,16 +167,10 @@ func (u URI) GetURI() *sip.Uri {
 	return su
 }
 
+// GetContactURI returns the URI for a Contact header. The hostname is only used if it's advertised for the transport,
+// see advertisedHost.
 func (u URI) GetContactURI() *sip.Uri {
-	su := u.GetURI()
-	switch u.Transport {
-	case TransportUDP, TransportTCP:
-		// Use IP instead of a hostname for TCP and UDP.
-		if addr := u.Addr.Addr(); addr.IsValid() {
-			su.Host = addr.String()
-		}
-	}
-	return su
+	return u.GetURI()
 }
 
 func (u URI) ToSIPUri() *livekit.SIPUri {
@@ -241,7 +242,7 @@ func LoggerWithHeaders(log logger.Logger, c Signaling) logger.Logger {
 	headers := c.RemoteHeaders()
 	for hdr, name := range headerToLog {
 		if h := headers.GetHeader(hdr); h != nil {
-			log = log.WithValues(name, h.Value())
+			log = log.WithValues(name, sanitizeValue(h.Value()))
 		}
 	}
 	return log
@@ -270,20 +271,27 @@ func HeadersToAttrs(attrs, hdrToAttr map[string]string, opts livekit.SIPHeaderOp
 					continue
 				}
 			}
-			attrs[livekit.AttrSIPHeaderPrefix+name] = h.Value()
+			attrs[livekit.AttrSIPHeaderPrefix+name] = sanitizeValue(h.Value())
 		}
 	}
 	// Global header mapping
 	for hdr, name := range headerToAttr {
 		if h := headers.GetHeader(hdr); h != nil {
-			attrs[name] = h.Value()
+			attrs[name] = sanitizeValue(h.Value())
 		}
 	}
-	// Request mapping
-	for hdr, name := range hdrToAttr {
-		if h := headers.GetHeader(hdr); h != nil {
-			attrs[name] = h.Value()
+	// Request mapping, with optional transforms
+	for hdr, spec := range hdrToAttr {
+		h := headers.GetHeader(hdr)
+		if h == nil {
+			continue
+		}
+		m, err := parseValueMapping(spec)
+		if err != nil {
+			logger.Warnw("invalid header to attribute mapping", err, "header", hdr)
+			continue
 		}
+		attrs[m.Name] = sanitizeValue(m.Apply(h.Value(), headers.lookup))
 	}
 	if c != nil {
 		// Other metadata
@@ -304,16 +312,38 @@ func AttrsToHeaders(attrs, attrToHdr, headers map[string]string) map[string]stri
 	if headers == nil {
 		headers = make(map[string]string)
 	}
-	for attr, hdr := range attrToHdr {
+	lookup := func(name string) (string, bool) {
+		v, ok := attrs[name]
+		return v, ok
+	}
+	for attr, spec := range attrToHdr {
 		val, ok := attrs[attr]
 		if !ok {
 			continue
 		}
-		headers[hdr] = val
+		m, err := parseValueMapping(spec)
+		if err != nil {
+			logger.Warnw("invalid attribute to header mapping", err, "attribute", attr)
+			continue
+		}
+		headers[m.Name] = m.Apply(val, lookup)
 	}
 	return headers
 }
 
+// MappingErrors validates header/attribute mappings and returns errors for invalid ones.
+func MappingErrors(mappings ...map[string]string) []error {
+	var errs []error
+	for _, mp := range mappings {
+		for _, spec := range mp {
+			if _, err := parseValueMapping(spec); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
 func sdpEncryption(e livekit.SIPMediaEncryption) (sdp.Encryption, error) {
 	switch e {
 	case livekit.SIPMediaEncryption_SIP_MEDIA_ENCRYPT_DISABLE:
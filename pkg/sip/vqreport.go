@@ -0,0 +1,199 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/livekit/media-sdk/rtp"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/sipgo/sip"
+
+	"github.com/livekit/sip/pkg/config"
+	"github.com/livekit/sip/pkg/stats"
+)
+
+const vqReportTimeout = 5 * time.Second
+
+// vqReport is a call quality report sent in SIP PUBLISH with vq-rtcpxr event (RFC 6035).
+type vqReport struct {
+	CallID     string
+	LocalID    string
+	RemoteID   string
+	OrigID     string
+	LocalAddr  netip.AddrPort
+	RemoteAddr netip.AddrPort
+	Start      time.Time
+	Stop       time.Time
+
+	PayloadType  byte
+	Codec        string
+	SampleRate   int
+	JitterBuffer bool
+	Metrics      VoIPMetrics
+}
+
+// newVQReport collects a quality report for the call. It returns nil if media was never established.
+func newVQReport(media *MediaPort, sig Signaling, dir stats.CallDir) *vqReport {
+	if media == nil {
+		return nil
+	}
+	conf := media.Config()
+	if conf == nil {
+		return nil
+	}
+	from, to := sig.From(), sig.To()
+	local, remote := &to, &from
+	if dir == stats.Outbound {
+		local, remote = remote, local
+	}
+	info := conf.Audio.Codec.Info()
+	r := &vqReport{
+		CallID:       sig.CallID(),
+		LocalID:      local.String(),
+		RemoteID:     remote.String(),
+		OrigID:       from.String(),
+		LocalAddr:    netip.AddrPortFrom(media.externalIP, uint16(media.Port())),
+		RemoteAddr:   conf.Remote,
+		Start:        media.Started(),
+		Stop:         time.Now(),
+		PayloadType:  conf.Audio.Type,
+		Codec:        info.SDPName,
+		SampleRate:   info.RTPClockRate,
+		JitterBuffer: media.JitterBufferEnabled(),
+		Metrics:      media.VoIPMetrics(),
+	}
+	if r.Start.IsZero() {
+		r.Start = r.Stop
+	}
+	return r
+}
+
+// String formats the report as application/vq-rtcpxr body.
+func (r *vqReport) String() string {
+	const ts = "2006-01-02T15:04:05Z"
+	m := &r.Metrics
+	codec, _, _ := strings.Cut(r.Codec, "/")
+	jba := 0
+	if r.JitterBuffer {
+		jba = 3
+	}
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString("\r\n")
+	}
+	line("VQSessionReport: CallTerm")
+	line("CallID: %s", r.CallID)
+	line("LocalID: <%s>", r.LocalID)
+	line("RemoteID: <%s>", r.RemoteID)
+	line("OrigID: <%s>", r.OrigID)
+	line("LocalAddr: IP=%s PORT=%d", r.LocalAddr.Addr(), r.LocalAddr.Port())
+	line("RemoteAddr: IP=%s PORT=%d SSRC=0x%08X", r.RemoteAddr.Addr(), r.RemoteAddr.Port(), m.SSRC)
+	line("LocalMetrics:")
+	line("Timestamps:START=%s STOP=%s", r.Start.UTC().Format(ts), r.Stop.UTC().Format(ts))
+	line("SessionDesc:PT=%d PD=%s SR=%d FD=%d", r.PayloadType, codec, r.SampleRate, rtp.DefFrameDur.Milliseconds())
+	line("JitterBuffer:JBA=%d", jba)
+	line("PacketLoss:NLR=%.1f JDR=%.1f", m.LossRate*100, m.DiscardRate*100)
+	line("BurstGapLoss:BLD=%.1f BD=%d GLD=%.1f GD=%d GMIN=%d",
+		m.BurstDensity*100, m.BurstDuration.Milliseconds(),
+		m.GapDensity*100, m.GapDuration.Milliseconds(), rtcpXRGmin)
+	line("Delay:IAJ=%d", m.Jitter.Milliseconds())
+	// MOSCQ is omitted, since delay is not measured.
+	if m.MOS > 0 {
+		line("QualityEst:RLQ=%.0f MOSLQ=%.1f", m.RFactor, m.MOS)
+	}
+	return b.String()
+}
+
+// vqReporter sends call quality reports to a collector at the end of each call.
+type vqReporter struct {
+	conf *config.VQReportConfig
+	cli  *Client
+}
+
+func newVQReporter(conf *config.VQReportConfig, cli *Client) *vqReporter {
+	if conf == nil || conf.Collector == "" {
+		return nil
+	}
+	return &vqReporter{conf: conf, cli: cli}
+}
+
+// Report sends a quality report for the call asynchronously.
+func (r *vqReporter) Report(log logger.Logger, media *MediaPort, sig Signaling, dir stats.CallDir) {
+	if r == nil {
+		return
+	}
+	rep := newVQReport(media, sig, dir)
+	if rep == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), vqReportTimeout)
+		defer cancel()
+		if err := r.publish(ctx, rep); err != nil {
+			log.Infow("cannot send call quality report", "error", err, "collector", r.conf.Collector)
+		}
+	}()
+}
+
+func (r *vqReporter) publish(ctx context.Context, rep *vqReport) error {
+	c := r.cli
+	if c.sipCli == nil {
+		return fmt.Errorf("sip client is not started")
+	}
+	tr := Transport(strings.ToLower(r.conf.Transport))
+	contact := c.ContactURI(tr)
+	to := CreateURIFromUserAndAddress("", r.conf.Collector, tr)
+	toHeader := &sip.ToHeader{Address: *to.GetURI()}
+	fromHeader := &sip.FromHeader{
+		Address: *contact.GetURI(),
+		Params:  sip.NewParams(),
+	}
+	fromHeader.Params.Add("tag", guid.HashedID(rep.CallID))
+	callID := sip.CallIDHeader(guid.New("SVQ_"))
+
+	req := sip.NewRequest(sip.PUBLISH, toHeader.Address)
+	setCSeq(req, 1)
+	req.RemoveHeader("Call-ID")
+	req.AppendHeader(&callID)
+	req.SetDestination(to.GetDest())
+	req.AppendHeader(toHeader)
+	req.AppendHeader(fromHeader)
+	req.AppendHeader(&sip.ContactHeader{Address: *contact.GetContactURI()})
+	req.AppendHeader(sip.NewHeader("Event", "vq-rtcpxr"))
+	req.AppendHeader(sip.NewHeader("Content-Type", "application/vq-rtcpxr"))
+	req.SetBody([]byte(rep.String()))
+	c.addVia(req)
+
+	tx, err := c.sipCli.TransactionRequest(req)
+	if err != nil {
+		return err
+	}
+	defer tx.Terminate()
+	resp, err := sipResponse(ctx, tx, c.closing.Watch(), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected response: %d %s", resp.StatusCode, resp.Reason)
+	}
+	return nil
+}
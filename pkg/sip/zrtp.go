@@ -0,0 +1,57 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// We do not support ZRTP (RFC 6189), but we detect and report when the remote attempts it.
+// ZRTP packets are never passed to the RTP stack, since they are not valid RTP.
+
+const (
+	zrtpMagicCookie = 0x5a525450 // "ZRTP"
+	zrtpPreamble    = 0x505a
+	zrtpHeaderSize  = 12
+)
+
+// isZRTPPacket checks if the packet is a ZRTP packet multiplexed on the RTP port.
+func isZRTPPacket(b []byte) bool {
+	return len(b) >= zrtpHeaderSize && b[0] == 0x10 && binary.BigEndian.Uint32(b[4:8]) == zrtpMagicCookie
+}
+
+// zrtpMessageType returns the type of ZRTP message (e.g. "Hello"), or an empty string if it cannot be parsed.
+func zrtpMessageType(b []byte) string {
+	b = b[zrtpHeaderSize:]
+	if len(b) < 12 || binary.BigEndian.Uint16(b[0:2]) != zrtpPreamble {
+		return ""
+	}
+	return string(bytes.TrimRight(b[4:12], " "))
+}
+
+// hasZRTPHash checks if SDP contains "a=zrtp-hash" attribute, which signals ZRTP support.
+func hasZRTPHash(sdp []byte) bool {
+	return bytes.Contains(sdp, []byte("a=zrtp-hash:"))
+}
+
+// reportZRTP logs and emits MediaEventZRTP the first time ZRTP is detected, either in SDP or in-band.
+func (c *udpConn) reportZRTP(source string, msg string) {
+	if !c.zrtp.CompareAndSwap(false, true) {
+		return
+	}
+	c.log.Infow("remote attempts ZRTP, which is not supported", "source", source, "message", msg)
+	c.events.emit(MediaEvent{Type: MediaEventZRTP})
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"testing"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func newZRTPHello() []byte {
+	pkt := []byte{
+		0x10, 0, 0, 1, // ZRTP flag, sequence
+		'Z', 'R', 'T', 'P', // magic cookie
+		1, 2, 3, 4, // SSRC
+		0x50, 0x5a, 0, 22, // preamble, length
+	}
+	pkt = append(pkt, "Hello   "...)
+	pkt = append(pkt, "1.10"...)
+	return pkt
+}
+
+func TestZRTPDetect(t *testing.T) {
+	hello := newZRTPHello()
+	require.Equal(t, mediaPacketZRTP, classifyMediaPacket(hello))
+	require.Equal(t, "Hello", zrtpMessageType(hello))
+
+	c1, c2 := newUDPPipe()
+	st := new(PortStats)
+	events := new(mediaEvents)
+	var got []MediaEvent
+	events.OnEvent(func(ev MediaEvent) {
+		if ev.Type == MediaEventZRTP {
+			got = append(got, ev)
+		}
+	})
+	u := newUDPConn(logger.GetLogger(), c1, st, events)
+
+	rtpPkt := make([]byte, 172)
+	rtpPkt[0] = 0x80
+	_, _ = c2.WriteToUDPAddrPort(hello, c1.addr)
+	_, _ = c2.WriteToUDPAddrPort(hello, c1.addr)
+	_, _ = c2.WriteToUDPAddrPort(rtpPkt, c1.addr)
+
+	buf := make([]byte, 1500)
+	n, err := u.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, rtpPkt, buf[:n], "ZRTP packets must not reach RTP stack")
+	require.Equal(t, uint64(2), st.ZRTPPackets.Load())
+	require.Len(t, got, 1)
+
+	u.reportZRTP("sdp", "")
+	require.Len(t, got, 1)
+	require.Equal(t, map[string]string{AttrSIPZRTP: "true"}, mediaStateAttrs(got[0]))
+}
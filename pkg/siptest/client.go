@@ -267,24 +267,35 @@ func (c *Client) Dial(ip string, uri string, number string, headers map[string]s
 	}
 
 	var (
-		authHeaderVal = ""
-		req           *sip.Request
-		resp          *sip.Response
+		authHeaderName = ""
+		authHeaderVal  = ""
+		req            *sip.Request
+		resp           *sip.Response
 	)
 
 	for {
-		req, resp, err = c.attemptInvite(ip, uri, number, offer, authHeaderVal, headers)
+		req, resp, err = c.attemptInvite(ip, uri, number, offer, authHeaderName, authHeaderVal, headers)
 		if err != nil {
 			return err
 		}
 
-		if resp.StatusCode == 407 {
+		if resp.StatusCode == 401 || resp.StatusCode == 407 {
 			c.log.Debug("auth requested")
 			if c.conf.AuthUser == "" || c.conf.AuthPass == "" {
-				return fmt.Errorf("server responded with 407, but no username or password was provided")
+				return fmt.Errorf("server responded with %d, but no username or password was provided", resp.StatusCode)
+			}
+			if authHeaderVal != "" {
+				return fmt.Errorf("server responded with %d, authentication failed", resp.StatusCode)
 			}
 
-			headerVal := resp.GetHeader("Proxy-Authenticate")
+			challengeName, authName := "Proxy-Authenticate", "Proxy-Authorization"
+			if resp.StatusCode == 401 {
+				challengeName, authName = "WWW-Authenticate", "Authorization"
+			}
+			headerVal := resp.GetHeader(challengeName)
+			if headerVal == nil {
+				return fmt.Errorf("no %s header on response", challengeName)
+			}
 			challenge, err := digest.ParseChallenge(headerVal.Value())
 			if err != nil {
 				return err
@@ -302,7 +313,7 @@ func (c *Client) Dial(ip string, uri string, number string, headers map[string]s
 				Password: c.conf.AuthPass,
 			})
 
-			authHeaderVal = cred.String()
+			authHeaderName, authHeaderVal = authName, cred.String()
 			// Compute digest and try again
 			continue
 		} else if resp.StatusCode != 200 {
@@ -347,7 +358,7 @@ func (c *Client) Dial(ip string, uri string, number string, headers map[string]s
 	return nil
 }
 
-func (c *Client) attemptInvite(ip, uri, number string, offer []byte, authHeader string, headers map[string]string) (*sip.Request, *sip.Response, error) {
+func (c *Client) attemptInvite(ip, uri, number string, offer []byte, authName, authHeader string, headers map[string]string) (*sip.Request, *sip.Response, error) {
 	req := sip.NewRequest(sip.INVITE, sip.Uri{User: number, Host: uri})
 	req.SetDestination(ip)
 	req.SetBody(offer)
@@ -359,7 +370,7 @@ func (c *Client) attemptInvite(ip, uri, number string, offer []byte, authHeader
 	}
 
 	if authHeader != "" {
-		req.AppendHeader(sip.NewHeader("Proxy-Authorization", authHeader))
+		req.AppendHeader(sip.NewHeader(authName, authHeader))
 	}
 	for k, v := range headers {
 		req.AppendHeader(sip.NewHeader(k, v))
@@ -367,7 +378,7 @@ func (c *Client) attemptInvite(ip, uri, number string, offer []byte, authHeader
 
 	tx, err := c.sipClient.TransactionRequest(req)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
 	defer tx.Terminate()
 
@@ -665,26 +676,21 @@ func (c *Client) WaitSignals(ctx context.Context, vals []int, w io.WriteCloser)
 
 	pkts := make(chan *rtp.Packet, 1)
 	done := make(chan struct{})
+	defer close(done)
 
 	h := rtp.Handler(rtp.HandlerFunc(func(hdr *rtp.Header, payload []byte) error {
-		// Make sure er do not send on a closed channel
+		// Must not block the receiver once the signal is found, since it also handles DTMF.
 		select {
 		case <-done:
-			return ctx.Err()
-		default:
-		}
-
-		select {
+			return nil
 		case <-ctx.Done():
-			close(pkts)
-			close(done)
 			return ctx.Err()
 		case pkts <- &rtp.Packet{Header: *hdr, Payload: slices.Clone(payload)}:
 		}
-
 		return nil
 	}))
 	c.recordHandler.Store(&h)
+	defer c.recordHandler.CompareAndSwap(&h, nil)
 
 	for {
 		var p *rtp.Packet
@@ -740,8 +746,6 @@ func (c *Client) WaitSignals(ctx context.Context, vals []int, w io.WriteCloser)
 			c.log.Debug("skipping signal", "len", len(decoded), "signals", out)
 		}
 	}
-
-	return nil
 }
 
 func getResponse(tx sip.ClientTransaction) (*sip.Response, error) {
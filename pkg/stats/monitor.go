@@ -16,8 +16,12 @@ package stats
 
 import (
 	"errors"
+	"slices"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/frostbyte73/core"
 	"github.com/prometheus/client_golang/prometheus"
@@ -34,6 +38,10 @@ var (
 	durBucketsOp = []float64{
 		0.1, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 3 * 60,
 	}
+	// durBucketsPhase lists histogram buckets for call setup phases, like auth lookup or room join.
+	durBucketsPhase = []float64{
+		0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30,
+	}
 	// durBucketsLong lists histogram buckets for long operations like call/session durations.
 	durBucketsLong = []float64{
 		1, 10, 60, 10 * 60, 30 * 60, 3600, 6 * 3600, 12 * 3600, 24 * 3600,
@@ -43,6 +51,48 @@ var (
 	}
 )
 
+// SetupPhase is a phase of call setup, measured separately to find which one regresses.
+type SetupPhase string
+
+const (
+	// SetupAuth is the trunk auth lookup for inbound calls.
+	SetupAuth = SetupPhase("auth")
+	// SetupDispatch is the dispatch RPC for inbound calls.
+	SetupDispatch = SetupPhase("dispatch")
+	// SetupSDP is the SDP offer/answer processing, including media port allocation.
+	SetupSDP = SetupPhase("sdp")
+	// SetupRoomJoin is the LiveKit room join.
+	SetupRoomJoin = SetupPhase("room_join")
+	// SetupFirstMedia is the time from the call being answered to the first RTP packet.
+	SetupFirstMedia = SetupPhase("first_media")
+)
+
+// TestCallResult is the outcome of a synthetic test call.
+type TestCallResult string
+
+const (
+	// TestCallPass means the call was answered and the test tone was received back.
+	TestCallPass = TestCallResult("pass")
+	// TestCallSetupFailed means the call was not answered.
+	TestCallSetupFailed = TestCallResult("setup_failed")
+	// TestCallNoAudio means the call was answered, but the test tone was not received back in time.
+	TestCallNoAudio = TestCallResult("no_audio")
+)
+
+// CallQueueOutcome is the reason a caller left a call queue.
+type CallQueueOutcome string
+
+const (
+	// CallQueueReleased means an agent was ready and the call joined the room.
+	CallQueueReleased = CallQueueOutcome("released")
+	// CallQueueAbandoned means the caller hung up while waiting.
+	CallQueueAbandoned = CallQueueOutcome("abandoned")
+	// CallQueueTimeout means the call was ended after waiting for too long.
+	CallQueueTimeout = CallQueueOutcome("timeout")
+	// CallQueueCallback means an agent was ready for a caller who requested a callback, and the caller is dialed back.
+	CallQueueCallback = CallQueueOutcome("callback")
+)
+
 type CallDir bool
 
 func (d CallDir) String() string {
@@ -61,18 +111,57 @@ type Monitor struct {
 	nodeID string
 
 	inviteReqRaw    prometheus.Counter
+	inviteDup       *prometheus.CounterVec
+	inviteLoop      *prometheus.CounterVec
 	inviteReq       *prometheus.CounterVec
 	inviteAccept    *prometheus.CounterVec
 	inviteErr       *prometheus.CounterVec
 	callsActive     *prometheus.GaugeVec
+	callsState      *prometheus.GaugeVec
 	callsTerminated *prometheus.CounterVec
+	callLeaks       *prometheus.CounterVec
+	memUsed         prometheus.Gauge
+	memDenied       *prometheus.CounterVec
+	failovers       *prometheus.CounterVec
+	publicIPChanges *prometheus.CounterVec
 	packetsRTP      *prometheus.CounterVec
 	durSession      *prometheus.HistogramVec
 	durCall         *prometheus.HistogramVec
 	durJoin         *prometheus.HistogramVec
+	durSetupPhase   *prometheus.HistogramVec
 	cpuLoad         prometheus.Gauge
 	sdpSize         *prometheus.HistogramVec
 	nodeAvailable   prometheus.GaugeFunc
+	portsUsed       *prometheus.GaugeVec
+	portsTotal      *prometheus.GaugeVec
+	portsExhausted  prometheus.Counter
+	portsWarm       prometheus.Gauge
+	portsWarmReqs   *prometheus.CounterVec
+	mediaEncryption *prometheus.CounterVec
+	sdpViolations   *prometheus.CounterVec
+	oneWayAudio     *prometheus.CounterVec
+
+	projectCallsActive *prometheus.GaugeVec
+	projectCallSec     *prometheus.CounterVec
+	projectRejected    *prometheus.CounterVec
+	policyRejected     *prometheus.CounterVec
+	emergencyCalls     *prometheus.CounterVec
+
+	testCalls     *prometheus.CounterVec
+	testCallSetup *prometheus.HistogramVec
+	testCallUp    *prometheus.GaugeVec
+
+	callQueueDepth  *prometheus.GaugeVec
+	callQueueOldest *prometheus.GaugeVec
+	callQueueCalls  *prometheus.CounterVec
+	callQueueWait   *prometheus.HistogramVec
+
+	trunkLabels   bool
+	trunkAllow    map[string]struct{}
+	trunkCalls    *prometheus.CounterVec
+	trunkFailures *prometheus.CounterVec
+	trunkSetup    *prometheus.HistogramVec
+	trunkDur      *prometheus.HistogramVec
 
 	cpu            *hwstats.CPUStats
 	maxUtilization float64
@@ -86,6 +175,13 @@ func NewMonitor(conf *config.Config) (*Monitor, error) {
 	m := &Monitor{
 		nodeID:         conf.NodeID,
 		maxUtilization: conf.MaxCpuUtilization,
+		trunkLabels:    conf.Metrics.TrunkLabels,
+	}
+	if len(conf.Metrics.TrunkAllowlist) != 0 {
+		m.trunkAllow = make(map[string]struct{}, len(conf.Metrics.TrunkAllowlist))
+		for _, id := range conf.Metrics.TrunkAllowlist {
+			m.trunkAllow[id] = struct{}{}
+		}
 	}
 	cpu, err := hwstats.NewCPUStats(func(idle float64) {
 		if m.started.IsBroken() {
@@ -125,6 +221,62 @@ func (m *Monitor) Start(conf *config.Config) error {
 		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
 	}))
 
+	m.inviteDup = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "invite_duplicates",
+		Help:        "Number of duplicate SIP INVITE requests not matched by the transaction layer: retransmit, merged or completed",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"kind"}))
+
+	m.inviteLoop = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "invite_loops",
+		Help:        "Number of SIP INVITE requests which looped back to this node: via, call_id, max_forwards or spiral",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"kind"}))
+
+	m.callLeaks = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "call_leaks",
+		Help:        "Number of call resources not released after the call ended: goroutine, socket or timer",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"kind"}))
+
+	m.memUsed = mustRegister(m, prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "memory_budget_used_bytes",
+		Help:        "Memory reserved for buffers of calls",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}))
+
+	m.memDenied = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "memory_budget_denied",
+		Help:        "Number of call buffers not allocated because of the memory budget: jitter, mixer or recorder",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"kind"}))
+
+	m.failovers = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "failover_calls",
+		Help:        "Number of calls of a failed peer instance taken over by this instance: recovered or failed",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"result"}))
+
+	m.publicIPChanges = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "public_ip_changes",
+		Help:        "Number of times the public IP discovered via STUN changed: signaling or media",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"kind"}))
+
 	m.inviteReq = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace:   "livekit",
 		Subsystem:   "sip",
@@ -157,6 +309,14 @@ func (m *Monitor) Start(conf *config.Config) error {
 		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
 	}, []string{"dir", "to"}))
 
+	m.callsState = mustRegister(m, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "calls_state",
+		Help:        "Number of SIP calls in each dialog state",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"dir", "state"}))
+
 	m.callsTerminated = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace:   "livekit",
 		Subsystem:   "sip",
@@ -200,6 +360,15 @@ func (m *Monitor) Start(conf *config.Config) error {
 		Buckets:     durBucketsOp,
 	}, []string{"dir"}))
 
+	m.durSetupPhase = mustRegister(m, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "dur_setup_phase_sec",
+		Help:        "SIP call setup duration, by phase (auth, dispatch, sdp, room_join, first_media)",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+		Buckets:     durBucketsPhase,
+	}, []string{"dir", "phase"}))
+
 	m.sdpSize = mustRegister(m, prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Namespace:   "livekit",
 		Subsystem:   "sip",
@@ -222,6 +391,204 @@ func (m *Monitor) Start(conf *config.Config) error {
 		return 0
 	}))
 
+	m.portsUsed = mustRegister(m, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "media_ports_used",
+		Help:        "Number of allocated RTP ports",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"pool"}))
+
+	m.portsTotal = mustRegister(m, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "media_ports_total",
+		Help:        "Number of RTP ports available for allocation",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"pool"}))
+
+	m.portsExhausted = mustRegister(m, prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "media_ports_exhausted",
+		Help:        "Number of times RTP port allocation failed",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}))
+
+	m.portsWarm = mustRegister(m, prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "media_ports_warm",
+		Help:        "Number of RTP ports bound in advance and ready for new calls",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}))
+
+	m.portsWarmReqs = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "media_ports_warm_requests",
+		Help:        "Number of RTP port allocations from the shared pool, by result: hit (pre-bound port was used) or miss",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"result"}))
+
+	m.mediaEncryption = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "media_encryption",
+		Help:        "Number of calls by negotiated media encryption: srtp, rtp, downgraded or rejected",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"dir", "to", "result"}))
+
+	m.sdpViolations = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "sdp_violations",
+		Help:        "Number of SDP offer/answer state violations by the remote",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"dir", "to", "violation"}))
+
+	m.oneWayAudio = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "one_way_audio",
+		Help:        "Number of calls with audio flowing in only one direction after answer: no-inbound or no-outbound",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"dir", "to", "missing"}))
+
+	m.projectCallsActive = mustRegister(m, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "project_calls_active",
+		Help:        "Number of active calls per configured project, other projects are counted as \"other\"",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"project", "dir"}))
+
+	m.projectCallSec = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "project_call_seconds",
+		Help:        "Total duration of calls per configured project, other projects are counted as \"other\"",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"project", "dir"}))
+
+	m.projectRejected = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "project_quota_rejected",
+		Help:        "Number of calls rejected because of project quota: calls, rate or minutes",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"project", "dir", "reason"}))
+
+	m.policyRejected = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "policy_rejected",
+		Help:        "Number of calls rejected by a policy, for example do-not-call list",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"dir", "reason"}))
+
+	m.emergencyCalls = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "emergency_calls",
+		Help:        "Number of calls to emergency numbers",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"dir", "trunk"}))
+
+	m.testCalls = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "test_calls",
+		Help:        "Number of synthetic test calls by result: pass, setup_failed or no_audio",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"name", "result"}))
+
+	m.testCallSetup = mustRegister(m, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "test_call_setup_sec",
+		Help:        "Setup latency of synthetic test calls (from INVITE to answered)",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+		Buckets:     durBucketsOp,
+	}, []string{"name"}))
+
+	m.testCallUp = mustRegister(m, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "test_call_up",
+		Help:        "Result of the last synthetic test call: 1 if passed, 0 otherwise",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"name"}))
+
+	m.callQueueDepth = mustRegister(m, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "call_queue_depth",
+		Help:        "Number of callers waiting in a call queue",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"queue"}))
+
+	m.callQueueOldest = mustRegister(m, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "call_queue_oldest_sec",
+		Help:        "Time the longest waiting caller spent in a call queue",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"queue"}))
+
+	m.callQueueCalls = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "call_queue_calls",
+		Help:        "Number of callers which left a call queue by outcome: released, callback, abandoned or timeout",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+	}, []string{"queue", "outcome"}))
+
+	m.callQueueWait = mustRegister(m, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   "livekit",
+		Subsystem:   "sip",
+		Name:        "call_queue_wait_sec",
+		Help:        "Time callers spent in a call queue by outcome",
+		ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+		Buckets:     durBucketsLong,
+	}, []string{"queue", "outcome"}))
+
+	if m.trunkLabels {
+		m.trunkCalls = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "livekit",
+			Subsystem:   "sip",
+			Name:        "trunk_calls",
+			Help:        "Number of calls per trunk by result: answered or failed",
+			ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+		}, []string{"dir", "trunk", "result"}))
+
+		m.trunkFailures = mustRegister(m, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "livekit",
+			Subsystem:   "sip",
+			Name:        "trunk_call_failures",
+			Help:        "Number of failed calls per trunk by SIP status code",
+			ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+		}, []string{"dir", "trunk", "code"}))
+
+		m.trunkSetup = mustRegister(m, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   "livekit",
+			Subsystem:   "sip",
+			Name:        "trunk_setup_sec",
+			Help:        "Call setup latency per trunk (from INVITE to answered)",
+			ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+			Buckets:     durBucketsOp,
+		}, []string{"dir", "trunk"}))
+
+		m.trunkDur = mustRegister(m, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   "livekit",
+			Subsystem:   "sip",
+			Name:        "trunk_call_sec",
+			Help:        "Call duration per trunk (from answered to closed)",
+			ConstLabels: prometheus.Labels{"node_id": conf.NodeID},
+			Buckets:     durBucketsLong,
+		}, []string{"dir", "trunk"}))
+	}
+
 	m.cpuLoad = mustRegister(m, prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace:   "livekit",
 		Subsystem:   "node",
@@ -278,12 +645,197 @@ func (m *Monitor) InviteReqRaw(dir CallDir) {
 	m.inviteReqRaw.Inc()
 }
 
+// InviteDuplicate records a duplicate INVITE, which was handled without creating a new call.
+func (m *Monitor) InviteDuplicate(kind string) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.inviteDup.WithLabelValues(kind).Inc()
+}
+
+// InviteLoop records an INVITE which looped back to this node. Spirals are accepted, other loops are rejected.
+func (m *Monitor) InviteLoop(kind string) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.inviteLoop.WithLabelValues(kind).Inc()
+}
+
+// CallLeaks reports resources of the kind which were not released by an ended call.
+func (m *Monitor) CallLeaks(kind string, n int) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.callLeaks.WithLabelValues(kind).Add(float64(n))
+}
+
+// MemoryBudgetUsed reports memory reserved for buffers of calls.
+func (m *Monitor) MemoryBudgetUsed(bytes int64) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.memUsed.Set(float64(bytes))
+}
+
+// MemoryBudgetDenied reports a buffer of the kind which was not allocated because of the memory budget.
+func (m *Monitor) MemoryBudgetDenied(kind string) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.memDenied.WithLabelValues(kind).Inc()
+}
+
+// FailoverCall reports a call of a failed peer instance taken over by this instance.
+func (m *Monitor) FailoverCall(recovered bool) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	result := "failed"
+	if recovered {
+		result = "recovered"
+	}
+	m.failovers.WithLabelValues(result).Inc()
+}
+
+// PublicIPChanged counts changes of the public IP, for signaling or media.
+func (m *Monitor) PublicIPChanged(kind string) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.publicIPChanges.WithLabelValues(kind).Inc()
+}
+
+// MediaPorts reports utilization of an RTP port pool.
+func (m *Monitor) MediaPorts(pool string, used, total int) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.portsUsed.WithLabelValues(pool).Set(float64(used))
+	m.portsTotal.WithLabelValues(pool).Set(float64(total))
+}
+
+func (m *Monitor) MediaPortsExhausted() {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.portsExhausted.Inc()
+}
+
+// MediaPortsWarm reports the number of RTP ports bound in advance.
+func (m *Monitor) MediaPortsWarm(n int) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.portsWarm.Set(float64(n))
+}
+
+// MediaPortWarmRequest records an RTP port allocation that used a pre-bound port (hit) or had to bind one (miss).
+func (m *Monitor) MediaPortWarmRequest(hit bool) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.portsWarmReqs.WithLabelValues(result).Inc()
+}
+
+// ProjectCallStart records a call that passed project quota checks.
+func (m *Monitor) ProjectCallStart(projectID string, dir CallDir) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.projectCallsActive.WithLabelValues(projectID, dir.String()).Inc()
+}
+
+// ProjectCallEnd records the end of the call started with ProjectCallStart.
+func (m *Monitor) ProjectCallEnd(projectID string, dir CallDir, dur time.Duration) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.projectCallsActive.WithLabelValues(projectID, dir.String()).Dec()
+	m.projectCallSec.WithLabelValues(projectID, dir.String()).Add(dur.Seconds())
+}
+
+// ProjectQuotaRejected records a call rejected because of the project quota.
+func (m *Monitor) ProjectQuotaRejected(projectID string, dir CallDir, reason string) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.projectRejected.WithLabelValues(projectID, dir.String(), reason).Inc()
+}
+
+// PolicyRejected records a call rejected by a policy, for example do-not-call list.
+func (m *Monitor) PolicyRejected(dir CallDir, reason string) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.policyRejected.WithLabelValues(dir.String(), reason).Inc()
+}
+
+// EmergencyCall records a call to an emergency number.
+func (m *Monitor) EmergencyCall(dir CallDir, trunkID string) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.emergencyCalls.WithLabelValues(dir.String(), m.TrunkLabel(trunkID)).Inc()
+}
+
+// TestCallDone records the result of a synthetic test call. Setup latency is only recorded if the call was answered.
+func (m *Monitor) TestCallDone(name string, result TestCallResult, setup time.Duration) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.testCalls.WithLabelValues(name, string(result)).Inc()
+	if setup > 0 {
+		m.testCallSetup.WithLabelValues(name).Observe(setup.Seconds())
+	}
+	up := 0.0
+	if result == TestCallPass {
+		up = 1
+	}
+	m.testCallUp.WithLabelValues(name).Set(up)
+}
+
+// CallQueueStats reports the number of queued callers and the wait time of the oldest one.
+func (m *Monitor) CallQueueStats(queue string, depth int, oldest time.Duration) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.callQueueDepth.WithLabelValues(queue).Set(float64(depth))
+	m.callQueueOldest.WithLabelValues(queue).Set(oldest.Seconds())
+}
+
+// CallQueueLeft records a caller leaving a call queue.
+func (m *Monitor) CallQueueLeft(queue string, outcome CallQueueOutcome, waited time.Duration) {
+	if m == nil || !m.started.IsBroken() {
+		return
+	}
+	m.callQueueCalls.WithLabelValues(queue, string(outcome)).Inc()
+	m.callQueueWait.WithLabelValues(queue, string(outcome)).Observe(waited.Seconds())
+}
+
+// TrunkLabel returns a value for the trunk metric label, applying the allowlist.
+func (m *Monitor) TrunkLabel(trunkID string) string {
+	if trunkID == "" {
+		return "unknown"
+	}
+	if m != nil && m.trunkAllow != nil {
+		if _, ok := m.trunkAllow[trunkID]; !ok {
+			return "other"
+		}
+	}
+	return trunkID
+}
+
 func (m *Monitor) NewCall(dir CallDir, fromHost, toHost string) *CallMonitor {
 	return &CallMonitor{
 		m:        m,
 		dir:      dir,
 		fromHost: fromHost,
 		toHost:   toHost,
+		trunk:    m.TrunkLabel(""),
 	}
 }
 
@@ -292,8 +844,112 @@ type CallMonitor struct {
 	dir        CallDir
 	fromHost   string
 	toHost     string
+	trunk      string
 	started    atomic.Bool
 	terminated atomic.Bool
+	inviteAt   atomic.Int64
+	answeredAt atomic.Int64
+	exemplar   atomic.Pointer[prometheus.Labels]
+	state      atomic.Pointer[string]
+}
+
+// SetTags sets call tags, which are attached as exemplars to call termination and duration metrics.
+// Tags that don't fit into the exemplar size limit are skipped.
+func (c *CallMonitor) SetTags(tags map[string]string) {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	l := make(prometheus.Labels, len(keys))
+	size := 0
+	for _, k := range keys {
+		name, v := exemplarLabelName(k), tags[k]
+		sz := utf8.RuneCountInString(name) + utf8.RuneCountInString(v)
+		if _, ok := l[name]; ok || !utf8.ValidString(v) || size+sz > prometheus.ExemplarMaxRunes {
+			continue
+		}
+		l[name] = v
+		size += sz
+	}
+	if len(l) == 0 {
+		c.exemplar.Store(nil)
+		return
+	}
+	c.exemplar.Store(&l)
+}
+
+// exemplarLabelName converts a tag name to a valid Prometheus label name.
+func exemplarLabelName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+func (c *CallMonitor) exemplarLabels() prometheus.Labels {
+	if l := c.exemplar.Load(); l != nil {
+		return *l
+	}
+	return nil
+}
+
+func (c *CallMonitor) inc(cnt prometheus.Counter) {
+	if l := c.exemplarLabels(); l != nil {
+		if ea, ok := cnt.(prometheus.ExemplarAdder); ok {
+			ea.AddWithExemplar(1, l)
+			return
+		}
+	}
+	cnt.Inc()
+}
+
+// SetTrunk sets the trunk for per-trunk metrics. It must be called before the call is answered.
+func (c *CallMonitor) SetTrunk(trunkID string) {
+	c.trunk = c.m.TrunkLabel(trunkID)
+}
+
+func (c *CallMonitor) trunkMetrics() bool {
+	return c.m != nil && c.m.trunkLabels && c.m.started.IsBroken()
+}
+
+func (c *CallMonitor) trunkLabels(l prometheus.Labels) prometheus.Labels {
+	out := prometheus.Labels{"dir": c.dir.String(), "trunk": c.trunk}
+	for k, v := range l {
+		out[k] = v
+	}
+	return out
+}
+
+// CallAnswered records the call setup latency for the trunk.
+func (c *CallMonitor) CallAnswered() {
+	now := time.Now()
+	if !c.answeredAt.CompareAndSwap(0, now.UnixNano()) || !c.trunkMetrics() {
+		return
+	}
+	c.m.trunkCalls.With(c.trunkLabels(prometheus.Labels{"result": "answered"})).Inc()
+	if t := c.inviteAt.Load(); t != 0 {
+		c.m.trunkSetup.With(c.trunkLabels(nil)).Observe(now.Sub(time.Unix(0, t)).Seconds())
+	}
+}
+
+// CallFailed records a failed call setup with a SIP status code for the trunk. Zero code is reported as "other".
+func (c *CallMonitor) CallFailed(code int) {
+	if !c.trunkMetrics() || c.answeredAt.Load() != 0 {
+		return
+	}
+	scode := "other"
+	if code != 0 {
+		scode = strconv.Itoa(code)
+	}
+	c.m.trunkCalls.With(c.trunkLabels(prometheus.Labels{"result": "failed"})).Inc()
+	c.m.trunkFailures.With(c.trunkLabels(prometheus.Labels{"code": scode})).Inc()
 }
 
 func (c *CallMonitor) labelsShort(l prometheus.Labels) prometheus.Labels {
@@ -313,6 +969,7 @@ func (c *CallMonitor) labels(l prometheus.Labels) prometheus.Labels {
 }
 
 func (c *CallMonitor) InviteReq() {
+	c.inviteAt.Store(time.Now().UnixNano())
 	c.m.inviteReq.With(c.labelsShort(nil)).Inc()
 }
 
@@ -342,11 +999,43 @@ func (c *CallMonitor) CallEnd() {
 	c.m.callsActive.With(c.labels(nil)).Dec()
 }
 
+// CallState moves the call to a given dialog state in the call state metric. Empty state removes the call from it.
+func (c *CallMonitor) CallState(state string) {
+	prev := c.state.Swap(&state)
+	if prev != nil && *prev == state {
+		return
+	}
+	if prev != nil && *prev != "" {
+		c.m.callsState.With(c.labelsShort(prometheus.Labels{"state": *prev})).Dec()
+	}
+	if state != "" {
+		c.m.callsState.With(c.labelsShort(prometheus.Labels{"state": state})).Inc()
+	}
+}
+
 func (c *CallMonitor) CallTerminate(reason string) {
 	if !c.terminated.CompareAndSwap(false, true) {
 		return
 	}
-	c.m.callsTerminated.With(c.labels(prometheus.Labels{"reason": reason})).Inc()
+	c.inc(c.m.callsTerminated.With(c.labels(prometheus.Labels{"reason": reason})))
+	if t := c.answeredAt.Load(); t != 0 && c.trunkMetrics() {
+		c.m.trunkDur.With(c.trunkLabels(nil)).Observe(time.Since(time.Unix(0, t)).Seconds())
+	}
+}
+
+// MediaEncryption records the result of media encryption negotiation for the call.
+func (c *CallMonitor) MediaEncryption(result string) {
+	c.m.mediaEncryption.With(c.labels(prometheus.Labels{"result": result})).Inc()
+}
+
+// SDPViolation records an offer/answer state violation by the remote.
+func (c *CallMonitor) SDPViolation(violation string) {
+	c.m.sdpViolations.With(c.labels(prometheus.Labels{"violation": violation})).Inc()
+}
+
+// OneWayAudio records a call where audio flows in only one direction.
+func (c *CallMonitor) OneWayAudio(missing string) {
+	c.m.oneWayAudio.With(c.labels(prometheus.Labels{"missing": missing})).Inc()
 }
 
 func (c *CallMonitor) RTPPacketSend(payloadType string) {
@@ -362,13 +1051,26 @@ func (c *CallMonitor) SessionDur() func() time.Duration {
 }
 
 func (c *CallMonitor) CallDur() func() time.Duration {
-	return prometheus.NewTimer(c.m.durCall.With(c.labelsShort(nil))).ObserveDuration
+	t := prometheus.NewTimer(c.m.durCall.With(c.labelsShort(nil)))
+	return func() time.Duration {
+		return t.ObserveDurationWithExemplar(c.exemplarLabels())
+	}
 }
 
 func (c *CallMonitor) JoinDur() func() time.Duration {
 	return prometheus.NewTimer(c.m.durJoin.With(c.labelsShort(nil))).ObserveDuration
 }
 
+// SetupPhaseDur starts a timer for a call setup phase. Calling the returned function records the duration.
+func (c *CallMonitor) SetupPhaseDur(phase SetupPhase) func() time.Duration {
+	return prometheus.NewTimer(c.m.durSetupPhase.With(c.labelsShort(prometheus.Labels{"phase": string(phase)}))).ObserveDuration
+}
+
+// SetupPhaseObserve records the duration of a call setup phase.
+func (c *CallMonitor) SetupPhaseObserve(phase SetupPhase, dur time.Duration) {
+	c.m.durSetupPhase.With(c.labelsShort(prometheus.Labels{"phase": string(phase)})).Observe(dur.Seconds())
+}
+
 func (c *CallMonitor) SDPSize(sz int, isOffer bool) {
 	typ := "answer"
 	if isOffer {
@@ -0,0 +1,172 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+func TestTrunkMetrics(t *testing.T) {
+	conf := &config.Config{
+		MaxCpuUtilization: 0.9,
+		Metrics: config.MetricsConfig{
+			TrunkLabels:    true,
+			TrunkAllowlist: []string{"ST_a"},
+		},
+	}
+	m, err := NewMonitor(conf)
+	require.NoError(t, err)
+	require.NoError(t, m.Start(conf))
+	t.Cleanup(m.Stop)
+
+	require.Equal(t, "ST_a", m.TrunkLabel("ST_a"))
+	require.Equal(t, "other", m.TrunkLabel("ST_b"))
+	require.Equal(t, "unknown", m.TrunkLabel(""))
+
+	c := m.NewCall(Inbound, "from", "to")
+	c.SetTrunk("ST_a")
+	c.InviteReq()
+	c.CallAnswered()
+	c.CallFailed(486) // ignored after answer
+	c.CallTerminate("hangup")
+
+	c = m.NewCall(Outbound, "from", "to")
+	c.SetTrunk("ST_b")
+	c.InviteReq()
+	c.CallFailed(486)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(m.trunkCalls.WithLabelValues("in", "ST_a", "answered")))
+	require.Equal(t, 0.0, testutil.ToFloat64(m.trunkFailures.WithLabelValues("in", "ST_a", "486")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.trunkCalls.WithLabelValues("out", "other", "failed")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.trunkFailures.WithLabelValues("out", "other", "486")))
+	require.Equal(t, 1, testutil.CollectAndCount(m.trunkSetup))
+	require.Equal(t, 1, testutil.CollectAndCount(m.trunkDur))
+}
+
+func TestCallTagsExemplar(t *testing.T) {
+	conf := &config.Config{MaxCpuUtilization: 0.9}
+	m, err := NewMonitor(conf)
+	require.NoError(t, err)
+	require.NoError(t, m.Start(conf))
+	t.Cleanup(m.Stop)
+
+	c := m.NewCall(Outbound, "from", "to")
+	require.Nil(t, c.exemplarLabels())
+
+	long := strings.Repeat("x", 120)
+	c.SetTags(map[string]string{
+		"campaign.id": "summer",
+		"1st":         "a",
+		"long":        long,
+		"ticket":      "T-1",
+	})
+	require.Equal(t, prometheus.Labels{
+		"_1st":        "a",
+		"campaign_id": "summer",
+		"ticket":      "T-1",
+	}, c.exemplarLabels())
+
+	// Must not panic on exemplars.
+	c.CallStart()
+	end := c.CallDur()
+	end()
+	c.CallTerminate("hangup")
+	require.Equal(t, 1.0, testutil.ToFloat64(m.callsTerminated.With(c.labels(prometheus.Labels{"reason": "hangup"}))))
+}
+
+func TestSetupPhaseMetrics(t *testing.T) {
+	conf := &config.Config{MaxCpuUtilization: 0.9}
+	m, err := NewMonitor(conf)
+	require.NoError(t, err)
+	require.NoError(t, m.Start(conf))
+	t.Cleanup(m.Stop)
+
+	in := m.NewCall(Inbound, "from", "to")
+	in.SetupPhaseDur(SetupAuth)()
+	in.SetupPhaseDur(SetupDispatch)()
+	in.SetupPhaseObserve(SetupFirstMedia, 50*time.Millisecond)
+
+	out := m.NewCall(Outbound, "from", "to")
+	out.SetupPhaseObserve(SetupSDP, time.Millisecond)
+	out.SetupPhaseDur(SetupRoomJoin)()
+
+	require.Equal(t, 5, testutil.CollectAndCount(m.durSetupPhase))
+}
+
+func TestTestCallMetrics(t *testing.T) {
+	conf := &config.Config{MaxCpuUtilization: 0.9}
+	m, err := NewMonitor(conf)
+	require.NoError(t, err)
+	require.NoError(t, m.Start(conf))
+	t.Cleanup(m.Stop)
+
+	m.TestCallDone("a", TestCallPass, time.Second)
+	m.TestCallDone("b", TestCallSetupFailed, 0)
+	m.TestCallDone("b", TestCallNoAudio, time.Second)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(m.testCallUp.WithLabelValues("a")))
+	require.Equal(t, 0.0, testutil.ToFloat64(m.testCallUp.WithLabelValues("b")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.testCalls.WithLabelValues("b", string(TestCallNoAudio))))
+	require.Equal(t, 2, testutil.CollectAndCount(m.testCallSetup))
+}
+
+func TestCallStateMetrics(t *testing.T) {
+	conf := &config.Config{MaxCpuUtilization: 0.9}
+	m, err := NewMonitor(conf)
+	require.NoError(t, err)
+	require.NoError(t, m.Start(conf))
+	t.Cleanup(m.Stop)
+
+	c1 := m.NewCall(Inbound, "from", "to")
+	c2 := m.NewCall(Inbound, "from", "to")
+	c1.CallState("proceeding")
+	c2.CallState("proceeding")
+	c1.CallState("answered")
+	c1.CallState("answered")
+
+	require.Equal(t, 1.0, testutil.ToFloat64(m.callsState.WithLabelValues("in", "proceeding")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.callsState.WithLabelValues("in", "answered")))
+
+	c1.CallState("")
+	c2.CallState("")
+	require.Equal(t, 0.0, testutil.ToFloat64(m.callsState.WithLabelValues("in", "proceeding")))
+	require.Equal(t, 0.0, testutil.ToFloat64(m.callsState.WithLabelValues("in", "answered")))
+}
+
+func TestCallQueueMetrics(t *testing.T) {
+	conf := &config.Config{MaxCpuUtilization: 0.9}
+	m, err := NewMonitor(conf)
+	require.NoError(t, err)
+	require.NoError(t, m.Start(conf))
+	t.Cleanup(m.Stop)
+
+	m.CallQueueStats("sales", 2, 30*time.Second)
+	m.CallQueueLeft("sales", CallQueueAbandoned, 10*time.Second)
+	m.CallQueueLeft("sales", CallQueueReleased, 40*time.Second)
+	m.CallQueueStats("sales", 0, 0)
+
+	require.Equal(t, 0.0, testutil.ToFloat64(m.callQueueDepth.WithLabelValues("sales")))
+	require.Equal(t, 0.0, testutil.ToFloat64(m.callQueueOldest.WithLabelValues("sales")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.callQueueCalls.WithLabelValues("sales", string(CallQueueAbandoned))))
+	require.Equal(t, 2, testutil.CollectAndCount(m.callQueueWait))
+}
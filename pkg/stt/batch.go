@@ -0,0 +1,189 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stt
+
+import (
+	"context"
+	"encoding/binary"
+	"strconv"
+	"sync"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/media/vad"
+)
+
+const (
+	// utteranceLevel is the audio level of speech, in -dBov.
+	utteranceLevel = 40
+	// utteranceHangover is the pause which ends the utterance.
+	utteranceHangover = 700 * time.Millisecond
+	// minUtterance is the shortest speech sent for recognition, which skips clicks and short noises.
+	minUtterance = 200 * time.Millisecond
+	// maxUtterance splits long speech without pauses.
+	maxUtterance = 30 * time.Second
+	// utteranceQueue is the number of utterances waiting for recognition.
+	utteranceQueue = 4
+)
+
+// recognizeFunc recognizes a complete utterance. It returns the text and the language, if the API reports it.
+type recognizeFunc func(ctx context.Context, audio msdk.PCM16Sample, sampleRate int) (text, lang string, err error)
+
+// batchEngine adapts an API which recognizes complete recordings. The audio is split into utterances on pauses,
+// and each utterance is reported as a final transcript once it's recognized.
+type batchEngine struct {
+	log       logger.Logger
+	name      string
+	timeout   time.Duration
+	recognize recognizeFunc
+}
+
+func (e *batchEngine) Start(ctx context.Context, sampleRate int, h func(t Transcript)) (Stream, error) {
+	s := &batchStream{
+		e:          e,
+		h:          h,
+		sampleRate: sampleRate,
+		queue:      make(chan msdk.PCM16Sample, utteranceQueue),
+		done:       make(chan struct{}),
+	}
+	go s.run(ctx)
+	return s, nil
+}
+
+func (e *batchEngine) Close() error {
+	return nil
+}
+
+type batchStream struct {
+	e          *batchEngine
+	h          func(t Transcript)
+	sampleRate int
+	queue      chan msdk.PCM16Sample
+	done       chan struct{}
+
+	mu       sync.Mutex
+	closed   bool
+	buf      msdk.PCM16Sample
+	speaking bool
+	dur      time.Duration
+	quiet    time.Duration
+}
+
+func (s *batchStream) WriteSample(sample msdk.PCM16Sample) error {
+	if len(sample) == 0 {
+		return nil
+	}
+	dur := time.Duration(len(sample)) * time.Second / time.Duration(s.sampleRate)
+	loud := vad.AudioLevel(sample) <= utteranceLevel
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	if !s.speaking {
+		if !loud {
+			return nil
+		}
+		s.speaking = true
+	}
+	s.buf = append(s.buf, sample...)
+	s.dur += dur
+	if loud {
+		s.quiet = 0
+	} else {
+		s.quiet += dur
+	}
+	if s.quiet >= utteranceHangover || s.dur >= maxUtterance {
+		s.flush()
+	}
+	return nil
+}
+
+// flush queues the current utterance for recognition. It blocks if the engine is behind.
+func (s *batchStream) flush() {
+	if s.dur-s.quiet >= minUtterance {
+		s.queue <- s.buf
+	}
+	s.buf, s.speaking, s.dur, s.quiet = nil, false, 0, 0
+}
+
+func (s *batchStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.flush()
+	close(s.queue)
+	return nil
+}
+
+func (s *batchStream) run(ctx context.Context) {
+	defer close(s.done)
+	n := 0
+	for audio := range s.queue {
+		if ctx.Err() != nil {
+			continue // drain
+		}
+		n++
+		rctx, cancel := context.WithTimeout(ctx, s.e.timeout)
+		text, lang, err := s.e.recognize(rctx, audio, s.sampleRate)
+		cancel()
+		if err != nil {
+			s.e.log.Warnw("cannot recognize speech", err, "engine", s.e.name)
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		s.h(Transcript{ID: "utt-" + strconv.Itoa(n), Text: text, Final: true, Language: lang})
+	}
+}
+
+// encodeWAV encodes 16-bit mono PCM as a WAV file.
+func encodeWAV(audio msdk.PCM16Sample, sampleRate int) []byte {
+	const (
+		headerSize = 44
+		channels   = 1
+		bits       = 16
+	)
+	size := len(audio) * 2
+	b := make([]byte, 0, headerSize+size)
+	b = append(b, "RIFF"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(headerSize-8+size))
+	b = append(b, "WAVEfmt "...)
+	b = binary.LittleEndian.AppendUint32(b, 16) // fmt chunk size
+	b = binary.LittleEndian.AppendUint16(b, 1)  // PCM
+	b = binary.LittleEndian.AppendUint16(b, channels)
+	b = binary.LittleEndian.AppendUint32(b, uint32(sampleRate))
+	b = binary.LittleEndian.AppendUint32(b, uint32(sampleRate*channels*bits/8))
+	b = binary.LittleEndian.AppendUint16(b, channels*bits/8)
+	b = binary.LittleEndian.AppendUint16(b, bits)
+	b = append(b, "data"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(size))
+	return appendPCM(b, audio)
+}
+
+// appendPCM appends audio as 16-bit little-endian samples.
+func appendPCM(b []byte, audio msdk.PCM16Sample) []byte {
+	for _, v := range audio {
+		b = binary.LittleEndian.AppendUint16(b, uint16(v))
+	}
+	return b
+}
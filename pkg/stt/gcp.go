@@ -0,0 +1,129 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	defaultGCPURL      = "https://speech.googleapis.com/v1/speech:recognize"
+	defaultGCPLanguage = "en-US"
+)
+
+// NewGCP creates an engine which sends each utterance to Google Cloud Speech-to-Text API.
+func NewGCP(conf *config.STTConfig, log logger.Logger) Engine {
+	g := &gcp{
+		conf: conf,
+		cli:  &http.Client{},
+		url:  defaultGCPURL,
+		lang: defaultGCPLanguage,
+	}
+	if conf.URL != "" {
+		g.url = conf.URL
+	}
+	if conf.Language != "" {
+		g.lang = conf.Language
+	}
+	return &batchEngine{
+		log:       log,
+		name:      config.STTEngineGCP,
+		timeout:   timeoutOrDefault(conf),
+		recognize: g.recognize,
+	}
+}
+
+type gcp struct {
+	conf *config.STTConfig
+	cli  *http.Client
+	url  string
+	lang string
+}
+
+type gcpRecognitionConfig struct {
+	Encoding        string `json:"encoding"`
+	SampleRateHertz int    `json:"sampleRateHertz"`
+	LanguageCode    string `json:"languageCode"`
+	Model           string `json:"model,omitempty"`
+}
+
+type gcpRecognizeRequest struct {
+	Config gcpRecognitionConfig `json:"config"`
+	Audio  struct {
+		Content []byte `json:"content"` // base64 in JSON
+	} `json:"audio"`
+}
+
+type gcpRecognizeResponse struct {
+	Results []struct {
+		Alternatives []struct {
+			Transcript string `json:"transcript"`
+		} `json:"alternatives"`
+		LanguageCode string `json:"languageCode"`
+	} `json:"results"`
+}
+
+func (g *gcp) recognize(ctx context.Context, audio msdk.PCM16Sample, sampleRate int) (string, string, error) {
+	var r gcpRecognizeRequest
+	r.Config = gcpRecognitionConfig{
+		Encoding:        "LINEAR16",
+		SampleRateHertz: sampleRate,
+		LanguageCode:    g.lang,
+		Model:           g.conf.Model,
+	}
+	r.Audio.Content = appendPCM(make([]byte, 0, 2*len(audio)), audio)
+	body, err := json.Marshal(&r)
+	if err != nil {
+		return "", "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.conf.APIKey != "" {
+		req.Header.Set("X-Goog-Api-Key", g.conf.APIKey)
+	}
+	var resp gcpRecognizeResponse
+	if err = doJSON(g.cli, req, &resp); err != nil {
+		return "", "", err
+	}
+	// Results are consecutive parts of the audio, each with the most likely alternative first.
+	var (
+		parts []string
+		lang  string
+	)
+	for _, res := range resp.Results {
+		if len(res.Alternatives) == 0 {
+			continue
+		}
+		if t := strings.TrimSpace(res.Alternatives[0].Transcript); t != "" {
+			parts = append(parts, t)
+		}
+		if lang == "" {
+			lang = res.LanguageCode
+		}
+	}
+	return strings.Join(parts, " "), lang, nil
+}
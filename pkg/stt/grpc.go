@@ -0,0 +1,111 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stt
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative stt.proto
+
+// NewGRPC creates an engine which streams audio to a plugin implementing SpeechToText service from stt.proto.
+// Plugins report interim and final transcripts.
+func NewGRPC(conf *config.STTConfig, log logger.Logger) (Engine, error) {
+	creds := insecure.NewCredentials()
+	if conf.TLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(conf.URL, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create stt client: %w", err)
+	}
+	return &grpcEngine{conf: conf, log: log, conn: conn, cli: NewSpeechToTextClient(conn)}, nil
+}
+
+type grpcEngine struct {
+	conf *config.STTConfig
+	log  logger.Logger
+	conn *grpc.ClientConn
+	cli  SpeechToTextClient
+}
+
+func (e *grpcEngine) Start(ctx context.Context, sampleRate int, h func(t Transcript)) (Stream, error) {
+	if e.conf.APIKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+e.conf.APIKey)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	cs, err := e.cli.Recognize(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	err = cs.Send(&RecognizeRequest{Request: &RecognizeRequest_Config{Config: &RecognitionConfig{
+		SampleRate: uint32(sampleRate),
+		Language:   e.conf.Language,
+		Model:      e.conf.Model,
+	}}})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	s := &grpcStream{cs: cs}
+	go func() {
+		defer cancel()
+		for {
+			resp, err := cs.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) && status.Code(err) != codes.Canceled {
+					e.log.Warnw("stt stream failed", err)
+				}
+				return
+			}
+			h(Transcript{ID: resp.GetId(), Text: resp.GetText(), Final: resp.GetFinal(), Language: resp.GetLanguage()})
+		}
+	}()
+	return s, nil
+}
+
+func (e *grpcEngine) Close() error {
+	return e.conn.Close()
+}
+
+type grpcStream struct {
+	cs SpeechToText_RecognizeClient
+}
+
+func (s *grpcStream) WriteSample(sample msdk.PCM16Sample) error {
+	audio := appendPCM(make([]byte, 0, 2*len(sample)), sample)
+	return s.cs.Send(&RecognizeRequest{Request: &RecognizeRequest_Audio{Audio: audio}})
+}
+
+func (s *grpcStream) Close() error {
+	return s.cs.CloseSend()
+}
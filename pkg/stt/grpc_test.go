@@ -0,0 +1,96 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+type testPlugin struct {
+	UnimplementedSpeechToTextServer
+}
+
+// Recognize reports the number of received audio bytes as interim results,
+// and a final result when the client closes the stream.
+func (testPlugin) Recognize(ss SpeechToText_RecognizeServer) error {
+	md, _ := metadata.FromIncomingContext(ss.Context())
+	if auth := md.Get("authorization"); len(auth) == 0 || auth[0] != "Bearer key" {
+		return errors.New("unauthorized")
+	}
+	req, err := ss.Recv()
+	if err != nil {
+		return err
+	}
+	if conf := req.GetConfig(); conf == nil || conf.GetSampleRate() != testRate || conf.GetLanguage() != "en" {
+		return errors.New("unexpected config")
+	}
+	total := 0
+	for {
+		req, err := ss.Recv()
+		if errors.Is(err, io.EOF) {
+			return ss.Send(&RecognizeResponse{Id: "1", Text: "done " + strconv.Itoa(total), Final: true, Language: "en"})
+		} else if err != nil {
+			return err
+		}
+		total += len(req.GetAudio())
+		if err = ss.Send(&RecognizeResponse{Id: "1", Text: strconv.Itoa(total)}); err != nil {
+			return err
+		}
+	}
+}
+
+func startTestPlugin(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	RegisterSpeechToTextServer(srv, testPlugin{})
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(srv.Stop)
+	return ln.Addr().String()
+}
+
+func TestGRPCEngine(t *testing.T) {
+	addr := startTestPlugin(t)
+	e, err := New(&config.STTConfig{Engine: config.STTEngineGRPC, URL: addr, APIKey: "key", Language: "en"}, logger.GetLogger())
+	require.NoError(t, err)
+	defer e.Close()
+
+	ch := make(chan Transcript, 10)
+	s, err := e.Start(context.Background(), testRate, func(t Transcript) { ch <- t })
+	require.NoError(t, err)
+	require.NoError(t, s.WriteSample(msdk.PCM16Sample{1, 2, 3}))
+	require.NoError(t, s.WriteSample(msdk.PCM16Sample{4}))
+	require.NoError(t, s.Close())
+
+	require.Equal(t, []Transcript{
+		{ID: "1", Text: "6"},
+		{ID: "1", Text: "8"},
+		{ID: "1", Text: "done 8", Final: true, Language: "en"},
+	}, collect(t, ch, 3))
+}
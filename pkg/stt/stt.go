@@ -0,0 +1,72 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stt streams audio of SIP calls to speech-to-text engines.
+package stt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Transcript is a part of the speech recognized by the engine.
+type Transcript struct {
+	// ID identifies the segment. Interim transcripts are replaced by the next ones with the same ID, until the final one.
+	ID    string
+	Text  string
+	Final bool
+	// Language is the language of the speech, if reported by the engine.
+	Language string
+}
+
+// Engine recognizes speech in audio streams. It's shared by all calls.
+type Engine interface {
+	// Start begins recognition of a single audio stream. Transcripts are passed to the handler,
+	// until the stream is closed or the context is cancelled.
+	Start(ctx context.Context, sampleRate int, h func(t Transcript)) (Stream, error)
+	// Close releases the engine. Streams must be closed first.
+	Close() error
+}
+
+// Stream accepts audio for recognition.
+type Stream interface {
+	// WriteSample sends an audio frame to the engine. It may block while the engine is busy.
+	WriteSample(sample msdk.PCM16Sample) error
+	// Close ends the audio. The engine may still report transcripts of the audio written before.
+	Close() error
+}
+
+// New creates the configured engine. It returns nil if speech-to-text is not configured.
+func New(conf *config.STTConfig, log logger.Logger) (Engine, error) {
+	if conf == nil {
+		return nil, nil
+	}
+	switch conf.Engine {
+	case config.STTEngineGRPC:
+		return NewGRPC(conf, log)
+	case config.STTEngineWhisper:
+		return NewWhisper(conf, log), nil
+	case config.STTEngineGCP:
+		return NewGCP(conf, log), nil
+	}
+	return nil, fmt.Errorf("invalid stt engine %q", conf.Engine)
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stt
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const testRate = 16000
+
+// testFrames returns 20ms frames with a constant value.
+func testFrames(dur time.Duration, v int16) []msdk.PCM16Sample {
+	out := make([]msdk.PCM16Sample, dur/(20*time.Millisecond))
+	for i := range out {
+		f := make(msdk.PCM16Sample, testRate/50)
+		for j := range f {
+			f[j] = v
+		}
+		out[i] = f
+	}
+	return out
+}
+
+func writeFrames(t *testing.T, s Stream, frames []msdk.PCM16Sample) {
+	for _, f := range frames {
+		require.NoError(t, s.WriteSample(f))
+	}
+}
+
+func collect(t *testing.T, ch <-chan Transcript, n int) []Transcript {
+	var out []Transcript
+	for range n {
+		select {
+		case tr := <-ch:
+			out = append(out, tr)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %d transcripts, got %d", n, len(out))
+		}
+	}
+	return out
+}
+
+func TestBatchEngine(t *testing.T) {
+	var lens []int
+	e := &batchEngine{
+		log:     logger.GetLogger(),
+		timeout: time.Second,
+		recognize: func(ctx context.Context, audio msdk.PCM16Sample, sampleRate int) (string, string, error) {
+			require.Equal(t, testRate, sampleRate)
+			lens = append(lens, len(audio))
+			return "hello", "en", nil
+		},
+	}
+	ch := make(chan Transcript, 10)
+	s, err := e.Start(context.Background(), testRate, func(t Transcript) { ch <- t })
+	require.NoError(t, err)
+
+	writeFrames(t, s, testFrames(time.Second, 0))             // silence is skipped
+	writeFrames(t, s, testFrames(500*time.Millisecond, 5000)) // utterance
+	writeFrames(t, s, testFrames(time.Second, 0))             // pause ends it
+	writeFrames(t, s, testFrames(100*time.Millisecond, 5000)) // click is skipped
+	writeFrames(t, s, testFrames(time.Second, 0))
+	writeFrames(t, s, testFrames(300*time.Millisecond, 5000)) // flushed on close
+	require.NoError(t, s.Close())
+
+	got := collect(t, ch, 2)
+	require.Equal(t, []Transcript{
+		{ID: "utt-1", Text: "hello", Final: true, Language: "en"},
+		{ID: "utt-2", Text: "hello", Final: true, Language: "en"},
+	}, got)
+	// Utterance includes the pause until the hangover.
+	require.Equal(t, testRate*(500+700)/1000, lens[0])
+	require.Equal(t, testRate*300/1000, lens[1])
+}
+
+func TestWhisper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		require.Equal(t, "whisper-1", r.FormValue("model"))
+		require.Equal(t, "de", r.FormValue("language"))
+		f, _, err := r.FormFile("file")
+		require.NoError(t, err)
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, "RIFF", string(data[:4]))
+		require.Len(t, data, 44+2*testRate/10)
+		_ = json.NewEncoder(w).Encode(map[string]string{"text": "hallo", "language": "german"})
+	}))
+	defer srv.Close()
+
+	e := NewWhisper(&config.STTConfig{Engine: config.STTEngineWhisper, URL: srv.URL, APIKey: "key", Language: "de"}, logger.GetLogger())
+	text, lang, err := e.(*batchEngine).recognize(context.Background(), make(msdk.PCM16Sample, testRate/10), testRate)
+	require.NoError(t, err)
+	require.Equal(t, "hallo", text)
+	require.Equal(t, "german", lang)
+}
+
+func TestGCP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "key", r.Header.Get("X-Goog-Api-Key"))
+		var req gcpRecognizeRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, gcpRecognitionConfig{Encoding: "LINEAR16", SampleRateHertz: testRate, LanguageCode: "en-US"}, req.Config)
+		require.Equal(t, []byte{1, 0, 0xff, 0xff}, req.Audio.Content)
+		_, _ = w.Write([]byte(`{"results":[
+			{"alternatives":[{"transcript":"hello"},{"transcript":"yellow"}],"languageCode":"en-us"},
+			{"alternatives":[{"transcript":" world"}]},
+			{"alternatives":[]}
+		]}`))
+	}))
+	defer srv.Close()
+
+	e := NewGCP(&config.STTConfig{Engine: config.STTEngineGCP, URL: srv.URL, APIKey: "key"}, logger.GetLogger())
+	text, lang, err := e.(*batchEngine).recognize(context.Background(), msdk.PCM16Sample{1, -1}, testRate)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", text)
+	require.Equal(t, "en-us", lang)
+}
+
+func TestWhisperError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	e := NewWhisper(&config.STTConfig{Engine: config.STTEngineWhisper, URL: srv.URL}, logger.GetLogger())
+	_, _, err := e.(*batchEngine).recognize(context.Background(), make(msdk.PCM16Sample, 160), testRate)
+	require.ErrorContains(t, err, "bad key")
+}
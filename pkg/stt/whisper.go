@@ -0,0 +1,134 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	msdk "github.com/livekit/media-sdk"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/sip/pkg/config"
+)
+
+const (
+	defaultWhisperURL   = "https://api.openai.com/v1/audio/transcriptions"
+	defaultWhisperModel = "whisper-1"
+	// maxSTTResponse limits the size of API responses.
+	maxSTTResponse = 1 << 20
+)
+
+// NewWhisper creates an engine which sends each utterance to OpenAI Whisper API, or a compatible service.
+func NewWhisper(conf *config.STTConfig, log logger.Logger) Engine {
+	w := &whisper{
+		conf:  conf,
+		cli:   &http.Client{},
+		url:   defaultWhisperURL,
+		model: defaultWhisperModel,
+	}
+	if conf.URL != "" {
+		w.url = conf.URL
+	}
+	if conf.Model != "" {
+		w.model = conf.Model
+	}
+	return &batchEngine{
+		log:       log,
+		name:      config.STTEngineWhisper,
+		timeout:   timeoutOrDefault(conf),
+		recognize: w.recognize,
+	}
+}
+
+type whisper struct {
+	conf  *config.STTConfig
+	cli   *http.Client
+	url   string
+	model string
+}
+
+func (w *whisper) recognize(ctx context.Context, audio msdk.PCM16Sample, sampleRate int) (string, string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return "", "", err
+	}
+	if _, err = fw.Write(encodeWAV(audio, sampleRate)); err != nil {
+		return "", "", err
+	}
+	fields := map[string]string{
+		"model":           w.model,
+		"response_format": "verbose_json",
+	}
+	if w.conf.Language != "" {
+		fields["language"] = w.conf.Language
+	}
+	for k, v := range fields {
+		if err = mw.WriteField(k, v); err != nil {
+			return "", "", err
+		}
+	}
+	if err = mw.Close(); err != nil {
+		return "", "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, &body)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if w.conf.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+w.conf.APIKey)
+	}
+	var resp struct {
+		Text     string `json:"text"`
+		Language string `json:"language"`
+	}
+	if err = doJSON(w.cli, req, &resp); err != nil {
+		return "", "", err
+	}
+	return resp.Text, resp.Language, nil
+}
+
+func timeoutOrDefault(conf *config.STTConfig) time.Duration {
+	if conf.Timeout > 0 {
+		return conf.Timeout
+	}
+	return defaultTimeout
+}
+
+// doJSON sends the request and decodes a JSON response.
+func doJSON(cli *http.Client, req *http.Request, out any) error {
+	resp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSTTResponse))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status: %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, out)
+}
@@ -3,6 +3,8 @@ package res
 import (
 	"bytes"
 	_ "embed"
+	"errors"
+	"fmt"
 	"io"
 
 	"github.com/jfreymuth/oggvorbis"
@@ -21,16 +23,25 @@ var WrongPinOgg []byte
 const SampleRate = 48000
 
 func ReadOggAudioFile(data []byte) []msdk.PCM16Sample {
+	frames, err := DecodeOggAudio(data)
+	if err != nil {
+		panic(err)
+	}
+	return frames
+}
+
+// DecodeOggAudio decodes Ogg Vorbis audio (48 kHz, mono) and splits it into frames.
+func DecodeOggAudio(data []byte) ([]msdk.PCM16Sample, error) {
 	const perFrame = SampleRate / msdk.DefFramesPerSec
 	r, err := oggvorbis.NewReader(bytes.NewReader(data))
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	if r.SampleRate() != SampleRate {
-		panic("unexpected sample rate")
+		return nil, fmt.Errorf("unexpected sample rate: %d", r.SampleRate())
 	}
 	if r.Channels() != 1 {
-		panic("expected mono audio")
+		return nil, errors.New("expected mono audio")
 	}
 	// Frames in the source file may be shorter,
 	// so we collect all samples and split them to frames again.
@@ -48,7 +59,7 @@ func ReadOggAudioFile(data []byte) []msdk.PCM16Sample {
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			panic(err)
+			return nil, err
 		}
 	}
 	var frames []msdk.PCM16Sample
@@ -60,5 +71,5 @@ func ReadOggAudioFile(data []byte) []msdk.PCM16Sample {
 		frames = append(frames, cur)
 		samples = samples[len(cur):]
 	}
-	return frames
+	return frames, nil
 }